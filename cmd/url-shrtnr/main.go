@@ -2,12 +2,28 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/kenplix/url-shrtnr/internal/app"
 )
 
 func main() {
-	if err := app.Run(); err != nil {
+	commands := map[string]func(args []string) error{
+		"migrate": app.Migrate,
+		"dump":    app.Dump,
+		"restore": app.Restore,
+		"role":    app.Role,
+	}
+
+	var err error
+
+	if len(os.Args) > 1 && commands[os.Args[1]] != nil {
+		err = commands[os.Args[1]](os.Args[2:])
+	} else {
+		err = app.Run()
+	}
+
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -19,9 +19,14 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/refresh-tokens": {
-            "post": {
-                "description": "Refresh users tokens",
+        "/admin/audit-events": {
+            "get": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Returns security events, optionally filtered by user and action, newest first",
                 "consumes": [
                     "application/json"
                 ],
@@ -29,29 +34,70 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Refresh users tokens",
+                "summary": "List security events",
                 "parameters": [
                     {
-                        "description": "JSON schema for tokens refresh",
-                        "name": "schema",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/v1.userRefreshTokensSchema"
-                        }
+                        "type": "string",
+                        "description": "User who performed action or was affected by it",
+                        "name": "userID",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Action",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Number of events per page",
+                        "name": "perPage",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User tokens was successfully refreshed",
+                        "description": "Security events page",
                         "schema": {
-                            "$ref": "#/definitions/entity.Tokens"
+                            "$ref": "#/definitions/entity.AuditEventsPage"
                         }
                     },
-                    "400": {
-                        "description": "Invalid JSON or wrong type of JSON values",
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or you aren't an administrator",
                         "schema": {
                             "allOf": [
                                 {
@@ -116,9 +162,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/sign-in": {
+        "/admin/sign-in/unlock": {
             "post": {
-                "description": "Sign in users into system",
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Clears failed sign in attempts and lockout of login and/or IP address",
                 "consumes": [
                     "application/json"
                 ],
@@ -126,26 +177,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Sign in users into system",
+                "summary": "Unlock sign in",
                 "parameters": [
                     {
-                        "description": "JSON schema for user sign in",
+                        "description": "JSON schema for sign in unlocking",
                         "name": "schema",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.userSignInSchema"
+                            "$ref": "#/definitions/v1.adminUnlockSignInSchema"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "User was successfully signed in",
-                        "schema": {
-                            "$ref": "#/definitions/entity.Tokens"
-                        }
+                    "204": {
+                        "description": "Sign in was successfully unlocked"
                     },
                     "400": {
                         "description": "Invalid JSON or wrong type of JSON values",
@@ -168,8 +216,29 @@ const docTemplate = `{
                             ]
                         }
                     },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
                     "403": {
-                        "description": "Your account has been suspended",
+                        "description": "Your account has been suspended or you aren't an administrator",
                         "schema": {
                             "allOf": [
                                 {
@@ -234,14 +303,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/sign-out": {
-            "post": {
+        "/admin/users": {
+            "get": {
                 "security": [
                     {
                         "JWT-RS256": []
                     }
                 ],
-                "description": "Sign out users from the system",
+                "description": "Returns users in order of registration, optionally filtered by username or email",
                 "consumes": [
                     "application/json"
                 ],
@@ -249,12 +318,40 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
+                ],
+                "summary": "List users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Part of username or email",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Number of users per page",
+                        "name": "perPage",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Sign out users from the system",
                 "responses": {
                     "200": {
-                        "description": "User was successfully signed out"
+                        "description": "Users page",
+                        "schema": {
+                            "$ref": "#/definitions/entity.UsersPage"
+                        }
                     },
                     "401": {
                         "description": "Access is denied due to invalid credentials",
@@ -278,7 +375,7 @@ const docTemplate = `{
                         }
                     },
                     "403": {
-                        "description": "Your account has been suspended",
+                        "description": "Your account has been suspended or you aren't an administrator",
                         "schema": {
                             "allOf": [
                                 {
@@ -298,6 +395,27 @@ const docTemplate = `{
                             ]
                         }
                     },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
                     "500": {
                         "description": "Internal server error",
                         "schema": {
@@ -322,9 +440,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/sign-up": {
-            "post": {
-                "description": "Sign up users into system",
+        "/admin/users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Returns user information including role and suspension details",
                 "consumes": [
                     "application/json"
                 ],
@@ -332,26 +455,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Sign up users into system",
+                "summary": "Get user",
                 "parameters": [
                     {
-                        "description": "JSON schema for user sign up",
-                        "name": "schema",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/v1.userSignUpSchema"
-                        }
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "User account was successfully signed up"
+                    "200": {
+                        "description": "User information",
+                        "schema": {
+                            "$ref": "#/definitions/entity.User"
+                        }
                     },
-                    "400": {
-                        "description": "Invalid JSON or wrong type of JSON values",
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
                         "schema": {
                             "allOf": [
                                 {
@@ -371,8 +495,8 @@ const docTemplate = `{
                             ]
                         }
                     },
-                    "422": {
-                        "description": "Validation failed through invalid fields",
+                    "403": {
+                        "description": "Your account has been suspended or you aren't an administrator",
                         "schema": {
                             "allOf": [
                                 {
@@ -384,7 +508,28 @@ const docTemplate = `{
                                         "errors": {
                                             "type": "array",
                                             "items": {
-                                                "$ref": "#/definitions/entity.ValidationError"
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
                                             }
                                         }
                                     }
@@ -416,14 +561,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/change-email": {
-            "patch": {
+        "/admin/users/{id}/2fa": {
+            "delete": {
                 "security": [
                     {
                         "JWT-RS256": []
                     }
                 ],
-                "description": "Changes users emails",
+                "description": "Disables two-factor authentication of the user who lost access to the authenticator app and recovery codes",
                 "consumes": [
                     "application/json"
                 ],
@@ -431,26 +576,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "Changes users emails",
+                "summary": "Reset two-factor authentication",
                 "parameters": [
                     {
-                        "description": "JSON schema for user email changing",
-                        "name": "schema",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/v1.userChangeEmailSchema"
-                        }
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "User email was successfully changed"
+                    "204": {
+                        "description": "Two-factor authentication was successfully reset"
                     },
-                    "400": {
-                        "description": "Invalid JSON or wrong type of JSON values",
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
                         "schema": {
                             "allOf": [
                                 {
@@ -470,8 +613,8 @@ const docTemplate = `{
                             ]
                         }
                     },
-                    "401": {
-                        "description": "Access is denied due to invalid credentials",
+                    "403": {
+                        "description": "Your account has been suspended or you aren't an administrator",
                         "schema": {
                             "allOf": [
                                 {
@@ -491,8 +634,8 @@ const docTemplate = `{
                             ]
                         }
                     },
-                    "403": {
-                        "description": "Your account has been suspended",
+                    "404": {
+                        "description": "User not found",
                         "schema": {
                             "allOf": [
                                 {
@@ -513,7 +656,7 @@ const docTemplate = `{
                         }
                     },
                     "422": {
-                        "description": "Validation failed through invalid fields",
+                        "description": "Two-factor authentication not enabled",
                         "schema": {
                             "allOf": [
                                 {
@@ -525,7 +668,7 @@ const docTemplate = `{
                                         "errors": {
                                             "type": "array",
                                             "items": {
-                                                "$ref": "#/definitions/entity.ValidationError"
+                                                "$ref": "#/definitions/entity.CoreError"
                                             }
                                         }
                                     }
@@ -557,14 +700,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/change-password": {
-            "patch": {
+        "/admin/users/{id}/role": {
+            "put": {
                 "security": [
                     {
                         "JWT-RS256": []
                     }
                 ],
-                "description": "Changes users passwords",
+                "description": "Grants or revokes administrator role, user gets scopes of the new role on the next tokens refresh",
                 "consumes": [
                     "application/json"
                 ],
@@ -572,23 +715,30 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "Changes users passwords",
+                "summary": "Set user role",
                 "parameters": [
                     {
-                        "description": "JSON schema for user password changing",
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON schema for user role",
                         "name": "schema",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.userChangePasswordSchema"
+                            "$ref": "#/definitions/v1.adminSetRoleSchema"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "User password was successfully changed"
+                    "204": {
+                        "description": "User role was successfully set"
                     },
                     "400": {
                         "description": "Invalid JSON or wrong type of JSON values",
@@ -633,7 +783,28 @@ const docTemplate = `{
                         }
                     },
                     "403": {
-                        "description": "Your account has been suspended",
+                        "description": "Your account has been suspended or you aren't an administrator",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
                         "schema": {
                             "allOf": [
                                 {
@@ -654,7 +825,7 @@ const docTemplate = `{
                         }
                     },
                     "422": {
-                        "description": "Validation failed through invalid fields",
+                        "description": "Validation failed through invalid fields or you tried to change your own role",
                         "schema": {
                             "allOf": [
                                 {
@@ -698,14 +869,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/me": {
-            "get": {
+        "/admin/users/{id}/sign-out": {
+            "post": {
                 "security": [
                     {
                         "JWT-RS256": []
                     }
                 ],
-                "description": "Returns users personal information",
+                "description": "Revokes all tokens of the user",
                 "consumes": [
                     "application/json"
                 ],
@@ -713,15 +884,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
+                ],
+                "summary": "Force user sign out",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Returns users personal information",
                 "responses": {
-                    "200": {
-                        "description": "User personal information",
-                        "schema": {
-                            "$ref": "#/definitions/entity.User"
-                        }
+                    "204": {
+                        "description": "User was successfully signed out"
                     },
                     "401": {
                         "description": "Access is denied due to invalid credentials",
@@ -745,7 +922,7 @@ const docTemplate = `{
                         }
                     },
                     "403": {
-                        "description": "Your account has been suspended",
+                        "description": "Your account has been suspended or you aren't an administrator",
                         "schema": {
                             "allOf": [
                                 {
@@ -765,8 +942,8 @@ const docTemplate = `{
                             ]
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "404": {
+                        "description": "User not found",
                         "schema": {
                             "allOf": [
                                 {
@@ -785,12 +962,4469 @@ const docTemplate = `{
                                 }
                             ]
                         }
-                    }
-                }
-            }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/suspend": {
+            "post": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Suspends user with provided reason and signs user out from all devices",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Suspend user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON schema for user suspension",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.adminSuspendUserSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "User was successfully suspended"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or wrong type of JSON values",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or you aren't an administrator",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields or you tried to suspend yourself",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/unsuspend": {
+            "post": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Lifts user suspension",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Unsuspend user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "User was successfully unsuspended"
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or you aren't an administrator",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/magic-link": {
+            "post": {
+                "description": "Sends single-use sign in link to the email. Response is the same whether user exists or not.\nLink works only in the browser which requested it, so the cookie set by this request must be kept",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Request magic link",
+                "parameters": [
+                    {
+                        "description": "JSON schema for magic link request",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.magicLinkRequestSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Link was sent if user with provided email exists",
+                        "headers": {
+                            "Set-Cookie": {
+                                "type": "string",
+                                "description": "Nonce binding link to the browser"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON or wrong type of JSON values",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "429": {
+                        "description": "Too many attempts",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds to wait before the next attempt"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/magic-link/sign-in": {
+            "post": {
+                "description": "Exchanges token from the magic link for tokens pair. Must be called from the browser which requested the link",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign in with magic link",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cookie"
+                        ],
+                        "type": "string",
+                        "description": "Set to cookie to receive tokens in HttpOnly cookies",
+                        "name": "X-Auth-Mode",
+                        "in": "header"
+                    },
+                    {
+                        "description": "JSON schema for sign in with magic link",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.magicLinkSignInSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User was successfully signed in",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Tokens"
+                        }
+                    },
+                    "202": {
+                        "description": "Second authentication factor required",
+                        "schema": {
+                            "$ref": "#/definitions/entity.MFAChallenge"
+                        }
+                    },
+                    "204": {
+                        "description": "Tokens were set in cookies"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or wrong type of JSON values",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Link expired, already used or requested from another browser",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed attempts",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds to wait before the next attempt"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/{provider}": {
+            "get": {
+                "description": "Redirects to the consent page of OpenID Connect or OAuth2 provider which redirects back to the callback",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign in with identity provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "google",
+                        "description": "Identity provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Redirect to the provider consent page",
+                        "headers": {
+                            "Set-Cookie": {
+                                "type": "string",
+                                "description": "Nonce binding authorization to the browser"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Identity provider not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/{provider}/callback": {
+            "get": {
+                "description": "Completes sign in with identity provider. New user is created for not linked provider account.\nIf email is already used by another user, that user must sign in and link provider account instead.\nMust be opened in the browser which started authorization",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Identity provider callback",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "google",
+                        "description": "Identity provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State of the authorization request",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization error",
+                        "name": "error",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User was successfully signed in",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Tokens"
+                        }
+                    },
+                    "202": {
+                        "description": "Second authentication factor required",
+                        "schema": {
+                            "$ref": "#/definitions/entity.MFAChallenge"
+                        }
+                    },
+                    "401": {
+                        "description": "Identity provider authentication failed",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Identity provider not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Authorization expired, started in another browser, email not verified or in use, or identity linked to another user",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh-tokens": {
+            "post": {
+                "description": "Refresh users tokens, new tokens are issued with current scopes of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh users tokens",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cookie"
+                        ],
+                        "type": "string",
+                        "description": "Set to cookie to receive tokens in HttpOnly cookies",
+                        "name": "X-Auth-Mode",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Value of csrf_token cookie, required in cookie mode",
+                        "name": "X-CSRF-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "JSON schema for tokens refresh, refresh token is read from the cookie in cookie mode",
+                        "name": "schema",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/v1.userRefreshTokensSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User tokens was successfully refreshed",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Tokens"
+                        }
+                    },
+                    "204": {
+                        "description": "Tokens were set in cookies"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or wrong type of JSON values",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "CSRF token is missing or invalid, your account has been suspended or scheduled for deletion",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sign-in": {
+            "post": {
+                "description": "Sign in users into system",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign in users into system",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cookie"
+                        ],
+                        "type": "string",
+                        "description": "Set to cookie to receive tokens in HttpOnly cookies",
+                        "name": "X-Auth-Mode",
+                        "in": "header"
+                    },
+                    {
+                        "description": "JSON schema for user sign in",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.userSignInSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User was successfully signed in",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Tokens"
+                        }
+                    },
+                    "202": {
+                        "description": "Second authentication factor required",
+                        "schema": {
+                            "$ref": "#/definitions/entity.MFAChallenge"
+                        }
+                    },
+                    "204": {
+                        "description": "Tokens were set in cookies"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or wrong type of JSON values",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed attempts",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "integer",
+                                "description": "Seconds to wait before the next attempt"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sign-in/mfa": {
+            "post": {
+                "description": "Exchanges MFA token received on sign in together with TOTP or recovery code for tokens pair",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Complete sign in with TOTP or recovery code",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cookie"
+                        ],
+                        "type": "string",
+                        "description": "Set to cookie to receive tokens in HttpOnly cookies",
+                        "name": "X-Auth-Mode",
+                        "in": "header"
+                    },
+                    {
+                        "description": "JSON schema for second factor verification",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.userSignInMFASchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User was successfully signed in",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Tokens"
+                        }
+                    },
+                    "204": {
+                        "description": "Tokens were set in cookies"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or wrong type of JSON values",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sign-out": {
+            "post": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Sign out users from the system",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign out users from the system",
+                "responses": {
+                    "200": {
+                        "description": "User was successfully signed out"
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sign-up": {
+            "post": {
+                "description": "Sign up users into system",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign up users into system",
+                "parameters": [
+                    {
+                        "description": "JSON schema for user sign up",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.userSignUpSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User account was successfully signed up"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or wrong type of JSON values",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/avatars/{name}": {
+            "get": {
+                "description": "Returns avatar image. Keys are changed together with images, so responses are cached forever",
+                "produces": [
+                    "image/png"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Get avatar",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Avatar file name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Avatar image",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Avatar isn't modified"
+                    },
+                    "404": {
+                        "description": "Avatar not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/profiles/{username}": {
+            "get": {
+                "description": "Returns publicly visible information of the user.\nRecently changed username is redirected to the profile with current username",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Get public profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Public profile",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Profile"
+                        }
+                    },
+                    "302": {
+                        "description": "Username was recently changed, Location header contains current profile"
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/2fa/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Enables two-factor authentication and returns one-time recovery codes which are shown only once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "2fa"
+                ],
+                "summary": "Confirm two-factor authentication enrollment",
+                "parameters": [
+                    {
+                        "description": "JSON schema for two-factor enrollment confirmation",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.twoFactorConfirmSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Two-factor authentication was successfully enabled",
+                        "schema": {
+                            "$ref": "#/definitions/entity.RecoveryCodes"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON or wrong type of JSON values",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/2fa/disable": {
+            "post": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Disables two-factor authentication, requires current password and TOTP or recovery code",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "2fa"
+                ],
+                "summary": "Disable two-factor authentication",
+                "parameters": [
+                    {
+                        "description": "JSON schema for two-factor authentication disabling",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.twoFactorDisableSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Two-factor authentication was successfully disabled"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or wrong type of JSON values",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/2fa/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Generates TOTP secret which must be confirmed with the first code during enrollment TTL",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "2fa"
+                ],
+                "summary": "Start two-factor authentication enrollment",
+                "responses": {
+                    "200": {
+                        "description": "TOTP secret, key URI and QR code",
+                        "schema": {
+                            "$ref": "#/definitions/entity.TwoFactorEnrollment"
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Two-factor authentication already enabled",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Returns users API keys without secrets",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "List personal access tokens",
+                "responses": {
+                    "200": {
+                        "description": "User API keys",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/entity.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Creates API key with provided scopes, key is shown only once and can't be recovered afterwards",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Create personal access token",
+                "parameters": [
+                    {
+                        "description": "JSON schema for API key creation",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.apiKeyCreateSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "API key was successfully created",
+                        "schema": {
+                            "$ref": "#/definitions/entity.CreatedAPIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON or wrong type of JSON values",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields or API keys limit reached",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Deletes API key, requests made with it are rejected immediately",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api-keys"
+                ],
+                "summary": "Revoke personal access token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "API key was successfully revoked"
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/audit-events": {
+            "get": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Returns security events performed by the user or on the user, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "List own security events",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Number of events per page",
+                        "name": "perPage",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Security events page",
+                        "schema": {
+                            "$ref": "#/definitions/entity.AuditEventsPage"
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/avatar": {
+            "put": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Accepts JPEG, PNG or GIF image up to 5MB, which is cropped to square and resized",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Uploads avatar",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the user, avatar isn't changed if the user was modified since then",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "file",
+                        "description": "Avatar image",
+                        "name": "avatar",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Avatar was successfully changed",
+                        "schema": {
+                            "$ref": "#/definitions/entity.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the changed user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Avatar is missing or too large or If-Match header is malformed",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "409": {
+                        "description": "User was modified since ETag from If-Match header",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Image is corrupted or its format isn't supported",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Removes avatar image of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Removes avatar",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the user, avatar isn't removed if the user was modified since then",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Avatar was successfully removed",
+                        "schema": {
+                            "$ref": "#/definitions/entity.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the changed user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Malformed If-Match header",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "409": {
+                        "description": "User was modified since ETag from If-Match header",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/change-email": {
+            "patch": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Changes users emails",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Changes users emails",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the user, email isn't changed if the user was modified since then",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "JSON schema for user email changing",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.userChangeEmailSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User email was successfully changed"
+                    },
+                    "400": {
+                        "description": "Invalid JSON, wrong type of JSON values or malformed If-Match header",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "409": {
+                        "description": "User was modified since ETag from If-Match header",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/change-password": {
+            "patch": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Changes users passwords",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Changes users passwords",
+                "parameters": [
+                    {
+                        "enum": [
+                            "cookie"
+                        ],
+                        "type": "string",
+                        "description": "Set to cookie to receive tokens in HttpOnly cookies",
+                        "name": "X-Auth-Mode",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the user, password isn't changed if the user was modified since then",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "JSON schema for user password changing",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.userChangePasswordSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User password was successfully changed and all sessions were revoked, tokens pair is returned when current session is kept",
+                        "schema": {
+                            "$ref": "#/definitions/entity.Tokens"
+                        }
+                    },
+                    "204": {
+                        "description": "User password was successfully changed, new tokens were set in cookies"
+                    },
+                    "400": {
+                        "description": "Invalid JSON, wrong type of JSON values or malformed If-Match header",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "409": {
+                        "description": "User was modified since ETag from If-Match header",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/change-username": {
+            "patch": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Changes username. Username may be changed once per cool-down period, previous username\nredirects to the current one and can't be taken by other users during redirect period",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Changes username",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the user, username isn't changed if the user was modified since then",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "JSON schema for username changing",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.userChangeUsernameSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Username was successfully changed",
+                        "schema": {
+                            "$ref": "#/definitions/entity.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the changed user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON, wrong type of JSON values or malformed If-Match header",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "409": {
+                        "description": "User was modified since ETag from If-Match header",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields, taken username or cool-down period",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/deletion": {
+            "post": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Requires current password and TOTP or recovery code when two-factor authentication is enabled.\nUser who has no password gets one-time confirmation by email and repeats request with token from it instead.\nSigns user out from all devices, account is permanently deleted after grace period unless deletion is cancelled",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Schedule account deletion",
+                "parameters": [
+                    {
+                        "description": "JSON schema for account deletion",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.scheduleDeletionSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Account deletion was successfully scheduled",
+                        "schema": {
+                            "$ref": "#/definitions/entity.User"
+                        }
+                    },
+                    "202": {
+                        "description": "Deletion confirmation was sent by email"
+                    },
+                    "400": {
+                        "description": "Invalid JSON or wrong type of JSON values",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields or incorrect credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Cancels account deletion scheduled less than grace period ago",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Cancel account deletion",
+                "responses": {
+                    "200": {
+                        "description": "Account deletion was successfully cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/entity.User"
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Account deletion isn't scheduled",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/export": {
+            "post": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Starts preparation of ZIP archive with profile, API keys and security events in background",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Request personal data export",
+                "responses": {
+                    "202": {
+                        "description": "Data export was successfully requested",
+                        "schema": {
+                            "$ref": "#/definitions/entity.DataExport"
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/export/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Returns state of personal data export, archive may be downloaded once export is ready",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Get personal data export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Data export ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Data export state",
+                        "schema": {
+                            "$ref": "#/definitions/entity.DataExport"
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Data export not found or expired",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/export/{id}/download": {
+            "get": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Returns ZIP archive of the ready personal data export",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Download personal data export",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Data export ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Personal data archive",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Data export not found or expired",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "409": {
+                        "description": "Data export isn't ready yet",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me": {
+            "get": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    },
+                    {
+                        "APIKey": []
+                    }
+                ],
+                "description": "Returns users personal information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Returns users personal information",
+                "responses": {
+                    "200": {
+                        "description": "User personal information",
+                        "schema": {
+                            "$ref": "#/definitions/entity.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the user, which is sent in If-Match header to change the user safely"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/oidc/{provider}/link": {
+            "post": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Returns consent page of the provider, once user passes it provider account is linked and may be used for sign in.\nConsent page must be opened in the same browser, so the cookie set by this request must be kept",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Link identity provider account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "google",
+                        "description": "Identity provider name",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Consent page of the provider",
+                        "schema": {
+                            "$ref": "#/definitions/entity.OIDCAuthorization"
+                        },
+                        "headers": {
+                            "Set-Cookie": {
+                                "type": "string",
+                                "description": "Nonce binding authorization to the browser"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Identity provider not found",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
+        },
+        "/users/profile": {
+            "patch": {
+                "security": [
+                    {
+                        "JWT-RS256": []
+                    }
+                ],
+                "description": "Changes provided display name and bio, omitted fields are kept",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profile"
+                ],
+                "summary": "Updates public profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the user, profile isn't updated if the user was modified since then",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "JSON schema for profile updating",
+                        "name": "schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.userUpdateProfileSchema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Profile was successfully updated",
+                        "schema": {
+                            "$ref": "#/definitions/entity.User"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the changed user"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid JSON, wrong type of JSON values or malformed If-Match header",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Access is denied due to invalid credentials",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Your account has been suspended or access token lacks required scopes",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "409": {
+                        "description": "User was modified since ETag from If-Match header",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed through invalid fields",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.ValidationError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.errResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "errors": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/entity.CoreError"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
+        "entity.APIKey": {
+            "description": "Personal access token information",
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-01-14T15:57:06.812377+02:00"
+                },
+                "expiresAt": {
+                    "description": "ExpiresAt is a date after which key is not accepted anymore (optional)",
+                    "type": "string",
+                    "example": "2023-12-24T21:49:33.072726+02:00"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "63c2b7a1e4b0f2a9d1c3e5f7"
+                },
+                "lastUsedAt": {
+                    "description": "LastUsedAt is an approximate date when key was used last time (optional)",
+                    "type": "string",
+                    "example": "2023-01-14T16:02:11.254314+02:00"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ci deploy"
+                },
+                "prefix": {
+                    "description": "Prefix is a public part of the key which helps to recognize it",
+                    "type": "string",
+                    "example": "shrtnr_3f9a1c2e"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "links:read",
+                        "links:write"
+                    ]
+                }
+            }
+        },
+        "entity.AuditEvent": {
+            "description": "Record of security-relevant action",
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "auth.signed_in"
+                },
+                "actorID": {
+                    "description": "ActorID is a user who performed action, it's empty when user isn't known, like on failed sign in",
+                    "type": "string",
+                    "example": "63a75a2574ef628a127ee972"
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-01-25T21:49:33.072726+02:00"
+                },
+                "details": {
+                    "description": "Details contains action specific information, like suspension reason",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string",
+                    "example": "63d1a2b3c4d5e6f7a8b9c0d1"
+                },
+                "ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "outcome": {
+                    "type": "string",
+                    "enum": [
+                        "success",
+                        "failure"
+                    ],
+                    "example": "success"
+                },
+                "requestID": {
+                    "type": "string",
+                    "example": "3b1f6c0e-5a5e-4b8e-9a8c-2f1d3e4a5b6c"
+                },
+                "targetUserID": {
+                    "description": "TargetUserID is a user affected by administrative action (optional)",
+                    "type": "string",
+                    "example": "63a75a2574ef628a127ee973"
+                },
+                "userAgent": {
+                    "type": "string",
+                    "example": "Mozilla/5.0"
+                }
+            }
+        },
+        "entity.AuditEventsPage": {
+            "description": "Part of audit events list",
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.AuditEvent"
+                    }
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "perPage": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "total": {
+                    "description": "Total is a number of events matching the query",
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
         "entity.CoreError": {
             "description": "Basic representation of API call error",
             "type": "object",
@@ -811,6 +5445,151 @@ const docTemplate = `{
                 }
             }
         },
+        "entity.CreatedAPIKey": {
+            "description": "Just created personal access token, key is shown only once",
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-01-14T15:57:06.812377+02:00"
+                },
+                "expiresAt": {
+                    "description": "ExpiresAt is a date after which key is not accepted anymore (optional)",
+                    "type": "string",
+                    "example": "2023-12-24T21:49:33.072726+02:00"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "63c2b7a1e4b0f2a9d1c3e5f7"
+                },
+                "key": {
+                    "description": "Key must be sent in \"X-API-Key\" or \"Authorization: ApiKey \u003ckey\u003e\" header",
+                    "type": "string",
+                    "example": "shrtnr_3f9a1c2e_9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d"
+                },
+                "lastUsedAt": {
+                    "description": "LastUsedAt is an approximate date when key was used last time (optional)",
+                    "type": "string",
+                    "example": "2023-01-14T16:02:11.254314+02:00"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ci deploy"
+                },
+                "prefix": {
+                    "description": "Prefix is a public part of the key which helps to recognize it",
+                    "type": "string",
+                    "example": "shrtnr_3f9a1c2e"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "links:read",
+                        "links:write"
+                    ]
+                }
+            }
+        },
+        "entity.DataExport": {
+            "description": "State of personal data archive preparation",
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-01-25T21:49:33.072726+02:00"
+                },
+                "expiresAt": {
+                    "description": "ExpiresAt is a date after which export and its archive are removed",
+                    "type": "string",
+                    "example": "2023-01-26T21:49:33.072726+02:00"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "ready",
+                        "failed"
+                    ],
+                    "example": "ready"
+                }
+            }
+        },
+        "entity.MFAChallenge": {
+            "description": "Returned on sign in of users with enabled two-factor authentication",
+            "type": "object",
+            "properties": {
+                "expiresIn": {
+                    "description": "ExpiresIn is a number of seconds until MFA token expires",
+                    "type": "integer",
+                    "example": 300
+                },
+                "mfaToken": {
+                    "description": "MFAToken is a short-lived token which should be exchanged together with TOTP or recovery code for tokens pair",
+                    "type": "string",
+                    "example": "4f1c2c7e0d2b4a6f9e8d7c6b5a4f3e2d"
+                }
+            }
+        },
+        "entity.OIDCAuthorization": {
+            "description": "Returned when account of identity provider is linked to the signed-in user",
+            "type": "object",
+            "properties": {
+                "authURL": {
+                    "description": "AuthURL is a consent page of the provider which user must be redirected to",
+                    "type": "string",
+                    "example": "https://accounts.google.com/o/oauth2/v2/auth?client_id=url-shrtnr\u0026state=9f8e7d6c"
+                }
+            }
+        },
+        "entity.Profile": {
+            "description": "Publicly visible information of the user",
+            "type": "object",
+            "properties": {
+                "avatar": {
+                    "description": "Avatar is a path of avatar image relative to API base path, e.g. GET /api/v1/avatars/{name} (optional)",
+                    "type": "string",
+                    "example": "avatars/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png"
+                },
+                "bio": {
+                    "type": "string",
+                    "example": "Go developer"
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2022-12-24T21:49:33.072726+02:00"
+                },
+                "displayName": {
+                    "type": "string",
+                    "example": "Oleksandr Tolstoi"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "kenplix"
+                }
+            }
+        },
+        "entity.RecoveryCodes": {
+            "description": "Set of one-time codes which can be used instead of TOTP codes",
+            "type": "object",
+            "properties": {
+                "recoveryCodes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "k3n9x-7pl2q"
+                    ]
+                }
+            }
+        },
         "entity.Tokens": {
             "description": "Pair of access and refresh token which uses for auth operations",
             "type": "object",
@@ -825,14 +5604,55 @@ const docTemplate = `{
                 }
             }
         },
+        "entity.TwoFactorEnrollment": {
+            "description": "Data required to add account into authenticator app",
+            "type": "object",
+            "properties": {
+                "qrCode": {
+                    "description": "QRCode is a base64 encoded PNG image of the key URI",
+                    "type": "string",
+                    "format": "base64",
+                    "example": "iVBORw0KGgo="
+                },
+                "secret": {
+                    "description": "Secret is a base32 encoded shared secret for manual entry",
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXP"
+                },
+                "uri": {
+                    "description": "URI is an otpauth:// key URI",
+                    "type": "string",
+                    "example": "otpauth://totp/url-shrtnr:kenplix?issuer=url-shrtnr\u0026secret=JBSWY3DPEHPK3PXP"
+                }
+            }
+        },
         "entity.User": {
             "description": "User entity information",
             "type": "object",
             "properties": {
+                "avatar": {
+                    "description": "Avatar is a path of avatar image relative to API base path, e.g. GET /api/v1/avatars/{name} (optional)",
+                    "type": "string",
+                    "example": "avatars/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png"
+                },
+                "bio": {
+                    "type": "string",
+                    "example": "Go developer"
+                },
                 "createdAt": {
                     "type": "string",
                     "example": "2022-12-24T21:49:33.072726+02:00"
                 },
+                "deletionScheduledAt": {
+                    "description": "DeletionScheduledAt is a date when account will be permanently deleted unless deletion is cancelled (optional)",
+                    "type": "string",
+                    "example": "2023-01-24T21:49:33.072726+02:00"
+                },
+                "displayName": {
+                    "description": "DisplayName is a name shown instead of username in public profile (optional)",
+                    "type": "string",
+                    "example": "Oleksandr Tolstoi"
+                },
                 "email": {
                     "type": "string",
                     "example": "tolstoi.job@gmail.com"
@@ -841,11 +5661,30 @@ const docTemplate = `{
                     "type": "string",
                     "example": "63a75a2574ef628a127ee972"
                 },
+                "role": {
+                    "description": "Role defines which operations are available to the user",
+                    "type": "string",
+                    "enum": [
+                        "user",
+                        "admin"
+                    ],
+                    "example": "user"
+                },
                 "suspendedAt": {
                     "description": "SuspendedAt is a date when user was suspended through certain reasons (optional)",
                     "type": "string",
                     "example": "2022-12-25T14:25:58.821989+02:00"
                 },
+                "suspensionReason": {
+                    "description": "SuspensionReason is a reason stated by administrator who suspended user (optional)",
+                    "type": "string",
+                    "example": "spam links"
+                },
+                "twoFactorEnabled": {
+                    "description": "TwoFactorEnabled shows whether user signs in with TOTP codes",
+                    "type": "boolean",
+                    "example": false
+                },
                 "updatedAt": {
                     "description": "UpdatedAt is a date of last user personal information modification",
                     "type": "string",
@@ -854,6 +5693,41 @@ const docTemplate = `{
                 "username": {
                     "type": "string",
                     "example": "kenplix"
+                },
+                "usernameChangedAt": {
+                    "description": "UsernameChangedAt is a date of the last username change (optional)",
+                    "type": "string",
+                    "example": "2023-01-20T11:02:45.072726+02:00"
+                },
+                "version": {
+                    "description": "Version is incremented on every change of the user, it's also returned in ETag header",
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "entity.UsersPage": {
+            "description": "Part of users list",
+            "type": "object",
+            "properties": {
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "perPage": {
+                    "type": "integer",
+                    "example": 20
+                },
+                "total": {
+                    "description": "Total is a number of users matching the search",
+                    "type": "integer",
+                    "example": 42
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entity.User"
+                    }
                 }
             }
         },
@@ -894,6 +5768,32 @@ const docTemplate = `{
                 "INCORRECT_CREDENTIALS",
                 "UNAUTHORIZED_ACCESS",
                 "CURRENT_USER_SUSPENDED",
+                "INCORRECT_OTP",
+                "TWO_FACTOR_ALREADY_ENABLED",
+                "TWO_FACTOR_NOT_ENABLED",
+                "TWO_FACTOR_ENROLLMENT_EXPIRED",
+                "TOO_MANY_ATTEMPTS",
+                "INSUFFICIENT_SCOPE",
+                "API_KEY_NOT_FOUND",
+                "API_KEYS_LIMIT_REACHED",
+                "IDENTITY_PROVIDER_NOT_FOUND",
+                "AUTHORIZATION_EXPIRED",
+                "EMAIL_NOT_VERIFIED",
+                "IDENTITY_ALREADY_LINKED",
+                "MAGIC_LINK_EXPIRED",
+                "CSRF_TOKEN_MISMATCH",
+                "INSUFFICIENT_ROLE",
+                "USER_NOT_FOUND",
+                "SELF_ADMINISTRATION",
+                "DATA_EXPORT_NOT_FOUND",
+                "DATA_EXPORT_NOT_READY",
+                "DELETION_NOT_SCHEDULED",
+                "DELETION_SCHEDULED",
+                "CONFIRMATION_EXPIRED",
+                "USERNAME_CHANGE_COOLDOWN",
+                "INVALID_IMAGE",
+                "AVATAR_NOT_FOUND",
+                "VERSION_CONFLICT",
                 "INTERNAL_ERROR"
             ],
             "x-enum-varnames": [
@@ -905,9 +5805,108 @@ const docTemplate = `{
                 "IncorrectCredentials",
                 "UnauthorizedAccess",
                 "CurrentUserSuspended",
+                "IncorrectOTP",
+                "TwoFactorAlreadyEnabled",
+                "TwoFactorNotEnabled",
+                "TwoFactorEnrollmentExpired",
+                "TooManyAttempts",
+                "InsufficientScope",
+                "APIKeyNotFound",
+                "APIKeysLimitReached",
+                "IdentityProviderNotFound",
+                "AuthorizationExpired",
+                "EmailNotVerified",
+                "IdentityAlreadyLinked",
+                "MagicLinkExpired",
+                "CSRFTokenMismatch",
+                "InsufficientRole",
+                "UserNotFound",
+                "SelfAdministration",
+                "DataExportNotFound",
+                "DataExportNotReady",
+                "DeletionNotScheduled",
+                "DeletionScheduled",
+                "ConfirmationExpired",
+                "UsernameChangeCooldown",
+                "InvalidImage",
+                "AvatarNotFound",
+                "VersionConflict",
                 "InternalError"
             ]
         },
+        "v1.adminSetRoleSchema": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "user",
+                        "admin"
+                    ],
+                    "example": "admin"
+                }
+            }
+        },
+        "v1.adminSuspendUserSchema": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 256,
+                    "example": "spam links"
+                }
+            }
+        },
+        "v1.adminUnlockSignInSchema": {
+            "type": "object",
+            "properties": {
+                "ip": {
+                    "type": "string",
+                    "example": "203.0.113.7"
+                },
+                "login": {
+                    "type": "string",
+                    "example": "kenplix"
+                }
+            }
+        },
+        "v1.apiKeyCreateSchema": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expiresAt": {
+                    "description": "ExpiresAt is a date after which key is not accepted anymore, key never expires if omitted",
+                    "type": "string",
+                    "example": "2023-12-24T21:49:33.072726+02:00"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "ci deploy"
+                },
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "uniqueItems": true,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "links:read",
+                        "links:write"
+                    ]
+                }
+            }
+        },
         "v1.errResponse": {
             "description": "Standardized representation of an errors that may occur in API calls",
             "type": "object",
@@ -919,6 +5918,86 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.magicLinkRequestSchema": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "tolstoi.job@gmail.com"
+                }
+            }
+        },
+        "v1.magicLinkSignInSchema": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string",
+                    "maxLength": 128,
+                    "example": "9b2f0c4e7a1d4c8e9f3b6a5d2e1c0b7a9b2f0c4e7a1d4c8e9f3b6a5d2e1c0b7a"
+                }
+            }
+        },
+        "v1.scheduleDeletionSchema": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is TOTP or recovery code, required when two-factor authentication is enabled",
+                    "type": "string",
+                    "maxLength": 32,
+                    "example": "123456"
+                },
+                "password": {
+                    "description": "Password is required unless user has no password, e.g. signed up with identity provider",
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "1wE$Rty2"
+                },
+                "token": {
+                    "description": "Token is one-time deletion confirmation sent by email to user who has no password",
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "6f1c2a9e0b7d4e3f8a5c1b2d9e0f7a6c6f1c2a9e0b7d4e3f8a5c1b2d9e0f7a6c"
+                }
+            }
+        },
+        "v1.twoFactorConfirmSchema": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 8,
+                    "minLength": 6,
+                    "example": "123456"
+                }
+            }
+        },
+        "v1.twoFactorDisableSchema": {
+            "type": "object",
+            "required": [
+                "code",
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "example": "123456"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "1wE$Rty2"
+                }
+            }
+        },
         "v1.userChangeEmailSchema": {
             "type": "object",
             "required": [
@@ -943,6 +6022,11 @@ const docTemplate = `{
                     "type": "string",
                     "example": "1wE$Rty2"
                 },
+                "keepCurrentSession": {
+                    "description": "KeepCurrentSession keeps the current session with new tokens pair. All sessions\nare revoked on password change, so without it the current one must sign in again too",
+                    "type": "boolean",
+                    "example": true
+                },
                 "newPassword": {
                     "type": "string",
                     "example": "2ytR$Ew1"
@@ -953,6 +6037,18 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.userChangeUsernameSchema": {
+            "type": "object",
+            "required": [
+                "newUsername"
+            ],
+            "properties": {
+                "newUsername": {
+                    "type": "string",
+                    "example": "kenplix"
+                }
+            }
+        },
         "v1.userRefreshTokensSchema": {
             "type": "object",
             "required": [
@@ -965,6 +6061,24 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.userSignInMFASchema": {
+            "type": "object",
+            "required": [
+                "code",
+                "mfaToken"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 32,
+                    "example": "123456"
+                },
+                "mfaToken": {
+                    "type": "string",
+                    "example": "4f1c2c7e0d2b4a6f9e8d7c6b5a4f3e2d"
+                }
+            }
+        },
         "v1.userSignInSchema": {
             "type": "object",
             "required": [
@@ -1003,9 +6117,31 @@ const docTemplate = `{
                     "example": "kenplix"
                 }
             }
+        },
+        "v1.userUpdateProfileSchema": {
+            "type": "object",
+            "properties": {
+                "bio": {
+                    "description": "Bio is kept if omitted and cleared if empty",
+                    "type": "string",
+                    "maxLength": 280,
+                    "example": "Go developer"
+                },
+                "displayName": {
+                    "description": "DisplayName is kept if omitted and cleared if empty",
+                    "type": "string",
+                    "maxLength": 64,
+                    "example": "Oleksandr Tolstoi"
+                }
+            }
         }
     },
     "securityDefinitions": {
+        "APIKey": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
         "JWT-RS256": {
             "type": "apiKey",
             "name": "Authorization",
@@ -3,6 +3,10 @@ package app
 import (
 	"context"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	transport "github.com/kenplix/url-shrtnr/internal/controller/http"
 
@@ -14,12 +18,12 @@ import (
 
 	"github.com/pkg/errors"
 
-	"github.com/kenplix/url-shrtnr/pkg/cache/redis"
-
 	"github.com/kenplix/url-shrtnr/internal/config"
 	"github.com/kenplix/url-shrtnr/internal/repository"
 	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/blob"
 	"github.com/kenplix/url-shrtnr/pkg/httpserver"
+	"github.com/kenplix/url-shrtnr/pkg/mail"
 )
 
 // Run initializes and starts whole application.
@@ -37,8 +41,13 @@ import (
 //	@securitydefinitions.apikey	JWT-RS256
 //	@in							header
 //	@name						Authorization
+//
+//	@securitydefinitions.apikey	APIKey
+//	@in							header
+//	@name						X-API-Key
 func Run() error {
-	ctx, cancel := context.WithCancel(context.Background())
+	// process is stopped gracefully, so state of the embedded storages is saved
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	cfg, err := config.Read("configs")
@@ -53,41 +62,78 @@ func Run() error {
 
 	defer logger.Sync()
 
+	for _, deprecation := range cfg.Deprecations {
+		logger.Warn(deprecation)
+	}
+
 	repos, err := repository.New(ctx, cfg.Database)
 	if err != nil {
 		return errors.Wrap(err, "failed to create repositories")
 	}
 	defer repos.Close(context.TODO())
 
-	cache, err := redis.NewClient(ctx, cfg.Redis)
+	cache, err := service.NewCache(log.ContextWithLogger(ctx, logger), cfg.Cache)
 	if err != nil {
-		return errors.Wrap(err, "failed to create redis client")
+		return errors.Wrap(err, "failed to create cache")
 	}
 
+	defer func() {
+		if closeErr := cache.Close(context.TODO()); closeErr != nil {
+			logger.Error("failed to close cache", zap.Error(closeErr))
+		}
+	}()
+
 	hasherServ, err := hash.NewHasherService(cfg.Hasher)
 	if err != nil {
 		return errors.Wrapf(err, "failed to create hasher service")
 	}
 
+	mailSender, err := mail.NewSender(cfg.Mail)
+	if err != nil {
+		return errors.Wrap(err, "failed to create mail sender")
+	}
+
+	blobStore, err := blob.NewStore(cfg.Blob)
+	if err != nil {
+		return errors.Wrap(err, "failed to create blob store")
+	}
+
 	services, err := service.NewServices(service.Dependencies{
-		Cache:            cache,
-		Repos:            repos,
-		HasherService:    hasherServ,
-		JWTServiceConfig: cfg.JWT,
+		Cache:                  cache,
+		Repos:                  repos,
+		HasherService:          hasherServ,
+		JWTServiceConfig:       cfg.JWT,
+		TwoFactorServiceConfig: cfg.TwoFactor,
+		ThrottleServiceConfig:  cfg.Throttle,
+		OIDCServiceConfig:      cfg.OIDC,
+		MagicLinkServiceConfig: cfg.MagicLink,
+		AccountServiceConfig:   cfg.Account,
+		ProfileServiceConfig:   cfg.Profile,
+		MailSender:             mailSender,
+		BlobStore:              blobStore,
 	})
 	if err != nil {
 		return errors.Wrapf(err, "failed to create services")
 	}
 
+	go reloadKeysOnSignal(ctx, logger, services.JWT)
+	go purgeDeletedAccounts(ctx, logger, services.Account)
+
 	handler, err := transport.NewHandler(logger, services)
 	if err != nil {
 		return errors.Wrap(err, "failed to create handler")
 	}
 
-	engine := handler.InitEngine(cfg.Environment, transport.Config{
-		Host: cfg.HTTP.Host,
-		Port: cfg.HTTP.Port,
+	engine, err := handler.InitEngine(cfg.Environment, transport.Config{
+		Host:           cfg.HTTP.Host,
+		Port:           cfg.HTTP.Port,
+		TrustedProxies: cfg.HTTP.TrustedProxies,
+		CORS:           cfg.HTTP.CORS,
+		AuthCookies:    cfg.HTTP.AuthCookies,
 	})
+	if err != nil {
+		return errors.Wrap(err, "failed to init engine")
+	}
 
 	httpServer := httpserver.New(engine, httpserver.SetConfig(cfg.HTTP))
 
@@ -96,13 +142,68 @@ func Run() error {
 		zap.String("addr", addr),
 	)
 
-	if err = <-httpServer.Notify(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		logger.Error("error occurred while running HTTP server",
-			zap.Error(err),
-		)
+	select {
+	case err = <-httpServer.Notify():
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("error occurred while running HTTP server",
+				zap.Error(err),
+			)
+		}
+	case <-ctx.Done():
+		logger.Info("shutting down on signal")
 	}
 
 	err = httpServer.Shutdown()
 
 	return errors.Wrap(err, "failed to shutdown HTTP server")
 }
+
+// reloadKeysOnSignal reloads tokens signing keys each time process receives SIGHUP
+func reloadKeysOnSignal(ctx context.Context, logger *zap.Logger, jwtServ service.JWTService) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	defer signal.Stop(sighup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			if err := jwtServ.ReloadKeys(); err != nil {
+				logger.Error("failed to reload tokens signing keys", zap.Error(err))
+				continue
+			}
+
+			logger.Info("tokens signing keys reloaded")
+		}
+	}
+}
+
+// accountsPurgeInterval is a period between checks of accounts whose deletion grace period is over
+const accountsPurgeInterval = time.Hour
+
+// purgeDeletedAccounts periodically deletes accounts whose deletion grace period is over
+func purgeDeletedAccounts(ctx context.Context, logger *zap.Logger, accountServ service.AccountService) {
+	ticker := time.NewTicker(accountsPurgeInterval)
+	defer ticker.Stop()
+
+	purgeCtx := log.ContextWithLogger(ctx, logger)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := accountServ.PurgeDeleted(purgeCtx)
+			if err != nil {
+				logger.Error("failed to purge deleted accounts", zap.Int("purged", purged), zap.Error(err))
+				continue
+			}
+
+			if purged > 0 {
+				logger.Info("deleted accounts purged", zap.Int("purged", purged))
+			}
+		}
+	}
+}
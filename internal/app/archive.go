@@ -0,0 +1,157 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kenplix/url-shrtnr/internal/config"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+)
+
+const dumpUsage = `Usage: url-shrtnr dump [flags]
+
+Writes all data of the configured database to gzip compressed tar archive,
+which contains newline delimited JSON file with checksum for every collection.
+Stop the application to take consistent backup.
+
+Flags:
+`
+
+const restoreUsage = `Usage: url-shrtnr restore [flags] <archive>
+
+Writes data of the archive to the configured database. Documents which are already
+stored are skipped, so restore can be repeated. Interrupted restore is resumed
+from its state file.
+
+Flags:
+`
+
+// Dump runs dump command with arguments following it
+func Dump(args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	database := flags.String("database", "", "database backend overriding configured one")
+	output := flags.String("o", fmt.Sprintf("url-shrtnr-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z")), "archive path")
+
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), dumpUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := readDatabaseConfig(*database)
+	if err != nil {
+		return err
+	}
+
+	repos, err := repository.New(ctx, cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to create repositories")
+	}
+	defer repos.Close(context.TODO())
+
+	// archive is written next to its destination, so partially written archive never replaces it
+	tmp, err := os.CreateTemp(filepath.Dir(*output), ".url-shrtnr-dump-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create archive")
+	}
+	defer os.Remove(tmp.Name())
+
+	manifest, err := repository.Dump(ctx, repos, cfg.Use, tmp)
+	if err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to dump database")
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to write archive")
+	}
+
+	if err = os.Rename(tmp.Name(), *output); err != nil {
+		return errors.Wrap(err, "failed to write archive")
+	}
+
+	fmt.Printf("dumped %s database to %s\n", cfg.Use, *output)
+
+	for _, coll := range manifest.Collections {
+		fmt.Printf("  %s: %d documents\n", coll.Name, coll.Documents)
+	}
+
+	return nil
+}
+
+// Restore runs restore command with arguments following it
+func Restore(args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	database := flags.String("database", "", "database backend overriding configured one")
+	state := flags.String("state", "", "file progress of restore is saved to, archive path with .state suffix by default")
+
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), restoreUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("archive path expected")
+	}
+
+	cfg, err := readDatabaseConfig(*database)
+	if err != nil {
+		return err
+	}
+
+	repos, err := repository.New(ctx, cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to create repositories")
+	}
+	defer repos.Close(context.TODO())
+
+	report, err := repository.Restore(ctx, repos, flags.Arg(0), repository.RestoreOptions{StatePath: *state})
+
+	for _, coll := range report {
+		fmt.Printf("  %s: %d restored, %d skipped\n", coll.Name, coll.Restored, coll.Skipped)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "failed to restore database, run restore again to resume it")
+	}
+
+	fmt.Printf("restored %s to %s database\n", flags.Arg(0), cfg.Use)
+
+	return nil
+}
+
+// readDatabaseConfig reads config of the database, backend is overridden if it isn't empty
+func readDatabaseConfig(use string) (repository.Config, error) {
+	cfg, err := config.Read("configs")
+	if err != nil {
+		return repository.Config{}, errors.Wrap(err, "failed to create config")
+	}
+
+	if use != "" {
+		cfg.Database.Use = use
+	}
+
+	return cfg.Database, nil
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kenplix/url-shrtnr/internal/config"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+)
+
+const migrateUsage = `Usage: url-shrtnr migrate [flags] [status|up|down]
+
+Runs migrations of the configured database. Command defaults to status.
+  status  lists migrations and time they were applied at
+  up      applies pending migrations
+  down    reverts the last applied migration
+
+Flags:
+`
+
+// Migrate runs migrate command with arguments following it
+func Migrate(args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	steps := flags.Int("steps", 0, "number of migrations to apply or revert, all pending migrations are applied if it's zero")
+	dryRun := flags.Bool("dry-run", false, "print migrations, which would be run, without running them")
+
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	command := "status"
+	if flags.NArg() > 0 {
+		command = flags.Arg(0)
+	}
+
+	cfg, err := config.Read("configs")
+	if err != nil {
+		return errors.Wrap(err, "failed to create config")
+	}
+
+	migrator, err := repository.NewMigrator(ctx, cfg.Database)
+	if err != nil {
+		return errors.Wrap(err, "failed to create migrator")
+	}
+	defer migrator.Close(context.TODO())
+
+	switch command {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to read migrations status")
+		}
+
+		return printMigrations(os.Stdout, statuses)
+	case "up", "down":
+		direction := repository.MigrationDirection(command)
+
+		statuses, err := migrator.Migrate(ctx, repository.MigrateOptions{
+			Direction: direction,
+			Steps:     *steps,
+			DryRun:    *dryRun,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to migrate %s", command)
+		}
+
+		if len(statuses) == 0 {
+			fmt.Println("no migrations to run")
+			return nil
+		}
+
+		switch {
+		case *dryRun && direction == repository.MigrateUp:
+			fmt.Println("migrations to apply:")
+		case *dryRun:
+			fmt.Println("migrations to revert:")
+		case direction == repository.MigrateUp:
+			fmt.Println("applied migrations:")
+		default:
+			fmt.Println("reverted migrations:")
+		}
+
+		return printMigrations(os.Stdout, statuses)
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+}
+
+func printMigrations(w io.Writer, statuses []repository.MigrationStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT\tREVERSIBLE")
+
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%t\n", s.Version, s.Name, appliedAt, s.Reversible)
+	}
+
+	return tw.Flush()
+}
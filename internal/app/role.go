@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/internal/service"
+)
+
+const roleUsage = `Usage: url-shrtnr role [flags] <username or email> <user|admin>
+
+Grants role to the user of the configured database, e.g. to bootstrap the first administrator.
+Further roles may be granted by administrators through API. Change is written to the audit log.
+
+Flags:
+`
+
+// Role runs role command with arguments following it
+func Role(args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	flags := flag.NewFlagSet("role", flag.ContinueOnError)
+	database := flags.String("database", "", "database backend overriding configured one")
+
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), roleUsage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return errors.New("user and role must be provided")
+	}
+
+	login, role := flags.Arg(0), flags.Arg(1)
+	if !lo.Contains(entity.Roles, role) {
+		return fmt.Errorf("unknown role %q, expected one of %v", role, entity.Roles)
+	}
+
+	cfg, err := readDatabaseConfig(*database)
+	if err != nil {
+		return err
+	}
+
+	repos, err := repository.New(ctx, cfg)
+	if err != nil {
+		return errors.Wrap(err, "failed to create repositories")
+	}
+	defer repos.Close(context.TODO())
+
+	user, err := repos.Users.FindByLogin(ctx, login)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find user %q", login)
+	}
+
+	auditServ, err := service.NewAuditService(repos.Audit)
+	if err != nil {
+		return errors.Wrap(err, "failed to create audit service")
+	}
+
+	// admin service isn't created, as its token and throttle dependencies need the whole application config,
+	// so the change is audited in the same way without acting administrator
+	err = repos.Users.SetRole(ctx, repository.SetRoleSchema{UserID: user.ID, Role: role})
+	if err != nil {
+		return errors.Wrapf(err, "failed to set role of user[id:%q]", user.ID.Hex())
+	}
+
+	err = auditServ.Record(ctx, entity.AuditEventModel{
+		Action:       entity.AuditActionUserRoleChanged,
+		TargetUserID: user.ID,
+		Details:      map[string]string{"role": role, "previousRole": user.UserRole()},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to record audit event")
+	}
+
+	fmt.Printf("user %s[id:%s] has role %s\n", user.Username, user.ID.Hex(), role)
+
+	return nil
+}
@@ -4,21 +4,22 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/kenplix/url-shrtnr/pkg/log"
 
 	"github.com/kenplix/url-shrtnr/internal/service"
 
-	"github.com/kenplix/url-shrtnr/pkg/cache/redis"
-
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 
 	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/pkg/blob"
 	"github.com/kenplix/url-shrtnr/pkg/hash"
 	"github.com/kenplix/url-shrtnr/pkg/httpserver"
+	"github.com/kenplix/url-shrtnr/pkg/mail"
 )
 
 const EnvPrefix = "URL_SHRTNR"
@@ -30,6 +31,8 @@ const (
 	TestingEnvironment     Environment = "testing"
 	DevelopmentEnvironment Environment = "development"
 	ProductionEnvironment  Environment = "production"
+	// EmbeddedEnvironment runs without external services, see configs/embedded.yml
+	EmbeddedEnvironment Environment = "embedded"
 )
 
 func (env Environment) Validate() error {
@@ -38,6 +41,7 @@ func (env Environment) Validate() error {
 	case ProductionEnvironment:
 	case OmitEnvironment:
 	case TestingEnvironment:
+	case EmbeddedEnvironment:
 	default:
 		return fmt.Errorf("unknown environment %q", env)
 	}
@@ -55,13 +59,44 @@ func (env Environment) ConfigName() string {
 
 // Config -.
 type Config struct {
-	Environment Environment              `mapstructure:"environment"`
-	HTTP        httpserver.Config        `mapstructure:"http"`
-	Database    repository.Config        `mapstructure:"database"`
-	Logger      log.Config               `mapstructure:"logger"`
-	Redis       redis.Config             `mapstructure:"redis"`
-	Hasher      hash.Config              `mapstructure:"hasher"`
-	JWT         service.JWTServiceConfig `mapstructure:"jwt"`
+	Environment Environment                    `mapstructure:"environment"`
+	HTTP        httpserver.Config              `mapstructure:"http"`
+	Database    repository.Config              `mapstructure:"database"`
+	Logger      log.Config                     `mapstructure:"logger"`
+	Cache       service.CacheConfig            `mapstructure:"cache"`
+	Hasher      hash.Config                    `mapstructure:"hasher"`
+	JWT         service.JWTServiceConfig       `mapstructure:"jwt"`
+	TwoFactor   service.TwoFactorServiceConfig `mapstructure:"twoFactor"`
+	Throttle    service.ThrottleServiceConfig  `mapstructure:"throttle"`
+	OIDC        service.OIDCServiceConfig      `mapstructure:"oidc"`
+	Mail        mail.Config                    `mapstructure:"mail"`
+	MagicLink   service.MagicLinkServiceConfig `mapstructure:"magicLink"`
+	Account     service.AccountServiceConfig   `mapstructure:"account"`
+	Profile     service.ProfileServiceConfig   `mapstructure:"profile"`
+	Blob        blob.Config                    `mapstructure:"blob"`
+	// Deprecations are warnings about deprecated keys found in the config
+	Deprecations []string `mapstructure:"-"`
+}
+
+// deprecatedKey is a key, which was renamed. It's still read, so configs and environments of previous
+// versions keep working, and overrides its replacement unless replacement is set with environment variable
+type deprecatedKey struct {
+	key         string
+	replacement string
+	apply       func(cfg *Config, value string)
+}
+
+var deprecatedKeys = []deprecatedKey{
+	{
+		key:         "redis.host",
+		replacement: "cache.redis.host",
+		apply:       func(cfg *Config, value string) { cfg.Cache.Redis.Host = value },
+	},
+	{
+		key:         "redis.port",
+		replacement: "cache.redis.port",
+		apply:       func(cfg *Config, value string) { cfg.Cache.Redis.Port = value },
+	},
 }
 
 // Read -.
@@ -83,9 +118,35 @@ func Read(dir string) (Config, error) {
 		return Config{}, errors.Wrap(err, "failed to unmarshall config")
 	}
 
+	applyDeprecatedKeys(&cfg)
+
 	return cfg, nil
 }
 
+func applyDeprecatedKeys(cfg *Config) {
+	for _, d := range deprecatedKeys {
+		if !viper.IsSet(d.key) {
+			continue
+		}
+
+		if _, found := os.LookupEnv(envName(d.replacement)); found {
+			continue
+		}
+
+		d.apply(cfg, viper.GetString(d.key))
+
+		cfg.Deprecations = append(cfg.Deprecations, fmt.Sprintf(
+			"%q key (%s environment variable) is deprecated, use %q key (%s environment variable) instead",
+			d.key, envName(d.key), d.replacement, envName(d.replacement),
+		))
+	}
+}
+
+// envName returns name of environment variable, which overrides the key
+func envName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
 func load(cfg *Config) error {
 	keys := map[string]any{}
 	if err := mapstructure.Decode(cfg, &keys); err != nil {
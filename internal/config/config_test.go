@@ -14,10 +14,14 @@ import (
 
 	"github.com/kenplix/url-shrtnr/internal/config"
 	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/pkg/blob"
 	"github.com/kenplix/url-shrtnr/pkg/hash"
 	"github.com/kenplix/url-shrtnr/pkg/hash/argon2"
 	"github.com/kenplix/url-shrtnr/pkg/hash/bcrypt"
 	"github.com/kenplix/url-shrtnr/pkg/httpserver"
+	"github.com/kenplix/url-shrtnr/pkg/mail"
+	"github.com/kenplix/url-shrtnr/pkg/oidc"
+	"github.com/kenplix/url-shrtnr/pkg/otp/totp"
 	"github.com/kenplix/url-shrtnr/pkg/token"
 
 	"github.com/stretchr/testify/assert"
@@ -44,12 +48,15 @@ func TestRead(t *testing.T) {
 		{
 			name: "testing environment",
 			environ: map[string]string{
-				"ENVIRONMENT":                 "testing",
-				"HTTP_PORT":                   "1308",
-				"JWT_ACCESSTOKEN_PRIVATEKEY":  "<access token private key>",
-				"JWT_ACCESSTOKEN_PUBLICKEY":   "<access token public key>",
-				"JWT_REFRESHTOKEN_PRIVATEKEY": "<refresh token private key>",
-				"JWT_REFRESHTOKEN_PUBLICKEY":  "<refresh token public key>",
+				"ENVIRONMENT":                          "testing",
+				"HTTP_PORT":                            "1308",
+				"HASHER_PEPPER":                        "<pepper>",
+				"JWT_ACCESSTOKEN_PRIVATEKEY":           "<access token private key>",
+				"JWT_ACCESSTOKEN_PUBLICKEY":            "<access token public key>",
+				"JWT_REFRESHTOKEN_PRIVATEKEY":          "<refresh token private key>",
+				"JWT_REFRESHTOKEN_PUBLICKEY":           "<refresh token public key>",
+				"OIDC_PROVIDERS_KEYCLOAK_CLIENTSECRET": "<keycloak client secret>",
+				"MAIL_SMTP_PASSWORD":                   "<smtp password>",
 			},
 			args: args{
 				fixture: "testdata",
@@ -63,9 +70,24 @@ func TestRead(t *testing.T) {
 						WriteTimeout:    3 * time.Second,
 						IdleTimeout:     0 * time.Second,
 						ShutdownTimeout: 8 * time.Second,
+						TrustedProxies:  []string{"10.0.0.0/8"},
+						CORS: httpserver.CORSConfig{
+							AllowOrigins:     []string{"https://testing.url-shrtnr.com"},
+							AllowCredentials: true,
+							MaxAge:           time.Hour,
+						},
+						AuthCookies: httpserver.AuthCookiesConfig{
+							Enabled:  true,
+							Domain:   "testing.url-shrtnr.com",
+							SameSite: "strict",
+							MaxAge:   24 * time.Hour,
+						},
 					},
 					Database: repository.Config{
 						Use: "mongodb",
+						Audit: repository.AuditConfig{
+							Retention: 2160 * time.Hour,
+						},
 					},
 					Logger: log.Config{
 						Level:    "debug",
@@ -84,20 +106,99 @@ func TestRead(t *testing.T) {
 							SaltLength:  16,
 							KeyLength:   16,
 						},
+						Pepper: "<pepper>",
 					},
 					JWT: service.JWTServiceConfig{
 						AccessToken: token.Config{
+							Algorithm:  token.ES256,
+							Issuer:     "url-shrtnr-testing",
+							Audience:   "url-shrtnr-testing-api",
 							PrivateKey: "<access token private key>",
 							PublicKey:  "<access token public key>",
 							TTL:        20 * time.Minute,
 						},
 						RefreshToken: token.Config{
+							Algorithm:  token.EdDSA,
+							Issuer:     "url-shrtnr-testing",
+							Audience:   "url-shrtnr-testing-refresh",
 							PrivateKey: "<refresh token private key>",
 							PublicKey:  "<refresh token public key>",
 							TTL:        60 * time.Minute,
 						},
 						InactiveTimeout: 10 * time.Minute,
 					},
+					TwoFactor: service.TwoFactorServiceConfig{
+						Issuer: "url-shrtnr-testing",
+						TOTP: totp.Config{
+							Digits: 8,
+							Period: 60 * time.Second,
+							Skew:   2,
+						},
+						RecoveryCodes: 5,
+						EnrollmentTTL: 3 * time.Minute,
+						ChallengeTTL:  2 * time.Minute,
+						MaxAttempts:   3,
+					},
+					Throttle: service.ThrottleServiceConfig{
+						Window:          10 * time.Minute,
+						BaseDelay:       2 * time.Second,
+						MaxDelay:        time.Minute,
+						LockoutDuration: 20 * time.Minute,
+						Login: service.ThrottleLimitsConfig{
+							FreeAttempts:     2,
+							LockoutThreshold: 5,
+						},
+						IP: service.ThrottleLimitsConfig{
+							FreeAttempts:     20,
+							LockoutThreshold: 100,
+						},
+					},
+					OIDC: service.OIDCServiceConfig{
+						StateTTL: 5 * time.Minute,
+						Providers: map[string]oidc.Config{
+							"keycloak": {
+								Issuer:       "http://localhost:8080/realms/url-shrtnr-testing",
+								ClientID:     "url-shrtnr-testing",
+								ClientSecret: "<keycloak client secret>",
+								RedirectURL:  "http://localhost/api/v1/auth/oidc/keycloak/callback",
+								Scopes:       []string{"openid", "email"},
+							},
+						},
+					},
+					Mail: mail.Config{
+						Use: "smtp",
+						SMTP: mail.SMTPConfig{
+							Host:     "localhost",
+							Port:     "1025",
+							Username: "testing@url-shrtnr.com",
+							Password: "<smtp password>",
+							From:     "testing@url-shrtnr.com",
+						},
+					},
+					MagicLink: service.MagicLinkServiceConfig{
+						TTL: 10 * time.Minute,
+						URL: "http://localhost/sign-in/magic-link",
+					},
+					Account: service.AccountServiceConfig{
+						DeletionGracePeriod:     168 * time.Hour,
+						ExportTTL:               time.Hour,
+						DeletionConfirmationTTL: 10 * time.Minute,
+						DeletionConfirmationURL: "http://localhost/account/deletion/confirm",
+					},
+					Profile: service.ProfileServiceConfig{
+						UsernameChangeCooldown: 24 * time.Hour,
+						UsernameRedirectPeriod: 48 * time.Hour,
+						AvatarSize:             128,
+					},
+					Blob: blob.Config{
+						Use: "local",
+						Local: blob.LocalConfig{
+							Path: "testdata/blobs",
+						},
+					},
+					Cache: service.CacheConfig{
+						Use: "memory",
+					},
 				},
 				hasErr: false,
 			},
@@ -153,3 +254,24 @@ func setEnv(t *testing.T, environ map[string]string) {
 		t.Setenv(key, value)
 	}
 }
+
+func TestRead_DeprecatedKeys(t *testing.T) {
+	t.Cleanup(shadowEnv(t))
+
+	setEnv(t, map[string]string{
+		"ENVIRONMENT":      "testing",
+		"REDIS_HOST":       "redis.internal",
+		"REDIS_PORT":       "6380",
+		"CACHE_REDIS_PORT": "6381",
+	})
+
+	cfg, err := config.Read("testdata")
+	require.NoError(t, err)
+
+	assert.Equal(t, "redis.internal", cfg.Cache.Redis.Host, "deprecated key must be read")
+	assert.Equal(t, "6381", cfg.Cache.Redis.Port, "environment variable of new key must take precedence")
+	assert.Equal(t, []string{
+		`"redis.host" key (URL_SHRTNR_REDIS_HOST environment variable) is deprecated, ` +
+			`use "cache.redis.host" key (URL_SHRTNR_CACHE_REDIS_HOST environment variable) instead`,
+	}, cfg.Deprecations)
+}
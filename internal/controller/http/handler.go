@@ -18,10 +18,12 @@ import (
 
 	v1 "github.com/kenplix/url-shrtnr/internal/controller/http/v1"
 	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/httpserver"
 )
 
 type Handler struct {
 	v1       *v1.Handler
+	services *service.Services
 	unitrans *ut.UniversalTranslator
 	logger   *zap.Logger
 }
@@ -43,6 +45,7 @@ func NewHandler(logger *zap.Logger, services *service.Services) (*Handler, error
 
 	h := &Handler{
 		v1:       handlerV1,
+		services: services,
 		unitrans: unitrans,
 		logger:   logger,
 	}
@@ -53,11 +56,20 @@ func NewHandler(logger *zap.Logger, services *service.Services) (*Handler, error
 type Config struct {
 	Host string
 	Port string
+	// TrustedProxies is a list of proxies whose forwarded headers are used to determine client IP.
+	// If empty, client IP is taken from the remote address of the connection
+	TrustedProxies []string
+	CORS           httpserver.CORSConfig
+	AuthCookies    httpserver.AuthCookiesConfig
 }
 
-func (h *Handler) InitEngine(env config.Environment, cfg Config) *gin.Engine {
+func (h *Handler) InitEngine(env config.Environment, cfg Config) (*gin.Engine, error) {
 	router := gin.New()
 
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, errors.Wrapf(err, "failed to set %q trusted proxies", cfg.TrustedProxies)
+	}
+
 	host := cfg.Host
 	if host == "" {
 		host = "localhost"
@@ -72,22 +84,31 @@ func (h *Handler) InitEngine(env config.Environment, cfg Config) *gin.Engine {
 		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
 
+	cors, err := corsMiddleware(cfg.CORS)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cors middleware")
+	}
+
 	router.Use(
 		requestIDMiddleware(h.logger),
 		requestReaderMiddleware,
 		responseWriterMiddleware,
 		loggerMiddleware(h.logger),
-		corsMiddleware(),
+		cors,
 		translatorMiddleware(h.unitrans),
 	)
 
-	h.initAPI(router)
+	h.initWellKnown(router)
+
+	if err = h.initAPI(router, cfg); err != nil {
+		return nil, errors.Wrap(err, "failed to init API routes")
+	}
 
-	return router
+	return router, nil
 }
 
-func (h *Handler) initAPI(router *gin.Engine) {
+func (h *Handler) initAPI(router *gin.Engine, cfg Config) error {
 	api := router.Group("/api")
 
-	h.v1.InitRoutes(api)
+	return h.v1.InitRoutes(api, v1.Config{AuthCookies: cfg.AuthCookies})
 }
@@ -15,10 +15,14 @@ import (
 	"github.com/gin-contrib/requestid"
 	ginzap "github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/kenplix/url-shrtnr/pkg/httpserver"
 	"github.com/kenplix/url-shrtnr/pkg/log"
+	"github.com/kenplix/url-shrtnr/pkg/requestinfo"
 )
 
 func requestIDMiddleware(logger *zap.Logger) gin.HandlerFunc {
@@ -27,6 +31,11 @@ func requestIDMiddleware(logger *zap.Logger) gin.HandlerFunc {
 			withRequestID := logger.With(zap.String("request-id", requestID))
 
 			ctx := log.ContextWithLogger(c.Request.Context(), withRequestID)
+			ctx = requestinfo.ContextWithInfo(ctx, requestinfo.Info{
+				IP:        c.ClientIP(),
+				UserAgent: c.Request.UserAgent(),
+				RequestID: requestID,
+			})
 			c.Request = c.Request.WithContext(ctx)
 		}),
 	)
@@ -64,6 +73,14 @@ func responseWriterMiddleware(c *gin.Context) {
 	}
 }
 
+// sensitiveRoutes are logged without request and response bodies. Unlike skipped paths
+// they contain parameters, so they are matched by the route instead of the request path
+var sensitiveRoutes = map[string]bool{
+	"/api/v1/auth/oidc/:provider/callback": true,
+	"/api/v1/users/export/:id/download":    true,
+	"/api/v1/avatars/:name":                true,
+}
+
 func loggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
 	return ginzap.GinzapWithConfig(logger, &ginzap.Config{
 		UTC:        true,
@@ -71,7 +88,16 @@ func loggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
 		SkipPaths: []string{
 			"/api/v1/auth/sign-up",
 			"/api/v1/auth/sign-in",
+			"/api/v1/auth/sign-in/mfa",
 			"/api/v1/auth/refresh-tokens",
+			"/api/v1/auth/magic-link",
+			"/api/v1/auth/magic-link/sign-in",
+			"/api/v1/users/2fa/enroll",
+			"/api/v1/users/2fa/confirm",
+			"/api/v1/users/2fa/disable",
+			"/api/v1/users/api-keys",
+			"/api/v1/users/deletion",
+			"/api/v1/users/avatar",
 		},
 		Context: func(c *gin.Context) []zapcore.Field {
 			var fields []zapcore.Field
@@ -79,6 +105,10 @@ func loggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
 				fields = append(fields, zap.String("request-id", requestID))
 			}
 
+			if sensitiveRoutes[c.FullPath()] {
+				return fields
+			}
+
 			r := c.Request.Body.(*requestReader)
 			fields = append(fields, zap.String("request-body", r.buf.String()))
 
@@ -125,21 +155,48 @@ func parseAcceptLanguageHeader(c *gin.Context) []string {
 	return languages
 }
 
-func corsMiddleware() gin.HandlerFunc {
-	return cors.New(cors.Config{
-		AllowOrigins: []string{"*"},
+const defaultCORSMaxAge = 12 * time.Hour
+
+func corsMiddleware(cfg httpserver.CORSConfig) (gin.HandlerFunc, error) {
+	allowOrigins := lo.Ternary(len(cfg.AllowOrigins) != 0, cfg.AllowOrigins, []string{"*"})
+
+	// browsers reject credentialed responses allowed for any origin
+	if cfg.AllowCredentials && lo.Contains(allowOrigins, "*") {
+		return nil, errors.New(`credentials can't be allowed for "*" origin`)
+	}
+
+	corsCfg := cors.Config{
 		AllowMethods: []string{
 			http.MethodGet,
 			http.MethodPost,
 			http.MethodPut,
+			http.MethodPatch,
 			http.MethodDelete,
 		},
 		AllowHeaders: []string{
 			"Content-Length",
 			"Content-Type",
 			"Authorization",
+			"X-API-Key",
+			"X-Auth-Mode",
+			"X-CSRF-Token",
+			"If-Match",
 		},
-		AllowCredentials: false,
-		MaxAge:           12 * time.Hour,
-	})
+		// clients send version of the user read from ETag back in If-Match header
+		ExposeHeaders:    []string{"ETag"},
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           lo.Ternary(cfg.MaxAge > 0, cfg.MaxAge, defaultCORSMaxAge),
+	}
+
+	if lo.Contains(allowOrigins, "*") {
+		corsCfg.AllowAllOrigins = true
+	} else {
+		corsCfg.AllowOrigins = allowOrigins
+	}
+
+	if err := corsCfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid cors config")
+	}
+
+	return cors.New(corsCfg), nil
 }
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
@@ -9,11 +10,13 @@ import (
 
 	"github.com/kenplix/url-shrtnr/internal/controller/http/ginctx"
 
+	"github.com/gin-gonic/gin"
 	"github.com/go-playground/assert/v2"
 	ut "github.com/go-playground/universal-translator"
 
 	"github.com/kenplix/url-shrtnr/internal/service"
 	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+	"github.com/kenplix/url-shrtnr/pkg/httpserver"
 )
 
 func TestTranslatorMiddleware(t *testing.T) {
@@ -97,3 +100,93 @@ func TestTranslatorMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestCORSMiddleware(t *testing.T) {
+	type args struct {
+		cfg    httpserver.CORSConfig
+		origin string
+	}
+
+	type ret struct {
+		allowOrigin      string
+		allowCredentials string
+		hasErr           bool
+	}
+
+	testCases := []struct {
+		name string
+		args args
+		ret  ret
+	}{
+		{
+			name: "all origins by default",
+			args: args{
+				origin: "https://frontend.com",
+			},
+			ret: ret{
+				allowOrigin: "*",
+			},
+		},
+		{
+			name: "credentials for all origins",
+			args: args{
+				cfg: httpserver.CORSConfig{
+					AllowOrigins:     []string{"*"},
+					AllowCredentials: true,
+				},
+			},
+			ret: ret{
+				hasErr: true,
+			},
+		},
+		{
+			name: "credentials for configured origin",
+			args: args{
+				cfg: httpserver.CORSConfig{
+					AllowOrigins:     []string{"https://url-shrtnr.com"},
+					AllowCredentials: true,
+				},
+				origin: "https://url-shrtnr.com",
+			},
+			ret: ret{
+				allowOrigin:      "https://url-shrtnr.com",
+				allowCredentials: "true",
+			},
+		},
+		{
+			name: "not configured origin",
+			args: args{
+				cfg: httpserver.CORSConfig{
+					AllowOrigins:     []string{"https://url-shrtnr.com"},
+					AllowCredentials: true,
+				},
+				origin: "https://frontend.com",
+			},
+			ret: ret{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			middleware, err := corsMiddleware(tc.args.cfg)
+			if tc.ret.hasErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoErrorf(t, err, "failed to create cors middleware: %s", err)
+
+			r := gin.New()
+			r.GET("/", middleware)
+
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Origin", tc.args.origin)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.ret.allowOrigin, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tc.ret.allowCredentials, rec.Header().Get("Access-Control-Allow-Credentials"))
+		})
+	}
+}
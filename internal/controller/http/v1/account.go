@@ -0,0 +1,311 @@
+package v1
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+func (h *Handler) initAccountRoutes(users *gin.RouterGroup) {
+	export := users.Group("/export")
+
+	export.POST("", h.requestDataExport)
+	export.GET("/:id", h.getDataExport)
+	export.GET("/:id/download", h.downloadDataExport)
+
+	deletion := users.Group("/deletion")
+
+	deletion.POST("", h.scheduleDeletion)
+	deletion.DELETE("", h.cancelDeletion)
+}
+
+// requestDataExport handler starts preparation of personal data archive
+//
+//	@Summary		Request personal data export
+//	@Security		JWT-RS256
+//	@Tags			account
+//	@Description	Starts preparation of ZIP archive with profile, API keys and security events in background
+//	@Accept			json
+//	@Produce		json
+//	@Success		202	{object}	entity.DataExport						"Data export was successfully requested"
+//	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or access token lacks required scopes"
+//	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/users/export [post]
+func (h *Handler) requestDataExport(c *gin.Context) {
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	export, err := h.services.Account.RequestExport(reqctx, user.ID)
+	if err != nil {
+		logger.Error("failed to request data export",
+			zap.String("userID", user.ID.Hex()),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+
+	c.JSON(http.StatusAccepted, export)
+}
+
+// getDataExport handler returns state of personal data export
+//
+//	@Summary		Get personal data export
+//	@Security		JWT-RS256
+//	@Tags			account
+//	@Description	Returns state of personal data export, archive may be downloaded once export is ready
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string									true	"Data export ID"
+//	@Success		200	{object}	entity.DataExport						"Data export state"
+//	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or access token lacks required scopes"
+//	@Failure		404	{object}	errResponse{errors=[]entity.CoreError}	"Data export not found or expired"
+//	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/users/export/{id} [get]
+func (h *Handler) getDataExport(c *gin.Context) {
+	schema, ok := dataExportSchema(c)
+	if !ok {
+		return
+	}
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	export, err := h.services.Account.GetExport(reqctx, schema)
+	if err != nil {
+		if errors.Is(err, entity.ErrDataExportNotFound) {
+			dataExportNotFoundErrorResponse(c)
+			return
+		}
+
+		logger.Error("failed to get data export",
+			zap.String("userID", schema.UserID.Hex()),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// downloadDataExport handler returns personal data archive
+//
+//	@Summary		Download personal data export
+//	@Security		JWT-RS256
+//	@Tags			account
+//	@Description	Returns ZIP archive of the ready personal data export
+//	@Accept			json
+//	@Produce		application/zip
+//	@Param			id	path		string									true	"Data export ID"
+//	@Success		200	{file}		file									"Personal data archive"
+//	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or access token lacks required scopes"
+//	@Failure		404	{object}	errResponse{errors=[]entity.CoreError}	"Data export not found or expired"
+//	@Failure		409	{object}	errResponse{errors=[]entity.CoreError}	"Data export isn't ready yet"
+//	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/users/export/{id}/download [get]
+func (h *Handler) downloadDataExport(c *gin.Context) {
+	schema, ok := dataExportSchema(c)
+	if !ok {
+		return
+	}
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	archive, err := h.services.Account.DownloadExport(reqctx, schema)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrDataExportNotFound):
+			dataExportNotFoundErrorResponse(c)
+		case errors.Is(err, entity.ErrDataExportNotReady):
+			errorResponse(c, http.StatusConflict, &entity.CoreError{
+				Code:    errorcode.DataExportNotReady,
+				Message: entity.ErrDataExportNotReady.Error(),
+			})
+		default:
+			logger.Error("failed to download data export",
+				zap.String("userID", schema.UserID.Hex()),
+				zap.Error(err),
+			)
+			internalErrorResponse(c)
+		}
+
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "url-shrtnr-export-"+schema.ExportID+".zip"))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+type scheduleDeletionSchema struct {
+	// Password is required unless user has no password, e.g. signed up with identity provider
+	Password string `json:"password" binding:"max=64" example:"1wE$Rty2"`
+	// Token is one-time deletion confirmation sent by email to user who has no password
+	Token string `json:"token" binding:"max=64" example:"6f1c2a9e0b7d4e3f8a5c1b2d9e0f7a6c6f1c2a9e0b7d4e3f8a5c1b2d9e0f7a6c"`
+	// Code is TOTP or recovery code, required when two-factor authentication is enabled
+	Code string `json:"code" binding:"max=32" example:"123456"`
+}
+
+// scheduleDeletion handler schedules account deletion
+//
+//	@Summary		Schedule account deletion
+//	@Security		JWT-RS256
+//	@Tags			account
+//	@Description	Requires current password and TOTP or recovery code when two-factor authentication is enabled.
+//	@Description	User who has no password gets one-time confirmation by email and repeats request with token from it instead.
+//	@Description	Signs user out from all devices, account is permanently deleted after grace period unless deletion is cancelled
+//	@Accept			json
+//	@Produce		json
+//	@Param			schema	body		scheduleDeletionSchema	true	"JSON schema for account deletion"
+//	@Success		200		{object}	entity.User				"Account deletion was successfully scheduled"
+//	@Success		202		"Deletion confirmation was sent by email"
+//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or access token lacks required scopes"
+//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields or incorrect credentials"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/users/deletion [post]
+func (h *Handler) scheduleDeletion(c *gin.Context) {
+	var schema scheduleDeletionSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	updatedUser, err := h.services.Account.ScheduleDeletion(reqctx, service.ScheduleDeletionSchema{
+		UserID:   user.ID,
+		Password: schema.Password,
+		Token:    schema.Token,
+		Code:     schema.Code,
+	})
+	if err != nil {
+		var apiErr *entity.CoreError
+
+		switch {
+		case errors.Is(err, entity.ErrDeletionConfirmationSent):
+			c.Status(http.StatusAccepted)
+
+			return
+		case errors.Is(err, entity.ErrDeletionConfirmationNotFound):
+			apiErr = &entity.CoreError{
+				Code:    errorcode.ConfirmationExpired,
+				Message: entity.ErrDeletionConfirmationNotFound.Error(),
+			}
+		case errors.Is(err, entity.ErrIncorrectCredentials):
+			apiErr = &entity.CoreError{
+				Code:    errorcode.IncorrectCredentials,
+				Message: entity.ErrIncorrectCredentials.Error(),
+			}
+		case errors.Is(err, entity.ErrIncorrectTwoFactorCode):
+			apiErr = newIncorrectOTPError()
+		default:
+			logger.Error("failed to schedule account deletion",
+				zap.String("userID", user.ID.Hex()),
+				zap.Error(err),
+			)
+			internalErrorResponse(c)
+
+			return
+		}
+
+		logger.Warn("failed to schedule account deletion",
+			zap.String("userID", user.ID.Hex()),
+			zap.Error(err),
+		)
+		errorResponse(c, http.StatusUnprocessableEntity, apiErr)
+
+		return
+	}
+
+	// all tokens are revoked, so cookies are useless anymore
+	if h.cookies.enabled {
+		h.cookies.clear(c)
+	}
+
+	c.JSON(http.StatusOK, updatedUser)
+}
+
+// cancelDeletion handler cancels scheduled account deletion
+//
+//	@Summary		Cancel account deletion
+//	@Security		JWT-RS256
+//	@Tags			account
+//	@Description	Cancels account deletion scheduled less than grace period ago
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	entity.User								"Account deletion was successfully cancelled"
+//	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or access token lacks required scopes"
+//	@Failure		422	{object}	errResponse{errors=[]entity.CoreError}	"Account deletion isn't scheduled"
+//	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/users/deletion [delete]
+func (h *Handler) cancelDeletion(c *gin.Context) {
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	updatedUser, err := h.services.Account.CancelDeletion(reqctx, user.ID)
+	if err != nil {
+		if errors.Is(err, entity.ErrDeletionNotScheduled) {
+			errorResponse(c, http.StatusUnprocessableEntity, &entity.CoreError{
+				Code:    errorcode.DeletionNotScheduled,
+				Message: entity.ErrDeletionNotScheduled.Error(),
+			})
+
+			return
+		}
+
+		logger.Error("failed to cancel account deletion",
+			zap.String("userID", user.ID.Hex()),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedUser)
+}
+
+// dataExportSchema returns export of the current user from path, unknown export is responded otherwise
+func dataExportSchema(c *gin.Context) (service.DataExportSchema, bool) {
+	user := c.MustGet(userContext).(entity.User)
+
+	exportID := c.Param("id")
+	if _, err := hex.DecodeString(exportID); err != nil || exportID == "" {
+		dataExportNotFoundErrorResponse(c)
+		return service.DataExportSchema{}, false
+	}
+
+	return service.DataExportSchema{UserID: user.ID, ExportID: exportID}, true
+}
+
+func dataExportNotFoundErrorResponse(c *gin.Context) {
+	errorResponse(c, http.StatusNotFound, &entity.CoreError{
+		Code:    errorcode.DataExportNotFound,
+		Message: entity.ErrDataExportNotFound.Error(),
+	})
+}
@@ -0,0 +1,281 @@
+package v1
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+)
+
+func TestHandler_ScheduleDeletion(t *testing.T) {
+	type args struct {
+		inputBody string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	type mockBehavior func(*servMocks.AccountService)
+
+	deletionScheduledAt := time.Date(2023, time.January, 24, 21, 49, 33, 0, time.UTC)
+	testUser := entity.User{Username: "kenplix", DeletionScheduledAt: &deletionScheduledAt}
+
+	testSchema := mustMarshal(t, scheduleDeletionSchema{Password: "1wE$Rty2", Code: "123456"})
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "invalid json",
+			args: args{
+				inputBody: `[]`,
+			},
+			ret: ret{
+				statusCode:   http.StatusBadRequest,
+				responseBody: testUnmarshalTypeError(t),
+			},
+			mockBehavior: func(_ *servMocks.AccountService) {},
+		},
+		{
+			name: "incorrect password",
+			args: args{
+				inputBody: testSchema,
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.IncorrectCredentials,
+							Message: entity.ErrIncorrectCredentials.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(accountServ *servMocks.AccountService) {
+				accountServ.
+					On("ScheduleDeletion", mock.Anything, mock.Anything).
+					Return(entity.User{}, entity.ErrIncorrectCredentials)
+			},
+		},
+		{
+			name: "deletion confirmation sent",
+			args: args{
+				inputBody: `{}`,
+			},
+			ret: ret{
+				statusCode: http.StatusAccepted,
+			},
+			mockBehavior: func(accountServ *servMocks.AccountService) {
+				accountServ.
+					On("ScheduleDeletion", mock.Anything, mock.Anything).
+					Return(entity.User{}, entity.ErrDeletionConfirmationSent)
+			},
+		},
+		{
+			name: "deletion confirmation expired",
+			args: args{
+				inputBody: mustMarshal(t, scheduleDeletionSchema{Token: "<token>"}),
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.ConfirmationExpired,
+							Message: entity.ErrDeletionConfirmationNotFound.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(accountServ *servMocks.AccountService) {
+				accountServ.
+					On("ScheduleDeletion", mock.Anything, mock.MatchedBy(func(schema service.ScheduleDeletionSchema) bool {
+						return schema.Token == "<token>"
+					})).
+					Return(entity.User{}, entity.ErrDeletionConfirmationNotFound)
+			},
+		},
+		{
+			name: "incorrect two-factor code",
+			args: args{
+				inputBody: testSchema,
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{newIncorrectOTPError()},
+				}),
+			},
+			mockBehavior: func(accountServ *servMocks.AccountService) {
+				accountServ.
+					On("ScheduleDeletion", mock.Anything, mock.Anything).
+					Return(entity.User{}, entity.ErrIncorrectTwoFactorCode)
+			},
+		},
+		{
+			name: "service failure",
+			args: args{
+				inputBody: testSchema,
+			},
+			ret: ret{
+				statusCode:   http.StatusInternalServerError,
+				responseBody: testInternalErrorResponse(t),
+			},
+			mockBehavior: func(accountServ *servMocks.AccountService) {
+				accountServ.
+					On("ScheduleDeletion", mock.Anything, mock.Anything).
+					Return(entity.User{}, assert.AnError)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				inputBody: testSchema,
+			},
+			ret: ret{
+				statusCode:   http.StatusOK,
+				responseBody: mustMarshal(t, testUser),
+			},
+			mockBehavior: func(accountServ *servMocks.AccountService) {
+				accountServ.
+					On("ScheduleDeletion", mock.Anything, mock.MatchedBy(func(schema service.ScheduleDeletionSchema) bool {
+						return schema.Password == "1wE$Rty2" && schema.Code == "123456"
+					})).
+					Return(testUser, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	initValidator(t)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			accountServ := servMocks.NewAccountService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				Account: accountServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(accountServ)
+
+			r := gin.New()
+			r.POST("/deletion", testLoggerMiddleware(t), testUserMiddleware(t), h.scheduleDeletion)
+
+			req := httptest.NewRequest(http.MethodPost, "/deletion", bytes.NewBufferString(tc.args.inputBody))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+		})
+	}
+}
+
+func TestHandler_DownloadDataExport(t *testing.T) {
+	const testExportID = "9f86d081884c7d659a2feaa0c55ad015"
+
+	testArchive := []byte("PK\x05\x06")
+
+	testCases := []struct {
+		name         string
+		exportID     string
+		statusCode   int
+		responseBody string
+		mockBehavior func(*servMocks.AccountService)
+	}{
+		{
+			name:       "invalid export id",
+			exportID:   "export:invalid",
+			statusCode: http.StatusNotFound,
+			responseBody: mustMarshal(t, errResponse{
+				Errors: []apiError{
+					&entity.CoreError{
+						Code:    errorcode.DataExportNotFound,
+						Message: entity.ErrDataExportNotFound.Error(),
+					},
+				},
+			}),
+			mockBehavior: func(_ *servMocks.AccountService) {},
+		},
+		{
+			name:       "not ready",
+			exportID:   testExportID,
+			statusCode: http.StatusConflict,
+			responseBody: mustMarshal(t, errResponse{
+				Errors: []apiError{
+					&entity.CoreError{
+						Code:    errorcode.DataExportNotReady,
+						Message: entity.ErrDataExportNotReady.Error(),
+					},
+				},
+			}),
+			mockBehavior: func(accountServ *servMocks.AccountService) {
+				accountServ.
+					On("DownloadExport", mock.Anything, mock.Anything).
+					Return(nil, entity.ErrDataExportNotReady)
+			},
+		},
+		{
+			name:         "ok",
+			exportID:     testExportID,
+			statusCode:   http.StatusOK,
+			responseBody: string(testArchive),
+			mockBehavior: func(accountServ *servMocks.AccountService) {
+				accountServ.
+					On("DownloadExport", mock.Anything, mock.MatchedBy(func(schema service.DataExportSchema) bool {
+						return schema.ExportID == testExportID
+					})).
+					Return(testArchive, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			accountServ := servMocks.NewAccountService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				Account: accountServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(accountServ)
+
+			r := gin.New()
+			r.GET("/export/:id/download", testLoggerMiddleware(t), testUserMiddleware(t), h.downloadDataExport)
+
+			req := httptest.NewRequest(http.MethodGet, "/export/"+tc.exportID+"/download", http.NoBody)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Code)
+			assert.Equal(t, tc.responseBody, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,419 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+func (h *Handler) initAdminRoutes(router *gin.RouterGroup) {
+	// admin scope is never granted to API keys, so administrative
+	// operations are available only within signed in session
+	admin := router.Group(
+		"/admin",
+		h.userIdentityMiddleware,
+		h.userActivityMiddleware,
+		h.requireScopes(entity.ScopeAdmin),
+		h.requireRole(entity.RoleAdmin),
+	)
+
+	users := admin.Group("/users")
+	users.GET("", h.adminListUsers)
+	users.GET("/:id", h.adminGetUser)
+	users.POST("/:id/suspend", h.adminSuspendUser)
+	users.POST("/:id/unsuspend", h.adminUnsuspendUser)
+	users.POST("/:id/sign-out", h.adminSignOutUser)
+	users.DELETE("/:id/2fa", h.adminResetTwoFactor)
+	users.PUT("/:id/role", h.adminSetRole)
+
+	admin.POST("/sign-in/unlock", h.adminUnlockSignIn)
+	admin.GET("/audit-events", h.adminListAuditEvents)
+}
+
+type adminListUsersQuery struct {
+	// Search matches username or email
+	Search  string `form:"search" binding:"max=64" example:"kenplix"`
+	Page    int    `form:"page" binding:"omitempty,min=1" example:"1"`
+	PerPage int    `form:"perPage" binding:"omitempty,min=1,max=100" example:"20"`
+}
+
+// adminListUsers handler returns users page
+//
+//	@Summary		List users
+//	@Security		JWT-RS256
+//	@Tags			admin
+//	@Description	Returns users in order of registration, optionally filtered by username or email
+//	@Accept			json
+//	@Produce		json
+//	@Param			search	query		string											false	"Part of username or email"
+//	@Param			page	query		int												false	"Page number"				minimum(1)	default(1)
+//	@Param			perPage	query		int												false	"Number of users per page"	minimum(1)	maximum(100)	default(20)
+//	@Success		200		{object}	entity.UsersPage								"Users page"
+//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or you aren't an administrator"
+//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/admin/users [get]
+func (h *Handler) adminListUsers(c *gin.Context) {
+	var query adminListUsersQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	reqctx := c.Request.Context()
+
+	usersPage, err := h.services.Admin.ListUsers(reqctx, service.ListUsersSchema{
+		Search:  query.Search,
+		Page:    query.Page,
+		PerPage: query.PerPage,
+	})
+	if err != nil {
+		logger := log.LoggerFromContext(reqctx)
+		logger.Error("failed to list users", zap.Error(err))
+		internalErrorResponse(c)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, usersPage)
+}
+
+// adminGetUser handler returns user information
+//
+//	@Summary		Get user
+//	@Security		JWT-RS256
+//	@Tags			admin
+//	@Description	Returns user information including role and suspension details
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string									true	"User ID"
+//	@Success		200	{object}	entity.User								"User information"
+//	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or you aren't an administrator"
+//	@Failure		404	{object}	errResponse{errors=[]entity.CoreError}	"User not found"
+//	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/admin/users/{id} [get]
+func (h *Handler) adminGetUser(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.services.Admin.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		adminErrorResponse(c, "failed to get user", userID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+type adminSuspendUserSchema struct {
+	Reason string `json:"reason" binding:"required,max=256" example:"spam links"`
+}
+
+// adminSuspendUser handler suspends user
+//
+//	@Summary		Suspend user
+//	@Security		JWT-RS256
+//	@Tags			admin
+//	@Description	Suspends user with provided reason and signs user out from all devices
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	string					true	"User ID"
+//	@Param			schema	body	adminSuspendUserSchema	true	"JSON schema for user suspension"
+//	@Success		204		"User was successfully suspended"
+//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or you aren't an administrator"
+//	@Failure		404		{object}	errResponse{errors=[]entity.CoreError}			"User not found"
+//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields or you tried to suspend yourself"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/admin/users/{id}/suspend [post]
+func (h *Handler) adminSuspendUser(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var schema adminSuspendUserSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	admin := c.MustGet(userContext).(entity.User)
+
+	err := h.services.Admin.SuspendUser(c.Request.Context(), service.SuspendUserSchema{
+		AdminID: admin.ID,
+		UserID:  userID,
+		Reason:  schema.Reason,
+	})
+	if err != nil {
+		adminErrorResponse(c, "failed to suspend user", userID, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type adminSetRoleSchema struct {
+	Role string `json:"role" binding:"required,oneof=user admin" enums:"user,admin" example:"admin"`
+}
+
+// adminSetRole handler grants role to the user
+//
+//	@Summary		Set user role
+//	@Security		JWT-RS256
+//	@Tags			admin
+//	@Description	Grants or revokes administrator role, user gets scopes of the new role on the next tokens refresh
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	string				true	"User ID"
+//	@Param			schema	body	adminSetRoleSchema	true	"JSON schema for user role"
+//	@Success		204		"User role was successfully set"
+//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or you aren't an administrator"
+//	@Failure		404		{object}	errResponse{errors=[]entity.CoreError}			"User not found"
+//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields or you tried to change your own role"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/admin/users/{id}/role [put]
+func (h *Handler) adminSetRole(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var schema adminSetRoleSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	admin := c.MustGet(userContext).(entity.User)
+
+	err := h.services.Admin.SetRole(c.Request.Context(), service.SetRoleSchema{
+		AdminID: admin.ID,
+		UserID:  userID,
+		Role:    schema.Role,
+	})
+	if err != nil {
+		adminErrorResponse(c, "failed to set user role", userID, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// adminUnsuspendUser handler lifts user suspension
+//
+//	@Summary		Unsuspend user
+//	@Security		JWT-RS256
+//	@Tags			admin
+//	@Description	Lifts user suspension
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"User ID"
+//	@Success		204	"User was successfully unsuspended"
+//	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or you aren't an administrator"
+//	@Failure		404	{object}	errResponse{errors=[]entity.CoreError}	"User not found"
+//	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/admin/users/{id}/unsuspend [post]
+func (h *Handler) adminUnsuspendUser(c *gin.Context) {
+	h.adminUserAction(c, "failed to unsuspend user", h.services.Admin.UnsuspendUser)
+}
+
+// adminSignOutUser handler signs user out from all devices
+//
+//	@Summary		Force user sign out
+//	@Security		JWT-RS256
+//	@Tags			admin
+//	@Description	Revokes all tokens of the user
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"User ID"
+//	@Success		204	"User was successfully signed out"
+//	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or you aren't an administrator"
+//	@Failure		404	{object}	errResponse{errors=[]entity.CoreError}	"User not found"
+//	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/admin/users/{id}/sign-out [post]
+func (h *Handler) adminSignOutUser(c *gin.Context) {
+	h.adminUserAction(c, "failed to sign out user", h.services.Admin.SignOutUser)
+}
+
+// adminResetTwoFactor handler disables users two-factor authentication
+//
+//	@Summary		Reset two-factor authentication
+//	@Security		JWT-RS256
+//	@Tags			admin
+//	@Description	Disables two-factor authentication of the user who lost access to the authenticator app and recovery codes
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"User ID"
+//	@Success		204	"Two-factor authentication was successfully reset"
+//	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or you aren't an administrator"
+//	@Failure		404	{object}	errResponse{errors=[]entity.CoreError}	"User not found"
+//	@Failure		422	{object}	errResponse{errors=[]entity.CoreError}	"Two-factor authentication not enabled"
+//	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/admin/users/{id}/2fa [delete]
+func (h *Handler) adminResetTwoFactor(c *gin.Context) {
+	h.adminUserAction(c, "failed to reset two-factor authentication", h.services.Admin.ResetTwoFactor)
+}
+
+type adminUnlockSignInSchema struct {
+	Login string `json:"login" binding:"required_without=IP" example:"kenplix"`
+	IP    string `json:"ip" binding:"required_without=Login,omitempty,ip" example:"203.0.113.7"`
+}
+
+// adminUnlockSignIn handler clears sign in lockout
+//
+//	@Summary		Unlock sign in
+//	@Security		JWT-RS256
+//	@Tags			admin
+//	@Description	Clears failed sign in attempts and lockout of login and/or IP address
+//	@Accept			json
+//	@Produce		json
+//	@Param			schema	body	adminUnlockSignInSchema	true	"JSON schema for sign in unlocking"
+//	@Success		204		"Sign in was successfully unlocked"
+//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or you aren't an administrator"
+//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/admin/sign-in/unlock [post]
+func (h *Handler) adminUnlockSignIn(c *gin.Context) {
+	var schema adminUnlockSignInSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	admin := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+
+	err := h.services.Admin.UnlockSignIn(reqctx, service.UnlockSignInSchema{
+		AdminID: admin.ID,
+		Login:   schema.Login,
+		IP:      schema.IP,
+	})
+	if err != nil {
+		logger := log.LoggerFromContext(reqctx)
+		logger.Error("failed to unlock sign in",
+			zap.String("adminID", admin.ID.Hex()),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// adminUserAction performs action without request body on the user from path
+func (h *Handler) adminUserAction(
+	c *gin.Context,
+	failure string,
+	action func(ctx context.Context, schema service.AdminActionSchema) error,
+) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	admin := c.MustGet(userContext).(entity.User)
+
+	err := action(c.Request.Context(), service.AdminActionSchema{
+		AdminID: admin.ID,
+		UserID:  userID,
+	})
+	if err != nil {
+		adminErrorResponse(c, failure, userID, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// parseUserID returns user ID from path, response is written if ID is invalid
+func parseUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		logger := log.LoggerFromContext(c.Request.Context())
+		logger.Warn("failed to parse user id",
+			zap.String("hex", c.Param("id")),
+			zap.Error(err),
+		)
+		userNotFoundErrorResponse(c)
+
+		return primitive.NilObjectID, false
+	}
+
+	return userID, true
+}
+
+func adminErrorResponse(c *gin.Context, failure string, userID primitive.ObjectID, err error) {
+	logger := log.LoggerFromContext(c.Request.Context())
+
+	var (
+		code   int
+		apiErr *entity.CoreError
+	)
+
+	switch {
+	case errors.Is(err, entity.ErrUserNotFound):
+		code = http.StatusNotFound
+		apiErr = &entity.CoreError{
+			Code:    errorcode.UserNotFound,
+			Message: entity.ErrUserNotFound.Error(),
+		}
+	case errors.Is(err, entity.ErrSelfAdministration):
+		code = http.StatusUnprocessableEntity
+		apiErr = &entity.CoreError{
+			Code:    errorcode.SelfAdministration,
+			Message: entity.ErrSelfAdministration.Error(),
+		}
+	case errors.Is(err, entity.ErrTwoFactorNotEnabled):
+		code = http.StatusUnprocessableEntity
+		apiErr = &entity.CoreError{
+			Code:    errorcode.TwoFactorNotEnabled,
+			Message: entity.ErrTwoFactorNotEnabled.Error(),
+		}
+	default:
+		logger.Error(failure,
+			zap.String("userID", userID.Hex()),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+
+	logger.Warn(failure,
+		zap.String("userID", userID.Hex()),
+		zap.Error(err),
+	)
+	errorResponse(c, code, apiErr)
+}
+
+func userNotFoundErrorResponse(c *gin.Context) {
+	errorResponse(c, http.StatusNotFound, &entity.CoreError{
+		Code:    errorcode.UserNotFound,
+		Message: entity.ErrUserNotFound.Error(),
+	})
+}
@@ -0,0 +1,399 @@
+package v1
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+)
+
+func TestRequireRole(t *testing.T) {
+	testCases := []struct {
+		name       string
+		role       string
+		statusCode int
+	}{
+		{
+			name:       "ordinary user",
+			role:       entity.RoleUser,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "admin",
+			role:       entity.RoleAdmin,
+			statusCode: http.StatusOK,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := NewHandler(testLogger(t), &service.Services{})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			r := gin.New()
+			r.GET(
+				"/admin",
+				testLoggerMiddleware(t),
+				func(c *gin.Context) {
+					c.Set(userContext, entity.User{ID: primitive.NewObjectID(), Role: tc.role})
+				},
+				h.requireRole(entity.RoleAdmin),
+				func(c *gin.Context) { c.Status(http.StatusOK) },
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", http.NoBody)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Code)
+		})
+	}
+}
+
+func TestHandler_AdminSuspendUser(t *testing.T) {
+	type args struct {
+		userID    string
+		inputBody string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	type mockBehavior func(*servMocks.AdminService)
+
+	testUserNotFoundResponse := mustMarshal(t, errResponse{
+		Errors: []apiError{
+			&entity.CoreError{
+				Code:    errorcode.UserNotFound,
+				Message: entity.ErrUserNotFound.Error(),
+			},
+		},
+	})
+
+	testSchema := mustMarshal(t, adminSuspendUserSchema{Reason: "spam links"})
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "invalid user id",
+			args: args{
+				userID:    "invalid",
+				inputBody: testSchema,
+			},
+			ret: ret{
+				statusCode:   http.StatusNotFound,
+				responseBody: testUserNotFoundResponse,
+			},
+			mockBehavior: func(_ *servMocks.AdminService) {},
+		},
+		{
+			name: "user not found",
+			args: args{
+				userID:    primitive.NewObjectID().Hex(),
+				inputBody: testSchema,
+			},
+			ret: ret{
+				statusCode:   http.StatusNotFound,
+				responseBody: testUserNotFoundResponse,
+			},
+			mockBehavior: func(adminServ *servMocks.AdminService) {
+				adminServ.
+					On("SuspendUser", mock.Anything, mock.Anything).
+					Return(entity.ErrUserNotFound)
+			},
+		},
+		{
+			name: "self suspension",
+			args: args{
+				userID:    primitive.NewObjectID().Hex(),
+				inputBody: testSchema,
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.SelfAdministration,
+							Message: entity.ErrSelfAdministration.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(adminServ *servMocks.AdminService) {
+				adminServ.
+					On("SuspendUser", mock.Anything, mock.Anything).
+					Return(entity.ErrSelfAdministration)
+			},
+		},
+		{
+			name: "service failure",
+			args: args{
+				userID:    primitive.NewObjectID().Hex(),
+				inputBody: testSchema,
+			},
+			ret: ret{
+				statusCode:   http.StatusInternalServerError,
+				responseBody: testInternalErrorResponse(t),
+			},
+			mockBehavior: func(adminServ *servMocks.AdminService) {
+				adminServ.
+					On("SuspendUser", mock.Anything, mock.Anything).
+					Return(assert.AnError)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				userID:    primitive.NewObjectID().Hex(),
+				inputBody: testSchema,
+			},
+			ret: ret{
+				statusCode: http.StatusNoContent,
+			},
+			mockBehavior: func(adminServ *servMocks.AdminService) {
+				adminServ.
+					On("SuspendUser", mock.Anything, mock.MatchedBy(func(schema service.SuspendUserSchema) bool {
+						return schema.Reason == "spam links"
+					})).
+					Return(nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	initValidator(t)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			adminServ := servMocks.NewAdminService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				Admin: adminServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(adminServ)
+
+			r := gin.New()
+			r.POST("/users/:id/suspend", testLoggerMiddleware(t), testUserMiddleware(t), h.adminSuspendUser)
+
+			req := httptest.NewRequest(
+				http.MethodPost,
+				"/users/"+tc.args.userID+"/suspend",
+				bytes.NewBufferString(tc.args.inputBody),
+			)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+		})
+	}
+}
+
+func TestHandler_AdminSetRole(t *testing.T) {
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	type mockBehavior func(*servMocks.AdminService)
+
+	testSchema := mustMarshal(t, adminSetRoleSchema{Role: entity.RoleAdmin})
+
+	testCases := []struct {
+		name         string
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "user not found",
+			ret: ret{
+				statusCode: http.StatusNotFound,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.UserNotFound,
+							Message: entity.ErrUserNotFound.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(adminServ *servMocks.AdminService) {
+				adminServ.
+					On("SetRole", mock.Anything, mock.Anything).
+					Return(entity.ErrUserNotFound)
+			},
+		},
+		{
+			name: "own role",
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.SelfAdministration,
+							Message: entity.ErrSelfAdministration.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(adminServ *servMocks.AdminService) {
+				adminServ.
+					On("SetRole", mock.Anything, mock.Anything).
+					Return(entity.ErrSelfAdministration)
+			},
+		},
+		{
+			name: "ok",
+			ret: ret{
+				statusCode: http.StatusNoContent,
+			},
+			mockBehavior: func(adminServ *servMocks.AdminService) {
+				adminServ.
+					On("SetRole", mock.Anything, mock.MatchedBy(func(schema service.SetRoleSchema) bool {
+						return schema.Role == entity.RoleAdmin && !schema.AdminID.IsZero()
+					})).
+					Return(nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	initValidator(t)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			adminServ := servMocks.NewAdminService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				Admin: adminServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(adminServ)
+
+			r := gin.New()
+			r.PUT("/users/:id/role", testLoggerMiddleware(t), testUserMiddleware(t), h.adminSetRole)
+
+			req := httptest.NewRequest(
+				http.MethodPut,
+				"/users/"+primitive.NewObjectID().Hex()+"/role",
+				bytes.NewBufferString(testSchema),
+			)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+		})
+	}
+}
+
+func TestHandler_AdminListAuditEvents(t *testing.T) {
+	userID := primitive.NewObjectID()
+
+	testEventsPage := entity.AuditEventsPage{
+		Events: []entity.AuditEvent{
+			{
+				ID:      primitive.NewObjectID(),
+				Action:  entity.AuditActionSignIn,
+				Outcome: entity.AuditOutcomeSuccess,
+				ActorID: &userID,
+			},
+		},
+		Total:   1,
+		Page:    1,
+		PerPage: 20,
+	}
+
+	testCases := []struct {
+		name         string
+		query        string
+		statusCode   int
+		mockBehavior func(*servMocks.AuditService)
+	}{
+		{
+			name:       "service failure",
+			query:      "",
+			statusCode: http.StatusInternalServerError,
+			mockBehavior: func(auditServ *servMocks.AuditService) {
+				auditServ.
+					On("List", mock.Anything, mock.Anything).
+					Return(entity.AuditEventsPage{}, assert.AnError)
+			},
+		},
+		{
+			name:       "ok",
+			query:      "?userID=" + userID.Hex() + "&action=" + entity.AuditActionSignIn + "&perPage=20",
+			statusCode: http.StatusOK,
+			mockBehavior: func(auditServ *servMocks.AuditService) {
+				auditServ.
+					On("List", mock.Anything, service.ListAuditEventsSchema{
+						UserID:  userID,
+						Action:  entity.AuditActionSignIn,
+						PerPage: 20,
+					}).
+					Return(testEventsPage, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	initValidator(t)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			auditServ := servMocks.NewAuditService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				Audit: auditServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(auditServ)
+
+			r := gin.New()
+			r.GET("/audit-events", testLoggerMiddleware(t), testUserMiddleware(t), h.adminListAuditEvents)
+
+			req := httptest.NewRequest(http.MethodGet, "/audit-events"+tc.query, http.NoBody)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Code)
+
+			if tc.statusCode == http.StatusOK {
+				assert.Equal(t, mustMarshal(t, testEventsPage), rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+func (h *Handler) initAPIKeysRoutes(users *gin.RouterGroup) {
+	apiKeys := users.Group("/api-keys")
+
+	apiKeys.POST("", h.createAPIKey)
+	apiKeys.GET("", h.listAPIKeys)
+	apiKeys.DELETE("/:id", h.revokeAPIKey)
+}
+
+type apiKeyCreateSchema struct {
+	Name   string   `json:"name" binding:"required,max=64" example:"ci deploy"`
+	Scopes []string `json:"scopes" binding:"required,min=1,unique,dive,oneof=account links:read links:write" example:"links:read,links:write"`
+	// ExpiresAt is a date after which key is not accepted anymore, key never expires if omitted
+	ExpiresAt *time.Time `json:"expiresAt" binding:"omitempty,gt" example:"2023-12-24T21:49:33.072726+02:00"`
+}
+
+// createAPIKey handler creates personal access token
+//
+//	@Summary		Create personal access token
+//	@Security		JWT-RS256
+//	@Tags			api-keys
+//	@Description	Creates API key with provided scopes, key is shown only once and can't be recovered afterwards
+//	@Accept			json
+//	@Produce		json
+//	@Param			schema	body		apiKeyCreateSchema								true	"JSON schema for API key creation"
+//	@Success		201		{object}	entity.CreatedAPIKey							"API key was successfully created"
+//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or access token lacks required scopes"
+//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields or API keys limit reached"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/users/api-keys [post]
+func (h *Handler) createAPIKey(c *gin.Context) {
+	var schema apiKeyCreateSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	apiKey, err := h.services.APIKeys.Create(reqctx, service.CreateAPIKeySchema{
+		UserID:    user.ID,
+		Name:      schema.Name,
+		Scopes:    schema.Scopes,
+		ExpiresAt: schema.ExpiresAt,
+	})
+	if err != nil {
+		var apiErr apiError
+
+		switch {
+		case errors.Is(err, entity.ErrAPIKeysLimitReached):
+			apiErr = &entity.CoreError{
+				Code:    errorcode.APIKeysLimitReached,
+				Message: entity.ErrAPIKeysLimitReached.Error(),
+			}
+		case errors.Is(err, entity.ErrAPIKeyScopesNotAllowed):
+			apiErr = &entity.ValidationError{
+				CoreError: entity.CoreError{
+					Code:    errorcode.InvalidField,
+					Message: entity.ErrAPIKeyScopesNotAllowed.Error(),
+				},
+				Field: "scopes",
+			}
+		default:
+			logger.Error("failed to create api key",
+				zap.String("userID", user.ID.Hex()),
+				zap.Error(err),
+			)
+			internalErrorResponse(c)
+
+			return
+		}
+
+		logger.Warn("failed to create api key",
+			zap.String("userID", user.ID.Hex()),
+			zap.Error(err),
+		)
+		errorResponse(c, http.StatusUnprocessableEntity, apiErr)
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiKey)
+}
+
+// listAPIKeys handler returns users personal access tokens
+//
+//	@Summary		List personal access tokens
+//	@Security		JWT-RS256
+//	@Tags			api-keys
+//	@Description	Returns users API keys without secrets
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{array}		entity.APIKey							"User API keys"
+//	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or access token lacks required scopes"
+//	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/users/api-keys [get]
+func (h *Handler) listAPIKeys(c *gin.Context) {
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+
+	apiKeys, err := h.services.APIKeys.List(reqctx, user.ID)
+	if err != nil {
+		logger := log.LoggerFromContext(reqctx)
+		logger.Error("failed to list api keys",
+			zap.String("userID", user.ID.Hex()),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeys)
+}
+
+// revokeAPIKey handler revokes personal access token
+//
+//	@Summary		Revoke personal access token
+//	@Security		JWT-RS256
+//	@Tags			api-keys
+//	@Description	Deletes API key, requests made with it are rejected immediately
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	string	true	"API key ID"
+//	@Success		204	"API key was successfully revoked"
+//	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or access token lacks required scopes"
+//	@Failure		404	{object}	errResponse{errors=[]entity.CoreError}	"API key not found"
+//	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/users/api-keys/{id} [delete]
+func (h *Handler) revokeAPIKey(c *gin.Context) {
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	keyID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		logger.Warn("failed to parse api key id",
+			zap.String("hex", c.Param("id")),
+			zap.Error(err),
+		)
+		apiKeyNotFoundErrorResponse(c)
+
+		return
+	}
+
+	err = h.services.APIKeys.Revoke(reqctx, service.RevokeAPIKeySchema{
+		UserID: user.ID,
+		KeyID:  keyID,
+	})
+	if err != nil {
+		if errors.Is(err, entity.ErrAPIKeyNotFound) {
+			logger.Warn("failed to revoke api key",
+				zap.String("userID", user.ID.Hex()),
+				zap.Error(err),
+			)
+			apiKeyNotFoundErrorResponse(c)
+
+			return
+		}
+
+		logger.Error("failed to revoke api key",
+			zap.String("userID", user.ID.Hex()),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func apiKeyNotFoundErrorResponse(c *gin.Context) {
+	errorResponse(c, http.StatusNotFound, &entity.CoreError{
+		Code:    errorcode.APIKeyNotFound,
+		Message: entity.ErrAPIKeyNotFound.Error(),
+	})
+}
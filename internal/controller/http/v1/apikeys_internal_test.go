@@ -0,0 +1,253 @@
+package v1
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+)
+
+func TestHandler_CreateAPIKey(t *testing.T) {
+	type args struct {
+		inputBody string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	type mockBehavior func(*servMocks.APIKeysService)
+
+	testAPIKeyCreateSchema := func(t *testing.T) apiKeyCreateSchema {
+		t.Helper()
+
+		return apiKeyCreateSchema{
+			Name:   "ci deploy",
+			Scopes: []string{entity.ScopeLinksRead, entity.ScopeLinksWrite},
+		}
+	}
+
+	testCreatedAPIKey := entity.CreatedAPIKey{
+		APIKey: entity.APIKey{
+			ID:        primitive.NewObjectID(),
+			Name:      "ci deploy",
+			Prefix:    "shrtnr_3f9a1c2e",
+			Scopes:    []string{entity.ScopeLinksRead, entity.ScopeLinksWrite},
+			CreatedAt: time.Now(),
+		},
+		Key: "shrtnr_3f9a1c2e_secret",
+	}
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "binding error",
+			args: args{
+				inputBody: "[]",
+			},
+			ret: ret{
+				statusCode:   http.StatusBadRequest,
+				responseBody: testUnmarshalTypeError(t),
+			},
+			mockBehavior: func(_ *servMocks.APIKeysService) {},
+		},
+		{
+			name: "api keys limit reached",
+			args: args{
+				inputBody: mustMarshal(t, testAPIKeyCreateSchema(t)),
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.APIKeysLimitReached,
+							Message: entity.ErrAPIKeysLimitReached.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(apiKeysServ *servMocks.APIKeysService) {
+				apiKeysServ.
+					On("Create", mock.Anything, mock.Anything).
+					Return(entity.CreatedAPIKey{}, entity.ErrAPIKeysLimitReached)
+			},
+		},
+		{
+			name: "service failure",
+			args: args{
+				inputBody: mustMarshal(t, testAPIKeyCreateSchema(t)),
+			},
+			ret: ret{
+				statusCode:   http.StatusInternalServerError,
+				responseBody: testInternalErrorResponse(t),
+			},
+			mockBehavior: func(apiKeysServ *servMocks.APIKeysService) {
+				apiKeysServ.
+					On("Create", mock.Anything, mock.Anything).
+					Return(entity.CreatedAPIKey{}, assert.AnError)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				inputBody: mustMarshal(t, testAPIKeyCreateSchema(t)),
+			},
+			ret: ret{
+				statusCode:   http.StatusCreated,
+				responseBody: mustMarshal(t, testCreatedAPIKey),
+			},
+			mockBehavior: func(apiKeysServ *servMocks.APIKeysService) {
+				apiKeysServ.
+					On("Create", mock.Anything, mock.Anything).
+					Return(testCreatedAPIKey, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	initValidator(t)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiKeysServ := servMocks.NewAPIKeysService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				APIKeys: apiKeysServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(apiKeysServ)
+
+			r := gin.New()
+			r.POST("/api-keys", testLoggerMiddleware(t), testUserMiddleware(t), h.createAPIKey)
+
+			req := httptest.NewRequest(http.MethodPost, "/api-keys", bytes.NewBufferString(tc.args.inputBody))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+		})
+	}
+}
+
+func TestHandler_RevokeAPIKey(t *testing.T) {
+	type args struct {
+		keyID string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	type mockBehavior func(*servMocks.APIKeysService)
+
+	testAPIKeyNotFoundResponse := mustMarshal(t, errResponse{
+		Errors: []apiError{
+			&entity.CoreError{
+				Code:    errorcode.APIKeyNotFound,
+				Message: entity.ErrAPIKeyNotFound.Error(),
+			},
+		},
+	})
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "invalid key id",
+			args: args{
+				keyID: "invalid",
+			},
+			ret: ret{
+				statusCode:   http.StatusNotFound,
+				responseBody: testAPIKeyNotFoundResponse,
+			},
+			mockBehavior: func(_ *servMocks.APIKeysService) {},
+		},
+		{
+			name: "key not found",
+			args: args{
+				keyID: primitive.NewObjectID().Hex(),
+			},
+			ret: ret{
+				statusCode:   http.StatusNotFound,
+				responseBody: testAPIKeyNotFoundResponse,
+			},
+			mockBehavior: func(apiKeysServ *servMocks.APIKeysService) {
+				apiKeysServ.
+					On("Revoke", mock.Anything, mock.Anything).
+					Return(entity.ErrAPIKeyNotFound)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				keyID: primitive.NewObjectID().Hex(),
+			},
+			ret: ret{
+				statusCode: http.StatusNoContent,
+			},
+			mockBehavior: func(apiKeysServ *servMocks.APIKeysService) {
+				apiKeysServ.
+					On("Revoke", mock.Anything, mock.Anything).
+					Return(nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiKeysServ := servMocks.NewAPIKeysService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				APIKeys: apiKeysServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(apiKeysServ)
+
+			r := gin.New()
+			r.DELETE("/api-keys/:id", testLoggerMiddleware(t), testUserMiddleware(t), h.revokeAPIKey)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api-keys/"+tc.args.keyID, http.NoBody)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+type auditEventsQuery struct {
+	Page    int `form:"page" binding:"omitempty,min=1" example:"1"`
+	PerPage int `form:"perPage" binding:"omitempty,min=1,max=100" example:"20"`
+}
+
+// listOwnAuditEvents handler returns security events of the user
+//
+//	@Summary		List own security events
+//	@Security		JWT-RS256
+//	@Tags			user
+//	@Description	Returns security events performed by the user or on the user, newest first
+//	@Accept			json
+//	@Produce		json
+//	@Param			page	query		int												false	"Page number"				minimum(1)	default(1)
+//	@Param			perPage	query		int												false	"Number of events per page"	minimum(1)	maximum(100)	default(20)
+//	@Success		200		{object}	entity.AuditEventsPage							"Security events page"
+//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or access token lacks required scopes"
+//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/users/audit-events [get]
+func (h *Handler) listOwnAuditEvents(c *gin.Context) {
+	var query auditEventsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	user := c.MustGet(userContext).(entity.User)
+
+	h.listAuditEvents(c, service.ListAuditEventsSchema{
+		UserID:  user.ID,
+		Page:    query.Page,
+		PerPage: query.PerPage,
+	})
+}
+
+type adminAuditEventsQuery struct {
+	auditEventsQuery
+	// UserID matches events performed by or on the user
+	UserID string `form:"userID" binding:"omitempty,len=24,hexadecimal" example:"63a75a2574ef628a127ee972"`
+	Action string `form:"action" binding:"max=64" example:"auth.signed_in"`
+}
+
+// adminListAuditEvents handler returns security events of all users
+//
+//	@Summary		List security events
+//	@Security		JWT-RS256
+//	@Tags			admin
+//	@Description	Returns security events, optionally filtered by user and action, newest first
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	query		string											false	"User who performed action or was affected by it"
+//	@Param			action	query		string											false	"Action"
+//	@Param			page	query		int												false	"Page number"				minimum(1)	default(1)
+//	@Param			perPage	query		int												false	"Number of events per page"	minimum(1)	maximum(100)	default(20)
+//	@Success		200		{object}	entity.AuditEventsPage							"Security events page"
+//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or you aren't an administrator"
+//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/admin/audit-events [get]
+func (h *Handler) adminListAuditEvents(c *gin.Context) {
+	var query adminAuditEventsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	var userID primitive.ObjectID
+	if query.UserID != "" {
+		// format is checked by the binding
+		userID, _ = primitive.ObjectIDFromHex(query.UserID)
+	}
+
+	h.listAuditEvents(c, service.ListAuditEventsSchema{
+		UserID:  userID,
+		Action:  query.Action,
+		Page:    query.Page,
+		PerPage: query.PerPage,
+	})
+}
+
+func (h *Handler) listAuditEvents(c *gin.Context, schema service.ListAuditEventsSchema) {
+	reqctx := c.Request.Context()
+
+	eventsPage, err := h.services.Audit.List(reqctx, schema)
+	if err != nil {
+		logger := log.LoggerFromContext(reqctx)
+		logger.Error("failed to list audit events",
+			zap.String("userID", schema.UserID.Hex()),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, eventsPage)
+}
+
+// recordAudit writes event of the request, failure doesn't affect the response
+func (h *Handler) recordAudit(c *gin.Context, event entity.AuditEventModel) {
+	reqctx := c.Request.Context()
+
+	if err := h.services.Audit.Record(reqctx, event); err != nil {
+		logger := log.LoggerFromContext(reqctx)
+		logger.Error("failed to record audit event",
+			zap.String("action", event.Action),
+			zap.Error(err),
+		)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
 	"github.com/kenplix/url-shrtnr/internal/service"
@@ -22,8 +23,12 @@ func (h *Handler) initAuthRoutes(router *gin.RouterGroup) {
 
 	auth.POST("/sign-up", h.signUp)
 	auth.POST("/sign-in", h.signIn)
+	auth.POST("/sign-in/mfa", h.signInMFA)
 	auth.POST("/sign-out", h.userIdentityMiddleware, h.signOut)
 	auth.POST("/refresh-tokens", h.refreshTokens)
+
+	h.initOIDCRoutes(auth)
+	h.initMagicLinkRoutes(auth)
 }
 
 type userSignUpSchema struct {
@@ -90,12 +95,17 @@ type userSignInSchema struct {
 //	@Description	Sign in users into system
 //	@Accept			json
 //	@Produce		json
-//	@Param			schema	body		userSignInSchema								true	"JSON schema for user sign in"
-//	@Success		200		{object}	entity.Tokens									"User was successfully signed in"
-//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
-//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended"
-//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
-//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Param			X-Auth-Mode	header		string				false	"Set to cookie to receive tokens in HttpOnly cookies"	Enums(cookie)
+//	@Param			schema		body		userSignInSchema	true	"JSON schema for user sign in"
+//	@Success		200			{object}	entity.Tokens		"User was successfully signed in"
+//	@Success		204			"Tokens were set in cookies"
+//	@Success		202			{object}	entity.MFAChallenge								"Second authentication factor required"
+//	@Failure		400			{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		403			{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended"
+//	@Failure		422			{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		429			{object}	errResponse{errors=[]entity.CoreError}			"Too many failed attempts"
+//	@Header			429			{integer}	Retry-After										"Seconds to wait before the next attempt"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
 //	@Router			/auth/sign-in [post]
 func (h *Handler) signIn(c *gin.Context) {
 	var schema userSignInSchema
@@ -110,8 +120,20 @@ func (h *Handler) signIn(c *gin.Context) {
 	tokens, err := h.services.Auth.SignIn(reqctx, service.UserSignInSchema{
 		Login:    schema.Login,
 		Password: schema.Password,
+		IP:       c.ClientIP(),
 	})
 	if err != nil {
+		var tooManyAttemptsError *entity.TooManyAttemptsError
+		if errors.As(err, &tooManyAttemptsError) {
+			logger.Warn("sign in attempts are throttled",
+				zap.String("login", schema.Login),
+				zap.Duration("retryAfter", tooManyAttemptsError.RetryAfter),
+			)
+			tooManyAttemptsErrorResponse(c, tooManyAttemptsError.RetryAfter)
+
+			return
+		}
+
 		if errors.Is(err, entity.ErrIncorrectCredentials) {
 			logger.Warn("failed to sign in", zap.Error(err))
 			errorResponse(c, http.StatusUnprocessableEntity, &entity.CoreError{
@@ -132,13 +154,98 @@ func (h *Handler) signIn(c *gin.Context) {
 			return
 		}
 
+		var mfaRequiredError *entity.MFARequiredError
+		if errors.As(err, &mfaRequiredError) {
+			logger.Debug("second authentication factor required",
+				zap.String("userID", mfaRequiredError.UserID),
+			)
+			c.JSON(http.StatusAccepted, mfaRequiredError.Challenge)
+
+			return
+		}
+
 		logger.Error("failed to sign in", zap.Error(err))
 		internalErrorResponse(c)
 
 		return
 	}
 
-	c.JSON(http.StatusOK, tokens)
+	h.tokensResponse(c, tokens)
+}
+
+type userSignInMFASchema struct {
+	MFAToken string `json:"mfaToken" binding:"required" example:"4f1c2c7e0d2b4a6f9e8d7c6b5a4f3e2d"`
+	Code     string `json:"code" binding:"required,max=32" example:"123456"`
+}
+
+// signInMFA handler completes sign in of users with enabled two-factor authentication
+//
+//	@Summary		Complete sign in with TOTP or recovery code
+//	@Tags			auth
+//	@Description	Exchanges MFA token received on sign in together with TOTP or recovery code for tokens pair
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Auth-Mode	header		string				false	"Set to cookie to receive tokens in HttpOnly cookies"	Enums(cookie)
+//	@Param			schema		body		userSignInMFASchema	true	"JSON schema for second factor verification"
+//	@Success		200			{object}	entity.Tokens		"User was successfully signed in"
+//	@Success		204			"Tokens were set in cookies"
+//	@Failure		400			{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		403			{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended"
+//	@Failure		422			{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/auth/sign-in/mfa [post]
+func (h *Handler) signInMFA(c *gin.Context) {
+	var schema userSignInMFASchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	tokens, err := h.services.Auth.SignInMFA(reqctx, service.UserSignInMFASchema{
+		MFAToken: schema.MFAToken,
+		Code:     schema.Code,
+	})
+	if err != nil {
+		if errors.Is(err, entity.ErrMFATokenNotFound) {
+			logger.Warn("failed to sign in with second factor", zap.Error(err))
+			errorResponse(c, http.StatusUnprocessableEntity, &entity.ValidationError{
+				CoreError: entity.CoreError{
+					Code:    errorcode.InvalidField,
+					Message: "mfa token is invalid, expired or revoked",
+				},
+				Field: "mfaToken",
+			})
+
+			return
+		}
+
+		if errors.Is(err, entity.ErrIncorrectTwoFactorCode) {
+			logger.Warn("failed to sign in with second factor", zap.Error(err))
+			errorResponse(c, http.StatusUnprocessableEntity, newIncorrectOTPError())
+
+			return
+		}
+
+		var suspUserError *entity.SuspendedUserError
+		if errors.As(err, &suspUserError) {
+			logger.Debug("suspended user tries to sign in",
+				zap.String("userID", suspUserError.UserID),
+			)
+			suspendedErrorResponse(c)
+
+			return
+		}
+
+		logger.Error("failed to sign in with second factor", zap.Error(err))
+		internalErrorResponse(c)
+
+		return
+	}
+
+	h.tokensResponse(c, tokens)
 }
 
 // signOut handler sign out users from the system
@@ -171,6 +278,10 @@ func (h *Handler) signOut(c *gin.Context) {
 		return
 	}
 
+	if h.cookies.enabled {
+		h.cookies.clear(c)
+	}
+
 	c.Status(http.StatusOK)
 }
 
@@ -182,29 +293,48 @@ type userRefreshTokensSchema struct {
 //
 //	@Summary		Refresh users tokens
 //	@Tags			auth
-//	@Description	Refresh users tokens
+//	@Description	Refresh users tokens, new tokens are issued with current scopes of the user
 //	@Accept			json
 //	@Produce		json
-//	@Param			schema	body		userRefreshTokensSchema							true	"JSON schema for tokens refresh"
-//	@Success		200		{object}	entity.Tokens									"User tokens was successfully refreshed"
-//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
-//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
-//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Param			X-Auth-Mode		header		string					false	"Set to cookie to receive tokens in HttpOnly cookies"	Enums(cookie)
+//	@Param			X-CSRF-Token	header		string					false	"Value of csrf_token cookie, required in cookie mode"
+//	@Param			schema			body		userRefreshTokensSchema	false	"JSON schema for tokens refresh, refresh token is read from the cookie in cookie mode"
+//	@Success		200				{object}	entity.Tokens			"User tokens was successfully refreshed"
+//	@Success		204				"Tokens were set in cookies"
+//	@Failure		400				{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		403				{object}	errResponse{errors=[]entity.CoreError}			"CSRF token is missing or invalid, your account has been suspended or scheduled for deletion"
+//	@Failure		422				{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		500				{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
 //	@Router			/auth/refresh-tokens [post]
 func (h *Handler) refreshTokens(c *gin.Context) {
-	var schema userRefreshTokensSchema
-	if err := c.ShouldBindJSON(&schema); err != nil {
-		bindingErrorResponse(c, err)
-		return
-	}
-
 	reqctx := c.Request.Context()
 	logger := log.LoggerFromContext(reqctx)
 
-	claims, err := h.services.JWT.ParseRefreshToken(schema.RefreshToken)
+	var refreshToken string
+
+	if h.cookies.requested(c) {
+		if !h.cookies.validCSRF(c) {
+			logger.Warn("tokens refresh request with invalid csrf token")
+			csrfErrorResponse(c)
+
+			return
+		}
+
+		refreshToken, _ = c.Cookie(refreshTokenCookie)
+	} else {
+		var schema userRefreshTokensSchema
+		if err := c.ShouldBindJSON(&schema); err != nil {
+			bindingErrorResponse(c, err)
+			return
+		}
+
+		refreshToken = schema.RefreshToken
+	}
+
+	claims, err := h.services.JWT.ParseRefreshToken(refreshToken)
 	if err != nil {
 		logger.Warn("failed to parse refresh token",
-			zap.String("token", schema.RefreshToken),
+			zap.String("token", refreshToken),
 			zap.Error(err),
 		)
 		errorResponse(c, http.StatusUnprocessableEntity, &entity.ValidationError{
@@ -218,12 +348,20 @@ func (h *Handler) refreshTokens(c *gin.Context) {
 		return
 	}
 
+	userID, _ := primitive.ObjectIDFromHex(claims.Subject)
+
 	err = h.services.JWT.ValidateRefreshToken(reqctx, claims)
 	if err != nil {
 		logger.Warn("failed to validate refresh token",
-			zap.String("token", schema.RefreshToken),
+			zap.String("token", refreshToken),
 			zap.Error(err),
 		)
+		// signed token which is not valid anymore may be a replay of the stolen one
+		h.recordAudit(c, entity.AuditEventModel{
+			Action:  entity.AuditActionTokensRefreshed,
+			Outcome: entity.AuditOutcomeFailure,
+			ActorID: userID,
+		})
 		errorResponse(c, http.StatusUnprocessableEntity, &entity.ValidationError{
 			CoreError: entity.CoreError{
 				Code:    errorcode.InvalidField,
@@ -235,16 +373,44 @@ func (h *Handler) refreshTokens(c *gin.Context) {
 		return
 	}
 
-	tokens, err := h.services.JWT.CreateTokens(reqctx, claims.Subject)
+	tokens, err := h.services.Auth.RefreshTokens(reqctx, userID)
 	if err != nil {
-		logger.Error("failed to create tokens pair",
-			zap.String("userID", claims.Subject),
-			zap.Error(err),
-		)
-		internalErrorResponse(c)
+		var suspUserError *entity.SuspendedUserError
+
+		switch {
+		case errors.As(err, &suspUserError):
+			logger.Debug("suspended user tries to refresh tokens",
+				zap.String("userID", suspUserError.UserID),
+			)
+			suspendedErrorResponse(c)
+		case errors.Is(err, entity.ErrDeletionScheduled):
+			errorResponse(c, http.StatusForbidden, &entity.CoreError{
+				Code:    errorcode.DeletionScheduled,
+				Message: entity.ErrDeletionScheduled.Error(),
+			})
+		case errors.Is(err, entity.ErrUserNotFound):
+			errorResponse(c, http.StatusUnprocessableEntity, &entity.ValidationError{
+				CoreError: entity.CoreError{
+					Code:    errorcode.InvalidField,
+					Message: "refresh token is invalid, expired or revoked",
+				},
+				Field: "refreshToken",
+			})
+		default:
+			logger.Error("failed to refresh tokens",
+				zap.String("userID", claims.Subject),
+				zap.Error(err),
+			)
+			internalErrorResponse(c)
+		}
 
 		return
 	}
 
-	c.JSON(http.StatusOK, tokens)
+	h.recordAudit(c, entity.AuditEventModel{
+		Action:  entity.AuditActionTokensRefreshed,
+		ActorID: userID,
+	})
+
+	h.tokensResponse(c, tokens)
 }
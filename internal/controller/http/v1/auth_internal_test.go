@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -170,6 +171,7 @@ func TestAuthHandler_SignIn(t *testing.T) {
 	type ret struct {
 		statusCode   int
 		responseBody string
+		retryAfter   string
 	}
 
 	type mockBehavior func(*servMocks.AuthService)
@@ -200,6 +202,24 @@ func TestAuthHandler_SignIn(t *testing.T) {
 			},
 			mockBehavior: func(authServ *servMocks.AuthService) {},
 		},
+		{
+			name: "too many attempts",
+			args: args{
+				inputBody: mustMarshal(t, testUserSignInSchema(t)),
+			},
+			ret: ret{
+				statusCode: http.StatusTooManyRequests,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{newTooManyAttemptsError()},
+				}),
+				retryAfter: "91",
+			},
+			mockBehavior: func(authServ *servMocks.AuthService) {
+				authServ.
+					On("SignIn", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, &entity.TooManyAttemptsError{RetryAfter: 90*time.Second + time.Millisecond})
+			},
+		},
 		{
 			name: "incorrect email or password",
 			args: args{
@@ -244,6 +264,30 @@ func TestAuthHandler_SignIn(t *testing.T) {
 					Return(entity.Tokens{}, &entity.SuspendedUserError{UserID: "<user id>"})
 			},
 		},
+		{
+			name: "mfa required",
+			args: args{
+				inputBody: mustMarshal(t, testUserSignInSchema(t)),
+			},
+			ret: ret{
+				statusCode: http.StatusAccepted,
+				responseBody: mustMarshal(t, entity.MFAChallenge{
+					MFAToken:  "<mfa token>",
+					ExpiresIn: 300,
+				}),
+			},
+			mockBehavior: func(authServ *servMocks.AuthService) {
+				authServ.
+					On("SignIn", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, &entity.MFARequiredError{
+						UserID: "<user id>",
+						Challenge: entity.MFAChallenge{
+							MFAToken:  "<mfa token>",
+							ExpiresIn: 300,
+						},
+					})
+			},
+		},
 		{
 			name: "service failure",
 			args: args{
@@ -316,6 +360,180 @@ func TestAuthHandler_SignIn(t *testing.T) {
 			resp := rec.Result()
 			body, _ := io.ReadAll(resp.Body)
 
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+			assert.Equal(t, tc.ret.retryAfter, resp.Header.Get("Retry-After"))
+		})
+	}
+}
+
+func TestAuthHandler_SignInMFA(t *testing.T) {
+	type args struct {
+		inputBody string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	type mockBehavior func(*servMocks.AuthService)
+
+	testUserSignInMFASchema := func(t *testing.T) userSignInMFASchema {
+		t.Helper()
+
+		return userSignInMFASchema{
+			MFAToken: "<mfa token>",
+			Code:     "123456",
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "binding error",
+			args: args{
+				inputBody: "[]",
+			},
+			ret: ret{
+				statusCode:   http.StatusBadRequest,
+				responseBody: testUnmarshalTypeError(t),
+			},
+			mockBehavior: func(_ *servMocks.AuthService) {},
+		},
+		{
+			name: "mfa token not found",
+			args: args{
+				inputBody: mustMarshal(t, testUserSignInMFASchema(t)),
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.ValidationError{
+							CoreError: entity.CoreError{
+								Code:    errorcode.InvalidField,
+								Message: "mfa token is invalid, expired or revoked",
+							},
+							Field: "mfaToken",
+						},
+					},
+				}),
+			},
+			mockBehavior: func(authServ *servMocks.AuthService) {
+				authServ.
+					On("SignInMFA", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, entity.ErrMFATokenNotFound)
+			},
+		},
+		{
+			name: "incorrect code",
+			args: args{
+				inputBody: mustMarshal(t, testUserSignInMFASchema(t)),
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{newIncorrectOTPError()},
+				}),
+			},
+			mockBehavior: func(authServ *servMocks.AuthService) {
+				authServ.
+					On("SignInMFA", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, entity.ErrIncorrectTwoFactorCode)
+			},
+		},
+		{
+			name: "suspended user",
+			args: args{
+				inputBody: mustMarshal(t, testUserSignInMFASchema(t)),
+			},
+			ret: ret{
+				statusCode:   http.StatusForbidden,
+				responseBody: testSuspendedErrorResponse(t),
+			},
+			mockBehavior: func(authServ *servMocks.AuthService) {
+				authServ.
+					On("SignInMFA", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, &entity.SuspendedUserError{UserID: "<user id>"})
+			},
+		},
+		{
+			name: "service failure",
+			args: args{
+				inputBody: mustMarshal(t, testUserSignInMFASchema(t)),
+			},
+			ret: ret{
+				statusCode:   http.StatusInternalServerError,
+				responseBody: testInternalErrorResponse(t),
+			},
+			mockBehavior: func(authServ *servMocks.AuthService) {
+				authServ.
+					On("SignInMFA", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, assert.AnError)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				inputBody: mustMarshal(t, testUserSignInMFASchema(t)),
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+				responseBody: mustMarshal(t, entity.Tokens{
+					AccessToken:  "<access token>",
+					RefreshToken: "<refresh token>",
+				}),
+			},
+			mockBehavior: func(authServ *servMocks.AuthService) {
+				authServ.
+					On("SignInMFA", mock.Anything, mock.Anything).
+					Return(
+						entity.Tokens{
+							AccessToken:  "<access token>",
+							RefreshToken: "<refresh token>",
+						},
+						nil,
+					)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	initValidator(t)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				jwtServ   = servMocks.NewJWTService(t)
+				authServ  = servMocks.NewAuthService(t)
+				usersServ = servMocks.NewUsersService(t)
+			)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				JWT:   jwtServ,
+				Auth:  authServ,
+				Users: usersServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(authServ)
+
+			r := gin.New()
+			r.POST("/sign-in/mfa", testLoggerMiddleware(t), h.signInMFA)
+
+			req := httptest.NewRequest(http.MethodPost, "/sign-in/mfa", bytes.NewBufferString(tc.args.inputBody))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
 			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
 			assert.Equal(t, tc.ret.responseBody, string(body))
 		})
@@ -332,7 +550,7 @@ func TestAuthHandler_RefreshTokens(t *testing.T) {
 		responseBody string
 	}
 
-	type mockBehavior func(*servMocks.JWTService)
+	type mockBehavior func(*servMocks.JWTService, *servMocks.AuthService)
 
 	testUserRefreshTokensSchema := func(t *testing.T) userRefreshTokensSchema {
 		t.Helper()
@@ -357,7 +575,7 @@ func TestAuthHandler_RefreshTokens(t *testing.T) {
 				statusCode:   http.StatusBadRequest,
 				responseBody: testUnmarshalTypeError(t),
 			},
-			mockBehavior: func(_ *servMocks.JWTService) {},
+			mockBehavior: func(_ *servMocks.JWTService, _ *servMocks.AuthService) {},
 		},
 		{
 			name: "token parsing error",
@@ -378,7 +596,7 @@ func TestAuthHandler_RefreshTokens(t *testing.T) {
 					},
 				}),
 			},
-			mockBehavior: func(jwtServ *servMocks.JWTService) {
+			mockBehavior: func(jwtServ *servMocks.JWTService, authServ *servMocks.AuthService) {
 				jwtServ.
 					On("ParseRefreshToken", mock.Anything).
 					Return(nil, assert.AnError)
@@ -403,7 +621,7 @@ func TestAuthHandler_RefreshTokens(t *testing.T) {
 					},
 				}),
 			},
-			mockBehavior: func(jwtServ *servMocks.JWTService) {
+			mockBehavior: func(jwtServ *servMocks.JWTService, authServ *servMocks.AuthService) {
 				jwtServ.
 					On("ParseRefreshToken", mock.Anything).
 					Return(&token.JWTCustomClaims{}, nil)
@@ -422,7 +640,7 @@ func TestAuthHandler_RefreshTokens(t *testing.T) {
 				statusCode:   http.StatusInternalServerError,
 				responseBody: testInternalErrorResponse(t),
 			},
-			mockBehavior: func(jwtServ *servMocks.JWTService) {
+			mockBehavior: func(jwtServ *servMocks.JWTService, authServ *servMocks.AuthService) {
 				jwtServ.
 					On("ParseRefreshToken", mock.Anything).
 					Return(&token.JWTCustomClaims{}, nil)
@@ -431,11 +649,34 @@ func TestAuthHandler_RefreshTokens(t *testing.T) {
 					On("ValidateRefreshToken", mock.Anything, mock.Anything).
 					Return(nil)
 
-				jwtServ.
-					On("CreateTokens", mock.Anything, mock.Anything).
+				authServ.
+					On("RefreshTokens", mock.Anything, mock.Anything).
 					Return(entity.Tokens{}, assert.AnError)
 			},
 		},
+		{
+			name: "suspended user",
+			args: args{
+				inputBody: mustMarshal(t, testUserRefreshTokensSchema(t)),
+			},
+			ret: ret{
+				statusCode:   http.StatusForbidden,
+				responseBody: testSuspendedErrorResponse(t),
+			},
+			mockBehavior: func(jwtServ *servMocks.JWTService, authServ *servMocks.AuthService) {
+				jwtServ.
+					On("ParseRefreshToken", mock.Anything).
+					Return(&token.JWTCustomClaims{}, nil)
+
+				jwtServ.
+					On("ValidateRefreshToken", mock.Anything, mock.Anything).
+					Return(nil)
+
+				authServ.
+					On("RefreshTokens", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, &entity.SuspendedUserError{UserID: "<user id>"})
+			},
+		},
 		{
 			name: "ok",
 			args: args{
@@ -448,7 +689,7 @@ func TestAuthHandler_RefreshTokens(t *testing.T) {
 					RefreshToken: "<new refresh token>",
 				}),
 			},
-			mockBehavior: func(jwtServ *servMocks.JWTService) {
+			mockBehavior: func(jwtServ *servMocks.JWTService, authServ *servMocks.AuthService) {
 				jwtServ.
 					On("ParseRefreshToken", mock.Anything).
 					Return(&token.JWTCustomClaims{}, nil)
@@ -457,8 +698,8 @@ func TestAuthHandler_RefreshTokens(t *testing.T) {
 					On("ValidateRefreshToken", mock.Anything, mock.Anything).
 					Return(nil)
 
-				jwtServ.
-					On("CreateTokens", mock.Anything, mock.Anything).
+				authServ.
+					On("RefreshTokens", mock.Anything, mock.Anything).
 					Return(
 						entity.Tokens{
 							AccessToken:  "<new access token>",
@@ -486,10 +727,11 @@ func TestAuthHandler_RefreshTokens(t *testing.T) {
 				JWT:   jwtServ,
 				Auth:  authServ,
 				Users: usersServ,
+				Audit: testAuditService(t),
 			})
 			require.NoErrorf(t, err, "failed to create handler: %s", err)
 
-			tc.mockBehavior(jwtServ)
+			tc.mockBehavior(jwtServ, authServ)
 
 			r := gin.New()
 			r.POST("/refresh-tokens", testLoggerMiddleware(t), h.refreshTokens)
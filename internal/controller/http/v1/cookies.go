@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/pkg/httpserver"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+const (
+	// authModeHeader set to "cookie" asks to keep issued tokens in cookies instead of response body
+	authModeHeader = "X-Auth-Mode"
+	csrfHeader     = "X-CSRF-Token"
+
+	accessTokenCookie  = "access_token"
+	refreshTokenCookie = "refresh_token"
+	// csrfTokenCookie is readable by scripts, so they can copy it into csrfHeader
+	csrfTokenCookie = "csrf_token"
+
+	accessTokenCookiePath  = "/api"
+	refreshTokenCookiePath = "/api/v1/auth/refresh-tokens"
+	csrfTokenCookiePath    = "/"
+
+	csrfTokenSize = 32
+)
+
+type authCookies struct {
+	enabled  bool
+	domain   string
+	sameSite http.SameSite
+	maxAge   int
+}
+
+func newAuthCookies(cfg httpserver.AuthCookiesConfig) (authCookies, error) {
+	var sameSite http.SameSite
+
+	switch strings.ToLower(cfg.SameSite) {
+	case "lax", "":
+		sameSite = http.SameSiteLaxMode
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	default:
+		return authCookies{}, fmt.Errorf("unknown same site mode %q", cfg.SameSite)
+	}
+
+	a := authCookies{
+		enabled:  cfg.Enabled,
+		domain:   cfg.Domain,
+		sameSite: sameSite,
+		maxAge:   int(cfg.MaxAge.Seconds()),
+	}
+
+	return a, nil
+}
+
+// requested reports whether client asked to keep tokens in cookies
+func (a authCookies) requested(c *gin.Context) bool {
+	return a.enabled && strings.EqualFold(c.GetHeader(authModeHeader), "cookie")
+}
+
+// accessToken returns access token from the cookie of the request without "Authorization" header
+func (a authCookies) accessToken(c *gin.Context) (string, bool) {
+	if !a.enabled || c.GetHeader("Authorization") != "" {
+		return "", false
+	}
+
+	accessToken, err := c.Cookie(accessTokenCookie)
+	if err != nil || accessToken == "" {
+		return "", false
+	}
+
+	return accessToken, true
+}
+
+// validCSRF implements double-submit check: state-changing request must repeat
+// value of the CSRF cookie in the header, which cross-site pages can't read
+func (a authCookies) validCSRF(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	csrfToken, err := c.Cookie(csrfTokenCookie)
+	if err != nil || csrfToken == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(c.GetHeader(csrfHeader)), []byte(csrfToken)) == 1
+}
+
+func (a authCookies) set(c *gin.Context, tokens entity.Tokens, csrfToken string) {
+	a.setCookie(c, accessTokenCookie, tokens.AccessToken, accessTokenCookiePath, a.maxAge, true)
+	a.setCookie(c, refreshTokenCookie, tokens.RefreshToken, refreshTokenCookiePath, a.maxAge, true)
+	a.setCookie(c, csrfTokenCookie, csrfToken, csrfTokenCookiePath, a.maxAge, false)
+}
+
+func (a authCookies) clear(c *gin.Context) {
+	a.setCookie(c, accessTokenCookie, "", accessTokenCookiePath, -1, true)
+	a.setCookie(c, refreshTokenCookie, "", refreshTokenCookiePath, -1, true)
+	a.setCookie(c, csrfTokenCookie, "", csrfTokenCookiePath, -1, false)
+}
+
+func (a authCookies) setCookie(c *gin.Context, name, value, path string, maxAge int, httpOnly bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   a.domain,
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: httpOnly,
+		SameSite: a.sameSite,
+	})
+}
+
+// tokensResponse returns issued tokens in response body or, in cookie mode,
+// sets them in HttpOnly cookies together with the new CSRF token
+func (h *Handler) tokensResponse(c *gin.Context, tokens entity.Tokens) {
+	if !h.cookies.requested(c) {
+		c.JSON(http.StatusOK, tokens)
+		return
+	}
+
+	buf := make([]byte, csrfTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		logger := log.LoggerFromContext(c.Request.Context())
+		logger.Error("failed to generate csrf token", zap.Error(err))
+		internalErrorResponse(c)
+
+		return
+	}
+
+	h.cookies.set(c, tokens, hex.EncodeToString(buf))
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,290 @@
+package v1
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+	"github.com/kenplix/url-shrtnr/pkg/httpserver"
+	"github.com/kenplix/url-shrtnr/pkg/token"
+)
+
+func testAuthCookies(t *testing.T) authCookies {
+	t.Helper()
+
+	cookies, err := newAuthCookies(httpserver.AuthCookiesConfig{
+		Enabled:  true,
+		SameSite: "strict",
+		MaxAge:   time.Hour,
+	})
+	require.NoErrorf(t, err, "failed to create auth cookies: %s", err)
+
+	return cookies
+}
+
+func TestHandler_TokensResponse(t *testing.T) {
+	type args struct {
+		enabled  bool
+		authMode string
+	}
+
+	type ret struct {
+		statusCode int
+		cookies    []string
+	}
+
+	tokens := entity.Tokens{
+		AccessToken:  "<access-token>",
+		RefreshToken: "<refresh token>",
+	}
+
+	testCases := []struct {
+		name string
+		args args
+		ret  ret
+	}{
+		{
+			name: "cookie mode not requested",
+			args: args{
+				enabled: true,
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+			},
+		},
+		{
+			name: "cookie mode disabled",
+			args: args{
+				authMode: "cookie",
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+			},
+		},
+		{
+			name: "cookie mode",
+			args: args{
+				enabled:  true,
+				authMode: "cookie",
+			},
+			ret: ret{
+				statusCode: http.StatusNoContent,
+				cookies:    []string{accessTokenCookie, refreshTokenCookie, csrfTokenCookie},
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := NewHandler(testLogger(t), &service.Services{})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			h.cookies = testAuthCookies(t)
+			h.cookies.enabled = tc.args.enabled
+
+			r := gin.New()
+			r.POST("/sign-in", testLoggerMiddleware(t), func(c *gin.Context) {
+				h.tokensResponse(c, tokens)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/sign-in", http.NoBody)
+			req.Header.Set(authModeHeader, tc.args.authMode)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			require.Equal(t, tc.ret.statusCode, resp.StatusCode)
+
+			if tc.ret.cookies == nil {
+				assert.Equal(t, mustMarshal(t, tokens), string(body))
+				assert.Empty(t, resp.Cookies())
+
+				return
+			}
+
+			assert.Empty(t, body, "tokens must not be exposed to scripts")
+
+			cookies := make(map[string]*http.Cookie)
+			for _, cookie := range resp.Cookies() {
+				cookies[cookie.Name] = cookie
+			}
+
+			require.Len(t, cookies, len(tc.ret.cookies))
+
+			for _, name := range tc.ret.cookies {
+				require.Contains(t, cookies, name)
+
+				cookie := cookies[name]
+				assert.NotEmpty(t, cookie.Value)
+				assert.Equal(t, 3600, cookie.MaxAge)
+				assert.True(t, cookie.Secure)
+				assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+				assert.Equal(t, name != csrfTokenCookie, cookie.HttpOnly)
+			}
+
+			assert.Equal(t, tokens.AccessToken, cookies[accessTokenCookie].Value)
+			assert.Equal(t, accessTokenCookiePath, cookies[accessTokenCookie].Path)
+			assert.Equal(t, tokens.RefreshToken, cookies[refreshTokenCookie].Value)
+			assert.Equal(t, refreshTokenCookiePath, cookies[refreshTokenCookie].Path)
+		})
+	}
+}
+
+func TestUserIdentityMiddleware_Cookie(t *testing.T) {
+	type args struct {
+		method     string
+		authHeader string
+		cookies    map[string]string
+		csrfHeader string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	type mockBehavior func(*servMocks.UsersService, *servMocks.JWTService)
+
+	testCSRFResponse := mustMarshal(t, errResponse{Errors: []apiError{newCSRFError()}})
+
+	authenticated := func(usersServ *servMocks.UsersService, jwtServ *servMocks.JWTService) {
+		jwtServ.
+			On("ParseAccessToken", "<access-token>").
+			Return(&token.JWTCustomClaims{
+				StandardClaims: jwt.StandardClaims{Subject: "63a6a9b1f1e0a2c3d4e5f607"},
+			}, nil)
+
+		jwtServ.
+			On("ValidateAccessToken", mock.Anything, mock.Anything).
+			Return(nil)
+
+		usersServ.
+			On("GetByID", mock.Anything, mock.Anything).
+			Return(entity.User{}, nil)
+	}
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "safe method without csrf token",
+			args: args{
+				method:  http.MethodGet,
+				cookies: map[string]string{accessTokenCookie: "<access-token>"},
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+			},
+			mockBehavior: authenticated,
+		},
+		{
+			name: "missing csrf token",
+			args: args{
+				method:  http.MethodPost,
+				cookies: map[string]string{accessTokenCookie: "<access-token>", csrfTokenCookie: "<csrf token>"},
+			},
+			ret: ret{
+				statusCode:   http.StatusForbidden,
+				responseBody: testCSRFResponse,
+			},
+			mockBehavior: func(_ *servMocks.UsersService, _ *servMocks.JWTService) {},
+		},
+		{
+			name: "mismatched csrf token",
+			args: args{
+				method:     http.MethodPost,
+				cookies:    map[string]string{accessTokenCookie: "<access-token>", csrfTokenCookie: "<csrf token>"},
+				csrfHeader: "<another csrf token>",
+			},
+			ret: ret{
+				statusCode:   http.StatusForbidden,
+				responseBody: testCSRFResponse,
+			},
+			mockBehavior: func(_ *servMocks.UsersService, _ *servMocks.JWTService) {},
+		},
+		{
+			name: "matched csrf token",
+			args: args{
+				method:     http.MethodPost,
+				cookies:    map[string]string{accessTokenCookie: "<access-token>", csrfTokenCookie: "<csrf token>"},
+				csrfHeader: "<csrf token>",
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+			},
+			mockBehavior: authenticated,
+		},
+		{
+			name: `"Authorization" header takes precedence without csrf token`,
+			args: args{
+				method:     http.MethodPost,
+				authHeader: "Bearer <access-token>",
+				cookies:    map[string]string{accessTokenCookie: "<cookie-access-token>"},
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+			},
+			mockBehavior: authenticated,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				jwtServ   = servMocks.NewJWTService(t)
+				usersServ = servMocks.NewUsersService(t)
+			)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				JWT:   jwtServ,
+				Users: usersServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			h.cookies = testAuthCookies(t)
+
+			tc.mockBehavior(usersServ, jwtServ)
+
+			r := gin.New()
+			r.Handle(tc.args.method, "/protected", testLoggerMiddleware(t), h.userIdentityMiddleware)
+
+			req := httptest.NewRequest(tc.args.method, "/protected", http.NoBody)
+			req.Header.Set("Authorization", tc.args.authHeader)
+			req.Header.Set(csrfHeader, tc.args.csrfHeader)
+
+			for name, value := range tc.args.cookies {
+				req.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+		})
+	}
+}
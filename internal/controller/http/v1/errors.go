@@ -3,8 +3,11 @@ package v1
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kenplix/url-shrtnr/internal/controller/http/ginctx"
 
@@ -70,6 +73,61 @@ func newUnauthorizedError() *entity.CoreError {
 	}
 }
 
+func insufficientScopeErrorResponse(c *gin.Context, missing []string) {
+	errorResponse(c, http.StatusForbidden, newInsufficientScopeError(missing))
+}
+
+func newInsufficientScopeError(missing []string) *entity.CoreError {
+	return &entity.CoreError{
+		Code:    errorcode.InsufficientScope,
+		Message: fmt.Sprintf("access token lacks required scopes: %s", strings.Join(missing, ", ")),
+	}
+}
+
+func insufficientRoleErrorResponse(c *gin.Context, role string) {
+	errorResponse(c, http.StatusForbidden, &entity.CoreError{
+		Code:    errorcode.InsufficientRole,
+		Message: fmt.Sprintf("%s role required", role),
+	})
+}
+
+func csrfErrorResponse(c *gin.Context) {
+	errorResponse(c, http.StatusForbidden, newCSRFError())
+}
+
+func newCSRFError() *entity.CoreError {
+	return &entity.CoreError{
+		Code:    errorcode.CSRFTokenMismatch,
+		Message: "csrf token is missing or invalid",
+	}
+}
+
+func tooManyAttemptsErrorResponse(c *gin.Context, retryAfter time.Duration) {
+	c.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+	errorResponse(c, http.StatusTooManyRequests, newTooManyAttemptsError())
+}
+
+func newTooManyAttemptsError() *entity.CoreError {
+	return &entity.CoreError{
+		Code:    errorcode.TooManyAttempts,
+		Message: "too many failed attempts, try again later",
+	}
+}
+
+func newIncorrectOTPError() *entity.CoreError {
+	return &entity.CoreError{
+		Code:    errorcode.IncorrectOTP,
+		Message: entity.ErrIncorrectTwoFactorCode.Error(),
+	}
+}
+
+func versionConflictErrorResponse(c *gin.Context) {
+	errorResponse(c, http.StatusConflict, &entity.CoreError{
+		Code:    errorcode.VersionConflict,
+		Message: entity.ErrUserVersionConflict.Error(),
+	})
+}
+
 func internalErrorResponse(c *gin.Context) {
 	errorResponse(c, http.StatusInternalServerError, newInternalError())
 }
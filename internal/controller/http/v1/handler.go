@@ -6,11 +6,17 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/httpserver"
 )
 
 type Handler struct {
 	services *service.Services
 	logger   *zap.Logger
+	cookies  authCookies
+}
+
+type Config struct {
+	AuthCookies httpserver.AuthCookiesConfig
 }
 
 func NewHandler(logger *zap.Logger, services *service.Services) (*Handler, error) {
@@ -30,9 +36,20 @@ func NewHandler(logger *zap.Logger, services *service.Services) (*Handler, error
 	return h, nil
 }
 
-func (h *Handler) InitRoutes(api *gin.RouterGroup) {
+func (h *Handler) InitRoutes(api *gin.RouterGroup, cfg Config) error {
+	cookies, err := newAuthCookies(cfg.AuthCookies)
+	if err != nil {
+		return errors.Wrap(err, "invalid auth cookies config")
+	}
+
+	h.cookies = cookies
+
 	v1 := api.Group("/v1")
 
 	h.initAuthRoutes(v1)
 	h.initUsersRoutes(v1)
+	h.initAdminRoutes(v1)
+	h.initPublicProfileRoutes(v1)
+
+	return nil
 }
@@ -7,15 +7,18 @@ import (
 	"github.com/kenplix/url-shrtnr/internal/entity"
 	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/kenplix/url-shrtnr/internal/controller/http/validator"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
 
 	"go.uber.org/zap"
 
 	"github.com/kenplix/url-shrtnr/pkg/log"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func init() {
@@ -38,6 +41,27 @@ func testLoggerMiddleware(t *testing.T) gin.HandlerFunc {
 	}
 }
 
+func testUserMiddleware(t *testing.T) gin.HandlerFunc {
+	t.Helper()
+
+	return func(c *gin.Context) {
+		c.Set(userContext, entity.User{ID: primitive.NewObjectID()})
+	}
+}
+
+// testAuditService accepts any events, it is used by tests which don't check auditing
+func testAuditService(t *testing.T) *servMocks.AuditService {
+	t.Helper()
+
+	auditServ := servMocks.NewAuditService(t)
+	auditServ.
+		On("Record", mock.Anything, mock.Anything).
+		Return(nil).
+		Maybe()
+
+	return auditServ
+}
+
 func testLogger(t *testing.T) *zap.Logger {
 	t.Helper()
 
@@ -0,0 +1,178 @@
+package v1
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+const (
+	// magicLinkCookie binds requested link to the browser
+	magicLinkCookie     = "magic_link_nonce"
+	magicLinkCookiePath = "/api/v1/auth/magic-link"
+)
+
+func (h *Handler) initMagicLinkRoutes(auth *gin.RouterGroup) {
+	magicLink := auth.Group("/magic-link")
+
+	magicLink.POST("", h.requestMagicLink)
+	magicLink.POST("/sign-in", h.signInMagicLink)
+}
+
+type magicLinkRequestSchema struct {
+	Email string `json:"email" binding:"required,email" example:"tolstoi.job@gmail.com"`
+}
+
+// requestMagicLink handler sends sign in link to the user email
+//
+//	@Summary		Request magic link
+//	@Tags			auth
+//	@Description	Sends single-use sign in link to the email. Response is the same whether user exists or not.
+//	@Description	Link works only in the browser which requested it, so the cookie set by this request must be kept
+//	@Accept			json
+//	@Produce		json
+//	@Param			schema	body	magicLinkRequestSchema	true	"JSON schema for magic link request"
+//	@Success		202		"Link was sent if user with provided email exists"
+//	@Header			202		{string}	Set-Cookie										"Nonce binding link to the browser"
+//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		429		{object}	errResponse{errors=[]entity.CoreError}			"Too many attempts"
+//	@Header			429		{integer}	Retry-After										"Seconds to wait before the next attempt"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/auth/magic-link [post]
+func (h *Handler) requestMagicLink(c *gin.Context) {
+	var schema magicLinkRequestSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	binding, err := h.services.MagicLink.Request(reqctx, service.MagicLinkRequestSchema{
+		Email: strings.ToLower(schema.Email),
+		IP:    c.ClientIP(),
+	})
+	if err != nil {
+		var tooManyAttemptsError *entity.TooManyAttemptsError
+		if errors.As(err, &tooManyAttemptsError) {
+			logger.Warn("magic link requests are throttled",
+				zap.Duration("retryAfter", tooManyAttemptsError.RetryAfter),
+			)
+			tooManyAttemptsErrorResponse(c, tooManyAttemptsError.RetryAfter)
+
+			return
+		}
+
+		logger.Error("failed to request magic link", zap.Error(err))
+		internalErrorResponse(c)
+
+		return
+	}
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(magicLinkCookie, binding.Nonce, int(binding.TTL.Seconds()), magicLinkCookiePath, "", true, true)
+	c.Status(http.StatusAccepted)
+}
+
+type magicLinkSignInSchema struct {
+	Token string `json:"token" binding:"required,max=128" example:"9b2f0c4e7a1d4c8e9f3b6a5d2e1c0b7a9b2f0c4e7a1d4c8e9f3b6a5d2e1c0b7a"`
+}
+
+// signInMagicLink handler exchanges magic link for tokens pair
+//
+//	@Summary		Sign in with magic link
+//	@Tags			auth
+//	@Description	Exchanges token from the magic link for tokens pair. Must be called from the browser which requested the link
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Auth-Mode	header		string					false	"Set to cookie to receive tokens in HttpOnly cookies"	Enums(cookie)
+//	@Param			schema		body		magicLinkSignInSchema	true	"JSON schema for sign in with magic link"
+//	@Success		200			{object}	entity.Tokens			"User was successfully signed in"
+//	@Success		204			"Tokens were set in cookies"
+//	@Success		202			{object}	entity.MFAChallenge								"Second authentication factor required"
+//	@Failure		400			{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		403			{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended"
+//	@Failure		422			{object}	errResponse{errors=[]entity.ValidationError}	"Link expired, already used or requested from another browser"
+//	@Failure		429			{object}	errResponse{errors=[]entity.CoreError}			"Too many failed attempts"
+//	@Header			429			{integer}	Retry-After										"Seconds to wait before the next attempt"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/auth/magic-link/sign-in [post]
+func (h *Handler) signInMagicLink(c *gin.Context) {
+	var schema magicLinkSignInSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	// missing cookie is treated as nonce mismatch
+	nonce, _ := c.Cookie(magicLinkCookie)
+
+	tokens, err := h.services.MagicLink.SignIn(reqctx, service.MagicLinkSignInSchema{
+		Token: schema.Token,
+		Nonce: nonce,
+		IP:    c.ClientIP(),
+	})
+	if err != nil {
+		var tooManyAttemptsError *entity.TooManyAttemptsError
+		if errors.As(err, &tooManyAttemptsError) {
+			logger.Warn("sign in attempts are throttled",
+				zap.Duration("retryAfter", tooManyAttemptsError.RetryAfter),
+			)
+			tooManyAttemptsErrorResponse(c, tooManyAttemptsError.RetryAfter)
+
+			return
+		}
+
+		if errors.Is(err, entity.ErrMagicLinkNotFound) {
+			logger.Warn("failed to sign in with magic link", zap.Error(err))
+			errorResponse(c, http.StatusUnprocessableEntity, &entity.CoreError{
+				Code:    errorcode.MagicLinkExpired,
+				Message: entity.ErrMagicLinkNotFound.Error(),
+			})
+
+			return
+		}
+
+		var suspUserError *entity.SuspendedUserError
+		if errors.As(err, &suspUserError) {
+			logger.Debug("suspended user tries to sign in",
+				zap.String("userID", suspUserError.UserID),
+			)
+			suspendedErrorResponse(c)
+
+			return
+		}
+
+		var mfaRequiredError *entity.MFARequiredError
+		if errors.As(err, &mfaRequiredError) {
+			logger.Debug("second authentication factor required",
+				zap.String("userID", mfaRequiredError.UserID),
+			)
+			c.SetCookie(magicLinkCookie, "", -1, magicLinkCookiePath, "", true, true)
+			c.JSON(http.StatusAccepted, mfaRequiredError.Challenge)
+
+			return
+		}
+
+		logger.Error("failed to sign in with magic link", zap.Error(err))
+		internalErrorResponse(c)
+
+		return
+	}
+
+	c.SetCookie(magicLinkCookie, "", -1, magicLinkCookiePath, "", true, true)
+	h.tokensResponse(c, tokens)
+}
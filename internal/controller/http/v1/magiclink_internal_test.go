@@ -0,0 +1,271 @@
+package v1
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+)
+
+func TestHandler_RequestMagicLink(t *testing.T) {
+	type args struct {
+		requestBody string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+		cookie       string
+	}
+
+	type mockBehavior func(*servMocks.MagicLinkService)
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "too many attempts",
+			args: args{
+				requestBody: `{"email":"tolstoi.job@gmail.com"}`,
+			},
+			ret: ret{
+				statusCode:   http.StatusTooManyRequests,
+				responseBody: mustMarshal(t, errResponse{Errors: []apiError{newTooManyAttemptsError()}}),
+			},
+			mockBehavior: func(magicLinkServ *servMocks.MagicLinkService) {
+				magicLinkServ.
+					On("Request", mock.Anything, mock.Anything).
+					Return(service.MagicLinkBinding{}, &entity.TooManyAttemptsError{RetryAfter: time.Minute})
+			},
+		},
+		{
+			name: "internal error",
+			args: args{
+				requestBody: `{"email":"tolstoi.job@gmail.com"}`,
+			},
+			ret: ret{
+				statusCode:   http.StatusInternalServerError,
+				responseBody: testInternalErrorResponse(t),
+			},
+			mockBehavior: func(magicLinkServ *servMocks.MagicLinkService) {
+				magicLinkServ.
+					On("Request", mock.Anything, mock.Anything).
+					Return(service.MagicLinkBinding{}, assert.AnError)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				requestBody: `{"email":"Tolstoi.Job@gmail.com"}`,
+			},
+			ret: ret{
+				statusCode: http.StatusAccepted,
+				cookie:     "4f1c2c7e0d2b4a6f",
+			},
+			mockBehavior: func(magicLinkServ *servMocks.MagicLinkService) {
+				magicLinkServ.
+					On("Request", mock.Anything, service.MagicLinkRequestSchema{
+						Email: "tolstoi.job@gmail.com",
+						IP:    "192.0.2.1",
+					}).
+					Return(service.MagicLinkBinding{Nonce: "4f1c2c7e0d2b4a6f", TTL: 15 * time.Minute}, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			magicLinkServ := servMocks.NewMagicLinkService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				MagicLink: magicLinkServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(magicLinkServ)
+
+			r := gin.New()
+			r.POST("/magic-link", testLoggerMiddleware(t), h.requestMagicLink)
+
+			req := httptest.NewRequest(http.MethodPost, "/magic-link", bytes.NewBufferString(tc.args.requestBody))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+
+			if tc.ret.cookie == "" {
+				assert.Empty(t, resp.Cookies())
+				return
+			}
+
+			require.Len(t, resp.Cookies(), 1)
+
+			cookie := resp.Cookies()[0]
+			assert.Equal(t, magicLinkCookie, cookie.Name)
+			assert.Equal(t, tc.ret.cookie, cookie.Value)
+			assert.Equal(t, magicLinkCookiePath, cookie.Path)
+			assert.Equal(t, 900, cookie.MaxAge)
+			assert.True(t, cookie.HttpOnly)
+			assert.True(t, cookie.Secure)
+			assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+		})
+	}
+}
+
+func TestHandler_SignInMagicLink(t *testing.T) {
+	type args struct {
+		requestBody string
+		nonce       string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	type mockBehavior func(*servMocks.MagicLinkService)
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "link expired",
+			args: args{
+				requestBody: `{"token":"<token>"}`,
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.MagicLinkExpired,
+							Message: entity.ErrMagicLinkNotFound.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(magicLinkServ *servMocks.MagicLinkService) {
+				magicLinkServ.
+					On("SignIn", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, entity.ErrMagicLinkNotFound)
+			},
+		},
+		{
+			name: "suspended user",
+			args: args{
+				requestBody: `{"token":"<token>"}`,
+				nonce:       "4f1c2c7e0d2b4a6f",
+			},
+			ret: ret{
+				statusCode:   http.StatusForbidden,
+				responseBody: testSuspendedErrorResponse(t),
+			},
+			mockBehavior: func(magicLinkServ *servMocks.MagicLinkService) {
+				magicLinkServ.
+					On("SignIn", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, &entity.SuspendedUserError{UserID: "<user id>"})
+			},
+		},
+		{
+			name: "second authentication factor required",
+			args: args{
+				requestBody: `{"token":"<token>"}`,
+				nonce:       "4f1c2c7e0d2b4a6f",
+			},
+			ret: ret{
+				statusCode:   http.StatusAccepted,
+				responseBody: mustMarshal(t, entity.MFAChallenge{MFAToken: "<mfa token>", ExpiresIn: 300}),
+			},
+			mockBehavior: func(magicLinkServ *servMocks.MagicLinkService) {
+				magicLinkServ.
+					On("SignIn", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, &entity.MFARequiredError{
+						UserID:    "<user id>",
+						Challenge: entity.MFAChallenge{MFAToken: "<mfa token>", ExpiresIn: 300},
+					})
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				requestBody: `{"token":"<token>"}`,
+				nonce:       "4f1c2c7e0d2b4a6f",
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+				responseBody: mustMarshal(t, entity.Tokens{
+					AccessToken:  "<access token>",
+					RefreshToken: "<refresh token>",
+				}),
+			},
+			mockBehavior: func(magicLinkServ *servMocks.MagicLinkService) {
+				magicLinkServ.
+					On("SignIn", mock.Anything, service.MagicLinkSignInSchema{
+						Token: "<token>",
+						Nonce: "4f1c2c7e0d2b4a6f",
+						IP:    "192.0.2.1",
+					}).
+					Return(entity.Tokens{
+						AccessToken:  "<access token>",
+						RefreshToken: "<refresh token>",
+					}, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			magicLinkServ := servMocks.NewMagicLinkService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				MagicLink: magicLinkServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(magicLinkServ)
+
+			r := gin.New()
+			r.POST("/magic-link/sign-in", testLoggerMiddleware(t), h.signInMagicLink)
+
+			req := httptest.NewRequest(http.MethodPost, "/magic-link/sign-in", bytes.NewBufferString(tc.args.requestBody))
+			if tc.args.nonce != "" {
+				req.AddCookie(&http.Cookie{Name: magicLinkCookie, Value: tc.args.nonce})
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+		})
+	}
+}
@@ -7,28 +7,52 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/pkg/token"
 )
 
-const userContext = "user"
+const (
+	userContext   = "user"
+	scopesContext = "scopes"
+	apiKeyContext = "apiKey"
+)
 
 func (h *Handler) userIdentityMiddleware(c *gin.Context) {
+	if apiKey, ok := parseAPIKey(c); ok {
+		h.apiKeyIdentity(c, apiKey)
+		return
+	}
+
 	reqctx := c.Request.Context()
 	logger := log.LoggerFromContext(reqctx)
 
-	accessToken, err := parseAuthorizationHeader(c)
-	if err != nil {
-		logger.Warn("failed to parse header",
-			zap.String("header", "Authorization"),
-			zap.Error(err),
-		)
-		unauthorizedErrorResponse(c)
+	accessToken, fromCookie := h.cookies.accessToken(c)
+	if fromCookie {
+		// cookies are sent by browser automatically, so cross-site requests must be rejected
+		if !h.cookies.validCSRF(c) {
+			logger.Warn("cookie authenticated request with invalid csrf token")
+			csrfErrorResponse(c)
 
-		return
+			return
+		}
+	} else {
+		var err error
+
+		accessToken, err = parseAuthorizationHeader(c)
+		if err != nil {
+			logger.Warn("failed to parse header",
+				zap.String("header", "Authorization"),
+				zap.Error(err),
+			)
+			unauthorizedErrorResponse(c)
+
+			return
+		}
 	}
 
 	claims, err := h.services.JWT.ParseAccessToken(accessToken)
@@ -86,7 +110,7 @@ func (h *Handler) userIdentityMiddleware(c *gin.Context) {
 		return nil
 	})
 
-	if err = g.Wait(); err != nil {
+	if err := g.Wait(); err != nil {
 		var suspUserError *entity.SuspendedUserError
 		if errors.As(err, &suspUserError) {
 			suspendedErrorResponse(c)
@@ -99,6 +123,107 @@ func (h *Handler) userIdentityMiddleware(c *gin.Context) {
 	}
 
 	c.Set(userContext, user)
+	c.Set(scopesContext, tokenScopes(claims))
+}
+
+// apiKeyIdentity authenticates request made with API key instead of access token.
+// Request is granted only scopes of the key
+func (h *Handler) apiKeyIdentity(c *gin.Context, apiKey string) {
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	key, err := h.services.APIKeys.Authenticate(reqctx, apiKey)
+	if err != nil {
+		logger.Warn("failed to authenticate api key", zap.Error(err))
+		unauthorizedErrorResponse(c)
+
+		return
+	}
+
+	user, err := h.services.Users.GetByID(reqctx, key.UserID)
+	if err != nil {
+		logger.Warn("failed to get user",
+			zap.String("userID", key.UserID.Hex()),
+			zap.Error(err),
+		)
+		unauthorizedErrorResponse(c)
+
+		return
+	} else if user.SuspendedAt != nil {
+		logger.Warn("protected route request from suspended user",
+			zap.String("userID", user.ID.Hex()),
+		)
+		suspendedErrorResponse(c)
+
+		return
+	}
+
+	c.Set(userContext, user)
+	c.Set(scopesContext, key.Scopes)
+	c.Set(apiKeyContext, key.Filter())
+}
+
+// tokenScopes returns scopes granted by the token. Tokens issued
+// before scopes were introduced are granted the default ones
+func tokenScopes(claims *token.JWTCustomClaims) []string {
+	if claims.Scopes == nil {
+		return entity.DefaultScopes
+	}
+
+	return claims.Scopes
+}
+
+// requireScopes aborts request if access token doesn't grant all provided scopes.
+// It must be used after userIdentityMiddleware
+func (h *Handler) requireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := c.GetStringSlice(scopesContext)
+
+		if missing, _ := lo.Difference(scopes, granted); len(missing) != 0 {
+			logger := log.LoggerFromContext(c.Request.Context())
+			logger.Warn("access token lacks required scopes",
+				zap.Strings("required", scopes),
+				zap.Strings("granted", granted),
+			)
+			insufficientScopeErrorResponse(c, missing)
+
+			return
+		}
+	}
+}
+
+// requireRole aborts request if user doesn't have provided role.
+// It must be used after userIdentityMiddleware
+func (h *Handler) requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet(userContext).(entity.User)
+
+		if user.Role != role {
+			logger := log.LoggerFromContext(c.Request.Context())
+			logger.Warn("user lacks required role",
+				zap.String("userID", user.ID.Hex()),
+				zap.String("required", role),
+				zap.String("role", user.Role),
+			)
+			insufficientRoleErrorResponse(c, role)
+
+			return
+		}
+	}
+}
+
+// parseAPIKey returns API key sent in "X-API-Key" or "Authorization: ApiKey <key>" header
+func parseAPIKey(c *gin.Context) (string, bool) {
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		return apiKey, true
+	}
+
+	headerParts := strings.Fields(c.GetHeader("Authorization"))
+	if len(headerParts) == 2 && headerParts[0] == "ApiKey" {
+		return headerParts[1], true
+	}
+
+	return "", false
 }
 
 func parseAuthorizationHeader(c *gin.Context) (string, error) {
@@ -116,7 +241,12 @@ func parseAuthorizationHeader(c *gin.Context) (string, error) {
 }
 
 func (h *Handler) userActivityMiddleware(c *gin.Context) {
+	// requests made with API keys don't belong to any session
+	if _, ok := c.Get(apiKeyContext); ok {
+		return
+	}
+
 	user := c.MustGet(userContext).(entity.User)
 
-	go h.services.JWT.ProlongTokens(c.Request.Context(), user.ID.Hex())
+	h.services.JWT.ProlongTokens(c.Request.Context(), user.ID.Hex())
 }
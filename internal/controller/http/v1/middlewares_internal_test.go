@@ -278,3 +278,281 @@ func TestUserIdentityMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireScopes(t *testing.T) {
+	type args struct {
+		granted  []string
+		required []string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	testCases := []struct {
+		name string
+		args args
+		ret  ret
+	}{
+		{
+			name: "no scopes required",
+			args: args{
+				granted:  []string{entity.ScopeLinksRead},
+				required: nil,
+			},
+			ret: ret{
+				statusCode:   http.StatusOK,
+				responseBody: "",
+			},
+		},
+		{
+			name: "missing scopes",
+			args: args{
+				granted:  []string{entity.ScopeLinksRead},
+				required: []string{entity.ScopeLinksRead, entity.ScopeLinksWrite, entity.ScopeAdmin},
+			},
+			ret: ret{
+				statusCode: http.StatusForbidden,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						newInsufficientScopeError([]string{entity.ScopeLinksWrite, entity.ScopeAdmin}),
+					},
+				}),
+			},
+		},
+		{
+			name: "token issued before scopes were introduced",
+			args: args{
+				granted:  nil,
+				required: []string{entity.ScopeAccount},
+			},
+			ret: ret{
+				statusCode:   http.StatusOK,
+				responseBody: "",
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				granted:  []string{entity.ScopeLinksRead, entity.ScopeLinksWrite},
+				required: []string{entity.ScopeLinksWrite},
+			},
+			ret: ret{
+				statusCode:   http.StatusOK,
+				responseBody: "",
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				jwtServ   = servMocks.NewJWTService(t)
+				authServ  = servMocks.NewAuthService(t)
+				usersServ = servMocks.NewUsersService(t)
+			)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				JWT:   jwtServ,
+				Auth:  authServ,
+				Users: usersServ,
+			})
+			require.NoError(t, err, "failed to create handler: %s", err)
+
+			userID := primitive.NewObjectID()
+
+			jwtServ.
+				On("ParseAccessToken", mock.Anything).
+				Return(
+					&token.JWTCustomClaims{
+						Scopes: tc.args.granted,
+						StandardClaims: jwt.StandardClaims{
+							Subject: userID.Hex(),
+						},
+					},
+					nil,
+				)
+
+			jwtServ.
+				On("ValidateAccessToken", mock.Anything, mock.Anything).
+				Return(nil)
+
+			usersServ.
+				On("GetByID", mock.Anything, mock.Anything).
+				Return(entity.User{ID: userID}, nil)
+
+			r := gin.New()
+			r.POST("/protected",
+				testLoggerMiddleware(t),
+				h.userIdentityMiddleware,
+				h.requireScopes(tc.args.required...),
+			)
+
+			req := httptest.NewRequest(http.MethodPost, "/protected", bytes.NewBufferString(""))
+			req.Header.Set("Authorization", "Bearer <token>")
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+		})
+	}
+}
+
+func TestUserIdentityMiddleware_APIKey(t *testing.T) {
+	type args struct {
+		header string
+		value  string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+		scopes       []string
+	}
+
+	type mockBehavior func(*servMocks.UsersService, *servMocks.APIKeysService)
+
+	testAPIKey := func(t *testing.T) entity.APIKeyModel {
+		t.Helper()
+
+		return entity.APIKeyModel{
+			ID:     primitive.NewObjectID(),
+			UserID: primitive.NewObjectID(),
+			Scopes: []string{entity.ScopeLinksRead},
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "unknown api key",
+			args: args{
+				header: "X-API-Key",
+				value:  "<api-key>",
+			},
+			ret: ret{
+				statusCode:   http.StatusUnauthorized,
+				responseBody: testUnauthorizedErrorResponse(t),
+			},
+			mockBehavior: func(_ *servMocks.UsersService, apiKeysServ *servMocks.APIKeysService) {
+				apiKeysServ.
+					On("Authenticate", mock.Anything, "<api-key>").
+					Return(entity.APIKeyModel{}, entity.ErrAPIKeyNotFound)
+			},
+		},
+		{
+			name: "suspended user",
+			args: args{
+				header: "X-API-Key",
+				value:  "<api-key>",
+			},
+			ret: ret{
+				statusCode:   http.StatusForbidden,
+				responseBody: testSuspendedErrorResponse(t),
+			},
+			mockBehavior: func(usersServ *servMocks.UsersService, apiKeysServ *servMocks.APIKeysService) {
+				apiKeysServ.
+					On("Authenticate", mock.Anything, "<api-key>").
+					Return(testAPIKey(t), nil)
+
+				suspendedAt := time.Now()
+				usersServ.
+					On("GetByID", mock.Anything, mock.Anything).
+					Return(entity.User{SuspendedAt: &suspendedAt}, nil)
+			},
+		},
+		{
+			name: "ok with X-API-Key header",
+			args: args{
+				header: "X-API-Key",
+				value:  "<api-key>",
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+				scopes:     []string{entity.ScopeLinksRead},
+			},
+			mockBehavior: func(usersServ *servMocks.UsersService, apiKeysServ *servMocks.APIKeysService) {
+				apiKeysServ.
+					On("Authenticate", mock.Anything, "<api-key>").
+					Return(testAPIKey(t), nil)
+
+				usersServ.
+					On("GetByID", mock.Anything, mock.Anything).
+					Return(entity.User{}, nil)
+			},
+		},
+		{
+			name: `ok with "Authorization" header`,
+			args: args{
+				header: "Authorization",
+				value:  "ApiKey <api-key>",
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+				scopes:     []string{entity.ScopeLinksRead},
+			},
+			mockBehavior: func(usersServ *servMocks.UsersService, apiKeysServ *servMocks.APIKeysService) {
+				apiKeysServ.
+					On("Authenticate", mock.Anything, "<api-key>").
+					Return(testAPIKey(t), nil)
+
+				usersServ.
+					On("GetByID", mock.Anything, mock.Anything).
+					Return(entity.User{}, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				jwtServ     = servMocks.NewJWTService(t)
+				usersServ   = servMocks.NewUsersService(t)
+				apiKeysServ = servMocks.NewAPIKeysService(t)
+			)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				JWT:     jwtServ,
+				Users:   usersServ,
+				APIKeys: apiKeysServ,
+			})
+			require.NoError(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(usersServ, apiKeysServ)
+
+			var scopes []string
+
+			r := gin.New()
+			r.POST("/protected", testLoggerMiddleware(t), h.userIdentityMiddleware, h.userActivityMiddleware, func(c *gin.Context) {
+				scopes = c.GetStringSlice(scopesContext)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/protected", bytes.NewBufferString(""))
+			req.Header.Set(tc.args.header, tc.args.value)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+			assert.Equal(t, tc.ret.scopes, scopes)
+		})
+	}
+}
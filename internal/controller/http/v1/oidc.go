@@ -0,0 +1,242 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+const (
+	// oidcCookie binds authorization to the browser. Callback is a cross-site redirect
+	// of the provider, so cookie can't be strict
+	oidcCookie     = "oidc_nonce"
+	oidcCookiePath = "/api/v1/auth/oidc"
+)
+
+func (h *Handler) initOIDCRoutes(auth *gin.RouterGroup) {
+	oidc := auth.Group("/oidc")
+
+	oidc.GET("/:provider", h.oidcAuthorize)
+	oidc.GET("/:provider/callback", h.oidcCallback)
+}
+
+func (h *Handler) initOIDCLinkRoutes(users *gin.RouterGroup) {
+	users.POST("/oidc/:provider/link", h.oidcLink)
+}
+
+// oidcAuthorize handler starts sign in with identity provider
+//
+//	@Summary		Sign in with identity provider
+//	@Tags			auth
+//	@Description	Redirects to the consent page of OpenID Connect or OAuth2 provider which redirects back to the callback
+//	@Param			provider	path	string	true	"Identity provider name"	example(google)
+//	@Success		302			"Redirect to the provider consent page"
+//	@Header			302			{string}	Set-Cookie								"Nonce binding authorization to the browser"
+//	@Failure		404			{object}	errResponse{errors=[]entity.CoreError}	"Identity provider not found"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/auth/oidc/{provider} [get]
+func (h *Handler) oidcAuthorize(c *gin.Context) {
+	provider := c.Param("provider")
+
+	reqctx := c.Request.Context()
+
+	authorization, err := h.services.OIDC.Authorize(reqctx, service.OIDCAuthorizeSchema{
+		Provider: provider,
+	})
+	if err != nil {
+		oidcErrorResponse(c, provider, err)
+		return
+	}
+
+	setOIDCCookie(c, authorization)
+	c.Redirect(http.StatusFound, authorization.AuthURL)
+}
+
+// oidcLink handler starts linking of identity provider account to the signed in user
+//
+//	@Summary		Link identity provider account
+//	@Security		JWT-RS256
+//	@Tags			user
+//	@Description	Returns consent page of the provider, once user passes it provider account is linked and may be used for sign in.
+//	@Description	Consent page must be opened in the same browser, so the cookie set by this request must be kept
+//	@Produce		json
+//	@Param			provider	path		string									true	"Identity provider name"	example(google)
+//	@Success		200			{object}	entity.OIDCAuthorization				"Consent page of the provider"
+//	@Header			200			{string}	Set-Cookie								"Nonce binding authorization to the browser"
+//	@Failure		401			{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403			{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or access token lacks required scopes"
+//	@Failure		404			{object}	errResponse{errors=[]entity.CoreError}	"Identity provider not found"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/users/oidc/{provider}/link [post]
+func (h *Handler) oidcLink(c *gin.Context) {
+	user := c.MustGet(userContext).(entity.User)
+	provider := c.Param("provider")
+
+	reqctx := c.Request.Context()
+
+	authorization, err := h.services.OIDC.Authorize(reqctx, service.OIDCAuthorizeSchema{
+		Provider: provider,
+		UserID:   user.ID,
+	})
+	if err != nil {
+		oidcErrorResponse(c, provider, err)
+		return
+	}
+
+	setOIDCCookie(c, authorization)
+	c.JSON(http.StatusOK, entity.OIDCAuthorization{AuthURL: authorization.AuthURL})
+}
+
+func setOIDCCookie(c *gin.Context, authorization service.OIDCAuthorization) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcCookie, authorization.Nonce, int(authorization.TTL.Seconds()), oidcCookiePath, "", true, true)
+}
+
+type oidcCallbackSchema struct {
+	State string `form:"state" binding:"required"`
+	Code  string `form:"code"`
+	// Error is set by provider when user denied access or authorization failed
+	Error string `form:"error"`
+}
+
+// oidcCallback handler completes sign in with identity provider
+//
+//	@Summary		Identity provider callback
+//	@Tags			auth
+//	@Description	Completes sign in with identity provider. New user is created for not linked provider account.
+//	@Description	If email is already used by another user, that user must sign in and link provider account instead.
+//	@Description	Must be opened in the browser which started authorization
+//	@Produce		json
+//	@Param			provider	path		string											true	"Identity provider name"	example(google)
+//	@Param			state		query		string											true	"State of the authorization request"
+//	@Param			code		query		string											false	"Authorization code"
+//	@Param			error		query		string											false	"Authorization error"
+//	@Success		200			{object}	entity.Tokens									"User was successfully signed in"
+//	@Success		202			{object}	entity.MFAChallenge								"Second authentication factor required"
+//	@Failure		401			{object}	errResponse{errors=[]entity.CoreError}			"Identity provider authentication failed"
+//	@Failure		403			{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended"
+//	@Failure		404			{object}	errResponse{errors=[]entity.CoreError}			"Identity provider not found"
+//	@Failure		422			{object}	errResponse{errors=[]entity.ValidationError}	"Authorization expired, started in another browser, email not verified or in use, or identity linked to another user"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/auth/oidc/{provider}/callback [get]
+func (h *Handler) oidcCallback(c *gin.Context) {
+	var schema oidcCallbackSchema
+	if err := c.ShouldBindQuery(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	provider := c.Param("provider")
+
+	if schema.Error != "" || schema.Code == "" {
+		logger := log.LoggerFromContext(c.Request.Context())
+		logger.Warn("identity provider denied authorization",
+			zap.String("provider", provider),
+			zap.String("error", schema.Error),
+		)
+		oidcErrorResponse(c, provider, entity.ErrOIDCAuthenticationFailed)
+
+		return
+	}
+
+	reqctx := c.Request.Context()
+
+	// missing cookie is treated as nonce mismatch
+	nonce, _ := c.Cookie(oidcCookie)
+
+	tokens, err := h.services.OIDC.SignIn(reqctx, service.OIDCCallbackSchema{
+		Provider: provider,
+		State:    schema.State,
+		Code:     schema.Code,
+		Nonce:    nonce,
+	})
+
+	// nonce of the finished authorization isn't needed anymore
+	c.SetCookie(oidcCookie, "", -1, oidcCookiePath, "", true, true)
+
+	if err != nil {
+		oidcErrorResponse(c, provider, err)
+		return
+	}
+
+	h.tokensResponse(c, tokens)
+}
+
+func oidcErrorResponse(c *gin.Context, provider string, err error) {
+	logger := log.LoggerFromContext(c.Request.Context()).With(zap.String("provider", provider))
+
+	var (
+		status int
+		apiErr *entity.CoreError
+	)
+
+	switch {
+	case errors.Is(err, entity.ErrOIDCProviderNotFound):
+		status, apiErr = http.StatusNotFound, &entity.CoreError{
+			Code:    errorcode.IdentityProviderNotFound,
+			Message: entity.ErrOIDCProviderNotFound.Error(),
+		}
+	case errors.Is(err, entity.ErrOIDCAuthenticationFailed):
+		status, apiErr = http.StatusUnauthorized, &entity.CoreError{
+			Code:    errorcode.UnauthorizedAccess,
+			Message: entity.ErrOIDCAuthenticationFailed.Error(),
+		}
+	case errors.Is(err, entity.ErrOIDCStateNotFound):
+		status, apiErr = http.StatusUnprocessableEntity, &entity.CoreError{
+			Code:    errorcode.AuthorizationExpired,
+			Message: entity.ErrOIDCStateNotFound.Error(),
+		}
+	case errors.Is(err, entity.ErrOIDCEmailNotVerified):
+		status, apiErr = http.StatusUnprocessableEntity, &entity.CoreError{
+			Code:    errorcode.EmailNotVerified,
+			Message: entity.ErrOIDCEmailNotVerified.Error(),
+		}
+	case errors.Is(err, entity.ErrOIDCIdentityAlreadyLinked):
+		status, apiErr = http.StatusUnprocessableEntity, &entity.CoreError{
+			Code:    errorcode.IdentityAlreadyLinked,
+			Message: entity.ErrOIDCIdentityAlreadyLinked.Error(),
+		}
+	case errors.Is(err, entity.ErrOIDCEmailInUse):
+		status, apiErr = http.StatusUnprocessableEntity, &entity.CoreError{
+			Code:    errorcode.AlreadyExists,
+			Message: entity.ErrOIDCEmailInUse.Error(),
+		}
+	}
+
+	if apiErr != nil {
+		logger.Warn("failed to sign in with identity provider", zap.Error(err))
+		errorResponse(c, status, apiErr)
+
+		return
+	}
+
+	var suspUserError *entity.SuspendedUserError
+	if errors.As(err, &suspUserError) {
+		logger.Debug("suspended user tries to sign in",
+			zap.String("userID", suspUserError.UserID),
+		)
+		suspendedErrorResponse(c)
+
+		return
+	}
+
+	var mfaRequiredError *entity.MFARequiredError
+	if errors.As(err, &mfaRequiredError) {
+		logger.Debug("second authentication factor required",
+			zap.String("userID", mfaRequiredError.UserID),
+		)
+		c.JSON(http.StatusAccepted, mfaRequiredError.Challenge)
+
+		return
+	}
+
+	logger.Error("failed to sign in with identity provider", zap.Error(err))
+	internalErrorResponse(c)
+}
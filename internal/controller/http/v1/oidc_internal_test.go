@@ -0,0 +1,244 @@
+package v1
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+)
+
+func TestHandler_OIDCCallback(t *testing.T) {
+	type args struct {
+		query string
+		nonce string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	type mockBehavior func(*servMocks.OIDCService)
+
+	testAuthenticationFailedResponse := mustMarshal(t, errResponse{
+		Errors: []apiError{
+			&entity.CoreError{
+				Code:    errorcode.UnauthorizedAccess,
+				Message: entity.ErrOIDCAuthenticationFailed.Error(),
+			},
+		},
+	})
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "access denied by user",
+			args: args{
+				query: "?state=<state>&error=access_denied",
+			},
+			ret: ret{
+				statusCode:   http.StatusUnauthorized,
+				responseBody: testAuthenticationFailedResponse,
+			},
+			mockBehavior: func(_ *servMocks.OIDCService) {},
+		},
+		{
+			name: "provider not found",
+			args: args{
+				query: "?state=<state>&code=<code>",
+			},
+			ret: ret{
+				statusCode: http.StatusNotFound,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.IdentityProviderNotFound,
+							Message: entity.ErrOIDCProviderNotFound.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(oidcServ *servMocks.OIDCService) {
+				oidcServ.
+					On("SignIn", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, entity.ErrOIDCProviderNotFound)
+			},
+		},
+		{
+			name: "email not verified",
+			args: args{
+				query: "?state=<state>&code=<code>",
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.EmailNotVerified,
+							Message: entity.ErrOIDCEmailNotVerified.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(oidcServ *servMocks.OIDCService) {
+				oidcServ.
+					On("SignIn", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, entity.ErrOIDCEmailNotVerified)
+			},
+		},
+		{
+			name: "email in use",
+			args: args{
+				query: "?state=<state>&code=<code>",
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.AlreadyExists,
+							Message: entity.ErrOIDCEmailInUse.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(oidcServ *servMocks.OIDCService) {
+				oidcServ.
+					On("SignIn", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, entity.ErrOIDCEmailInUse)
+			},
+		},
+		{
+			name: "second authentication factor required",
+			args: args{
+				query: "?state=<state>&code=<code>",
+			},
+			ret: ret{
+				statusCode:   http.StatusAccepted,
+				responseBody: mustMarshal(t, entity.MFAChallenge{MFAToken: "<mfa token>", ExpiresIn: 300}),
+			},
+			mockBehavior: func(oidcServ *servMocks.OIDCService) {
+				oidcServ.
+					On("SignIn", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, &entity.MFARequiredError{
+						UserID:    "<user id>",
+						Challenge: entity.MFAChallenge{MFAToken: "<mfa token>", ExpiresIn: 300},
+					})
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				query: "?state=<state>&code=<code>",
+				nonce: "<nonce>",
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+				responseBody: mustMarshal(t, entity.Tokens{
+					AccessToken:  "<access token>",
+					RefreshToken: "<refresh token>",
+				}),
+			},
+			mockBehavior: func(oidcServ *servMocks.OIDCService) {
+				oidcServ.
+					On("SignIn", mock.Anything, service.OIDCCallbackSchema{
+						Provider: "google",
+						State:    "<state>",
+						Code:     "<code>",
+						Nonce:    "<nonce>",
+					}).
+					Return(entity.Tokens{
+						AccessToken:  "<access token>",
+						RefreshToken: "<refresh token>",
+					}, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			oidcServ := servMocks.NewOIDCService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				OIDC: oidcServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(oidcServ)
+
+			r := gin.New()
+			r.GET("/oidc/:provider/callback", testLoggerMiddleware(t), h.oidcCallback)
+
+			req := httptest.NewRequest(http.MethodGet, "/oidc/google/callback"+tc.args.query, http.NoBody)
+			if tc.args.nonce != "" {
+				req.AddCookie(&http.Cookie{Name: oidcCookie, Value: tc.args.nonce})
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+		})
+	}
+}
+
+func TestHandler_OIDCAuthorize(t *testing.T) {
+	oidcServ := servMocks.NewOIDCService(t)
+
+	h, err := NewHandler(testLogger(t), &service.Services{
+		OIDC: oidcServ,
+	})
+	require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+	oidcServ.
+		On("Authorize", mock.Anything, service.OIDCAuthorizeSchema{Provider: "google"}).
+		Return(service.OIDCAuthorization{
+			AuthURL: "https://accounts.google.com/o/oauth2/v2/auth?state=<state>",
+			Nonce:   "9b2f0c4e7a1d4c8e",
+			TTL:     10 * time.Minute,
+		}, nil)
+
+	r := gin.New()
+	r.GET("/oidc/:provider", testLoggerMiddleware(t), h.oidcAuthorize)
+
+	req := httptest.NewRequest(http.MethodGet, "/oidc/google", http.NoBody)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	resp := rec.Result()
+
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://accounts.google.com/o/oauth2/v2/auth?state=<state>", resp.Header.Get("Location"))
+
+	require.Len(t, resp.Cookies(), 1)
+
+	cookie := resp.Cookies()[0]
+	assert.Equal(t, oidcCookie, cookie.Name)
+	assert.Equal(t, "9b2f0c4e7a1d4c8e", cookie.Value)
+	assert.Equal(t, oidcCookiePath, cookie.Path)
+	assert.Equal(t, 600, cookie.MaxAge)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+}
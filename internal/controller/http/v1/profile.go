@@ -0,0 +1,398 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/blob"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+// maxAvatarUploadSize limits size of the whole multipart request with avatar image
+const maxAvatarUploadSize = 5 << 20
+
+func (h *Handler) initProfileRoutes(users *gin.RouterGroup) {
+	users.PATCH("/change-username", h.changeUsername)
+	users.PATCH("/profile", h.updateProfile)
+	users.PUT("/avatar", h.changeAvatar)
+	users.DELETE("/avatar", h.deleteAvatar)
+}
+
+// initPublicProfileRoutes registers routes available without authentication
+func (h *Handler) initPublicProfileRoutes(router *gin.RouterGroup) {
+	router.GET("/profiles/:username", h.getProfile)
+	router.GET("/avatars/:name", h.getAvatar)
+}
+
+// getProfile handler returns public profile of the user
+//
+//	@Summary		Get public profile
+//	@Tags			profile
+//	@Description	Returns publicly visible information of the user.
+//	@Description	Recently changed username is redirected to the profile with current username
+//	@Accept			json
+//	@Produce		json
+//	@Param			username	path		string			true	"Username"
+//	@Success		200			{object}	entity.Profile	"Public profile"
+//	@Success		302			"Username was recently changed, Location header contains current profile"
+//	@Failure		404			{object}	errResponse{errors=[]entity.CoreError}	"User not found"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/profiles/{username} [get]
+func (h *Handler) getProfile(c *gin.Context) {
+	username := c.Param("username")
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	profile, err := h.services.Profile.GetProfile(reqctx, username)
+	if err != nil {
+		var movedErr *entity.UsernameMovedError
+
+		switch {
+		case errors.As(err, &movedErr):
+			location := strings.TrimSuffix(c.Request.URL.Path, url.PathEscape(username)) + url.PathEscape(movedErr.Username)
+			c.Redirect(http.StatusFound, location)
+		case errors.Is(err, entity.ErrUserNotFound):
+			userNotFoundErrorResponse(c)
+		default:
+			logger.Error("failed to get profile",
+				zap.String("username", username),
+				zap.Error(err),
+			)
+			internalErrorResponse(c)
+		}
+
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// getAvatar handler returns avatar image
+//
+//	@Summary		Get avatar
+//	@Tags			profile
+//	@Description	Returns avatar image. Keys are changed together with images, so responses are cached forever
+//	@Produce		png
+//	@Param			name	path	string	true	"Avatar file name"
+//	@Success		200		{file}	file	"Avatar image"
+//	@Success		304		"Avatar isn't modified"
+//	@Failure		404		{object}	errResponse{errors=[]entity.CoreError}	"Avatar not found"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/avatars/{name} [get]
+func (h *Handler) getAvatar(c *gin.Context) {
+	key := "avatars/" + c.Param("name")
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	object, err := h.services.Profile.GetAvatar(reqctx, key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			errorResponse(c, http.StatusNotFound, &entity.CoreError{
+				Code:    errorcode.AvatarNotFound,
+				Message: "avatar not found",
+			})
+
+			return
+		}
+
+		logger.Error("failed to get avatar",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+	defer object.Close()
+
+	c.Header("Content-Type", object.ContentType)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", fmt.Sprintf("%q", key))
+
+	http.ServeContent(c.Writer, c.Request, "", object.ModTime, object)
+}
+
+type userChangeUsernameSchema struct {
+	NewUsername string `json:"newUsername" binding:"required,username" example:"kenplix"`
+}
+
+// changeUsername handler changes username
+//
+//	@Summary		Changes username
+//	@Security		JWT-RS256
+//	@Tags			profile
+//	@Description	Changes username. Username may be changed once per cool-down period, previous username
+//	@Description	redirects to the current one and can't be taken by other users during redirect period
+//	@Accept			json
+//	@Produce		json
+//	@Param			If-Match	header		string											false	"ETag of the user, username isn't changed if the user was modified since then"
+//	@Param			schema		body		userChangeUsernameSchema						true	"JSON schema for username changing"
+//	@Success		200			{object}	entity.User										"Username was successfully changed"
+//	@Header			200			{string}	ETag											"Version of the changed user"
+//	@Failure		400			{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON, wrong type of JSON values or malformed If-Match header"
+//	@Failure		401			{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403			{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or access token lacks required scopes"
+//	@Failure		409			{object}	errResponse{errors=[]entity.CoreError}			"User was modified since ETag from If-Match header"
+//	@Failure		422			{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields, taken username or cool-down period"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/users/change-username [patch]
+func (h *Handler) changeUsername(c *gin.Context) {
+	var schema userChangeUsernameSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	updatedUser, err := h.services.Profile.ChangeUsername(reqctx, service.ChangeUsernameSchema{
+		UserID:      user.ID,
+		NewUsername: schema.NewUsername,
+		Version:     version,
+	})
+	if err != nil {
+		var (
+			validationErr *entity.ValidationError
+			cooldownErr   *entity.UsernameChangeCooldownError
+		)
+
+		switch {
+		case errors.As(err, &validationErr):
+			logger.Warn("failed to change username", zap.Error(err))
+			errorResponse(c, http.StatusUnprocessableEntity, validationErr)
+		case errors.As(err, &cooldownErr):
+			logger.Warn("failed to change username", zap.Error(err))
+			errorResponse(c, http.StatusUnprocessableEntity, &entity.CoreError{
+				Code:    errorcode.UsernameChangeCooldown,
+				Message: cooldownErr.Error(),
+			})
+		case errors.Is(err, entity.ErrUserVersionConflict):
+			versionConflictErrorResponse(c)
+		default:
+			logger.Error("failed to change username",
+				zap.String("userID", user.ID.Hex()),
+				zap.Error(err),
+			)
+			internalErrorResponse(c)
+		}
+
+		return
+	}
+
+	userResponse(c, updatedUser)
+}
+
+type userUpdateProfileSchema struct {
+	// DisplayName is kept if omitted and cleared if empty
+	DisplayName *string `json:"displayName" binding:"omitempty,max=64" example:"Oleksandr Tolstoi"`
+	// Bio is kept if omitted and cleared if empty
+	Bio *string `json:"bio" binding:"omitempty,max=280" example:"Go developer"`
+}
+
+// updateProfile handler changes display name and bio
+//
+//	@Summary		Updates public profile
+//	@Security		JWT-RS256
+//	@Tags			profile
+//	@Description	Changes provided display name and bio, omitted fields are kept
+//	@Accept			json
+//	@Produce		json
+//	@Param			If-Match	header		string											false	"ETag of the user, profile isn't updated if the user was modified since then"
+//	@Param			schema		body		userUpdateProfileSchema							true	"JSON schema for profile updating"
+//	@Success		200			{object}	entity.User										"Profile was successfully updated"
+//	@Header			200			{string}	ETag											"Version of the changed user"
+//	@Failure		400			{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON, wrong type of JSON values or malformed If-Match header"
+//	@Failure		401			{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403			{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or access token lacks required scopes"
+//	@Failure		409			{object}	errResponse{errors=[]entity.CoreError}			"User was modified since ETag from If-Match header"
+//	@Failure		422			{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/users/profile [patch]
+func (h *Handler) updateProfile(c *gin.Context) {
+	var schema userUpdateProfileSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	updatedUser, err := h.services.Profile.UpdateProfile(reqctx, service.UpdateProfileSchema{
+		UserID:      user.ID,
+		DisplayName: schema.DisplayName,
+		Bio:         schema.Bio,
+		Version:     version,
+	})
+	if err != nil {
+		if errors.Is(err, entity.ErrUserVersionConflict) {
+			versionConflictErrorResponse(c)
+			return
+		}
+
+		logger.Error("failed to update profile",
+			zap.String("userID", user.ID.Hex()),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+
+	userResponse(c, updatedUser)
+}
+
+// changeAvatar handler uploads avatar image
+//
+//	@Summary		Uploads avatar
+//	@Security		JWT-RS256
+//	@Tags			profile
+//	@Description	Accepts JPEG, PNG or GIF image up to 5MB, which is cropped to square and resized
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			If-Match	header		string									false	"ETag of the user, avatar isn't changed if the user was modified since then"
+//	@Param			avatar		formData	file									true	"Avatar image"
+//	@Success		200			{object}	entity.User								"Avatar was successfully changed"
+//	@Header			200			{string}	ETag									"Version of the changed user"
+//	@Failure		400			{object}	errResponse{errors=[]entity.CoreError}	"Avatar is missing or too large or If-Match header is malformed"
+//	@Failure		401			{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403			{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or access token lacks required scopes"
+//	@Failure		409			{object}	errResponse{errors=[]entity.CoreError}	"User was modified since ETag from If-Match header"
+//	@Failure		422			{object}	errResponse{errors=[]entity.CoreError}	"Image is corrupted or its format isn't supported"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/users/avatar [put]
+func (h *Handler) changeAvatar(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAvatarUploadSize)
+
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, &entity.CoreError{
+			Code:    errorcode.InvalidSchema,
+			Message: "avatar image up to 5MB must be provided in \"avatar\" field",
+		})
+
+		return
+	}
+
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Error("failed to open uploaded avatar", zap.Error(err))
+		internalErrorResponse(c)
+
+		return
+	}
+	defer file.Close()
+
+	updatedUser, err := h.services.Profile.ChangeAvatar(reqctx, service.ChangeAvatarSchema{
+		UserID:  user.ID,
+		Content: file,
+		Version: version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrUnsupportedImage):
+			errorResponse(c, http.StatusUnprocessableEntity, &entity.CoreError{
+				Code:    errorcode.InvalidImage,
+				Message: entity.ErrUnsupportedImage.Error(),
+			})
+		case errors.Is(err, entity.ErrUserVersionConflict):
+			versionConflictErrorResponse(c)
+		default:
+			logger.Error("failed to change avatar",
+				zap.String("userID", user.ID.Hex()),
+				zap.Error(err),
+			)
+			internalErrorResponse(c)
+		}
+
+		return
+	}
+
+	userResponse(c, updatedUser)
+}
+
+// deleteAvatar handler removes avatar
+//
+//	@Summary		Removes avatar
+//	@Security		JWT-RS256
+//	@Tags			profile
+//	@Description	Removes avatar image of the user
+//	@Accept			json
+//	@Produce		json
+//	@Param			If-Match	header		string									false	"ETag of the user, avatar isn't removed if the user was modified since then"
+//	@Success		200			{object}	entity.User								"Avatar was successfully removed"
+//	@Header			200			{string}	ETag									"Version of the changed user"
+//	@Failure		400			{object}	errResponse{errors=[]entity.CoreError}	"Malformed If-Match header"
+//	@Failure		401			{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403			{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or access token lacks required scopes"
+//	@Failure		409			{object}	errResponse{errors=[]entity.CoreError}	"User was modified since ETag from If-Match header"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/users/avatar [delete]
+func (h *Handler) deleteAvatar(c *gin.Context) {
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	updatedUser, err := h.services.Profile.DeleteAvatar(reqctx, service.DeleteAvatarSchema{
+		UserID:  user.ID,
+		Version: version,
+	})
+	if err != nil {
+		if errors.Is(err, entity.ErrUserVersionConflict) {
+			versionConflictErrorResponse(c)
+			return
+		}
+
+		logger.Error("failed to delete avatar",
+			zap.String("userID", user.ID.Hex()),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+
+	userResponse(c, updatedUser)
+}
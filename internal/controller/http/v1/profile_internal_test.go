@@ -0,0 +1,328 @@
+package v1
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+	"github.com/kenplix/url-shrtnr/pkg/blob"
+)
+
+func TestHandler_GetProfile(t *testing.T) {
+	testProfile := entity.Profile{
+		Username:    "kenplix",
+		DisplayName: "Oleksandr Tolstoi",
+		CreatedAt:   time.Date(2022, time.December, 24, 21, 49, 33, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		name         string
+		username     string
+		statusCode   int
+		location     string
+		responseBody string
+		mockBehavior func(*servMocks.ProfileService)
+	}{
+		{
+			name:       "not found",
+			username:   "unknown",
+			statusCode: http.StatusNotFound,
+			responseBody: mustMarshal(t, errResponse{
+				Errors: []apiError{
+					&entity.CoreError{
+						Code:    errorcode.UserNotFound,
+						Message: entity.ErrUserNotFound.Error(),
+					},
+				},
+			}),
+			mockBehavior: func(profileServ *servMocks.ProfileService) {
+				profileServ.
+					On("GetProfile", mock.Anything, "unknown").
+					Return(entity.Profile{}, entity.ErrUserNotFound)
+			},
+		},
+		{
+			name:       "moved",
+			username:   "tolstoi",
+			statusCode: http.StatusFound,
+			location:   "/profiles/kenplix",
+			mockBehavior: func(profileServ *servMocks.ProfileService) {
+				profileServ.
+					On("GetProfile", mock.Anything, "tolstoi").
+					Return(entity.Profile{}, &entity.UsernameMovedError{Username: "kenplix"})
+			},
+		},
+		{
+			name:         "service failure",
+			username:     "kenplix",
+			statusCode:   http.StatusInternalServerError,
+			responseBody: testInternalErrorResponse(t),
+			mockBehavior: func(profileServ *servMocks.ProfileService) {
+				profileServ.
+					On("GetProfile", mock.Anything, "kenplix").
+					Return(entity.Profile{}, assert.AnError)
+			},
+		},
+		{
+			name:         "ok",
+			username:     "kenplix",
+			statusCode:   http.StatusOK,
+			responseBody: mustMarshal(t, testProfile),
+			mockBehavior: func(profileServ *servMocks.ProfileService) {
+				profileServ.
+					On("GetProfile", mock.Anything, "kenplix").
+					Return(testProfile, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			profileServ := servMocks.NewProfileService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				Profile: profileServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(profileServ)
+
+			r := gin.New()
+			r.GET("/profiles/:username", testLoggerMiddleware(t), h.getProfile)
+
+			req := httptest.NewRequest(http.MethodGet, "/profiles/"+tc.username, http.NoBody)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Code)
+			assert.Equal(t, tc.location, rec.Header().Get("Location"))
+
+			if tc.responseBody != "" {
+				assert.Equal(t, tc.responseBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+type nopSeekCloser struct {
+	*bytes.Reader
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+func TestHandler_GetAvatar(t *testing.T) {
+	const testKey = "avatars/9f86d081884c7d659a2feaa0c55ad015.png"
+
+	testImage := []byte("\x89PNG\r\n\x1a\n")
+
+	testCases := []struct {
+		name         string
+		key          string
+		ifNoneMatch  string
+		statusCode   int
+		responseBody string
+		mockBehavior func(*servMocks.ProfileService)
+	}{
+		{
+			name:       "not found",
+			key:        "avatars/unknown.png",
+			statusCode: http.StatusNotFound,
+			responseBody: mustMarshal(t, errResponse{
+				Errors: []apiError{
+					&entity.CoreError{
+						Code:    errorcode.AvatarNotFound,
+						Message: "avatar not found",
+					},
+				},
+			}),
+			mockBehavior: func(profileServ *servMocks.ProfileService) {
+				profileServ.
+					On("GetAvatar", mock.Anything, "avatars/unknown.png").
+					Return(blob.Object{}, blob.ErrNotFound)
+			},
+		},
+		{
+			name:         "ok",
+			key:          testKey,
+			statusCode:   http.StatusOK,
+			responseBody: string(testImage),
+			mockBehavior: func(profileServ *servMocks.ProfileService) {
+				profileServ.
+					On("GetAvatar", mock.Anything, testKey).
+					Return(blob.Object{
+						ReadSeekCloser: nopSeekCloser{bytes.NewReader(testImage)},
+						ContentType:    "image/png",
+						Size:           int64(len(testImage)),
+					}, nil)
+			},
+		},
+		{
+			name:        "not modified",
+			key:         testKey,
+			ifNoneMatch: `"` + testKey + `"`,
+			statusCode:  http.StatusNotModified,
+			mockBehavior: func(profileServ *servMocks.ProfileService) {
+				profileServ.
+					On("GetAvatar", mock.Anything, testKey).
+					Return(blob.Object{
+						ReadSeekCloser: nopSeekCloser{bytes.NewReader(testImage)},
+						ContentType:    "image/png",
+						Size:           int64(len(testImage)),
+					}, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			profileServ := servMocks.NewProfileService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				Profile: profileServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(profileServ)
+
+			r := gin.New()
+			r.GET("/avatars/:name", testLoggerMiddleware(t), h.getAvatar)
+
+			req := httptest.NewRequest(http.MethodGet, "/avatars/"+tc.key[len("avatars/"):], http.NoBody)
+			req.Header.Set("If-None-Match", tc.ifNoneMatch)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Code)
+			assert.Equal(t, tc.responseBody, rec.Body.String())
+
+			if tc.statusCode == http.StatusOK {
+				assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+				assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestHandler_UpdateProfile(t *testing.T) {
+	testUser := entity.User{ID: primitive.NewObjectID(), Username: "kenplix", Bio: "Go developer", Version: 4}
+
+	testCases := []struct {
+		name         string
+		ifMatch      string
+		statusCode   int
+		etag         string
+		responseBody string
+		mockBehavior func(*servMocks.ProfileService)
+	}{
+		{
+			name:       "malformed if-match",
+			ifMatch:    "W/\"3\"",
+			statusCode: http.StatusBadRequest,
+			responseBody: mustMarshal(t, errResponse{
+				Errors: []apiError{
+					&entity.CoreError{
+						Code:    errorcode.InvalidSchema,
+						Message: "If-Match header must contain entity tag returned in ETag header",
+					},
+				},
+			}),
+			mockBehavior: func(_ *servMocks.ProfileService) {},
+		},
+		{
+			name:       "version conflict",
+			ifMatch:    `"3"`,
+			statusCode: http.StatusConflict,
+			responseBody: mustMarshal(t, errResponse{
+				Errors: []apiError{
+					&entity.CoreError{
+						Code:    errorcode.VersionConflict,
+						Message: entity.ErrUserVersionConflict.Error(),
+					},
+				},
+			}),
+			mockBehavior: func(profileServ *servMocks.ProfileService) {
+				profileServ.
+					On("UpdateProfile", mock.Anything, mock.MatchedBy(func(schema service.UpdateProfileSchema) bool {
+						return schema.Version == 3
+					})).
+					Return(entity.User{}, errors.Wrap(entity.ErrUserVersionConflict, "failed to update profile"))
+			},
+		},
+		{
+			name:         "ok with if-match",
+			ifMatch:      `"3"`,
+			statusCode:   http.StatusOK,
+			etag:         `"4"`,
+			responseBody: mustMarshal(t, testUser),
+			mockBehavior: func(profileServ *servMocks.ProfileService) {
+				profileServ.
+					On("UpdateProfile", mock.Anything, mock.MatchedBy(func(schema service.UpdateProfileSchema) bool {
+						return schema.Version == 3
+					})).
+					Return(testUser, nil)
+			},
+		},
+		{
+			name:         "ok without if-match",
+			statusCode:   http.StatusOK,
+			etag:         `"4"`,
+			responseBody: mustMarshal(t, testUser),
+			mockBehavior: func(profileServ *servMocks.ProfileService) {
+				profileServ.
+					On("UpdateProfile", mock.Anything, mock.MatchedBy(func(schema service.UpdateProfileSchema) bool {
+						return schema.Version == 0
+					})).
+					Return(testUser, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	initValidator(t)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			profileServ := servMocks.NewProfileService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				Profile: profileServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(profileServ)
+
+			r := gin.New()
+			r.PATCH("/profile", testLoggerMiddleware(t), testUserMiddleware(t), h.updateProfile)
+
+			req := httptest.NewRequest(http.MethodPatch, "/profile", strings.NewReader(`{"bio":"Go developer"}`))
+			req.Header.Set("If-Match", tc.ifMatch)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Code)
+			assert.Equal(t, tc.responseBody, rec.Body.String())
+			assert.Equal(t, tc.etag, rec.Header().Get("ETag"))
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+func (h *Handler) initTwoFactorRoutes(users *gin.RouterGroup) {
+	twoFactor := users.Group("/2fa")
+
+	twoFactor.POST("/enroll", h.enrollTwoFactor)
+	twoFactor.POST("/confirm", h.confirmTwoFactor)
+	twoFactor.POST("/disable", h.disableTwoFactor)
+}
+
+// enrollTwoFactor handler starts two-factor authentication enrollment
+//
+//	@Summary		Start two-factor authentication enrollment
+//	@Security		JWT-RS256
+//	@Tags			2fa
+//	@Description	Generates TOTP secret which must be confirmed with the first code during enrollment TTL
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	entity.TwoFactorEnrollment				"TOTP secret, key URI and QR code"
+//	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or access token lacks required scopes"
+//	@Failure		422	{object}	errResponse{errors=[]entity.CoreError}	"Two-factor authentication already enabled"
+//	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
+//	@Router			/users/2fa/enroll [post]
+func (h *Handler) enrollTwoFactor(c *gin.Context) {
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	enrollment, err := h.services.TwoFactor.Enroll(reqctx, user.ID)
+	if err != nil {
+		if errors.Is(err, entity.ErrTwoFactorAlreadyEnabled) {
+			logger.Warn("failed to enroll two-factor authentication",
+				zap.String("userID", user.ID.Hex()),
+				zap.Error(err),
+			)
+			errorResponse(c, http.StatusUnprocessableEntity, &entity.CoreError{
+				Code:    errorcode.TwoFactorAlreadyEnabled,
+				Message: entity.ErrTwoFactorAlreadyEnabled.Error(),
+			})
+
+			return
+		}
+
+		logger.Error("failed to enroll two-factor authentication",
+			zap.String("userID", user.ID.Hex()),
+			zap.Error(err),
+		)
+		internalErrorResponse(c)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+type twoFactorConfirmSchema struct {
+	Code string `json:"code" binding:"required,numeric,min=6,max=8" example:"123456"`
+}
+
+// confirmTwoFactor handler confirms two-factor authentication enrollment
+//
+//	@Summary		Confirm two-factor authentication enrollment
+//	@Security		JWT-RS256
+//	@Tags			2fa
+//	@Description	Enables two-factor authentication and returns one-time recovery codes which are shown only once
+//	@Accept			json
+//	@Produce		json
+//	@Param			schema	body		twoFactorConfirmSchema							true	"JSON schema for two-factor enrollment confirmation"
+//	@Success		200		{object}	entity.RecoveryCodes							"Two-factor authentication was successfully enabled"
+//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or access token lacks required scopes"
+//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/users/2fa/confirm [post]
+func (h *Handler) confirmTwoFactor(c *gin.Context) {
+	var schema twoFactorConfirmSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	recoveryCodes, err := h.services.TwoFactor.ConfirmEnrollment(reqctx, service.ConfirmTwoFactorSchema{
+		UserID: user.ID,
+		Code:   schema.Code,
+	})
+	if err != nil {
+		var apiErr *entity.CoreError
+
+		switch {
+		case errors.Is(err, entity.ErrTwoFactorEnrollmentNotFound):
+			apiErr = &entity.CoreError{
+				Code:    errorcode.TwoFactorEnrollmentExpired,
+				Message: entity.ErrTwoFactorEnrollmentNotFound.Error(),
+			}
+		case errors.Is(err, entity.ErrIncorrectTwoFactorCode):
+			apiErr = newIncorrectOTPError()
+		default:
+			logger.Error("failed to confirm two-factor authentication",
+				zap.String("userID", user.ID.Hex()),
+				zap.Error(err),
+			)
+			internalErrorResponse(c)
+
+			return
+		}
+
+		logger.Warn("failed to confirm two-factor authentication",
+			zap.String("userID", user.ID.Hex()),
+			zap.Error(err),
+		)
+		errorResponse(c, http.StatusUnprocessableEntity, apiErr)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveryCodes)
+}
+
+type twoFactorDisableSchema struct {
+	Password string `json:"password" binding:"required,password" example:"1wE$Rty2"`
+	Code     string `json:"code" binding:"required,max=32" example:"123456"`
+}
+
+// disableTwoFactor handler disables two-factor authentication
+//
+//	@Summary		Disable two-factor authentication
+//	@Security		JWT-RS256
+//	@Tags			2fa
+//	@Description	Disables two-factor authentication, requires current password and TOTP or recovery code
+//	@Accept			json
+//	@Produce		json
+//	@Param			schema	body	twoFactorDisableSchema	true	"JSON schema for two-factor authentication disabling"
+//	@Success		200		"Two-factor authentication was successfully disabled"
+//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
+//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or access token lacks required scopes"
+//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Router			/users/2fa/disable [post]
+func (h *Handler) disableTwoFactor(c *gin.Context) {
+	var schema twoFactorDisableSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		bindingErrorResponse(c, err)
+		return
+	}
+
+	user := c.MustGet(userContext).(entity.User)
+
+	reqctx := c.Request.Context()
+	logger := log.LoggerFromContext(reqctx)
+
+	err := h.services.TwoFactor.Disable(reqctx, service.DisableTwoFactorSchema{
+		UserID:   user.ID,
+		Password: schema.Password,
+		Code:     schema.Code,
+	})
+	if err != nil {
+		var apiErr *entity.CoreError
+
+		switch {
+		case errors.Is(err, entity.ErrTwoFactorNotEnabled):
+			apiErr = &entity.CoreError{
+				Code:    errorcode.TwoFactorNotEnabled,
+				Message: entity.ErrTwoFactorNotEnabled.Error(),
+			}
+		case errors.Is(err, entity.ErrIncorrectCredentials):
+			apiErr = &entity.CoreError{
+				Code:    errorcode.IncorrectCredentials,
+				Message: entity.ErrIncorrectCredentials.Error(),
+			}
+		case errors.Is(err, entity.ErrIncorrectTwoFactorCode):
+			apiErr = newIncorrectOTPError()
+		default:
+			logger.Error("failed to disable two-factor authentication",
+				zap.String("userID", user.ID.Hex()),
+				zap.Error(err),
+			)
+			internalErrorResponse(c)
+
+			return
+		}
+
+		logger.Warn("failed to disable two-factor authentication",
+			zap.String("userID", user.ID.Hex()),
+			zap.Error(err),
+		)
+		errorResponse(c, http.StatusUnprocessableEntity, apiErr)
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,279 @@
+package v1
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+)
+
+func TestHandler_ConfirmTwoFactor(t *testing.T) {
+	type args struct {
+		inputBody string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	type mockBehavior func(*servMocks.TwoFactorService)
+
+	testTwoFactorConfirmSchema := func(t *testing.T) twoFactorConfirmSchema {
+		t.Helper()
+
+		return twoFactorConfirmSchema{
+			Code: "123456",
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "binding error",
+			args: args{
+				inputBody: "[]",
+			},
+			ret: ret{
+				statusCode:   http.StatusBadRequest,
+				responseBody: testUnmarshalTypeError(t),
+			},
+			mockBehavior: func(_ *servMocks.TwoFactorService) {},
+		},
+		{
+			name: "enrollment expired",
+			args: args{
+				inputBody: mustMarshal(t, testTwoFactorConfirmSchema(t)),
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.TwoFactorEnrollmentExpired,
+							Message: entity.ErrTwoFactorEnrollmentNotFound.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(twoFactorServ *servMocks.TwoFactorService) {
+				twoFactorServ.
+					On("ConfirmEnrollment", mock.Anything, mock.Anything).
+					Return(entity.RecoveryCodes{}, entity.ErrTwoFactorEnrollmentNotFound)
+			},
+		},
+		{
+			name: "incorrect code",
+			args: args{
+				inputBody: mustMarshal(t, testTwoFactorConfirmSchema(t)),
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{newIncorrectOTPError()},
+				}),
+			},
+			mockBehavior: func(twoFactorServ *servMocks.TwoFactorService) {
+				twoFactorServ.
+					On("ConfirmEnrollment", mock.Anything, mock.Anything).
+					Return(entity.RecoveryCodes{}, entity.ErrIncorrectTwoFactorCode)
+			},
+		},
+		{
+			name: "service failure",
+			args: args{
+				inputBody: mustMarshal(t, testTwoFactorConfirmSchema(t)),
+			},
+			ret: ret{
+				statusCode:   http.StatusInternalServerError,
+				responseBody: testInternalErrorResponse(t),
+			},
+			mockBehavior: func(twoFactorServ *servMocks.TwoFactorService) {
+				twoFactorServ.
+					On("ConfirmEnrollment", mock.Anything, mock.Anything).
+					Return(entity.RecoveryCodes{}, assert.AnError)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				inputBody: mustMarshal(t, testTwoFactorConfirmSchema(t)),
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+				responseBody: mustMarshal(t, entity.RecoveryCodes{
+					RecoveryCodes: []string{"abcde-fghjk"},
+				}),
+			},
+			mockBehavior: func(twoFactorServ *servMocks.TwoFactorService) {
+				twoFactorServ.
+					On("ConfirmEnrollment", mock.Anything, mock.Anything).
+					Return(entity.RecoveryCodes{RecoveryCodes: []string{"abcde-fghjk"}}, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	initValidator(t)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			twoFactorServ := servMocks.NewTwoFactorService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				TwoFactor: twoFactorServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(twoFactorServ)
+
+			r := gin.New()
+			r.POST("/2fa/confirm", testLoggerMiddleware(t), testUserMiddleware(t), h.confirmTwoFactor)
+
+			req := httptest.NewRequest(http.MethodPost, "/2fa/confirm", bytes.NewBufferString(tc.args.inputBody))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+		})
+	}
+}
+
+func TestHandler_DisableTwoFactor(t *testing.T) {
+	type args struct {
+		inputBody string
+	}
+
+	type ret struct {
+		statusCode   int
+		responseBody string
+	}
+
+	type mockBehavior func(*servMocks.TwoFactorService)
+
+	testTwoFactorDisableSchema := func(t *testing.T) twoFactorDisableSchema {
+		t.Helper()
+
+		return twoFactorDisableSchema{
+			Password: "1wE$Rty2",
+			Code:     "123456",
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "two-factor authentication not enabled",
+			args: args{
+				inputBody: mustMarshal(t, testTwoFactorDisableSchema(t)),
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.TwoFactorNotEnabled,
+							Message: entity.ErrTwoFactorNotEnabled.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(twoFactorServ *servMocks.TwoFactorService) {
+				twoFactorServ.
+					On("Disable", mock.Anything, mock.Anything).
+					Return(entity.ErrTwoFactorNotEnabled)
+			},
+		},
+		{
+			name: "incorrect password",
+			args: args{
+				inputBody: mustMarshal(t, testTwoFactorDisableSchema(t)),
+			},
+			ret: ret{
+				statusCode: http.StatusUnprocessableEntity,
+				responseBody: mustMarshal(t, errResponse{
+					Errors: []apiError{
+						&entity.CoreError{
+							Code:    errorcode.IncorrectCredentials,
+							Message: entity.ErrIncorrectCredentials.Error(),
+						},
+					},
+				}),
+			},
+			mockBehavior: func(twoFactorServ *servMocks.TwoFactorService) {
+				twoFactorServ.
+					On("Disable", mock.Anything, mock.Anything).
+					Return(entity.ErrIncorrectCredentials)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				inputBody: mustMarshal(t, testTwoFactorDisableSchema(t)),
+			},
+			ret: ret{
+				statusCode:   http.StatusOK,
+				responseBody: "",
+			},
+			mockBehavior: func(twoFactorServ *servMocks.TwoFactorService) {
+				twoFactorServ.
+					On("Disable", mock.Anything, mock.Anything).
+					Return(nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	initValidator(t)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			twoFactorServ := servMocks.NewTwoFactorService(t)
+
+			h, err := NewHandler(testLogger(t), &service.Services{
+				TwoFactor: twoFactorServ,
+			})
+			require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+			tc.mockBehavior(twoFactorServ)
+
+			r := gin.New()
+			r.POST("/2fa/disable", testLoggerMiddleware(t), testUserMiddleware(t), h.disableTwoFactor)
+
+			req := httptest.NewRequest(http.MethodPost, "/2fa/disable", bytes.NewBufferString(tc.args.inputBody))
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+
+			assert.Equal(t, tc.ret.statusCode, resp.StatusCode)
+			assert.Equal(t, tc.ret.responseBody, string(body))
+		})
+	}
+}
@@ -2,6 +2,8 @@ package v1
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -23,30 +25,40 @@ func (h *Handler) initUsersRoutes(router *gin.RouterGroup) {
 		"/users",
 		h.userIdentityMiddleware,
 		h.userActivityMiddleware,
+		h.requireScopes(entity.ScopeAccount),
 	)
 
 	users.GET("/me", h.me)
 	users.PATCH("/change-email", h.changeEmail)
 	users.PATCH("/change-password", h.changePassword)
+	users.GET("/audit-events", h.listOwnAuditEvents)
+
+	h.initTwoFactorRoutes(users)
+	h.initAPIKeysRoutes(users)
+	h.initOIDCLinkRoutes(users)
+	h.initAccountRoutes(users)
+	h.initProfileRoutes(users)
 }
 
 // me handler returns users personal information
 //
 //	@Summary		Returns users personal information
 //	@Security		JWT-RS256
+//	@Security		APIKey
 //	@Tags			user
 //	@Description	Returns users personal information
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{object}	entity.User								"User personal information"
+//	@Header			200	{string}	ETag									"Version of the user, which is sent in If-Match header to change the user safely"
 //	@Failure		401	{object}	errResponse{errors=[]entity.CoreError}	"Access is denied due to invalid credentials"
-//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended"
+//	@Failure		403	{object}	errResponse{errors=[]entity.CoreError}	"Your account has been suspended or access token lacks required scopes"
 //	@Failure		500	{object}	errResponse{errors=[]entity.CoreError}	"Internal server error"
 //	@Router			/users/me [get]
 func (h *Handler) me(c *gin.Context) {
 	user := c.MustGet(userContext).(entity.User)
 
-	c.JSON(http.StatusOK, user)
+	userResponse(c, user)
 }
 
 type userChangeEmailSchema struct {
@@ -61,13 +73,15 @@ type userChangeEmailSchema struct {
 //	@Description	Changes users emails
 //	@Accept			json
 //	@Produce		json
-//	@Param			schema	body	userChangeEmailSchema	true	"JSON schema for user email changing"
-//	@Success		200		"User email was successfully changed"
-//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
-//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
-//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended"
-//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
-//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Param			If-Match	header	string					false	"ETag of the user, email isn't changed if the user was modified since then"
+//	@Param			schema		body	userChangeEmailSchema	true	"JSON schema for user email changing"
+//	@Success		200			"User email was successfully changed"
+//	@Failure		400			{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON, wrong type of JSON values or malformed If-Match header"
+//	@Failure		401			{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403			{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or access token lacks required scopes"
+//	@Failure		409			{object}	errResponse{errors=[]entity.CoreError}			"User was modified since ETag from If-Match header"
+//	@Failure		422			{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
 //	@Router			/users/change-email [patch]
 func (h *Handler) changeEmail(c *gin.Context) {
 	var schema userChangeEmailSchema
@@ -76,6 +90,11 @@ func (h *Handler) changeEmail(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	user := c.MustGet(userContext).(entity.User)
 
 	reqctx := c.Request.Context()
@@ -83,9 +102,15 @@ func (h *Handler) changeEmail(c *gin.Context) {
 
 	err := h.services.Users.ChangeEmail(reqctx, service.ChangeEmailSchema{
 		UserID:   user.ID,
-		NewEmail: schema.NewEmail,
+		NewEmail: strings.ToLower(schema.NewEmail),
+		Version:  version,
 	})
 	if err != nil {
+		if errors.Is(err, entity.ErrUserVersionConflict) {
+			versionConflictErrorResponse(c)
+			return
+		}
+
 		logger.Error("failed to change email",
 			zap.String("userID", user.ID.Hex()),
 			zap.Error(err),
@@ -102,6 +127,9 @@ type userChangePasswordSchema struct {
 	CurrentPassword      string `json:"currentPassword" binding:"required,password" example:"1wE$Rty2"`
 	NewPassword          string `json:"newPassword" binding:"required,password,eqfield=PasswordConfirmation" example:"2ytR$Ew1"`
 	PasswordConfirmation string `json:"passwordConfirmation" binding:"required,password" example:"2ytR$Ew1"`
+	// KeepCurrentSession keeps the current session with new tokens pair. All sessions
+	// are revoked on password change, so without it the current one must sign in again too
+	KeepCurrentSession bool `json:"keepCurrentSession" example:"true"`
 }
 
 // changePassword handler changes users passwords
@@ -112,13 +140,17 @@ type userChangePasswordSchema struct {
 //	@Description	Changes users passwords
 //	@Accept			json
 //	@Produce		json
-//	@Param			schema	body	userChangePasswordSchema	true	"JSON schema for user password changing"
-//	@Success		200		"User password was successfully changed"
-//	@Failure		400		{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON or wrong type of JSON values"
-//	@Failure		401		{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
-//	@Failure		403		{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended"
-//	@Failure		422		{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
-//	@Failure		500		{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
+//	@Param			X-Auth-Mode	header		string						false	"Set to cookie to receive tokens in HttpOnly cookies"	Enums(cookie)
+//	@Param			If-Match	header		string						false	"ETag of the user, password isn't changed if the user was modified since then"
+//	@Param			schema		body		userChangePasswordSchema	true	"JSON schema for user password changing"
+//	@Success		200			{object}	entity.Tokens				"User password was successfully changed and all sessions were revoked, tokens pair is returned when current session is kept"
+//	@Success		204			"User password was successfully changed, new tokens were set in cookies"
+//	@Failure		400			{object}	errResponse{errors=[]entity.CoreError}			"Invalid JSON, wrong type of JSON values or malformed If-Match header"
+//	@Failure		401			{object}	errResponse{errors=[]entity.CoreError}			"Access is denied due to invalid credentials"
+//	@Failure		403			{object}	errResponse{errors=[]entity.CoreError}			"Your account has been suspended or access token lacks required scopes"
+//	@Failure		409			{object}	errResponse{errors=[]entity.CoreError}			"User was modified since ETag from If-Match header"
+//	@Failure		422			{object}	errResponse{errors=[]entity.ValidationError}	"Validation failed through invalid fields"
+//	@Failure		500			{object}	errResponse{errors=[]entity.CoreError}			"Internal server error"
 //	@Router			/users/change-password [patch]
 func (h *Handler) changePassword(c *gin.Context) {
 	var schema userChangePasswordSchema
@@ -127,17 +159,29 @@ func (h *Handler) changePassword(c *gin.Context) {
 		return
 	}
 
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
 	user := c.MustGet(userContext).(entity.User)
 
 	reqctx := c.Request.Context()
 	logger := log.LoggerFromContext(reqctx)
 
-	err := h.services.Users.ChangePassword(reqctx, service.ChangePasswordSchema{
-		UserID:          user.ID,
-		CurrentPassword: schema.CurrentPassword,
-		NewPassword:     schema.NewPassword,
+	tokens, err := h.services.Users.ChangePassword(reqctx, service.ChangePasswordSchema{
+		UserID:             user.ID,
+		CurrentPassword:    schema.CurrentPassword,
+		NewPassword:        schema.NewPassword,
+		KeepCurrentSession: schema.KeepCurrentSession,
+		Version:            version,
 	})
 	if err != nil {
+		if errors.Is(err, entity.ErrUserVersionConflict) {
+			versionConflictErrorResponse(c)
+			return
+		}
+
 		if errors.Is(err, entity.ErrIncorrectCredentials) {
 			logger.Warn("failed to change password",
 				zap.String("userID", user.ID.Hex()),
@@ -160,5 +204,48 @@ func (h *Handler) changePassword(c *gin.Context) {
 		return
 	}
 
+	if schema.KeepCurrentSession {
+		h.tokensResponse(c, tokens)
+		return
+	}
+
 	c.Status(http.StatusOK)
 }
+
+// userResponse responds with the user and its entity tag
+func userResponse(c *gin.Context, user entity.User) {
+	c.Header("ETag", userETag(user))
+	c.JSON(http.StatusOK, user)
+}
+
+// userETag is a strong entity tag, which changes together with version of the user
+func userETag(user entity.User) string {
+	return strconv.Quote(strconv.FormatInt(user.Version, 10))
+}
+
+// ifMatchVersion returns version of the user from If-Match header, which must contain single entity tag.
+// Zero is returned if header is absent or matches any version, malformed header is responded with error
+func ifMatchVersion(c *gin.Context) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	var version int64
+
+	tag, err := strconv.Unquote(header)
+	if err == nil && strings.HasPrefix(header, `"`) {
+		version, err = strconv.ParseInt(tag, 10, 64)
+	}
+
+	if err != nil || version <= 0 {
+		errorResponse(c, http.StatusBadRequest, &entity.CoreError{
+			Code:    errorcode.InvalidSchema,
+			Message: "If-Match header must contain entity tag returned in ETag header",
+		})
+
+		return 0, false
+	}
+
+	return version, true
+}
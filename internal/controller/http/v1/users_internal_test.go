@@ -38,7 +38,7 @@ func TestHandler_ChangeEmail(t *testing.T) {
 		t.Helper()
 
 		return userChangeEmailSchema{
-			NewEmail: "example@gmail.com",
+			NewEmail: "Example@Gmail.com",
 		}
 	}
 
@@ -151,7 +151,9 @@ func TestHandler_ChangeEmail(t *testing.T) {
 					On("ProlongTokens", mock.Anything, mock.Anything)
 
 				usersServ.
-					On("ChangeEmail", mock.Anything, mock.Anything).
+					On("ChangeEmail", mock.Anything, mock.MatchedBy(func(schema service.ChangeEmailSchema) bool {
+						return schema.NewEmail == "example@gmail.com"
+					})).
 					Return(nil)
 			},
 		},
@@ -177,7 +179,7 @@ func TestHandler_ChangeEmail(t *testing.T) {
 			require.NoErrorf(t, err, "failed to create handler: %s", err)
 
 			r := gin.New()
-			h.InitRoutes(r.Group("/api", testLoggerMiddleware(t)))
+			require.NoError(t, h.InitRoutes(r.Group("/api", testLoggerMiddleware(t)), Config{}))
 
 			tc.mockBehavior(jwtServ, usersServ)
 
@@ -302,7 +304,7 @@ func TestHandler_ChangePassword(t *testing.T) {
 
 				usersServ.
 					On("ChangePassword", mock.Anything, mock.Anything).
-					Return(entity.ErrIncorrectCredentials)
+					Return(entity.Tokens{}, entity.ErrIncorrectCredentials)
 			},
 		},
 		{
@@ -339,7 +341,7 @@ func TestHandler_ChangePassword(t *testing.T) {
 
 				usersServ.
 					On("ChangePassword", mock.Anything, mock.Anything).
-					Return(assert.AnError)
+					Return(entity.Tokens{}, assert.AnError)
 			},
 		},
 		{
@@ -376,7 +378,62 @@ func TestHandler_ChangePassword(t *testing.T) {
 
 				usersServ.
 					On("ChangePassword", mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, nil)
+			},
+		},
+		{
+			name: "ok with other devices signed out",
+			args: args{
+				inputBody: func(t *testing.T) string {
+					t.Helper()
+
+					schema := testUserChangePasswordSchema(t)
+					schema.KeepCurrentSession = true
+
+					return mustMarshal(t, schema)
+				}(t),
+			},
+			ret: ret{
+				statusCode: http.StatusOK,
+				responseBody: mustMarshal(t, entity.Tokens{
+					AccessToken:  "<access token>",
+					RefreshToken: "<refresh token>",
+				}),
+			},
+			mockBehavior: func(jwtServ *servMocks.JWTService, usersServ *servMocks.UsersService) {
+				userID := primitive.NewObjectID()
+				claims := &token.JWTCustomClaims{
+					StandardClaims: jwt.StandardClaims{
+						Subject: userID.Hex(),
+					},
+				}
+
+				jwtServ.
+					On("ParseAccessToken", mock.Anything).
+					Return(claims, nil)
+
+				jwtServ.
+					On("ValidateAccessToken", mock.Anything, mock.Anything).
 					Return(nil)
+
+				usersServ.
+					On("GetByID", mock.Anything, mock.Anything).
+					Return(entity.User{ID: userID}, nil)
+
+				jwtServ.
+					On("ProlongTokens", mock.Anything, mock.Anything)
+
+				usersServ.
+					On("ChangePassword", mock.Anything, service.ChangePasswordSchema{
+						UserID:             userID,
+						CurrentPassword:    "1wE$Rty2",
+						NewPassword:        "2ytR$Ew1",
+						KeepCurrentSession: true,
+					}).
+					Return(entity.Tokens{
+						AccessToken:  "<access token>",
+						RefreshToken: "<refresh token>",
+					}, nil)
 			},
 		},
 	}
@@ -401,7 +458,7 @@ func TestHandler_ChangePassword(t *testing.T) {
 			require.NoErrorf(t, err, "failed to create handler: %s", err)
 
 			r := gin.New()
-			h.InitRoutes(r.Group("/api", testLoggerMiddleware(t)))
+			require.NoError(t, h.InitRoutes(r.Group("/api", testLoggerMiddleware(t)), Config{}))
 
 			tc.mockBehavior(jwtServ, usersServ)
 
@@ -423,3 +480,20 @@ func TestHandler_ChangePassword(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_Me(t *testing.T) {
+	user := entity.User{ID: primitive.NewObjectID(), Username: "kenplix", Version: 7}
+
+	h, err := NewHandler(testLogger(t), &service.Services{})
+	require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+	r := gin.New()
+	r.GET("/me", func(c *gin.Context) { c.Set(userContext, user) }, h.me)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/me", http.NoBody))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, mustMarshal(t, user), rec.Body.String())
+	assert.Equal(t, `"7"`, rec.Header().Get("ETag"), "version of the user must be returned as entity tag")
+}
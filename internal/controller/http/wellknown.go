@@ -0,0 +1,24 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwksMaxAge defines how long clients may cache published keys.
+// It should be noticeably less than the time between adding a new key and activating it
+const jwksMaxAge = "max-age=300"
+
+func (h *Handler) initWellKnown(router *gin.Engine) {
+	wellKnown := router.Group("/.well-known")
+	{
+		wellKnown.GET("/jwks.json", h.jwks)
+	}
+}
+
+// jwks handler publishes public keys which may be used by other services to verify access tokens
+func (h *Handler) jwks(c *gin.Context) {
+	c.Header("Cache-Control", jwksMaxAge)
+	c.JSON(http.StatusOK, h.services.JWT.AccessTokenJWKS())
+}
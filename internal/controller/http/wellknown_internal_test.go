@@ -0,0 +1,65 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/internal/config"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+	"github.com/kenplix/url-shrtnr/pkg/token"
+)
+
+func TestHandler_JWKS(t *testing.T) {
+	testJWKS := token.JWKS{
+		Keys: []token.JWK{
+			{
+				KeyType:   "RSA",
+				Use:       "sig",
+				Algorithm: "RS256",
+				KeyID:     "<key id>",
+				N:         "<modulus>",
+				E:         "AQAB",
+			},
+		},
+	}
+
+	var (
+		jwtServ   = servMocks.NewJWTService(t)
+		authServ  = servMocks.NewAuthService(t)
+		usersServ = servMocks.NewUsersService(t)
+	)
+
+	jwtServ.
+		On("AccessTokenJWKS").
+		Return(testJWKS)
+
+	h, err := NewHandler(testLogger(t), &service.Services{
+		JWT:   jwtServ,
+		Auth:  authServ,
+		Users: usersServ,
+	})
+	require.NoErrorf(t, err, "failed to create handler: %s", err)
+
+	r, err := h.InitEngine(config.TestingEnvironment, Config{})
+	require.NoErrorf(t, err, "failed to init engine: %s", err)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", http.NoBody))
+
+	resp := rec.Result()
+	body, _ := io.ReadAll(resp.Body)
+
+	expected, err := json.Marshal(testJWKS)
+	require.NoErrorf(t, err, "failed to marshal JWKS: %s", err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, string(expected), string(body))
+	assert.Equal(t, jwksMaxAge, resp.Header.Get("Cache-Control"))
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// Data export statuses
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExport is a state of personal data archive preparation
+//
+//	@Description	State of personal data archive preparation
+type DataExport struct {
+	ID        string    `json:"id" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	Status    string    `json:"status" enums:"pending,ready,failed" example:"ready"`
+	CreatedAt time.Time `json:"createdAt" example:"2023-01-25T21:49:33.072726+02:00"`
+	// ExpiresAt is a date after which export and its archive are removed
+	ExpiresAt time.Time `json:"expiresAt" example:"2023-01-26T21:49:33.072726+02:00"`
+}
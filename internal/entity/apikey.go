@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKey is a personal access token information
+//
+//	@Description	Personal access token information
+type APIKey struct {
+	ID   primitive.ObjectID `json:"id" example:"63c2b7a1e4b0f2a9d1c3e5f7"`
+	Name string             `json:"name" example:"ci deploy"`
+	// Prefix is a public part of the key which helps to recognize it
+	Prefix string   `json:"prefix" example:"shrtnr_3f9a1c2e"`
+	Scopes []string `json:"scopes" example:"links:read,links:write"`
+	// ExpiresAt is a date after which key is not accepted anymore (optional)
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" example:"2023-12-24T21:49:33.072726+02:00"`
+	// LastUsedAt is an approximate date when key was used last time (optional)
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" example:"2023-01-14T16:02:11.254314+02:00"`
+	CreatedAt  time.Time  `json:"createdAt" example:"2023-01-14T15:57:06.812377+02:00"`
+}
+
+// CreatedAPIKey is a just created personal access token together with its secret
+//
+//	@Description	Just created personal access token, key is shown only once
+type CreatedAPIKey struct {
+	APIKey
+	// Key must be sent in "X-API-Key" or "Authorization: ApiKey <key>" header
+	Key string `json:"key" example:"shrtnr_3f9a1c2e_9b8c7d6e5f4a3b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d"`
+}
+
+type APIKeyModel struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID `json:"userID" bson:"userID"`
+	Name       string             `json:"name" bson:"name"`
+	Prefix     string             `json:"prefix" bson:"prefix"`
+	SecretHash string             `json:"secretHash" bson:"secretHash"`
+	Scopes     []string           `json:"scopes" bson:"scopes"`
+	ExpiresAt  *time.Time         `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	LastUsedAt *time.Time         `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// Expired reports whether key is expired at the moment t
+func (k APIKeyModel) Expired(t time.Time) bool {
+	return k.ExpiresAt != nil && !t.Before(*k.ExpiresAt)
+}
+
+func (k APIKeyModel) Filter() APIKey {
+	return APIKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
@@ -0,0 +1,109 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audited actions
+const (
+	AuditActionSignUp          = "auth.signed_up"
+	AuditActionSignIn          = "auth.signed_in"
+	AuditActionSignOut         = "auth.signed_out"
+	AuditActionTokensRefreshed = "auth.tokens_refreshed"
+	AuditActionEmailChanged    = "user.email_changed"
+	AuditActionPasswordChanged = "user.password_changed"
+	AuditActionDataExported    = "user.data_exported"
+	AuditActionUsernameChanged = "user.username_changed"
+
+	AuditActionDeletionScheduled = "user.deletion_scheduled"
+	AuditActionDeletionCancelled = "user.deletion_cancelled"
+	AuditActionUserDeleted       = "user.deleted"
+
+	AuditActionUserSuspended      = "user.suspended"
+	AuditActionUserUnsuspended    = "user.unsuspended"
+	AuditActionUserSignedOut      = "user.signed_out"
+	AuditActionUserTwoFactorReset = "user.two_factor_reset"
+	AuditActionUserRoleChanged    = "user.role_changed"
+	AuditActionSignInUnlocked     = "sign_in.unlocked"
+
+	// AuditActionUserDuplicateResolved is recorded by migration, which resolves users sharing unique keys
+	AuditActionUserDuplicateResolved = "user.duplicate_resolved"
+)
+
+// Outcomes of audited actions
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent is a record of security-relevant action
+//
+//	@Description	Record of security-relevant action
+type AuditEvent struct {
+	ID      primitive.ObjectID `json:"id" example:"63d1a2b3c4d5e6f7a8b9c0d1"`
+	Action  string             `json:"action" example:"auth.signed_in"`
+	Outcome string             `json:"outcome" enums:"success,failure" example:"success"`
+	// ActorID is a user who performed action, it's empty when user isn't known, like on failed sign in
+	ActorID *primitive.ObjectID `json:"actorID,omitempty" example:"63a75a2574ef628a127ee972"`
+	// TargetUserID is a user affected by administrative action (optional)
+	TargetUserID *primitive.ObjectID `json:"targetUserID,omitempty" example:"63a75a2574ef628a127ee973"`
+	IP           string              `json:"ip,omitempty" example:"203.0.113.7"`
+	UserAgent    string              `json:"userAgent,omitempty" example:"Mozilla/5.0"`
+	RequestID    string              `json:"requestID,omitempty" example:"3b1f6c0e-5a5e-4b8e-9a8c-2f1d3e4a5b6c"`
+	// Details contains action specific information, like suspension reason
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"createdAt" example:"2023-01-25T21:49:33.072726+02:00"`
+}
+
+// AuditEventsPage is a part of audit events list
+//
+//	@Description	Part of audit events list
+type AuditEventsPage struct {
+	Events []AuditEvent `json:"events"`
+	// Total is a number of events matching the query
+	Total   int64 `json:"total" example:"42"`
+	Page    int   `json:"page" example:"1"`
+	PerPage int   `json:"perPage" example:"20"`
+}
+
+// AuditEventModel records who performed an action, on whom, when and from where
+type AuditEventModel struct {
+	ID      primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Action  string             `json:"action" bson:"action"`
+	Outcome string             `json:"outcome" bson:"outcome"`
+	// ActorID is empty when user isn't known
+	ActorID primitive.ObjectID `json:"actorID,omitempty" bson:"actorID,omitempty"`
+	// TargetUserID is empty for actions which are not related to another user
+	TargetUserID primitive.ObjectID `json:"targetUserID,omitempty" bson:"targetUserID,omitempty"`
+	IP           string             `json:"ip,omitempty" bson:"ip,omitempty"`
+	UserAgent    string             `json:"userAgent,omitempty" bson:"userAgent,omitempty"`
+	RequestID    string             `json:"requestID,omitempty" bson:"requestID,omitempty"`
+	// Details contains action specific information, like suspension reason
+	Details   map[string]string `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt time.Time         `json:"createdAt" bson:"createdAt"`
+}
+
+func (e AuditEventModel) Filter() AuditEvent {
+	return AuditEvent{
+		ID:           e.ID,
+		Action:       e.Action,
+		Outcome:      e.Outcome,
+		ActorID:      objectIDOrNil(e.ActorID),
+		TargetUserID: objectIDOrNil(e.TargetUserID),
+		IP:           e.IP,
+		UserAgent:    e.UserAgent,
+		RequestID:    e.RequestID,
+		Details:      e.Details,
+		CreatedAt:    e.CreatedAt,
+	}
+}
+
+func objectIDOrNil(id primitive.ObjectID) *primitive.ObjectID {
+	if id.IsZero() {
+		return nil
+	}
+
+	return &id
+}
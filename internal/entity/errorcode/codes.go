@@ -6,13 +6,39 @@ package errorcode
 type ErrorCode string
 
 const (
-	ParsingError         ErrorCode = "PARSING_ERROR"
-	InvalidSchema        ErrorCode = "INVALID_SCHEMA"
-	InvalidField         ErrorCode = "INVALID_FIELD"
-	MissingField         ErrorCode = "MISSING_FIELD"
-	AlreadyExists        ErrorCode = "ALREADY_EXISTS"
-	IncorrectCredentials ErrorCode = "INCORRECT_CREDENTIALS"
-	UnauthorizedAccess   ErrorCode = "UNAUTHORIZED_ACCESS"
-	CurrentUserSuspended ErrorCode = "CURRENT_USER_SUSPENDED"
-	InternalError        ErrorCode = "INTERNAL_ERROR"
+	ParsingError               ErrorCode = "PARSING_ERROR"
+	InvalidSchema              ErrorCode = "INVALID_SCHEMA"
+	InvalidField               ErrorCode = "INVALID_FIELD"
+	MissingField               ErrorCode = "MISSING_FIELD"
+	AlreadyExists              ErrorCode = "ALREADY_EXISTS"
+	IncorrectCredentials       ErrorCode = "INCORRECT_CREDENTIALS"
+	UnauthorizedAccess         ErrorCode = "UNAUTHORIZED_ACCESS"
+	CurrentUserSuspended       ErrorCode = "CURRENT_USER_SUSPENDED"
+	IncorrectOTP               ErrorCode = "INCORRECT_OTP"
+	TwoFactorAlreadyEnabled    ErrorCode = "TWO_FACTOR_ALREADY_ENABLED"
+	TwoFactorNotEnabled        ErrorCode = "TWO_FACTOR_NOT_ENABLED"
+	TwoFactorEnrollmentExpired ErrorCode = "TWO_FACTOR_ENROLLMENT_EXPIRED"
+	TooManyAttempts            ErrorCode = "TOO_MANY_ATTEMPTS"
+	InsufficientScope          ErrorCode = "INSUFFICIENT_SCOPE"
+	APIKeyNotFound             ErrorCode = "API_KEY_NOT_FOUND"
+	APIKeysLimitReached        ErrorCode = "API_KEYS_LIMIT_REACHED"
+	IdentityProviderNotFound   ErrorCode = "IDENTITY_PROVIDER_NOT_FOUND"
+	AuthorizationExpired       ErrorCode = "AUTHORIZATION_EXPIRED"
+	EmailNotVerified           ErrorCode = "EMAIL_NOT_VERIFIED"
+	IdentityAlreadyLinked      ErrorCode = "IDENTITY_ALREADY_LINKED"
+	MagicLinkExpired           ErrorCode = "MAGIC_LINK_EXPIRED"
+	CSRFTokenMismatch          ErrorCode = "CSRF_TOKEN_MISMATCH"
+	InsufficientRole           ErrorCode = "INSUFFICIENT_ROLE"
+	UserNotFound               ErrorCode = "USER_NOT_FOUND"
+	SelfAdministration         ErrorCode = "SELF_ADMINISTRATION"
+	DataExportNotFound         ErrorCode = "DATA_EXPORT_NOT_FOUND"
+	DataExportNotReady         ErrorCode = "DATA_EXPORT_NOT_READY"
+	DeletionNotScheduled       ErrorCode = "DELETION_NOT_SCHEDULED"
+	DeletionScheduled          ErrorCode = "DELETION_SCHEDULED"
+	ConfirmationExpired        ErrorCode = "CONFIRMATION_EXPIRED"
+	UsernameChangeCooldown     ErrorCode = "USERNAME_CHANGE_COOLDOWN"
+	InvalidImage               ErrorCode = "INVALID_IMAGE"
+	AvatarNotFound             ErrorCode = "AVATAR_NOT_FOUND"
+	VersionConflict            ErrorCode = "VERSION_CONFLICT"
+	InternalError              ErrorCode = "INTERNAL_ERROR"
 )
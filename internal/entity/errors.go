@@ -3,6 +3,7 @@ package entity
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
 )
@@ -10,7 +11,43 @@ import (
 var (
 	ErrUserNotFound         = errors.New("user not found")
 	ErrUserAlreadyExists    = errors.New("user already exists")
+	ErrUserVersionConflict  = errors.New("user was modified concurrently")
 	ErrIncorrectCredentials = errors.New("incorrect credentials")
+
+	ErrTwoFactorAlreadyEnabled     = errors.New("two-factor authentication already enabled")
+	ErrTwoFactorNotEnabled         = errors.New("two-factor authentication not enabled")
+	ErrTwoFactorEnrollmentNotFound = errors.New("two-factor authentication enrollment not found or expired")
+	ErrIncorrectTwoFactorCode      = errors.New("incorrect two-factor authentication code")
+	ErrMFATokenNotFound            = errors.New("mfa token not found or expired")
+
+	ErrAPIKeyNotFound         = errors.New("api key not found or expired")
+	ErrAPIKeysLimitReached    = errors.New("api keys limit reached")
+	ErrAPIKeyScopesNotAllowed = errors.New("api key scopes not allowed")
+
+	ErrOIDCProviderNotFound      = errors.New("identity provider not found")
+	ErrOIDCStateNotFound         = errors.New("authorization state not found or expired")
+	ErrOIDCAuthenticationFailed  = errors.New("identity provider authentication failed")
+	ErrOIDCEmailNotVerified      = errors.New("identity provider didn't confirm email address")
+	ErrOIDCIdentityAlreadyLinked = errors.New("identity already linked to another user")
+	ErrOIDCEmailInUse            = errors.New("email address already in use, sign in and link identity to your account")
+
+	ErrMagicLinkNotFound = errors.New("magic link not found or expired")
+
+	ErrSelfAdministration = errors.New("administrative action can't be applied to yourself")
+	ErrUnknownRole        = errors.New("unknown role")
+
+	ErrDataExportNotFound   = errors.New("data export not found or expired")
+	ErrDataExportNotReady   = errors.New("data export isn't ready yet")
+	ErrDeletionNotScheduled = errors.New("account deletion isn't scheduled")
+	ErrDeletionScheduled    = errors.New("account is scheduled for deletion, sign in to cancel it")
+
+	ErrDeletionConfirmationSent     = errors.New("deletion confirmation was sent to the email address of the account")
+	ErrDeletionConfirmationNotFound = errors.New("deletion confirmation is invalid or expired")
+
+	ErrAuditEventAlreadyExists = errors.New("audit event already exists")
+
+	ErrUsernameTaken    = errors.New("username is already taken")
+	ErrUnsupportedImage = errors.New("image is corrupted or its format isn't supported")
 )
 
 type SuspendedUserError struct {
@@ -21,6 +58,43 @@ func (e *SuspendedUserError) Error() string {
 	return fmt.Sprintf("user[id:%q] suspended", e.UserID)
 }
 
+// TooManyAttemptsError is returned when sign in attempts are throttled
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("too many attempts, retry after %s", e.RetryAfter)
+}
+
+// UsernameChangeCooldownError is returned when username was changed recently
+type UsernameChangeCooldownError struct {
+	AvailableAt time.Time
+}
+
+func (e *UsernameChangeCooldownError) Error() string {
+	return fmt.Sprintf("username was changed recently, next change is available at %s", e.AvailableAt.Format(time.RFC3339))
+}
+
+// UsernameMovedError is returned when profile is requested by username which user had before the change
+type UsernameMovedError struct {
+	Username string
+}
+
+func (e *UsernameMovedError) Error() string {
+	return fmt.Sprintf("username was changed to %q", e.Username)
+}
+
+// MFARequiredError is returned on sign in when user must pass second authentication factor
+type MFARequiredError struct {
+	UserID    string
+	Challenge MFAChallenge
+}
+
+func (e *MFARequiredError) Error() string {
+	return fmt.Sprintf("user[id:%q]: second authentication factor required", e.UserID)
+}
+
 // CoreError is a basic representation of API call error
 //
 //	@Description	Basic representation of API call error
@@ -0,0 +1,9 @@
+package entity
+
+// OIDCAuthorization is returned when account of identity provider is linked to the signed-in user
+//
+//	@Description	Returned when account of identity provider is linked to the signed-in user
+type OIDCAuthorization struct {
+	// AuthURL is a consent page of the provider which user must be redirected to
+	AuthURL string `json:"authURL" example:"https://accounts.google.com/o/oauth2/v2/auth?client_id=url-shrtnr&state=9f8e7d6c"`
+}
@@ -0,0 +1,20 @@
+package entity
+
+// Scopes restrict operations that may be performed with an access token
+const (
+	// ScopeAccount allows to manage own account: email, password, two-factor authentication and sessions
+	ScopeAccount = "account"
+	// ScopeLinksRead allows to read own links
+	ScopeLinksRead = "links:read"
+	// ScopeLinksWrite allows to create, change and delete own links
+	ScopeLinksWrite = "links:write"
+	// ScopeAdmin allows to perform administrative operations
+	ScopeAdmin = "admin"
+)
+
+// DefaultScopes are granted to every signed-in user
+var DefaultScopes = []string{
+	ScopeAccount,
+	ScopeLinksRead,
+	ScopeLinksWrite,
+}
@@ -0,0 +1,40 @@
+package entity
+
+import "time"
+
+// TwoFactorModel holds user TOTP settings once two-factor authentication is enabled
+type TwoFactorModel struct {
+	TOTPSecret string `json:"totpSecret" bson:"totpSecret"`
+	// RecoveryCodeHashes contains hashes of not yet used one-time recovery codes
+	RecoveryCodeHashes []string  `json:"recoveryCodeHashes" bson:"recoveryCodeHashes"`
+	EnabledAt          time.Time `json:"enabledAt" bson:"enabledAt"`
+}
+
+// TwoFactorEnrollment is a data required to add account into authenticator app
+//
+//	@Description	Data required to add account into authenticator app
+type TwoFactorEnrollment struct {
+	// Secret is a base32 encoded shared secret for manual entry
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	// URI is an otpauth:// key URI
+	URI string `json:"uri" example:"otpauth://totp/url-shrtnr:kenplix?issuer=url-shrtnr&secret=JBSWY3DPEHPK3PXP"`
+	// QRCode is a base64 encoded PNG image of the key URI
+	QRCode []byte `json:"qrCode" swaggertype:"string" format:"base64" example:"iVBORw0KGgo="`
+}
+
+// RecoveryCodes is a set of one-time codes which can be used instead of TOTP codes
+//
+//	@Description	Set of one-time codes which can be used instead of TOTP codes
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes" example:"k3n9x-7pl2q"`
+}
+
+// MFAChallenge is returned on sign in of users with enabled two-factor authentication
+//
+//	@Description	Returned on sign in of users with enabled two-factor authentication
+type MFAChallenge struct {
+	// MFAToken is a short-lived token which should be exchanged together with TOTP or recovery code for tokens pair
+	MFAToken string `json:"mfaToken" example:"4f1c2c7e0d2b4a6f9e8d7c6b5a4f3e2d"`
+	// ExpiresIn is a number of seconds until MFA token expires
+	ExpiresIn int64 `json:"expiresIn" example:"300"`
+}
@@ -10,24 +10,96 @@ import (
 //
 //	@Description	User entity information
 type User struct {
-	ID        primitive.ObjectID `json:"id" example:"63a75a2574ef628a127ee972"`
-	Username  string             `json:"username" example:"kenplix"`
-	Email     string             `json:"email" example:"tolstoi.job@gmail.com"`
-	CreatedAt time.Time          `json:"createdAt" example:"2022-12-24T21:49:33.072726+02:00"`
+	ID       primitive.ObjectID `json:"id" example:"63a75a2574ef628a127ee972"`
+	Username string             `json:"username" example:"kenplix"`
+	Email    string             `json:"email" example:"tolstoi.job@gmail.com"`
+	// DisplayName is a name shown instead of username in public profile (optional)
+	DisplayName string `json:"displayName,omitempty" example:"Oleksandr Tolstoi"`
+	Bio         string `json:"bio,omitempty" example:"Go developer"`
+	// Avatar is a path of avatar image relative to API base path, e.g. GET /api/v1/avatars/{name} (optional)
+	Avatar    string    `json:"avatar,omitempty" example:"avatars/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png"`
+	CreatedAt time.Time `json:"createdAt" example:"2022-12-24T21:49:33.072726+02:00"`
 	// UpdatedAt is a date of last user personal information modification
 	UpdatedAt time.Time `json:"updatedAt" example:"2022-12-24T21:58:27.072726+02:00"`
+	// Role defines which operations are available to the user
+	Role string `json:"role" enums:"user,admin" example:"user"`
 	// SuspendedAt is a date when user was suspended through certain reasons (optional)
 	SuspendedAt *time.Time `json:"suspendedAt,omitempty" example:"2022-12-25T14:25:58.821989+02:00"`
+	// SuspensionReason is a reason stated by administrator who suspended user (optional)
+	SuspensionReason string `json:"suspensionReason,omitempty" example:"spam links"`
+	// TwoFactorEnabled shows whether user signs in with TOTP codes
+	TwoFactorEnabled bool `json:"twoFactorEnabled" example:"false"`
+	// DeletionScheduledAt is a date when account will be permanently deleted unless deletion is cancelled (optional)
+	DeletionScheduledAt *time.Time `json:"deletionScheduledAt,omitempty" example:"2023-01-24T21:49:33.072726+02:00"`
+	// UsernameChangedAt is a date of the last username change (optional)
+	UsernameChangedAt *time.Time `json:"usernameChangedAt,omitempty" example:"2023-01-20T11:02:45.072726+02:00"`
+	// Version is incremented on every change of the user, it's also returned in ETag header
+	Version int64 `json:"version" example:"3"`
 }
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Roles are all roles which may be assigned to the user
+var Roles = []string{RoleUser, RoleAdmin}
+
 type UserModel struct {
-	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Username     string             `json:"username" bson:"username"`
-	Email        string             `json:"email" bson:"email"`
-	PasswordHash string             `json:"passwordHash" bson:"passwordHash"`
-	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
-	UpdatedAt    time.Time          `json:"updatedAt" bson:"updatedAt"`
-	SuspendedAt  *time.Time         `json:"suspendedAt,omitempty" bson:"suspendedAt"`
+	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Username         string             `json:"username" bson:"username"`
+	Email            string             `json:"email" bson:"email"`
+	PasswordHash     string             `json:"passwordHash" bson:"passwordHash"`
+	Role             string             `json:"role,omitempty" bson:"role,omitempty"`
+	CreatedAt        time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt        time.Time          `json:"updatedAt" bson:"updatedAt"`
+	SuspendedAt      *time.Time         `json:"suspendedAt,omitempty" bson:"suspendedAt"`
+	SuspensionReason string             `json:"suspensionReason,omitempty" bson:"suspensionReason,omitempty"`
+	TwoFactor        *TwoFactorModel    `json:"twoFactor,omitempty" bson:"twoFactor,omitempty"`
+	Identities       []IdentityModel    `json:"identities,omitempty" bson:"identities,omitempty"`
+	// DeletionScheduledAt is a date after which account is purged together with owned data
+	DeletionScheduledAt *time.Time `json:"deletionScheduledAt,omitempty" bson:"deletionScheduledAt,omitempty"`
+	DisplayName         string     `json:"displayName,omitempty" bson:"displayName,omitempty"`
+	Bio                 string     `json:"bio,omitempty" bson:"bio,omitempty"`
+	// Avatar is a key of avatar image in blob store
+	Avatar            string     `json:"avatar,omitempty" bson:"avatar,omitempty"`
+	UsernameChangedAt *time.Time `json:"usernameChangedAt,omitempty" bson:"usernameChangedAt,omitempty"`
+	// PreviousUsernames are recently used usernames, which redirect to the current one for a while
+	PreviousUsernames []PreviousUsernameModel `json:"previousUsernames,omitempty" bson:"previousUsernames,omitempty"`
+	// Version is incremented on every write, conditional updates fail if it was changed concurrently
+	Version int64 `json:"version" bson:"version"`
+}
+
+// PreviousUsernameModel is a username which user had before the change
+type PreviousUsernameModel struct {
+	Username  string    `json:"username" bson:"username"`
+	ChangedAt time.Time `json:"changedAt" bson:"changedAt"`
+}
+
+// UserRole returns role of the user. Users created before roles were introduced are ordinary users
+func (u UserModel) UserRole() string {
+	if u.Role == "" {
+		return RoleUser
+	}
+
+	return u.Role
+}
+
+// Scopes returns scopes granted to the user on sign in
+func (u UserModel) Scopes() []string {
+	if u.UserRole() == RoleAdmin {
+		return append(DefaultScopes[:len(DefaultScopes):len(DefaultScopes)], ScopeAdmin)
+	}
+
+	return DefaultScopes
+}
+
+// IdentityModel links user to the account of external OpenID Connect or OAuth2 provider
+type IdentityModel struct {
+	Provider string    `json:"provider" bson:"provider"`
+	Subject  string    `json:"subject" bson:"subject"`
+	LinkedAt time.Time `json:"linkedAt" bson:"linkedAt"`
 }
 
 func (u UserModel) Filter() User {
@@ -35,8 +107,53 @@ func (u UserModel) Filter() User {
 		ID:          u.ID,
 		Username:    u.Username,
 		Email:       u.Email,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		Avatar:      u.Avatar,
 		CreatedAt:   u.CreatedAt,
 		UpdatedAt:   u.UpdatedAt,
+		Role:        u.UserRole(),
 		SuspendedAt: u.SuspendedAt,
+
+		SuspensionReason: u.SuspensionReason,
+		TwoFactorEnabled: u.TwoFactor != nil,
+
+		DeletionScheduledAt: u.DeletionScheduledAt,
+		UsernameChangedAt:   u.UsernameChangedAt,
+		Version:             u.Version,
+	}
+}
+
+// Profile returns publicly visible information of the user
+func (u UserModel) Profile() Profile {
+	return Profile{
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		Avatar:      u.Avatar,
+		CreatedAt:   u.CreatedAt,
 	}
 }
+
+// Profile is a publicly visible information of the user
+//
+//	@Description	Publicly visible information of the user
+type Profile struct {
+	Username    string `json:"username" example:"kenplix"`
+	DisplayName string `json:"displayName,omitempty" example:"Oleksandr Tolstoi"`
+	Bio         string `json:"bio,omitempty" example:"Go developer"`
+	// Avatar is a path of avatar image relative to API base path, e.g. GET /api/v1/avatars/{name} (optional)
+	Avatar    string    `json:"avatar,omitempty" example:"avatars/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png"`
+	CreatedAt time.Time `json:"createdAt" example:"2022-12-24T21:49:33.072726+02:00"`
+}
+
+// UsersPage is a part of users list
+//
+//	@Description	Part of users list
+type UsersPage struct {
+	Users []User `json:"users"`
+	// Total is a number of users matching the search
+	Total   int64 `json:"total" example:"42"`
+	Page    int   `json:"page" example:"1"`
+	PerPage int   `json:"perPage" example:"20"`
+}
@@ -0,0 +1,565 @@
+package repository
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+const (
+	// ArchiveFormatVersion is incremented every time layout of the archive or its documents changes
+	ArchiveFormatVersion = 1
+	archiveManifestFile  = "manifest.json"
+	// archiveBatchSize is a number of documents read from the repository at once
+	archiveBatchSize = 500
+	// restoreCheckpointInterval is a number of restored documents after which restore state is saved
+	restoreCheckpointInterval = 100
+)
+
+// ArchiveManifest is the first entry of the archive, it describes collections following it
+type ArchiveManifest struct {
+	FormatVersion int       `json:"formatVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	// Source is a database backend the archive was dumped from
+	Source      string              `json:"source"`
+	Collections []ArchiveCollection `json:"collections"`
+}
+
+// ArchiveCollection is a collection stored as a file of newline delimited JSON documents
+type ArchiveCollection struct {
+	Name      string `json:"name"`
+	File      string `json:"file"`
+	Documents int64  `json:"documents"`
+	// SHA256 is a hex encoded checksum of the file
+	SHA256 string `json:"sha256"`
+}
+
+type RestoreOptions struct {
+	// StatePath is a file progress of restore is saved to, so interrupted restore is resumed.
+	// Archive path with ".state" suffix is used by default
+	StatePath string
+}
+
+// RestoredCollection counts documents of the collection. Documents which are already stored are skipped
+type RestoredCollection struct {
+	Name     string
+	Restored int64
+	Skipped  int64
+}
+
+// archiveCollections are in order of dump and restore, documents referencing users follow them
+var archiveCollections = []string{usersCollection, apiKeysCollection, auditCollection}
+
+// Dump writes all documents of the repositories to gzip compressed tar archive. Collections are read one
+// by one, so writes made while dump is in progress may be partially included, stop the application to take
+// consistent backup
+func Dump(ctx context.Context, repos *Repositories, source string, w io.Writer) (ArchiveManifest, error) {
+	dir, err := os.MkdirTemp("", "url-shrtnr-dump-*")
+	if err != nil {
+		return ArchiveManifest{}, errors.Wrap(err, "failed to create temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	writers := make(map[string]*archiveCollectionWriter, len(archiveCollections))
+
+	for _, name := range archiveCollections {
+		writer, err := newArchiveCollectionWriter(filepath.Join(dir, name+".ndjson"))
+		if err != nil {
+			return ArchiveManifest{}, err
+		}
+		defer writer.file.Close()
+
+		writers[name] = writer
+	}
+
+	if err = dumpUsers(ctx, repos, writers[usersCollection], writers[apiKeysCollection]); err != nil {
+		return ArchiveManifest{}, errors.Wrap(err, "failed to dump users")
+	}
+
+	if err = dumpAudit(ctx, repos.Audit, writers[auditCollection]); err != nil {
+		return ArchiveManifest{}, errors.Wrap(err, "failed to dump audit events")
+	}
+
+	manifest := ArchiveManifest{
+		FormatVersion: ArchiveFormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Source:        source,
+	}
+
+	for _, name := range archiveCollections {
+		coll, err := writers[name].finish(name)
+		if err != nil {
+			return ArchiveManifest{}, errors.Wrapf(err, "failed to write %q collection", name)
+		}
+
+		manifest.Collections = append(manifest.Collections, coll)
+	}
+
+	if err = writeArchive(w, manifest, dir); err != nil {
+		return ArchiveManifest{}, errors.Wrap(err, "failed to write archive")
+	}
+
+	return manifest, nil
+}
+
+// dumpUsers writes users in order of creation together with their api keys
+func dumpUsers(ctx context.Context, repos *Repositories, users, apiKeys *archiveCollectionWriter) error {
+	for offset := int64(0); ; offset += archiveBatchSize {
+		batch, _, err := repos.Users.List(ctx, ListUsersSchema{Offset: offset, Limit: archiveBatchSize})
+		if err != nil {
+			return err
+		}
+
+		for _, user := range batch {
+			if err = users.write(user); err != nil {
+				return err
+			}
+
+			keys, err := repos.APIKeys.FindByUserID(ctx, user.ID)
+			if err != nil {
+				return errors.Wrapf(err, "failed to find api keys of %s user", user.ID.Hex())
+			}
+
+			for _, key := range keys {
+				if err = apiKeys.write(key); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(batch) < archiveBatchSize {
+			return nil
+		}
+	}
+}
+
+// dumpAudit writes events from oldest to newest, so they're restored in order of creation
+func dumpAudit(ctx context.Context, audit AuditRepository, events *archiveCollectionWriter) error {
+	_, total, err := audit.Find(ctx, FindAuditEventsSchema{Limit: 1})
+	if err != nil {
+		return err
+	}
+
+	// events are found from newest to oldest, so pages are read from the end
+	for end := total; end > 0; end -= archiveBatchSize {
+		offset := lo.Max([]int64{end - archiveBatchSize, 0})
+
+		batch, _, err := audit.Find(ctx, FindAuditEventsSchema{Offset: offset, Limit: end - offset})
+		if err != nil {
+			return err
+		}
+
+		for i := len(batch) - 1; i >= 0; i-- {
+			if err = events.write(batch[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type archiveCollectionWriter struct {
+	file      *os.File
+	buf       *bufio.Writer
+	hash      hash.Hash
+	encoder   *json.Encoder
+	documents int64
+}
+
+func newArchiveCollectionWriter(path string) (*archiveCollectionWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %q", path)
+	}
+
+	w := &archiveCollectionWriter{
+		file: file,
+		buf:  bufio.NewWriter(file),
+		hash: sha256.New(),
+	}
+
+	// encoder terminates every document with newline
+	w.encoder = json.NewEncoder(io.MultiWriter(w.buf, w.hash))
+
+	return w, nil
+}
+
+func (w *archiveCollectionWriter) write(doc any) error {
+	if err := w.encoder.Encode(doc); err != nil {
+		return errors.Wrap(err, "failed to encode document")
+	}
+
+	w.documents++
+
+	return nil
+}
+
+func (w *archiveCollectionWriter) finish(name string) (ArchiveCollection, error) {
+	if err := w.buf.Flush(); err != nil {
+		return ArchiveCollection{}, err
+	}
+
+	coll := ArchiveCollection{
+		Name:      name,
+		File:      filepath.Base(w.file.Name()),
+		Documents: w.documents,
+		SHA256:    hex.EncodeToString(w.hash.Sum(nil)),
+	}
+
+	return coll, nil
+}
+
+// writeArchive writes manifest followed by files of collections, which are stored in the directory
+func writeArchive(w io.Writer, manifest ArchiveManifest, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode manifest")
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    archiveManifestFile,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: manifest.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = tw.Write(data); err != nil {
+		return err
+	}
+
+	for _, coll := range manifest.Collections {
+		if err = writeArchiveFile(tw, filepath.Join(dir, coll.File), manifest.CreatedAt); err != nil {
+			return errors.Wrapf(err, "failed to write %q file", coll.File)
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+func writeArchiveFile(tw *tar.Writer, path string, modTime time.Time) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    filepath.Base(path),
+		Mode:    0o600,
+		Size:    info.Size(),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, file)
+
+	return err
+}
+
+// restoreState is a progress of restore, documents of the archive are restored in order,
+// so only number of restored documents is kept for every collection
+type restoreState struct {
+	// Archive is a checksum of the manifest, so state of another archive isn't resumed
+	Archive  string           `json:"archive"`
+	Restored map[string]int64 `json:"restored"`
+}
+
+// Restore writes documents of the archive to the repositories. Archive is verified before anything is
+// written. Documents which are already stored are skipped, so restore can be repeated, and progress is
+// saved to the state file, so interrupted restore is resumed where it stopped
+func Restore(ctx context.Context, repos *Repositories, path string, opts RestoreOptions) ([]RestoredCollection, error) {
+	manifest, checksum, err := verifyArchive(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to verify %q archive", path)
+	}
+
+	statePath := lo.Ternary(opts.StatePath != "", opts.StatePath, path+".state")
+
+	state := restoreState{Archive: checksum, Restored: make(map[string]int64)}
+	if err = readJSONFile(statePath, &state); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %q restore state", statePath)
+	}
+
+	if state.Archive != checksum {
+		return nil, fmt.Errorf("restore state %q belongs to another archive, remove it to restore from the beginning", statePath)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tr, closeArchive, err := openArchive(file)
+	if err != nil {
+		return nil, err
+	}
+	defer closeArchive()
+
+	// manifest was read while archive was verified
+	if _, err = nextArchiveFile(tr, archiveManifestFile); err != nil {
+		return nil, err
+	}
+
+	restorers := map[string]func(ctx context.Context, doc json.RawMessage) (bool, error){
+		usersCollection:   repos.restoreUser,
+		apiKeysCollection: repos.restoreAPIKey,
+		auditCollection:   repos.restoreAuditEvent,
+	}
+
+	saveState := func() error {
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+
+		return errors.Wrapf(writeFileAtomic(statePath, data), "failed to save %q restore state", statePath)
+	}
+
+	report := make([]RestoredCollection, 0, len(manifest.Collections))
+
+	for _, coll := range manifest.Collections {
+		if _, err = nextArchiveFile(tr, coll.File); err != nil {
+			return report, err
+		}
+
+		restored := RestoredCollection{Name: coll.Name}
+		decoder := json.NewDecoder(tr)
+
+		for i := int64(0); decoder.More(); i++ {
+			var doc json.RawMessage
+			if err = decoder.Decode(&doc); err != nil {
+				return report, errors.Wrapf(err, "failed to decode document %d of %q collection", i+1, coll.Name)
+			}
+
+			// documents restored before interruption
+			if i < state.Restored[coll.Name] {
+				continue
+			}
+
+			stored, err := restorers[coll.Name](ctx, doc)
+			if err != nil {
+				_ = saveState()
+				return report, errors.Wrapf(err, "failed to restore document %d of %q collection", i+1, coll.Name)
+			}
+
+			if stored {
+				restored.Restored++
+			} else {
+				restored.Skipped++
+			}
+
+			state.Restored[coll.Name] = i + 1
+
+			if state.Restored[coll.Name]%restoreCheckpointInterval == 0 {
+				if err = saveState(); err != nil {
+					return report, err
+				}
+			}
+		}
+
+		if err = saveState(); err != nil {
+			return report, err
+		}
+
+		report = append(report, restored)
+	}
+
+	if err = os.Remove(statePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return report, errors.Wrapf(err, "failed to remove %q restore state", statePath)
+	}
+
+	return report, nil
+}
+
+// verifyArchive reads the whole archive and checks that every collection matches its checksum.
+// Manifest is returned together with its checksum
+func verifyArchive(path string) (ArchiveManifest, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ArchiveManifest{}, "", err
+	}
+	defer file.Close()
+
+	tr, closeArchive, err := openArchive(file)
+	if err != nil {
+		return ArchiveManifest{}, "", err
+	}
+	defer closeArchive()
+
+	if _, err = nextArchiveFile(tr, archiveManifestFile); err != nil {
+		return ArchiveManifest{}, "", err
+	}
+
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return ArchiveManifest{}, "", errors.Wrap(err, "failed to read manifest")
+	}
+
+	var manifest ArchiveManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return ArchiveManifest{}, "", errors.Wrap(err, "failed to decode manifest")
+	}
+
+	if manifest.FormatVersion != ArchiveFormatVersion {
+		return ArchiveManifest{}, "", fmt.Errorf("archive format version %d isn't supported, %d expected",
+			manifest.FormatVersion, ArchiveFormatVersion)
+	}
+
+	names := lo.Map(manifest.Collections, func(coll ArchiveCollection, _ int) string { return coll.Name })
+	if !lo.Every(names, archiveCollections) || len(names) != len(archiveCollections) {
+		return ArchiveManifest{}, "", fmt.Errorf("archive collections %v don't match %v", names, archiveCollections)
+	}
+
+	for _, coll := range manifest.Collections {
+		if _, err = nextArchiveFile(tr, coll.File); err != nil {
+			return ArchiveManifest{}, "", err
+		}
+
+		h := sha256.New()
+		decoder := json.NewDecoder(io.TeeReader(tr, h))
+
+		var documents int64
+		for ; decoder.More(); documents++ {
+			var doc json.RawMessage
+			if err = decoder.Decode(&doc); err != nil {
+				return ArchiveManifest{}, "", errors.Wrapf(err, "failed to decode document %d of %q collection", documents+1, coll.Name)
+			}
+		}
+
+		// trailing newline isn't consumed by decoder
+		if _, err = io.Copy(io.Discard, io.TeeReader(tr, h)); err != nil {
+			return ArchiveManifest{}, "", err
+		}
+
+		if hex.EncodeToString(h.Sum(nil)) != coll.SHA256 {
+			return ArchiveManifest{}, "", fmt.Errorf("checksum of %q collection doesn't match, archive is corrupted", coll.Name)
+		}
+
+		if documents != coll.Documents {
+			return ArchiveManifest{}, "", fmt.Errorf("%q collection has %d documents, %d expected", coll.Name, documents, coll.Documents)
+		}
+	}
+
+	checksum := sha256.Sum256(data)
+
+	return manifest, hex.EncodeToString(checksum[:]), nil
+}
+
+func openArchive(r io.Reader) (*tar.Reader, func(), error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decompress archive")
+	}
+
+	return tar.NewReader(gz), func() { gz.Close() }, nil
+}
+
+// nextArchiveFile moves reader to the next file, which must have provided name
+func nextArchiveFile(tr *tar.Reader, name string) (*tar.Header, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %q file", name)
+	}
+
+	if header.Name != name {
+		return nil, fmt.Errorf("%q file expected, %q found", name, header.Name)
+	}
+
+	return header, nil
+}
+
+// restoreUser stores the user, unless user with the same ID exists
+func (r *Repositories) restoreUser(ctx context.Context, doc json.RawMessage) (bool, error) {
+	var user entity.UserModel
+	if err := json.Unmarshal(doc, &user); err != nil {
+		return false, err
+	}
+
+	_, err := r.Users.FindByID(ctx, user.ID)
+	if err == nil {
+		return false, nil
+	} else if !errors.Is(err, entity.ErrUserNotFound) {
+		return false, err
+	}
+
+	if err = r.Users.Create(ctx, user); err != nil {
+		return false, errors.Wrapf(err, "failed to create %s user", user.ID.Hex())
+	}
+
+	return true, nil
+}
+
+// restoreAPIKey stores the key, unless key with the same prefix exists
+func (r *Repositories) restoreAPIKey(ctx context.Context, doc json.RawMessage) (bool, error) {
+	var key entity.APIKeyModel
+	if err := json.Unmarshal(doc, &key); err != nil {
+		return false, err
+	}
+
+	stored, err := r.APIKeys.FindByPrefix(ctx, key.Prefix)
+	if err == nil {
+		if stored.ID != key.ID {
+			return false, fmt.Errorf("%s api key conflicts with stored %s api key", key.ID.Hex(), stored.ID.Hex())
+		}
+
+		return false, nil
+	} else if !errors.Is(err, entity.ErrAPIKeyNotFound) {
+		return false, err
+	}
+
+	if err = r.APIKeys.Create(ctx, key); err != nil {
+		return false, errors.Wrapf(err, "failed to create %s api key", key.ID.Hex())
+	}
+
+	return true, nil
+}
+
+func (r *Repositories) restoreAuditEvent(ctx context.Context, doc json.RawMessage) (bool, error) {
+	var event entity.AuditEventModel
+	if err := json.Unmarshal(doc, &event); err != nil {
+		return false, err
+	}
+
+	err := r.Audit.Create(ctx, event)
+	if errors.Is(err, entity.ErrAuditEventAlreadyExists) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrapf(err, "failed to create %s audit event", event.ID.Hex())
+	}
+
+	return true, nil
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+// newTestArchive dumps memory database with users, their api keys and audit events
+func newTestArchive(t *testing.T, users int) (*Repositories, string) {
+	t.Helper()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	source, err := New(ctx, Config{Use: "memory"})
+	require.NoError(t, err)
+
+	for i := 0; i < users; i++ {
+		user := entity.UserModel{
+			ID:         primitive.NewObjectID(),
+			Username:   fmt.Sprintf("user%d", i),
+			Email:      fmt.Sprintf("user%d@example.com", i),
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			Identities: []entity.IdentityModel{{Provider: "keycloak", Subject: fmt.Sprintf("<subject%d>", i), LinkedAt: now}},
+		}
+		require.NoError(t, source.Users.Create(ctx, user))
+
+		key := entity.APIKeyModel{ID: primitive.NewObjectID(), UserID: user.ID, Prefix: fmt.Sprintf("prefix%d", i), CreatedAt: now}
+		require.NoError(t, source.APIKeys.Create(ctx, key))
+
+		event := entity.AuditEventModel{ID: primitive.NewObjectID(), Action: entity.AuditActionSignUp, ActorID: user.ID, CreatedAt: now}
+		require.NoError(t, source.Audit.Create(ctx, event))
+	}
+
+	path := filepath.Join(t.TempDir(), "dump.tar.gz")
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	manifest, err := Dump(ctx, source, "memory", file)
+	require.NoError(t, err)
+	assert.Equal(t, ArchiveFormatVersion, manifest.FormatVersion)
+
+	for _, coll := range manifest.Collections {
+		assert.Equalf(t, int64(users), coll.Documents, "documents of %q collection", coll.Name)
+	}
+
+	return source, path
+}
+
+func TestDumpAndRestore(t *testing.T) {
+	ctx := context.Background()
+	source, path := newTestArchive(t, archiveBatchSize+1)
+
+	target, err := New(ctx, Config{Use: "filedb", FileDB: FileDBConfig{Path: t.TempDir()}})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = target.Close(ctx) })
+
+	report, err := Restore(ctx, target, path, RestoreOptions{})
+	require.NoError(t, err)
+
+	for _, coll := range report {
+		assert.Equalf(t, int64(archiveBatchSize+1), coll.Restored, "restored documents of %q collection", coll.Name)
+	}
+
+	assert.NoFileExists(t, path+".state", "state of completed restore must be removed")
+
+	sourceUsers, _, err := source.Users.List(ctx, ListUsersSchema{})
+	require.NoError(t, err)
+
+	targetUsers, _, err := target.Users.List(ctx, ListUsersSchema{})
+	require.NoError(t, err)
+
+	require.Len(t, targetUsers, len(sourceUsers))
+
+	for i := range sourceUsers {
+		assert.Equal(t, normalizeUser(sourceUsers[i]), normalizeUser(targetUsers[i]))
+	}
+
+	sourceEvents, _, err := source.Audit.Find(ctx, FindAuditEventsSchema{})
+	require.NoError(t, err)
+
+	targetEvents, _, err := target.Audit.Find(ctx, FindAuditEventsSchema{})
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		lo.Map(sourceEvents, func(e entity.AuditEventModel, _ int) primitive.ObjectID { return e.ID }),
+		lo.Map(targetEvents, func(e entity.AuditEventModel, _ int) primitive.ObjectID { return e.ID }),
+		"events must be restored in order of creation",
+	)
+
+	keys, err := target.APIKeys.FindByUserID(ctx, sourceUsers[0].ID)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	report, err = Restore(ctx, target, path, RestoreOptions{})
+	require.NoError(t, err, "restore must be idempotent")
+
+	for _, coll := range report {
+		assert.Zerof(t, coll.Restored, "documents of %q collection must not be restored twice", coll.Name)
+		assert.Equalf(t, int64(archiveBatchSize+1), coll.Skipped, "documents of %q collection must be skipped", coll.Name)
+	}
+}
+
+func TestRestore_Resume(t *testing.T) {
+	ctx := context.Background()
+	_, path := newTestArchive(t, 3)
+
+	_, checksum, err := verifyArchive(path)
+	require.NoError(t, err)
+
+	// restore was interrupted after the first user
+	statePath := filepath.Join(t.TempDir(), "restore.state")
+	state, err := json.Marshal(restoreState{Archive: checksum, Restored: map[string]int64{usersCollection: 1}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(statePath, state, 0o600))
+
+	target, err := New(ctx, Config{Use: "memory"})
+	require.NoError(t, err)
+
+	report, err := Restore(ctx, target, path, RestoreOptions{StatePath: statePath})
+	require.NoError(t, err)
+	assert.Equal(t, RestoredCollection{Name: usersCollection, Restored: 2}, report[0], "restored users must not be read again")
+
+	_, err = target.Users.FindByUsername(ctx, "user0")
+	assert.ErrorIs(t, err, entity.ErrUserNotFound)
+
+	_, err = target.Users.FindByUsername(ctx, "user2")
+	assert.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"archive":"another"}`), 0o600))
+
+	_, err = Restore(ctx, target, path, RestoreOptions{StatePath: statePath})
+	assert.Error(t, err, "state of another archive must not be resumed")
+}
+
+func TestRestore_CorruptedArchive(t *testing.T) {
+	ctx := context.Background()
+	_, path := newTestArchive(t, 2)
+
+	// every file of the archive is rewritten, one line of users collection is altered
+	file, err := os.Open(path)
+	require.NoError(t, err)
+
+	tr, closeArchive, err := openArchive(file)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+
+		if header.Name == usersCollection+".ndjson" {
+			data = bytes.Replace(data, []byte("user1"), []byte("userX"), 1)
+		}
+
+		require.NoError(t, tw.WriteHeader(header))
+		_, err = tw.Write(data)
+		require.NoError(t, err)
+	}
+
+	closeArchive()
+	file.Close()
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+	target, err := New(ctx, Config{Use: "memory"})
+	require.NoError(t, err)
+
+	_, err = Restore(ctx, target, path, RestoreOptions{})
+	assert.ErrorContains(t, err, "checksum")
+
+	_, total, err := target.Users.List(ctx, ListUsersSchema{})
+	require.NoError(t, err)
+	assert.Zero(t, total, "nothing must be restored from corrupted archive")
+}
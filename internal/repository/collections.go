@@ -1,3 +1,14 @@
 package repository
 
-const usersCollection = "users"
+const (
+	usersCollection   = "users"
+	apiKeysCollection = "apiKeys"
+	auditCollection   = "audit"
+)
+
+const (
+	// migrationsCollection keeps versions of applied migrations
+	migrationsCollection = "schemaMigrations"
+	// migrationsLockCollection keeps lock of the instance running migrations
+	migrationsLockCollection = "schemaMigrationsLock"
+)
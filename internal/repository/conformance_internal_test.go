@@ -0,0 +1,1007 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+// testMongoDBURIEnv points tests to running server, local server is used by default
+const testMongoDBURIEnv = "URL_SHRTNR_TEST_MONGODB_URI"
+
+// conformanceBackends create empty repositories of every backend, backends which aren't available are skipped
+var conformanceBackends = []struct {
+	name    string
+	users   func(t *testing.T) UsersRepository
+	apiKeys func(t *testing.T) APIKeysRepository
+	audit   func(t *testing.T) AuditRepository
+}{
+	{
+		name: "filedb",
+		users: func(t *testing.T) UsersRepository {
+			db := newTestFileDB(t)
+			require.NoError(t, db.createUsersRepository())
+
+			return db.users
+		},
+		apiKeys: func(t *testing.T) APIKeysRepository {
+			db := newTestFileDB(t)
+			require.NoError(t, db.createAPIKeysRepository())
+
+			return db.apiKeys
+		},
+		audit: func(t *testing.T) AuditRepository {
+			db := newTestFileDB(t)
+			require.NoError(t, db.createAuditRepository(AuditConfig{}))
+
+			return db.audit
+		},
+	},
+	{
+		name: "memory",
+		users: func(t *testing.T) UsersRepository {
+			db := newMemoryDB()
+			require.NoError(t, db.createUsersRepository())
+
+			return db.users
+		},
+		apiKeys: func(t *testing.T) APIKeysRepository {
+			db := newMemoryDB()
+			require.NoError(t, db.createAPIKeysRepository())
+
+			return db.apiKeys
+		},
+		audit: func(t *testing.T) AuditRepository {
+			db := newMemoryDB()
+			require.NoError(t, db.createAuditRepository(AuditConfig{}))
+
+			return db.audit
+		},
+	},
+	{
+		name: "mongodb",
+		users: func(t *testing.T) UsersRepository {
+			db := newTestMongoDB(t)
+			require.NoError(t, db.createUsersRepository())
+
+			return db.users
+		},
+		apiKeys: func(t *testing.T) APIKeysRepository {
+			db := newTestMongoDB(t)
+			require.NoError(t, db.createAPIKeysRepository())
+
+			return db.apiKeys
+		},
+		audit: func(t *testing.T) AuditRepository {
+			db := newTestMongoDB(t)
+			require.NoError(t, db.createAuditRepository(context.Background(), AuditConfig{}))
+
+			return db.audit
+		},
+	},
+	{
+		name: "postgres",
+		users: func(t *testing.T) UsersRepository {
+			return newTestPostgresDB(t).users
+		},
+		apiKeys: func(t *testing.T) APIKeysRepository {
+			return newTestPostgresDB(t).apiKeys
+		},
+		audit: func(t *testing.T) AuditRepository {
+			return newTestPostgresDB(t).audit
+		},
+	},
+	{
+		name: "sqlite",
+		users: func(t *testing.T) UsersRepository {
+			return newTestSQLiteDB(t, SQLiteConfig{Path: sqliteMemoryPath}).users
+		},
+		apiKeys: func(t *testing.T) APIKeysRepository {
+			return newTestSQLiteDB(t, SQLiteConfig{Path: sqliteMemoryPath}).apiKeys
+		},
+		audit: func(t *testing.T) AuditRepository {
+			return newTestSQLiteDB(t, SQLiteConfig{Path: sqliteMemoryPath}).audit
+		},
+	},
+}
+
+// newTestFileDB creates empty database in temporary directory, which is closed after the test
+func newTestFileDB(t *testing.T) *fileDB {
+	t.Helper()
+
+	db, err := newFileDB(FileDBConfig{Path: t.TempDir()})
+	require.NoErrorf(t, err, "failed to create filedb: %s", err)
+	t.Cleanup(func() { _ = db.close(context.Background()) })
+
+	return db
+}
+
+var testMongoDB struct {
+	once sync.Once
+	err  error
+}
+
+// newTestMongoDB connects to empty database, which is dropped after the test.
+// Test is skipped if server isn't available
+func newTestMongoDB(t *testing.T) *mongoDB {
+	t.Helper()
+
+	uri := os.Getenv(testMongoDBURIEnv)
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+
+	cfg := MongoDBConfig{URI: uri, Database: "test_" + primitive.NewObjectID().Hex()}
+
+	// server isn't looked for again after the first failure
+	testMongoDB.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		var db *mongoDB
+
+		if db, testMongoDB.err = newMongoDB(ctx, cfg); testMongoDB.err == nil {
+			_ = db.close(ctx)
+		}
+	})
+
+	if testMongoDB.err != nil {
+		t.Skipf("mongodb isn't available: %s", testMongoDB.err)
+	}
+
+	ctx := context.Background()
+
+	db, err := newMongoDB(ctx, cfg)
+	require.NoErrorf(t, err, "failed to create mongodb: %s", err)
+
+	t.Cleanup(func() {
+		_ = db.db.Drop(ctx)
+		_ = db.close(ctx)
+	})
+
+	_, err = migrate(ctx, db, MigrateOptions{Direction: MigrateUp})
+	require.NoErrorf(t, err, "failed to migrate mongodb: %s", err)
+
+	return db
+}
+
+func TestUsersRepositoryConformance(t *testing.T) {
+	t.Parallel()
+
+	for _, backend := range conformanceBackends {
+		backend := backend
+
+		t.Run(backend.name, func(t *testing.T) {
+			t.Parallel()
+			testUsersRepository(t, backend.users)
+		})
+	}
+}
+
+// testUsersRepository checks behaviour every UsersRepository implementation must follow.
+// newRepository must return empty repository
+func testUsersRepository(t *testing.T, newRepository func(t *testing.T) UsersRepository) {
+	// mongodb keeps dates with millisecond precision
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	newUser := func(name string) entity.UserModel {
+		return entity.UserModel{
+			ID:           primitive.NewObjectID(),
+			Username:     name,
+			Email:        name + "@example.com",
+			PasswordHash: "<hash>",
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			Version:      1,
+		}
+	}
+
+	t.Run("stored user is found", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		user := newUser("user")
+		user.Role = entity.RoleAdmin
+		user.DisplayName = "User"
+		user.Identities = []entity.IdentityModel{{Provider: "keycloak", Subject: "<subject>", LinkedAt: now}}
+		user.TwoFactor = &entity.TwoFactorModel{TOTPSecret: "<secret>", RecoveryCodeHashes: []string{"<code>"}, EnabledAt: now}
+		user.PreviousUsernames = []entity.PreviousUsernameModel{{Username: "previous", ChangedAt: now}}
+		user.UsernameChangedAt = &now
+
+		require.NoError(t, r.Create(ctx, user))
+
+		finders := map[string]func() (entity.UserModel, error){
+			"id":                func() (entity.UserModel, error) { return r.FindByID(ctx, user.ID) },
+			"username":          func() (entity.UserModel, error) { return r.FindByUsername(ctx, user.Username) },
+			"email":             func() (entity.UserModel, error) { return r.FindByEmail(ctx, user.Email) },
+			"login by username": func() (entity.UserModel, error) { return r.FindByLogin(ctx, user.Username) },
+			"login by email":    func() (entity.UserModel, error) { return r.FindByLogin(ctx, user.Email) },
+			// emails are compared case-insensitively, usernames aren't
+			"email in another case":          func() (entity.UserModel, error) { return r.FindByEmail(ctx, strings.ToUpper(user.Email)) },
+			"login by email in another case": func() (entity.UserModel, error) { return r.FindByLogin(ctx, strings.ToUpper(user.Email)) },
+			"identity":                       func() (entity.UserModel, error) { return r.FindByIdentity(ctx, "keycloak", "<subject>") },
+			"previous username": func() (entity.UserModel, error) {
+				return r.FindByPreviousUsername(ctx, "previous", now.Add(-time.Second))
+			},
+		}
+
+		for by, find := range finders {
+			found, err := find()
+			if assert.NoErrorf(t, err, "find by %s", by) {
+				assert.Equalf(t, normalizeUser(user), normalizeUser(found), "find by %s", by)
+			}
+		}
+
+		err := r.Create(ctx, entity.UserModel{Username: "generated", Email: "generated@example.com", CreatedAt: now, UpdatedAt: now})
+		require.NoError(t, err)
+
+		found, err := r.FindByUsername(ctx, "generated")
+		require.NoError(t, err)
+		assert.False(t, found.ID.IsZero(), "ID must be assigned if it's empty")
+		assert.Equal(t, int64(1), found.Version, "the first version must be assigned if it's empty")
+	})
+
+	t.Run("missing user isn't found", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+		missingID := primitive.NewObjectID()
+
+		require.NoError(t, r.Create(ctx, newUser("user")))
+
+		finders := map[string]func() (entity.UserModel, error){
+			"id":                       func() (entity.UserModel, error) { return r.FindByID(ctx, missingID) },
+			"username":                 func() (entity.UserModel, error) { return r.FindByUsername(ctx, "missing") },
+			"email":                    func() (entity.UserModel, error) { return r.FindByEmail(ctx, "missing@example.com") },
+			"login":                    func() (entity.UserModel, error) { return r.FindByLogin(ctx, "missing") },
+			"username in another case": func() (entity.UserModel, error) { return r.FindByUsername(ctx, "USER") },
+			"identity":                 func() (entity.UserModel, error) { return r.FindByIdentity(ctx, "keycloak", "<missing>") },
+			"previous username":        func() (entity.UserModel, error) { return r.FindByPreviousUsername(ctx, "missing", time.Time{}) },
+		}
+
+		for by, find := range finders {
+			_, err := find()
+			assert.ErrorIsf(t, err, entity.ErrUserNotFound, "find by %s", by)
+		}
+
+		changes := map[string]error{
+			"change email":          r.ChangeEmail(ctx, ChangeEmailSchema{UserID: missingID, NewEmail: "new@example.com"}),
+			"change password":       r.ChangePassword(ctx, ChangePasswordSchema{UserID: missingID, NewPasswordHash: "<hash>"}),
+			"change username":       r.ChangeUsername(ctx, ChangeUsernameSchema{UserID: missingID, NewUsername: "new", ChangedAt: now}),
+			"update profile":        r.UpdateProfile(ctx, UpdateProfileSchema{UserID: missingID, DisplayName: "New"}),
+			"change avatar":         r.ChangeAvatar(ctx, ChangeAvatarSchema{UserID: missingID, Avatar: "<avatar>"}),
+			"enable two factor":     r.EnableTwoFactor(ctx, EnableTwoFactorSchema{UserID: missingID}),
+			"disable two factor":    r.DisableTwoFactor(ctx, missingID),
+			"change recovery codes": r.ChangeRecoveryCodes(ctx, ChangeRecoveryCodesSchema{UserID: missingID}),
+			"link identity":         r.LinkIdentity(ctx, LinkIdentitySchema{UserID: missingID, Identity: entity.IdentityModel{Provider: "keycloak", Subject: "<new>"}}),
+			"set role":              r.SetRole(ctx, SetRoleSchema{UserID: missingID, Role: entity.RoleAdmin}),
+			"suspend":               r.Suspend(ctx, SuspendUserSchema{UserID: missingID, SuspendedAt: now}),
+			"unsuspend":             r.Unsuspend(ctx, missingID),
+			"schedule deletion":     r.ScheduleDeletion(ctx, ScheduleDeletionSchema{UserID: missingID, DeletionScheduledAt: now}),
+			"cancel deletion":       r.CancelDeletion(ctx, missingID),
+			"delete":                r.Delete(ctx, missingID),
+		}
+
+		for change, err := range changes {
+			assert.ErrorIsf(t, err, entity.ErrUserNotFound, change)
+		}
+
+		users, total, err := r.List(ctx, ListUsersSchema{Search: "missing"})
+		require.NoError(t, err)
+		assert.Zero(t, total)
+		assert.NotNil(t, users, "empty list must be returned")
+		assert.Empty(t, users)
+
+		users, err = r.FindScheduledForDeletion(ctx, now)
+		require.NoError(t, err)
+		assert.NotNil(t, users, "empty list must be returned")
+		assert.Empty(t, users)
+	})
+
+	t.Run("duplicates are rejected", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		existing := newUser("existing")
+		existing.Identities = []entity.IdentityModel{{Provider: "keycloak", Subject: "<subject>", LinkedAt: now}}
+		user := newUser("user")
+
+		require.NoError(t, r.Create(ctx, existing))
+		require.NoError(t, r.Create(ctx, user))
+
+		taken := newUser("other")
+		taken.ID = existing.ID
+
+		testCases := []struct {
+			name string
+			err  error
+			got  error
+		}{
+			{
+				name: "create with taken ID",
+				err:  entity.ErrUserAlreadyExists,
+				got:  r.Create(ctx, taken),
+			},
+			{
+				name: "create with taken username",
+				err:  entity.ErrUserAlreadyExists,
+				got:  r.Create(ctx, entity.UserModel{Username: existing.Username, Email: "other@example.com", CreatedAt: now}),
+			},
+			{
+				name: "create with taken email",
+				err:  entity.ErrUserAlreadyExists,
+				got:  r.Create(ctx, entity.UserModel{Username: "other", Email: existing.Email, CreatedAt: now}),
+			},
+			{
+				name: "create with taken email in another case",
+				err:  entity.ErrUserAlreadyExists,
+				got:  r.Create(ctx, entity.UserModel{Username: "other", Email: strings.ToUpper(existing.Email), CreatedAt: now}),
+			},
+			{
+				name: "change to taken email",
+				err:  entity.ErrUserAlreadyExists,
+				got:  r.ChangeEmail(ctx, ChangeEmailSchema{UserID: user.ID, NewEmail: existing.Email}),
+			},
+			{
+				name: "change to taken email in another case",
+				err:  entity.ErrUserAlreadyExists,
+				got:  r.ChangeEmail(ctx, ChangeEmailSchema{UserID: user.ID, NewEmail: "Existing@Example.com"}),
+			},
+			{
+				name: "change to taken username",
+				err:  entity.ErrUsernameTaken,
+				got:  r.ChangeUsername(ctx, ChangeUsernameSchema{UserID: user.ID, NewUsername: existing.Username, ChangedAt: now}),
+			},
+			{
+				name: "link taken identity",
+				err:  entity.ErrOIDCIdentityAlreadyLinked,
+				got:  r.LinkIdentity(ctx, LinkIdentitySchema{UserID: user.ID, Identity: existing.Identities[0]}),
+			},
+		}
+
+		for _, tc := range testCases {
+			assert.ErrorIs(t, tc.got, tc.err, tc.name)
+		}
+
+		found, err := r.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, normalizeUser(user), normalizeUser(found), "rejected changes must not be applied")
+
+		_, total, err := r.List(ctx, ListUsersSchema{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total, "rejected users must not be created")
+	})
+
+	t.Run("concurrent writers", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		const writers = 8
+
+		user := newUser("user")
+		require.NoError(t, r.Create(ctx, user))
+
+		var (
+			wg      sync.WaitGroup
+			created = make(chan error, writers)
+		)
+
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				// every writer tries to take the same username
+				candidate := newUser("taken")
+				candidate.Email = fmt.Sprintf("taken%d@example.com", i)
+				created <- r.Create(ctx, candidate)
+
+				identity := entity.IdentityModel{Provider: "keycloak", Subject: fmt.Sprintf("<subject%d>", i), LinkedAt: now}
+				assert.NoError(t, r.LinkIdentity(ctx, LinkIdentitySchema{UserID: user.ID, Identity: identity}))
+			}(i)
+		}
+
+		wg.Wait()
+		close(created)
+
+		var succeeded int
+
+		for err := range created {
+			if err == nil {
+				succeeded++
+			} else {
+				assert.ErrorIs(t, err, entity.ErrUserAlreadyExists)
+			}
+		}
+
+		assert.Equal(t, 1, succeeded, "unique username must be taken only once")
+
+		found, err := r.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Len(t, found.Identities, writers, "concurrent changes must not be lost")
+	})
+
+	t.Run("recovery code is removed once", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		const consumers = 8
+
+		user := newUser("user")
+		user.TwoFactor = &entity.TwoFactorModel{TOTPSecret: "<secret>", RecoveryCodeHashes: []string{"<a>", "<b>", "<c>"}}
+		require.NoError(t, r.Create(ctx, user))
+
+		var (
+			wg      sync.WaitGroup
+			removed = make(chan error, consumers)
+		)
+
+		for i := 0; i < consumers; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				// every consumer tries to use the same code, like concurrent sign in requests
+				removed <- r.RemoveRecoveryCode(ctx, RemoveRecoveryCodeSchema{UserID: user.ID, RecoveryCodeHash: "<b>"})
+			}()
+		}
+
+		wg.Wait()
+		close(removed)
+
+		var succeeded int
+
+		for err := range removed {
+			if err == nil {
+				succeeded++
+			} else {
+				assert.ErrorIs(t, err, entity.ErrIncorrectTwoFactorCode)
+			}
+		}
+
+		assert.Equal(t, 1, succeeded, "recovery code must be used only once")
+
+		found, err := r.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.NotNil(t, found.TwoFactor)
+		assert.Equal(t, []string{"<a>", "<c>"}, found.TwoFactor.RecoveryCodeHashes)
+		assert.Equal(t, user.Version+1, found.Version)
+
+		require.NoError(t, r.RemoveRecoveryCode(ctx, RemoveRecoveryCodeSchema{UserID: user.ID, RecoveryCodeHash: "<a>"}))
+		require.NoError(t, r.RemoveRecoveryCode(ctx, RemoveRecoveryCodeSchema{UserID: user.ID, RecoveryCodeHash: "<c>"}))
+
+		found, err = r.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.NotNil(t, found.TwoFactor, "two-factor authentication must stay enabled without recovery codes")
+		assert.Empty(t, found.TwoFactor.RecoveryCodeHashes)
+
+		other := newUser("other")
+		require.NoError(t, r.Create(ctx, other))
+
+		err = r.RemoveRecoveryCode(ctx, RemoveRecoveryCodeSchema{UserID: other.ID, RecoveryCodeHash: "<a>"})
+		assert.ErrorIs(t, err, entity.ErrIncorrectTwoFactorCode, "user without two-factor authentication has no codes")
+	})
+
+	t.Run("versioned changes", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		user := newUser("user")
+		require.NoError(t, r.Create(ctx, user))
+
+		testCases := []struct {
+			name   string
+			change func(userID primitive.ObjectID, version int64) error
+		}{
+			{
+				name: "change email",
+				change: func(userID primitive.ObjectID, version int64) error {
+					return r.ChangeEmail(ctx, ChangeEmailSchema{UserID: userID, NewEmail: "changed@example.com", Version: version})
+				},
+			},
+			{
+				name: "change password",
+				change: func(userID primitive.ObjectID, version int64) error {
+					return r.ChangePassword(ctx, ChangePasswordSchema{UserID: userID, NewPasswordHash: "<changed>", Version: version})
+				},
+			},
+			{
+				name: "change username",
+				change: func(userID primitive.ObjectID, version int64) error {
+					return r.ChangeUsername(ctx, ChangeUsernameSchema{UserID: userID, NewUsername: "changed", ChangedAt: now, Version: version})
+				},
+			},
+			{
+				name: "update profile",
+				change: func(userID primitive.ObjectID, version int64) error {
+					return r.UpdateProfile(ctx, UpdateProfileSchema{UserID: userID, Bio: "changed", Version: version})
+				},
+			},
+			{
+				name: "change avatar",
+				change: func(userID primitive.ObjectID, version int64) error {
+					return r.ChangeAvatar(ctx, ChangeAvatarSchema{UserID: userID, Avatar: "avatars/changed.png", Version: version})
+				},
+			},
+		}
+
+		version := user.Version
+
+		for _, tc := range testCases {
+			require.NoErrorf(t, tc.change(user.ID, version), "%s with the current version", tc.name)
+
+			found, err := r.FindByID(ctx, user.ID)
+			require.NoError(t, err)
+			assert.Equalf(t, version+1, found.Version, "%s must increment version", tc.name)
+
+			err = tc.change(user.ID, version)
+			assert.ErrorIsf(t, err, entity.ErrUserVersionConflict, "%s with stale version must be rejected", tc.name)
+
+			err = tc.change(primitive.NewObjectID(), version)
+			assert.ErrorIsf(t, err, entity.ErrUserNotFound, "%s of missing user must not be reported as conflict", tc.name)
+
+			version = found.Version
+		}
+
+		require.NoError(t, r.Suspend(ctx, SuspendUserSchema{UserID: user.ID, SuspendedAt: now, Reason: "spam"}))
+		require.NoError(t, r.ChangeAvatar(ctx, ChangeAvatarSchema{UserID: user.ID}), "change without version must not be checked")
+		require.NoError(t, r.SetRole(ctx, SetRoleSchema{UserID: user.ID, Role: entity.RoleAdmin}))
+
+		found, err := r.FindByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, version+3, found.Version, "every change must increment version")
+		assert.Empty(t, found.Avatar)
+		assert.Equal(t, entity.RoleAdmin, found.Role)
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		r := newRepository(t)
+
+		user := newUser("user")
+		require.NoError(t, r.Create(context.Background(), user))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := r.Create(ctx, newUser("canceled"))
+		assert.ErrorIs(t, err, context.Canceled)
+
+		err = r.ChangeEmail(ctx, ChangeEmailSchema{UserID: user.ID, NewEmail: "canceled@example.com"})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = r.FindByID(ctx, user.ID)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, _, err = r.List(ctx, ListUsersSchema{})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = r.FindByUsername(context.Background(), "canceled")
+		assert.ErrorIs(t, err, entity.ErrUserNotFound, "user must not be created with canceled context")
+
+		found, err := r.FindByID(context.Background(), user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user.Email, found.Email, "user must not be changed with canceled context")
+	})
+}
+
+func TestAPIKeysRepositoryConformance(t *testing.T) {
+	t.Parallel()
+
+	for _, backend := range conformanceBackends {
+		backend := backend
+
+		t.Run(backend.name, func(t *testing.T) {
+			t.Parallel()
+			testAPIKeysRepository(t, backend.apiKeys)
+		})
+	}
+}
+
+// testAPIKeysRepository checks behaviour every APIKeysRepository implementation must follow.
+// newRepository must return empty repository
+func testAPIKeysRepository(t *testing.T, newRepository func(t *testing.T) APIKeysRepository) {
+	// mongodb keeps dates with millisecond precision
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	newKey := func(userID primitive.ObjectID, prefix string, createdAt time.Time) entity.APIKeyModel {
+		return entity.APIKeyModel{
+			ID:         primitive.NewObjectID(),
+			UserID:     userID,
+			Name:       prefix,
+			Prefix:     prefix,
+			SecretHash: "<hash>",
+			Scopes:     []string{entity.ScopeLinksRead},
+			CreatedAt:  createdAt,
+		}
+	}
+
+	t.Run("stored keys are found", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		userID := primitive.NewObjectID()
+		expiresAt := now.Add(time.Hour)
+
+		first := newKey(userID, "first", now.Add(-time.Minute))
+		first.ExpiresAt = &expiresAt
+		second := newKey(userID, "second", now)
+		other := newKey(primitive.NewObjectID(), "other", now)
+
+		// keys are created out of order, so order of creation dates is checked
+		for _, key := range []entity.APIKeyModel{second, other, first} {
+			require.NoError(t, r.Create(ctx, key))
+		}
+
+		found, err := r.FindByPrefix(ctx, first.Prefix)
+		require.NoError(t, err)
+		assert.Equal(t, normalizeAPIKey(first), normalizeAPIKey(found))
+
+		keys, err := r.FindByUserID(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t,
+			[]entity.APIKeyModel{normalizeAPIKey(first), normalizeAPIKey(second)},
+			lo.Map(keys, func(key entity.APIKeyModel, _ int) entity.APIKeyModel { return normalizeAPIKey(key) }),
+			"keys of the user must be found in order of creation",
+		)
+
+		generated := newKey(userID, "generated", now)
+		generated.ID = primitive.NilObjectID
+		require.NoError(t, r.Create(ctx, generated))
+
+		found, err = r.FindByPrefix(ctx, generated.Prefix)
+		require.NoError(t, err)
+		assert.False(t, found.ID.IsZero(), "ID must be assigned if it's empty")
+	})
+
+	t.Run("missing keys aren't found", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		key := newKey(primitive.NewObjectID(), "key", now)
+		require.NoError(t, r.Create(ctx, key))
+
+		_, err := r.FindByPrefix(ctx, "missing")
+		assert.ErrorIs(t, err, entity.ErrAPIKeyNotFound)
+
+		keys, err := r.FindByUserID(ctx, primitive.NewObjectID())
+		require.NoError(t, err)
+		assert.NotNil(t, keys, "empty list must be returned")
+		assert.Empty(t, keys)
+
+		err = r.Delete(ctx, DeleteAPIKeySchema{UserID: key.UserID, KeyID: primitive.NewObjectID()})
+		assert.ErrorIs(t, err, entity.ErrAPIKeyNotFound)
+
+		err = r.Delete(ctx, DeleteAPIKeySchema{UserID: primitive.NewObjectID(), KeyID: key.ID})
+		assert.ErrorIs(t, err, entity.ErrAPIKeyNotFound, "key of another user must not be deleted")
+
+		err = r.UpdateLastUsed(ctx, UpdateAPIKeyLastUsedSchema{KeyID: primitive.NewObjectID(), LastUsedAt: now})
+		assert.ErrorIs(t, err, entity.ErrAPIKeyNotFound)
+
+		_, err = r.FindByPrefix(ctx, key.Prefix)
+		assert.NoError(t, err, "key must be kept after rejected deletion")
+	})
+
+	t.Run("duplicate prefix is rejected", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		require.NoError(t, r.Create(ctx, newKey(primitive.NewObjectID(), "prefix", now)))
+		assert.Error(t, r.Create(ctx, newKey(primitive.NewObjectID(), "prefix", now)))
+
+		_, err := r.FindByPrefix(ctx, "prefix")
+		require.NoError(t, err)
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		userID := primitive.NewObjectID()
+		first := newKey(userID, "first", now)
+		second := newKey(userID, "second", now)
+		other := newKey(primitive.NewObjectID(), "other", now)
+
+		for _, key := range []entity.APIKeyModel{first, second, other} {
+			require.NoError(t, r.Create(ctx, key))
+		}
+
+		lastUsedAt := now.Add(time.Minute)
+		require.NoError(t, r.UpdateLastUsed(ctx, UpdateAPIKeyLastUsedSchema{KeyID: first.ID, LastUsedAt: lastUsedAt}))
+
+		found, err := r.FindByPrefix(ctx, first.Prefix)
+		require.NoError(t, err)
+		require.NotNil(t, found.LastUsedAt)
+		assert.Equal(t, lastUsedAt, found.LastUsedAt.UTC())
+
+		require.NoError(t, r.Delete(ctx, DeleteAPIKeySchema{UserID: userID, KeyID: first.ID}))
+
+		_, err = r.FindByPrefix(ctx, first.Prefix)
+		assert.ErrorIs(t, err, entity.ErrAPIKeyNotFound)
+
+		require.NoError(t, r.DeleteByUserID(ctx, userID))
+		require.NoError(t, r.DeleteByUserID(ctx, userID), "deletion of user without keys must succeed")
+
+		keys, err := r.FindByUserID(ctx, userID)
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+
+		_, err = r.FindByPrefix(ctx, other.Prefix)
+		assert.NoError(t, err, "keys of other users must be kept")
+	})
+}
+
+func TestAuditRepositoryConformance(t *testing.T) {
+	t.Parallel()
+
+	for _, backend := range conformanceBackends {
+		backend := backend
+
+		t.Run(backend.name, func(t *testing.T) {
+			t.Parallel()
+			testAuditRepository(t, backend.audit)
+		})
+	}
+}
+
+// testAuditRepository checks behaviour every AuditRepository implementation must follow.
+// newRepository must return empty repository
+func testAuditRepository(t *testing.T, newRepository func(t *testing.T) AuditRepository) {
+	// mongodb keeps dates with millisecond precision
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	var (
+		actorID  = primitive.NewObjectID()
+		targetID = primitive.NewObjectID()
+	)
+
+	// events are sorted from oldest to newest
+	events := []entity.AuditEventModel{
+		{
+			ID:        primitive.NewObjectID(),
+			Action:    entity.AuditActionSignIn,
+			Outcome:   entity.AuditOutcomeFailure,
+			IP:        "203.0.113.7",
+			Details:   map[string]string{"reason": "unknown_login"},
+			CreatedAt: now.Add(-3 * time.Minute),
+		},
+		{
+			ID:        primitive.NewObjectID(),
+			Action:    entity.AuditActionSignIn,
+			Outcome:   entity.AuditOutcomeSuccess,
+			ActorID:   actorID,
+			IP:        "203.0.113.8",
+			UserAgent: "curl/7.88.1",
+			RequestID: "<request id>",
+			CreatedAt: now.Add(-2 * time.Minute),
+		},
+		{
+			ID:           primitive.NewObjectID(),
+			Action:       entity.AuditActionUserSuspended,
+			Outcome:      entity.AuditOutcomeSuccess,
+			ActorID:      actorID,
+			TargetUserID: targetID,
+			IP:           "203.0.113.8",
+			Details:      map[string]string{"reason": "spam links"},
+			CreatedAt:    now.Add(-time.Minute),
+		},
+		{
+			ID:        primitive.NewObjectID(),
+			Action:    entity.AuditActionSignIn,
+			Outcome:   entity.AuditOutcomeSuccess,
+			ActorID:   targetID,
+			IP:        "203.0.113.9",
+			CreatedAt: now,
+		},
+	}
+
+	// events are created out of order, so order of creation dates is checked
+	create := func(t *testing.T, r AuditRepository) {
+		t.Helper()
+
+		for _, i := range []int{1, 0, 3, 2} {
+			require.NoError(t, r.Create(context.Background(), events[i]))
+		}
+	}
+
+	// newest returns events with provided indexes from newest to oldest
+	newest := func(indexes ...int) []entity.AuditEventModel {
+		found := make([]entity.AuditEventModel, 0, len(indexes))
+		for i := len(indexes) - 1; i >= 0; i-- {
+			found = append(found, normalizeAuditEvent(events[indexes[i]]))
+		}
+
+		return found
+	}
+
+	t.Run("stored events are found", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		create(t, r)
+
+		testCases := []struct {
+			name   string
+			schema FindAuditEventsSchema
+			found  []entity.AuditEventModel
+			total  int64
+		}{
+			{
+				name:   "all",
+				schema: FindAuditEventsSchema{},
+				found:  newest(0, 1, 2, 3),
+				total:  4,
+			},
+			{
+				name:   "performed by or on the user",
+				schema: FindAuditEventsSchema{UserID: targetID},
+				found:  newest(2, 3),
+				total:  2,
+			},
+			{
+				name:   "of the action",
+				schema: FindAuditEventsSchema{Action: entity.AuditActionSignIn},
+				found:  newest(0, 1, 3),
+				total:  3,
+			},
+			{
+				name:   "of the user and action",
+				schema: FindAuditEventsSchema{UserID: actorID, Action: entity.AuditActionSignIn},
+				found:  newest(1),
+				total:  1,
+			},
+			{
+				name:   "page",
+				schema: FindAuditEventsSchema{Offset: 1, Limit: 2},
+				found:  newest(1, 2),
+				total:  4,
+			},
+			{
+				name:   "page after the last one",
+				schema: FindAuditEventsSchema{Offset: 4, Limit: 2},
+				found:  []entity.AuditEventModel{},
+				total:  4,
+			},
+			{
+				name:   "missing user",
+				schema: FindAuditEventsSchema{UserID: primitive.NewObjectID()},
+				found:  []entity.AuditEventModel{},
+				total:  0,
+			},
+		}
+
+		for _, tc := range testCases {
+			found, total, err := r.Find(ctx, tc.schema)
+			if assert.NoError(t, err, tc.name) {
+				assert.Equal(t, tc.total, total, tc.name)
+				assert.NotNil(t, found, "%s: empty list must be returned", tc.name)
+				assert.Equal(t, tc.found, lo.Map(found, func(event entity.AuditEventModel, _ int) entity.AuditEventModel {
+					return normalizeAuditEvent(event)
+				}), tc.name)
+			}
+		}
+	})
+
+	t.Run("duplicates are rejected", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		create(t, r)
+
+		duplicate := events[0]
+		duplicate.Action = entity.AuditActionUserDeleted
+
+		err := r.Create(ctx, duplicate)
+		assert.ErrorIs(t, err, entity.ErrAuditEventAlreadyExists)
+
+		err = r.Create(ctx, entity.AuditEventModel{Action: entity.AuditActionUserDeleted, CreatedAt: now})
+		require.NoError(t, err, "ID must be assigned if it's empty")
+
+		_, total, err := r.Find(ctx, FindAuditEventsSchema{Action: entity.AuditActionUserDeleted})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total, "rejected events must not be created")
+	})
+
+	t.Run("anonymize", func(t *testing.T) {
+		r := newRepository(t)
+		ctx := context.Background()
+
+		create(t, r)
+
+		require.NoError(t, r.Anonymize(ctx, targetID))
+		require.NoError(t, r.Anonymize(ctx, primitive.NewObjectID()), "anonymization of user without events must succeed")
+
+		anonymized := func(event entity.AuditEventModel) entity.AuditEventModel {
+			event.IP = ""
+			event.UserAgent = ""
+			event.Details = nil
+
+			return event
+		}
+
+		expected := newest(0, 1)
+		expected = append([]entity.AuditEventModel{
+			normalizeAuditEvent(anonymized(events[3])),
+			normalizeAuditEvent(anonymized(events[2])),
+		}, expected...)
+
+		found, _, err := r.Find(ctx, FindAuditEventsSchema{})
+		require.NoError(t, err)
+		assert.Equal(t, expected, lo.Map(found, func(event entity.AuditEventModel, _ int) entity.AuditEventModel {
+			return normalizeAuditEvent(event)
+		}), "only events performed by or on the user must be anonymized")
+	})
+}
+
+// normalizeUser converts dates to UTC, so users read from different backends are comparable
+func normalizeUser(user entity.UserModel) entity.UserModel {
+	utc := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+
+		converted := t.UTC()
+
+		return &converted
+	}
+
+	user.CreatedAt = user.CreatedAt.UTC()
+	user.UpdatedAt = user.UpdatedAt.UTC()
+	user.SuspendedAt = utc(user.SuspendedAt)
+	user.DeletionScheduledAt = utc(user.DeletionScheduledAt)
+	user.UsernameChangedAt = utc(user.UsernameChangedAt)
+
+	if user.TwoFactor != nil {
+		twoFactor := *user.TwoFactor
+		twoFactor.EnabledAt = twoFactor.EnabledAt.UTC()
+		user.TwoFactor = &twoFactor
+	}
+
+	user.Identities = append([]entity.IdentityModel(nil), user.Identities...)
+	for i := range user.Identities {
+		user.Identities[i].LinkedAt = user.Identities[i].LinkedAt.UTC()
+	}
+
+	user.PreviousUsernames = append([]entity.PreviousUsernameModel(nil), user.PreviousUsernames...)
+	for i := range user.PreviousUsernames {
+		user.PreviousUsernames[i].ChangedAt = user.PreviousUsernames[i].ChangedAt.UTC()
+	}
+
+	return user
+}
+
+// normalizeAPIKey converts dates to UTC, so keys read from different backends are comparable
+func normalizeAPIKey(key entity.APIKeyModel) entity.APIKeyModel {
+	utc := func(t *time.Time) *time.Time {
+		if t == nil {
+			return nil
+		}
+
+		converted := t.UTC()
+
+		return &converted
+	}
+
+	key.CreatedAt = key.CreatedAt.UTC()
+	key.ExpiresAt = utc(key.ExpiresAt)
+	key.LastUsedAt = utc(key.LastUsedAt)
+
+	return key
+}
+
+// normalizeAuditEvent converts date to UTC, so events read from different backends are comparable
+func normalizeAuditEvent(event entity.AuditEventModel) entity.AuditEventModel {
+	event.CreatedAt = event.CreatedAt.UTC()
+	return event
+}
@@ -1,20 +1,41 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultFileDBCompactThreshold = 1000
+	fileDBLockFile                = "LOCK"
 )
 
 type FileDBConfig struct {
 	Path string `mapstructure:"path"`
+	// CompactThreshold is a number of write-ahead log records after which
+	// the snapshot of the collection is rewritten and the log is truncated
+	CompactThreshold int `mapstructure:"compactThreshold"`
 }
 
 type fileDB struct {
-	users *fileDBUsersRepository
-	dir   string
+	users            *fileDBUsersRepository
+	apiKeys          *fileDBAPIKeysRepository
+	audit            *fileDBAuditRepository
+	dir              string
+	compactThreshold int
+	lock             *os.File
+	// migrations are versions of applied migrations, they're read on the first run of migrations
+	migrations    map[int64]time.Time
+	migrationsMux sync.Mutex
 }
 
 func newFileDB(cfg FileDBConfig) (*fileDB, error) {
@@ -28,9 +49,334 @@ func newFileDB(cfg FileDBConfig) (*fileDB, error) {
 		return nil, errors.Wrapf(err, "failed to create %q director(y/ies)", path)
 	}
 
-	return &fileDB{dir: path}, nil
+	lock, err := lockFileDB(filepath.Join(path, fileDBLockFile))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to lock %q directory, it may be used by another instance", path)
+	}
+
+	f := &fileDB{
+		dir:              path,
+		compactThreshold: lo.Ternary(cfg.CompactThreshold > 0, cfg.CompactThreshold, defaultFileDBCompactThreshold),
+		lock:             lock,
+	}
+
+	return f, nil
+}
+
+// newMemoryDB returns database, which keeps collections only in memory, so its data is lost on close
+func newMemoryDB() *fileDB {
+	return &fileDB{
+		compactThreshold: defaultFileDBCompactThreshold,
+	}
+}
+
+// inMemory reports whether database was created with newMemoryDB
+func (f *fileDB) inMemory() bool {
+	return f.dir == ""
 }
 
 func (f *fileDB) close(_ context.Context) error {
+	if f.users != nil {
+		if err := f.users.close(); err != nil {
+			return errors.Wrap(err, "failed to close users collection")
+		}
+	}
+
+	if f.apiKeys != nil {
+		if err := f.apiKeys.close(); err != nil {
+			return errors.Wrap(err, "failed to close api keys collection")
+		}
+	}
+
+	if f.audit != nil {
+		if err := f.audit.close(); err != nil {
+			return errors.Wrap(err, "failed to close audit log")
+		}
+	}
+
+	if f.lock == nil {
+		return nil
+	}
+
+	err := unlockFileDB(f.lock)
+	f.lock = nil
+
+	return err
+}
+
+// fileDBLogRecord is a line of the write-ahead log. Put contains the whole document,
+// so replay of records already included into the snapshot doesn't change it
+type fileDBLogRecord struct {
+	Put    json.RawMessage      `json:"put,omitempty"`
+	Delete []primitive.ObjectID `json:"delete,omitempty"`
+}
+
+// fileDBCollection is a collection persisted with fileDBLog, it's encoded as a snapshot
+type fileDBCollection interface {
+	// build is called when snapshot is loaded, before the log is replayed
+	build() error
+	// apply applies replayed record of the log
+	apply(record fileDBLogRecord) error
+}
+
+// fileDBLog persists collection as a snapshot and a write-ahead log of changes made after it.
+// Every change is appended to the log and synced before it's applied in memory, so acknowledged
+// changes survive a crash. Snapshot is replaced atomically when the log is compacted.
+// Log of in-memory database has no files, changes are only applied.
+// Callers must serialize calls of the log
+type fileDBLog struct {
+	snapshotPath string
+	wal          *os.File
+	records      int
+	threshold    int
+	coll         fileDBCollection
+}
+
+// openLog loads snapshot of the collection and replays its write-ahead log.
+// Replayed records are compacted into the new snapshot right away
+func (f *fileDB) openLog(name string, coll fileDBCollection) (*fileDBLog, error) {
+	if f.inMemory() {
+		return &fileDBLog{threshold: f.compactThreshold, coll: coll}, coll.build()
+	}
+
+	l := &fileDBLog{
+		snapshotPath: filepath.Join(f.dir, name+".json"),
+		threshold:    f.compactThreshold,
+		coll:         coll,
+	}
+
+	if err := readJSONFile(l.snapshotPath, coll); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %q snapshot", l.snapshotPath)
+	}
+
+	if err := coll.build(); err != nil {
+		return nil, errors.Wrapf(err, "failed to build indexes of %q snapshot", l.snapshotPath)
+	}
+
+	walPath := filepath.Join(f.dir, name+".wal")
+
+	records, err := replayLog(walPath, coll.apply)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to replay %q log", walPath)
+	}
+
+	l.wal, err = os.OpenFile(walPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %q log", walPath)
+	}
+
+	if records > 0 {
+		if err = l.compact(); err != nil {
+			l.wal.Close()
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// put writes the document to the log and applies it with apply
+func (l *fileDBLog) put(doc any, apply func()) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode document")
+	}
+
+	return l.commit(fileDBLogRecord{Put: data}, apply)
+}
+
+// delete writes removal of the documents to the log and applies it with apply
+func (l *fileDBLog) delete(ids []primitive.ObjectID, apply func()) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return l.commit(fileDBLogRecord{Delete: ids}, apply)
+}
+
+func (l *fileDBLog) commit(record fileDBLogRecord, apply func()) error {
+	if l.wal == nil {
+		apply()
+		return nil
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode log record")
+	}
+
+	if err = l.append(append(line, '\n')); err != nil {
+		return err
+	}
+
+	apply()
+	l.records++
+
+	if l.records >= l.threshold {
+		// change is already durable in the log, failed compaction is retried on the next change
+		_ = l.compact()
+	}
+
 	return nil
 }
+
+// append writes the line to the log and syncs it. On failure the log is truncated back to its
+// previous size, so a partially written record isn't followed by the next acknowledged one.
+// The log is opened in append mode, hence writes always go to its end and no seek is needed
+func (l *fileDBLog) append(line []byte) error {
+	info, err := l.wal.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %q log", l.wal.Name())
+	}
+
+	_, err = l.wal.Write(line)
+	if err != nil {
+		err = errors.Wrap(err, "failed to write log record")
+	} else if err = l.wal.Sync(); err != nil {
+		err = errors.Wrap(err, "failed to sync log")
+	}
+
+	if err != nil {
+		if truncErr := l.wal.Truncate(info.Size()); truncErr != nil {
+			return errors.Wrapf(err, "failed to cut off partially written record: %s", truncErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// compact replaces snapshot with the current state of the collection and truncates the log.
+// Crash between these steps only makes already applied records to be replayed again
+func (l *fileDBLog) compact() error {
+	if l.wal == nil {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(l.coll, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode snapshot")
+	}
+
+	if err = writeFileAtomic(l.snapshotPath, data); err != nil {
+		return errors.Wrapf(err, "failed to write %q snapshot", l.snapshotPath)
+	}
+
+	if err = l.wal.Truncate(0); err != nil {
+		return errors.Wrapf(err, "failed to truncate %q log", l.wal.Name())
+	}
+
+	if err = l.wal.Sync(); err != nil {
+		return errors.Wrapf(err, "failed to sync %q log", l.wal.Name())
+	}
+
+	l.records = 0
+
+	return nil
+}
+
+func (l *fileDBLog) close() error {
+	if l.wal == nil {
+		return nil
+	}
+
+	if err := l.compact(); err != nil {
+		l.wal.Close()
+		return err
+	}
+
+	return l.wal.Close()
+}
+
+// replayLog applies complete records of the log and returns their number. Last line without
+// newline is a record torn by a crash, it was never acknowledged, so it's cut off
+func replayLog(path string, apply func(record fileDBLogRecord) error) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	var records, offset int
+
+	for {
+		end := bytes.IndexByte(data[offset:], '\n')
+		if end < 0 {
+			break
+		}
+
+		var record fileDBLogRecord
+		if err = json.Unmarshal(data[offset:offset+end], &record); err != nil {
+			return 0, errors.Wrapf(err, "record %d: failed to decode", records+1)
+		}
+
+		if err = apply(record); err != nil {
+			return 0, errors.Wrapf(err, "record %d: failed to apply", records+1)
+		}
+
+		records++
+		offset += end + 1
+	}
+
+	if offset < len(data) {
+		if err = os.Truncate(path, int64(offset)); err != nil {
+			return 0, errors.Wrap(err, "failed to cut off torn record")
+		}
+	}
+
+	return records, nil
+}
+
+// readJSONFile decodes content of the file into v, missing file is left as is
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// writeFileAtomic writes data to temporary file, which replaces the file after it's synced,
+// so readers never see partially written file
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "failed to write %q file", tmp.Name())
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "failed to sync %q file", tmp.Name())
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close %q file", tmp.Name())
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrapf(err, "failed to rename %q file", tmp.Name())
+	}
+
+	return syncDir(dir)
+}
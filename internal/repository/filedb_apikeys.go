@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+// indexes of the api keys collection, they mirror indexes of mongodb collection
+const (
+	apiKeysPrefixIndex = "prefix"
+	apiKeysUserIDIndex = "userID"
+)
+
+type fileDBAPIKeysRepository struct {
+	APIKeys []entity.APIKeyModel `json:"apiKeys"`
+	log     *fileDBLog
+	indexes *fileDBIndexes[entity.APIKeyModel]
+	mux     sync.RWMutex
+}
+
+func (f *fileDB) createAPIKeysRepository() error {
+	r := &fileDBAPIKeysRepository{
+		indexes: newFileDBIndexes(
+			func(key entity.APIKeyModel) primitive.ObjectID { return key.ID },
+			&fileDBIndex[entity.APIKeyModel]{
+				name:   apiKeysPrefixIndex,
+				keys:   func(key entity.APIKeyModel) []string { return []string{key.Prefix} },
+				unique: true,
+			},
+			&fileDBIndex[entity.APIKeyModel]{
+				name: apiKeysUserIDIndex,
+				keys: func(key entity.APIKeyModel) []string { return []string{key.UserID.Hex()} },
+			},
+		),
+	}
+
+	log, err := f.openLog(apiKeysCollection, r)
+	if err != nil {
+		return err
+	}
+
+	r.log = log
+	f.apiKeys = r
+
+	return nil
+}
+
+func (f *fileDB) getAPIKeysRepository() APIKeysRepository {
+	return f.apiKeys
+}
+
+func (r *fileDBAPIKeysRepository) Create(ctx context.Context, key entity.APIKeyModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, found := r.indexes.find(key.ID); found {
+		return &fileDBDuplicateKeyError{index: "_id", key: key.ID.Hex()}
+	}
+
+	return r.save(key)
+}
+
+func (r *fileDBAPIKeysRepository) FindByPrefix(ctx context.Context, prefix string) (entity.APIKeyModel, error) {
+	if err := ctx.Err(); err != nil {
+		return entity.APIKeyModel{}, err
+	}
+
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	positions := r.indexes.lookup(apiKeysPrefixIndex, prefix)
+	if len(positions) == 0 {
+		return entity.APIKeyModel{}, entity.ErrAPIKeyNotFound
+	}
+
+	return r.APIKeys[positions[0]], nil
+}
+
+func (r *fileDBAPIKeysRepository) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]entity.APIKeyModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	return r.findByUserID(userID), nil
+}
+
+func (r *fileDBAPIKeysRepository) Delete(ctx context.Context, schema DeleteAPIKeySchema) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	i, found := r.indexes.find(schema.KeyID)
+	if !found || r.APIKeys[i].UserID != schema.UserID {
+		return entity.ErrAPIKeyNotFound
+	}
+
+	return r.delete([]primitive.ObjectID{schema.KeyID})
+}
+
+func (r *fileDBAPIKeysRepository) UpdateLastUsed(ctx context.Context, schema UpdateAPIKeyLastUsedSchema) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	i, found := r.indexes.find(schema.KeyID)
+	if !found {
+		return entity.ErrAPIKeyNotFound
+	}
+
+	key := r.APIKeys[i]
+	lastUsedAt := schema.LastUsedAt
+	key.LastUsedAt = &lastUsedAt
+
+	return r.save(key)
+}
+
+func (r *fileDBAPIKeysRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	ids := lo.Map(r.findByUserID(userID), func(key entity.APIKeyModel, _ int) primitive.ObjectID {
+		return key.ID
+	})
+
+	return r.delete(ids)
+}
+
+// findByUserID returns keys of the user in order of creation, must be called with locked mutex
+func (r *fileDBAPIKeysRepository) findByUserID(userID primitive.ObjectID) []entity.APIKeyModel {
+	keys := []entity.APIKeyModel{}
+
+	for _, i := range r.indexes.lookup(apiKeysUserIDIndex, userID.Hex()) {
+		keys = append(keys, r.APIKeys[i])
+	}
+
+	// keys are stored in order of insertion, which may differ from order of creation dates
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+
+		return keys[i].ID.Hex() < keys[j].ID.Hex()
+	})
+
+	return keys
+}
+
+// save checks unique constraints and stores the key, must be called with locked mutex
+func (r *fileDBAPIKeysRepository) save(key entity.APIKeyModel) error {
+	if err := r.indexes.check(key); err != nil {
+		return err
+	}
+
+	return r.log.put(key, func() {
+		r.APIKeys = r.indexes.put(r.APIKeys, key)
+	})
+}
+
+// delete removes keys with provided IDs, must be called with locked mutex
+func (r *fileDBAPIKeysRepository) delete(ids []primitive.ObjectID) error {
+	return r.log.delete(ids, func() {
+		r.APIKeys = r.indexes.delete(r.APIKeys, ids)
+	})
+}
+
+func (r *fileDBAPIKeysRepository) build() error {
+	return r.indexes.build(r.APIKeys)
+}
+
+func (r *fileDBAPIKeysRepository) apply(record fileDBLogRecord) (err error) {
+	r.APIKeys, err = r.indexes.apply(r.APIKeys, record)
+	return err
+}
+
+func (r *fileDBAPIKeysRepository) close() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return r.log.close()
+}
@@ -0,0 +1,349 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+// fileDBAuditRecord is a line of the audit log. Every record contains hash of the
+// previous one, so modification or removal of any record breaks the chain. Hashes are
+// keyed with chain key if it's configured, so the chain can't be recomputed without it
+type fileDBAuditRecord struct {
+	Event    json.RawMessage `json:"event"`
+	PrevHash string          `json:"prevHash"`
+	Hash     string          `json:"hash"`
+}
+
+type fileDBAuditRepository struct {
+	events []entity.AuditEventModel
+	// ids are IDs of stored events, so duplicates aren't appended
+	ids      map[primitive.ObjectID]struct{}
+	lastHash string
+	chainKey []byte
+	file     *os.File
+	mux      sync.RWMutex
+}
+
+func (f *fileDB) createAuditRepository(cfg AuditConfig) error {
+	// events of in-memory database have no file, so chain isn't written anywhere
+	if f.inMemory() {
+		f.audit = &fileDBAuditRepository{ids: make(map[primitive.ObjectID]struct{})}
+		return nil
+	}
+
+	path := filepath.Join(f.dir, auditCollection+".jsonl")
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %q", path)
+	}
+
+	f.audit = &fileDBAuditRepository{
+		ids:      make(map[primitive.ObjectID]struct{}),
+		chainKey: []byte(cfg.ChainKey),
+		file:     file,
+	}
+
+	if err = f.audit.load(); err != nil {
+		file.Close()
+		return errors.Wrapf(err, "failed to load %q", path)
+	}
+
+	return nil
+}
+
+func (f *fileDB) getAuditRepository() AuditRepository {
+	return f.audit
+}
+
+func (r *fileDBAuditRepository) Create(ctx context.Context, event entity.AuditEventModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if _, found := r.ids[event.ID]; found {
+		return entity.ErrAuditEventAlreadyExists
+	}
+
+	line, hash, err := r.encodeRecord(r.lastHash, event)
+	if err != nil {
+		return err
+	}
+
+	if r.file != nil {
+		if err = r.append(line); err != nil {
+			return err
+		}
+	}
+
+	r.events = append(r.events, event)
+	r.ids[event.ID] = struct{}{}
+	r.lastHash = hash
+
+	return nil
+}
+
+func (r *fileDBAuditRepository) Find(ctx context.Context, schema FindAuditEventsSchema) ([]entity.AuditEventModel, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	r.mux.RLock()
+	matched := lo.Filter(r.events, func(event entity.AuditEventModel, _ int) bool {
+		if !schema.UserID.IsZero() && event.ActorID != schema.UserID && event.TargetUserID != schema.UserID {
+			return false
+		}
+
+		return schema.Action == "" || event.Action == schema.Action
+	})
+	r.mux.RUnlock()
+
+	// events are appended in order of insertion, which may differ from order of creation dates
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+
+		return matched[i].ID.Hex() > matched[j].ID.Hex()
+	})
+
+	total := int64(len(matched))
+	if schema.Offset >= total {
+		return []entity.AuditEventModel{}, total, nil
+	}
+
+	end := total
+	if schema.Limit > 0 && schema.Offset+schema.Limit < total {
+		end = schema.Offset + schema.Limit
+	}
+
+	return matched[schema.Offset:end], total, nil
+}
+
+// Anonymize rewrites the whole log, so the chain is computed anew for anonymized events
+func (r *fileDBAuditRepository) Anonymize(ctx context.Context, userID primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	events := make([]entity.AuditEventModel, len(r.events))
+	copy(events, r.events)
+
+	for i := range events {
+		if events[i].ActorID == userID || events[i].TargetUserID == userID {
+			events[i].IP = ""
+			events[i].UserAgent = ""
+			events[i].Details = nil
+		}
+	}
+
+	if r.file == nil {
+		r.events = events
+		return nil
+	}
+
+	path := r.file.Name()
+	tmpPath := path + ".tmp"
+
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %q", tmpPath)
+	}
+	defer os.Remove(tmpPath)
+
+	writer := bufio.NewWriter(tmp)
+
+	var lastHash string
+	for _, event := range events {
+		var line []byte
+
+		line, lastHash, err = r.encodeRecord(lastHash, event)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+
+		if _, err = writer.Write(line); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err = writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		return errors.Wrapf(err, "failed to replace %q", path)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return errors.Wrapf(err, "failed to reopen %q", path)
+	}
+
+	r.file.Close()
+
+	r.file = file
+	r.events = events
+	r.lastHash = lastHash
+
+	return nil
+}
+
+// load reads all records and verifies that the chain is not broken. Record torn by crash
+// in the middle of append is cut off, any other broken record fails loading
+func (r *fileDBAuditRepository) load() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	data, err := io.ReadAll(r.file)
+	if err != nil {
+		return err
+	}
+
+	var line, offset int
+
+	for {
+		end := bytes.IndexByte(data[offset:], '\n')
+		if end < 0 {
+			break
+		}
+
+		line++
+
+		var record fileDBAuditRecord
+		if err = json.Unmarshal(data[offset:offset+end], &record); err != nil {
+			return errors.Wrapf(err, "line %d: failed to decode record", line)
+		}
+
+		if record.PrevHash != r.lastHash || !hmac.Equal([]byte(record.Hash), []byte(r.hash(record.PrevHash, record.Event))) {
+			return fmt.Errorf("line %d: audit log chain is broken", line)
+		}
+
+		var event entity.AuditEventModel
+		if err = json.Unmarshal(record.Event, &event); err != nil {
+			return errors.Wrapf(err, "line %d: failed to decode event", line)
+		}
+
+		r.events = append(r.events, event)
+		r.ids[event.ID] = struct{}{}
+		r.lastHash = record.Hash
+		offset += end + 1
+	}
+
+	// every record is written together with line break, so record without it is torn
+	if offset < len(data) {
+		if err = r.file.Truncate(int64(offset)); err != nil {
+			return errors.Wrap(err, "failed to cut off torn record")
+		}
+	}
+
+	return nil
+}
+
+// append writes line to the end of the log. Partially written line is cut off,
+// so the next line isn't appended to it, must be called with locked mutex
+func (r *fileDBAuditRepository) append(line []byte) error {
+	info, err := r.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.file.Write(line)
+	if err == nil {
+		err = r.file.Sync()
+	}
+
+	if err != nil {
+		if truncErr := r.file.Truncate(info.Size()); truncErr != nil {
+			return errors.Wrapf(err, "failed to cut off partially written record: %s", truncErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *fileDBAuditRepository) close() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	return r.file.Close()
+}
+
+// encodeRecord returns line of the record chained to the previous one together with its hash
+func (r *fileDBAuditRepository) encodeRecord(prevHash string, event entity.AuditEventModel) (line []byte, hash string, _ error) {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to encode event")
+	}
+
+	record := fileDBAuditRecord{
+		Event:    raw,
+		PrevHash: prevHash,
+		Hash:     r.hash(prevHash, raw),
+	}
+
+	line, err = json.Marshal(record)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to encode record")
+	}
+
+	return append(line, '\n'), record.Hash, nil
+}
+
+// hash returns HMAC of the record if chain key is configured, otherwise plain SHA-256 is used
+func (r *fileDBAuditRepository) hash(prevHash string, event []byte) string {
+	h := sha256.New()
+	if len(r.chainKey) != 0 {
+		h = hmac.New(sha256.New, r.chainKey)
+	}
+
+	h.Write([]byte(prevHash))
+	h.Write(event)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
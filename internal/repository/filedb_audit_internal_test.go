@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+func TestFileDBAuditRepository_Chain(t *testing.T) {
+	testCases := []struct {
+		name   string
+		tamper func(lines [][]byte) [][]byte
+		hasErr bool
+	}{
+		{
+			name:   "untouched",
+			tamper: func(lines [][]byte) [][]byte { return lines },
+			hasErr: false,
+		},
+		{
+			name: "modified event",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1] = bytes.Replace(lines[1], []byte(entity.AuditOutcomeFailure), []byte(entity.AuditOutcomeSuccess), 1)
+				return lines
+			},
+			hasErr: true,
+		},
+		{
+			name: "removed event",
+			tamper: func(lines [][]byte) [][]byte {
+				return append(lines[:1], lines[2:]...)
+			},
+			hasErr: true,
+		},
+		{
+			name: "reordered events",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[0], lines[1] = lines[1], lines[0]
+				return lines
+			},
+			hasErr: true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := &fileDB{dir: t.TempDir()}
+
+			require.NoError(t, f.createAuditRepository(AuditConfig{}))
+
+			outcomes := []string{entity.AuditOutcomeSuccess, entity.AuditOutcomeFailure, entity.AuditOutcomeSuccess}
+			for _, outcome := range outcomes {
+				err := f.audit.Create(ctx, entity.AuditEventModel{
+					Action:  entity.AuditActionSignIn,
+					Outcome: outcome,
+					ActorID: primitive.NewObjectID(),
+				})
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, f.close(ctx))
+
+			path := filepath.Join(f.dir, auditCollection+".jsonl")
+
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			lines := bytes.Split(bytes.TrimSpace(content), []byte("\n"))
+			require.Len(t, lines, len(outcomes))
+
+			lines = tc.tamper(lines)
+			require.NoError(t, os.WriteFile(path, append(bytes.Join(lines, []byte("\n")), '\n'), 0o600))
+
+			err = f.createAuditRepository(AuditConfig{})
+			assert.Falsef(t, (err != nil) != tc.hasErr, "expected error: %t, but got: %v", tc.hasErr, err)
+
+			if err == nil {
+				require.NoError(t, f.close(ctx))
+			}
+		})
+	}
+}
+
+func TestFileDBAuditRepository_TornRecord(t *testing.T) {
+	ctx := context.Background()
+	f := &fileDB{dir: t.TempDir()}
+	path := filepath.Join(f.dir, auditCollection+".jsonl")
+
+	require.NoError(t, f.createAuditRepository(AuditConfig{}))
+	require.NoError(t, f.audit.Create(ctx, entity.AuditEventModel{Action: entity.AuditActionSignIn}))
+	require.NoError(t, f.close(ctx))
+
+	complete, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// crash in the middle of append leaves record without line break
+	torn := append(append([]byte(nil), complete...), []byte(`{"event":{"action":"sign_`)...)
+	require.NoError(t, os.WriteFile(path, torn, 0o600))
+
+	require.NoError(t, f.createAuditRepository(AuditConfig{}), "torn record must be cut off")
+	require.NoError(t, f.audit.Create(ctx, entity.AuditEventModel{Action: entity.AuditActionSignOut}))
+	require.NoError(t, f.close(ctx))
+
+	require.NoError(t, f.createAuditRepository(AuditConfig{}))
+
+	_, total, err := f.audit.Find(ctx, FindAuditEventsSchema{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.NoError(t, f.close(ctx))
+
+	// broken record followed by another one isn't left by crash, so it must not be cut off
+	broken := append(append([]byte(nil), complete...), []byte("{\"event\":{\"action\":\"sign_\n")...)
+	broken = append(broken, complete...)
+	require.NoError(t, os.WriteFile(path, broken, 0o600))
+
+	assert.Error(t, f.createAuditRepository(AuditConfig{}))
+}
+
+func TestFileDBAuditRepository_ChainKey(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name        string
+		writtenWith string
+		readWith    string
+		hasErr      bool
+	}{
+		{
+			name:        "same key",
+			writtenWith: "<chain key>",
+			readWith:    "<chain key>",
+			hasErr:      false,
+		},
+		{
+			name:        "another key",
+			writtenWith: "<chain key>",
+			readWith:    "<another chain key>",
+			hasErr:      true,
+		},
+		{
+			name:        "chain recomputed without key",
+			writtenWith: "",
+			readWith:    "<chain key>",
+			hasErr:      true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := &fileDB{dir: t.TempDir()}
+
+			require.NoError(t, f.createAuditRepository(AuditConfig{ChainKey: tc.writtenWith}))
+			require.NoError(t, f.audit.Create(ctx, entity.AuditEventModel{Action: entity.AuditActionSignIn}))
+			require.NoError(t, f.close(ctx))
+
+			err := f.createAuditRepository(AuditConfig{ChainKey: tc.readWith})
+			assert.Falsef(t, (err != nil) != tc.hasErr, "expected error: %t, but got: %v", tc.hasErr, err)
+
+			if err == nil {
+				require.NoError(t, f.close(ctx))
+			}
+		})
+	}
+}
+
+func TestFileDBAuditRepository_Anonymize(t *testing.T) {
+	ctx := context.Background()
+	f := &fileDB{dir: t.TempDir()}
+	cfg := AuditConfig{ChainKey: "<chain key>"}
+
+	require.NoError(t, f.createAuditRepository(cfg))
+
+	deleted, other := primitive.NewObjectID(), primitive.NewObjectID()
+
+	events := []entity.AuditEventModel{
+		{Action: entity.AuditActionSignIn, ActorID: deleted, IP: "203.0.113.7"},
+		{Action: entity.AuditActionUserSuspended, ActorID: other, TargetUserID: deleted, IP: "203.0.113.8", Details: map[string]string{"reason": "spam links"}},
+		{Action: entity.AuditActionSignIn, ActorID: other, IP: "203.0.113.8"},
+	}
+
+	for _, event := range events {
+		require.NoError(t, f.audit.Create(ctx, event))
+	}
+
+	require.NoError(t, f.audit.Anonymize(ctx, deleted))
+	require.NoError(t, f.audit.Create(ctx, entity.AuditEventModel{Action: entity.AuditActionUserDeleted, TargetUserID: deleted}))
+	require.NoError(t, f.close(ctx))
+
+	// rewritten log must keep valid chain
+	require.NoError(t, f.createAuditRepository(cfg))
+	t.Cleanup(func() { _ = f.close(ctx) })
+
+	found, total, err := f.audit.Find(ctx, FindAuditEventsSchema{})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), total)
+
+	for _, event := range found {
+		if event.ActorID == deleted || event.TargetUserID == deleted {
+			assert.Emptyf(t, event.IP, "%s event must be anonymized", event.Action)
+			assert.Emptyf(t, event.Details, "%s event must be anonymized", event.Action)
+		} else {
+			assert.Equal(t, "203.0.113.8", event.IP)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fileDBDuplicateKeyError is returned when document violates unique index
+type fileDBDuplicateKeyError struct {
+	index string
+	key   string
+}
+
+func (e *fileDBDuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key %q of %q index", e.key, e.index)
+}
+
+// isDuplicateKeyError is the filedb counterpart of mongo.IsDuplicateKeyError
+func isDuplicateKeyError(err error) bool {
+	var target *fileDBDuplicateKeyError
+	return errors.As(err, &target)
+}
+
+// fileDBIndex is an in-memory hash index, which maps keys to IDs of documents.
+// Like sparse mongodb indexes it skips empty keys
+type fileDBIndex[T any] struct {
+	name string
+	// keys returns keys of the document, several keys are returned for arrays
+	keys            func(doc T) []string
+	unique          bool
+	caseInsensitive bool
+	entries         map[string][]primitive.ObjectID
+}
+
+func (x *fileDBIndex[T]) normalize(key string) string {
+	return lo.Ternary(x.caseInsensitive, strings.ToLower(key), key)
+}
+
+func (x *fileDBIndex[T]) docKeys(doc T) []string {
+	keys := lo.FilterMap(x.keys(doc), func(key string, _ int) (string, bool) {
+		return x.normalize(key), key != ""
+	})
+
+	return lo.Uniq(keys)
+}
+
+func (x *fileDBIndex[T]) add(id primitive.ObjectID, doc T) {
+	for _, key := range x.docKeys(doc) {
+		x.entries[key] = append(x.entries[key], id)
+	}
+}
+
+func (x *fileDBIndex[T]) remove(id primitive.ObjectID, doc T) {
+	for _, key := range x.docKeys(doc) {
+		ids := lo.Without(x.entries[key], id)
+		if len(ids) == 0 {
+			delete(x.entries, key)
+		} else {
+			x.entries[key] = ids
+		}
+	}
+}
+
+// fileDBIndexes keeps positions of documents in the collection slice by their IDs
+// together with secondary indexes, so lookups don't scan the whole collection
+type fileDBIndexes[T any] struct {
+	id        func(doc T) primitive.ObjectID
+	positions map[primitive.ObjectID]int
+	indexes   map[string]*fileDBIndex[T]
+}
+
+func newFileDBIndexes[T any](id func(doc T) primitive.ObjectID, indexes ...*fileDBIndex[T]) *fileDBIndexes[T] {
+	x := &fileDBIndexes[T]{
+		id:      id,
+		indexes: make(map[string]*fileDBIndex[T], len(indexes)),
+	}
+
+	for _, index := range indexes {
+		x.indexes[index.name] = index
+	}
+
+	return x
+}
+
+// build indexes loaded documents. Documents written before unique indexes were introduced
+// may share unique keys, they're indexed too, so duplicates can be resolved by migration
+func (x *fileDBIndexes[T]) build(docs []T) error {
+	x.positions = make(map[primitive.ObjectID]int, len(docs))
+
+	for _, index := range x.indexes {
+		index.entries = make(map[string][]primitive.ObjectID)
+	}
+
+	for i, doc := range docs {
+		x.positions[x.id(doc)] = i
+
+		for _, index := range x.indexes {
+			index.add(x.id(doc), doc)
+		}
+	}
+
+	return nil
+}
+
+// find returns position of the document with provided ID
+func (x *fileDBIndexes[T]) find(id primitive.ObjectID) (int, bool) {
+	i, ok := x.positions[id]
+	return i, ok
+}
+
+// lookup returns positions of documents with the key in order of insertion
+func (x *fileDBIndexes[T]) lookup(name, key string) []int {
+	index := x.indexes[name]
+
+	positions := lo.Map(index.entries[index.normalize(key)], func(id primitive.ObjectID, _ int) int {
+		return x.positions[id]
+	})
+	sort.Ints(positions)
+
+	return positions
+}
+
+// fileDBDuplicate is a unique key shared by several documents
+type fileDBDuplicate struct {
+	index string
+	key   string
+	// ids are sorted in order of insertion
+	ids []primitive.ObjectID
+}
+
+// duplicates returns keys of unique indexes shared by several documents sorted by index and key
+func (x *fileDBIndexes[T]) duplicates() []fileDBDuplicate {
+	var duplicates []fileDBDuplicate
+
+	for _, index := range x.indexes {
+		if !index.unique {
+			continue
+		}
+
+		for key, ids := range index.entries {
+			if len(ids) < 2 {
+				continue
+			}
+
+			ids = append([]primitive.ObjectID(nil), ids...)
+			sort.Slice(ids, func(i, j int) bool { return x.positions[ids[i]] < x.positions[ids[j]] })
+
+			duplicates = append(duplicates, fileDBDuplicate{index: index.name, key: key, ids: ids})
+		}
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool {
+		if duplicates[i].index != duplicates[j].index {
+			return duplicates[i].index < duplicates[j].index
+		}
+
+		return duplicates[i].key < duplicates[j].key
+	})
+
+	return duplicates
+}
+
+// check returns *fileDBDuplicateKeyError if unique key of the document belongs to another document
+func (x *fileDBIndexes[T]) check(doc T) error {
+	id := x.id(doc)
+
+	for _, index := range x.indexes {
+		if !index.unique {
+			continue
+		}
+
+		for _, key := range index.docKeys(doc) {
+			if lo.ContainsBy(index.entries[key], func(other primitive.ObjectID) bool { return other != id }) {
+				return &fileDBDuplicateKeyError{index: index.name, key: key}
+			}
+		}
+	}
+
+	return nil
+}
+
+// put replaces document with the same ID or appends it
+func (x *fileDBIndexes[T]) put(docs []T, doc T) []T {
+	id := x.id(doc)
+
+	i, found := x.positions[id]
+	if found {
+		for _, index := range x.indexes {
+			index.remove(id, docs[i])
+		}
+
+		docs[i] = doc
+	} else {
+		x.positions[id] = len(docs)
+		docs = append(docs, doc)
+	}
+
+	for _, index := range x.indexes {
+		index.add(id, doc)
+	}
+
+	return docs
+}
+
+// delete removes documents with provided IDs, positions of the following documents are shifted
+func (x *fileDBIndexes[T]) delete(docs []T, ids []primitive.ObjectID) []T {
+	removed := false
+
+	for _, id := range ids {
+		i, found := x.positions[id]
+		if !found {
+			continue
+		}
+
+		for _, index := range x.indexes {
+			index.remove(id, docs[i])
+		}
+
+		delete(x.positions, id)
+		removed = true
+	}
+
+	if !removed {
+		return docs
+	}
+
+	kept := docs[:0]
+
+	for _, doc := range docs {
+		if _, found := x.positions[x.id(doc)]; found {
+			x.positions[x.id(doc)] = len(kept)
+			kept = append(kept, doc)
+		}
+	}
+
+	return kept
+}
+
+// apply applies record of the write-ahead log, constraints were checked when record was written
+func (x *fileDBIndexes[T]) apply(docs []T, record fileDBLogRecord) ([]T, error) {
+	if record.Put != nil {
+		var doc T
+		if err := json.Unmarshal(record.Put, &doc); err != nil {
+			return nil, err
+		}
+
+		docs = x.put(docs, doc)
+	}
+
+	return x.delete(docs, record.Delete), nil
+}
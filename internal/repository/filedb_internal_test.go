@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+func TestFileDB_Recovery(t *testing.T) {
+	testCases := []struct {
+		name  string
+		crash func(t *testing.T, dir string)
+	}{
+		{
+			name:  "crash after acknowledged changes",
+			crash: func(t *testing.T, dir string) {},
+		},
+		{
+			name: "crash during write of log record",
+			crash: func(t *testing.T, dir string) {
+				f, err := os.OpenFile(filepath.Join(dir, usersCollection+".wal"), os.O_WRONLY|os.O_APPEND, 0o600)
+				require.NoError(t, err)
+
+				_, err = f.WriteString(`{"put":{"_id":"`)
+				require.NoError(t, err)
+				require.NoError(t, f.Close())
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+
+			db, err := newFileDB(FileDBConfig{Path: dir})
+			require.NoError(t, err)
+			require.NoError(t, db.createUsersRepository())
+
+			kept, deleted := primitive.NewObjectID(), primitive.NewObjectID()
+
+			require.NoError(t, db.users.Create(ctx, entity.UserModel{ID: kept, Username: "kept", Email: "kept@example.com"}))
+			require.NoError(t, db.users.Create(ctx, entity.UserModel{ID: deleted, Username: "deleted", Email: "deleted@example.com"}))
+			require.NoError(t, db.users.ChangeEmail(ctx, ChangeEmailSchema{UserID: kept, NewEmail: "changed@example.com"}))
+			require.NoError(t, db.users.Delete(ctx, deleted))
+
+			// process dies without compaction, only its lock is released by the system
+			require.NoError(t, unlockFileDB(db.lock))
+			tc.crash(t, dir)
+
+			recovered, err := newFileDB(FileDBConfig{Path: dir})
+			require.NoError(t, err)
+			require.NoError(t, recovered.createUsersRepository())
+			t.Cleanup(func() { _ = recovered.close(ctx) })
+
+			user, err := recovered.users.FindByID(ctx, kept)
+			require.NoError(t, err)
+			assert.Equal(t, "changed@example.com", user.Email)
+
+			_, err = recovered.users.FindByID(ctx, deleted)
+			assert.ErrorIs(t, err, entity.ErrUserNotFound)
+
+			info, err := os.Stat(filepath.Join(dir, usersCollection+".wal"))
+			require.NoError(t, err)
+			assert.Zero(t, info.Size(), "recovered log must be compacted into snapshot")
+		})
+	}
+}
+
+func TestFileDB_Compaction(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	db, err := newFileDB(FileDBConfig{Path: dir, CompactThreshold: 2})
+	require.NoError(t, err)
+	require.NoError(t, db.createUsersRepository())
+	t.Cleanup(func() { _ = db.close(ctx) })
+
+	for _, username := range []string{"first", "second", "third"} {
+		require.NoError(t, db.users.Create(ctx, entity.UserModel{Username: username}))
+	}
+
+	var snapshot fileDBUsersRepository
+	require.NoError(t, readJSONFile(filepath.Join(dir, usersCollection+".json"), &snapshot))
+	assert.Len(t, snapshot.Users, 2)
+
+	content, err := os.ReadFile(filepath.Join(dir, usersCollection+".wal"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "third")
+}
+
+func TestFileDB_Lock(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	db, err := newFileDB(FileDBConfig{Path: dir})
+	require.NoError(t, err)
+
+	_, err = newFileDB(FileDBConfig{Path: dir})
+	assert.Error(t, err, "directory must not be opened by two instances")
+
+	require.NoError(t, db.close(context.Background()))
+
+	db, err = newFileDB(FileDBConfig{Path: dir})
+	require.NoError(t, err)
+	assert.NoError(t, db.close(context.Background()))
+}
+
+func TestFileDBUsersRepository_UniqueConstraints(t *testing.T) {
+	ctx := context.Background()
+
+	existing := entity.UserModel{
+		ID:       primitive.NewObjectID(),
+		Username: "existing",
+		Email:    "existing@example.com",
+		Identities: []entity.IdentityModel{
+			{Provider: "keycloak", Subject: "<subject>"},
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		change func(r *fileDBUsersRepository, userID primitive.ObjectID) error
+		err    error
+	}{
+		{
+			name: "create with taken username",
+			change: func(r *fileDBUsersRepository, _ primitive.ObjectID) error {
+				return r.Create(ctx, entity.UserModel{Username: existing.Username, Email: "other@example.com"})
+			},
+			err: entity.ErrUserAlreadyExists,
+		},
+		{
+			name: "create with taken email in another case",
+			change: func(r *fileDBUsersRepository, _ primitive.ObjectID) error {
+				return r.Create(ctx, entity.UserModel{Username: "other", Email: "Existing@Example.com"})
+			},
+			err: entity.ErrUserAlreadyExists,
+		},
+		{
+			name: "create with taken ID",
+			change: func(r *fileDBUsersRepository, _ primitive.ObjectID) error {
+				return r.Create(ctx, entity.UserModel{ID: existing.ID, Username: "other", Email: "other@example.com"})
+			},
+			err: entity.ErrUserAlreadyExists,
+		},
+		{
+			name: "change to taken username",
+			change: func(r *fileDBUsersRepository, userID primitive.ObjectID) error {
+				return r.ChangeUsername(ctx, ChangeUsernameSchema{UserID: userID, NewUsername: existing.Username})
+			},
+			err: entity.ErrUsernameTaken,
+		},
+		{
+			name: "change to taken email",
+			change: func(r *fileDBUsersRepository, userID primitive.ObjectID) error {
+				return r.ChangeEmail(ctx, ChangeEmailSchema{UserID: userID, NewEmail: existing.Email})
+			},
+			err: entity.ErrUserAlreadyExists,
+		},
+		{
+			name: "link taken identity",
+			change: func(r *fileDBUsersRepository, userID primitive.ObjectID) error {
+				return r.LinkIdentity(ctx, LinkIdentitySchema{UserID: userID, Identity: existing.Identities[0]})
+			},
+			err: entity.ErrOIDCIdentityAlreadyLinked,
+		},
+		{
+			name: "change to own username",
+			change: func(r *fileDBUsersRepository, userID primitive.ObjectID) error {
+				return r.ChangeUsername(ctx, ChangeUsernameSchema{UserID: userID, NewUsername: "user"})
+			},
+			err: nil,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := newFileDB(FileDBConfig{Path: t.TempDir()})
+			require.NoError(t, err)
+			require.NoError(t, db.createUsersRepository())
+			t.Cleanup(func() { _ = db.close(ctx) })
+
+			user := entity.UserModel{ID: primitive.NewObjectID(), Username: "user", Email: "user@example.com", Version: 1}
+
+			require.NoError(t, db.users.Create(ctx, existing))
+			require.NoError(t, db.users.Create(ctx, user))
+
+			err = tc.change(db.users, user.ID)
+			assert.ErrorIs(t, err, tc.err)
+
+			found, err := db.users.FindByID(ctx, user.ID)
+			require.NoError(t, err)
+
+			if tc.err != nil {
+				assert.Equal(t, user, found, "rejected change must not be applied")
+			}
+
+			found, err = db.users.FindByUsername(ctx, existing.Username)
+			require.NoError(t, err)
+			assert.Equal(t, existing.ID, found.ID)
+		})
+	}
+}
+
+func TestFileDBUsersRepository_Indexes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db, err := newFileDB(FileDBConfig{Path: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, db.createUsersRepository())
+	t.Cleanup(func() { _ = db.close(ctx) })
+
+	users := []entity.UserModel{
+		{ID: primitive.NewObjectID(), Username: "first", Email: "first@example.com"},
+		{ID: primitive.NewObjectID(), Username: "second", Email: "second@example.com"},
+		{ID: primitive.NewObjectID(), Username: "third", Email: "third@example.com"},
+	}
+
+	for _, user := range users {
+		require.NoError(t, db.users.Create(ctx, user))
+	}
+
+	// deletion shifts positions of the following users
+	require.NoError(t, db.users.Delete(ctx, users[0].ID))
+	require.NoError(t, db.users.ChangeUsername(ctx, ChangeUsernameSchema{UserID: users[2].ID, NewUsername: "renamed", ChangedAt: time.Now()}))
+
+	_, err = db.users.FindByUsername(ctx, "first")
+	assert.ErrorIs(t, err, entity.ErrUserNotFound)
+
+	found, err := db.users.FindByEmail(ctx, "SECOND@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, users[1].ID, found.ID)
+
+	found, err = db.users.FindByLogin(ctx, "renamed")
+	require.NoError(t, err)
+	assert.Equal(t, users[2].ID, found.ID)
+
+	found, err = db.users.FindByPreviousUsername(ctx, "third", time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, users[2].ID, found.ID)
+
+	_, err = db.users.FindByUsername(ctx, "third")
+	assert.ErrorIs(t, err, entity.ErrUserNotFound, "previous username must be removed from index")
+
+	// released username may be taken by another user
+	require.NoError(t, db.users.Create(ctx, entity.UserModel{Username: "first", Email: "first@example.com"}))
+}
@@ -0,0 +1,45 @@
+//go:build !windows
+
+package repository
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// lockFileDB takes exclusive lock of the file, which is released by the system if process dies
+func lockFileDB(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "failed to acquire lock")
+	}
+
+	return f, nil
+}
+
+func unlockFileDB(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to release lock")
+	}
+
+	return f.Close()
+}
+
+// syncDir makes renames in the directory durable
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.Sync()
+}
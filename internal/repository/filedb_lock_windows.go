@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"os"
+)
+
+// lockFileDB creates the file exclusively, so lock of crashed process must be removed manually
+func lockFileDB(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0o600)
+}
+
+func unlockFileDB(f *os.File) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(f.Name())
+}
+
+// syncDir is no-op, because directories can't be synced on windows
+func syncDir(_ string) error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// fileDBMigrations are sorted by version, versions must never be reused.
+// Collections are loaded before migrations are run, so migrations change them through repositories
+var fileDBMigrations = []migration[*fileDB]{
+	{
+		version: 1,
+		name:    "user_version",
+		up: func(ctx context.Context, f *fileDB) error {
+			return errors.Wrap(f.users.initVersions(ctx), "failed to set initial version of users")
+		},
+		// versions are kept, previous versions of the application drop them on the next change of the user
+		down: func(_ context.Context, _ *fileDB) error { return nil },
+	},
+	{
+		version: 2,
+		name:    "user_duplicates",
+		// users written before unique indexes were introduced may share usernames, emails differing
+		// only in case or identities. Every change is written to the audit log, so it can be reviewed
+		up: func(ctx context.Context, f *fileDB) error {
+			events, err := f.users.resolveDuplicates(ctx)
+			if err != nil {
+				return errors.Wrap(err, "failed to resolve duplicates of users")
+			}
+
+			for _, event := range events {
+				if err = f.audit.Create(ctx, event); err != nil {
+					return errors.Wrap(err, "failed to record resolved duplicate")
+				}
+			}
+
+			return nil
+		},
+	},
+}
+
+// withMigrations runs migrations holding the lock of the directory, which is taken when database is opened.
+// Every migration is recorded right after it's run
+func (f *fileDB) withMigrations(ctx context.Context, fn func(ctx context.Context, journal migrationsJournal, steps []migrationStep) error) error {
+	f.migrationsMux.Lock()
+	defer f.migrationsMux.Unlock()
+
+	return fn(ctx, fileDBMigrationsJournal{db: f}, bindMigrations(fileDBMigrations, f))
+}
+
+// fileDBMigrationsJournal keeps versions of applied migrations in a file next to collections,
+// in-memory database keeps them only in memory
+type fileDBMigrationsJournal struct {
+	db *fileDB
+}
+
+func (j fileDBMigrationsJournal) applied(_ context.Context) (map[int64]time.Time, error) {
+	if j.db.migrations == nil {
+		j.db.migrations = make(map[int64]time.Time)
+
+		if !j.db.inMemory() {
+			if err := readJSONFile(j.path(), &j.db.migrations); err != nil {
+				return nil, errors.Wrap(err, "failed to read migrations file")
+			}
+		}
+	}
+
+	applied := make(map[int64]time.Time, len(j.db.migrations))
+	for version, appliedAt := range j.db.migrations {
+		applied[version] = appliedAt
+	}
+
+	return applied, nil
+}
+
+func (j fileDBMigrationsJournal) record(ctx context.Context, version int64, appliedAt time.Time) error {
+	return j.save(ctx, func(migrations map[int64]time.Time) {
+		migrations[version] = appliedAt
+	})
+}
+
+func (j fileDBMigrationsJournal) forget(ctx context.Context, version int64) error {
+	return j.save(ctx, func(migrations map[int64]time.Time) {
+		delete(migrations, version)
+	})
+}
+
+// save changes copy of applied migrations, which replaces them after the file is written
+func (j fileDBMigrationsJournal) save(ctx context.Context, change func(migrations map[int64]time.Time)) error {
+	migrations, err := j.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	change(migrations)
+
+	if !j.db.inMemory() {
+		data, err := json.Marshal(migrations)
+		if err != nil {
+			return err
+		}
+
+		if err = writeFileAtomic(j.path(), data); err != nil {
+			return errors.Wrap(err, "failed to write migrations file")
+		}
+	}
+
+	j.db.migrations = migrations
+
+	return nil
+}
+
+func (j fileDBMigrationsJournal) path() string {
+	return filepath.Join(j.db.dir, migrationsCollection+".json")
+}
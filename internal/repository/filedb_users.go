@@ -2,177 +2,583 @@ package repository
 
 import (
 	"context"
-	"encoding/json"
-	"io"
-	"os"
-	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/samber/lo"
 
-	"github.com/pkg/errors"
-
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/kenplix/url-shrtnr/internal/entity"
 )
 
+// indexes of the users collection, they mirror indexes of mongodb collection
+const (
+	usersUsernameIndex         = "username"
+	usersEmailIndex            = "email"
+	usersIdentityIndex         = "identities.provider_subject"
+	usersPreviousUsernameIndex = "previousUsernames.username"
+)
+
 type fileDBUsersRepository struct {
-	Users []entity.UserModel `json:"users"`
-	path  string
-	mux   sync.RWMutex
-	db    *fileDB
+	Users   []entity.UserModel `json:"users"`
+	log     *fileDBLog
+	indexes *fileDBIndexes[entity.UserModel]
+	mux     sync.RWMutex
 }
 
 func (f *fileDB) createUsersRepository() error {
-	f.users = &fileDBUsersRepository{
-		path: filepath.Join(f.dir, usersCollection+".json"),
-		db:   f,
+	r := &fileDBUsersRepository{
+		indexes: newFileDBIndexes(
+			func(user entity.UserModel) primitive.ObjectID { return user.ID },
+			&fileDBIndex[entity.UserModel]{
+				name:   usersUsernameIndex,
+				keys:   func(user entity.UserModel) []string { return []string{user.Username} },
+				unique: true,
+			},
+			&fileDBIndex[entity.UserModel]{
+				name: usersEmailIndex,
+				keys: func(user entity.UserModel) []string { return []string{user.Email} },
+				// emails are lowercased before they are stored, so it matches mongodb
+				// index and protects from records written before normalization
+				unique:          true,
+				caseInsensitive: true,
+			},
+			&fileDBIndex[entity.UserModel]{
+				name: usersIdentityIndex,
+				keys: func(user entity.UserModel) []string {
+					return lo.Map(user.Identities, func(identity entity.IdentityModel, _ int) string {
+						return identityKey(identity.Provider, identity.Subject)
+					})
+				},
+				unique: true,
+			},
+			&fileDBIndex[entity.UserModel]{
+				name: usersPreviousUsernameIndex,
+				keys: func(user entity.UserModel) []string {
+					return lo.Map(user.PreviousUsernames, func(previous entity.PreviousUsernameModel, _ int) string {
+						return previous.Username
+					})
+				},
+			},
+		),
+	}
+
+	log, err := f.openLog(usersCollection, r)
+	if err != nil {
+		return err
 	}
 
-	return f.users.load()
+	r.log = log
+	f.users = r
+
+	return nil
 }
 
 func (f *fileDB) getUsersRepository() UsersRepository {
 	return f.users
 }
 
-func (r *fileDBUsersRepository) Create(_ context.Context, user entity.UserModel) error {
-	user.ID = primitive.NewObjectID()
+func (r *fileDBUsersRepository) Create(ctx context.Context, user entity.UserModel) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+
+	if user.Version == 0 {
+		user.Version = 1
+	}
 
 	r.mux.Lock()
-	r.Users = append(r.Users, user)
-	r.mux.Unlock()
+	defer r.mux.Unlock()
+
+	if _, found := r.indexes.find(user.ID); found {
+		return entity.ErrUserAlreadyExists
+	}
 
-	return r.store()
+	err := r.save(user)
+	if isDuplicateKeyError(err) {
+		return entity.ErrUserAlreadyExists
+	}
+
+	return err
 }
 
-func (r *fileDBUsersRepository) FindByID(_ context.Context, userID primitive.ObjectID) (entity.UserModel, error) {
+func (r *fileDBUsersRepository) FindByID(ctx context.Context, userID primitive.ObjectID) (entity.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return entity.UserModel{}, err
+	}
+
 	r.mux.RLock()
-	user, found := lo.Find(r.Users, func(user entity.UserModel) bool {
-		return user.ID == userID
-	})
-	r.mux.RUnlock()
+	defer r.mux.RUnlock()
 
+	i, found := r.indexes.find(userID)
 	if !found {
 		return entity.UserModel{}, entity.ErrUserNotFound
 	}
 
-	return user, nil
+	return r.Users[i], nil
 }
 
-func (r *fileDBUsersRepository) FindByUsername(_ context.Context, username string) (entity.UserModel, error) {
+func (r *fileDBUsersRepository) FindByUsername(ctx context.Context, username string) (entity.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return entity.UserModel{}, err
+	}
+
 	r.mux.RLock()
-	user, found := lo.Find(r.Users, func(user entity.UserModel) bool {
-		return user.Username == username
-	})
-	r.mux.RUnlock()
+	defer r.mux.RUnlock()
 
-	if !found {
-		return entity.UserModel{}, entity.ErrUserNotFound
+	return r.findOne(r.indexes.lookup(usersUsernameIndex, username))
+}
+
+func (r *fileDBUsersRepository) FindByEmail(ctx context.Context, email string) (entity.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return entity.UserModel{}, err
 	}
 
-	return user, nil
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	return r.findOne(r.indexes.lookup(usersEmailIndex, email))
 }
 
-func (r *fileDBUsersRepository) FindByEmail(_ context.Context, email string) (entity.UserModel, error) {
+func (r *fileDBUsersRepository) FindByLogin(ctx context.Context, login string) (entity.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return entity.UserModel{}, err
+	}
+
 	r.mux.RLock()
-	user, found := lo.Find(r.Users, func(user entity.UserModel) bool {
-		return user.Email == email
+	defer r.mux.RUnlock()
+
+	positions := append(r.indexes.lookup(usersUsernameIndex, login), r.indexes.lookup(usersEmailIndex, login)...)
+	sort.Ints(positions)
+
+	return r.findOne(positions)
+}
+
+func (r *fileDBUsersRepository) ChangeEmail(ctx context.Context, schema ChangeEmailSchema) error {
+	err := r.update(ctx, schema.UserID, schema.Version, func(user *entity.UserModel) {
+		user.Email = schema.NewEmail
 	})
-	r.mux.RUnlock()
+	if isDuplicateKeyError(err) {
+		return entity.ErrUserAlreadyExists
+	}
 
-	if !found {
-		return entity.UserModel{}, entity.ErrUserNotFound
+	return err
+}
+
+func (r *fileDBUsersRepository) ChangePassword(ctx context.Context, schema ChangePasswordSchema) error {
+	return r.update(ctx, schema.UserID, schema.Version, func(user *entity.UserModel) {
+		user.PasswordHash = schema.NewPasswordHash
+	})
+}
+
+func (r *fileDBUsersRepository) ChangeUsername(ctx context.Context, schema ChangeUsernameSchema) error {
+	err := r.update(ctx, schema.UserID, schema.Version, func(user *entity.UserModel) {
+		previous := make([]entity.PreviousUsernameModel, 0, len(user.PreviousUsernames)+1)
+		previous = append(previous, user.PreviousUsernames...)
+		previous = append(previous, entity.PreviousUsernameModel{
+			Username:  user.Username,
+			ChangedAt: schema.ChangedAt,
+		})
+		if len(previous) > maxPreviousUsernames {
+			previous = previous[len(previous)-maxPreviousUsernames:]
+		}
+
+		changedAt := schema.ChangedAt
+		user.Username = schema.NewUsername
+		user.UsernameChangedAt = &changedAt
+		user.PreviousUsernames = previous
+	})
+	if isDuplicateKeyError(err) {
+		return entity.ErrUsernameTaken
 	}
 
-	return user, nil
+	return err
 }
 
-func (r *fileDBUsersRepository) FindByLogin(_ context.Context, login string) (entity.UserModel, error) {
+func (r *fileDBUsersRepository) FindByPreviousUsername(ctx context.Context, username string, since time.Time) (entity.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return entity.UserModel{}, err
+	}
+
 	r.mux.RLock()
-	user, found := lo.Find(r.Users, func(user entity.UserModel) bool {
-		return user.Username == login || user.Email == login
+	defer r.mux.RUnlock()
+
+	positions := lo.Filter(r.indexes.lookup(usersPreviousUsernameIndex, username), func(i int, _ int) bool {
+		return lo.ContainsBy(r.Users[i].PreviousUsernames, func(previous entity.PreviousUsernameModel) bool {
+			return previous.Username == username && previous.ChangedAt.After(since)
+		})
 	})
-	r.mux.RUnlock()
 
+	return r.findOne(positions)
+}
+
+func (r *fileDBUsersRepository) UpdateProfile(ctx context.Context, schema UpdateProfileSchema) error {
+	return r.update(ctx, schema.UserID, schema.Version, func(user *entity.UserModel) {
+		user.DisplayName = schema.DisplayName
+		user.Bio = schema.Bio
+	})
+}
+
+func (r *fileDBUsersRepository) ChangeAvatar(ctx context.Context, schema ChangeAvatarSchema) error {
+	return r.update(ctx, schema.UserID, schema.Version, func(user *entity.UserModel) {
+		user.Avatar = schema.Avatar
+	})
+}
+
+func (r *fileDBUsersRepository) EnableTwoFactor(ctx context.Context, schema EnableTwoFactorSchema) error {
+	return r.update(ctx, schema.UserID, 0, func(user *entity.UserModel) {
+		twoFactor := schema.TwoFactor
+		user.TwoFactor = &twoFactor
+	})
+}
+
+func (r *fileDBUsersRepository) DisableTwoFactor(ctx context.Context, userID primitive.ObjectID) error {
+	return r.update(ctx, userID, 0, func(user *entity.UserModel) {
+		user.TwoFactor = nil
+	})
+}
+
+func (r *fileDBUsersRepository) ChangeRecoveryCodes(ctx context.Context, schema ChangeRecoveryCodesSchema) error {
+	var enabled bool
+
+	err := r.update(ctx, schema.UserID, 0, func(user *entity.UserModel) {
+		if user.TwoFactor == nil {
+			return
+		}
+
+		enabled = true
+		twoFactor := *user.TwoFactor
+		twoFactor.RecoveryCodeHashes = schema.RecoveryCodeHashes
+		user.TwoFactor = &twoFactor
+	})
+	if err != nil {
+		return err
+	} else if !enabled {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *fileDBUsersRepository) RemoveRecoveryCode(ctx context.Context, schema RemoveRecoveryCodeSchema) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	i, found := r.indexes.find(schema.UserID)
 	if !found {
-		return entity.UserModel{}, entity.ErrUserNotFound
+		return entity.ErrUserNotFound
 	}
 
-	return user, nil
+	user := r.Users[i]
+	if user.TwoFactor == nil || !lo.Contains(user.TwoFactor.RecoveryCodeHashes, schema.RecoveryCodeHash) {
+		return entity.ErrIncorrectTwoFactorCode
+	}
+
+	twoFactor := *user.TwoFactor
+	twoFactor.RecoveryCodeHashes = lo.Without(twoFactor.RecoveryCodeHashes, schema.RecoveryCodeHash)
+	user.TwoFactor = &twoFactor
+	user.Version++
+
+	return r.save(user)
 }
 
-//nolint:dupl // not a duplication
-func (r *fileDBUsersRepository) ChangeEmail(_ context.Context, schema ChangeEmailSchema) error {
+func (r *fileDBUsersRepository) FindByIdentity(ctx context.Context, provider, subject string) (entity.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return entity.UserModel{}, err
+	}
+
 	r.mux.RLock()
-	user, index, found := lo.FindIndexOf(r.Users, func(user entity.UserModel) bool {
-		return user.ID == schema.UserID
+	defer r.mux.RUnlock()
+
+	return r.findOne(r.indexes.lookup(usersIdentityIndex, identityKey(provider, subject)))
+}
+
+func (r *fileDBUsersRepository) LinkIdentity(ctx context.Context, schema LinkIdentitySchema) error {
+	err := r.update(ctx, schema.UserID, 0, func(user *entity.UserModel) {
+		user.Identities = append(user.Identities[:len(user.Identities):len(user.Identities)], schema.Identity)
+	})
+	if isDuplicateKeyError(err) {
+		return entity.ErrOIDCIdentityAlreadyLinked
+	}
+
+	return err
+}
+
+func (r *fileDBUsersRepository) List(ctx context.Context, schema ListUsersSchema) ([]entity.UserModel, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	search := strings.ToLower(schema.Search)
+
+	r.mux.RLock()
+	matched := lo.Filter(r.Users, func(user entity.UserModel, _ int) bool {
+		return strings.Contains(strings.ToLower(user.Username), search) ||
+			strings.Contains(strings.ToLower(user.Email), search)
 	})
 	r.mux.RUnlock()
 
-	if !found {
-		return entity.ErrUserNotFound
+	total := int64(len(matched))
+	if schema.Offset >= total {
+		return []entity.UserModel{}, total, nil
 	}
 
-	r.mux.Lock()
-	user.Email = schema.NewEmail
-	r.Users[index] = user
-	r.mux.Unlock()
+	end := total
+	if schema.Limit > 0 && schema.Offset+schema.Limit < total {
+		end = schema.Offset + schema.Limit
+	}
+
+	return matched[schema.Offset:end], total, nil
+}
+
+func (r *fileDBUsersRepository) SetRole(ctx context.Context, schema SetRoleSchema) error {
+	return r.update(ctx, schema.UserID, 0, func(user *entity.UserModel) {
+		user.Role = schema.Role
+	})
+}
+
+func (r *fileDBUsersRepository) Suspend(ctx context.Context, schema SuspendUserSchema) error {
+	return r.update(ctx, schema.UserID, 0, func(user *entity.UserModel) {
+		suspendedAt := schema.SuspendedAt
+		user.SuspendedAt = &suspendedAt
+		user.SuspensionReason = schema.Reason
+	})
+}
+
+func (r *fileDBUsersRepository) Unsuspend(ctx context.Context, userID primitive.ObjectID) error {
+	return r.update(ctx, userID, 0, func(user *entity.UserModel) {
+		user.SuspendedAt = nil
+		user.SuspensionReason = ""
+	})
+}
+
+func (r *fileDBUsersRepository) ScheduleDeletion(ctx context.Context, schema ScheduleDeletionSchema) error {
+	return r.update(ctx, schema.UserID, 0, func(user *entity.UserModel) {
+		deletionScheduledAt := schema.DeletionScheduledAt
+		user.DeletionScheduledAt = &deletionScheduledAt
+	})
+}
 
-	return r.store()
+func (r *fileDBUsersRepository) CancelDeletion(ctx context.Context, userID primitive.ObjectID) error {
+	return r.update(ctx, userID, 0, func(user *entity.UserModel) {
+		user.DeletionScheduledAt = nil
+	})
 }
 
-//nolint:dupl // not a duplication
-func (r *fileDBUsersRepository) ChangePassword(_ context.Context, schema ChangePasswordSchema) error {
+func (r *fileDBUsersRepository) FindScheduledForDeletion(ctx context.Context, before time.Time) ([]entity.UserModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mux.RLock()
-	user, index, found := lo.FindIndexOf(r.Users, func(user entity.UserModel) bool {
-		return user.ID == schema.UserID
+	users := lo.Filter(r.Users, func(user entity.UserModel, _ int) bool {
+		return user.DeletionScheduledAt != nil && !user.DeletionScheduledAt.After(before)
 	})
 	r.mux.RUnlock()
 
-	if !found {
-		return entity.ErrUserNotFound
+	return users, nil
+}
+
+func (r *fileDBUsersRepository) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
 	r.mux.Lock()
-	user.PasswordHash = schema.NewPasswordHash
-	r.Users[index] = user
-	r.mux.Unlock()
+	defer r.mux.Unlock()
+
+	if _, found := r.indexes.find(userID); !found {
+		return entity.ErrUserNotFound
+	}
+
+	ids := []primitive.ObjectID{userID}
+
+	return r.log.delete(ids, func() {
+		r.Users = r.indexes.delete(r.Users, ids)
+	})
+}
 
-	return r.store()
+func identityKey(provider, subject string) string {
+	return provider + "\x00" + subject
 }
 
-func (r *fileDBUsersRepository) load() error {
+// findOne returns the first of found users, must be called with locked mutex
+func (r *fileDBUsersRepository) findOne(positions []int) (entity.UserModel, error) {
+	if len(positions) == 0 {
+		return entity.UserModel{}, entity.ErrUserNotFound
+	}
+
+	return r.Users[positions[0]], nil
+}
+
+// update applies fn to the copy of the user with provided ID and stores changes with incremented version.
+// Version of the user must match expected one unless it's zero
+func (r *fileDBUsersRepository) update(ctx context.Context, userID primitive.ObjectID, version int64, fn func(user *entity.UserModel)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	r.mux.Lock()
 	defer r.mux.Unlock()
 
-	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_RDONLY, 0o600)
-	if err != nil {
-		return err
+	i, found := r.indexes.find(userID)
+	if !found {
+		return entity.ErrUserNotFound
 	}
-	defer f.Close()
 
-	dec := json.NewDecoder(f)
-	if err = dec.Decode(r); err != nil && !errors.Is(err, io.EOF) {
+	user := r.Users[i]
+	if version != 0 && user.Version != version {
+		return entity.ErrUserVersionConflict
+	}
+
+	fn(&user)
+	user.Version++
+
+	return r.save(user)
+}
+
+// initVersions sets the first version to users stored before versions were introduced
+func (r *fileDBUsersRepository) initVersions(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	unversioned := lo.Filter(r.Users, func(user entity.UserModel, _ int) bool {
+		return user.Version == 0
+	})
+
+	for _, user := range unversioned {
+		user.Version = 1
+
+		if err := r.save(user); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (r *fileDBUsersRepository) store() error {
+// resolveDuplicates keeps unique keys shared by several users, which were stored before unique indexes
+// were introduced, to the user stored first. Other users lose shared email and identity, shared username
+// is suffixed with ID of the user. Events describing changes are returned, so they can be audited
+func (r *fileDBUsersRepository) resolveDuplicates(ctx context.Context) ([]entity.AuditEventModel, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	r.mux.Lock()
 	defer r.mux.Unlock()
 
-	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
-	if err != nil {
+	var (
+		changed = make(map[primitive.ObjectID]entity.UserModel)
+		order   []primitive.ObjectID
+		events  []entity.AuditEventModel
+	)
+
+	for _, duplicate := range r.indexes.duplicates() {
+		for _, id := range duplicate.ids[1:] {
+			user, found := changed[id]
+			if !found {
+				i, _ := r.indexes.find(id)
+				user = r.Users[i]
+				order = append(order, id)
+			}
+
+			var previous, current string
+
+			switch duplicate.index {
+			case usersUsernameIndex:
+				previous = user.Username
+				user.Username += "-" + id.Hex()
+				current = user.Username
+			case usersEmailIndex:
+				previous = user.Email
+				user.Email = ""
+			case usersIdentityIndex:
+				user.Identities = lo.Filter(user.Identities, func(identity entity.IdentityModel, _ int) bool {
+					if identityKey(identity.Provider, identity.Subject) != duplicate.key {
+						return true
+					}
+
+					previous = identity.Provider + ":" + identity.Subject
+					return false
+				})
+			}
+
+			changed[id] = user
+
+			events = append(events, entity.AuditEventModel{
+				Action:       entity.AuditActionUserDuplicateResolved,
+				Outcome:      entity.AuditOutcomeSuccess,
+				TargetUserID: id,
+				Details: map[string]string{
+					"index":      duplicate.index,
+					"previous":   previous,
+					"current":    current,
+					"keptUserID": duplicate.ids[0].Hex(),
+				},
+				CreatedAt: time.Now(),
+			})
+		}
+	}
+
+	for _, id := range order {
+		user := changed[id]
+		user.Version++
+
+		// users keeping shared keys may be changed later in order, so constraints are checked once all are stored
+		err := r.log.put(user, func() {
+			r.Users = r.indexes.put(r.Users, user)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if duplicates := r.indexes.duplicates(); len(duplicates) > 0 {
+		return nil, &fileDBDuplicateKeyError{index: duplicates[0].index, key: duplicates[0].key}
+	}
+
+	return events, nil
+}
+
+// save checks unique constraints and stores the user, must be called with locked mutex
+func (r *fileDBUsersRepository) save(user entity.UserModel) error {
+	if err := r.indexes.check(user); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	enc := json.NewEncoder(f)
-	enc.SetIndent("", "\t")
+	return r.log.put(user, func() {
+		r.Users = r.indexes.put(r.Users, user)
+	})
+}
+
+func (r *fileDBUsersRepository) build() error {
+	return r.indexes.build(r.Users)
+}
+
+func (r *fileDBUsersRepository) apply(record fileDBLogRecord) (err error) {
+	r.Users, err = r.indexes.apply(r.Users, record)
+	return err
+}
+
+func (r *fileDBUsersRepository) close() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
 
-	return enc.Encode(r)
+	return r.log.close()
 }
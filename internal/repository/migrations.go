@@ -0,0 +1,351 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type MigrationDirection string
+
+const (
+	MigrateUp   MigrationDirection = "up"
+	MigrateDown MigrationDirection = "down"
+)
+
+type MigrateOptions struct {
+	Direction MigrationDirection
+	// Steps bounds number of migrations, all pending migrations are applied
+	// and the last applied migration is reverted if it's zero
+	Steps int
+	// DryRun plans migrations without running them
+	DryRun bool
+}
+
+// MigrationStatus describes migration known to the application
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	// AppliedAt is nil if migration isn't applied
+	AppliedAt *time.Time
+	// Reversible reports whether migration has down step
+	Reversible bool
+}
+
+// Migrator applies and reverts migrations of the database, repositories aren't used while it's open
+type Migrator struct {
+	db database
+}
+
+func NewMigrator(ctx context.Context, cfg Config) (*Migrator, error) {
+	f, err := createDatabaseFactory(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create database factory")
+	}
+
+	db, err := f.make(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db}, nil
+}
+
+// Status returns all migrations known to the application sorted by version
+func (m *Migrator) Status(ctx context.Context) (statuses []MigrationStatus, err error) {
+	err = m.db.withMigrations(ctx, func(ctx context.Context, journal migrationsJournal, steps []migrationStep) error {
+		applied, err := journal.applied(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to read applied migrations")
+		}
+
+		statuses = migrationsStatus(applied, steps)
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// Migrate returns migrations, which were applied or reverted in order of running
+func (m *Migrator) Migrate(ctx context.Context, opts MigrateOptions) ([]MigrationStatus, error) {
+	return migrate(ctx, m.db, opts)
+}
+
+func (m *Migrator) Close(ctx context.Context) error {
+	return m.db.close(ctx)
+}
+
+// migration is a versioned change of schema or data of the database. Steps are run with handle H,
+// which is a transaction of backends supporting them
+type migration[H any] struct {
+	version int64
+	name    string
+	up      func(ctx context.Context, h H) error
+	// down reverts up, migration is irreversible if it's nil
+	down func(ctx context.Context, h H) error
+}
+
+// migrationStep is a migration bound to the handle of the running migrations
+type migrationStep struct {
+	version int64
+	name    string
+	up      func(ctx context.Context) error
+	down    func(ctx context.Context) error
+}
+
+// bindMigrations binds migrations to the handle, so they're run by the backend agnostic code
+func bindMigrations[H any](migrations []migration[H], h H) []migrationStep {
+	steps := make([]migrationStep, 0, len(migrations))
+
+	for _, m := range migrations {
+		m := m
+
+		step := migrationStep{
+			version: m.version,
+			name:    m.name,
+			up:      func(ctx context.Context) error { return m.up(ctx, h) },
+		}
+
+		if m.down != nil {
+			step.down = func(ctx context.Context) error { return m.down(ctx, h) }
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps
+}
+
+// migrationsJournal keeps versions of applied migrations
+type migrationsJournal interface {
+	applied(ctx context.Context) (map[int64]time.Time, error)
+	record(ctx context.Context, version int64, appliedAt time.Time) error
+	forget(ctx context.Context, version int64) error
+}
+
+// migrationsRunner is implemented by every database
+type migrationsRunner interface {
+	// withMigrations calls fn with migrations of the database while no other instance migrates it.
+	// Changes made by failed fn are discarded if database supports transactional schema changes
+	withMigrations(ctx context.Context, fn func(ctx context.Context, journal migrationsJournal, steps []migrationStep) error) error
+}
+
+// migrationsStatus returns all migrations sorted by version
+func migrationsStatus(applied map[int64]time.Time, steps []migrationStep) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(steps))
+
+	for _, step := range steps {
+		status := MigrationStatus{
+			Version:    step.version,
+			Name:       step.name,
+			Reversible: step.down != nil,
+		}
+
+		if appliedAt, ok := applied[step.version]; ok {
+			appliedAt := appliedAt
+			status.AppliedAt = &appliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// planMigrations returns steps to run in order of running. Pending migrations are applied in ascending order,
+// applied migrations are reverted in descending order
+func planMigrations(applied map[int64]time.Time, migrations []migrationStep, opts MigrateOptions) ([]migrationStep, error) {
+	known := make(map[int64]bool, len(migrations))
+	for _, step := range migrations {
+		known[step.version] = true
+	}
+
+	for version := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("applied migration %d is unknown, database was migrated by newer version of the application", version)
+		}
+	}
+
+	var plan []migrationStep
+
+	switch opts.Direction {
+	case MigrateUp:
+		for _, step := range migrations {
+			if _, ok := applied[step.version]; !ok {
+				plan = append(plan, step)
+			}
+		}
+	case MigrateDown:
+		for i := len(migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[migrations[i].version]; ok {
+				plan = append(plan, migrations[i])
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown migration direction %q", opts.Direction)
+	}
+
+	steps := opts.Steps
+	if steps <= 0 && opts.Direction == MigrateDown {
+		steps = 1
+	}
+
+	if steps > 0 && len(plan) > steps {
+		plan = plan[:steps]
+	}
+
+	for _, step := range plan {
+		if opts.Direction == MigrateDown && step.down == nil {
+			return nil, fmt.Errorf("migration %d %q is irreversible", step.version, step.name)
+		}
+	}
+
+	return plan, nil
+}
+
+// runMigrations runs planned migrations and records them in the journal one by one,
+// migrations which would be run are returned if it's dry run
+func runMigrations(ctx context.Context, journal migrationsJournal, steps []migrationStep, opts MigrateOptions) ([]MigrationStatus, error) {
+	applied, err := journal.applied(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read applied migrations")
+	}
+
+	plan, err := planMigrations(applied, steps, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []migrationStep
+
+	for _, step := range plan {
+		if opts.DryRun {
+			done = append(done, step)
+			continue
+		}
+
+		if opts.Direction == MigrateUp {
+			if err = step.up(ctx); err != nil {
+				return nil, errors.Wrapf(err, "failed to apply %d %q migration", step.version, step.name)
+			}
+
+			now := time.Now().UTC()
+			if err = journal.record(ctx, step.version, now); err != nil {
+				return nil, errors.Wrapf(err, "failed to record %d %q migration", step.version, step.name)
+			}
+
+			applied[step.version] = now
+		} else {
+			if err = step.down(ctx); err != nil {
+				return nil, errors.Wrapf(err, "failed to revert %d %q migration", step.version, step.name)
+			}
+
+			if err = journal.forget(ctx, step.version); err != nil {
+				return nil, errors.Wrapf(err, "failed to forget %d %q migration", step.version, step.name)
+			}
+
+			delete(applied, step.version)
+		}
+
+		done = append(done, step)
+	}
+
+	return migrationsStatus(applied, done), nil
+}
+
+// migrate runs migrations of the database
+func migrate(ctx context.Context, db migrationsRunner, opts MigrateOptions) (statuses []MigrationStatus, err error) {
+	err = db.withMigrations(ctx, func(ctx context.Context, journal migrationsJournal, steps []migrationStep) error {
+		statuses, err = runMigrations(ctx, journal, steps, opts)
+		return err
+	})
+
+	return statuses, err
+}
+
+// sqlMigration is a pair of scripts which change schema of SQL database
+type sqlMigration struct {
+	version int64
+	name    string
+	up      string
+	// down is empty if migration is irreversible
+	down string
+}
+
+// readMigrations returns migrations of the directory sorted by version. Migration consists of
+// <version>_<name>.up.sql file and optional <version>_<name>.down.sql file
+func readMigrations(fsys embed.FS, dir string) ([]sqlMigration, error) {
+	names, err := fs.Glob(fsys, dir+"/*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]sqlMigration, 0, len(names))
+
+	for _, name := range names {
+		base := strings.TrimSuffix(path.Base(name), ".up.sql")
+		prefix, title, _ := strings.Cut(base, "_")
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %q: version prefix expected", name)
+		}
+
+		up, err := fsys.ReadFile(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %q migration", name)
+		}
+
+		down, err := fsys.ReadFile(path.Join(dir, base+".down.sql"))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, errors.Wrapf(err, "failed to read %q migration", name)
+		}
+
+		migrations = append(migrations, sqlMigration{version: version, name: title, up: string(up), down: string(down)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return nil, fmt.Errorf("migrations %q and %q have the same version", migrations[i-1].name, migrations[i].name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// execMigrations converts SQL migrations into migrations running scripts with exec
+func execMigrations[H any](migrations []sqlMigration, exec func(ctx context.Context, h H, sql string) error) []migration[H] {
+	converted := make([]migration[H], 0, len(migrations))
+
+	for _, m := range migrations {
+		m := m
+
+		c := migration[H]{
+			version: m.version,
+			name:    m.name,
+			up:      func(ctx context.Context, h H) error { return exec(ctx, h, m.up) },
+		}
+
+		if m.down != "" {
+			c.down = func(ctx context.Context, h H) error { return exec(ctx, h, m.down) }
+		}
+
+		converted = append(converted, c)
+	}
+
+	return converted
+}
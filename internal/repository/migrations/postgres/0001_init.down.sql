@@ -0,0 +1,4 @@
+DROP TABLE audit_events;
+DROP TABLE api_keys;
+DROP TABLE user_identities;
+DROP TABLE users;
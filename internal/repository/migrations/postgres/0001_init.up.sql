@@ -0,0 +1,68 @@
+-- IDs are hex encoded ObjectIDs, so they are interchangeable with the other backends
+
+CREATE TABLE users (
+    id                    CHAR(24) PRIMARY KEY,
+    username              TEXT        NOT NULL,
+    email                 TEXT        NOT NULL,
+    password_hash         TEXT        NOT NULL DEFAULT '',
+    role                  TEXT        NOT NULL DEFAULT '',
+    created_at            TIMESTAMPTZ NOT NULL,
+    updated_at            TIMESTAMPTZ NOT NULL,
+    suspended_at          TIMESTAMPTZ,
+    suspension_reason     TEXT        NOT NULL DEFAULT '',
+    two_factor            JSONB,
+    deletion_scheduled_at TIMESTAMPTZ,
+    display_name          TEXT        NOT NULL DEFAULT '',
+    bio                   TEXT        NOT NULL DEFAULT '',
+    avatar                TEXT        NOT NULL DEFAULT '',
+    username_changed_at   TIMESTAMPTZ,
+    previous_usernames    JSONB       NOT NULL DEFAULT '[]'
+);
+
+-- like sparse mongodb indexes, empty values are not indexed
+CREATE UNIQUE INDEX users_username_key ON users (username) WHERE username <> '';
+CREATE UNIQUE INDEX users_email_key ON users (lower(email)) WHERE email <> '';
+CREATE INDEX users_previous_usernames_idx ON users USING GIN (previous_usernames jsonb_path_ops);
+CREATE INDEX users_deletion_scheduled_at_idx ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+-- identities are kept in separate table, so the same account can't be linked to several users
+CREATE TABLE user_identities (
+    provider  TEXT        NOT NULL,
+    subject   TEXT        NOT NULL,
+    user_id   CHAR(24)    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    linked_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+CREATE TABLE api_keys (
+    id           CHAR(24) PRIMARY KEY,
+    user_id      CHAR(24)    NOT NULL,
+    name         TEXT        NOT NULL,
+    prefix       TEXT        NOT NULL UNIQUE,
+    secret_hash  TEXT        NOT NULL,
+    scopes       TEXT[]      NOT NULL DEFAULT '{}',
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id, created_at);
+
+CREATE TABLE audit_events (
+    id             CHAR(24) PRIMARY KEY,
+    action         TEXT        NOT NULL,
+    outcome        TEXT        NOT NULL,
+    actor_id       CHAR(24),
+    target_user_id CHAR(24),
+    ip             TEXT        NOT NULL DEFAULT '',
+    user_agent     TEXT        NOT NULL DEFAULT '',
+    request_id     TEXT        NOT NULL DEFAULT '',
+    details        JSONB,
+    created_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, created_at DESC);
+CREATE INDEX audit_events_target_user_id_idx ON audit_events (target_user_id, created_at DESC);
@@ -0,0 +1,2 @@
+-- version is incremented on every change of the user, so concurrent changes are detected
+ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
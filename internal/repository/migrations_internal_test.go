@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+func TestRunMigrations(t *testing.T) {
+	var run []string
+
+	newStep := func(version int64, reversible bool) migrationStep {
+		step := migrationStep{
+			version: version,
+			name:    "step",
+			up: func(_ context.Context) error {
+				run = append(run, fmt.Sprintf("up %d", version))
+				return nil
+			},
+		}
+
+		if reversible {
+			step.down = func(_ context.Context) error {
+				run = append(run, fmt.Sprintf("down %d", version))
+				return nil
+			}
+		}
+
+		return step
+	}
+
+	steps := []migrationStep{newStep(1, false), newStep(2, true), newStep(3, true)}
+
+	testCases := []struct {
+		name     string
+		applied  []int64
+		opts     MigrateOptions
+		run      []string
+		versions []int64
+		err      bool
+	}{
+		{
+			name:     "all pending migrations are applied",
+			applied:  []int64{1},
+			opts:     MigrateOptions{Direction: MigrateUp},
+			run:      []string{"up 2", "up 3"},
+			versions: []int64{1, 2, 3},
+		},
+		{
+			name:     "applied migrations are bounded by steps",
+			opts:     MigrateOptions{Direction: MigrateUp, Steps: 2},
+			run:      []string{"up 1", "up 2"},
+			versions: []int64{1, 2},
+		},
+		{
+			name:     "the last migration is reverted by default",
+			applied:  []int64{1, 2, 3},
+			opts:     MigrateOptions{Direction: MigrateDown},
+			run:      []string{"down 3"},
+			versions: []int64{1, 2},
+		},
+		{
+			name:     "migrations are reverted in descending order",
+			applied:  []int64{1, 2, 3},
+			opts:     MigrateOptions{Direction: MigrateDown, Steps: 2},
+			run:      []string{"down 3", "down 2"},
+			versions: []int64{1},
+		},
+		{
+			name:     "dry run doesn't run migrations",
+			opts:     MigrateOptions{Direction: MigrateUp, DryRun: true},
+			versions: []int64{},
+		},
+		{
+			name:     "irreversible migration isn't reverted",
+			applied:  []int64{1, 2},
+			opts:     MigrateOptions{Direction: MigrateDown, Steps: 2},
+			versions: []int64{1, 2},
+			err:      true,
+		},
+		{
+			name:     "unknown applied migration stops migrations",
+			applied:  []int64{1, 4},
+			opts:     MigrateOptions{Direction: MigrateUp},
+			versions: []int64{1, 4},
+			err:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run = nil
+
+			db := newMemoryDB()
+			journal := fileDBMigrationsJournal{db: db}
+			ctx := context.Background()
+
+			for _, version := range tc.applied {
+				require.NoError(t, journal.record(ctx, version, time.Now()))
+			}
+
+			_, err := runMigrations(ctx, journal, steps, tc.opts)
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.run, run)
+
+			applied, err := journal.applied(ctx)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.versions, lo.Keys(applied))
+		})
+	}
+}
+
+func TestRunMigrations_Failure(t *testing.T) {
+	db := newMemoryDB()
+	journal := fileDBMigrationsJournal{db: db}
+	ctx := context.Background()
+
+	steps := []migrationStep{
+		{version: 1, name: "first", up: func(_ context.Context) error { return nil }},
+		{version: 2, name: "second", up: func(_ context.Context) error { return errors.New("failure") }},
+	}
+
+	_, err := runMigrations(ctx, journal, steps, MigrateOptions{Direction: MigrateUp})
+	assert.Error(t, err)
+
+	applied, err := journal.applied(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, lo.Keys(applied), "migrations run before failed one must be recorded")
+}
+
+func TestFileDBMigrationsJournal(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	appliedAt := time.Now().UTC()
+
+	db, err := newFileDB(FileDBConfig{Path: dir})
+	require.NoError(t, err)
+
+	require.NoError(t, fileDBMigrationsJournal{db: db}.record(ctx, 1, appliedAt))
+	require.NoError(t, fileDBMigrationsJournal{db: db}.record(ctx, 2, appliedAt))
+	require.NoError(t, fileDBMigrationsJournal{db: db}.forget(ctx, 2))
+	require.NoError(t, db.close(ctx))
+
+	reopened, err := newFileDB(FileDBConfig{Path: dir})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = reopened.close(ctx) })
+
+	applied, err := fileDBMigrationsJournal{db: reopened}.applied(ctx)
+	require.NoError(t, err)
+
+	if assert.Len(t, applied, 1) {
+		assert.True(t, appliedAt.Equal(applied[1]))
+	}
+}
+
+func TestFileDBMigrations_UserVersion(t *testing.T) {
+	ctx := context.Background()
+
+	db := newMemoryDB()
+	require.NoError(t, db.createUsersRepository())
+
+	// user stored before versions were introduced
+	user := entity.UserModel{ID: primitive.NewObjectID(), Username: "user", Email: "user@example.com"}
+	require.NoError(t, db.users.save(user))
+
+	_, err := migrate(ctx, db, MigrateOptions{Direction: MigrateUp})
+	require.NoError(t, err)
+
+	found, err := db.users.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), found.Version)
+}
+
+func TestFileDBMigrations_UserDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	first := entity.UserModel{
+		ID:         primitive.NewObjectID(),
+		Username:   "kenplix",
+		Email:      "tolstoi.job@gmail.com",
+		Identities: []entity.IdentityModel{{Provider: "google", Subject: "1"}},
+		Version:    1,
+	}
+	second := entity.UserModel{
+		ID:         primitive.NewObjectID(),
+		Username:   "kenplix",
+		Email:      "Tolstoi.Job@gmail.com",
+		Identities: []entity.IdentityModel{{Provider: "google", Subject: "1"}, {Provider: "github", Subject: "2"}},
+		Version:    1,
+	}
+
+	// snapshot written before unique indexes were introduced
+	data, err := json.Marshal(fileDBUsersRepository{Users: []entity.UserModel{first, second}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, usersCollection+".json"), data, 0o600))
+
+	db, err := newFileDB(FileDBConfig{Path: dir})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.close(ctx) })
+
+	require.NoError(t, db.createUsersRepository(), "duplicates must not prevent database from opening")
+	require.NoError(t, db.createAuditRepository(AuditConfig{}))
+
+	_, err = migrate(ctx, db, MigrateOptions{Direction: MigrateUp})
+	require.NoError(t, err)
+
+	assert.Empty(t, db.users.indexes.duplicates())
+
+	found, err := db.users.FindByLogin(ctx, "kenplix")
+	require.NoError(t, err)
+	assert.Equal(t, first, found, "user stored first must keep shared keys")
+
+	found, err = db.users.FindByID(ctx, second.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "kenplix-"+second.ID.Hex(), found.Username)
+	assert.Empty(t, found.Email)
+	assert.Equal(t, []entity.IdentityModel{{Provider: "github", Subject: "2"}}, found.Identities)
+	assert.Equal(t, int64(2), found.Version)
+
+	events, total, err := db.audit.Find(ctx, FindAuditEventsSchema{UserID: second.ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total, "every resolved duplicate must be audited")
+
+	for _, event := range events {
+		assert.Equal(t, entity.AuditActionUserDuplicateResolved, event.Action)
+		assert.Equal(t, first.ID.Hex(), event.Details["keptUserID"])
+	}
+}
+
+func TestMongoDBMigrationsLock(t *testing.T) {
+	db := newTestMongoDB(t)
+	ctx := context.Background()
+
+	lock, err := db.lockMigrations(ctx)
+	require.NoError(t, err)
+	t.Cleanup(lock.release)
+
+	journal := mongoDBMigrationsJournal{coll: db.db.Collection(migrationsCollection), lock: lock}
+
+	// expired lock is renewed by its owner
+	_, err = lock.coll.UpdateOne(ctx, bson.M{"_id": mongoDBMigrationsLockID}, bson.M{"$set": bson.M{"expiresAt": time.Now()}})
+	require.NoError(t, err)
+	require.NoError(t, lock.renew(ctx))
+
+	// expired lock is taken by another instance
+	_, err = lock.coll.UpdateOne(ctx, bson.M{"_id": mongoDBMigrationsLockID}, bson.M{"$set": bson.M{"owner": primitive.NewObjectID()}})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, journal.record(ctx, 100, time.Now()), errMigrationsLockLost)
+	assert.ErrorIs(t, journal.forget(ctx, 1), errMigrationsLockLost)
+
+	applied, err := journal.applied(ctx)
+	require.NoError(t, err)
+	assert.NotContains(t, applied, int64(100), "migration must not be recorded without lock")
+	assert.Contains(t, applied, int64(1), "migration must not be forgotten without lock")
+}
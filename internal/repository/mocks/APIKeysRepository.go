@@ -0,0 +1,134 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	entity "github.com/kenplix/url-shrtnr/internal/entity"
+	mock "github.com/stretchr/testify/mock"
+
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+
+	repository "github.com/kenplix/url-shrtnr/internal/repository"
+)
+
+// APIKeysRepository is an autogenerated mock type for the APIKeysRepository type
+type APIKeysRepository struct {
+	mock.Mock
+}
+
+// Create provides a mock function with given fields: ctx, key
+func (_m *APIKeysRepository) Create(ctx context.Context, key entity.APIKeyModel) error {
+	ret := _m.Called(ctx, key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.APIKeyModel) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Delete provides a mock function with given fields: ctx, schema
+func (_m *APIKeysRepository) Delete(ctx context.Context, schema repository.DeleteAPIKeySchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.DeleteAPIKeySchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteByUserID provides a mock function with given fields: ctx, userID
+func (_m *APIKeysRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindByPrefix provides a mock function with given fields: ctx, prefix
+func (_m *APIKeysRepository) FindByPrefix(ctx context.Context, prefix string) (entity.APIKeyModel, error) {
+	ret := _m.Called(ctx, prefix)
+
+	var r0 entity.APIKeyModel
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.APIKeyModel); ok {
+		r0 = rf(ctx, prefix)
+	} else {
+		r0 = ret.Get(0).(entity.APIKeyModel)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, prefix)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindByUserID provides a mock function with given fields: ctx, userID
+func (_m *APIKeysRepository) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]entity.APIKeyModel, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []entity.APIKeyModel
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) []entity.APIKeyModel); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.APIKeyModel)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, primitive.ObjectID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateLastUsed provides a mock function with given fields: ctx, schema
+func (_m *APIKeysRepository) UpdateLastUsed(ctx context.Context, schema repository.UpdateAPIKeyLastUsedSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.UpdateAPIKeyLastUsedSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewAPIKeysRepository interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewAPIKeysRepository creates a new instance of APIKeysRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewAPIKeysRepository(t mockConstructorTestingTNewAPIKeysRepository) *APIKeysRepository {
+	mock := &APIKeysRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
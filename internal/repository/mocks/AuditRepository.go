@@ -0,0 +1,92 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	entity "github.com/kenplix/url-shrtnr/internal/entity"
+	mock "github.com/stretchr/testify/mock"
+
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+
+	repository "github.com/kenplix/url-shrtnr/internal/repository"
+)
+
+// AuditRepository is an autogenerated mock type for the AuditRepository type
+type AuditRepository struct {
+	mock.Mock
+}
+
+// Anonymize provides a mock function with given fields: ctx, userID
+func (_m *AuditRepository) Anonymize(ctx context.Context, userID primitive.ObjectID) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Create provides a mock function with given fields: ctx, event
+func (_m *AuditRepository) Create(ctx context.Context, event entity.AuditEventModel) error {
+	ret := _m.Called(ctx, event)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AuditEventModel) error); ok {
+		r0 = rf(ctx, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Find provides a mock function with given fields: ctx, schema
+func (_m *AuditRepository) Find(ctx context.Context, schema repository.FindAuditEventsSchema) ([]entity.AuditEventModel, int64, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 []entity.AuditEventModel
+	if rf, ok := ret.Get(0).(func(context.Context, repository.FindAuditEventsSchema) []entity.AuditEventModel); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.AuditEventModel)
+		}
+	}
+
+	var r1 int64
+	if rf, ok := ret.Get(1).(func(context.Context, repository.FindAuditEventsSchema) int64); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, repository.FindAuditEventsSchema) error); ok {
+		r2 = rf(ctx, schema)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+type mockConstructorTestingTNewAuditRepository interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewAuditRepository creates a new instance of AuditRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewAuditRepository(t mockConstructorTestingTNewAuditRepository) *AuditRepository {
+	mock := &AuditRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
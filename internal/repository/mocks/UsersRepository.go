@@ -11,6 +11,8 @@ import (
 	primitive "go.mongodb.org/mongo-driver/bson/primitive"
 
 	repository "github.com/kenplix/url-shrtnr/internal/repository"
+
+	time "time"
 )
 
 // UsersRepository is an autogenerated mock type for the UsersRepository type
@@ -18,6 +20,34 @@ type UsersRepository struct {
 	mock.Mock
 }
 
+// CancelDeletion provides a mock function with given fields: ctx, userID
+func (_m *UsersRepository) CancelDeletion(ctx context.Context, userID primitive.ObjectID) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ChangeAvatar provides a mock function with given fields: ctx, schema
+func (_m *UsersRepository) ChangeAvatar(ctx context.Context, schema repository.ChangeAvatarSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.ChangeAvatarSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ChangeEmail provides a mock function with given fields: ctx, schema
 func (_m *UsersRepository) ChangeEmail(ctx context.Context, schema repository.ChangeEmailSchema) error {
 	ret := _m.Called(ctx, schema)
@@ -46,6 +76,34 @@ func (_m *UsersRepository) ChangePassword(ctx context.Context, schema repository
 	return r0
 }
 
+// ChangeRecoveryCodes provides a mock function with given fields: ctx, schema
+func (_m *UsersRepository) ChangeRecoveryCodes(ctx context.Context, schema repository.ChangeRecoveryCodesSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.ChangeRecoveryCodesSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ChangeUsername provides a mock function with given fields: ctx, schema
+func (_m *UsersRepository) ChangeUsername(ctx context.Context, schema repository.ChangeUsernameSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.ChangeUsernameSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Create provides a mock function with given fields: ctx, user
 func (_m *UsersRepository) Create(ctx context.Context, user entity.UserModel) error {
 	ret := _m.Called(ctx, user)
@@ -60,6 +118,48 @@ func (_m *UsersRepository) Create(ctx context.Context, user entity.UserModel) er
 	return r0
 }
 
+// Delete provides a mock function with given fields: ctx, userID
+func (_m *UsersRepository) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DisableTwoFactor provides a mock function with given fields: ctx, userID
+func (_m *UsersRepository) DisableTwoFactor(ctx context.Context, userID primitive.ObjectID) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnableTwoFactor provides a mock function with given fields: ctx, schema
+func (_m *UsersRepository) EnableTwoFactor(ctx context.Context, schema repository.EnableTwoFactorSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.EnableTwoFactorSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // FindByEmail provides a mock function with given fields: ctx, email
 func (_m *UsersRepository) FindByEmail(ctx context.Context, email string) (entity.UserModel, error) {
 	ret := _m.Called(ctx, email)
@@ -102,6 +202,27 @@ func (_m *UsersRepository) FindByID(ctx context.Context, userID primitive.Object
 	return r0, r1
 }
 
+// FindByIdentity provides a mock function with given fields: ctx, provider, subject
+func (_m *UsersRepository) FindByIdentity(ctx context.Context, provider string, subject string) (entity.UserModel, error) {
+	ret := _m.Called(ctx, provider, subject)
+
+	var r0 entity.UserModel
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) entity.UserModel); ok {
+		r0 = rf(ctx, provider, subject)
+	} else {
+		r0 = ret.Get(0).(entity.UserModel)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, provider, subject)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindByLogin provides a mock function with given fields: ctx, login
 func (_m *UsersRepository) FindByLogin(ctx context.Context, login string) (entity.UserModel, error) {
 	ret := _m.Called(ctx, login)
@@ -123,6 +244,27 @@ func (_m *UsersRepository) FindByLogin(ctx context.Context, login string) (entit
 	return r0, r1
 }
 
+// FindByPreviousUsername provides a mock function with given fields: ctx, username, since
+func (_m *UsersRepository) FindByPreviousUsername(ctx context.Context, username string, since time.Time) (entity.UserModel, error) {
+	ret := _m.Called(ctx, username, since)
+
+	var r0 entity.UserModel
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) entity.UserModel); ok {
+		r0 = rf(ctx, username, since)
+	} else {
+		r0 = ret.Get(0).(entity.UserModel)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(ctx, username, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindByUsername provides a mock function with given fields: ctx, username
 func (_m *UsersRepository) FindByUsername(ctx context.Context, username string) (entity.UserModel, error) {
 	ret := _m.Called(ctx, username)
@@ -144,6 +286,157 @@ func (_m *UsersRepository) FindByUsername(ctx context.Context, username string)
 	return r0, r1
 }
 
+// FindScheduledForDeletion provides a mock function with given fields: ctx, before
+func (_m *UsersRepository) FindScheduledForDeletion(ctx context.Context, before time.Time) ([]entity.UserModel, error) {
+	ret := _m.Called(ctx, before)
+
+	var r0 []entity.UserModel
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []entity.UserModel); ok {
+		r0 = rf(ctx, before)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.UserModel)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkIdentity provides a mock function with given fields: ctx, schema
+func (_m *UsersRepository) LinkIdentity(ctx context.Context, schema repository.LinkIdentitySchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.LinkIdentitySchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// List provides a mock function with given fields: ctx, schema
+func (_m *UsersRepository) List(ctx context.Context, schema repository.ListUsersSchema) ([]entity.UserModel, int64, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 []entity.UserModel
+	if rf, ok := ret.Get(0).(func(context.Context, repository.ListUsersSchema) []entity.UserModel); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.UserModel)
+		}
+	}
+
+	var r1 int64
+	if rf, ok := ret.Get(1).(func(context.Context, repository.ListUsersSchema) int64); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Get(1).(int64)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, repository.ListUsersSchema) error); ok {
+		r2 = rf(ctx, schema)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// RemoveRecoveryCode provides a mock function with given fields: ctx, schema
+func (_m *UsersRepository) RemoveRecoveryCode(ctx context.Context, schema repository.RemoveRecoveryCodeSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.RemoveRecoveryCodeSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ScheduleDeletion provides a mock function with given fields: ctx, schema
+func (_m *UsersRepository) ScheduleDeletion(ctx context.Context, schema repository.ScheduleDeletionSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.ScheduleDeletionSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetRole provides a mock function with given fields: ctx, schema
+func (_m *UsersRepository) SetRole(ctx context.Context, schema repository.SetRoleSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SetRoleSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Suspend provides a mock function with given fields: ctx, schema
+func (_m *UsersRepository) Suspend(ctx context.Context, schema repository.SuspendUserSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.SuspendUserSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Unsuspend provides a mock function with given fields: ctx, userID
+func (_m *UsersRepository) Unsuspend(ctx context.Context, userID primitive.ObjectID) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateProfile provides a mock function with given fields: ctx, schema
+func (_m *UsersRepository) UpdateProfile(ctx context.Context, schema repository.UpdateProfileSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.UpdateProfileSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewUsersRepository interface {
 	mock.TestingT
 	Cleanup(func())
@@ -18,9 +18,11 @@ type MongoDBConfig struct {
 }
 
 type mongoDB struct {
-	client *mongo.Client
-	db     *mongo.Database
-	users  UsersRepository
+	client  *mongo.Client
+	db      *mongo.Database
+	users   UsersRepository
+	apiKeys APIKeysRepository
+	audit   AuditRepository
 }
 
 func newMongoDB(ctx context.Context, cfg MongoDBConfig) (*mongoDB, error) {
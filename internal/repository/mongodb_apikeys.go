@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+type mongoDBAPIKeysRepository struct {
+	coll *mongo.Collection
+}
+
+func (m *mongoDB) createAPIKeysRepository() error {
+	coll := m.db.Collection(apiKeysCollection)
+
+	m.apiKeys = &mongoDBAPIKeysRepository{
+		coll: coll,
+	}
+
+	return nil
+}
+
+func (m *mongoDB) getAPIKeysRepository() APIKeysRepository {
+	return m.apiKeys
+}
+
+func (r *mongoDBAPIKeysRepository) Create(ctx context.Context, key entity.APIKeyModel) error {
+	_, err := r.coll.InsertOne(ctx, key)
+	return err
+}
+
+func (r *mongoDBAPIKeysRepository) FindByPrefix(ctx context.Context, prefix string) (entity.APIKeyModel, error) {
+	result := r.coll.FindOne(ctx, bson.M{
+		"prefix": prefix,
+	})
+
+	var key entity.APIKeyModel
+	if err := result.Decode(&key); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return entity.APIKeyModel{}, entity.ErrAPIKeyNotFound
+		}
+
+		return entity.APIKeyModel{}, err
+	}
+
+	return key, nil
+}
+
+func (r *mongoDBAPIKeysRepository) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]entity.APIKeyModel, error) {
+	cursor, err := r.coll.Find(ctx, bson.M{
+		"userID": userID,
+	}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []entity.APIKeyModel{}
+	if err = cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *mongoDBAPIKeysRepository) Delete(ctx context.Context, schema DeleteAPIKeySchema) error {
+	result, err := r.coll.DeleteOne(ctx, bson.M{
+		"_id":    schema.KeyID,
+		"userID": schema.UserID,
+	})
+	if err != nil {
+		return err
+	} else if result.DeletedCount == 0 {
+		return entity.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *mongoDBAPIKeysRepository) UpdateLastUsed(ctx context.Context, schema UpdateAPIKeyLastUsedSchema) error {
+	result, err := r.coll.UpdateOne(ctx, bson.M{"_id": schema.KeyID}, bson.M{
+		"$set": bson.M{"lastUsedAt": schema.LastUsedAt},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return entity.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *mongoDBAPIKeysRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.coll.DeleteMany(ctx, bson.M{"userID": userID})
+	return err
+}
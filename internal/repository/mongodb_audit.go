@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+const defaultAuditRetention = 365 * 24 * time.Hour
+
+type mongoDBAuditRepository struct {
+	coll *mongo.Collection
+}
+
+func (m *mongoDB) createAuditRepository(ctx context.Context, cfg AuditConfig) error {
+	coll := m.db.Collection(auditCollection)
+
+	retention := lo.Ternary(cfg.Retention <= 0, defaultAuditRetention, cfg.Retention)
+
+	indexModels := []mongo.IndexModel{
+		{
+			// events are removed by mongodb after retention period
+			Keys:    bson.M{"createdAt": 1},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())),
+		},
+	}
+
+	_, err := coll.Indexes().CreateMany(ctx, indexModels)
+	if err != nil {
+		return errors.Wrap(err, "failed to crete indices")
+	}
+
+	m.audit = &mongoDBAuditRepository{
+		coll: coll,
+	}
+
+	return nil
+}
+
+func (m *mongoDB) getAuditRepository() AuditRepository {
+	return m.audit
+}
+
+func (r *mongoDBAuditRepository) Create(ctx context.Context, event entity.AuditEventModel) error {
+	_, err := r.coll.InsertOne(ctx, event)
+	if mongo.IsDuplicateKeyError(err) {
+		return entity.ErrAuditEventAlreadyExists
+	}
+
+	return err
+}
+
+func (r *mongoDBAuditRepository) Find(ctx context.Context, schema FindAuditEventsSchema) ([]entity.AuditEventModel, int64, error) {
+	filter := bson.M{}
+	if !schema.UserID.IsZero() {
+		filter["$or"] = []bson.M{
+			{"actorID": schema.UserID},
+			{"targetUserID": schema.UserID},
+		}
+	}
+
+	if schema.Action != "" {
+		filter["action"] = schema.Action
+	}
+
+	total, err := r.coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := r.coll.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(schema.Offset).
+		SetLimit(schema.Limit),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events := []entity.AuditEventModel{}
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+func (r *mongoDBAuditRepository) Anonymize(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.coll.UpdateMany(ctx, bson.M{
+		"$or": []bson.M{
+			{"actorID": userID},
+			{"targetUserID": userID},
+		},
+	}, bson.M{
+		"$unset": bson.M{"ip": "", "userAgent": "", "details": ""},
+	})
+
+	return err
+}
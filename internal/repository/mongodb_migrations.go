@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mongoDBMigrationsLockID = "migrations"
+	// mongoDBMigrationsLockTTL releases lock of the instance, which crashed while migrating.
+	// Lock of running migrations is renewed, so they may run longer
+	mongoDBMigrationsLockTTL     = 15 * time.Minute
+	mongoDBMigrationsLockRenewal = mongoDBMigrationsLockTTL / 3
+	mongoDBMigrationsLockRetry   = time.Second
+)
+
+// errMigrationsLockLost is returned when lock expired and may be taken by another instance
+var errMigrationsLockLost = errors.New("migrations lock was lost")
+
+// mongoDBIndexes are indexes created by the first migration. TTL index of audit events
+// depends on configured retention, so it's created together with the repository
+var mongoDBIndexes = map[string][]mongo.IndexModel{
+	usersCollection: {
+		{
+			Keys:    bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "previousUsernames.username", Value: 1}},
+		},
+	},
+	apiKeysCollection: {
+		{
+			Keys:    bson.D{{Key: "prefix", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "userID", Value: 1}},
+		},
+	},
+	auditCollection: {
+		{
+			Keys: bson.D{{Key: "actorID", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "targetUserID", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+	},
+}
+
+// mongoDBMigrations are sorted by version, versions must never be reused
+var mongoDBMigrations = []migration[*mongo.Database]{
+	{
+		version: 1,
+		name:    "init",
+		up: func(ctx context.Context, db *mongo.Database) error {
+			for coll, indexModels := range mongoDBIndexes {
+				_, err := db.Collection(coll).Indexes().CreateMany(ctx, indexModels)
+				if err != nil {
+					return errors.Wrapf(err, "failed to create indices of %q collection", coll)
+				}
+			}
+
+			return nil
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			for coll, indexModels := range mongoDBIndexes {
+				for _, model := range indexModels {
+					name := mongoDBIndexName(model.Keys.(bson.D))
+
+					_, err := db.Collection(coll).Indexes().DropOne(ctx, name)
+					if err != nil {
+						return errors.Wrapf(err, "failed to drop %q index of %q collection", name, coll)
+					}
+				}
+			}
+
+			return nil
+		},
+	},
+	{
+		version: 2,
+		name:    "user_version",
+		up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(usersCollection).UpdateMany(ctx,
+				bson.M{"version": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"version": 1}},
+			)
+
+			return errors.Wrap(err, "failed to set initial version of users")
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(usersCollection).UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"version": ""}})
+			return errors.Wrap(err, "failed to unset version of users")
+		},
+	}, {
+		version: 3,
+		name:    "user_email_collation",
+		up: func(ctx context.Context, db *mongo.Database) error {
+			return replaceMongoDBEmailIndex(ctx, db, options.Index().
+				SetUnique(true).
+				SetSparse(true).
+				SetCollation(mongoDBEmailCollation),
+			)
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return replaceMongoDBEmailIndex(ctx, db, options.Index().
+				SetUnique(true).
+				SetSparse(true),
+			)
+		},
+	},
+}
+
+// replaceMongoDBEmailIndex recreates email index of users, index options can't be changed in place
+func replaceMongoDBEmailIndex(ctx context.Context, db *mongo.Database, opts *options.IndexOptions) error {
+	keys := bson.D{{Key: "email", Value: 1}}
+	indexes := db.Collection(usersCollection).Indexes()
+
+	_, err := indexes.DropOne(ctx, mongoDBIndexName(keys))
+	if err != nil {
+		return errors.Wrap(err, "failed to drop email index of users")
+	}
+
+	// users with emails differing only in case make creation fail, they must be resolved manually
+	_, err = indexes.CreateOne(ctx, mongo.IndexModel{Keys: keys, Options: opts})
+	if err != nil {
+		return errors.Wrap(err, "failed to create email index of users")
+	}
+
+	return nil
+}
+
+// mongoDBIndexName returns name, which mongodb gives to index without explicit name
+func mongoDBIndexName(keys bson.D) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s_%v", key.Key, key.Value))
+	}
+
+	return strings.Join(parts, "_")
+}
+
+// withMigrations runs migrations holding lock document. MongoDB has no transactional index changes,
+// so every migration is recorded right after it's run. Lock is renewed while migrations run,
+// they're cancelled once it's lost and migration isn't recorded unless lock is still held
+func (m *mongoDB) withMigrations(ctx context.Context, fn func(ctx context.Context, journal migrationsJournal, steps []migrationStep) error) error {
+	lock, err := m.lockMigrations(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to acquire migrations lock")
+	}
+	defer lock.release()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lost := make(chan struct{})
+	go lock.keep(ctx, func() {
+		close(lost)
+		cancel()
+	})
+
+	journal := mongoDBMigrationsJournal{coll: m.db.Collection(migrationsCollection), lock: lock}
+
+	err = fn(ctx, journal, bindMigrations(mongoDBMigrations, m.db))
+	if err != nil {
+		select {
+		case <-lost:
+			return errors.Wrap(errMigrationsLockLost, err.Error())
+		default:
+		}
+	}
+
+	return err
+}
+
+// mongoDBMigrationsLock is a lock document owned by the instance running migrations
+type mongoDBMigrationsLock struct {
+	coll  *mongo.Collection
+	owner primitive.ObjectID
+}
+
+// renew extends expiration of the lock, errMigrationsLockLost is returned if it's owned by another instance
+func (l mongoDBMigrationsLock) renew(ctx context.Context) error {
+	res, err := l.coll.UpdateOne(ctx,
+		bson.M{"_id": mongoDBMigrationsLockID, "owner": l.owner},
+		bson.M{"$set": bson.M{"expiresAt": time.Now().Add(mongoDBMigrationsLockTTL)}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return errMigrationsLockLost
+	}
+
+	return nil
+}
+
+// keep renews lock until ctx is done and calls lost once lock is owned by another instance.
+// Failed renewals are retried, as lock is valid until it expires
+func (l mongoDBMigrationsLock) keep(ctx context.Context, lost func()) {
+	ticker := time.NewTicker(mongoDBMigrationsLockRenewal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.renew(ctx); errors.Is(err, errMigrationsLockLost) {
+				lost()
+				return
+			}
+		}
+	}
+}
+
+func (l mongoDBMigrationsLock) release() {
+	_, _ = l.coll.DeleteOne(context.Background(), bson.M{"_id": mongoDBMigrationsLockID, "owner": l.owner})
+}
+
+// lockMigrations waits until lock document is released or expired and takes it
+func (m *mongoDB) lockMigrations(ctx context.Context) (mongoDBMigrationsLock, error) {
+	coll := m.db.Collection(migrationsLockCollection)
+	owner := primitive.NewObjectID()
+
+	for {
+		now := time.Now()
+
+		// lock held by another instance isn't matched, so upsert fails with duplicate key
+		_, err := coll.UpdateOne(ctx,
+			bson.M{"_id": mongoDBMigrationsLockID, "expiresAt": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": owner, "expiresAt": now.Add(mongoDBMigrationsLockTTL)}},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			break
+		}
+
+		if !mongo.IsDuplicateKeyError(err) {
+			return mongoDBMigrationsLock{}, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return mongoDBMigrationsLock{}, ctx.Err()
+		case <-time.After(mongoDBMigrationsLockRetry):
+		}
+	}
+
+	return mongoDBMigrationsLock{coll: coll, owner: owner}, nil
+}
+
+type mongoDBMigrationsJournal struct {
+	coll *mongo.Collection
+	// lock fences changes of the journal, so migration isn't recorded by instance, which lost the lock
+	lock mongoDBMigrationsLock
+}
+
+type mongoDBMigrationRecord struct {
+	Version   int64     `bson:"_id"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+func (j mongoDBMigrationsJournal) applied(ctx context.Context) (map[int64]time.Time, error) {
+	cursor, err := j.coll.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []mongoDBMigrationRecord
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]time.Time, len(records))
+	for _, record := range records {
+		applied[record.Version] = record.AppliedAt.UTC()
+	}
+
+	return applied, nil
+}
+
+func (j mongoDBMigrationsJournal) record(ctx context.Context, version int64, appliedAt time.Time) error {
+	if err := j.lock.renew(ctx); err != nil {
+		return err
+	}
+
+	_, err := j.coll.InsertOne(ctx, mongoDBMigrationRecord{Version: version, AppliedAt: appliedAt})
+	return err
+}
+
+func (j mongoDBMigrationsJournal) forget(ctx context.Context, version int64) error {
+	if err := j.lock.renew(ctx); err != nil {
+		return err
+	}
+
+	_, err := j.coll.DeleteOne(ctx, bson.M{"_id": version})
+	return err
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"regexp"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,29 +14,17 @@ import (
 	"github.com/kenplix/url-shrtnr/internal/entity"
 )
 
+// mongoDBEmailCollation compares emails case-insensitively like the other backends do,
+// email index of users is created with it by migration 3
+var mongoDBEmailCollation = &options.Collation{Locale: "en", Strength: 2}
+
 type mongoDBUsersRepository struct {
 	coll *mongo.Collection
 }
 
-func (m *mongoDB) createUsersRepository(ctx context.Context) error {
+func (m *mongoDB) createUsersRepository() error {
 	coll := m.db.Collection(usersCollection)
 
-	indexModels := []mongo.IndexModel{
-		{
-			Keys:    bson.M{"username": 1},
-			Options: options.Index().SetUnique(true).SetSparse(true),
-		},
-		{
-			Keys:    bson.M{"email": 1},
-			Options: options.Index().SetUnique(true).SetSparse(true),
-		},
-	}
-
-	_, err := coll.Indexes().CreateMany(ctx, indexModels)
-	if err != nil {
-		return errors.Wrap(err, "failed to crete indices")
-	}
-
 	m.users = &mongoDBUsersRepository{
 		coll: coll,
 	}
@@ -47,6 +37,10 @@ func (m *mongoDB) getUsersRepository() UsersRepository {
 }
 
 func (r *mongoDBUsersRepository) Create(ctx context.Context, user entity.UserModel) error {
+	if user.Version == 0 {
+		user.Version = 1
+	}
+
 	_, err := r.coll.InsertOne(ctx, user)
 	if mongo.IsDuplicateKeyError(err) {
 		return entity.ErrUserAlreadyExists
@@ -90,9 +84,10 @@ func (r *mongoDBUsersRepository) FindByUsername(ctx context.Context, username st
 }
 
 func (r *mongoDBUsersRepository) FindByEmail(ctx context.Context, email string) (entity.UserModel, error) {
+	// collation matches the one of email index, so emails are compared case-insensitively
 	result := r.coll.FindOne(ctx, bson.M{
 		"email": email,
-	})
+	}, options.FindOne().SetCollation(mongoDBEmailCollation))
 
 	var user entity.UserModel
 	if err := result.Decode(&user); err != nil {
@@ -107,10 +102,186 @@ func (r *mongoDBUsersRepository) FindByEmail(ctx context.Context, email string)
 }
 
 func (r *mongoDBUsersRepository) FindByLogin(ctx context.Context, login string) (entity.UserModel, error) {
+	// usernames and emails are compared with different collations, so they can't be matched by single query
+	user, err := r.FindByUsername(ctx, login)
+	if !errors.Is(err, entity.ErrUserNotFound) {
+		return user, err
+	}
+
+	return r.FindByEmail(ctx, login)
+}
+
+func (r *mongoDBUsersRepository) ChangeEmail(ctx context.Context, schema ChangeEmailSchema) error {
+	result, err := r.coll.UpdateOne(ctx, userVersionFilter(schema.UserID, schema.Version), bson.M{
+		"$set": bson.M{"email": schema.NewEmail},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return entity.ErrUserAlreadyExists
+		}
+
+		return err
+	} else if result.MatchedCount == 0 {
+		return r.unmatchedError(ctx, schema.UserID, schema.Version)
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) ChangePassword(ctx context.Context, schema ChangePasswordSchema) error {
+	result, err := r.coll.UpdateOne(ctx, userVersionFilter(schema.UserID, schema.Version), bson.M{
+		"$set": bson.M{"passwordHash": schema.NewPasswordHash},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return r.unmatchedError(ctx, schema.UserID, schema.Version)
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) ChangeUsername(ctx context.Context, schema ChangeUsernameSchema) error {
+	// all expressions of the stage see document before the update, so "$username" is the previous username
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"previousUsernames": bson.M{"$slice": bson.A{
+				bson.M{"$concatArrays": bson.A{
+					bson.M{"$ifNull": bson.A{"$previousUsernames", bson.A{}}},
+					bson.A{bson.M{"username": "$username", "changedAt": schema.ChangedAt}},
+				}},
+				-maxPreviousUsernames,
+			}},
+			"username":          schema.NewUsername,
+			"usernameChangedAt": schema.ChangedAt,
+			"version":           bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+		}}},
+	}
+
+	result, err := r.coll.UpdateOne(ctx, userVersionFilter(schema.UserID, schema.Version), pipeline)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return entity.ErrUsernameTaken
+		}
+
+		return err
+	} else if result.MatchedCount == 0 {
+		return r.unmatchedError(ctx, schema.UserID, schema.Version)
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) FindByPreviousUsername(ctx context.Context, username string, since time.Time) (entity.UserModel, error) {
+	var user entity.UserModel
+
+	err := r.coll.FindOne(ctx, bson.M{
+		"previousUsernames": bson.M{"$elemMatch": bson.M{
+			"username":  username,
+			"changedAt": bson.M{"$gt": since},
+		}},
+	}).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return entity.UserModel{}, entity.ErrUserNotFound
+		}
+
+		return entity.UserModel{}, err
+	}
+
+	return user, nil
+}
+
+func (r *mongoDBUsersRepository) UpdateProfile(ctx context.Context, schema UpdateProfileSchema) error {
+	result, err := r.coll.UpdateOne(ctx, userVersionFilter(schema.UserID, schema.Version), bson.M{
+		"$set": bson.M{"displayName": schema.DisplayName, "bio": schema.Bio},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return r.unmatchedError(ctx, schema.UserID, schema.Version)
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) ChangeAvatar(ctx context.Context, schema ChangeAvatarSchema) error {
+	result, err := r.coll.UpdateOne(ctx, userVersionFilter(schema.UserID, schema.Version), bson.M{
+		"$set": bson.M{"avatar": schema.Avatar},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return r.unmatchedError(ctx, schema.UserID, schema.Version)
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) EnableTwoFactor(ctx context.Context, schema EnableTwoFactorSchema) error {
+	result, err := r.coll.UpdateOne(ctx, bson.M{"_id": schema.UserID}, bson.M{
+		"$set": bson.M{"twoFactor": schema.TwoFactor},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) DisableTwoFactor(ctx context.Context, userID primitive.ObjectID) error {
+	result, err := r.coll.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$unset": bson.M{"twoFactor": ""},
+		"$inc":   bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) ChangeRecoveryCodes(ctx context.Context, schema ChangeRecoveryCodesSchema) error {
+	result, err := r.coll.UpdateOne(ctx, bson.M{"_id": schema.UserID, "twoFactor": bson.M{"$exists": true}}, bson.M{
+		"$set": bson.M{"twoFactor.recoveryCodeHashes": schema.RecoveryCodeHashes},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) RemoveRecoveryCode(ctx context.Context, schema RemoveRecoveryCodeSchema) error {
+	result, err := r.coll.UpdateOne(ctx, bson.M{"_id": schema.UserID, "twoFactor.recoveryCodeHashes": schema.RecoveryCodeHash}, bson.M{
+		"$pull": bson.M{"twoFactor.recoveryCodeHashes": schema.RecoveryCodeHash},
+		"$inc":  bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return entity.ErrIncorrectTwoFactorCode
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) FindByIdentity(ctx context.Context, provider, subject string) (entity.UserModel, error) {
 	result := r.coll.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"username": login},
-			{"email": login},
+		"identities": bson.M{
+			"$elemMatch": bson.M{"provider": provider, "subject": subject},
 		},
 	})
 
@@ -126,24 +297,176 @@ func (r *mongoDBUsersRepository) FindByLogin(ctx context.Context, login string)
 	return user, nil
 }
 
-func (r *mongoDBUsersRepository) ChangeEmail(ctx context.Context, schema ChangeEmailSchema) error {
+func (r *mongoDBUsersRepository) LinkIdentity(ctx context.Context, schema LinkIdentitySchema) error {
 	result, err := r.coll.UpdateOne(ctx, bson.M{"_id": schema.UserID}, bson.M{
-		"$set": bson.M{"email": schema.NewEmail},
+		"$push": bson.M{"identities": schema.Identity},
+		"$inc":  bson.M{"version": 1},
 	})
-	if result.MatchedCount == 0 {
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return entity.ErrOIDCIdentityAlreadyLinked
+		}
+
+		return err
+	} else if result.MatchedCount == 0 {
 		return entity.ErrUserNotFound
 	}
 
-	return err
+	return nil
 }
 
-func (r *mongoDBUsersRepository) ChangePassword(ctx context.Context, schema ChangePasswordSchema) error {
+func (r *mongoDBUsersRepository) List(ctx context.Context, schema ListUsersSchema) ([]entity.UserModel, int64, error) {
+	filter := bson.M{}
+	if schema.Search != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(schema.Search), Options: "i"}
+		filter["$or"] = []bson.M{
+			{"username": pattern},
+			{"email": pattern},
+		}
+	}
+
+	total, err := r.coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := r.coll.Find(ctx, filter, options.Find().
+		SetSort(bson.M{"_id": 1}).
+		SetSkip(schema.Offset).
+		SetLimit(schema.Limit),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := []entity.UserModel{}
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (r *mongoDBUsersRepository) SetRole(ctx context.Context, schema SetRoleSchema) error {
 	result, err := r.coll.UpdateOne(ctx, bson.M{"_id": schema.UserID}, bson.M{
-		"$set": bson.M{"passwordHash": schema.NewPasswordHash},
+		"$set": bson.M{"role": schema.Role},
+		"$inc": bson.M{"version": 1},
 	})
-	if result.MatchedCount == 0 {
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
 		return entity.ErrUserNotFound
 	}
 
-	return err
+	return nil
+}
+
+func (r *mongoDBUsersRepository) Suspend(ctx context.Context, schema SuspendUserSchema) error {
+	result, err := r.coll.UpdateOne(ctx, bson.M{"_id": schema.UserID}, bson.M{
+		"$set": bson.M{"suspendedAt": schema.SuspendedAt, "suspensionReason": schema.Reason},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) Unsuspend(ctx context.Context, userID primitive.ObjectID) error {
+	result, err := r.coll.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$set":   bson.M{"suspendedAt": nil},
+		"$unset": bson.M{"suspensionReason": ""},
+		"$inc":   bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) ScheduleDeletion(ctx context.Context, schema ScheduleDeletionSchema) error {
+	result, err := r.coll.UpdateOne(ctx, bson.M{"_id": schema.UserID}, bson.M{
+		"$set": bson.M{"deletionScheduledAt": schema.DeletionScheduledAt},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) CancelDeletion(ctx context.Context, userID primitive.ObjectID) error {
+	result, err := r.coll.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{
+		"$unset": bson.M{"deletionScheduledAt": ""},
+		"$inc":   bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	} else if result.MatchedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *mongoDBUsersRepository) FindScheduledForDeletion(ctx context.Context, before time.Time) ([]entity.UserModel, error) {
+	cursor, err := r.coll.Find(ctx, bson.M{
+		"deletionScheduledAt": bson.M{"$lte": before},
+	}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	users := []entity.UserModel{}
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *mongoDBUsersRepository) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	result, err := r.coll.DeleteOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return err
+	} else if result.DeletedCount == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// userVersionFilter matches the user and, if expected version isn't zero, its version
+func userVersionFilter(userID primitive.ObjectID, version int64) bson.M {
+	filter := bson.M{"_id": userID}
+	if version != 0 {
+		filter["version"] = version
+	}
+
+	return filter
+}
+
+// unmatchedError tells apart missing user and the user, whose version was changed concurrently
+func (r *mongoDBUsersRepository) unmatchedError(ctx context.Context, userID primitive.ObjectID, version int64) error {
+	if version == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	count, err := r.coll.CountDocuments(ctx, bson.M{"_id": userID}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	} else if count == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return entity.ErrUserVersionConflict
 }
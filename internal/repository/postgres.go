@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/pkg/database/postgres"
+)
+
+// postgresMigrationsLock is a key of advisory lock held while migrations are applied,
+// so instances started at the same time don't apply them concurrently
+const postgresMigrationsLock = 0x75726c73 // "urls"
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+// PostgreSQL error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+type postgresDB struct {
+	pool    *pgxpool.Pool
+	users   UsersRepository
+	apiKeys APIKeysRepository
+	audit   AuditRepository
+}
+
+func newPostgresDB(ctx context.Context, cfg postgres.Config) (*postgresDB, error) {
+	pool, err := postgres.NewPool(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &postgresDB{
+		pool: pool,
+	}
+
+	return db, nil
+}
+
+func (p *postgresDB) close(_ context.Context) error {
+	p.pool.Close()
+	return nil
+}
+
+// postgresMigrationsList returns embedded SQL migrations followed by migrations written in Go
+func postgresMigrationsList() ([]migration[pgx.Tx], error) {
+	migrations, err := readMigrations(postgresMigrations, "migrations/postgres")
+	if err != nil {
+		return nil, err
+	}
+
+	return execMigrations(migrations, func(ctx context.Context, tx pgx.Tx, script string) error {
+		_, err := tx.Exec(ctx, script)
+		return err
+	}), nil
+}
+
+// withMigrations runs migrations in a single transaction holding advisory lock,
+// so failed migration doesn't leave schema half-changed
+func (p *postgresDB) withMigrations(ctx context.Context, fn func(ctx context.Context, journal migrationsJournal, steps []migrationStep) error) error {
+	migrations, err := postgresMigrationsList()
+	if err != nil {
+		return err
+	}
+
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresMigrationsLock)
+		if err != nil {
+			return errors.Wrap(err, "failed to acquire migrations lock")
+		}
+
+		_, err = tx.Exec(ctx, `
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version    BIGINT PRIMARY KEY,
+				applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			)`)
+		if err != nil {
+			return errors.Wrap(err, "failed to create migrations table")
+		}
+
+		return fn(ctx, postgresMigrationsJournal{tx: tx}, bindMigrations(migrations, tx))
+	})
+}
+
+type postgresMigrationsJournal struct {
+	tx pgx.Tx
+}
+
+func (j postgresMigrationsJournal) applied(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := j.tx.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt.UTC()
+	}
+
+	return applied, rows.Err()
+}
+
+func (j postgresMigrationsJournal) record(ctx context.Context, version int64, appliedAt time.Time) error {
+	_, err := j.tx.Exec(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)`, version, appliedAt)
+	return err
+}
+
+func (j postgresMigrationsJournal) forget(ctx context.Context, version int64) error {
+	_, err := j.tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, version)
+	return err
+}
+
+// isUniqueViolation is the postgres counterpart of mongo.IsDuplicateKeyError
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation
+}
+
+// nullableID returns nil for zero ID, so it's stored as NULL
+func nullableID(id primitive.ObjectID) *string {
+	if id.IsZero() {
+		return nil
+	}
+
+	hex := id.Hex()
+
+	return &hex
+}
+
+// parseID is the inverse of nullableID
+func parseID(hex *string) (primitive.ObjectID, error) {
+	if hex == nil {
+		return primitive.NilObjectID, nil
+	}
+
+	return primitive.ObjectIDFromHex(*hex)
+}
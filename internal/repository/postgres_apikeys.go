@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+const postgresAPIKeyColumns = `id, user_id, name, prefix, secret_hash, scopes, expires_at, last_used_at, created_at`
+
+type postgresAPIKeysRepository struct {
+	db *postgresDB
+}
+
+func (p *postgresDB) createAPIKeysRepository() error {
+	p.apiKeys = &postgresAPIKeysRepository{
+		db: p,
+	}
+
+	return nil
+}
+
+func (p *postgresDB) getAPIKeysRepository() APIKeysRepository {
+	return p.apiKeys
+}
+
+func (r *postgresAPIKeysRepository) Create(ctx context.Context, key entity.APIKeyModel) error {
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.db.pool.Exec(ctx, `
+		INSERT INTO api_keys (`+postgresAPIKeyColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		key.ID.Hex(), key.UserID.Hex(), key.Name, key.Prefix, key.SecretHash,
+		lo.Ternary(key.Scopes == nil, []string{}, key.Scopes), key.ExpiresAt, key.LastUsedAt, key.CreatedAt,
+	)
+
+	return err
+}
+
+func (r *postgresAPIKeysRepository) FindByPrefix(ctx context.Context, prefix string) (entity.APIKeyModel, error) {
+	rows, err := r.db.pool.Query(ctx, `SELECT `+postgresAPIKeyColumns+` FROM api_keys WHERE prefix = $1`, prefix)
+	if err != nil {
+		return entity.APIKeyModel{}, err
+	}
+
+	key, err := pgx.CollectOneRow(rows, scanPostgresAPIKey)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.APIKeyModel{}, entity.ErrAPIKeyNotFound
+	}
+
+	return key, err
+}
+
+func (r *postgresAPIKeysRepository) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]entity.APIKeyModel, error) {
+	rows, err := r.db.pool.Query(ctx, `
+		SELECT `+postgresAPIKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at, id`,
+		userID.Hex(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := pgx.CollectRows(rows, scanPostgresAPIKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Ternary(keys == nil, []entity.APIKeyModel{}, keys), nil
+}
+
+func (r *postgresAPIKeysRepository) Delete(ctx context.Context, schema DeleteAPIKeySchema) error {
+	tag, err := r.db.pool.Exec(ctx, `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, schema.KeyID.Hex(), schema.UserID.Hex())
+	if err != nil {
+		return err
+	} else if tag.RowsAffected() == 0 {
+		return entity.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresAPIKeysRepository) UpdateLastUsed(ctx context.Context, schema UpdateAPIKeyLastUsedSchema) error {
+	tag, err := r.db.pool.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, schema.KeyID.Hex(), schema.LastUsedAt)
+	if err != nil {
+		return err
+	} else if tag.RowsAffected() == 0 {
+		return entity.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresAPIKeysRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.db.pool.Exec(ctx, `DELETE FROM api_keys WHERE user_id = $1`, userID.Hex())
+	return err
+}
+
+func scanPostgresAPIKey(row pgx.CollectableRow) (entity.APIKeyModel, error) {
+	var (
+		key        entity.APIKeyModel
+		id, userID string
+	)
+
+	err := row.Scan(&id, &userID, &key.Name, &key.Prefix, &key.SecretHash, &key.Scopes, &key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt)
+	if err != nil {
+		return entity.APIKeyModel{}, err
+	}
+
+	if key.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return entity.APIKeyModel{}, errors.Wrapf(err, "invalid api key ID %q", id)
+	}
+
+	if key.UserID, err = primitive.ObjectIDFromHex(userID); err != nil {
+		return entity.APIKeyModel{}, errors.Wrapf(err, "invalid user ID %q", userID)
+	}
+
+	return key, nil
+}
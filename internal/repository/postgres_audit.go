@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+const postgresAuditEventColumns = `id, action, outcome, actor_id, target_user_id, ip, user_agent, request_id, details, created_at`
+
+type postgresAuditRepository struct {
+	db *postgresDB
+}
+
+func (p *postgresDB) createAuditRepository() error {
+	p.audit = &postgresAuditRepository{
+		db: p,
+	}
+
+	return nil
+}
+
+func (p *postgresDB) getAuditRepository() AuditRepository {
+	return p.audit
+}
+
+func (r *postgresAuditRepository) Create(ctx context.Context, event entity.AuditEventModel) error {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode details")
+	}
+
+	_, err = r.db.pool.Exec(ctx, `
+		INSERT INTO audit_events (`+postgresAuditEventColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9::jsonb, 'null'), $10)`,
+		event.ID.Hex(), event.Action, event.Outcome, nullableID(event.ActorID), nullableID(event.TargetUserID),
+		event.IP, event.UserAgent, event.RequestID, string(details), event.CreatedAt,
+	)
+	if isUniqueViolation(err) {
+		return entity.ErrAuditEventAlreadyExists
+	}
+
+	return err
+}
+
+func (r *postgresAuditRepository) Find(ctx context.Context, schema FindAuditEventsSchema) ([]entity.AuditEventModel, int64, error) {
+	const filter = `($1::text IS NULL OR actor_id = $1 OR target_user_id = $1) AND ($2 = '' OR action = $2)`
+
+	userID := nullableID(schema.UserID)
+
+	var total int64
+
+	err := r.db.pool.QueryRow(ctx, `SELECT count(*) FROM audit_events WHERE `+filter, userID, schema.Action).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.pool.Query(ctx, `
+		SELECT `+postgresAuditEventColumns+` FROM audit_events WHERE `+filter+`
+		ORDER BY created_at DESC, id DESC OFFSET $3 LIMIT NULLIF($4, 0)`,
+		userID, schema.Action, schema.Offset, schema.Limit,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events, err := pgx.CollectRows(rows, scanPostgresAuditEvent)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return lo.Ternary(events == nil, []entity.AuditEventModel{}, events), total, nil
+}
+
+func (r *postgresAuditRepository) Anonymize(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.db.pool.Exec(ctx, `
+		UPDATE audit_events SET ip = '', user_agent = '', details = NULL
+		WHERE actor_id = $1 OR target_user_id = $1`,
+		userID.Hex(),
+	)
+
+	return err
+}
+
+func scanPostgresAuditEvent(row pgx.CollectableRow) (entity.AuditEventModel, error) {
+	var (
+		event                 entity.AuditEventModel
+		id                    string
+		actorID, targetUserID *string
+		details               []byte
+	)
+
+	err := row.Scan(&id, &event.Action, &event.Outcome, &actorID, &targetUserID,
+		&event.IP, &event.UserAgent, &event.RequestID, &details, &event.CreatedAt)
+	if err != nil {
+		return entity.AuditEventModel{}, err
+	}
+
+	if event.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return entity.AuditEventModel{}, errors.Wrapf(err, "invalid event ID %q", id)
+	}
+
+	if event.ActorID, err = parseID(actorID); err != nil {
+		return entity.AuditEventModel{}, errors.Wrap(err, "invalid actor ID")
+	}
+
+	if event.TargetUserID, err = parseID(targetUserID); err != nil {
+		return entity.AuditEventModel{}, errors.Wrap(err, "invalid target user ID")
+	}
+
+	if details != nil {
+		if err = json.Unmarshal(details, &event.Details); err != nil {
+			return entity.AuditEventModel{}, errors.Wrap(err, "failed to decode details")
+		}
+	}
+
+	return event, nil
+}
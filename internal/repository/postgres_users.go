@@ -0,0 +1,410 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+// postgresUserColumns are selected from users table aliased as u, identities are aggregated into JSON array
+const postgresUserColumns = `
+	u.id, u.username, u.email, u.password_hash, u.role, u.created_at, u.updated_at,
+	u.suspended_at, u.suspension_reason, u.two_factor, u.deletion_scheduled_at,
+	u.display_name, u.bio, u.avatar, u.username_changed_at, u.previous_usernames, u.version,
+	COALESCE((
+		SELECT jsonb_agg(jsonb_build_object('provider', i.provider, 'subject', i.subject, 'linkedAt', i.linked_at) ORDER BY i.linked_at)
+		FROM user_identities i
+		WHERE i.user_id = u.id
+	), '[]')`
+
+// postgresSearchEscaper escapes wildcards of LIKE patterns
+var postgresSearchEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type postgresUsersRepository struct {
+	db *postgresDB
+}
+
+func (p *postgresDB) createUsersRepository() error {
+	p.users = &postgresUsersRepository{
+		db: p,
+	}
+
+	return nil
+}
+
+func (p *postgresDB) getUsersRepository() UsersRepository {
+	return p.users
+}
+
+func (r *postgresUsersRepository) Create(ctx context.Context, user entity.UserModel) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+
+	if user.Version == 0 {
+		user.Version = 1
+	}
+
+	twoFactor, err := json.Marshal(user.TwoFactor)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode two factor")
+	}
+
+	previousUsernames, err := json.Marshal(lo.Ternary(user.PreviousUsernames == nil, []entity.PreviousUsernameModel{}, user.PreviousUsernames))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode previous usernames")
+	}
+
+	err = pgx.BeginFunc(ctx, r.db.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO users (
+				id, username, email, password_hash, role, created_at, updated_at,
+				suspended_at, suspension_reason, two_factor, deletion_scheduled_at,
+				display_name, bio, avatar, username_changed_at, previous_usernames, version
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10::jsonb, 'null'), $11, $12, $13, $14, $15, $16, $17)`,
+			user.ID.Hex(), user.Username, user.Email, user.PasswordHash, user.Role, user.CreatedAt, user.UpdatedAt,
+			user.SuspendedAt, user.SuspensionReason, string(twoFactor), user.DeletionScheduledAt,
+			user.DisplayName, user.Bio, user.Avatar, user.UsernameChangedAt, string(previousUsernames), user.Version,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, identity := range user.Identities {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO user_identities (provider, subject, user_id, linked_at) VALUES ($1, $2, $3, $4)`,
+				identity.Provider, identity.Subject, user.ID.Hex(), identity.LinkedAt,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if isUniqueViolation(err) {
+		return entity.ErrUserAlreadyExists
+	}
+
+	return err
+}
+
+func (r *postgresUsersRepository) FindByID(ctx context.Context, userID primitive.ObjectID) (entity.UserModel, error) {
+	return r.findOne(ctx, `u.id = $1`, userID.Hex())
+}
+
+func (r *postgresUsersRepository) FindByUsername(ctx context.Context, username string) (entity.UserModel, error) {
+	return r.findOne(ctx, `u.username = $1`, username)
+}
+
+func (r *postgresUsersRepository) FindByEmail(ctx context.Context, email string) (entity.UserModel, error) {
+	return r.findOne(ctx, `lower(u.email) = lower($1)`, email)
+}
+
+func (r *postgresUsersRepository) FindByLogin(ctx context.Context, login string) (entity.UserModel, error) {
+	return r.findOne(ctx, `u.username = $1 OR lower(u.email) = lower($1)`, login)
+}
+
+func (r *postgresUsersRepository) ChangeEmail(ctx context.Context, schema ChangeEmailSchema) error {
+	err := r.update(ctx, schema.UserID, schema.Version, `email = $2`, schema.NewEmail)
+	if isUniqueViolation(err) {
+		return entity.ErrUserAlreadyExists
+	}
+
+	return err
+}
+
+func (r *postgresUsersRepository) ChangePassword(ctx context.Context, schema ChangePasswordSchema) error {
+	return r.update(ctx, schema.UserID, schema.Version, `password_hash = $2`, schema.NewPasswordHash)
+}
+
+func (r *postgresUsersRepository) ChangeUsername(ctx context.Context, schema ChangeUsernameSchema) error {
+	// all expressions of SET see the row before the update, so "username" is the previous username
+	err := r.update(ctx, schema.UserID, schema.Version, `
+		previous_usernames = (
+			SELECT COALESCE(jsonb_agg(p.value ORDER BY p.ordinality), '[]')
+			FROM (
+				SELECT value, ordinality
+				FROM jsonb_array_elements(previous_usernames || jsonb_build_array(jsonb_build_object('username', username, 'changedAt', $3::timestamptz)))
+					WITH ORDINALITY
+				ORDER BY ordinality DESC
+				LIMIT $4
+			) p
+		),
+		username = $2,
+		username_changed_at = $3`,
+		schema.NewUsername, schema.ChangedAt, maxPreviousUsernames,
+	)
+	if isUniqueViolation(err) {
+		return entity.ErrUsernameTaken
+	}
+
+	return err
+}
+
+func (r *postgresUsersRepository) FindByPreviousUsername(ctx context.Context, username string, since time.Time) (entity.UserModel, error) {
+	return r.findOne(ctx, `
+		u.previous_usernames @> jsonb_build_array(jsonb_build_object('username', $1::text)) AND EXISTS (
+			SELECT 1
+			FROM jsonb_array_elements(u.previous_usernames) p
+			WHERE p.value->>'username' = $1 AND (p.value->>'changedAt')::timestamptz > $2
+		)`,
+		username, since,
+	)
+}
+
+func (r *postgresUsersRepository) UpdateProfile(ctx context.Context, schema UpdateProfileSchema) error {
+	return r.update(ctx, schema.UserID, schema.Version, `display_name = $2, bio = $3`, schema.DisplayName, schema.Bio)
+}
+
+func (r *postgresUsersRepository) ChangeAvatar(ctx context.Context, schema ChangeAvatarSchema) error {
+	return r.update(ctx, schema.UserID, schema.Version, `avatar = $2`, schema.Avatar)
+}
+
+func (r *postgresUsersRepository) EnableTwoFactor(ctx context.Context, schema EnableTwoFactorSchema) error {
+	twoFactor, err := json.Marshal(schema.TwoFactor)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode two factor")
+	}
+
+	return r.update(ctx, schema.UserID, 0, `two_factor = $2::jsonb`, string(twoFactor))
+}
+
+func (r *postgresUsersRepository) DisableTwoFactor(ctx context.Context, userID primitive.ObjectID) error {
+	return r.update(ctx, userID, 0, `two_factor = NULL`)
+}
+
+func (r *postgresUsersRepository) ChangeRecoveryCodes(ctx context.Context, schema ChangeRecoveryCodesSchema) error {
+	hashes, err := json.Marshal(schema.RecoveryCodeHashes)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode recovery code hashes")
+	}
+
+	tag, err := r.db.pool.Exec(ctx, `
+		UPDATE users SET two_factor = jsonb_set(two_factor, '{recoveryCodeHashes}', $2::jsonb), version = version + 1
+		WHERE id = $1 AND two_factor IS NOT NULL`,
+		schema.UserID.Hex(), string(hashes),
+	)
+	if err != nil {
+		return err
+	} else if tag.RowsAffected() == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *postgresUsersRepository) RemoveRecoveryCode(ctx context.Context, schema RemoveRecoveryCodeSchema) error {
+	tag, err := r.db.pool.Exec(ctx, `
+		UPDATE users SET two_factor = jsonb_set(two_factor, '{recoveryCodeHashes}', (two_factor->'recoveryCodeHashes') - $2::text),
+			version = version + 1
+		WHERE id = $1 AND two_factor->'recoveryCodeHashes' @> jsonb_build_array($2::text)`,
+		schema.UserID.Hex(), schema.RecoveryCodeHash,
+	)
+	if err != nil {
+		return err
+	} else if tag.RowsAffected() == 0 {
+		return entity.ErrIncorrectTwoFactorCode
+	}
+
+	return nil
+}
+
+func (r *postgresUsersRepository) FindByIdentity(ctx context.Context, provider, subject string) (entity.UserModel, error) {
+	return r.findOne(ctx, `u.id = (SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2)`, provider, subject)
+}
+
+func (r *postgresUsersRepository) LinkIdentity(ctx context.Context, schema LinkIdentitySchema) error {
+	err := pgx.BeginFunc(ctx, r.db.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO user_identities (provider, subject, user_id, linked_at) VALUES ($1, $2, $3, $4)`,
+			schema.Identity.Provider, schema.Identity.Subject, schema.UserID.Hex(), schema.Identity.LinkedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE users SET version = version + 1 WHERE id = $1`, schema.UserID.Hex())
+
+		return err
+	})
+	if isUniqueViolation(err) {
+		return entity.ErrOIDCIdentityAlreadyLinked
+	} else if isForeignKeyViolation(err) {
+		return entity.ErrUserNotFound
+	}
+
+	return err
+}
+
+func (r *postgresUsersRepository) List(ctx context.Context, schema ListUsersSchema) ([]entity.UserModel, int64, error) {
+	const filter = `$1 = '' OR u.username ILIKE '%' || $1 || '%' OR u.email ILIKE '%' || $1 || '%'`
+
+	search := postgresSearchEscaper.Replace(schema.Search)
+
+	var total int64
+
+	err := r.db.pool.QueryRow(ctx, `SELECT count(*) FROM users u WHERE `+filter, search).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users, err := r.find(ctx, filter+` ORDER BY u.id OFFSET $2 LIMIT NULLIF($3, 0)`, search, schema.Offset, schema.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (r *postgresUsersRepository) SetRole(ctx context.Context, schema SetRoleSchema) error {
+	return r.update(ctx, schema.UserID, 0, `role = $2`, schema.Role)
+}
+
+func (r *postgresUsersRepository) Suspend(ctx context.Context, schema SuspendUserSchema) error {
+	return r.update(ctx, schema.UserID, 0, `suspended_at = $2, suspension_reason = $3`, schema.SuspendedAt, schema.Reason)
+}
+
+func (r *postgresUsersRepository) Unsuspend(ctx context.Context, userID primitive.ObjectID) error {
+	return r.update(ctx, userID, 0, `suspended_at = NULL, suspension_reason = ''`)
+}
+
+func (r *postgresUsersRepository) ScheduleDeletion(ctx context.Context, schema ScheduleDeletionSchema) error {
+	return r.update(ctx, schema.UserID, 0, `deletion_scheduled_at = $2`, schema.DeletionScheduledAt)
+}
+
+func (r *postgresUsersRepository) CancelDeletion(ctx context.Context, userID primitive.ObjectID) error {
+	return r.update(ctx, userID, 0, `deletion_scheduled_at = NULL`)
+}
+
+func (r *postgresUsersRepository) FindScheduledForDeletion(ctx context.Context, before time.Time) ([]entity.UserModel, error) {
+	return r.find(ctx, `u.deletion_scheduled_at <= $1 ORDER BY u.id`, before)
+}
+
+func (r *postgresUsersRepository) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	tag, err := r.db.pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID.Hex())
+	if err != nil {
+		return err
+	} else if tag.RowsAffected() == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// update sets columns of the user and increments its version, ID of the user is the first argument
+// of the set expression. Version of the user must match expected one unless it's zero
+func (r *postgresUsersRepository) update(ctx context.Context, userID primitive.ObjectID, version int64, set string, args ...any) error {
+	query := `UPDATE users SET ` + set + `, version = version + 1 WHERE id = $1`
+	args = append([]any{userID.Hex()}, args...)
+
+	if version != 0 {
+		args = append(args, version)
+		query += fmt.Sprintf(` AND version = $%d`, len(args))
+	}
+
+	tag, err := r.db.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	} else if tag.RowsAffected() == 0 {
+		return r.unmatchedError(ctx, userID, version)
+	}
+
+	return nil
+}
+
+// unmatchedError tells apart missing user and the user, whose version was changed concurrently
+func (r *postgresUsersRepository) unmatchedError(ctx context.Context, userID primitive.ObjectID, version int64) error {
+	if version == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	var exists bool
+
+	err := r.db.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID.Hex()).Scan(&exists)
+	if err != nil {
+		return err
+	} else if !exists {
+		return entity.ErrUserNotFound
+	}
+
+	return entity.ErrUserVersionConflict
+}
+
+// findOne returns the first user, who matches condition, in order of creation
+func (r *postgresUsersRepository) findOne(ctx context.Context, condition string, args ...any) (entity.UserModel, error) {
+	users, err := r.find(ctx, condition+` ORDER BY u.id LIMIT 1`, args...)
+	if err != nil {
+		return entity.UserModel{}, err
+	} else if len(users) == 0 {
+		return entity.UserModel{}, entity.ErrUserNotFound
+	}
+
+	return users[0], nil
+}
+
+// find returns users, who match condition, condition may be followed by ordering and limits
+func (r *postgresUsersRepository) find(ctx context.Context, condition string, args ...any) ([]entity.UserModel, error) {
+	rows, err := r.db.pool.Query(ctx, `SELECT `+postgresUserColumns+` FROM users u WHERE `+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := pgx.CollectRows(rows, scanPostgresUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Ternary(users == nil, []entity.UserModel{}, users), nil
+}
+
+func scanPostgresUser(row pgx.CollectableRow) (entity.UserModel, error) {
+	var (
+		user                                     entity.UserModel
+		id                                       string
+		twoFactor, previousUsernames, identities []byte
+	)
+
+	err := row.Scan(
+		&id, &user.Username, &user.Email, &user.PasswordHash, &user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.SuspendedAt, &user.SuspensionReason, &twoFactor, &user.DeletionScheduledAt,
+		&user.DisplayName, &user.Bio, &user.Avatar, &user.UsernameChangedAt, &previousUsernames, &user.Version,
+		&identities,
+	)
+	if err != nil {
+		return entity.UserModel{}, err
+	}
+
+	if user.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return entity.UserModel{}, errors.Wrapf(err, "invalid user ID %q", id)
+	}
+
+	if twoFactor != nil {
+		if err = json.Unmarshal(twoFactor, &user.TwoFactor); err != nil {
+			return entity.UserModel{}, errors.Wrap(err, "failed to decode two factor")
+		}
+	}
+
+	if err = json.Unmarshal(previousUsernames, &user.PreviousUsernames); err != nil {
+		return entity.UserModel{}, errors.Wrap(err, "failed to decode previous usernames")
+	}
+
+	if err = json.Unmarshal(identities, &user.Identities); err != nil {
+		return entity.UserModel{}, errors.Wrap(err, "failed to decode identities")
+	}
+
+	// empty arrays are omitted like in the other backends
+	user.PreviousUsernames = lo.Ternary(len(user.PreviousUsernames) == 0, nil, user.PreviousUsernames)
+	user.Identities = lo.Ternary(len(user.Identities) == 0, nil, user.Identities)
+
+	return user, nil
+}
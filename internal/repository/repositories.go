@@ -3,46 +3,214 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/pkg/database/postgres"
 )
 
 type ChangeEmailSchema struct {
 	UserID   primitive.ObjectID
 	NewEmail string
+	// Version is expected version of the user, it isn't checked if zero
+	Version int64
 }
 
 type ChangePasswordSchema struct {
 	UserID          primitive.ObjectID
 	NewPasswordHash string
+	// Version is expected version of the user, it isn't checked if zero
+	Version int64
+}
+
+type EnableTwoFactorSchema struct {
+	UserID    primitive.ObjectID
+	TwoFactor entity.TwoFactorModel
+}
+
+type ChangeRecoveryCodesSchema struct {
+	UserID             primitive.ObjectID
+	RecoveryCodeHashes []string
+}
+
+type RemoveRecoveryCodeSchema struct {
+	UserID           primitive.ObjectID
+	RecoveryCodeHash string
+}
+
+type LinkIdentitySchema struct {
+	UserID   primitive.ObjectID
+	Identity entity.IdentityModel
+}
+
+type ListUsersSchema struct {
+	// Search matches username or email case-insensitively, all users are listed if empty
+	Search string
+	Offset int64
+	Limit  int64
+}
+
+type SetRoleSchema struct {
+	UserID primitive.ObjectID
+	Role   string
+}
+
+type SuspendUserSchema struct {
+	UserID      primitive.ObjectID
+	SuspendedAt time.Time
+	Reason      string
+}
+
+// maxPreviousUsernames bounds history of usernames kept for redirects
+const maxPreviousUsernames = 10
+
+type ChangeUsernameSchema struct {
+	UserID      primitive.ObjectID
+	NewUsername string
+	ChangedAt   time.Time
+	// Version is expected version of the user, it isn't checked if zero
+	Version int64
+}
+
+type UpdateProfileSchema struct {
+	UserID      primitive.ObjectID
+	DisplayName string
+	Bio         string
+	// Version is expected version of the user, it isn't checked if zero
+	Version int64
+}
+
+type ChangeAvatarSchema struct {
+	UserID primitive.ObjectID
+	// Avatar is a key of image in blob store, avatar is removed if empty
+	Avatar string
+	// Version is expected version of the user, it isn't checked if zero
+	Version int64
+}
+
+type ScheduleDeletionSchema struct {
+	UserID              primitive.ObjectID
+	DeletionScheduledAt time.Time
 }
 
 // UsersRepository is a store for users
 //
 //go:generate mockery --dir . --name UsersRepository --output ./mocks
 type UsersRepository interface {
+	// Create stores the user with version 1 unless version is provided. Every change increments
+	// version of the user, changes with expected version return entity.ErrUserVersionConflict
+	// if the user was changed since then
 	Create(ctx context.Context, user entity.UserModel) error
 	FindByID(ctx context.Context, userID primitive.ObjectID) (entity.UserModel, error)
 	FindByUsername(ctx context.Context, username string) (entity.UserModel, error)
 	FindByEmail(ctx context.Context, email string) (entity.UserModel, error)
 	FindByLogin(ctx context.Context, login string) (entity.UserModel, error)
+	// ChangeEmail returns entity.ErrUserAlreadyExists if another user has the same email
 	ChangeEmail(ctx context.Context, schema ChangeEmailSchema) error
 	ChangePassword(ctx context.Context, schema ChangePasswordSchema) error
+	// ChangeUsername returns entity.ErrUsernameTaken if another user has the same username,
+	// previous username is kept for redirects
+	ChangeUsername(ctx context.Context, schema ChangeUsernameSchema) error
+	// FindByPreviousUsername returns user who changed provided username after since
+	FindByPreviousUsername(ctx context.Context, username string, since time.Time) (entity.UserModel, error)
+	UpdateProfile(ctx context.Context, schema UpdateProfileSchema) error
+	ChangeAvatar(ctx context.Context, schema ChangeAvatarSchema) error
+	EnableTwoFactor(ctx context.Context, schema EnableTwoFactorSchema) error
+	DisableTwoFactor(ctx context.Context, userID primitive.ObjectID) error
+	ChangeRecoveryCodes(ctx context.Context, schema ChangeRecoveryCodesSchema) error
+	// RemoveRecoveryCode removes used recovery code in a single conditional write, so concurrent requests
+	// can't use the same code twice. entity.ErrIncorrectTwoFactorCode is returned if user has no such code
+	RemoveRecoveryCode(ctx context.Context, schema RemoveRecoveryCodeSchema) error
+	// FindByIdentity returns user linked to the account of external provider
+	FindByIdentity(ctx context.Context, provider, subject string) (entity.UserModel, error)
+	LinkIdentity(ctx context.Context, schema LinkIdentitySchema) error
+	// List returns users matching the search in order of creation together with their total number
+	List(ctx context.Context, schema ListUsersSchema) ([]entity.UserModel, int64, error)
+	SetRole(ctx context.Context, schema SetRoleSchema) error
+	Suspend(ctx context.Context, schema SuspendUserSchema) error
+	Unsuspend(ctx context.Context, userID primitive.ObjectID) error
+	ScheduleDeletion(ctx context.Context, schema ScheduleDeletionSchema) error
+	CancelDeletion(ctx context.Context, userID primitive.ObjectID) error
+	// FindScheduledForDeletion returns users whose deletion is scheduled not later than provided time
+	FindScheduledForDeletion(ctx context.Context, before time.Time) ([]entity.UserModel, error)
+	Delete(ctx context.Context, userID primitive.ObjectID) error
+}
+
+type DeleteAPIKeySchema struct {
+	UserID primitive.ObjectID
+	KeyID  primitive.ObjectID
+}
+
+type UpdateAPIKeyLastUsedSchema struct {
+	KeyID      primitive.ObjectID
+	LastUsedAt time.Time
+}
+
+// APIKeysRepository is a store for users personal access tokens
+//
+//go:generate mockery --dir . --name APIKeysRepository --output ./mocks
+type APIKeysRepository interface {
+	Create(ctx context.Context, key entity.APIKeyModel) error
+	FindByPrefix(ctx context.Context, prefix string) (entity.APIKeyModel, error)
+	FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]entity.APIKeyModel, error)
+	Delete(ctx context.Context, schema DeleteAPIKeySchema) error
+	UpdateLastUsed(ctx context.Context, schema UpdateAPIKeyLastUsedSchema) error
+	DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error
+}
+
+type FindAuditEventsSchema struct {
+	// UserID matches events performed by or on the user, events of all users are found if empty
+	UserID primitive.ObjectID
+	// Action matches events of certain action, events of all actions are found if empty
+	Action string
+	Offset int64
+	Limit  int64
+}
+
+// AuditRepository is an append-only store for audit events
+//
+//go:generate mockery --dir . --name AuditRepository --output ./mocks
+type AuditRepository interface {
+	// Create returns entity.ErrAuditEventAlreadyExists if event with the same ID is stored
+	Create(ctx context.Context, event entity.AuditEventModel) error
+	// Find returns events matching the query from newest to oldest together with their total number
+	Find(ctx context.Context, schema FindAuditEventsSchema) ([]entity.AuditEventModel, int64, error)
+	// Anonymize removes client information and details from events performed by or on the user,
+	// so only pseudonymous trail of actions is kept
+	Anonymize(ctx context.Context, userID primitive.ObjectID) error
+}
+
+type AuditConfig struct {
+	// Retention is a period after which events are removed, supported only by mongodb
+	Retention time.Duration `mapstructure:"retention"`
+	// ChainKey is a secret of HMAC, which chains events of filedb audit log, so the log can't be
+	// rewritten without it. Events are chained with plain SHA-256 if empty, which detects only
+	// accidental corruption. Log written with one key can't be read with another
+	ChainKey string `mapstructure:"chainKey"`
 }
 
 type Config struct {
-	Use     string        `mapstructure:"use"`
-	MongoDB MongoDBConfig `mapstructure:"mongodb"`
-	FileDB  FileDBConfig  `mapstructure:"filedb"`
+	// Use is a database backend: "mongodb", "postgres", "sqlite", "filedb" or "memory".
+	// Data of "memory" backend is lost on shutdown, it's meant for tests and demos
+	Use      string          `mapstructure:"use"`
+	MongoDB  MongoDBConfig   `mapstructure:"mongodb"`
+	Postgres postgres.Config `mapstructure:"postgres"`
+	SQLite   SQLiteConfig    `mapstructure:"sqlite"`
+	FileDB   FileDBConfig    `mapstructure:"filedb"`
+	Audit    AuditConfig     `mapstructure:"audit"`
+	// SkipMigrations disables migrations on startup, so they're run only with migrate command
+	SkipMigrations bool `mapstructure:"skipMigrations"`
 }
 
 // Repositories -.
 type Repositories struct {
-	Users UsersRepository
-	close func(ctx context.Context) error
+	Users   UsersRepository
+	APIKeys APIKeysRepository
+	Audit   AuditRepository
+	close   func(ctx context.Context) error
 }
 
 func New(ctx context.Context, cfg Config) (*Repositories, error) {
@@ -56,16 +224,28 @@ func New(ctx context.Context, cfg Config) (*Repositories, error) {
 		return nil, err
 	}
 
+	if !cfg.SkipMigrations {
+		if _, err = migrate(ctx, db, MigrateOptions{Direction: MigrateUp}); err != nil {
+			_ = db.close(ctx)
+			return nil, errors.Wrap(err, "failed to migrate database")
+		}
+	}
+
 	r := &Repositories{
-		Users: db.getUsersRepository(),
-		close: db.close,
+		Users:   db.getUsersRepository(),
+		APIKeys: db.getAPIKeysRepository(),
+		Audit:   db.getAuditRepository(),
+		close:   db.close,
 	}
 
 	return r, nil
 }
 
 type database interface {
+	migrationsRunner
 	getUsersRepository() UsersRepository
+	getAPIKeysRepository() APIKeysRepository
+	getAuditRepository() AuditRepository
 	close(ctx context.Context) error
 }
 
@@ -76,9 +256,15 @@ type databaseMaker interface {
 func createDatabaseFactory(cfg Config) (databaseMaker, error) {
 	switch cfg.Use {
 	case "mongodb":
-		return &mongoDBMaker{config: cfg.MongoDB}, nil
+		return &mongoDBMaker{config: cfg.MongoDB, audit: cfg.Audit}, nil
+	case "postgres":
+		return &postgresMaker{config: cfg.Postgres}, nil
+	case "sqlite":
+		return &sqliteMaker{config: cfg.SQLite}, nil
 	case "filedb":
-		return &fileDBMaker{config: cfg.FileDB}, nil
+		return &fileDBMaker{config: cfg.FileDB, audit: cfg.Audit}, nil
+	case "memory":
+		return &memoryMaker{}, nil
 	default:
 		return nil, fmt.Errorf("unknown database %q", cfg.Use)
 	}
@@ -86,6 +272,7 @@ func createDatabaseFactory(cfg Config) (databaseMaker, error) {
 
 type mongoDBMaker struct {
 	config MongoDBConfig
+	audit  AuditConfig
 }
 
 func (m *mongoDBMaker) make(ctx context.Context) (database, error) {
@@ -94,16 +281,83 @@ func (m *mongoDBMaker) make(ctx context.Context) (database, error) {
 		return nil, errors.Wrapf(err, "failed to create mongodb")
 	}
 
-	err = db.createUsersRepository(ctx)
+	err = db.createUsersRepository()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create users repository")
+	}
+
+	err = db.createAPIKeysRepository()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create api keys repository")
+	}
+
+	err = db.createAuditRepository(ctx, m.audit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create audit repository")
+	}
+
+	return db, nil
+}
+
+type postgresMaker struct {
+	config postgres.Config
+}
+
+func (m *postgresMaker) make(ctx context.Context) (database, error) {
+	db, err := newPostgresDB(ctx, m.config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create postgres")
+	}
+
+	err = db.createUsersRepository()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create users repository")
 	}
 
+	err = db.createAPIKeysRepository()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create api keys repository")
+	}
+
+	err = db.createAuditRepository()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create audit repository")
+	}
+
+	return db, nil
+}
+
+type sqliteMaker struct {
+	config SQLiteConfig
+}
+
+func (m *sqliteMaker) make(ctx context.Context) (database, error) {
+	db, err := newSQLiteDB(ctx, m.config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create sqlite")
+	}
+
+	err = db.createUsersRepository()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create users repository")
+	}
+
+	err = db.createAPIKeysRepository()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create api keys repository")
+	}
+
+	err = db.createAuditRepository()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create audit repository")
+	}
+
 	return db, nil
 }
 
 type fileDBMaker struct {
 	config FileDBConfig
+	audit  AuditConfig
 }
 
 func (m *fileDBMaker) make(_ context.Context) (database, error) {
@@ -117,6 +371,39 @@ func (m *fileDBMaker) make(_ context.Context) (database, error) {
 		return nil, errors.Wrap(err, "failed to create users repository")
 	}
 
+	err = db.createAPIKeysRepository()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create api keys repository")
+	}
+
+	err = db.createAuditRepository(m.audit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create audit repository")
+	}
+
+	return db, nil
+}
+
+type memoryMaker struct{}
+
+func (m *memoryMaker) make(_ context.Context) (database, error) {
+	db := newMemoryDB()
+
+	err := db.createUsersRepository()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create users repository")
+	}
+
+	err = db.createAPIKeysRepository()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create api keys repository")
+	}
+
+	err = db.createAuditRepository(AuditConfig{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create audit repository")
+	}
+
 	return db, nil
 }
 
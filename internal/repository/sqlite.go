@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	// modernc.org/sqlite is a CGO-free translation of SQLite, it registers "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+const (
+	sqliteDriver             = "sqlite"
+	sqliteMemoryPath         = ":memory:"
+	defaultSQLiteBusyTimeout = 5 * time.Second
+	// sqliteTimeLayout has fixed width, so stored dates are ordered and compared as strings
+	sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+)
+
+// SQLite extended result codes, see https://www.sqlite.org/rescode.html
+const (
+	sqliteConstraintForeignKey = 787
+	sqliteConstraintPrimaryKey = 1555
+	sqliteConstraintUnique     = 2067
+)
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrations embed.FS
+
+type SQLiteConfig struct {
+	// Path is a database file, database is kept in memory if it's ":memory:"
+	Path string `mapstructure:"path"`
+	// BusyTimeout is a duration for which statement waits until database locked by another writer is released
+	BusyTimeout time.Duration `mapstructure:"busyTimeout"`
+}
+
+type sqliteDB struct {
+	db      *sql.DB
+	users   UsersRepository
+	apiKeys APIKeysRepository
+	audit   AuditRepository
+}
+
+func newSQLiteDB(ctx context.Context, cfg SQLiteConfig) (*sqliteDB, error) {
+	path := cfg.Path
+	if path == "" {
+		path = filepath.Join("bin", "url-shrtnr.db")
+	}
+
+	if path != sqliteMemoryPath {
+		err := os.MkdirAll(filepath.Dir(path), 0o700)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create %q director(y/ies)", filepath.Dir(path))
+		}
+	}
+
+	busyTimeout := lo.Ternary(cfg.BusyTimeout > 0, cfg.BusyTimeout, defaultSQLiteBusyTimeout)
+
+	// pragmas are applied to every connection of the pool
+	pragmas := url.Values{"_pragma": {
+		fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()),
+		"journal_mode(WAL)",
+		"synchronous(NORMAL)",
+		"foreign_keys(1)",
+	}}
+
+	db, err := sql.Open(sqliteDriver, path+"?"+pragmas.Encode())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %q database", path)
+	}
+
+	if path == sqliteMemoryPath {
+		// every connection to in-memory database opens its own empty database
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxIdleTime(0)
+		db.SetConnMaxLifetime(0)
+	}
+
+	s := &sqliteDB{
+		db: db,
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrapf(err, "failed to open %q database", path)
+	}
+
+	return s, nil
+}
+
+func (s *sqliteDB) close(_ context.Context) error {
+	return s.db.Close()
+}
+
+// sqliteMigrationsList returns embedded SQL migrations followed by migrations written in Go
+func sqliteMigrationsList() ([]migration[*sql.Conn], error) {
+	migrations, err := readMigrations(sqliteMigrations, "migrations/sqlite")
+	if err != nil {
+		return nil, err
+	}
+
+	return execMigrations(migrations, func(ctx context.Context, conn *sql.Conn, script string) error {
+		_, err := conn.ExecContext(ctx, script)
+		return err
+	}), nil
+}
+
+// withMigrations runs migrations in a single transaction, which takes write lock before applied migrations
+// are read, so instances started at the same time don't apply them concurrently
+func (s *sqliteDB) withMigrations(ctx context.Context, fn func(ctx context.Context, journal migrationsJournal, steps []migrationStep) error) error {
+	migrations, err := sqliteMigrationsList()
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return errors.Wrap(err, "failed to acquire migrations lock")
+	}
+
+	err = func() error {
+		_, err := conn.ExecContext(ctx, `
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version    INTEGER PRIMARY KEY,
+				applied_at TEXT NOT NULL
+			)`)
+		if err != nil {
+			return errors.Wrap(err, "failed to create migrations table")
+		}
+
+		return fn(ctx, sqliteMigrationsJournal{conn: conn}, bindMigrations(migrations, conn))
+	}()
+	if err != nil {
+		_, _ = conn.ExecContext(context.Background(), `ROLLBACK`)
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, `COMMIT`)
+
+	return err
+}
+
+type sqliteMigrationsJournal struct {
+	conn *sql.Conn
+}
+
+func (j sqliteMigrationsJournal) applied(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := j.conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+
+		if err = rows.Scan(&version, sqliteTime{dst: &appliedAt}); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+func (j sqliteMigrationsJournal) record(ctx context.Context, version int64, appliedAt time.Time) error {
+	_, err := j.conn.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?1, ?2)`,
+		version, sqliteTimeValue(appliedAt))
+
+	return err
+}
+
+func (j sqliteMigrationsJournal) forget(ctx context.Context, version int64) error {
+	_, err := j.conn.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?1`, version)
+	return err
+}
+
+// withTx runs fn in transaction, which is committed if fn succeeds
+func (s *sqliteDB) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// sqliteErrorCode returns extended result code of the driver error or zero for other errors
+func sqliteErrorCode(err error) int {
+	var coded interface{ Code() int }
+	if errors.As(err, &coded) {
+		return coded.Code()
+	}
+
+	return 0
+}
+
+// isSQLiteUniqueViolation is the sqlite counterpart of mongo.IsDuplicateKeyError
+func isSQLiteUniqueViolation(err error) bool {
+	code := sqliteErrorCode(err)
+	return code == sqliteConstraintUnique || code == sqliteConstraintPrimaryKey
+}
+
+func isSQLiteForeignKeyViolation(err error) bool {
+	return sqliteErrorCode(err) == sqliteConstraintForeignKey
+}
+
+// affectedOrNotFound returns notFound if statement didn't change any row
+func affectedOrNotFound(result sql.Result, err, notFound error) error {
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if affected == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+func sqliteTimeValue(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// sqliteNullableTime returns nil for nil time, so it's stored as NULL
+func sqliteNullableTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+
+	value := sqliteTimeValue(*t)
+
+	return &value
+}
+
+// sqliteTime scans stored date into time
+type sqliteTime struct {
+	dst *time.Time
+}
+
+func (s sqliteTime) Scan(src any) error {
+	var value string
+
+	switch v := src.(type) {
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported date type %T", src)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return errors.Wrapf(err, "invalid date %q", value)
+	}
+
+	*s.dst = t
+
+	return nil
+}
+
+// sqliteNullTime scans stored date into time, which is nil for NULL
+type sqliteNullTime struct {
+	dst **time.Time
+}
+
+func (s sqliteNullTime) Scan(src any) error {
+	if src == nil {
+		*s.dst = nil
+		return nil
+	}
+
+	var t time.Time
+
+	if err := (sqliteTime{dst: &t}).Scan(src); err != nil {
+		return err
+	}
+
+	*s.dst = &t
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+const sqliteAPIKeyColumns = `id, user_id, name, prefix, secret_hash, scopes, expires_at, last_used_at, created_at`
+
+type sqliteAPIKeysRepository struct {
+	db *sqliteDB
+}
+
+func (s *sqliteDB) createAPIKeysRepository() error {
+	s.apiKeys = &sqliteAPIKeysRepository{
+		db: s,
+	}
+
+	return nil
+}
+
+func (s *sqliteDB) getAPIKeysRepository() APIKeysRepository {
+	return s.apiKeys
+}
+
+func (r *sqliteAPIKeysRepository) Create(ctx context.Context, key entity.APIKeyModel) error {
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+
+	scopes, err := json.Marshal(lo.Ternary(key.Scopes == nil, []string{}, key.Scopes))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode scopes")
+	}
+
+	_, err = r.db.db.ExecContext(ctx, `
+		INSERT INTO api_keys (`+sqliteAPIKeyColumns+`) VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9)`,
+		key.ID.Hex(), key.UserID.Hex(), key.Name, key.Prefix, key.SecretHash, string(scopes),
+		sqliteNullableTime(key.ExpiresAt), sqliteNullableTime(key.LastUsedAt), sqliteTimeValue(key.CreatedAt),
+	)
+
+	return err
+}
+
+func (r *sqliteAPIKeysRepository) FindByPrefix(ctx context.Context, prefix string) (entity.APIKeyModel, error) {
+	keys, err := r.find(ctx, `prefix = ?1`, prefix)
+	if err != nil {
+		return entity.APIKeyModel{}, err
+	} else if len(keys) == 0 {
+		return entity.APIKeyModel{}, entity.ErrAPIKeyNotFound
+	}
+
+	return keys[0], nil
+}
+
+func (r *sqliteAPIKeysRepository) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]entity.APIKeyModel, error) {
+	return r.find(ctx, `user_id = ?1 ORDER BY created_at, id`, userID.Hex())
+}
+
+func (r *sqliteAPIKeysRepository) Delete(ctx context.Context, schema DeleteAPIKeySchema) error {
+	result, err := r.db.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ?1 AND user_id = ?2`, schema.KeyID.Hex(), schema.UserID.Hex())
+	return affectedOrNotFound(result, err, entity.ErrAPIKeyNotFound)
+}
+
+func (r *sqliteAPIKeysRepository) UpdateLastUsed(ctx context.Context, schema UpdateAPIKeyLastUsedSchema) error {
+	result, err := r.db.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ?2 WHERE id = ?1`,
+		schema.KeyID.Hex(), sqliteTimeValue(schema.LastUsedAt))
+	return affectedOrNotFound(result, err, entity.ErrAPIKeyNotFound)
+}
+
+func (r *sqliteAPIKeysRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.db.db.ExecContext(ctx, `DELETE FROM api_keys WHERE user_id = ?1`, userID.Hex())
+	return err
+}
+
+// find returns keys, which match condition, condition may be followed by ordering
+func (r *sqliteAPIKeysRepository) find(ctx context.Context, condition string, args ...any) ([]entity.APIKeyModel, error) {
+	rows, err := r.db.db.QueryContext(ctx, `SELECT `+sqliteAPIKeyColumns+` FROM api_keys WHERE `+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []entity.APIKeyModel{}
+
+	for rows.Next() {
+		key, err := scanSQLiteAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func scanSQLiteAPIKey(rows *sql.Rows) (entity.APIKeyModel, error) {
+	var (
+		key                entity.APIKeyModel
+		id, userID, scopes string
+	)
+
+	err := rows.Scan(&id, &userID, &key.Name, &key.Prefix, &key.SecretHash, &scopes,
+		sqliteNullTime{dst: &key.ExpiresAt}, sqliteNullTime{dst: &key.LastUsedAt}, sqliteTime{dst: &key.CreatedAt})
+	if err != nil {
+		return entity.APIKeyModel{}, err
+	}
+
+	if key.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return entity.APIKeyModel{}, errors.Wrapf(err, "invalid api key ID %q", id)
+	}
+
+	if key.UserID, err = primitive.ObjectIDFromHex(userID); err != nil {
+		return entity.APIKeyModel{}, errors.Wrapf(err, "invalid user ID %q", userID)
+	}
+
+	if err = json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return entity.APIKeyModel{}, errors.Wrap(err, "failed to decode scopes")
+	}
+
+	return key, nil
+}
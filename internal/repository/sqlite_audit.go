@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+const sqliteAuditEventColumns = `id, action, outcome, actor_id, target_user_id, ip, user_agent, request_id, details, created_at`
+
+type sqliteAuditRepository struct {
+	db *sqliteDB
+}
+
+func (s *sqliteDB) createAuditRepository() error {
+	s.audit = &sqliteAuditRepository{
+		db: s,
+	}
+
+	return nil
+}
+
+func (s *sqliteDB) getAuditRepository() AuditRepository {
+	return s.audit
+}
+
+func (r *sqliteAuditRepository) Create(ctx context.Context, event entity.AuditEventModel) error {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+
+	var details *string
+
+	if event.Details != nil {
+		encoded, err := json.Marshal(event.Details)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode details")
+		}
+
+		details = lo.ToPtr(string(encoded))
+	}
+
+	_, err := r.db.db.ExecContext(ctx, `
+		INSERT INTO audit_events (`+sqliteAuditEventColumns+`) VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)`,
+		event.ID.Hex(), event.Action, event.Outcome, nullableID(event.ActorID), nullableID(event.TargetUserID),
+		event.IP, event.UserAgent, event.RequestID, details, sqliteTimeValue(event.CreatedAt),
+	)
+	if isSQLiteUniqueViolation(err) {
+		return entity.ErrAuditEventAlreadyExists
+	}
+
+	return err
+}
+
+func (r *sqliteAuditRepository) Find(ctx context.Context, schema FindAuditEventsSchema) ([]entity.AuditEventModel, int64, error) {
+	const filter = `(?1 IS NULL OR actor_id = ?1 OR target_user_id = ?1) AND (?2 = '' OR action = ?2)`
+
+	userID := nullableID(schema.UserID)
+
+	var total int64
+
+	err := r.db.db.QueryRowContext(ctx, `SELECT count(*) FROM audit_events WHERE `+filter, userID, schema.Action).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// negative limit means there is no limit
+	rows, err := r.db.db.QueryContext(ctx, `
+		SELECT `+sqliteAuditEventColumns+` FROM audit_events WHERE `+filter+`
+		ORDER BY created_at DESC, id DESC LIMIT CASE WHEN ?4 > 0 THEN ?4 ELSE -1 END OFFSET ?3`,
+		userID, schema.Action, schema.Offset, schema.Limit,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []entity.AuditEventModel{}
+
+	for rows.Next() {
+		event, err := scanSQLiteAuditEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+func (r *sqliteAuditRepository) Anonymize(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.db.db.ExecContext(ctx, `
+		UPDATE audit_events SET ip = '', user_agent = '', details = NULL
+		WHERE actor_id = ?1 OR target_user_id = ?1`,
+		userID.Hex(),
+	)
+
+	return err
+}
+
+func scanSQLiteAuditEvent(rows *sql.Rows) (entity.AuditEventModel, error) {
+	var (
+		event                          entity.AuditEventModel
+		id                             string
+		actorID, targetUserID, details *string
+	)
+
+	err := rows.Scan(&id, &event.Action, &event.Outcome, &actorID, &targetUserID,
+		&event.IP, &event.UserAgent, &event.RequestID, &details, sqliteTime{dst: &event.CreatedAt})
+	if err != nil {
+		return entity.AuditEventModel{}, err
+	}
+
+	if event.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return entity.AuditEventModel{}, errors.Wrapf(err, "invalid event ID %q", id)
+	}
+
+	if event.ActorID, err = parseID(actorID); err != nil {
+		return entity.AuditEventModel{}, errors.Wrap(err, "invalid actor ID")
+	}
+
+	if event.TargetUserID, err = parseID(targetUserID); err != nil {
+		return entity.AuditEventModel{}, errors.Wrap(err, "invalid target user ID")
+	}
+
+	if details != nil {
+		if err = json.Unmarshal([]byte(*details), &event.Details); err != nil {
+			return entity.AuditEventModel{}, errors.Wrap(err, "failed to decode details")
+		}
+	}
+
+	return event, nil
+}
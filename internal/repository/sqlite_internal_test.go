@@ -0,0 +1,218 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+// newTestSQLiteDB opens database with all repositories
+func newTestSQLiteDB(t *testing.T, cfg SQLiteConfig) *sqliteDB {
+	t.Helper()
+
+	ctx := context.Background()
+
+	db, err := newSQLiteDB(ctx, cfg)
+	require.NoErrorf(t, err, "failed to create sqlite: %s", err)
+	t.Cleanup(func() { _ = db.close(ctx) })
+
+	_, err = migrate(ctx, db, MigrateOptions{Direction: MigrateUp})
+	require.NoErrorf(t, err, "failed to migrate sqlite: %s", err)
+
+	require.NoError(t, db.createUsersRepository())
+	require.NoError(t, db.createAPIKeysRepository())
+	require.NoError(t, db.createAuditRepository())
+
+	return db
+}
+
+func TestSQLiteDB_Migrate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := SQLiteConfig{Path: filepath.Join(t.TempDir(), "db.sqlite")}
+
+	db := newTestSQLiteDB(t, cfg)
+	require.NoError(t, db.users.Create(ctx, entity.UserModel{Username: "user", Email: "user@example.com"}))
+	require.NoError(t, db.close(ctx))
+
+	reopened := newTestSQLiteDB(t, cfg)
+
+	migrations, err := sqliteMigrationsList()
+	require.NoError(t, err)
+
+	var applied int
+	require.NoError(t, reopened.db.QueryRowContext(ctx, `SELECT count(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, len(migrations), applied, "applied migrations must be skipped")
+
+	var journalMode string
+	require.NoError(t, reopened.db.QueryRowContext(ctx, `PRAGMA journal_mode`).Scan(&journalMode))
+	assert.Equal(t, "wal", journalMode)
+
+	_, err = reopened.users.FindByUsername(ctx, "user")
+	assert.NoError(t, err)
+}
+
+func TestSQLiteUsersRepository(t *testing.T) {
+	testCases := []struct {
+		name string
+		path func(t *testing.T) string
+	}{
+		{
+			name: "file",
+			path: func(t *testing.T) string { return filepath.Join(t.TempDir(), "db.sqlite") },
+		},
+		{
+			name: "memory",
+			path: func(t *testing.T) string { return sqliteMemoryPath },
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestSQLiteDB(t, SQLiteConfig{Path: tc.path(t)})
+			ctx := context.Background()
+			now := time.Now().UTC()
+
+			user := entity.UserModel{
+				ID:        primitive.NewObjectID(),
+				Username:  "user",
+				Email:     "user@example.com",
+				CreatedAt: now,
+				UpdatedAt: now,
+				Version:   1,
+				Identities: []entity.IdentityModel{
+					{Provider: "keycloak", Subject: "<subject>", LinkedAt: now},
+				},
+			}
+
+			require.NoError(t, db.users.Create(ctx, user))
+
+			found, err := db.users.FindByIdentity(ctx, "keycloak", "<subject>")
+			require.NoError(t, err)
+			assert.Equal(t, user, found)
+
+			err = db.users.Create(ctx, entity.UserModel{Username: "other", Email: "USER@example.com", CreatedAt: now, UpdatedAt: now})
+			assert.ErrorIs(t, err, entity.ErrUserAlreadyExists)
+
+			other := entity.UserModel{ID: primitive.NewObjectID(), Username: "other", Email: "other@example.com", CreatedAt: now, UpdatedAt: now}
+			require.NoError(t, db.users.Create(ctx, other))
+
+			err = db.users.ChangeEmail(ctx, ChangeEmailSchema{UserID: other.ID, NewEmail: user.Email})
+			assert.ErrorIs(t, err, entity.ErrUserAlreadyExists)
+
+			err = db.users.ChangeUsername(ctx, ChangeUsernameSchema{UserID: other.ID, NewUsername: user.Username, ChangedAt: now})
+			assert.ErrorIs(t, err, entity.ErrUsernameTaken)
+
+			err = db.users.LinkIdentity(ctx, LinkIdentitySchema{UserID: other.ID, Identity: user.Identities[0]})
+			assert.ErrorIs(t, err, entity.ErrOIDCIdentityAlreadyLinked)
+
+			err = db.users.LinkIdentity(ctx, LinkIdentitySchema{UserID: primitive.NewObjectID(), Identity: entity.IdentityModel{Provider: "keycloak", Subject: "<other>"}})
+			assert.ErrorIs(t, err, entity.ErrUserNotFound)
+
+			for i := 0; i < maxPreviousUsernames+2; i++ {
+				err = db.users.ChangeUsername(ctx, ChangeUsernameSchema{UserID: other.ID, NewUsername: fmt.Sprintf("other%d", i), ChangedAt: now})
+				require.NoError(t, err)
+			}
+
+			found, err = db.users.FindByID(ctx, other.ID)
+			require.NoError(t, err)
+			assert.Len(t, found.PreviousUsernames, maxPreviousUsernames)
+			assert.Equal(t, fmt.Sprintf("other%d", maxPreviousUsernames), found.PreviousUsernames[maxPreviousUsernames-1].Username)
+
+			found, err = db.users.FindByPreviousUsername(ctx, "other1", now.Add(-time.Minute))
+			require.NoError(t, err)
+			assert.Equal(t, other.ID, found.ID)
+
+			_, err = db.users.FindByPreviousUsername(ctx, "other1", now)
+			assert.ErrorIs(t, err, entity.ErrUserNotFound)
+
+			twoFactor := entity.TwoFactorModel{TOTPSecret: "<secret>", RecoveryCodeHashes: []string{"<first>", "<second>"}, EnabledAt: now}
+			require.NoError(t, db.users.EnableTwoFactor(ctx, EnableTwoFactorSchema{UserID: user.ID, TwoFactor: twoFactor}))
+			require.NoError(t, db.users.ChangeRecoveryCodes(ctx, ChangeRecoveryCodesSchema{UserID: user.ID, RecoveryCodeHashes: []string{"<second>"}}))
+
+			found, err = db.users.FindByLogin(ctx, "USER@example.com")
+			require.NoError(t, err)
+			require.NotNil(t, found.TwoFactor)
+			assert.Equal(t, []string{"<second>"}, found.TwoFactor.RecoveryCodeHashes)
+
+			err = db.users.ChangeRecoveryCodes(ctx, ChangeRecoveryCodesSchema{UserID: other.ID})
+			assert.ErrorIs(t, err, entity.ErrUserNotFound, "recovery codes can't be changed without two factor")
+
+			users, total, err := db.users.List(ctx, ListUsersSchema{Search: "OTHER", Limit: 10})
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), total)
+			assert.Len(t, users, 1)
+
+			users, total, err = db.users.List(ctx, ListUsersSchema{Search: "_", Offset: 1})
+			require.NoError(t, err)
+			assert.Zero(t, total, "wildcards must be escaped")
+			assert.Empty(t, users)
+
+			require.NoError(t, db.users.ScheduleDeletion(ctx, ScheduleDeletionSchema{UserID: other.ID, DeletionScheduledAt: now}))
+
+			users, err = db.users.FindScheduledForDeletion(ctx, now)
+			require.NoError(t, err)
+			assert.Len(t, users, 1)
+
+			require.NoError(t, db.users.Delete(ctx, user.ID))
+
+			_, err = db.users.FindByIdentity(ctx, "keycloak", "<subject>")
+			assert.ErrorIs(t, err, entity.ErrUserNotFound, "identities must be deleted together with user")
+
+			assert.ErrorIs(t, db.users.Delete(ctx, user.ID), entity.ErrUserNotFound)
+			assert.ErrorIs(t, db.users.Unsuspend(ctx, user.ID), entity.ErrUserNotFound)
+		})
+	}
+}
+
+func TestSQLiteAPIKeysAndAuditRepositories(t *testing.T) {
+	t.Parallel()
+
+	db := newTestSQLiteDB(t, SQLiteConfig{Path: sqliteMemoryPath})
+	ctx := context.Background()
+	now := time.Now().UTC()
+	userID := primitive.NewObjectID()
+
+	key := entity.APIKeyModel{ID: primitive.NewObjectID(), UserID: userID, Name: "ci", Prefix: "shrtnr_1", Scopes: []string{entity.ScopeAdmin}, CreatedAt: now}
+	require.NoError(t, db.apiKeys.Create(ctx, key))
+
+	found, err := db.apiKeys.FindByPrefix(ctx, key.Prefix)
+	require.NoError(t, err)
+	assert.Equal(t, key, found)
+
+	require.NoError(t, db.apiKeys.UpdateLastUsed(ctx, UpdateAPIKeyLastUsedSchema{KeyID: key.ID, LastUsedAt: now}))
+	assert.ErrorIs(t, db.apiKeys.Delete(ctx, DeleteAPIKeySchema{UserID: primitive.NewObjectID(), KeyID: key.ID}), entity.ErrAPIKeyNotFound)
+	require.NoError(t, db.apiKeys.DeleteByUserID(ctx, userID))
+
+	_, err = db.apiKeys.FindByPrefix(ctx, key.Prefix)
+	assert.ErrorIs(t, err, entity.ErrAPIKeyNotFound)
+
+	for i, action := range []string{"sign-in", "sign-out", "sign-in"} {
+		event := entity.AuditEventModel{Action: action, ActorID: userID, IP: "127.0.0.1", CreatedAt: now.Add(time.Duration(i) * time.Second)}
+		require.NoError(t, db.audit.Create(ctx, event))
+	}
+
+	events, total, err := db.audit.Find(ctx, FindAuditEventsSchema{UserID: userID, Action: "sign-in", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, events, 1)
+	assert.True(t, now.Add(2*time.Second).Equal(events[0].CreatedAt), "newest event must be the first")
+
+	require.NoError(t, db.audit.Anonymize(ctx, userID))
+
+	events, _, err = db.audit.Find(ctx, FindAuditEventsSchema{})
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+	assert.Empty(t, events[0].IP)
+}
@@ -0,0 +1,422 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+// sqliteUserColumns are selected from users table aliased as u, identities are aggregated into JSON array
+const sqliteUserColumns = `
+	u.id, u.username, u.email, u.password_hash, u.role, u.created_at, u.updated_at,
+	u.suspended_at, u.suspension_reason, u.two_factor, u.deletion_scheduled_at,
+	u.display_name, u.bio, u.avatar, u.username_changed_at, u.previous_usernames, u.version,
+	(
+		SELECT json_group_array(json_object('provider', i.provider, 'subject', i.subject, 'linkedAt', i.linked_at))
+		FROM (SELECT * FROM user_identities WHERE user_id = u.id ORDER BY linked_at) i
+	)`
+
+// sqliteSearchEscaper escapes wildcards of LIKE patterns
+var sqliteSearchEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// sqlitePreviousUsername is stored form of entity.PreviousUsernameModel,
+// its date is compared as string by FindByPreviousUsername
+type sqlitePreviousUsername struct {
+	Username  string `json:"username"`
+	ChangedAt string `json:"changedAt"`
+}
+
+type sqliteUsersRepository struct {
+	db *sqliteDB
+}
+
+func (s *sqliteDB) createUsersRepository() error {
+	s.users = &sqliteUsersRepository{
+		db: s,
+	}
+
+	return nil
+}
+
+func (s *sqliteDB) getUsersRepository() UsersRepository {
+	return s.users
+}
+
+func (r *sqliteUsersRepository) Create(ctx context.Context, user entity.UserModel) error {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+
+	if user.Version == 0 {
+		user.Version = 1
+	}
+
+	var twoFactor *string
+
+	if user.TwoFactor != nil {
+		encoded, err := json.Marshal(user.TwoFactor)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode two factor")
+		}
+
+		twoFactor = lo.ToPtr(string(encoded))
+	}
+
+	previousUsernames, err := json.Marshal(lo.Map(user.PreviousUsernames, func(p entity.PreviousUsernameModel, _ int) sqlitePreviousUsername {
+		return sqlitePreviousUsername{Username: p.Username, ChangedAt: sqliteTimeValue(p.ChangedAt)}
+	}))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode previous usernames")
+	}
+
+	err = r.db.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO users (
+				id, username, email, password_hash, role, created_at, updated_at,
+				suspended_at, suspension_reason, two_factor, deletion_scheduled_at,
+				display_name, bio, avatar, username_changed_at, previous_usernames, version
+			) VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15, ?16, ?17)`,
+			user.ID.Hex(), user.Username, user.Email, user.PasswordHash, user.Role,
+			sqliteTimeValue(user.CreatedAt), sqliteTimeValue(user.UpdatedAt),
+			sqliteNullableTime(user.SuspendedAt), user.SuspensionReason, twoFactor, sqliteNullableTime(user.DeletionScheduledAt),
+			user.DisplayName, user.Bio, user.Avatar, sqliteNullableTime(user.UsernameChangedAt), string(previousUsernames), user.Version,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, identity := range user.Identities {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO user_identities (provider, subject, user_id, linked_at) VALUES (?1, ?2, ?3, ?4)`,
+				identity.Provider, identity.Subject, user.ID.Hex(), sqliteTimeValue(identity.LinkedAt),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if isSQLiteUniqueViolation(err) {
+		return entity.ErrUserAlreadyExists
+	}
+
+	return err
+}
+
+func (r *sqliteUsersRepository) FindByID(ctx context.Context, userID primitive.ObjectID) (entity.UserModel, error) {
+	return r.findOne(ctx, `u.id = ?1`, userID.Hex())
+}
+
+func (r *sqliteUsersRepository) FindByUsername(ctx context.Context, username string) (entity.UserModel, error) {
+	return r.findOne(ctx, `u.username = ?1`, username)
+}
+
+func (r *sqliteUsersRepository) FindByEmail(ctx context.Context, email string) (entity.UserModel, error) {
+	return r.findOne(ctx, `lower(u.email) = lower(?1)`, email)
+}
+
+func (r *sqliteUsersRepository) FindByLogin(ctx context.Context, login string) (entity.UserModel, error) {
+	return r.findOne(ctx, `u.username = ?1 OR lower(u.email) = lower(?1)`, login)
+}
+
+func (r *sqliteUsersRepository) ChangeEmail(ctx context.Context, schema ChangeEmailSchema) error {
+	err := r.update(ctx, schema.UserID, schema.Version, `email = ?2`, schema.NewEmail)
+	if isSQLiteUniqueViolation(err) {
+		return entity.ErrUserAlreadyExists
+	}
+
+	return err
+}
+
+func (r *sqliteUsersRepository) ChangePassword(ctx context.Context, schema ChangePasswordSchema) error {
+	return r.update(ctx, schema.UserID, schema.Version, `password_hash = ?2`, schema.NewPasswordHash)
+}
+
+func (r *sqliteUsersRepository) ChangeUsername(ctx context.Context, schema ChangeUsernameSchema) error {
+	// all expressions of SET see the row before the update, so "username" is the previous username
+	err := r.update(ctx, schema.UserID, schema.Version, `
+		previous_usernames = (
+			SELECT json_group_array(json(p.value))
+			FROM (
+				SELECT key, value
+				FROM (
+					SELECT key, value
+					FROM json_each(json_insert(previous_usernames, '$[#]', json_object('username', username, 'changedAt', ?3)))
+					ORDER BY key DESC
+					LIMIT ?4
+				)
+				ORDER BY key
+			) p
+		),
+		username = ?2,
+		username_changed_at = ?3`,
+		schema.NewUsername, sqliteTimeValue(schema.ChangedAt), maxPreviousUsernames,
+	)
+	if isSQLiteUniqueViolation(err) {
+		return entity.ErrUsernameTaken
+	}
+
+	return err
+}
+
+func (r *sqliteUsersRepository) FindByPreviousUsername(ctx context.Context, username string, since time.Time) (entity.UserModel, error) {
+	return r.findOne(ctx, `
+		EXISTS (
+			SELECT 1
+			FROM json_each(u.previous_usernames) p
+			WHERE json_extract(p.value, '$.username') = ?1 AND json_extract(p.value, '$.changedAt') > ?2
+		)`,
+		username, sqliteTimeValue(since),
+	)
+}
+
+func (r *sqliteUsersRepository) UpdateProfile(ctx context.Context, schema UpdateProfileSchema) error {
+	return r.update(ctx, schema.UserID, schema.Version, `display_name = ?2, bio = ?3`, schema.DisplayName, schema.Bio)
+}
+
+func (r *sqliteUsersRepository) ChangeAvatar(ctx context.Context, schema ChangeAvatarSchema) error {
+	return r.update(ctx, schema.UserID, schema.Version, `avatar = ?2`, schema.Avatar)
+}
+
+func (r *sqliteUsersRepository) EnableTwoFactor(ctx context.Context, schema EnableTwoFactorSchema) error {
+	twoFactor, err := json.Marshal(schema.TwoFactor)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode two factor")
+	}
+
+	return r.update(ctx, schema.UserID, 0, `two_factor = ?2`, string(twoFactor))
+}
+
+func (r *sqliteUsersRepository) DisableTwoFactor(ctx context.Context, userID primitive.ObjectID) error {
+	return r.update(ctx, userID, 0, `two_factor = NULL`)
+}
+
+func (r *sqliteUsersRepository) ChangeRecoveryCodes(ctx context.Context, schema ChangeRecoveryCodesSchema) error {
+	hashes, err := json.Marshal(lo.Ternary(schema.RecoveryCodeHashes == nil, []string{}, schema.RecoveryCodeHashes))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode recovery code hashes")
+	}
+
+	result, err := r.db.db.ExecContext(ctx, `
+		UPDATE users SET two_factor = json_set(two_factor, '$.recoveryCodeHashes', json(?2)), version = version + 1
+		WHERE id = ?1 AND two_factor IS NOT NULL`,
+		schema.UserID.Hex(), string(hashes),
+	)
+
+	return affectedOrNotFound(result, err, entity.ErrUserNotFound)
+}
+
+func (r *sqliteUsersRepository) RemoveRecoveryCode(ctx context.Context, schema RemoveRecoveryCodeSchema) error {
+	result, err := r.db.db.ExecContext(ctx, `
+		UPDATE users SET two_factor = json_set(two_factor, '$.recoveryCodeHashes', json((
+				SELECT json_group_array(value) FROM json_each(two_factor, '$.recoveryCodeHashes') WHERE value <> ?2
+			))), version = version + 1
+		WHERE id = ?1 AND EXISTS (SELECT 1 FROM json_each(two_factor, '$.recoveryCodeHashes') WHERE value = ?2)`,
+		schema.UserID.Hex(), schema.RecoveryCodeHash,
+	)
+
+	return affectedOrNotFound(result, err, entity.ErrIncorrectTwoFactorCode)
+}
+
+func (r *sqliteUsersRepository) FindByIdentity(ctx context.Context, provider, subject string) (entity.UserModel, error) {
+	return r.findOne(ctx, `u.id = (SELECT user_id FROM user_identities WHERE provider = ?1 AND subject = ?2)`, provider, subject)
+}
+
+func (r *sqliteUsersRepository) LinkIdentity(ctx context.Context, schema LinkIdentitySchema) error {
+	err := r.db.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO user_identities (provider, subject, user_id, linked_at) VALUES (?1, ?2, ?3, ?4)`,
+			schema.Identity.Provider, schema.Identity.Subject, schema.UserID.Hex(), sqliteTimeValue(schema.Identity.LinkedAt),
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE users SET version = version + 1 WHERE id = ?1`, schema.UserID.Hex())
+
+		return err
+	})
+	if isSQLiteUniqueViolation(err) {
+		return entity.ErrOIDCIdentityAlreadyLinked
+	} else if isSQLiteForeignKeyViolation(err) {
+		return entity.ErrUserNotFound
+	}
+
+	return err
+}
+
+func (r *sqliteUsersRepository) List(ctx context.Context, schema ListUsersSchema) ([]entity.UserModel, int64, error) {
+	// LIKE is case-insensitive for ASCII characters
+	const filter = `(?1 = '' OR u.username LIKE '%' || ?1 || '%' ESCAPE '\' OR u.email LIKE '%' || ?1 || '%' ESCAPE '\')`
+
+	search := sqliteSearchEscaper.Replace(schema.Search)
+
+	var total int64
+
+	err := r.db.db.QueryRowContext(ctx, `SELECT count(*) FROM users u WHERE `+filter, search).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// negative limit means there is no limit
+	users, err := r.find(ctx, filter+` ORDER BY u.id LIMIT CASE WHEN ?3 > 0 THEN ?3 ELSE -1 END OFFSET ?2`,
+		search, schema.Offset, schema.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (r *sqliteUsersRepository) SetRole(ctx context.Context, schema SetRoleSchema) error {
+	return r.update(ctx, schema.UserID, 0, `role = ?2`, schema.Role)
+}
+
+func (r *sqliteUsersRepository) Suspend(ctx context.Context, schema SuspendUserSchema) error {
+	return r.update(ctx, schema.UserID, 0, `suspended_at = ?2, suspension_reason = ?3`, sqliteTimeValue(schema.SuspendedAt), schema.Reason)
+}
+
+func (r *sqliteUsersRepository) Unsuspend(ctx context.Context, userID primitive.ObjectID) error {
+	return r.update(ctx, userID, 0, `suspended_at = NULL, suspension_reason = ''`)
+}
+
+func (r *sqliteUsersRepository) ScheduleDeletion(ctx context.Context, schema ScheduleDeletionSchema) error {
+	return r.update(ctx, schema.UserID, 0, `deletion_scheduled_at = ?2`, sqliteTimeValue(schema.DeletionScheduledAt))
+}
+
+func (r *sqliteUsersRepository) CancelDeletion(ctx context.Context, userID primitive.ObjectID) error {
+	return r.update(ctx, userID, 0, `deletion_scheduled_at = NULL`)
+}
+
+func (r *sqliteUsersRepository) FindScheduledForDeletion(ctx context.Context, before time.Time) ([]entity.UserModel, error) {
+	return r.find(ctx, `u.deletion_scheduled_at <= ?1 ORDER BY u.id`, sqliteTimeValue(before))
+}
+
+func (r *sqliteUsersRepository) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	result, err := r.db.db.ExecContext(ctx, `DELETE FROM users WHERE id = ?1`, userID.Hex())
+	return affectedOrNotFound(result, err, entity.ErrUserNotFound)
+}
+
+// update sets columns of the user and increments its version, ID of the user is the first argument
+// of the set expression. Version of the user must match expected one unless it's zero
+func (r *sqliteUsersRepository) update(ctx context.Context, userID primitive.ObjectID, version int64, set string, args ...any) error {
+	query := `UPDATE users SET ` + set + `, version = version + 1 WHERE id = ?1`
+	args = append([]any{userID.Hex()}, args...)
+
+	if version != 0 {
+		args = append(args, version)
+		query += fmt.Sprintf(` AND version = ?%d`, len(args))
+	}
+
+	result, err := r.db.db.ExecContext(ctx, query, args...)
+	if err = affectedOrNotFound(result, err, entity.ErrUserNotFound); errors.Is(err, entity.ErrUserNotFound) {
+		return r.unmatchedError(ctx, userID, version)
+	}
+
+	return err
+}
+
+// unmatchedError tells apart missing user and the user, whose version was changed concurrently
+func (r *sqliteUsersRepository) unmatchedError(ctx context.Context, userID primitive.ObjectID, version int64) error {
+	if version == 0 {
+		return entity.ErrUserNotFound
+	}
+
+	var exists bool
+
+	err := r.db.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = ?1)`, userID.Hex()).Scan(&exists)
+	if err != nil {
+		return err
+	} else if !exists {
+		return entity.ErrUserNotFound
+	}
+
+	return entity.ErrUserVersionConflict
+}
+
+// findOne returns the first user, who matches condition, in order of creation
+func (r *sqliteUsersRepository) findOne(ctx context.Context, condition string, args ...any) (entity.UserModel, error) {
+	users, err := r.find(ctx, condition+` ORDER BY u.id LIMIT 1`, args...)
+	if err != nil {
+		return entity.UserModel{}, err
+	} else if len(users) == 0 {
+		return entity.UserModel{}, entity.ErrUserNotFound
+	}
+
+	return users[0], nil
+}
+
+// find returns users, who match condition, condition may be followed by ordering and limits
+func (r *sqliteUsersRepository) find(ctx context.Context, condition string, args ...any) ([]entity.UserModel, error) {
+	rows, err := r.db.db.QueryContext(ctx, `SELECT `+sqliteUserColumns+` FROM users u WHERE `+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []entity.UserModel{}
+
+	for rows.Next() {
+		user, err := scanSQLiteUser(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func scanSQLiteUser(rows *sql.Rows) (entity.UserModel, error) {
+	var (
+		user                          entity.UserModel
+		id, previousUsernames, idents string
+		twoFactor                     *string
+	)
+
+	err := rows.Scan(
+		&id, &user.Username, &user.Email, &user.PasswordHash, &user.Role,
+		sqliteTime{dst: &user.CreatedAt}, sqliteTime{dst: &user.UpdatedAt},
+		sqliteNullTime{dst: &user.SuspendedAt}, &user.SuspensionReason, &twoFactor, sqliteNullTime{dst: &user.DeletionScheduledAt},
+		&user.DisplayName, &user.Bio, &user.Avatar, sqliteNullTime{dst: &user.UsernameChangedAt}, &previousUsernames, &user.Version,
+		&idents,
+	)
+	if err != nil {
+		return entity.UserModel{}, err
+	}
+
+	if user.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return entity.UserModel{}, errors.Wrapf(err, "invalid user ID %q", id)
+	}
+
+	if twoFactor != nil {
+		if err = json.Unmarshal([]byte(*twoFactor), &user.TwoFactor); err != nil {
+			return entity.UserModel{}, errors.Wrap(err, "failed to decode two factor")
+		}
+	}
+
+	if err = json.Unmarshal([]byte(previousUsernames), &user.PreviousUsernames); err != nil {
+		return entity.UserModel{}, errors.Wrap(err, "failed to decode previous usernames")
+	}
+
+	if err = json.Unmarshal([]byte(idents), &user.Identities); err != nil {
+		return entity.UserModel{}, errors.Wrap(err, "failed to decode identities")
+	}
+
+	// empty arrays are omitted like in the other backends
+	user.PreviousUsernames = lo.Ternary(len(user.PreviousUsernames) == 0, nil, user.PreviousUsernames)
+	user.Identities = lo.Ternary(len(user.Identities) == 0, nil, user.Identities)
+
+	return user, nil
+}
@@ -0,0 +1,550 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/pkg/blob"
+	"github.com/kenplix/url-shrtnr/pkg/hash"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+	"github.com/kenplix/url-shrtnr/pkg/mail"
+)
+
+const (
+	defaultDeletionGracePeriod = 30 * 24 * time.Hour
+	defaultDataExportTTL       = 24 * time.Hour
+	dataExportIDSize           = 16
+	dataExportBuildTimeout     = 5 * time.Minute
+	dataExportAuditPageSize    = 100
+
+	defaultDeletionConfirmationTTL  = 15 * time.Minute
+	defaultDeletionConfirmationURL  = "http://localhost/account/deletion/confirm"
+	deletionConfirmationTokenSize   = 32
+	deletionConfirmationSendTimeout = 30 * time.Second
+)
+
+type AccountServiceConfig struct {
+	// DeletionGracePeriod is a period during which scheduled account deletion may be cancelled
+	DeletionGracePeriod time.Duration `mapstructure:"deletionGracePeriod"`
+	// ExportTTL is a period during which prepared personal data archive may be downloaded
+	ExportTTL time.Duration `mapstructure:"exportTTL"`
+	// DeletionConfirmationTTL is a period during which emailed deletion confirmation may be used
+	// by user who has no password to re-authenticate with
+	DeletionConfirmationTTL time.Duration `mapstructure:"deletionConfirmationTTL"`
+	// DeletionConfirmationURL is a page of the frontend which confirms deletion with token
+	// from the "token" query parameter
+	DeletionConfirmationURL string `mapstructure:"deletionConfirmationURL"`
+}
+
+// dataExportProfile is a content of profile.json in personal data archive
+type dataExportProfile struct {
+	entity.User
+	Identities []entity.IdentityModel `json:"identities,omitempty"`
+}
+
+type accountService struct {
+	cache         Cache
+	usersRepo     repository.UsersRepository
+	apiKeysRepo   repository.APIKeysRepository
+	auditRepo     repository.AuditRepository
+	hasherServ    hash.HasherService
+	jwtServ       JWTService
+	twoFactorServ TwoFactorService
+	auditServ     AuditService
+	blobStore     blob.Store
+	mailSender    mail.Sender
+	gracePeriod   time.Duration
+	exportTTL     time.Duration
+	confirmTTL    time.Duration
+	confirmURL    string
+}
+
+func NewAccountService(
+	cfg AccountServiceConfig,
+	cache Cache,
+	repos *repository.Repositories,
+	hasherServ hash.HasherService,
+	jwtServ JWTService,
+	twoFactorServ TwoFactorService,
+	auditServ AuditService,
+	blobStore blob.Store,
+	mailSender mail.Sender,
+) (AccountService, error) {
+	if cache == nil {
+		return nil, errors.New("cache not provided")
+	}
+
+	if repos == nil {
+		return nil, errors.New("repositories not provided")
+	}
+
+	if hasherServ == nil {
+		return nil, errors.New("hasher service not provided")
+	}
+
+	if jwtServ == nil {
+		return nil, errors.New("jwt service not provided")
+	}
+
+	if twoFactorServ == nil {
+		return nil, errors.New("two-factor service not provided")
+	}
+
+	if auditServ == nil {
+		return nil, errors.New("audit service not provided")
+	}
+
+	if blobStore == nil {
+		return nil, errors.New("blob store not provided")
+	}
+
+	if mailSender == nil {
+		return nil, errors.New("mail sender not provided")
+	}
+
+	confirmURL := lo.Ternary(cfg.DeletionConfirmationURL != "", cfg.DeletionConfirmationURL, defaultDeletionConfirmationURL)
+	if _, err := url.Parse(confirmURL); err != nil {
+		return nil, errors.Wrapf(err, "invalid deletion confirmation url %q", confirmURL)
+	}
+
+	s := &accountService{
+		cache:         cache,
+		usersRepo:     repos.Users,
+		apiKeysRepo:   repos.APIKeys,
+		auditRepo:     repos.Audit,
+		hasherServ:    hasherServ,
+		jwtServ:       jwtServ,
+		twoFactorServ: twoFactorServ,
+		auditServ:     auditServ,
+		blobStore:     blobStore,
+		mailSender:    mailSender,
+		gracePeriod:   lo.Ternary(cfg.DeletionGracePeriod > 0, cfg.DeletionGracePeriod, defaultDeletionGracePeriod),
+		exportTTL:     lo.Ternary(cfg.ExportTTL > 0, cfg.ExportTTL, defaultDataExportTTL),
+		confirmTTL:    lo.Ternary(cfg.DeletionConfirmationTTL > 0, cfg.DeletionConfirmationTTL, defaultDeletionConfirmationTTL),
+		confirmURL:    confirmURL,
+	}
+
+	return s, nil
+}
+
+func (s *accountService) RequestExport(ctx context.Context, userID primitive.ObjectID) (entity.DataExport, error) {
+	exportID, err := randomHex(dataExportIDSize)
+	if err != nil {
+		return entity.DataExport{}, errors.Wrap(err, "failed to generate export id")
+	}
+
+	now := time.Now()
+	export := entity.DataExport{
+		ID:        exportID,
+		Status:    entity.DataExportPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.exportTTL),
+	}
+
+	if err = s.storeExport(ctx, userID, export); err != nil {
+		return entity.DataExport{}, err
+	}
+
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionDataExported,
+		ActorID: userID,
+	})
+
+	logger := log.LoggerFromContext(ctx).With(
+		zap.String("userID", userID.Hex()),
+		zap.String("exportID", exportID),
+	)
+
+	go func() {
+		buildCtx, cancel := context.WithTimeout(log.ContextWithLogger(context.Background(), logger), dataExportBuildTimeout)
+		defer cancel()
+
+		s.buildExport(buildCtx, userID, export)
+	}()
+
+	return export, nil
+}
+
+func (s *accountService) GetExport(ctx context.Context, schema DataExportSchema) (entity.DataExport, error) {
+	exportKey := dataExportCacheKey(schema.UserID.Hex(), schema.ExportID)
+
+	exportJSON, err := s.cache.Get(ctx, exportKey)
+	if err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			return entity.DataExport{}, entity.ErrDataExportNotFound
+		}
+
+		return entity.DataExport{}, errors.Wrapf(err, "cache: failed to get %q key", exportKey)
+	}
+
+	var export entity.DataExport
+	if err = json.Unmarshal(exportJSON, &export); err != nil {
+		return entity.DataExport{}, errors.Wrap(err, "failed to unmarshal data export")
+	}
+
+	return export, nil
+}
+
+func (s *accountService) DownloadExport(ctx context.Context, schema DataExportSchema) ([]byte, error) {
+	export, err := s.GetExport(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	if export.Status != entity.DataExportReady {
+		return nil, entity.ErrDataExportNotReady
+	}
+
+	archiveKey := dataExportArchiveCacheKey(schema.UserID.Hex(), schema.ExportID)
+
+	archive, err := s.cache.Get(ctx, archiveKey)
+	if err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			return nil, entity.ErrDataExportNotFound
+		}
+
+		return nil, errors.Wrapf(err, "cache: failed to get %q key", archiveKey)
+	}
+
+	return archive, nil
+}
+
+func (s *accountService) ScheduleDeletion(ctx context.Context, schema ScheduleDeletionSchema) (entity.User, error) {
+	user, err := s.usersRepo.FindByID(ctx, schema.UserID)
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "failed to get user[id:%q]", schema.UserID.Hex())
+	}
+
+	if err = s.reauthenticate(ctx, user, schema); err != nil {
+		return entity.User{}, err
+	}
+
+	if user.TwoFactor != nil {
+		if err = s.twoFactorServ.VerifyCode(ctx, user, schema.Code); err != nil {
+			return entity.User{}, err
+		}
+	}
+
+	// repeated request keeps originally scheduled date, so grace period can't be prolonged
+	if user.DeletionScheduledAt == nil {
+		deletionScheduledAt := time.Now().Add(s.gracePeriod)
+
+		err = s.usersRepo.ScheduleDeletion(ctx, repository.ScheduleDeletionSchema{
+			UserID:              user.ID,
+			DeletionScheduledAt: deletionScheduledAt,
+		})
+		if err != nil {
+			return entity.User{}, errors.Wrapf(err, "user[id:%q]: failed to schedule deletion", user.ID.Hex())
+		}
+
+		user.DeletionScheduledAt = &deletionScheduledAt
+	}
+
+	err = s.jwtServ.RevokeTokens(ctx, user.ID.Hex())
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "user[id:%q]: failed to revoke tokens", user.ID.Hex())
+	}
+
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionDeletionScheduled,
+		ActorID: user.ID,
+		Details: map[string]string{"deletionScheduledAt": user.DeletionScheduledAt.UTC().Format(time.RFC3339)},
+	})
+
+	return user.Filter(), nil
+}
+
+// reauthenticate checks password of the user, user who has no password (e.g. signed up with identity provider)
+// confirms deletion with one-time token sent by email instead
+func (s *accountService) reauthenticate(ctx context.Context, user entity.UserModel, schema ScheduleDeletionSchema) error {
+	if user.PasswordHash != "" {
+		if ok := s.hasherServ.VerifyPassword(schema.Password, user.PasswordHash); !ok {
+			return entity.ErrIncorrectCredentials
+		}
+
+		return nil
+	}
+
+	if schema.Token == "" {
+		return s.requestDeletionConfirmation(ctx, user)
+	}
+
+	confirmationKey := deletionConfirmationCacheKey(user.ID.Hex(), hashSecret(schema.Token))
+
+	// only one of concurrent requests deletes the key, which guarantees single use
+	deleted, err := s.cache.Del(ctx, confirmationKey)
+	if err != nil {
+		return errors.Wrapf(err, "cache: failed to delete %q key", confirmationKey)
+	} else if deleted == 0 {
+		return entity.ErrDeletionConfirmationNotFound
+	}
+
+	return nil
+}
+
+// requestDeletionConfirmation emails one-time deletion confirmation and returns
+// entity.ErrDeletionConfirmationSent, so the request is completed with the token from email
+func (s *accountService) requestDeletionConfirmation(ctx context.Context, user entity.UserModel) error {
+	token, err := randomHex(deletionConfirmationTokenSize)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate token")
+	}
+
+	confirmationKey := deletionConfirmationCacheKey(user.ID.Hex(), hashSecret(token))
+
+	err = s.cache.Set(ctx, confirmationKey, []byte(user.ID.Hex()), s.confirmTTL)
+	if err != nil {
+		return errors.Wrapf(err, "cache: failed to set %q key", confirmationKey)
+	}
+
+	logger := log.LoggerFromContext(ctx).With(zap.String("userID", user.ID.Hex()))
+
+	link, _ := url.Parse(s.confirmURL)
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Confirm your account deletion",
+		Body: fmt.Sprintf("Hi %s,\n\nfollow the link below to confirm deletion of your account, it expires in %s and works only once:\n%s\n\n"+
+			"If you didn't request it, ignore this email and change access to your identity provider account.\n", user.Username, s.confirmTTL, link),
+	}
+
+	go func() {
+		sendCtx, cancel := context.WithTimeout(log.ContextWithLogger(context.Background(), logger), deletionConfirmationSendTimeout)
+		defer cancel()
+
+		if err := s.mailSender.Send(sendCtx, msg); err != nil {
+			logger.Error("failed to send deletion confirmation", zap.Error(err))
+		}
+	}()
+
+	return entity.ErrDeletionConfirmationSent
+}
+
+func (s *accountService) CancelDeletion(ctx context.Context, userID primitive.ObjectID) (entity.User, error) {
+	user, err := s.usersRepo.FindByID(ctx, userID)
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "failed to get user[id:%q]", userID.Hex())
+	}
+
+	if user.DeletionScheduledAt == nil {
+		return entity.User{}, entity.ErrDeletionNotScheduled
+	}
+
+	err = s.usersRepo.CancelDeletion(ctx, userID)
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "user[id:%q]: failed to cancel deletion", userID.Hex())
+	}
+
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionDeletionCancelled,
+		ActorID: userID,
+	})
+
+	user.DeletionScheduledAt = nil
+
+	return user.Filter(), nil
+}
+
+func (s *accountService) PurgeDeleted(ctx context.Context) (int, error) {
+	users, err := s.usersRepo.FindScheduledForDeletion(ctx, time.Now())
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to find users scheduled for deletion")
+	}
+
+	for i, user := range users {
+		if err = s.purge(ctx, user); err != nil {
+			return i, err
+		}
+	}
+
+	return len(users), nil
+}
+
+// purge removes owned data before the user itself, so interrupted purge is retried on the next run
+func (s *accountService) purge(ctx context.Context, user entity.UserModel) error {
+	userID := user.ID
+
+	err := s.apiKeysRepo.DeleteByUserID(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "user[id:%q]: failed to delete api keys", userID.Hex())
+	}
+
+	if user.Avatar != "" {
+		err = s.blobStore.Delete(ctx, user.Avatar)
+		if err != nil {
+			return errors.Wrapf(err, "user[id:%q]: failed to delete avatar", userID.Hex())
+		}
+	}
+
+	err = s.auditRepo.Anonymize(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "user[id:%q]: failed to anonymize audit events", userID.Hex())
+	}
+
+	err = s.usersRepo.Delete(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete user[id:%q]", userID.Hex())
+	}
+
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:       entity.AuditActionUserDeleted,
+		TargetUserID: userID,
+	})
+
+	return nil
+}
+
+// buildExport prepares archive and stores it together with updated export state
+func (s *accountService) buildExport(ctx context.Context, userID primitive.ObjectID, export entity.DataExport) {
+	logger := log.LoggerFromContext(ctx)
+
+	archive, err := s.exportArchive(ctx, userID)
+	if err != nil {
+		logger.Error("failed to build data export", zap.Error(err))
+
+		export.Status = entity.DataExportFailed
+		if err = s.storeExport(ctx, userID, export); err != nil {
+			logger.Error("failed to store data export", zap.Error(err))
+		}
+
+		return
+	}
+
+	archiveKey := dataExportArchiveCacheKey(userID.Hex(), export.ID)
+
+	err = s.cache.Set(ctx, archiveKey, archive, time.Until(export.ExpiresAt))
+	if err != nil {
+		logger.Error("failed to store data export archive", zap.String("key", archiveKey), zap.Error(err))
+		return
+	}
+
+	export.Status = entity.DataExportReady
+	if err = s.storeExport(ctx, userID, export); err != nil {
+		logger.Error("failed to store data export", zap.Error(err))
+	}
+}
+
+// exportArchive returns ZIP archive with JSON file per kind of personal data
+func (s *accountService) exportArchive(ctx context.Context, userID primitive.ObjectID) ([]byte, error) {
+	user, err := s.usersRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get user[id:%q]", userID.Hex())
+	}
+
+	keys, err := s.apiKeysRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get user[id:%q] api keys", userID.Hex())
+	}
+
+	var events []entity.AuditEvent
+
+	for offset := int64(0); ; offset += dataExportAuditPageSize {
+		var (
+			page  []entity.AuditEventModel
+			total int64
+		)
+
+		page, total, err = s.auditRepo.Find(ctx, repository.FindAuditEventsSchema{
+			UserID: userID,
+			Offset: offset,
+			Limit:  dataExportAuditPageSize,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get user[id:%q] audit events", userID.Hex())
+		}
+
+		for _, event := range page {
+			events = append(events, event.Filter())
+		}
+
+		if len(page) == 0 || offset+int64(len(page)) >= total {
+			break
+		}
+	}
+
+	files := []struct {
+		name    string
+		content any
+	}{
+		{
+			name:    "profile.json",
+			content: dataExportProfile{User: user.Filter(), Identities: user.Identities},
+		},
+		{
+			name: "api_keys.json",
+			content: lo.Map(keys, func(key entity.APIKeyModel, _ int) entity.APIKey {
+				return key.Filter()
+			}),
+		},
+		{
+			name:    "audit_events.json",
+			content: events,
+		},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create %q", file.name)
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+
+		if err = enc.Encode(file.content); err != nil {
+			return nil, errors.Wrapf(err, "failed to encode %q", file.name)
+		}
+	}
+
+	if err = zw.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to close archive")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *accountService) storeExport(ctx context.Context, userID primitive.ObjectID, export entity.DataExport) error {
+	exportJSON, err := json.Marshal(export)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal data export")
+	}
+
+	exportKey := dataExportCacheKey(userID.Hex(), export.ID)
+
+	err = s.cache.Set(ctx, exportKey, exportJSON, time.Until(export.ExpiresAt))
+	if err != nil {
+		return errors.Wrapf(err, "cache: failed to set %q key", exportKey)
+	}
+
+	return nil
+}
+
+func dataExportCacheKey(userID, exportID string) string {
+	return fmt.Sprintf("export:%s:%s", userID, exportID)
+}
+
+func dataExportArchiveCacheKey(userID, exportID string) string {
+	return fmt.Sprintf("export:%s:%s:archive", userID, exportID)
+}
+
+func deletionConfirmationCacheKey(userID, tokenHash string) string {
+	return fmt.Sprintf("deletion-confirmation:%s:%s", userID, tokenHash)
+}
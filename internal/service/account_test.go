@@ -0,0 +1,320 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+	"github.com/kenplix/url-shrtnr/pkg/blob"
+	hashMocks "github.com/kenplix/url-shrtnr/pkg/hash/mocks"
+	"github.com/kenplix/url-shrtnr/pkg/mail"
+	mailMocks "github.com/kenplix/url-shrtnr/pkg/mail/mocks"
+)
+
+type accountTestEnv struct {
+	repos         *repository.Repositories
+	accountServ   service.AccountService
+	hasherServ    *hashMocks.HasherService
+	jwtServ       *servMocks.JWTService
+	twoFactorServ *servMocks.TwoFactorService
+	mailSender    *mailMocks.Sender
+	blobStore     blob.Store
+}
+
+func newAccountTestEnv(t *testing.T, cfg service.AccountServiceConfig) accountTestEnv {
+	t.Helper()
+
+	ctx := context.Background()
+
+	cache := newTestMemoryCache(t)
+
+	repos, err := repository.New(ctx, repository.Config{
+		Use:    "filedb",
+		FileDB: repository.FileDBConfig{Path: t.TempDir()},
+	})
+	require.NoErrorf(t, err, "failed to create repositories: %s", err)
+
+	t.Cleanup(func() {
+		_ = repos.Close(ctx)
+	})
+
+	auditServ, err := service.NewAuditService(repos.Audit)
+	require.NoErrorf(t, err, "failed to create audit service: %s", err)
+
+	blobStore, err := blob.NewStore(blob.Config{Local: blob.LocalConfig{Path: t.TempDir()}})
+	require.NoErrorf(t, err, "failed to create blob store: %s", err)
+
+	env := accountTestEnv{
+		repos:         repos,
+		blobStore:     blobStore,
+		hasherServ:    hashMocks.NewHasherService(t),
+		jwtServ:       servMocks.NewJWTService(t),
+		twoFactorServ: servMocks.NewTwoFactorService(t),
+		mailSender:    mailMocks.NewSender(t),
+	}
+
+	env.accountServ, err = service.NewAccountService(
+		cfg,
+		cache,
+		repos,
+		env.hasherServ,
+		env.jwtServ,
+		env.twoFactorServ,
+		auditServ,
+		env.blobStore,
+		env.mailSender,
+	)
+	require.NoErrorf(t, err, "failed to create account service: %s", err)
+
+	return env
+}
+
+func TestAccountService_Deletion(t *testing.T) {
+	ctx := context.Background()
+
+	env := newAccountTestEnv(t, service.AccountServiceConfig{DeletionGracePeriod: time.Hour})
+
+	user := entity.UserModel{ID: primitive.NewObjectID(), Username: "kenplix", PasswordHash: "<hash>"}
+	require.NoError(t, env.repos.Users.Create(ctx, user))
+
+	env.hasherServ.On("VerifyPassword", "wrong", "<hash>").Return(false)
+	env.hasherServ.On("VerifyPassword", "1wE$Rty2", "<hash>").Return(true)
+	env.jwtServ.On("RevokeTokens", mock.Anything, user.ID.Hex()).Return(nil)
+
+	_, err := env.accountServ.ScheduleDeletion(ctx, service.ScheduleDeletionSchema{UserID: user.ID, Password: "wrong"})
+	require.ErrorIs(t, err, entity.ErrIncorrectCredentials)
+
+	scheduled, err := env.accountServ.ScheduleDeletion(ctx, service.ScheduleDeletionSchema{UserID: user.ID, Password: "1wE$Rty2"})
+	require.NoError(t, err)
+	require.NotNil(t, scheduled.DeletionScheduledAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *scheduled.DeletionScheduledAt, time.Minute)
+
+	repeated, err := env.accountServ.ScheduleDeletion(ctx, service.ScheduleDeletionSchema{UserID: user.ID, Password: "1wE$Rty2"})
+	require.NoError(t, err)
+	assert.Equal(t, scheduled.DeletionScheduledAt, repeated.DeletionScheduledAt, "grace period must not be prolonged")
+
+	purged, err := env.accountServ.PurgeDeleted(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, purged, "account must be kept during grace period")
+
+	cancelled, err := env.accountServ.CancelDeletion(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, cancelled.DeletionScheduledAt)
+
+	_, err = env.accountServ.CancelDeletion(ctx, user.ID)
+	assert.ErrorIs(t, err, entity.ErrDeletionNotScheduled)
+
+	env.jwtServ.AssertNumberOfCalls(t, "RevokeTokens", 2)
+}
+
+func TestAccountService_DeletionWithoutPassword(t *testing.T) {
+	ctx := context.Background()
+
+	env := newAccountTestEnv(t, service.AccountServiceConfig{DeletionConfirmationURL: "https://url-shrtnr.com/account/deletion/confirm"})
+
+	// user signed up with identity provider has no password to re-authenticate with
+	user := entity.UserModel{ID: primitive.NewObjectID(), Username: "kenplix", Email: "tolstoi.job@gmail.com"}
+	require.NoError(t, env.repos.Users.Create(ctx, user))
+
+	sent := make(chan mail.Message, 1)
+
+	env.mailSender.
+		On("Send", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { sent <- args.Get(1).(mail.Message) }).
+		Return(nil).
+		Once()
+	env.jwtServ.On("RevokeTokens", mock.Anything, user.ID.Hex()).Return(nil).Once()
+
+	_, err := env.accountServ.ScheduleDeletion(ctx, service.ScheduleDeletionSchema{UserID: user.ID})
+	require.ErrorIs(t, err, entity.ErrDeletionConfirmationSent)
+
+	var msg mail.Message
+	select {
+	case msg = <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deletion confirmation wasn't sent")
+	}
+
+	assert.Equal(t, user.Email, msg.To)
+
+	idx := strings.Index(msg.Body, "https://url-shrtnr.com/account/deletion/confirm?token=")
+	require.NotEqual(t, -1, idx, "email must contain confirmation link")
+
+	token := strings.Fields(msg.Body[idx+len("https://url-shrtnr.com/account/deletion/confirm?token="):])[0]
+
+	found, err := env.repos.Users.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found.DeletionScheduledAt, "deletion must not be scheduled before confirmation")
+
+	_, err = env.accountServ.ScheduleDeletion(ctx, service.ScheduleDeletionSchema{UserID: user.ID, Token: "<unknown token>"})
+	require.ErrorIs(t, err, entity.ErrDeletionConfirmationNotFound)
+
+	scheduled, err := env.accountServ.ScheduleDeletion(ctx, service.ScheduleDeletionSchema{UserID: user.ID, Token: token})
+	require.NoError(t, err)
+	assert.NotNil(t, scheduled.DeletionScheduledAt)
+
+	_, err = env.accountServ.ScheduleDeletion(ctx, service.ScheduleDeletionSchema{UserID: user.ID, Token: token})
+	assert.ErrorIs(t, err, entity.ErrDeletionConfirmationNotFound, "confirmation must work only once")
+}
+
+func TestAccountService_DeletionWithTwoFactor(t *testing.T) {
+	ctx := context.Background()
+
+	env := newAccountTestEnv(t, service.AccountServiceConfig{})
+
+	user := entity.UserModel{
+		ID:           primitive.NewObjectID(),
+		Username:     "kenplix",
+		PasswordHash: "<hash>",
+		TwoFactor:    &entity.TwoFactorModel{},
+	}
+	require.NoError(t, env.repos.Users.Create(ctx, user))
+
+	env.hasherServ.On("VerifyPassword", "1wE$Rty2", "<hash>").Return(true)
+	env.twoFactorServ.On("VerifyCode", mock.Anything, mock.Anything, "").Return(entity.ErrIncorrectTwoFactorCode)
+
+	_, err := env.accountServ.ScheduleDeletion(ctx, service.ScheduleDeletionSchema{UserID: user.ID, Password: "1wE$Rty2"})
+	require.ErrorIs(t, err, entity.ErrIncorrectTwoFactorCode)
+
+	found, err := env.repos.Users.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found.DeletionScheduledAt)
+}
+
+func TestAccountService_PurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+
+	env := newAccountTestEnv(t, service.AccountServiceConfig{DeletionGracePeriod: time.Nanosecond})
+
+	user := entity.UserModel{ID: primitive.NewObjectID(), Username: "kenplix", PasswordHash: "<hash>", Avatar: "avatars/kenplix.png"}
+	other := entity.UserModel{ID: primitive.NewObjectID(), Username: "other"}
+
+	require.NoError(t, env.blobStore.Put(ctx, user.Avatar, "image/png", strings.NewReader("<png>")))
+
+	require.NoError(t, env.repos.Users.Create(ctx, user))
+	require.NoError(t, env.repos.Users.Create(ctx, other))
+
+	for _, userID := range []primitive.ObjectID{user.ID, other.ID} {
+		require.NoError(t, env.repos.APIKeys.Create(ctx, entity.APIKeyModel{UserID: userID, Prefix: userID.Hex()}))
+		require.NoError(t, env.repos.Audit.Create(ctx, entity.AuditEventModel{
+			Action:  entity.AuditActionSignIn,
+			ActorID: userID,
+			IP:      "203.0.113.7",
+		}))
+	}
+
+	env.hasherServ.On("VerifyPassword", "1wE$Rty2", "<hash>").Return(true)
+	env.jwtServ.On("RevokeTokens", mock.Anything, user.ID.Hex()).Return(nil)
+
+	_, err := env.accountServ.ScheduleDeletion(ctx, service.ScheduleDeletionSchema{UserID: user.ID, Password: "1wE$Rty2"})
+	require.NoError(t, err)
+
+	purged, err := env.accountServ.PurgeDeleted(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	_, err = env.repos.Users.FindByID(ctx, user.ID)
+	assert.ErrorIs(t, err, entity.ErrUserNotFound)
+
+	_, err = env.repos.Users.FindByID(ctx, other.ID)
+	assert.NoError(t, err, "other users must be kept")
+
+	_, err = env.blobStore.Get(ctx, user.Avatar)
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+
+	keys, err := env.repos.APIKeys.FindByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	keys, err = env.repos.APIKeys.FindByUserID(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	events, _, err := env.repos.Audit.Find(ctx, repository.FindAuditEventsSchema{UserID: user.ID})
+	require.NoError(t, err)
+
+	for _, event := range events {
+		assert.Emptyf(t, event.IP, "%s event must be anonymized", event.Action)
+		assert.Emptyf(t, event.Details, "%s event must be anonymized", event.Action)
+	}
+
+	events, _, err = env.repos.Audit.Find(ctx, repository.FindAuditEventsSchema{UserID: other.ID})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "203.0.113.7", events[0].IP, "events of other users must be kept")
+}
+
+func TestAccountService_Export(t *testing.T) {
+	ctx := context.Background()
+
+	env := newAccountTestEnv(t, service.AccountServiceConfig{})
+
+	user := entity.UserModel{ID: primitive.NewObjectID(), Username: "kenplix", Email: "tolstoi.job@gmail.com"}
+	require.NoError(t, env.repos.Users.Create(ctx, user))
+	require.NoError(t, env.repos.APIKeys.Create(ctx, entity.APIKeyModel{UserID: user.ID, Name: "ci deploy"}))
+
+	export, err := env.accountServ.RequestExport(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.DataExportPending, export.Status)
+
+	schema := service.DataExportSchema{UserID: user.ID, ExportID: export.ID}
+
+	require.Eventually(t, func() bool {
+		export, err = env.accountServ.GetExport(ctx, schema)
+		return err == nil && export.Status == entity.DataExportReady
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = env.accountServ.GetExport(ctx, service.DataExportSchema{UserID: primitive.NewObjectID(), ExportID: export.ID})
+	assert.ErrorIs(t, err, entity.ErrDataExportNotFound, "export of another user must not be found")
+
+	archive, err := env.accountServ.DownloadExport(ctx, schema)
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+
+	for _, file := range zr.File {
+		r, err := file.Open()
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(r)
+		require.NoError(t, err)
+		r.Close()
+
+		files[file.Name] = buf.Bytes()
+	}
+
+	require.Contains(t, files, "profile.json")
+	require.Contains(t, files, "api_keys.json")
+	require.Contains(t, files, "audit_events.json")
+
+	var profile entity.User
+	require.NoError(t, json.Unmarshal(files["profile.json"], &profile))
+	assert.Equal(t, user.Email, profile.Email)
+
+	var keys []entity.APIKey
+	require.NoError(t, json.Unmarshal(files["api_keys.json"], &keys))
+	require.Len(t, keys, 1)
+	assert.Equal(t, "ci deploy", keys[0].Name)
+
+	var events []entity.AuditEvent
+	require.NoError(t, json.Unmarshal(files["audit_events.json"], &events))
+	require.NotEmpty(t, events)
+	assert.Equal(t, entity.AuditActionDataExported, events[0].Action)
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+)
+
+const (
+	defaultUsersPerPage = 20
+	maxUsersPerPage     = 100
+)
+
+type adminService struct {
+	usersRepo    repository.UsersRepository
+	auditServ    AuditService
+	jwtServ      JWTService
+	throttleServ ThrottleService
+}
+
+func NewAdminService(
+	usersRepo repository.UsersRepository,
+	auditServ AuditService,
+	jwtServ JWTService,
+	throttleServ ThrottleService,
+) (AdminService, error) {
+	if usersRepo == nil {
+		return nil, errors.New("users repository not provided")
+	}
+
+	if auditServ == nil {
+		return nil, errors.New("audit service not provided")
+	}
+
+	if jwtServ == nil {
+		return nil, errors.New("jwt service not provided")
+	}
+
+	if throttleServ == nil {
+		return nil, errors.New("throttle service not provided")
+	}
+
+	s := &adminService{
+		usersRepo:    usersRepo,
+		auditServ:    auditServ,
+		jwtServ:      jwtServ,
+		throttleServ: throttleServ,
+	}
+
+	return s, nil
+}
+
+func (s *adminService) ListUsers(ctx context.Context, schema ListUsersSchema) (entity.UsersPage, error) {
+	page := lo.Ternary(schema.Page < 1, 1, schema.Page)
+	perPage := lo.Ternary(schema.PerPage < 1, defaultUsersPerPage, lo.Min([]int{schema.PerPage, maxUsersPerPage}))
+
+	users, total, err := s.usersRepo.List(ctx, repository.ListUsersSchema{
+		Search: schema.Search,
+		Offset: int64((page - 1) * perPage),
+		Limit:  int64(perPage),
+	})
+	if err != nil {
+		return entity.UsersPage{}, errors.Wrapf(err, "failed to list users matching %q", schema.Search)
+	}
+
+	usersPage := entity.UsersPage{
+		Users: lo.Map(users, func(user entity.UserModel, _ int) entity.User {
+			return user.Filter()
+		}),
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+	}
+
+	return usersPage, nil
+}
+
+func (s *adminService) GetUser(ctx context.Context, userID primitive.ObjectID) (entity.User, error) {
+	user, err := s.usersRepo.FindByID(ctx, userID)
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "failed to get user[id:%q]", userID.Hex())
+	}
+
+	return user.Filter(), nil
+}
+
+func (s *adminService) SuspendUser(ctx context.Context, schema SuspendUserSchema) error {
+	if schema.AdminID == schema.UserID {
+		return entity.ErrSelfAdministration
+	}
+
+	err := s.usersRepo.Suspend(ctx, repository.SuspendUserSchema{
+		UserID:      schema.UserID,
+		SuspendedAt: time.Now(),
+		Reason:      schema.Reason,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to suspend user[id:%q]", schema.UserID.Hex())
+	}
+
+	// suspended users are rejected on every request anyway,
+	// revocation makes sure they aren't able to refresh tokens
+	err = s.jwtServ.RevokeTokens(ctx, schema.UserID.Hex())
+	if err != nil {
+		return errors.Wrapf(err, "user[id:%q]: failed to revoke tokens", schema.UserID.Hex())
+	}
+
+	return s.audit(ctx, entity.AuditEventModel{
+		Action:       entity.AuditActionUserSuspended,
+		ActorID:      schema.AdminID,
+		TargetUserID: schema.UserID,
+		Details:      map[string]string{"reason": schema.Reason},
+	})
+}
+
+func (s *adminService) UnsuspendUser(ctx context.Context, schema AdminActionSchema) error {
+	err := s.usersRepo.Unsuspend(ctx, schema.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to unsuspend user[id:%q]", schema.UserID.Hex())
+	}
+
+	return s.audit(ctx, entity.AuditEventModel{
+		Action:       entity.AuditActionUserUnsuspended,
+		ActorID:      schema.AdminID,
+		TargetUserID: schema.UserID,
+	})
+}
+
+func (s *adminService) SignOutUser(ctx context.Context, schema AdminActionSchema) error {
+	_, err := s.usersRepo.FindByID(ctx, schema.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get user[id:%q]", schema.UserID.Hex())
+	}
+
+	err = s.jwtServ.RevokeTokens(ctx, schema.UserID.Hex())
+	if err != nil {
+		return errors.Wrapf(err, "user[id:%q]: failed to revoke tokens", schema.UserID.Hex())
+	}
+
+	return s.audit(ctx, entity.AuditEventModel{
+		Action:       entity.AuditActionUserSignedOut,
+		ActorID:      schema.AdminID,
+		TargetUserID: schema.UserID,
+	})
+}
+
+func (s *adminService) ResetTwoFactor(ctx context.Context, schema AdminActionSchema) error {
+	user, err := s.usersRepo.FindByID(ctx, schema.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get user[id:%q]", schema.UserID.Hex())
+	}
+
+	if user.TwoFactor == nil {
+		return entity.ErrTwoFactorNotEnabled
+	}
+
+	err = s.usersRepo.DisableTwoFactor(ctx, schema.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "user[id:%q]: failed to disable two-factor authentication", schema.UserID.Hex())
+	}
+
+	return s.audit(ctx, entity.AuditEventModel{
+		Action:       entity.AuditActionUserTwoFactorReset,
+		ActorID:      schema.AdminID,
+		TargetUserID: schema.UserID,
+	})
+}
+
+func (s *adminService) UnlockSignIn(ctx context.Context, schema UnlockSignInSchema) error {
+	err := s.throttleServ.Unlock(ctx, ThrottleSchema{
+		Login:       schema.Login,
+		MagicLink:   schema.Login,
+		IP:          schema.IP,
+		MagicLinkIP: schema.IP,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to unlock sign in of login %q and IP %q", schema.Login, schema.IP)
+	}
+
+	details := map[string]string{}
+	if schema.Login != "" {
+		details["login"] = schema.Login
+	}
+
+	if schema.IP != "" {
+		details["ip"] = schema.IP
+	}
+
+	return s.audit(ctx, entity.AuditEventModel{
+		Action:  entity.AuditActionSignInUnlocked,
+		ActorID: schema.AdminID,
+		Details: details,
+	})
+}
+
+func (s *adminService) SetRole(ctx context.Context, schema SetRoleSchema) error {
+	if !lo.Contains(entity.Roles, schema.Role) {
+		return entity.ErrUnknownRole
+	}
+
+	// administrator can't demote themself, so there is always someone left to manage roles
+	if schema.AdminID == schema.UserID {
+		return entity.ErrSelfAdministration
+	}
+
+	user, err := s.usersRepo.FindByID(ctx, schema.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get user[id:%q]", schema.UserID.Hex())
+	}
+
+	err = s.usersRepo.SetRole(ctx, repository.SetRoleSchema{
+		UserID: schema.UserID,
+		Role:   schema.Role,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to set role of user[id:%q]", schema.UserID.Hex())
+	}
+
+	// role is checked on every request, refreshed tokens get scopes of the new role
+	return s.audit(ctx, entity.AuditEventModel{
+		Action:       entity.AuditActionUserRoleChanged,
+		ActorID:      schema.AdminID,
+		TargetUserID: schema.UserID,
+		Details:      map[string]string{"role": schema.Role, "previousRole": user.UserRole()},
+	})
+}
+
+// audit writes event of the action which was already performed
+func (s *adminService) audit(ctx context.Context, event entity.AuditEventModel) error {
+	err := s.auditServ.Record(ctx, event)
+	if err != nil {
+		return errors.Wrapf(err, "admin[id:%q]", event.ActorID.Hex())
+	}
+
+	return nil
+}
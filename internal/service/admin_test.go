@@ -0,0 +1,341 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	repoMocks "github.com/kenplix/url-shrtnr/internal/repository/mocks"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+)
+
+type adminMocks struct {
+	usersRepo    *repoMocks.UsersRepository
+	auditServ    *servMocks.AuditService
+	jwtServ      *servMocks.JWTService
+	throttleServ *servMocks.ThrottleService
+}
+
+func newTestAdminService(t *testing.T) (service.AdminService, adminMocks) {
+	t.Helper()
+
+	m := adminMocks{
+		usersRepo:    repoMocks.NewUsersRepository(t),
+		auditServ:    servMocks.NewAuditService(t),
+		jwtServ:      servMocks.NewJWTService(t),
+		throttleServ: servMocks.NewThrottleService(t),
+	}
+
+	adminServ, err := service.NewAdminService(m.usersRepo, m.auditServ, m.jwtServ, m.throttleServ)
+	require.NoErrorf(t, err, "failed to create admin service: %s", err)
+
+	return adminServ, m
+}
+
+func TestAdminService_SuspendUser(t *testing.T) {
+	adminID := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+
+	type args struct {
+		schema service.SuspendUserSchema
+	}
+
+	type ret struct {
+		err    error
+		hasErr bool
+	}
+
+	type mockBehavior func(m adminMocks)
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "self suspension",
+			args: args{
+				schema: service.SuspendUserSchema{AdminID: adminID, UserID: adminID, Reason: "test"},
+			},
+			ret: ret{
+				err:    entity.ErrSelfAdministration,
+				hasErr: true,
+			},
+			mockBehavior: func(_ adminMocks) {},
+		},
+		{
+			name: "user not found",
+			args: args{
+				schema: service.SuspendUserSchema{AdminID: adminID, UserID: userID, Reason: "spam links"},
+			},
+			ret: ret{
+				err:    entity.ErrUserNotFound,
+				hasErr: true,
+			},
+			mockBehavior: func(m adminMocks) {
+				m.usersRepo.
+					On("Suspend", mock.Anything, mock.Anything).
+					Return(entity.ErrUserNotFound)
+			},
+		},
+		{
+			name: "failed to revoke tokens",
+			args: args{
+				schema: service.SuspendUserSchema{AdminID: adminID, UserID: userID, Reason: "spam links"},
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(m adminMocks) {
+				m.usersRepo.
+					On("Suspend", mock.Anything, mock.Anything).
+					Return(nil)
+
+				m.jwtServ.
+					On("RevokeTokens", mock.Anything, userID.Hex()).
+					Return(assert.AnError)
+			},
+		},
+		{
+			name: "failed to write audit event",
+			args: args{
+				schema: service.SuspendUserSchema{AdminID: adminID, UserID: userID, Reason: "spam links"},
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(m adminMocks) {
+				m.usersRepo.
+					On("Suspend", mock.Anything, mock.Anything).
+					Return(nil)
+
+				m.jwtServ.
+					On("RevokeTokens", mock.Anything, userID.Hex()).
+					Return(nil)
+
+				m.auditServ.
+					On("Record", mock.Anything, mock.Anything).
+					Return(assert.AnError)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				schema: service.SuspendUserSchema{AdminID: adminID, UserID: userID, Reason: "spam links"},
+			},
+			ret: ret{
+				hasErr: false,
+			},
+			mockBehavior: func(m adminMocks) {
+				m.usersRepo.
+					On("Suspend", mock.Anything, mock.MatchedBy(func(schema repository.SuspendUserSchema) bool {
+						return schema.UserID == userID && schema.Reason == "spam links" && !schema.SuspendedAt.IsZero()
+					})).
+					Return(nil)
+
+				m.jwtServ.
+					On("RevokeTokens", mock.Anything, userID.Hex()).
+					Return(nil)
+
+				m.auditServ.
+					On("Record", mock.Anything, mock.MatchedBy(func(event entity.AuditEventModel) bool {
+						return event.Action == entity.AuditActionUserSuspended &&
+							event.ActorID == adminID &&
+							event.TargetUserID == userID &&
+							event.Details["reason"] == "spam links"
+					})).
+					Return(nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			adminServ, m := newTestAdminService(t)
+
+			tc.mockBehavior(m)
+
+			err := adminServ.SuspendUser(context.Background(), tc.args.schema)
+			assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+
+			if tc.ret.err != nil {
+				assert.ErrorIs(t, err, tc.ret.err)
+			}
+		})
+	}
+}
+
+func TestAdminService_ResetTwoFactor(t *testing.T) {
+	adminID := primitive.NewObjectID()
+
+	t.Parallel()
+
+	t.Run("not enabled", func(t *testing.T) {
+		adminServ, m := newTestAdminService(t)
+
+		user := entity.UserModel{ID: primitive.NewObjectID()}
+
+		m.usersRepo.
+			On("FindByID", mock.Anything, user.ID).
+			Return(user, nil)
+
+		err := adminServ.ResetTwoFactor(context.Background(), service.AdminActionSchema{AdminID: adminID, UserID: user.ID})
+		assert.ErrorIs(t, err, entity.ErrTwoFactorNotEnabled)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		adminServ, m := newTestAdminService(t)
+
+		user := entity.UserModel{ID: primitive.NewObjectID(), TwoFactor: &entity.TwoFactorModel{}}
+
+		m.usersRepo.
+			On("FindByID", mock.Anything, user.ID).
+			Return(user, nil)
+
+		m.usersRepo.
+			On("DisableTwoFactor", mock.Anything, user.ID).
+			Return(nil)
+
+		m.auditServ.
+			On("Record", mock.Anything, mock.MatchedBy(func(event entity.AuditEventModel) bool {
+				return event.Action == entity.AuditActionUserTwoFactorReset && event.TargetUserID == user.ID
+			})).
+			Return(nil)
+
+		err := adminServ.ResetTwoFactor(context.Background(), service.AdminActionSchema{AdminID: adminID, UserID: user.ID})
+		assert.NoError(t, err)
+	})
+}
+
+func TestAdminService_SetRole(t *testing.T) {
+	adminID := primitive.NewObjectID()
+
+	t.Parallel()
+
+	t.Run("unknown role", func(t *testing.T) {
+		adminServ, _ := newTestAdminService(t)
+
+		err := adminServ.SetRole(context.Background(), service.SetRoleSchema{AdminID: adminID, UserID: primitive.NewObjectID(), Role: "root"})
+		assert.ErrorIs(t, err, entity.ErrUnknownRole)
+	})
+
+	t.Run("own role", func(t *testing.T) {
+		adminServ, _ := newTestAdminService(t)
+
+		err := adminServ.SetRole(context.Background(), service.SetRoleSchema{AdminID: adminID, UserID: adminID, Role: entity.RoleUser})
+		assert.ErrorIs(t, err, entity.ErrSelfAdministration)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		adminServ, m := newTestAdminService(t)
+
+		userID := primitive.NewObjectID()
+
+		m.usersRepo.
+			On("FindByID", mock.Anything, userID).
+			Return(entity.UserModel{}, entity.ErrUserNotFound)
+
+		err := adminServ.SetRole(context.Background(), service.SetRoleSchema{AdminID: adminID, UserID: userID, Role: entity.RoleAdmin})
+		assert.ErrorIs(t, err, entity.ErrUserNotFound)
+	})
+
+	t.Run("first administrator", func(t *testing.T) {
+		adminServ, m := newTestAdminService(t)
+
+		user := entity.UserModel{ID: primitive.NewObjectID()}
+
+		m.usersRepo.
+			On("FindByID", mock.Anything, user.ID).
+			Return(user, nil)
+
+		m.usersRepo.
+			On("SetRole", mock.Anything, repository.SetRoleSchema{UserID: user.ID, Role: entity.RoleAdmin}).
+			Return(nil)
+
+		m.auditServ.
+			On("Record", mock.Anything, entity.AuditEventModel{
+				Action:       entity.AuditActionUserRoleChanged,
+				TargetUserID: user.ID,
+				Details:      map[string]string{"role": entity.RoleAdmin, "previousRole": entity.RoleUser},
+			}).
+			Return(nil)
+
+		// role is set from command line without acting administrator
+		err := adminServ.SetRole(context.Background(), service.SetRoleSchema{UserID: user.ID, Role: entity.RoleAdmin})
+		assert.NoError(t, err)
+	})
+}
+
+func TestAdminService_ListUsers(t *testing.T) {
+	testCases := []struct {
+		name    string
+		schema  service.ListUsersSchema
+		offset  int64
+		limit   int64
+		page    int
+		perPage int
+	}{
+		{
+			name:    "defaults",
+			schema:  service.ListUsersSchema{},
+			offset:  0,
+			limit:   20,
+			page:    1,
+			perPage: 20,
+		},
+		{
+			name:    "third page",
+			schema:  service.ListUsersSchema{Search: "ken", Page: 3, PerPage: 10},
+			offset:  20,
+			limit:   10,
+			page:    3,
+			perPage: 10,
+		},
+		{
+			name:    "too many per page",
+			schema:  service.ListUsersSchema{PerPage: 1000},
+			offset:  0,
+			limit:   100,
+			page:    1,
+			perPage: 100,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			adminServ, m := newTestAdminService(t)
+
+			users := []entity.UserModel{{ID: primitive.NewObjectID(), Username: "kenplix"}}
+
+			m.usersRepo.
+				On("List", mock.Anything, repository.ListUsersSchema{
+					Search: tc.schema.Search,
+					Offset: tc.offset,
+					Limit:  tc.limit,
+				}).
+				Return(users, int64(42), nil)
+
+			usersPage, err := adminServ.ListUsers(context.Background(), tc.schema)
+			require.NoError(t, err)
+
+			assert.Equal(t, entity.UsersPage{
+				Users:   []entity.User{users[0].Filter()},
+				Total:   42,
+				Page:    tc.page,
+				PerPage: tc.perPage,
+			}, usersPage)
+		})
+	}
+}
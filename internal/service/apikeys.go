@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+const (
+	apiKeyLabel      = "shrtnr"
+	apiKeyPrefixSize = 4
+	apiKeySecretSize = 32
+
+	maxAPIKeysPerUser = 20
+	// apiKeyLastUsedPrecision limits writes to the storage on every request made with API key
+	apiKeyLastUsedPrecision = time.Minute
+)
+
+type apiKeysService struct {
+	apiKeysRepo repository.APIKeysRepository
+}
+
+func NewAPIKeysService(apiKeysRepo repository.APIKeysRepository) (APIKeysService, error) {
+	if apiKeysRepo == nil {
+		return nil, errors.New("api keys repository not provided")
+	}
+
+	s := &apiKeysService{
+		apiKeysRepo: apiKeysRepo,
+	}
+
+	return s, nil
+}
+
+func (s *apiKeysService) Create(ctx context.Context, schema CreateAPIKeySchema) (entity.CreatedAPIKey, error) {
+	if missing, _ := lo.Difference(schema.Scopes, entity.DefaultScopes); len(missing) != 0 {
+		return entity.CreatedAPIKey{}, entity.ErrAPIKeyScopesNotAllowed
+	}
+
+	keys, err := s.apiKeysRepo.FindByUserID(ctx, schema.UserID)
+	if err != nil {
+		return entity.CreatedAPIKey{}, errors.Wrapf(err, "failed to get user[id:%q] api keys", schema.UserID.Hex())
+	}
+
+	if len(keys) >= maxAPIKeysPerUser {
+		return entity.CreatedAPIKey{}, entity.ErrAPIKeysLimitReached
+	}
+
+	prefix, err := randomHex(apiKeyPrefixSize)
+	if err != nil {
+		return entity.CreatedAPIKey{}, errors.Wrap(err, "failed to generate api key prefix")
+	}
+
+	secret, err := randomHex(apiKeySecretSize)
+	if err != nil {
+		return entity.CreatedAPIKey{}, errors.Wrap(err, "failed to generate api key secret")
+	}
+
+	key := entity.APIKeyModel{
+		ID:         primitive.NewObjectID(),
+		UserID:     schema.UserID,
+		Name:       schema.Name,
+		Prefix:     apiKeyLabel + "_" + prefix,
+		SecretHash: hashSecret(secret),
+		Scopes:     lo.Uniq(schema.Scopes),
+		ExpiresAt:  schema.ExpiresAt,
+		CreatedAt:  time.Now(),
+	}
+
+	err = s.apiKeysRepo.Create(ctx, key)
+	if err != nil {
+		return entity.CreatedAPIKey{}, errors.Wrapf(err, "user[id:%q]: failed to create api key", schema.UserID.Hex())
+	}
+
+	created := entity.CreatedAPIKey{
+		APIKey: key.Filter(),
+		Key:    key.Prefix + "_" + secret,
+	}
+
+	return created, nil
+}
+
+func (s *apiKeysService) List(ctx context.Context, userID primitive.ObjectID) ([]entity.APIKey, error) {
+	keys, err := s.apiKeysRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get user[id:%q] api keys", userID.Hex())
+	}
+
+	return lo.Map(keys, func(key entity.APIKeyModel, _ int) entity.APIKey {
+		return key.Filter()
+	}), nil
+}
+
+func (s *apiKeysService) Revoke(ctx context.Context, schema RevokeAPIKeySchema) error {
+	err := s.apiKeysRepo.Delete(ctx, repository.DeleteAPIKeySchema{
+		UserID: schema.UserID,
+		KeyID:  schema.KeyID,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "user[id:%q]: failed to revoke api key[id:%q]", schema.UserID.Hex(), schema.KeyID.Hex())
+	}
+
+	return nil
+}
+
+func (s *apiKeysService) Authenticate(ctx context.Context, apiKey string) (entity.APIKeyModel, error) {
+	label, rest, _ := strings.Cut(apiKey, "_")
+	prefix, secret, found := strings.Cut(rest, "_")
+
+	if label != apiKeyLabel || !found || secret == "" {
+		return entity.APIKeyModel{}, entity.ErrAPIKeyNotFound
+	}
+
+	key, err := s.apiKeysRepo.FindByPrefix(ctx, label+"_"+prefix)
+	if err != nil {
+		if errors.Is(err, entity.ErrAPIKeyNotFound) {
+			return entity.APIKeyModel{}, err
+		}
+
+		return entity.APIKeyModel{}, errors.Wrap(err, "failed to get api key")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return entity.APIKeyModel{}, entity.ErrAPIKeyNotFound
+	}
+
+	now := time.Now()
+	if key.Expired(now) {
+		return entity.APIKeyModel{}, entity.ErrAPIKeyNotFound
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyLastUsedPrecision {
+		err = s.apiKeysRepo.UpdateLastUsed(ctx, repository.UpdateAPIKeyLastUsedSchema{
+			KeyID:      key.ID,
+			LastUsedAt: now,
+		})
+		if err != nil {
+			logger := log.LoggerFromContext(ctx)
+			logger.Warn("failed to update api key last usage",
+				zap.String("keyID", key.ID.Hex()),
+				zap.Error(err),
+			)
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+
+	return key, nil
+}
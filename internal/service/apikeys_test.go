@@ -0,0 +1,217 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	repoMocks "github.com/kenplix/url-shrtnr/internal/repository/mocks"
+	"github.com/kenplix/url-shrtnr/internal/service"
+)
+
+func TestAPIKeysService_Create(t *testing.T) {
+	type args struct {
+		schema service.CreateAPIKeySchema
+	}
+
+	type ret struct {
+		err    error
+		hasErr bool
+	}
+
+	type mockBehavior func(*repoMocks.APIKeysRepository)
+
+	testCreateAPIKeySchema := func(t *testing.T, scopes ...string) service.CreateAPIKeySchema {
+		t.Helper()
+
+		return service.CreateAPIKeySchema{
+			UserID: primitive.NewObjectID(),
+			Name:   "ci deploy",
+			Scopes: scopes,
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "scopes not allowed",
+			args: args{
+				schema: testCreateAPIKeySchema(t, entity.ScopeLinksRead, entity.ScopeAdmin),
+			},
+			ret: ret{
+				err:    entity.ErrAPIKeyScopesNotAllowed,
+				hasErr: true,
+			},
+			mockBehavior: func(_ *repoMocks.APIKeysRepository) {},
+		},
+		{
+			name: "limit reached",
+			args: args{
+				schema: testCreateAPIKeySchema(t, entity.ScopeLinksRead),
+			},
+			ret: ret{
+				err:    entity.ErrAPIKeysLimitReached,
+				hasErr: true,
+			},
+			mockBehavior: func(apiKeysRepo *repoMocks.APIKeysRepository) {
+				apiKeysRepo.
+					On("FindByUserID", mock.Anything, mock.Anything).
+					Return(make([]entity.APIKeyModel, 20), nil)
+			},
+		},
+		{
+			name: "failed to create api key",
+			args: args{
+				schema: testCreateAPIKeySchema(t, entity.ScopeLinksRead),
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(apiKeysRepo *repoMocks.APIKeysRepository) {
+				apiKeysRepo.
+					On("FindByUserID", mock.Anything, mock.Anything).
+					Return(nil, nil)
+
+				apiKeysRepo.
+					On("Create", mock.Anything, mock.Anything).
+					Return(assert.AnError)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				schema: testCreateAPIKeySchema(t, entity.ScopeLinksRead, entity.ScopeLinksWrite),
+			},
+			ret: ret{
+				hasErr: false,
+			},
+			mockBehavior: func(apiKeysRepo *repoMocks.APIKeysRepository) {
+				apiKeysRepo.
+					On("FindByUserID", mock.Anything, mock.Anything).
+					Return(nil, nil)
+
+				apiKeysRepo.
+					On("Create", mock.Anything, mock.MatchedBy(func(key entity.APIKeyModel) bool {
+						return strings.HasPrefix(key.Prefix, "shrtnr_") && len(key.SecretHash) == 64
+					})).
+					Return(nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			apiKeysRepo := repoMocks.NewAPIKeysRepository(t)
+
+			apiKeysServ, err := service.NewAPIKeysService(apiKeysRepo)
+			require.NoErrorf(t, err, "failed to create api keys service: %s", err)
+
+			tc.mockBehavior(apiKeysRepo)
+
+			created, err := apiKeysServ.Create(context.Background(), tc.args.schema)
+			assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+
+			if tc.ret.err != nil {
+				assert.ErrorIs(t, err, tc.ret.err)
+			}
+
+			if !tc.ret.hasErr {
+				assert.True(t, strings.HasPrefix(created.Key, created.Prefix+"_"))
+				assert.Equal(t, tc.args.schema.Scopes, created.Scopes)
+			}
+		})
+	}
+}
+
+func TestAPIKeysService_Authenticate(t *testing.T) {
+	apiKeysRepo := repoMocks.NewAPIKeysRepository(t)
+
+	apiKeysServ, err := service.NewAPIKeysService(apiKeysRepo)
+	require.NoErrorf(t, err, "failed to create api keys service: %s", err)
+
+	var stored entity.APIKeyModel
+
+	apiKeysRepo.
+		On("FindByUserID", mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	apiKeysRepo.
+		On("Create", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			stored = args.Get(1).(entity.APIKeyModel)
+		}).
+		Return(nil)
+
+	created, err := apiKeysServ.Create(context.Background(), service.CreateAPIKeySchema{
+		UserID: primitive.NewObjectID(),
+		Name:   "ci deploy",
+		Scopes: []string{entity.ScopeLinksRead},
+	})
+	require.NoErrorf(t, err, "failed to create api key: %s", err)
+
+	apiKeysRepo.
+		On("FindByPrefix", mock.Anything, created.Prefix).
+		Return(func(context.Context, string) entity.APIKeyModel { return stored }, nil)
+
+	apiKeysRepo.
+		On("FindByPrefix", mock.Anything, mock.Anything).
+		Return(entity.APIKeyModel{}, entity.ErrAPIKeyNotFound)
+
+	apiKeysRepo.
+		On("UpdateLastUsed", mock.Anything, mock.MatchedBy(func(schema repository.UpdateAPIKeyLastUsedSchema) bool {
+			return schema.KeyID == stored.ID
+		})).
+		Return(nil).
+		Once()
+
+	t.Run("ok", func(t *testing.T) {
+		key, err := apiKeysServ.Authenticate(context.Background(), created.Key)
+		require.NoError(t, err)
+		assert.Equal(t, stored.ID, key.ID)
+		assert.NotNil(t, key.LastUsedAt)
+
+		stored.LastUsedAt = key.LastUsedAt
+	})
+
+	t.Run("last usage is not updated too often", func(t *testing.T) {
+		_, err := apiKeysServ.Authenticate(context.Background(), created.Key)
+		require.NoError(t, err)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		_, err := apiKeysServ.Authenticate(context.Background(), created.Prefix+"_0000")
+		assert.ErrorIs(t, err, entity.ErrAPIKeyNotFound)
+	})
+
+	t.Run("unknown prefix", func(t *testing.T) {
+		_, err := apiKeysServ.Authenticate(context.Background(), "shrtnr_00000000_0000")
+		assert.ErrorIs(t, err, entity.ErrAPIKeyNotFound)
+	})
+
+	t.Run("malformed key", func(t *testing.T) {
+		_, err := apiKeysServ.Authenticate(context.Background(), "malformed")
+		assert.ErrorIs(t, err, entity.ErrAPIKeyNotFound)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Second)
+		stored.ExpiresAt = &expiresAt
+
+		_, err := apiKeysServ.Authenticate(context.Background(), created.Key)
+		assert.ErrorIs(t, err, entity.ErrAPIKeyNotFound)
+	})
+}
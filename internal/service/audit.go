@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+	"github.com/kenplix/url-shrtnr/pkg/requestinfo"
+)
+
+const (
+	defaultAuditEventsPerPage = 20
+	maxAuditEventsPerPage     = 100
+)
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) (AuditService, error) {
+	if auditRepo == nil {
+		return nil, errors.New("audit repository not provided")
+	}
+
+	s := &auditService{
+		auditRepo: auditRepo,
+	}
+
+	return s, nil
+}
+
+func (s *auditService) Record(ctx context.Context, event entity.AuditEventModel) error {
+	info := requestinfo.FromContext(ctx)
+
+	event.ID = primitive.NewObjectID()
+	event.Outcome = lo.Ternary(event.Outcome == "", entity.AuditOutcomeSuccess, event.Outcome)
+	event.IP = info.IP
+	event.UserAgent = info.UserAgent
+	event.RequestID = info.RequestID
+	event.CreatedAt = time.Now()
+
+	err := s.auditRepo.Create(ctx, event)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write %q audit event", event.Action)
+	}
+
+	return nil
+}
+
+func (s *auditService) List(ctx context.Context, schema ListAuditEventsSchema) (entity.AuditEventsPage, error) {
+	page := lo.Ternary(schema.Page < 1, 1, schema.Page)
+	perPage := lo.Ternary(schema.PerPage < 1, defaultAuditEventsPerPage, lo.Min([]int{schema.PerPage, maxAuditEventsPerPage}))
+
+	events, total, err := s.auditRepo.Find(ctx, repository.FindAuditEventsSchema{
+		UserID: schema.UserID,
+		Action: schema.Action,
+		Offset: int64((page - 1) * perPage),
+		Limit:  int64(perPage),
+	})
+	if err != nil {
+		return entity.AuditEventsPage{}, errors.Wrapf(err, "failed to find audit events of %+v", schema)
+	}
+
+	eventsPage := entity.AuditEventsPage{
+		Events: lo.Map(events, func(event entity.AuditEventModel, _ int) entity.AuditEvent {
+			return event.Filter()
+		}),
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+	}
+
+	return eventsPage, nil
+}
+
+// recordAudit writes event of the action which must not fail if audit log is unavailable
+func recordAudit(ctx context.Context, auditServ AuditService, event entity.AuditEventModel) {
+	if err := auditServ.Record(ctx, event); err != nil {
+		logger := log.LoggerFromContext(ctx)
+		logger.Error("failed to record audit event",
+			zap.String("action", event.Action),
+			zap.String("actorID", event.ActorID.Hex()),
+			zap.Error(err),
+		)
+	}
+}
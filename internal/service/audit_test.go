@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+	"github.com/kenplix/url-shrtnr/pkg/requestinfo"
+)
+
+// testAuditService accepts any events, it is used by tests which don't check auditing
+func testAuditService(t *testing.T) *servMocks.AuditService {
+	t.Helper()
+
+	auditServ := servMocks.NewAuditService(t)
+	auditServ.
+		On("Record", mock.Anything, mock.Anything).
+		Return(nil).
+		Maybe()
+
+	return auditServ
+}
+
+func TestAuditService(t *testing.T) {
+	ctx := context.Background()
+
+	repos, err := repository.New(ctx, repository.Config{
+		Use:    "filedb",
+		FileDB: repository.FileDBConfig{Path: t.TempDir()},
+	})
+	require.NoErrorf(t, err, "failed to create repositories: %s", err)
+
+	t.Cleanup(func() {
+		_ = repos.Close(ctx)
+	})
+
+	auditServ, err := service.NewAuditService(repos.Audit)
+	require.NoErrorf(t, err, "failed to create audit service: %s", err)
+
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+
+	reqctx := requestinfo.ContextWithInfo(ctx, requestinfo.Info{
+		IP:        "203.0.113.7",
+		UserAgent: "Mozilla/5.0",
+		RequestID: "3b1f6c0e",
+	})
+
+	events := []entity.AuditEventModel{
+		{Action: entity.AuditActionSignIn, ActorID: alice},
+		{Action: entity.AuditActionSignIn, Outcome: entity.AuditOutcomeFailure, Details: map[string]string{"login": "bob"}},
+		{Action: entity.AuditActionSignIn, ActorID: bob},
+		{Action: entity.AuditActionUserSuspended, ActorID: alice, TargetUserID: bob},
+	}
+
+	for _, event := range events {
+		require.NoError(t, auditServ.Record(reqctx, event))
+	}
+
+	t.Run("request info", func(t *testing.T) {
+		page, err := auditServ.List(ctx, service.ListAuditEventsSchema{UserID: alice, Action: entity.AuditActionSignIn})
+		require.NoError(t, err)
+		require.Len(t, page.Events, 1)
+
+		event := page.Events[0]
+		assert.Equal(t, entity.AuditOutcomeSuccess, event.Outcome)
+		assert.Equal(t, "203.0.113.7", event.IP)
+		assert.Equal(t, "Mozilla/5.0", event.UserAgent)
+		assert.Equal(t, "3b1f6c0e", event.RequestID)
+		assert.Nil(t, event.TargetUserID)
+	})
+
+	t.Run("events of the user", func(t *testing.T) {
+		page, err := auditServ.List(ctx, service.ListAuditEventsSchema{UserID: bob})
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(2), page.Total)
+		assert.Equal(t, []string{entity.AuditActionUserSuspended, entity.AuditActionSignIn}, []string{
+			page.Events[0].Action,
+			page.Events[1].Action,
+		}, "newest events must be first")
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		page, err := auditServ.List(ctx, service.ListAuditEventsSchema{Page: 2, PerPage: 3})
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(4), page.Total)
+		require.Len(t, page.Events, 1)
+		assert.Equal(t, alice, *page.Events[0].ActorID)
+	})
+}
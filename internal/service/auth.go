@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/go-redis/redis/v9"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
 
 	"github.com/pkg/errors"
 
@@ -14,20 +14,29 @@ import (
 	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
 	"github.com/kenplix/url-shrtnr/internal/repository"
 	"github.com/kenplix/url-shrtnr/pkg/hash"
+	"github.com/kenplix/url-shrtnr/pkg/log"
 )
 
 type authService struct {
-	cache      *redis.Client
-	usersRepo  repository.UsersRepository
-	hasherServ hash.HasherService
-	jwtServ    JWTService
+	cache         Cache
+	usersRepo     repository.UsersRepository
+	hasherServ    hash.HasherService
+	jwtServ       JWTService
+	twoFactorServ TwoFactorService
+	throttleServ  ThrottleService
+	auditServ     AuditService
+	profileServ   ProfileService
 }
 
 func NewAuthService(
-	cache *redis.Client,
+	cache Cache,
 	usersRepo repository.UsersRepository,
 	hasherServ hash.HasherService,
 	jwtServ JWTService,
+	twoFactorServ TwoFactorService,
+	throttleServ ThrottleService,
+	auditServ AuditService,
+	profileServ ProfileService,
 ) (AuthService, error) {
 	if cache == nil {
 		return nil, errors.New("cache not provided")
@@ -45,11 +54,31 @@ func NewAuthService(
 		return nil, errors.New("jwt service not provided")
 	}
 
+	if twoFactorServ == nil {
+		return nil, errors.New("two-factor service not provided")
+	}
+
+	if throttleServ == nil {
+		return nil, errors.New("throttle service not provided")
+	}
+
+	if auditServ == nil {
+		return nil, errors.New("audit service not provided")
+	}
+
+	if profileServ == nil {
+		return nil, errors.New("profile service not provided")
+	}
+
 	s := &authService{
-		cache:      cache,
-		usersRepo:  usersRepo,
-		hasherServ: hasherServ,
-		jwtServ:    jwtServ,
+		cache:         cache,
+		usersRepo:     usersRepo,
+		hasherServ:    hasherServ,
+		jwtServ:       jwtServ,
+		twoFactorServ: twoFactorServ,
+		throttleServ:  throttleServ,
+		auditServ:     auditServ,
+		profileServ:   profileServ,
 	}
 
 	return s, nil
@@ -69,8 +98,10 @@ func (s *authService) SignUp(ctx context.Context, schema UserSignUpSchema) error
 		return errors.Wrapf(err, "failed to find user[email:%q]", schema.Email)
 	}
 
-	_, err = s.usersRepo.FindByUsername(ctx, schema.Username)
-	if err == nil {
+	available, err := s.profileServ.IsUsernameAvailable(ctx, schema.Username)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check availability of %q username", schema.Username)
+	} else if !available {
 		return &entity.ValidationError{
 			CoreError: entity.CoreError{
 				Code:    errorcode.AlreadyExists,
@@ -78,8 +109,6 @@ func (s *authService) SignUp(ctx context.Context, schema UserSignUpSchema) error
 			},
 			Field: "username",
 		}
-	} else if err != nil && !errors.Is(err, entity.ErrUserNotFound) {
-		return errors.Wrapf(err, "failed to find user[username:%q]", schema.Username)
 	}
 
 	passwordHash, err := s.hasherServ.HashPassword(schema.Password)
@@ -90,6 +119,7 @@ func (s *authService) SignUp(ctx context.Context, schema UserSignUpSchema) error
 	now := time.Now()
 
 	user := entity.UserModel{
+		ID:           primitive.NewObjectID(),
 		Username:     schema.Username,
 		Email:        schema.Email,
 		PasswordHash: passwordHash,
@@ -102,32 +132,94 @@ func (s *authService) SignUp(ctx context.Context, schema UserSignUpSchema) error
 		return errors.Wrapf(err, "failed to create %+v user", user)
 	}
 
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionSignUp,
+		ActorID: user.ID,
+	})
+
 	return nil
 }
 
 func (s *authService) SignIn(ctx context.Context, schema UserSignInSchema) (entity.Tokens, error) {
+	throttleSchema := ThrottleSchema{Login: schema.Login, IP: schema.IP}
+
+	err := s.throttleServ.Reserve(ctx, throttleSchema)
+	if err != nil {
+		var tooManyAttemptsErr *entity.TooManyAttemptsError
+		if errors.As(err, &tooManyAttemptsErr) {
+			s.auditSignInFailure(ctx, primitive.NilObjectID, schema.Login, "too_many_attempts")
+			return entity.Tokens{}, err
+		}
+
+		return entity.Tokens{}, errors.Wrapf(err, "failed to reserve sign in attempt of %+v", throttleSchema)
+	}
+
 	user, err := s.usersRepo.FindByLogin(ctx, schema.Login)
 	if err != nil {
 		if errors.Is(err, entity.ErrUserNotFound) {
-			return entity.Tokens{}, entity.ErrIncorrectCredentials
+			s.auditSignInFailure(ctx, primitive.NilObjectID, schema.Login, "unknown_login")
+			return entity.Tokens{}, s.failSignIn(ctx, throttleSchema)
 		}
 
 		return entity.Tokens{}, errors.Wrapf(err, "failed to find user[login:%q]", schema.Login)
 	}
 
 	if user.SuspendedAt != nil {
+		s.auditSignInFailure(ctx, user.ID, schema.Login, "suspended")
 		return entity.Tokens{}, &entity.SuspendedUserError{UserID: user.ID.Hex()}
 	}
 
 	if ok := s.hasherServ.VerifyPassword(schema.Password, user.PasswordHash); !ok {
-		return entity.Tokens{}, entity.ErrIncorrectCredentials
+		s.auditSignInFailure(ctx, user.ID, schema.Login, "incorrect_password")
+		return entity.Tokens{}, s.failSignIn(ctx, throttleSchema)
 	}
 
-	tokens, err := s.jwtServ.CreateTokens(ctx, user.ID.Hex())
+	err = s.throttleServ.Release(ctx, ThrottleSchema{IP: schema.IP})
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "failed to release sign in attempt of IP %q", schema.IP)
+	}
+
+	err = s.throttleServ.Reset(ctx, schema.Login)
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "failed to reset sign in attempts of login %q", schema.Login)
+	}
+
+	if s.hasherServ.NeedsRehash(user.PasswordHash) {
+		s.rehashPassword(ctx, user.ID, schema.Password)
+	}
+
+	return startSession(ctx, s.jwtServ, s.twoFactorServ, s.auditServ, user, signInMethodPassword)
+}
+
+func (s *authService) SignInMFA(ctx context.Context, schema UserSignInMFASchema) (entity.Tokens, error) {
+	user, err := s.twoFactorServ.VerifyChallenge(ctx, VerifyMFAChallengeSchema{
+		MFAToken: schema.MFAToken,
+		Code:     schema.Code,
+	})
+	if err != nil {
+		if errors.Is(err, entity.ErrIncorrectTwoFactorCode) {
+			s.auditSignInFailure(ctx, user.ID, "", "incorrect_two_factor_code")
+		}
+
+		return entity.Tokens{}, err
+	}
+
+	if user.SuspendedAt != nil {
+		s.auditSignInFailure(ctx, user.ID, "", "suspended")
+		return entity.Tokens{}, &entity.SuspendedUserError{UserID: user.ID.Hex()}
+	}
+
+	tokens, err := s.jwtServ.CreateTokens(ctx, user.ID.Hex(), user.Scopes())
 	if err != nil {
 		return entity.Tokens{}, errors.Wrapf(err, "user[id:%q]: failed to create tokens", user.ID.Hex())
 	}
 
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionSignIn,
+		ActorID: user.ID,
+		Details: map[string]string{"method": signInMethodMFA},
+	})
+
 	return tokens, nil
 }
 
@@ -135,12 +227,130 @@ func (s *authService) SignOut(ctx context.Context, userID primitive.ObjectID) er
 	userIDHex := userID.Hex()
 	tokenKey := tokenCacheKey(userIDHex)
 
-	val, err := s.cache.Del(ctx, tokenKey).Result()
+	val, err := s.cache.Del(ctx, tokenKey)
 	if err != nil {
 		return errors.Wrapf(err, "cache: failed to delete %q key", tokenKey)
 	} else if val == 0 {
 		return fmt.Errorf("user[id:%q]: already signed out", userIDHex)
 	}
 
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionSignOut,
+		ActorID: userID,
+	})
+
 	return nil
 }
+
+func (s *authService) RefreshTokens(ctx context.Context, userID primitive.ObjectID) (entity.Tokens, error) {
+	// user is loaded again, so changed role and suspension take effect on the next refresh
+	user, err := s.usersRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, entity.ErrUserNotFound) {
+			return entity.Tokens{}, err
+		}
+
+		return entity.Tokens{}, errors.Wrapf(err, "failed to get user[id:%q]", userID.Hex())
+	}
+
+	if user.SuspendedAt != nil {
+		return entity.Tokens{}, &entity.SuspendedUserError{UserID: user.ID.Hex()}
+	}
+
+	if user.DeletionScheduledAt != nil {
+		return entity.Tokens{}, entity.ErrDeletionScheduled
+	}
+
+	tokens, err := s.jwtServ.CreateTokens(ctx, user.ID.Hex(), user.Scopes())
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "user[id:%q]: failed to create tokens", user.ID.Hex())
+	}
+
+	return tokens, nil
+}
+
+// auditSignInFailure records failed sign in attempt, userID is empty when login doesn't match any user
+func (s *authService) auditSignInFailure(ctx context.Context, userID primitive.ObjectID, login, reason string) {
+	details := map[string]string{"reason": reason}
+	if login != "" {
+		details["login"] = login
+	}
+
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionSignIn,
+		Outcome: entity.AuditOutcomeFailure,
+		ActorID: userID,
+		Details: details,
+	})
+}
+
+// Sign in methods recorded in the audit log
+const (
+	signInMethodPassword  = "password"
+	signInMethodMFA       = "mfa"
+	signInMethodMagicLink = "magic_link"
+	signInMethodOIDC      = "oidc"
+)
+
+// startSession returns tokens pair of the authenticated user or *entity.MFARequiredError
+// if user has to pass the second authentication factor first
+func startSession(
+	ctx context.Context,
+	jwtServ JWTService,
+	twoFactorServ TwoFactorService,
+	auditServ AuditService,
+	user entity.UserModel,
+	method string,
+) (entity.Tokens, error) {
+	if user.TwoFactor != nil {
+		challenge, err := twoFactorServ.CreateChallenge(ctx, user.ID)
+		if err != nil {
+			return entity.Tokens{}, errors.Wrapf(err, "user[id:%q]: failed to create mfa challenge", user.ID.Hex())
+		}
+
+		return entity.Tokens{}, &entity.MFARequiredError{UserID: user.ID.Hex(), Challenge: challenge}
+	}
+
+	tokens, err := jwtServ.CreateTokens(ctx, user.ID.Hex(), user.Scopes())
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "user[id:%q]: failed to create tokens", user.ID.Hex())
+	}
+
+	recordAudit(ctx, auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionSignIn,
+		ActorID: user.ID,
+		Details: map[string]string{"method": method},
+	})
+
+	return tokens, nil
+}
+
+// failSignIn registers failed sign in attempt and returns error that should be returned to the client
+func (s *authService) failSignIn(ctx context.Context, schema ThrottleSchema) error {
+	err := s.throttleServ.Fail(ctx, schema)
+	if err != nil {
+		return errors.Wrapf(err, "failed to register failed sign in attempt of %+v", schema)
+	}
+
+	return entity.ErrIncorrectCredentials
+}
+
+// rehashPassword replaces outdated password hash of the user. Failure is not critical
+// for the sign in, so it's only logged and the upgrade is retried on the next sign in
+func (s *authService) rehashPassword(ctx context.Context, userID primitive.ObjectID, password string) {
+	logger := log.LoggerFromContext(ctx).With(zap.String("userID", userID.Hex()))
+
+	passwordHash, err := s.hasherServ.HashPassword(password)
+	if err != nil {
+		logger.Warn("failed to rehash password", zap.Error(err))
+		return
+	}
+
+	err = s.usersRepo.ChangePassword(ctx, repository.ChangePasswordSchema{
+		UserID:          userID,
+		NewPasswordHash: passwordHash,
+	})
+	if err != nil {
+		logger.Warn("failed to store rehashed password", zap.Error(err))
+	}
+}
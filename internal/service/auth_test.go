@@ -8,17 +8,16 @@ import (
 
 	"github.com/stretchr/testify/require"
 
-	"github.com/go-redis/redis/v9"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/kenplix/url-shrtnr/internal/service"
 
 	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
 	repoMocks "github.com/kenplix/url-shrtnr/internal/repository/mocks"
 	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
 	hashMocks "github.com/kenplix/url-shrtnr/pkg/hash/mocks"
 
-	"github.com/alicebob/miniredis/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -32,7 +31,7 @@ func TestAuthService_SignUp(t *testing.T) {
 		hasErr bool
 	}
 
-	type mockBehavior func(*repoMocks.UsersRepository, *hashMocks.HasherService)
+	type mockBehavior func(*repoMocks.UsersRepository, *servMocks.ProfileService, *hashMocks.HasherService)
 
 	testUserSignUpSchema := func(t *testing.T) service.UserSignUpSchema {
 		t.Helper()
@@ -58,7 +57,7 @@ func TestAuthService_SignUp(t *testing.T) {
 			ret: ret{
 				hasErr: true,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, _ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, _ *servMocks.ProfileService, _ *hashMocks.HasherService) {
 				usersRepo.
 					On("FindByEmail", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, nil)
@@ -72,46 +71,46 @@ func TestAuthService_SignUp(t *testing.T) {
 			ret: ret{
 				hasErr: true,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, _ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, _ *servMocks.ProfileService, _ *hashMocks.HasherService) {
 				usersRepo.
 					On("FindByEmail", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, assert.AnError)
 			},
 		},
 		{
-			name: "user with such username already exists",
+			name: "username is taken or reserved",
 			args: args{
 				schema: testUserSignUpSchema(t),
 			},
 			ret: ret{
 				hasErr: true,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, _ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, profileServ *servMocks.ProfileService, _ *hashMocks.HasherService) {
 				usersRepo.
 					On("FindByEmail", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, entity.ErrUserNotFound)
 
-				usersRepo.
-					On("FindByUsername", mock.Anything, mock.Anything).
-					Return(entity.UserModel{}, nil)
+				profileServ.
+					On("IsUsernameAvailable", mock.Anything, "kenplix").
+					Return(false, nil)
 			},
 		},
 		{
-			name: "failed to find user by username",
+			name: "failed to check username availability",
 			args: args{
 				schema: testUserSignUpSchema(t),
 			},
 			ret: ret{
 				hasErr: true,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, _ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, profileServ *servMocks.ProfileService, _ *hashMocks.HasherService) {
 				usersRepo.
 					On("FindByEmail", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, entity.ErrUserNotFound)
 
-				usersRepo.
-					On("FindByUsername", mock.Anything, mock.Anything).
-					Return(entity.UserModel{}, assert.AnError)
+				profileServ.
+					On("IsUsernameAvailable", mock.Anything, mock.Anything).
+					Return(false, assert.AnError)
 			},
 		},
 		{
@@ -129,14 +128,14 @@ func TestAuthService_SignUp(t *testing.T) {
 			ret: ret{
 				hasErr: true,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, profileServ *servMocks.ProfileService, hasherServ *hashMocks.HasherService) {
 				usersRepo.
 					On("FindByEmail", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, entity.ErrUserNotFound)
 
-				usersRepo.
-					On("FindByUsername", mock.Anything, mock.Anything).
-					Return(entity.UserModel{}, entity.ErrUserNotFound)
+				profileServ.
+					On("IsUsernameAvailable", mock.Anything, mock.Anything).
+					Return(true, nil)
 
 				hasherServ.
 					On("HashPassword", mock.Anything).
@@ -151,14 +150,14 @@ func TestAuthService_SignUp(t *testing.T) {
 			ret: ret{
 				hasErr: true,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, profileServ *servMocks.ProfileService, hasherServ *hashMocks.HasherService) {
 				usersRepo.
 					On("FindByEmail", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, entity.ErrUserNotFound)
 
-				usersRepo.
-					On("FindByUsername", mock.Anything, mock.Anything).
-					Return(entity.UserModel{}, entity.ErrUserNotFound)
+				profileServ.
+					On("IsUsernameAvailable", mock.Anything, mock.Anything).
+					Return(true, nil)
 
 				hasherServ.
 					On("HashPassword", mock.Anything).
@@ -177,14 +176,14 @@ func TestAuthService_SignUp(t *testing.T) {
 			ret: ret{
 				hasErr: false,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, profileServ *servMocks.ProfileService, hasherServ *hashMocks.HasherService) {
 				usersRepo.
 					On("FindByEmail", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, entity.ErrUserNotFound)
 
-				usersRepo.
-					On("FindByUsername", mock.Anything, mock.Anything).
-					Return(entity.UserModel{}, entity.ErrUserNotFound)
+				profileServ.
+					On("IsUsernameAvailable", mock.Anything, mock.Anything).
+					Return(true, nil)
 
 				hasherServ.
 					On("HashPassword", mock.Anything).
@@ -201,26 +200,24 @@ func TestAuthService_SignUp(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			var (
-				redisServ = miniredis.RunT(t)
-				cache     = redis.NewClient(&redis.Options{
-					Addr: redisServ.Addr(),
-				})
-			)
-
-			var (
-				usersRepo  = repoMocks.NewUsersRepository(t)
-				hasherServ = hashMocks.NewHasherService(t)
-				jwtServ    = servMocks.NewJWTService(t)
-			)
+			runWithCaches(t, func(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+				var (
+					usersRepo     = repoMocks.NewUsersRepository(t)
+					hasherServ    = hashMocks.NewHasherService(t)
+					jwtServ       = servMocks.NewJWTService(t)
+					twoFactorServ = servMocks.NewTwoFactorService(t)
+					throttleServ  = servMocks.NewThrottleService(t)
+					profileServ   = servMocks.NewProfileService(t)
+				)
 
-			authServ, err := service.NewAuthService(cache, usersRepo, hasherServ, jwtServ)
-			require.NoErrorf(t, err, "failed to create auth service: %s", err)
+				authServ, err := service.NewAuthService(cache, usersRepo, hasherServ, jwtServ, twoFactorServ, throttleServ, testAuditService(t), profileServ)
+				require.NoErrorf(t, err, "failed to create auth service: %s", err)
 
-			tc.mockBehavior(usersRepo, hasherServ)
+				tc.mockBehavior(usersRepo, profileServ, hasherServ)
 
-			err = authServ.SignUp(context.Background(), tc.args.schema)
-			assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+				err = authServ.SignUp(context.Background(), tc.args.schema)
+				assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+			})
 		})
 	}
 }
@@ -239,6 +236,8 @@ func TestAuthService_SignIn(t *testing.T) {
 		*repoMocks.UsersRepository,
 		*hashMocks.HasherService,
 		*servMocks.JWTService,
+		*servMocks.TwoFactorService,
+		*servMocks.ThrottleService,
 	)
 
 	testUserSignInSchema := func(t *testing.T) service.UserSignInSchema {
@@ -247,6 +246,7 @@ func TestAuthService_SignIn(t *testing.T) {
 		return service.UserSignInSchema{
 			Login:    "tolstoi.job@gmail.com",
 			Password: "1wE$Rty2",
+			IP:       "192.0.2.1",
 		}
 	}
 
@@ -256,6 +256,46 @@ func TestAuthService_SignIn(t *testing.T) {
 		ret          ret
 		mockBehavior mockBehavior
 	}{
+		{
+			name: "too many attempts",
+			args: args{
+				schema: testUserSignInSchema(t),
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(
+				_ *repoMocks.UsersRepository,
+				_ *hashMocks.HasherService,
+				_ *servMocks.JWTService,
+				_ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
+			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(&entity.TooManyAttemptsError{RetryAfter: time.Minute})
+			},
+		},
+		{
+			name: "failed to check attempts",
+			args: args{
+				schema: testUserSignInSchema(t),
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(
+				_ *repoMocks.UsersRepository,
+				_ *hashMocks.HasherService,
+				_ *servMocks.JWTService,
+				_ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
+			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(assert.AnError)
+			},
+		},
 		{
 			name: "user with such credentials not found",
 			args: args{
@@ -268,10 +308,20 @@ func TestAuthService_SignIn(t *testing.T) {
 				usersRepo *repoMocks.UsersRepository,
 				_ *hashMocks.HasherService,
 				_ *servMocks.JWTService,
+				_ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
 			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(nil)
+
 				usersRepo.
 					On("FindByLogin", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, entity.ErrUserNotFound)
+
+				throttleServ.
+					On("Fail", mock.Anything, mock.Anything).
+					Return(nil)
 			},
 		},
 		{
@@ -286,7 +336,13 @@ func TestAuthService_SignIn(t *testing.T) {
 				usersRepo *repoMocks.UsersRepository,
 				_ *hashMocks.HasherService,
 				_ *servMocks.JWTService,
+				_ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
 			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(nil)
+
 				usersRepo.
 					On("FindByLogin", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, assert.AnError)
@@ -304,7 +360,13 @@ func TestAuthService_SignIn(t *testing.T) {
 				usersRepo *repoMocks.UsersRepository,
 				_ *hashMocks.HasherService,
 				_ *servMocks.JWTService,
+				_ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
 			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(nil)
+
 				suspendedAt := time.Now()
 
 				usersRepo.
@@ -324,7 +386,13 @@ func TestAuthService_SignIn(t *testing.T) {
 				usersRepo *repoMocks.UsersRepository,
 				hasherServ *hashMocks.HasherService,
 				_ *servMocks.JWTService,
+				_ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
 			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(nil)
+
 				usersRepo.
 					On("FindByLogin", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, nil)
@@ -332,6 +400,98 @@ func TestAuthService_SignIn(t *testing.T) {
 				hasherServ.
 					On("VerifyPassword", mock.Anything, mock.Anything).
 					Return(false)
+
+				throttleServ.
+					On("Fail", mock.Anything, mock.Anything).
+					Return(nil)
+			},
+		},
+		{
+			name: "failed to create mfa challenge",
+			args: args{
+				schema: testUserSignInSchema(t),
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(
+				usersRepo *repoMocks.UsersRepository,
+				hasherServ *hashMocks.HasherService,
+				_ *servMocks.JWTService,
+				twoFactorServ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
+			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(nil)
+
+				usersRepo.
+					On("FindByLogin", mock.Anything, mock.Anything).
+					Return(entity.UserModel{TwoFactor: &entity.TwoFactorModel{}}, nil)
+
+				hasherServ.
+					On("VerifyPassword", mock.Anything, mock.Anything).
+					Return(true)
+
+				throttleServ.
+					On("Release", mock.Anything, mock.Anything).
+					Return(nil)
+
+				throttleServ.
+					On("Reset", mock.Anything, mock.Anything).
+					Return(nil)
+
+				hasherServ.
+					On("NeedsRehash", mock.Anything).
+					Return(false)
+
+				twoFactorServ.
+					On("CreateChallenge", mock.Anything, mock.Anything).
+					Return(entity.MFAChallenge{}, assert.AnError)
+			},
+		},
+		{
+			name: "mfa required",
+			args: args{
+				schema: testUserSignInSchema(t),
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(
+				usersRepo *repoMocks.UsersRepository,
+				hasherServ *hashMocks.HasherService,
+				_ *servMocks.JWTService,
+				twoFactorServ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
+			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(nil)
+
+				usersRepo.
+					On("FindByLogin", mock.Anything, mock.Anything).
+					Return(entity.UserModel{TwoFactor: &entity.TwoFactorModel{}}, nil)
+
+				hasherServ.
+					On("VerifyPassword", mock.Anything, mock.Anything).
+					Return(true)
+
+				throttleServ.
+					On("Release", mock.Anything, mock.Anything).
+					Return(nil)
+
+				throttleServ.
+					On("Reset", mock.Anything, mock.Anything).
+					Return(nil)
+
+				hasherServ.
+					On("NeedsRehash", mock.Anything).
+					Return(false)
+
+				twoFactorServ.
+					On("CreateChallenge", mock.Anything, mock.Anything).
+					Return(entity.MFAChallenge{MFAToken: "<mfa token>"}, nil)
 			},
 		},
 		{
@@ -346,7 +506,13 @@ func TestAuthService_SignIn(t *testing.T) {
 				usersRepo *repoMocks.UsersRepository,
 				hasherServ *hashMocks.HasherService,
 				jwtServ *servMocks.JWTService,
+				_ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
 			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(nil)
+
 				usersRepo.
 					On("FindByLogin", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, nil)
@@ -355,11 +521,129 @@ func TestAuthService_SignIn(t *testing.T) {
 					On("VerifyPassword", mock.Anything, mock.Anything).
 					Return(true)
 
+				throttleServ.
+					On("Release", mock.Anything, mock.Anything).
+					Return(nil)
+
+				throttleServ.
+					On("Reset", mock.Anything, mock.Anything).
+					Return(nil)
+
+				hasherServ.
+					On("NeedsRehash", mock.Anything).
+					Return(false)
+
 				jwtServ.
-					On("CreateTokens", mock.Anything, mock.Anything).
+					On("CreateTokens", mock.Anything, mock.Anything, mock.Anything).
 					Return(entity.Tokens{}, assert.AnError)
 			},
 		},
+		{
+			name: "outdated password hash upgraded",
+			args: args{
+				schema: testUserSignInSchema(t),
+			},
+			ret: ret{
+				hasErr: false,
+			},
+			mockBehavior: func(
+				usersRepo *repoMocks.UsersRepository,
+				hasherServ *hashMocks.HasherService,
+				jwtServ *servMocks.JWTService,
+				_ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
+			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(nil)
+
+				usersRepo.
+					On("FindByLogin", mock.Anything, mock.Anything).
+					Return(entity.UserModel{PasswordHash: "<outdated password hash>"}, nil)
+
+				hasherServ.
+					On("VerifyPassword", mock.Anything, mock.Anything).
+					Return(true)
+
+				throttleServ.
+					On("Release", mock.Anything, mock.Anything).
+					Return(nil)
+
+				throttleServ.
+					On("Reset", mock.Anything, mock.Anything).
+					Return(nil)
+
+				hasherServ.
+					On("NeedsRehash", "<outdated password hash>").
+					Return(true)
+
+				hasherServ.
+					On("HashPassword", mock.Anything).
+					Return("<password hash>", nil)
+
+				usersRepo.
+					On("ChangePassword", mock.Anything, mock.MatchedBy(func(schema repository.ChangePasswordSchema) bool {
+						return schema.NewPasswordHash == "<password hash>"
+					})).
+					Return(nil)
+
+				jwtServ.
+					On("CreateTokens", mock.Anything, mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, nil)
+			},
+		},
+		{
+			name: "failed password hash upgrade does not prevent sign in",
+			args: args{
+				schema: testUserSignInSchema(t),
+			},
+			ret: ret{
+				hasErr: false,
+			},
+			mockBehavior: func(
+				usersRepo *repoMocks.UsersRepository,
+				hasherServ *hashMocks.HasherService,
+				jwtServ *servMocks.JWTService,
+				_ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
+			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(nil)
+
+				usersRepo.
+					On("FindByLogin", mock.Anything, mock.Anything).
+					Return(entity.UserModel{}, nil)
+
+				hasherServ.
+					On("VerifyPassword", mock.Anything, mock.Anything).
+					Return(true)
+
+				throttleServ.
+					On("Release", mock.Anything, mock.Anything).
+					Return(nil)
+
+				throttleServ.
+					On("Reset", mock.Anything, mock.Anything).
+					Return(nil)
+
+				hasherServ.
+					On("NeedsRehash", mock.Anything).
+					Return(true)
+
+				hasherServ.
+					On("HashPassword", mock.Anything).
+					Return("<password hash>", nil)
+
+				usersRepo.
+					On("ChangePassword", mock.Anything, mock.Anything).
+					Return(assert.AnError)
+
+				jwtServ.
+					On("CreateTokens", mock.Anything, mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, nil)
+			},
+		},
 		{
 			name: "ok",
 			args: args{
@@ -372,7 +656,13 @@ func TestAuthService_SignIn(t *testing.T) {
 				usersRepo *repoMocks.UsersRepository,
 				hasherServ *hashMocks.HasherService,
 				jwtServ *servMocks.JWTService,
+				_ *servMocks.TwoFactorService,
+				throttleServ *servMocks.ThrottleService,
 			) {
+				throttleServ.
+					On("Reserve", mock.Anything, mock.Anything).
+					Return(nil)
+
 				usersRepo.
 					On("FindByLogin", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, nil)
@@ -381,8 +671,20 @@ func TestAuthService_SignIn(t *testing.T) {
 					On("VerifyPassword", mock.Anything, mock.Anything).
 					Return(true)
 
+				throttleServ.
+					On("Release", mock.Anything, mock.Anything).
+					Return(nil)
+
+				throttleServ.
+					On("Reset", mock.Anything, mock.Anything).
+					Return(nil)
+
+				hasherServ.
+					On("NeedsRehash", mock.Anything).
+					Return(false)
+
 				jwtServ.
-					On("CreateTokens", mock.Anything, mock.Anything).
+					On("CreateTokens", mock.Anything, mock.Anything, mock.Anything).
 					Return(entity.Tokens{}, nil)
 			},
 		},
@@ -392,31 +694,188 @@ func TestAuthService_SignIn(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			var (
-				redisServ = miniredis.RunT(t)
-				cache     = redis.NewClient(&redis.Options{
-					Addr: redisServ.Addr(),
-				})
-			)
+			runWithCaches(t, func(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+				var (
+					usersRepo     = repoMocks.NewUsersRepository(t)
+					hasherServ    = hashMocks.NewHasherService(t)
+					jwtServ       = servMocks.NewJWTService(t)
+					twoFactorServ = servMocks.NewTwoFactorService(t)
+					throttleServ  = servMocks.NewThrottleService(t)
+				)
 
-			var (
-				usersRepo  = repoMocks.NewUsersRepository(t)
-				hasherServ = hashMocks.NewHasherService(t)
-				jwtServ    = servMocks.NewJWTService(t)
-			)
+				authServ, err := service.NewAuthService(cache, usersRepo, hasherServ, jwtServ, twoFactorServ, throttleServ, testAuditService(t), servMocks.NewProfileService(t))
+				require.NoErrorf(t, err, "failed to create auth service: %s", err)
 
-			authServ, err := service.NewAuthService(cache, usersRepo, hasherServ, jwtServ)
-			require.NoErrorf(t, err, "failed to create auth service: %s", err)
+				tc.mockBehavior(usersRepo, hasherServ, jwtServ, twoFactorServ, throttleServ)
+
+				tokens, err := authServ.SignIn(context.Background(), tc.args.schema)
+				assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+				assert.Equal(t, tc.ret.tokens, tokens)
+			})
+		})
+	}
+}
+
+func TestAuthService_SignInMFA(t *testing.T) {
+	type args struct {
+		schema service.UserSignInMFASchema
+	}
+
+	type ret struct {
+		tokens entity.Tokens
+		hasErr bool
+	}
+
+	type mockBehavior func(*servMocks.JWTService, *servMocks.TwoFactorService)
 
-			tc.mockBehavior(usersRepo, hasherServ, jwtServ)
+	testUserSignInMFASchema := func(t *testing.T) service.UserSignInMFASchema {
+		t.Helper()
+
+		return service.UserSignInMFASchema{
+			MFAToken: "<mfa token>",
+			Code:     "123456",
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "failed to verify challenge",
+			args: args{
+				schema: testUserSignInMFASchema(t),
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(_ *servMocks.JWTService, twoFactorServ *servMocks.TwoFactorService) {
+				twoFactorServ.
+					On("VerifyChallenge", mock.Anything, mock.Anything).
+					Return(entity.UserModel{}, entity.ErrIncorrectTwoFactorCode)
+			},
+		},
+		{
+			name: "suspended user",
+			args: args{
+				schema: testUserSignInMFASchema(t),
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(_ *servMocks.JWTService, twoFactorServ *servMocks.TwoFactorService) {
+				suspendedAt := time.Now()
 
-			tokens, err := authServ.SignIn(context.Background(), tc.args.schema)
-			assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
-			assert.Equal(t, tc.ret.tokens, tokens)
+				twoFactorServ.
+					On("VerifyChallenge", mock.Anything, mock.Anything).
+					Return(entity.UserModel{SuspendedAt: &suspendedAt}, nil)
+			},
+		},
+		{
+			name: "failed to create tokens",
+			args: args{
+				schema: testUserSignInMFASchema(t),
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(jwtServ *servMocks.JWTService, twoFactorServ *servMocks.TwoFactorService) {
+				twoFactorServ.
+					On("VerifyChallenge", mock.Anything, mock.Anything).
+					Return(entity.UserModel{}, nil)
+
+				jwtServ.
+					On("CreateTokens", mock.Anything, mock.Anything, mock.Anything).
+					Return(entity.Tokens{}, assert.AnError)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				schema: testUserSignInMFASchema(t),
+			},
+			ret: ret{
+				tokens: entity.Tokens{
+					AccessToken:  "<access token>",
+					RefreshToken: "<refresh token>",
+				},
+				hasErr: false,
+			},
+			mockBehavior: func(jwtServ *servMocks.JWTService, twoFactorServ *servMocks.TwoFactorService) {
+				twoFactorServ.
+					On("VerifyChallenge", mock.Anything, mock.Anything).
+					Return(entity.UserModel{}, nil)
+
+				jwtServ.
+					On("CreateTokens", mock.Anything, mock.Anything, mock.Anything).
+					Return(entity.Tokens{
+						AccessToken:  "<access token>",
+						RefreshToken: "<refresh token>",
+					}, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runWithCaches(t, func(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+				var (
+					usersRepo     = repoMocks.NewUsersRepository(t)
+					hasherServ    = hashMocks.NewHasherService(t)
+					jwtServ       = servMocks.NewJWTService(t)
+					twoFactorServ = servMocks.NewTwoFactorService(t)
+					throttleServ  = servMocks.NewThrottleService(t)
+				)
+
+				authServ, err := service.NewAuthService(cache, usersRepo, hasherServ, jwtServ, twoFactorServ, throttleServ, testAuditService(t), servMocks.NewProfileService(t))
+				require.NoErrorf(t, err, "failed to create auth service: %s", err)
+
+				tc.mockBehavior(jwtServ, twoFactorServ)
+
+				tokens, err := authServ.SignInMFA(context.Background(), tc.args.schema)
+				assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+				assert.Equal(t, tc.ret.tokens, tokens)
+			})
 		})
 	}
 }
 
+func TestAuthService_SignInMFA_AuditFailure(t *testing.T) {
+	userID := primitive.NewObjectID()
+
+	var (
+		usersRepo     = repoMocks.NewUsersRepository(t)
+		hasherServ    = hashMocks.NewHasherService(t)
+		jwtServ       = servMocks.NewJWTService(t)
+		twoFactorServ = servMocks.NewTwoFactorService(t)
+		throttleServ  = servMocks.NewThrottleService(t)
+		auditServ     = servMocks.NewAuditService(t)
+	)
+
+	authServ, err := service.NewAuthService(newTestMemoryCache(t), usersRepo, hasherServ, jwtServ, twoFactorServ, throttleServ, auditServ, servMocks.NewProfileService(t))
+	require.NoErrorf(t, err, "failed to create auth service: %s", err)
+
+	twoFactorServ.
+		On("VerifyChallenge", mock.Anything, mock.Anything).
+		Return(entity.UserModel{ID: userID}, entity.ErrIncorrectTwoFactorCode)
+
+	auditServ.
+		On("Record", mock.Anything, mock.MatchedBy(func(event entity.AuditEventModel) bool {
+			return event.Action == entity.AuditActionSignIn &&
+				event.Outcome == entity.AuditOutcomeFailure &&
+				event.ActorID == userID
+		})).
+		Return(nil).
+		Once()
+
+	_, err = authServ.SignInMFA(context.Background(), service.UserSignInMFASchema{MFAToken: "<mfa token>", Code: "123456"})
+	assert.ErrorIs(t, err, entity.ErrIncorrectTwoFactorCode)
+}
+
 func TestAuthService_SignOut(t *testing.T) {
 	type args struct {
 		userID primitive.ObjectID
@@ -426,7 +885,7 @@ func TestAuthService_SignOut(t *testing.T) {
 		hasErr bool
 	}
 
-	type mockBehavior func(userID primitive.ObjectID) func(*miniredis.Miniredis)
+	type mockBehavior func(userID primitive.ObjectID) func(service.Cache)
 
 	testCases := []struct {
 		name         string
@@ -442,8 +901,8 @@ func TestAuthService_SignOut(t *testing.T) {
 			ret: ret{
 				hasErr: true,
 			},
-			mockBehavior: func(userID primitive.ObjectID) func(*miniredis.Miniredis) {
-				return func(redisServ *miniredis.Miniredis) {}
+			mockBehavior: func(userID primitive.ObjectID) func(service.Cache) {
+				return func(service.Cache) {}
 			},
 		},
 		{
@@ -454,12 +913,12 @@ func TestAuthService_SignOut(t *testing.T) {
 			ret: ret{
 				hasErr: false,
 			},
-			mockBehavior: func(userID primitive.ObjectID) func(*miniredis.Miniredis) {
-				return func(redisServ *miniredis.Miniredis) {
-					err := redisServ.Set(service.TokenCacheKey(userID.Hex()), mustMarshal(t, entity.TokensUIDs{
+			mockBehavior: func(userID primitive.ObjectID) func(service.Cache) {
+				return func(cache service.Cache) {
+					err := cache.Set(context.Background(), service.TokenCacheKey(userID.Hex()), mustMarshal(t, entity.TokensUIDs{
 						AccessTokenUID:  "<access token UID>",
 						RefreshTokenUID: "<refresh token UID>",
-					}))
+					}), 0)
 					require.NoErrorf(t, err, "failed to set %q token cache key: %s", service.TokenCacheKey(userID.Hex()), err)
 				}
 			},
@@ -470,35 +929,112 @@ func TestAuthService_SignOut(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			var (
-				redisServ = miniredis.RunT(t)
-				cache     = redis.NewClient(&redis.Options{
-					Addr: redisServ.Addr(),
-				})
-			)
+			runWithCaches(t, func(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+				var (
+					usersRepo     = repoMocks.NewUsersRepository(t)
+					hasherServ    = hashMocks.NewHasherService(t)
+					jwtServ       = servMocks.NewJWTService(t)
+					twoFactorServ = servMocks.NewTwoFactorService(t)
+					throttleServ  = servMocks.NewThrottleService(t)
+				)
 
-			var (
-				usersRepo  = repoMocks.NewUsersRepository(t)
-				hasherServ = hashMocks.NewHasherService(t)
-				jwtServ    = servMocks.NewJWTService(t)
-			)
+				authServ, err := service.NewAuthService(cache, usersRepo, hasherServ, jwtServ, twoFactorServ, throttleServ, testAuditService(t), servMocks.NewProfileService(t))
+				require.NoErrorf(t, err, "failed to create auth service: %s", err)
 
-			authServ, err := service.NewAuthService(cache, usersRepo, hasherServ, jwtServ)
-			require.NoErrorf(t, err, "failed to create auth service: %s", err)
-
-			tc.mockBehavior(tc.args.userID)(redisServ)
+				tc.mockBehavior(tc.args.userID)(cache)
 
-			err = authServ.SignOut(context.Background(), tc.args.userID)
-			assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+				err = authServ.SignOut(context.Background(), tc.args.userID)
+				assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+			})
 		})
 	}
 }
 
-func mustMarshal(t *testing.T, data any) string {
+func mustMarshal(t *testing.T, data any) []byte {
 	t.Helper()
 
 	buf, err := json.Marshal(data)
 	require.NoErrorf(t, err, "failed to marshal %v data", err)
 
-	return string(buf)
+	return buf
+}
+
+func TestAuthService_RefreshTokens(t *testing.T) {
+	var (
+		now    = time.Now()
+		userID = primitive.NewObjectID()
+	)
+
+	testCases := []struct {
+		name    string
+		user    entity.UserModel
+		findErr error
+		scopes  []string
+		err     error
+	}{
+		{
+			name:    "user deleted",
+			findErr: entity.ErrUserNotFound,
+			err:     entity.ErrUserNotFound,
+		},
+		{
+			name: "user suspended",
+			user: entity.UserModel{SuspendedAt: &now},
+			err:  &entity.SuspendedUserError{UserID: userID.Hex()},
+		},
+		{
+			name: "deletion scheduled",
+			user: entity.UserModel{DeletionScheduledAt: &now},
+			err:  entity.ErrDeletionScheduled,
+		},
+		{
+			name:   "demoted admin",
+			user:   entity.UserModel{Role: entity.RoleUser},
+			scopes: entity.DefaultScopes,
+		},
+		{
+			name:   "admin",
+			user:   entity.UserModel{Role: entity.RoleAdmin},
+			scopes: append(entity.DefaultScopes[:len(entity.DefaultScopes):len(entity.DefaultScopes)], entity.ScopeAdmin),
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				usersRepo     = repoMocks.NewUsersRepository(t)
+				hasherServ    = hashMocks.NewHasherService(t)
+				jwtServ       = servMocks.NewJWTService(t)
+				twoFactorServ = servMocks.NewTwoFactorService(t)
+				throttleServ  = servMocks.NewThrottleService(t)
+			)
+
+			authServ, err := service.NewAuthService(newTestMemoryCache(t), usersRepo, hasherServ, jwtServ, twoFactorServ, throttleServ, testAuditService(t), servMocks.NewProfileService(t))
+			require.NoErrorf(t, err, "failed to create auth service: %s", err)
+
+			user := tc.user
+			user.ID = userID
+
+			usersRepo.
+				On("FindByID", mock.Anything, user.ID).
+				Return(user, tc.findErr)
+
+			if tc.scopes != nil {
+				jwtServ.
+					On("CreateTokens", mock.Anything, user.ID.Hex(), tc.scopes).
+					Return(entity.Tokens{AccessToken: "<access token>"}, nil)
+			}
+
+			tokens, err := authServ.RefreshTokens(context.Background(), user.ID)
+			if tc.err != nil {
+				assert.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "<access token>", tokens.AccessToken)
+		})
+	}
 }
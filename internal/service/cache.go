@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kenplix/url-shrtnr/pkg/cache/redis"
+)
+
+// ErrCacheMiss is returned when key doesn't exist or is expired
+var ErrCacheMiss = errors.New("cache: key not found")
+
+// Cache is a key-value storage of short-living state, like tokens, challenges and counters.
+// Zero TTL means that key never expires
+//
+//go:generate mockery --dir . --name Cache --output ./mocks
+type Cache interface {
+	// Get returns ErrCacheMiss if key doesn't exist
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetNX sets value only if key doesn't exist and reports whether it was set
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// GetDel atomically returns value and deletes key, ErrCacheMiss is returned if key doesn't exist
+	GetDel(ctx context.Context, key string) ([]byte, error)
+	// Del returns number of deleted keys
+	Del(ctx context.Context, keys ...string) (int64, error)
+	// TTL returns remaining time to live of the key,
+	// non-positive duration is returned if key doesn't exist or never expires
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Expire changes TTL of the key, missing key is ignored
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	// IncrBy atomically adds delta to counter and returns the new value. Positive TTL is set
+	// in the same step, otherwise TTL of existing key is kept and missing key is created without expiration.
+	// Counters mustn't be overwritten with Set
+	IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	// Close releases resources of the cache, persistent caches are saved
+	Close(ctx context.Context) error
+}
+
+type CacheConfig struct {
+	// Use is a cache backend: "redis" or in-process "memory"
+	Use    string            `mapstructure:"use"`
+	Redis  redis.Config      `mapstructure:"redis"`
+	Memory MemoryCacheConfig `mapstructure:"memory"`
+}
+
+func NewCache(ctx context.Context, cfg CacheConfig) (Cache, error) {
+	switch cfg.Use {
+	case "redis":
+		client, err := redis.NewClient(ctx, cfg.Redis)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create redis client")
+		}
+
+		return NewRedisCache(client)
+	case "memory":
+		return NewMemoryCache(ctx, cfg.Memory)
+	default:
+		return nil, fmt.Errorf("unknown cache %q", cfg.Use)
+	}
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/pkg/cache/mapcache"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+const defaultMemoryCacheSnapshotInterval = time.Minute
+
+type MemoryCacheConfig struct {
+	// Path is a file where cache is saved, so tokens and sessions survive restarts.
+	// Cache isn't persisted if path is empty
+	Path string `mapstructure:"path"`
+	// SnapshotInterval is a period between saves of the cache, it's also saved on close
+	SnapshotInterval time.Duration `mapstructure:"snapshotInterval"`
+}
+
+// memoryCache keeps values in process memory, so state isn't shared between instances.
+// Values are stored as []byte, while counters are stored as int64 to be incremented atomically
+type memoryCache struct {
+	cache  *mapcache.Cache
+	path   string
+	logger *zap.Logger
+	done   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// memoryCacheEntry is a cache item saved to snapshot file
+type memoryCacheEntry struct {
+	Key       string     `json:"key"`
+	Value     []byte     `json:"value,omitempty"`
+	Counter   *int64     `json:"counter,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// NewMemoryCache creates in-process cache, failed background saves are reported to the logger of the context
+func NewMemoryCache(ctx context.Context, cfg MemoryCacheConfig) (Cache, error) {
+	return newMemoryCache(ctx, cfg, mapcache.New())
+}
+
+func newMemoryCache(ctx context.Context, cfg MemoryCacheConfig, cache *mapcache.Cache) (Cache, error) {
+	c := &memoryCache{
+		cache:  cache,
+		path:   cfg.Path,
+		logger: log.LoggerFromContext(ctx),
+		done:   make(chan struct{}),
+	}
+
+	if c.path == "" {
+		return c, nil
+	}
+
+	if err := c.load(); err != nil {
+		c.cache.Close()
+		return nil, errors.Wrapf(err, "failed to load cache snapshot %q", c.path)
+	}
+
+	c.wg.Add(1)
+
+	go c.snapshotPeriodically(lo.Ternary(cfg.SnapshotInterval > 0, cfg.SnapshotInterval, defaultMemoryCacheSnapshotInterval))
+
+	return c, nil
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) ([]byte, error) {
+	value, err := c.cache.Get(key)
+	if err != nil {
+		return nil, memoryCacheError(err)
+	}
+
+	return memoryCacheValue(value), nil
+}
+
+func (c *memoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.cache.Set(key, copyBytes(value), memoryCacheTTL(ttl))
+	return nil
+}
+
+func (c *memoryCache) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return c.cache.SetNX(key, copyBytes(value), memoryCacheTTL(ttl)), nil
+}
+
+func (c *memoryCache) GetDel(_ context.Context, key string) ([]byte, error) {
+	value, err := c.cache.GetDel(key)
+	if err != nil {
+		return nil, memoryCacheError(err)
+	}
+
+	return memoryCacheValue(value), nil
+}
+
+func (c *memoryCache) Del(_ context.Context, keys ...string) (int64, error) {
+	var deleted int64
+
+	for _, key := range keys {
+		if _, err := c.cache.GetDel(key); err == nil {
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (c *memoryCache) TTL(_ context.Context, key string) (time.Duration, error) {
+	ttl, err := c.cache.TTL(key)
+	if errors.Is(err, mapcache.ErrItemNotFound) {
+		return 0, nil
+	}
+
+	return ttl, err
+}
+
+func (c *memoryCache) Expire(_ context.Context, key string, ttl time.Duration) error {
+	err := c.cache.Expire(key, memoryCacheTTL(ttl))
+	if errors.Is(err, mapcache.ErrItemNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+func (c *memoryCache) IncrBy(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return c.cache.IncrBy(key, delta, memoryCacheTTL(ttl))
+}
+
+func (c *memoryCache) Close(_ context.Context) error {
+	var err error
+
+	c.once.Do(func() {
+		close(c.done)
+		c.wg.Wait()
+		c.cache.Close()
+
+		if c.path != "" {
+			err = c.save()
+		}
+	})
+
+	return err
+}
+
+func (c *memoryCache) snapshotPeriodically(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.save(); err != nil {
+				c.logger.Error("failed to save cache snapshot",
+					zap.String("path", c.path),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+// save writes not expired items to temporary file, which replaces snapshot,
+// so interrupted save doesn't corrupt previous snapshot
+func (c *memoryCache) save() error {
+	now := time.Now()
+
+	var entries []memoryCacheEntry
+
+	c.cache.Range(func(key string, value any, ttl time.Duration) {
+		entry := memoryCacheEntry{Key: key}
+
+		switch v := value.(type) {
+		case int64:
+			entry.Counter = lo.ToPtr(v)
+		case []byte:
+			entry.Value = v
+		default:
+			return
+		}
+
+		if ttl != mapcache.TTLWithoutExpiration {
+			entry.ExpiresAt = lo.ToPtr(now.Add(ttl))
+		}
+
+		entries = append(entries, entry)
+	})
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cache entries")
+	}
+
+	err = os.MkdirAll(filepath.Dir(c.path), 0o700)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %q director(y/ies)", filepath.Dir(c.path))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "failed to write %q file", tmp.Name())
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close %q file", tmp.Name())
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// load restores not expired items of the snapshot, missing snapshot means empty cache
+func (c *memoryCache) load() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	var entries []memoryCacheEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return errors.Wrap(err, "failed to unmarshal cache entries")
+	}
+
+	now := time.Now()
+
+	for _, entry := range entries {
+		ttl := mapcache.TTLWithoutExpiration
+
+		if entry.ExpiresAt != nil {
+			ttl = entry.ExpiresAt.Sub(now)
+			if ttl <= 0 {
+				continue
+			}
+		}
+
+		if entry.Counter != nil {
+			c.cache.Set(entry.Key, *entry.Counter, ttl)
+		} else {
+			c.cache.Set(entry.Key, entry.Value, ttl)
+		}
+	}
+
+	return nil
+}
+
+func memoryCacheError(err error) error {
+	if errors.Is(err, mapcache.ErrItemNotFound) {
+		return ErrCacheMiss
+	}
+
+	return err
+}
+
+// memoryCacheValue returns copy of stored value, so callers can't modify cached one
+func memoryCacheValue(value any) []byte {
+	switch v := value.(type) {
+	case int64:
+		return []byte(strconv.FormatInt(v, 10))
+	case []byte:
+		return copyBytes(v)
+	default:
+		return nil
+	}
+}
+
+func memoryCacheTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return mapcache.TTLWithoutExpiration
+	}
+
+	return ttl
+}
+
+func copyBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
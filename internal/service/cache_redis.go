@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/pkg/errors"
+)
+
+type redisCache struct {
+	client *redis.Client
+}
+
+func NewRedisCache(client *redis.Client) (Cache, error) {
+	if client == nil {
+		return nil, errors.New("redis client not provided")
+	}
+
+	return &redisCache{client: client}, nil
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+
+	return value, err
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *redisCache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (c *redisCache) GetDel(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.client.GetDel(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+
+	return value, err
+}
+
+func (c *redisCache) Del(ctx context.Context, keys ...string) (int64, error) {
+	return c.client.Del(ctx, keys...).Result()
+}
+
+func (c *redisCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	// redis responds with negative values for missing keys and keys without expiration
+	return c.client.TTL(ctx, key).Result()
+}
+
+func (c *redisCache) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return c.client.Expire(ctx, key, ttl).Err()
+}
+
+func (c *redisCache) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	if ttl <= 0 {
+		return c.client.IncrBy(ctx, key, delta).Result()
+	}
+
+	var incr *redis.IntCmd
+
+	// transaction sets TTL together with the new value, so counter never stays without expiration
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.IncrBy(ctx, key, delta)
+		pipe.Expire(ctx, key, ttl)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+func (c *redisCache) Close(_ context.Context) error {
+	return c.client.Close()
+}
@@ -0,0 +1,225 @@
+package service_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/internal/service"
+)
+
+func newTestRedisCache(t *testing.T, redisServ *miniredis.Miniredis) service.Cache {
+	t.Helper()
+
+	cache, err := service.NewRedisCache(redis.NewClient(&redis.Options{Addr: redisServ.Addr()}))
+	require.NoErrorf(t, err, "failed to create redis cache: %s", err)
+
+	return cache
+}
+
+func newTestMemoryCache(t *testing.T) service.Cache {
+	t.Helper()
+
+	cache, err := service.NewMemoryCache(context.Background(), service.MemoryCacheConfig{})
+	require.NoErrorf(t, err, "failed to create memory cache: %s", err)
+
+	t.Cleanup(func() {
+		_ = cache.Close(context.Background())
+	})
+
+	return cache
+}
+
+// newTestFrozenMemoryCache creates memory cache with stopped clock, which is moved only by returned fast forward
+func newTestFrozenMemoryCache(t *testing.T) (service.Cache, func(time.Duration)) {
+	t.Helper()
+
+	var (
+		mux sync.Mutex
+		now = time.Now()
+	)
+
+	cache, err := service.NewMemoryCacheWithClock(context.Background(), func() time.Time {
+		mux.Lock()
+		defer mux.Unlock()
+
+		return now
+	})
+	require.NoErrorf(t, err, "failed to create memory cache: %s", err)
+
+	t.Cleanup(func() {
+		_ = cache.Close(context.Background())
+	})
+
+	fastForward := func(d time.Duration) {
+		mux.Lock()
+		now = now.Add(d)
+		mux.Unlock()
+	}
+
+	return cache, fastForward
+}
+
+// testCaches are implementations of the Cache, services are tested against each of them.
+// Cache is created together with the function which moves its clock forward
+var testCaches = []struct {
+	name  string
+	cache func(t *testing.T) (service.Cache, func(time.Duration))
+}{
+	{
+		name: "redis",
+		cache: func(t *testing.T) (service.Cache, func(time.Duration)) {
+			redisServ := miniredis.RunT(t)
+			return newTestRedisCache(t, redisServ), redisServ.FastForward
+		},
+	},
+	{
+		name:  "memory",
+		cache: newTestFrozenMemoryCache,
+	},
+}
+
+// runWithCaches runs test against every implementation of the Cache
+func runWithCaches(t *testing.T, test func(t *testing.T, cache service.Cache, fastForward func(time.Duration))) {
+	t.Helper()
+
+	for _, tc := range testCaches {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			cache, fastForward := tc.cache(t)
+			test(t, cache, fastForward)
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range testCaches {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			cache, _ := tc.cache(t)
+
+			_, err := cache.Get(ctx, "missing")
+			assert.ErrorIs(t, err, service.ErrCacheMiss)
+
+			_, err = cache.GetDel(ctx, "missing")
+			assert.ErrorIs(t, err, service.ErrCacheMiss)
+
+			ttl, err := cache.TTL(ctx, "missing")
+			require.NoError(t, err)
+			assert.LessOrEqual(t, ttl, time.Duration(0))
+
+			require.NoError(t, cache.Expire(ctx, "missing", time.Minute), "missing key must be ignored")
+
+			require.NoError(t, cache.Set(ctx, "key", []byte("value"), time.Minute))
+
+			value, err := cache.Get(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("value"), value)
+
+			ttl, err = cache.TTL(ctx, "key")
+			require.NoError(t, err)
+			assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+			require.NoError(t, cache.Expire(ctx, "key", time.Hour))
+
+			ttl, err = cache.TTL(ctx, "key")
+			require.NoError(t, err)
+			assert.InDelta(t, time.Hour, ttl, float64(time.Second))
+
+			set, err := cache.SetNX(ctx, "key", []byte("other"), time.Minute)
+			require.NoError(t, err)
+			assert.False(t, set, "existing key must not be overwritten")
+
+			value, err = cache.GetDel(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("value"), value)
+
+			_, err = cache.Get(ctx, "key")
+			assert.ErrorIs(t, err, service.ErrCacheMiss)
+
+			set, err = cache.SetNX(ctx, "key", []byte("other"), time.Minute)
+			require.NoError(t, err)
+			assert.True(t, set)
+
+			for i := int64(1); i <= 3; i++ {
+				n, err := cache.IncrBy(ctx, "counter", 1, time.Hour)
+				require.NoError(t, err)
+				assert.Equal(t, i, n)
+			}
+
+			n, err := cache.IncrBy(ctx, "counter", -1, 0)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), n)
+
+			ttl, err = cache.TTL(ctx, "counter")
+			require.NoError(t, err)
+			assert.InDelta(t, time.Hour, ttl, float64(time.Second), "TTL must be set together with the value and kept if zero")
+
+			value, err = cache.Get(ctx, "counter")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("2"), value)
+
+			deleted, err := cache.Del(ctx, "key", "counter", "missing")
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), deleted)
+		})
+	}
+}
+
+func TestMemoryCache_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	cfg := service.MemoryCacheConfig{Path: filepath.Join(t.TempDir(), "cache.json")}
+
+	cache, err := service.NewMemoryCache(ctx, cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Set(ctx, "token", []byte("uids"), time.Hour))
+	require.NoError(t, cache.Set(ctx, "permanent", []byte("value"), 0))
+	require.NoError(t, cache.Set(ctx, "expiring", []byte("value"), time.Millisecond))
+
+	_, err = cache.IncrBy(ctx, "counter", 1, 0)
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, cache.Close(ctx))
+
+	restored, err := service.NewMemoryCache(ctx, cfg)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = restored.Close(ctx)
+	})
+
+	value, err := restored.Get(ctx, "token")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("uids"), value)
+
+	ttl, err := restored.TTL(ctx, "token")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour, ttl, float64(time.Second), "TTL must be kept across restarts")
+
+	value, err = restored.Get(ctx, "permanent")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	_, err = restored.Get(ctx, "expiring")
+	assert.ErrorIs(t, err, service.ErrCacheMiss, "expired keys must not be restored")
+
+	n, err := restored.IncrBy(ctx, "counter", 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n, "counters must be restored as counters")
+}
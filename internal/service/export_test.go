@@ -1,3 +1,20 @@
 package service
 
+import (
+	"context"
+	"time"
+
+	"github.com/kenplix/url-shrtnr/pkg/cache/mapcache"
+)
+
 var TokenCacheKey = tokenCacheKey
+
+var (
+	TwoFactorEnrollmentCacheKey = twoFactorEnrollmentCacheKey
+	MFAChallengeCacheKey        = mfaChallengeCacheKey
+)
+
+// NewMemoryCacheWithClock creates not persisted memory cache, which expires values according to now
+func NewMemoryCacheWithClock(ctx context.Context, now func() time.Time) (Cache, error) {
+	return newMemoryCache(ctx, MemoryCacheConfig{}, mapcache.NewWithClock(now))
+}
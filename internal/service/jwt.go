@@ -4,13 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/kenplix/url-shrtnr/pkg/log"
 
 	"go.uber.org/zap"
 
-	"github.com/go-redis/redis/v9"
 	"github.com/pkg/errors"
 
 	"github.com/kenplix/url-shrtnr/internal/entity"
@@ -24,13 +24,13 @@ type JWTServiceConfig struct {
 }
 
 type jwtService struct {
-	cache          *redis.Client
+	cache          Cache
 	accessServ     token.JWTService
 	refreshServ    token.JWTService
 	signOutTimeout time.Duration
 }
 
-func NewJWTService(cfg JWTServiceConfig, cache *redis.Client) (JWTService, error) {
+func NewJWTService(cfg JWTServiceConfig, cache Cache) (JWTService, error) {
 	accessServ, err := token.NewJWTService(cfg.AccessToken)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create access token service")
@@ -75,13 +75,13 @@ func NewJWTService(cfg JWTServiceConfig, cache *redis.Client) (JWTService, error
 	return &s, nil
 }
 
-func (s *jwtService) CreateTokens(ctx context.Context, userID string) (entity.Tokens, error) {
-	accessToken, accessTokenUID, err := s.accessServ.CreateToken(userID)
+func (s *jwtService) CreateTokens(ctx context.Context, userID string, scopes []string) (entity.Tokens, error) {
+	accessToken, accessTokenUID, err := s.accessServ.CreateToken(userID, scopes)
 	if err != nil {
 		return entity.Tokens{}, errors.Wrapf(err, "failed to create access token")
 	}
 
-	refreshToken, refreshTokenUID, err := s.refreshServ.CreateToken(userID)
+	refreshToken, refreshTokenUID, err := s.refreshServ.CreateToken(userID, scopes)
 	if err != nil {
 		return entity.Tokens{}, errors.Wrapf(err, "failed to create refresh token")
 	}
@@ -94,7 +94,12 @@ func (s *jwtService) CreateTokens(ctx context.Context, userID string) (entity.To
 		return entity.Tokens{}, errors.Wrapf(err, "failed to marshal tokens UIDs")
 	}
 
-	s.cache.Set(ctx, tokenCacheKey(userID), cacheJSON, s.signOutTimeout)
+	tokenKey := tokenCacheKey(userID)
+
+	err = s.cache.Set(ctx, tokenKey, cacheJSON, s.signOutTimeout)
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "cache: failed to set %q key", tokenKey)
+	}
 
 	tokens := entity.Tokens{
 		AccessToken:  accessToken,
@@ -116,8 +121,11 @@ func (s *jwtService) ProlongTokens(ctx context.Context, userID string) {
 
 	tokenKey := tokenCacheKey(userID)
 
-	ttl := s.cache.TTL(ctx, tokenKey).Val()
-	s.cache.Expire(ctx, tokenKey, s.signOutTimeout)
+	ttl, _ := s.cache.TTL(ctx, tokenKey)
+	if err := s.cache.Expire(ctx, tokenKey, s.signOutTimeout); err != nil {
+		logger.Warn("failed to prolong tokens", zap.Error(err))
+		return
+	}
 
 	logger.Debug("tokens prolonged",
 		zap.Duration("ttl", ttl),
@@ -141,10 +149,49 @@ func (s *jwtService) ValidateRefreshToken(ctx context.Context, claims *token.JWT
 	return s.validateToken(ctx, claims, true)
 }
 
+func (s *jwtService) RevokeTokens(ctx context.Context, userID string) error {
+	revokedKey := tokensRevokedCacheKey(userID)
+
+	// tokens don't outlive refresh token TTL, so there is no need to keep revocation longer
+	err := s.cache.Set(ctx, revokedKey, []byte(strconv.FormatInt(time.Now().Unix(), 10)), s.refreshServ.TokenTTL())
+	if err != nil {
+		return errors.Wrapf(err, "cache: failed to set %q key", revokedKey)
+	}
+
+	// tokens issued within the same second as revocation are rejected by the session check
+	tokenKey := tokenCacheKey(userID)
+
+	if _, err = s.cache.Del(ctx, tokenKey); err != nil {
+		return errors.Wrapf(err, "cache: failed to delete %q key", tokenKey)
+	}
+
+	return nil
+}
+
 func (s *jwtService) validateToken(ctx context.Context, claims *token.JWTCustomClaims, isRefreshToken bool) error {
+	revokedKey := tokensRevokedCacheKey(claims.Subject)
+
+	revokedAtBytes, err := s.cache.Get(ctx, revokedKey)
+	if err != nil && !errors.Is(err, ErrCacheMiss) {
+		return errors.Wrapf(err, "cache: failed to get %q key", revokedKey)
+	}
+
+	var revokedAt int64
+
+	if revokedAtBytes != nil {
+		revokedAt, err = strconv.ParseInt(string(revokedAtBytes), 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "cache: invalid %q key value", revokedKey)
+		}
+	}
+
+	if claims.IssuedAt < revokedAt {
+		return errors.New("token revoked")
+	}
+
 	tokenKey := tokenCacheKey(claims.Subject)
 
-	cacheBytes, err := s.cache.Get(ctx, tokenKey).Bytes()
+	cacheBytes, err := s.cache.Get(ctx, tokenKey)
 	if err != nil {
 		return errors.Wrapf(err, "cache: failed to get %q key", tokenKey)
 	}
@@ -168,6 +215,27 @@ func (s *jwtService) validateToken(ctx context.Context, claims *token.JWTCustomC
 	return nil
 }
 
+func (s *jwtService) AccessTokenJWKS() token.JWKS {
+	return s.accessServ.JWKS()
+}
+
+func (s *jwtService) ReloadKeys() error {
+	if err := s.accessServ.Reload(); err != nil {
+		return errors.Wrap(err, "failed to reload access token keys")
+	}
+
+	if err := s.refreshServ.Reload(); err != nil {
+		return errors.Wrap(err, "failed to reload refresh token keys")
+	}
+
+	return nil
+}
+
 func tokenCacheKey(userID string) string {
 	return fmt.Sprintf("token:%s", userID)
 }
+
+// tokensRevokedCacheKey keeps time before which all tokens of the user are invalid
+func tokensRevokedCacheKey(userID string) string {
+	return fmt.Sprintf("token:%s:revoked", userID)
+}
@@ -0,0 +1,95 @@
+package service_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/token"
+)
+
+func testEdTokenConfig(t *testing.T, ttl time.Duration) token.Config {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoErrorf(t, err, "failed to generate Ed25519 keypair: %s", err)
+
+	pkcs8PrivateKey, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoErrorf(t, err, "failed to marshal private key: %s", err)
+
+	pkixPublicKey, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoErrorf(t, err, "failed to marshal public key: %s", err)
+
+	return token.Config{
+		Algorithm:  "EdDSA",
+		PrivateKey: base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8PrivateKey})),
+		PublicKey:  base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkixPublicKey})),
+		TTL:        ttl,
+	}
+}
+
+func TestJWTService_RevokeTokens(t *testing.T) {
+	runWithCaches(t, testJWTServiceRevokeTokens)
+}
+
+func testJWTServiceRevokeTokens(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+	const userID = "63a6a9b1f1e0a2c3d4e5f607"
+
+	jwtServ, err := service.NewJWTService(service.JWTServiceConfig{
+		AccessToken:  testEdTokenConfig(t, time.Hour),
+		RefreshToken: testEdTokenConfig(t, 24*time.Hour),
+	}, cache)
+	require.NoErrorf(t, err, "failed to create jwt service: %s", err)
+
+	ctx := context.Background()
+
+	validate := func(t *testing.T, tokens entity.Tokens) (accessErr, refreshErr error) {
+		t.Helper()
+
+		accessClaims, err := jwtServ.ParseAccessToken(tokens.AccessToken)
+		require.NoErrorf(t, err, "failed to parse access token: %s", err)
+
+		refreshClaims, err := jwtServ.ParseRefreshToken(tokens.RefreshToken)
+		require.NoErrorf(t, err, "failed to parse refresh token: %s", err)
+
+		return jwtServ.ValidateAccessToken(ctx, accessClaims), jwtServ.ValidateRefreshToken(ctx, refreshClaims)
+	}
+
+	revoked, err := jwtServ.CreateTokens(ctx, userID, entity.DefaultScopes)
+	require.NoError(t, err)
+
+	accessErr, refreshErr := validate(t, revoked)
+	require.NoError(t, accessErr)
+	require.NoError(t, refreshErr)
+
+	require.NoError(t, jwtServ.RevokeTokens(ctx, userID))
+
+	accessErr, refreshErr = validate(t, revoked)
+	assert.Error(t, accessErr, "access token must be revoked")
+	assert.Error(t, refreshErr, "refresh token must be revoked")
+
+	issued, err := jwtServ.CreateTokens(ctx, userID, entity.DefaultScopes)
+	require.NoError(t, err)
+
+	accessErr, refreshErr = validate(t, issued)
+	assert.NoError(t, accessErr, "tokens issued after revocation must be valid")
+	assert.NoError(t, refreshErr, "tokens issued after revocation must be valid")
+
+	accessErr, refreshErr = validate(t, revoked)
+	assert.Error(t, accessErr, "revoked access token must not become valid again")
+	assert.Error(t, refreshErr, "revoked refresh token must not become valid again")
+
+	ttl, err := cache.TTL(ctx, "token:"+userID+":revoked")
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, ttl)
+}
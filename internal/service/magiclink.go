@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+	"github.com/kenplix/url-shrtnr/pkg/mail"
+)
+
+const (
+	defaultMagicLinkTTL  = 15 * time.Minute
+	defaultMagicLinkURL  = "http://localhost/sign-in/magic-link"
+	magicLinkTokenSize   = 32
+	magicLinkNonceSize   = 32
+	magicLinkSendTimeout = 30 * time.Second
+)
+
+type MagicLinkServiceConfig struct {
+	TTL time.Duration `mapstructure:"ttl"`
+	// URL is a page of the frontend which exchanges token from the "token" query parameter
+	URL string `mapstructure:"url"`
+}
+
+// magicLink is kept in cache until it's used or expired
+type magicLink struct {
+	UserID    string `json:"userID"`
+	Email     string `json:"email"`
+	NonceHash string `json:"nonceHash"`
+}
+
+type magicLinkService struct {
+	cache         Cache
+	usersRepo     repository.UsersRepository
+	jwtServ       JWTService
+	twoFactorServ TwoFactorService
+	throttleServ  ThrottleService
+	auditServ     AuditService
+	mailSender    mail.Sender
+	ttl           time.Duration
+	url           string
+}
+
+func NewMagicLinkService(
+	cfg MagicLinkServiceConfig,
+	cache Cache,
+	usersRepo repository.UsersRepository,
+	jwtServ JWTService,
+	twoFactorServ TwoFactorService,
+	throttleServ ThrottleService,
+	auditServ AuditService,
+	mailSender mail.Sender,
+) (MagicLinkService, error) {
+	if cache == nil {
+		return nil, errors.New("cache not provided")
+	}
+
+	if usersRepo == nil {
+		return nil, errors.New("users repository not provided")
+	}
+
+	if jwtServ == nil {
+		return nil, errors.New("jwt service not provided")
+	}
+
+	if twoFactorServ == nil {
+		return nil, errors.New("two-factor service not provided")
+	}
+
+	if throttleServ == nil {
+		return nil, errors.New("throttle service not provided")
+	}
+
+	if auditServ == nil {
+		return nil, errors.New("audit service not provided")
+	}
+
+	if mailSender == nil {
+		return nil, errors.New("mail sender not provided")
+	}
+
+	linkURL := lo.Ternary(cfg.URL != "", cfg.URL, defaultMagicLinkURL)
+	if _, err := url.Parse(linkURL); err != nil {
+		return nil, errors.Wrapf(err, "invalid magic link url %q", linkURL)
+	}
+
+	s := &magicLinkService{
+		cache:         cache,
+		usersRepo:     usersRepo,
+		jwtServ:       jwtServ,
+		twoFactorServ: twoFactorServ,
+		throttleServ:  throttleServ,
+		auditServ:     auditServ,
+		mailSender:    mailSender,
+		ttl:           lo.Ternary(cfg.TTL > 0, cfg.TTL, defaultMagicLinkTTL),
+		url:           linkURL,
+	}
+
+	return s, nil
+}
+
+func (s *magicLinkService) Request(ctx context.Context, schema MagicLinkRequestSchema) (MagicLinkBinding, error) {
+	email := strings.ToLower(strings.TrimSpace(schema.Email))
+	throttleSchema := ThrottleSchema{MagicLink: email, MagicLinkIP: schema.IP}
+
+	err := s.throttleServ.Reserve(ctx, throttleSchema)
+	if err != nil {
+		var tooManyAttemptsErr *entity.TooManyAttemptsError
+		if errors.As(err, &tooManyAttemptsErr) {
+			return MagicLinkBinding{}, err
+		}
+
+		return MagicLinkBinding{}, errors.Wrapf(err, "failed to reserve sign in attempt of %+v", throttleSchema)
+	}
+
+	// every request is treated as failed attempt, so links can't be used to flood mailboxes
+	err = s.throttleServ.Fail(ctx, throttleSchema)
+	if err != nil {
+		return MagicLinkBinding{}, errors.Wrapf(err, "failed to register sign in attempt of %+v", throttleSchema)
+	}
+
+	// nonce is returned even for unknown emails, so response doesn't reveal whether user exists
+	nonce, err := randomHex(magicLinkNonceSize)
+	if err != nil {
+		return MagicLinkBinding{}, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	binding := MagicLinkBinding{Nonce: nonce, TTL: s.ttl}
+
+	user, err := s.usersRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, entity.ErrUserNotFound) {
+			return binding, nil
+		}
+
+		return MagicLinkBinding{}, errors.Wrapf(err, "failed to find user[email:%q]", email)
+	}
+
+	if user.SuspendedAt != nil {
+		return binding, nil
+	}
+
+	token, err := randomHex(magicLinkTokenSize)
+	if err != nil {
+		return MagicLinkBinding{}, errors.Wrap(err, "failed to generate token")
+	}
+
+	linkJSON, err := json.Marshal(magicLink{
+		UserID:    user.ID.Hex(),
+		Email:     email,
+		NonceHash: hashSecret(nonce),
+	})
+	if err != nil {
+		return MagicLinkBinding{}, errors.Wrap(err, "failed to marshal magic link")
+	}
+
+	linkKey := magicLinkCacheKey(hashSecret(token))
+
+	err = s.cache.Set(ctx, linkKey, linkJSON, s.ttl)
+	if err != nil {
+		return MagicLinkBinding{}, errors.Wrapf(err, "cache: failed to set %q key", linkKey)
+	}
+
+	s.sendLink(ctx, user, token)
+
+	return binding, nil
+}
+
+func (s *magicLinkService) SignIn(ctx context.Context, schema MagicLinkSignInSchema) (entity.Tokens, error) {
+	throttleSchema := ThrottleSchema{IP: schema.IP}
+
+	err := s.throttleServ.Reserve(ctx, throttleSchema)
+	if err != nil {
+		var tooManyAttemptsErr *entity.TooManyAttemptsError
+		if errors.As(err, &tooManyAttemptsErr) {
+			return entity.Tokens{}, err
+		}
+
+		return entity.Tokens{}, errors.Wrapf(err, "failed to reserve sign in attempt of %+v", throttleSchema)
+	}
+
+	linkKey := magicLinkCacheKey(hashSecret(schema.Token))
+
+	linkJSON, err := s.cache.Get(ctx, linkKey)
+	if err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			return entity.Tokens{}, s.failMagicLink(ctx, throttleSchema)
+		}
+
+		return entity.Tokens{}, errors.Wrapf(err, "cache: failed to get %q key", linkKey)
+	}
+
+	var link magicLink
+	if err = json.Unmarshal(linkJSON, &link); err != nil {
+		return entity.Tokens{}, errors.Wrap(err, "failed to unmarshal magic link")
+	}
+
+	// link opened in another browser is rejected but kept, so forwarded link can't be burned
+	if subtle.ConstantTimeCompare([]byte(hashSecret(schema.Nonce)), []byte(link.NonceHash)) != 1 {
+		return entity.Tokens{}, s.failMagicLink(ctx, throttleSchema)
+	}
+
+	// only one of concurrent requests deletes the key, which guarantees single use
+	deleted, err := s.cache.Del(ctx, linkKey)
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "cache: failed to delete %q key", linkKey)
+	} else if deleted == 0 {
+		return entity.Tokens{}, entity.ErrMagicLinkNotFound
+	}
+
+	userID, err := primitive.ObjectIDFromHex(link.UserID)
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "failed to parse user id %q", link.UserID)
+	}
+
+	user, err := s.usersRepo.FindByID(ctx, userID)
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "failed to get user[id:%q]", link.UserID)
+	}
+
+	if user.SuspendedAt != nil {
+		return entity.Tokens{}, &entity.SuspendedUserError{UserID: user.ID.Hex()}
+	}
+
+	err = s.throttleServ.Release(ctx, throttleSchema)
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "failed to release sign in attempt of %+v", throttleSchema)
+	}
+
+	err = s.throttleServ.Reset(ctx, link.Email)
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "failed to reset sign in attempts of login %q", link.Email)
+	}
+
+	return startSession(ctx, s.jwtServ, s.twoFactorServ, s.auditServ, user, signInMethodMagicLink)
+}
+
+// sendLink delivers email in background, so response time doesn't reveal whether user exists
+func (s *magicLinkService) sendLink(ctx context.Context, user entity.UserModel, token string) {
+	logger := log.LoggerFromContext(ctx).With(zap.String("userID", user.ID.Hex()))
+
+	link, _ := url.Parse(s.url)
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Your sign in link",
+		Body: fmt.Sprintf("Hi %s,\n\nfollow the link below to sign in, it expires in %s and works only once:\n%s\n\n"+
+			"The link must be opened in the same browser you requested it from. "+
+			"If you didn't request it, ignore this email.\n", user.Username, s.ttl, link),
+	}
+
+	go func() {
+		sendCtx, cancel := context.WithTimeout(log.ContextWithLogger(context.Background(), logger), magicLinkSendTimeout)
+		defer cancel()
+
+		if err := s.mailSender.Send(sendCtx, msg); err != nil {
+			logger.Error("failed to send magic link", zap.Error(err))
+		}
+	}()
+}
+
+// failMagicLink registers failed attempt and returns error that should be returned to the client
+func (s *magicLinkService) failMagicLink(ctx context.Context, schema ThrottleSchema) error {
+	err := s.throttleServ.Fail(ctx, schema)
+	if err != nil {
+		return errors.Wrapf(err, "failed to register failed sign in attempt of %+v", schema)
+	}
+
+	return entity.ErrMagicLinkNotFound
+}
+
+func magicLinkCacheKey(tokenHash string) string {
+	return fmt.Sprintf("magiclink:%s", tokenHash)
+}
@@ -0,0 +1,219 @@
+package service_test
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+	"github.com/kenplix/url-shrtnr/pkg/mail"
+	mailMocks "github.com/kenplix/url-shrtnr/pkg/mail/mocks"
+)
+
+var magicLinkRegexp = regexp.MustCompile(`http://localhost/sign-in/magic-link\?token=\S+`)
+
+func TestMagicLinkService(t *testing.T) {
+	runWithCaches(t, testMagicLinkService)
+}
+
+func testMagicLinkService(t *testing.T, cache service.Cache, fastForward func(time.Duration)) {
+	const testIP = "127.0.0.1"
+
+	ctx := context.Background()
+
+	repos, err := repository.New(ctx, repository.Config{
+		Use:    "filedb",
+		FileDB: repository.FileDBConfig{Path: t.TempDir()},
+	})
+	require.NoErrorf(t, err, "failed to create repositories: %s", err)
+
+	createUser := func(t *testing.T, user entity.UserModel) entity.UserModel {
+		t.Helper()
+
+		require.NoError(t, repos.Users.Create(ctx, user))
+
+		user, err := repos.Users.FindByEmail(ctx, user.Email)
+		require.NoError(t, err)
+
+		return user
+	}
+
+	var (
+		now       = time.Now()
+		user      = createUser(t, entity.UserModel{Username: "kenplix", Email: "tolstoi.job@gmail.com"})
+		suspended = createUser(t, entity.UserModel{Username: "suspended", Email: "suspended@example.com", SuspendedAt: &now})
+	)
+
+	var (
+		jwtServ       = servMocks.NewJWTService(t)
+		twoFactorServ = servMocks.NewTwoFactorService(t)
+		mailSender    = mailMocks.NewSender(t)
+		sent          = make(chan mail.Message, 1)
+	)
+
+	jwtServ.
+		On("CreateTokens", mock.Anything, user.ID.Hex(), entity.DefaultScopes).
+		Return(entity.Tokens{AccessToken: "<access token>"}, nil).
+		Maybe()
+
+	mailSender.
+		On("Send", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { sent <- args.Get(1).(mail.Message) }).
+		Return(nil).
+		Maybe()
+
+	throttleServ, err := service.NewThrottleService(service.ThrottleServiceConfig{
+		Login: service.ThrottleLimitsConfig{FreeAttempts: 5},
+		IP:    service.ThrottleLimitsConfig{FreeAttempts: 100},
+	}, cache)
+	require.NoErrorf(t, err, "failed to create throttle service: %s", err)
+
+	magicLinkServ, err := service.NewMagicLinkService(service.MagicLinkServiceConfig{
+		TTL: time.Minute,
+		URL: "http://localhost/sign-in/magic-link",
+	}, cache, repos.Users, jwtServ, twoFactorServ, throttleServ, testAuditService(t), mailSender)
+	require.NoErrorf(t, err, "failed to create magic link service: %s", err)
+
+	request := func(t *testing.T, email string) (service.MagicLinkBinding, string) {
+		t.Helper()
+
+		binding, err := magicLinkServ.Request(ctx, service.MagicLinkRequestSchema{Email: email, IP: testIP})
+		require.NoErrorf(t, err, "failed to request magic link: %s", err)
+		require.NotEmpty(t, binding.Nonce)
+		assert.Equal(t, time.Minute, binding.TTL)
+
+		select {
+		case msg := <-sent:
+			assert.Equal(t, email, msg.To)
+
+			link, err := url.Parse(magicLinkRegexp.FindString(msg.Body))
+			require.NoErrorf(t, err, "failed to parse link: %s", err)
+
+			return binding, link.Query().Get("token")
+		case <-time.After(time.Second):
+			t.Fatal("magic link wasn't sent")
+		}
+
+		return binding, ""
+	}
+
+	assertNotSent := func(t *testing.T) {
+		t.Helper()
+
+		select {
+		case msg := <-sent:
+			t.Fatalf("unexpected email to %q", msg.To)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	t.Run("unknown email", func(t *testing.T) {
+		binding, err := magicLinkServ.Request(ctx, service.MagicLinkRequestSchema{Email: "unknown@example.com", IP: testIP})
+		require.NoError(t, err)
+		assert.NotEmpty(t, binding.Nonce, "response must not reveal whether user exists")
+		assertNotSent(t)
+	})
+
+	t.Run("suspended user", func(t *testing.T) {
+		binding, err := magicLinkServ.Request(ctx, service.MagicLinkRequestSchema{Email: suspended.Email, IP: testIP})
+		require.NoError(t, err)
+		assert.NotEmpty(t, binding.Nonce)
+		assertNotSent(t)
+	})
+
+	t.Run("link is single use", func(t *testing.T) {
+		binding, token := request(t, user.Email)
+
+		tokens, err := magicLinkServ.SignIn(ctx, service.MagicLinkSignInSchema{Token: token, Nonce: binding.Nonce, IP: testIP})
+		require.NoError(t, err)
+		assert.Equal(t, "<access token>", tokens.AccessToken)
+
+		_, err = magicLinkServ.SignIn(ctx, service.MagicLinkSignInSchema{Token: token, Nonce: binding.Nonce, IP: testIP})
+		assert.ErrorIs(t, err, entity.ErrMagicLinkNotFound)
+	})
+
+	t.Run("link is bound to the browser", func(t *testing.T) {
+		binding, token := request(t, user.Email)
+
+		_, err := magicLinkServ.SignIn(ctx, service.MagicLinkSignInSchema{Token: token, Nonce: "<another nonce>", IP: testIP})
+		assert.ErrorIs(t, err, entity.ErrMagicLinkNotFound)
+
+		_, err = magicLinkServ.SignIn(ctx, service.MagicLinkSignInSchema{Token: token, IP: testIP})
+		assert.ErrorIs(t, err, entity.ErrMagicLinkNotFound)
+
+		_, err = magicLinkServ.SignIn(ctx, service.MagicLinkSignInSchema{Token: token, Nonce: binding.Nonce, IP: testIP})
+		assert.NoError(t, err, "forwarded link must not burn the original one")
+	})
+
+	t.Run("successful sign in resets link requests", func(t *testing.T) {
+		var (
+			binding service.MagicLinkBinding
+			token   string
+		)
+
+		// all requests are free, the next ones would be delayed without reset
+		for i := 0; i < 5; i++ {
+			binding, token = request(t, user.Email)
+		}
+
+		_, err := magicLinkServ.SignIn(ctx, service.MagicLinkSignInSchema{Token: token, Nonce: binding.Nonce, IP: testIP})
+		require.NoError(t, err)
+
+		for i := 0; i < 5; i++ {
+			request(t, user.Email)
+		}
+	})
+
+	t.Run("link expired", func(t *testing.T) {
+		binding, token := request(t, user.Email)
+
+		fastForward(time.Minute)
+
+		_, err := magicLinkServ.SignIn(ctx, service.MagicLinkSignInSchema{Token: token, Nonce: binding.Nonce, IP: testIP})
+		assert.ErrorIs(t, err, entity.ErrMagicLinkNotFound)
+	})
+
+	t.Run("requests are throttled", func(t *testing.T) {
+		const email = "flood@example.com"
+
+		var err error
+		for i := 0; i < 10 && err == nil; i++ {
+			_, err = magicLinkServ.Request(ctx, service.MagicLinkRequestSchema{Email: email, IP: testIP})
+		}
+
+		var tooManyAttemptsErr *entity.TooManyAttemptsError
+		assert.ErrorAs(t, err, &tooManyAttemptsErr)
+
+		err = throttleServ.Reserve(ctx, service.ThrottleSchema{Login: email})
+		assert.NoError(t, err, "link requests must not lock out password sign in")
+	})
+
+	t.Run("second authentication factor required", func(t *testing.T) {
+		err := repos.Users.EnableTwoFactor(ctx, repository.EnableTwoFactorSchema{
+			UserID:    user.ID,
+			TwoFactor: entity.TwoFactorModel{TOTPSecret: testTOTPSecret},
+		})
+		require.NoError(t, err)
+
+		twoFactorServ.
+			On("CreateChallenge", mock.Anything, user.ID).
+			Return(entity.MFAChallenge{MFAToken: "<mfa token>"}, nil)
+
+		binding, token := request(t, user.Email)
+
+		_, err = magicLinkServ.SignIn(ctx, service.MagicLinkSignInSchema{Token: token, Nonce: binding.Nonce, IP: testIP})
+
+		var mfaRequiredErr *entity.MFARequiredError
+		require.ErrorAs(t, err, &mfaRequiredErr)
+		assert.Equal(t, "<mfa token>", mfaRequiredErr.Challenge.MFAToken)
+	})
+}
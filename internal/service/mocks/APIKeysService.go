@@ -0,0 +1,113 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	entity "github.com/kenplix/url-shrtnr/internal/entity"
+	mock "github.com/stretchr/testify/mock"
+
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+
+	service "github.com/kenplix/url-shrtnr/internal/service"
+)
+
+// APIKeysService is an autogenerated mock type for the APIKeysService type
+type APIKeysService struct {
+	mock.Mock
+}
+
+// Authenticate provides a mock function with given fields: ctx, apiKey
+func (_m *APIKeysService) Authenticate(ctx context.Context, apiKey string) (entity.APIKeyModel, error) {
+	ret := _m.Called(ctx, apiKey)
+
+	var r0 entity.APIKeyModel
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.APIKeyModel); ok {
+		r0 = rf(ctx, apiKey)
+	} else {
+		r0 = ret.Get(0).(entity.APIKeyModel)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, apiKey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Create provides a mock function with given fields: ctx, schema
+func (_m *APIKeysService) Create(ctx context.Context, schema service.CreateAPIKeySchema) (entity.CreatedAPIKey, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.CreatedAPIKey
+	if rf, ok := ret.Get(0).(func(context.Context, service.CreateAPIKeySchema) entity.CreatedAPIKey); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.CreatedAPIKey)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.CreateAPIKeySchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: ctx, userID
+func (_m *APIKeysService) List(ctx context.Context, userID primitive.ObjectID) ([]entity.APIKey, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []entity.APIKey
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) []entity.APIKey); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, primitive.ObjectID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Revoke provides a mock function with given fields: ctx, schema
+func (_m *APIKeysService) Revoke(ctx context.Context, schema service.RevokeAPIKeySchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.RevokeAPIKeySchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewAPIKeysService interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewAPIKeysService creates a new instance of APIKeysService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewAPIKeysService(t mockConstructorTestingTNewAPIKeysService) *APIKeysService {
+	mock := &APIKeysService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
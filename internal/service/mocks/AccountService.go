@@ -0,0 +1,162 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	entity "github.com/kenplix/url-shrtnr/internal/entity"
+	mock "github.com/stretchr/testify/mock"
+
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+
+	service "github.com/kenplix/url-shrtnr/internal/service"
+)
+
+// AccountService is an autogenerated mock type for the AccountService type
+type AccountService struct {
+	mock.Mock
+}
+
+// CancelDeletion provides a mock function with given fields: ctx, userID
+func (_m *AccountService) CancelDeletion(ctx context.Context, userID primitive.ObjectID) (entity.User, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) entity.User); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, primitive.ObjectID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DownloadExport provides a mock function with given fields: ctx, schema
+func (_m *AccountService) DownloadExport(ctx context.Context, schema service.DataExportSchema) ([]byte, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, service.DataExportSchema) []byte); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.DataExportSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetExport provides a mock function with given fields: ctx, schema
+func (_m *AccountService) GetExport(ctx context.Context, schema service.DataExportSchema) (entity.DataExport, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.DataExport
+	if rf, ok := ret.Get(0).(func(context.Context, service.DataExportSchema) entity.DataExport); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.DataExport)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.DataExportSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeDeleted provides a mock function with given fields: ctx
+func (_m *AccountService) PurgeDeleted(ctx context.Context) (int, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RequestExport provides a mock function with given fields: ctx, userID
+func (_m *AccountService) RequestExport(ctx context.Context, userID primitive.ObjectID) (entity.DataExport, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 entity.DataExport
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) entity.DataExport); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(entity.DataExport)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, primitive.ObjectID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ScheduleDeletion provides a mock function with given fields: ctx, schema
+func (_m *AccountService) ScheduleDeletion(ctx context.Context, schema service.ScheduleDeletionSchema) (entity.User, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, service.ScheduleDeletionSchema) entity.User); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.ScheduleDeletionSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewAccountService interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewAccountService creates a new instance of AccountService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewAccountService(t mockConstructorTestingTNewAccountService) *AccountService {
+	mock := &AccountService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
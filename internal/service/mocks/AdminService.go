@@ -0,0 +1,160 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	entity "github.com/kenplix/url-shrtnr/internal/entity"
+	mock "github.com/stretchr/testify/mock"
+
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+
+	service "github.com/kenplix/url-shrtnr/internal/service"
+)
+
+// AdminService is an autogenerated mock type for the AdminService type
+type AdminService struct {
+	mock.Mock
+}
+
+// GetUser provides a mock function with given fields: ctx, userID
+func (_m *AdminService) GetUser(ctx context.Context, userID primitive.ObjectID) (entity.User, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) entity.User); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, primitive.ObjectID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListUsers provides a mock function with given fields: ctx, schema
+func (_m *AdminService) ListUsers(ctx context.Context, schema service.ListUsersSchema) (entity.UsersPage, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.UsersPage
+	if rf, ok := ret.Get(0).(func(context.Context, service.ListUsersSchema) entity.UsersPage); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.UsersPage)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.ListUsersSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResetTwoFactor provides a mock function with given fields: ctx, schema
+func (_m *AdminService) ResetTwoFactor(ctx context.Context, schema service.AdminActionSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.AdminActionSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetRole provides a mock function with given fields: ctx, schema
+func (_m *AdminService) SetRole(ctx context.Context, schema service.SetRoleSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.SetRoleSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SignOutUser provides a mock function with given fields: ctx, schema
+func (_m *AdminService) SignOutUser(ctx context.Context, schema service.AdminActionSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.AdminActionSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SuspendUser provides a mock function with given fields: ctx, schema
+func (_m *AdminService) SuspendUser(ctx context.Context, schema service.SuspendUserSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.SuspendUserSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnlockSignIn provides a mock function with given fields: ctx, schema
+func (_m *AdminService) UnlockSignIn(ctx context.Context, schema service.UnlockSignInSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.UnlockSignInSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnsuspendUser provides a mock function with given fields: ctx, schema
+func (_m *AdminService) UnsuspendUser(ctx context.Context, schema service.AdminActionSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.AdminActionSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewAdminService interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewAdminService creates a new instance of AdminService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewAdminService(t mockConstructorTestingTNewAdminService) *AdminService {
+	mock := &AdminService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
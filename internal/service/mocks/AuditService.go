@@ -0,0 +1,67 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	entity "github.com/kenplix/url-shrtnr/internal/entity"
+	mock "github.com/stretchr/testify/mock"
+
+	service "github.com/kenplix/url-shrtnr/internal/service"
+)
+
+// AuditService is an autogenerated mock type for the AuditService type
+type AuditService struct {
+	mock.Mock
+}
+
+// List provides a mock function with given fields: ctx, schema
+func (_m *AuditService) List(ctx context.Context, schema service.ListAuditEventsSchema) (entity.AuditEventsPage, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.AuditEventsPage
+	if rf, ok := ret.Get(0).(func(context.Context, service.ListAuditEventsSchema) entity.AuditEventsPage); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.AuditEventsPage)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.ListAuditEventsSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Record provides a mock function with given fields: ctx, event
+func (_m *AuditService) Record(ctx context.Context, event entity.AuditEventModel) error {
+	ret := _m.Called(ctx, event)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.AuditEventModel) error); ok {
+		r0 = rf(ctx, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewAuditService interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewAuditService creates a new instance of AuditService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewAuditService(t mockConstructorTestingTNewAuditService) *AuditService {
+	mock := &AuditService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
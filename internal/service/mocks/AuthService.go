@@ -18,6 +18,27 @@ type AuthService struct {
 	mock.Mock
 }
 
+// RefreshTokens provides a mock function with given fields: ctx, userID
+func (_m *AuthService) RefreshTokens(ctx context.Context, userID primitive.ObjectID) (entity.Tokens, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 entity.Tokens
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) entity.Tokens); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(entity.Tokens)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, primitive.ObjectID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SignIn provides a mock function with given fields: ctx, schema
 func (_m *AuthService) SignIn(ctx context.Context, schema service.UserSignInSchema) (entity.Tokens, error) {
 	ret := _m.Called(ctx, schema)
@@ -39,6 +60,27 @@ func (_m *AuthService) SignIn(ctx context.Context, schema service.UserSignInSche
 	return r0, r1
 }
 
+// SignInMFA provides a mock function with given fields: ctx, schema
+func (_m *AuthService) SignInMFA(ctx context.Context, schema service.UserSignInMFASchema) (entity.Tokens, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.Tokens
+	if rf, ok := ret.Get(0).(func(context.Context, service.UserSignInMFASchema) entity.Tokens); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.Tokens)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.UserSignInMFASchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SignOut provides a mock function with given fields: ctx, userID
 func (_m *AuthService) SignOut(ctx context.Context, userID primitive.ObjectID) error {
 	ret := _m.Called(ctx, userID)
@@ -0,0 +1,210 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// Cache is an autogenerated mock type for the Cache type
+type Cache struct {
+	mock.Mock
+}
+
+// Close provides a mock function with given fields: ctx
+func (_m *Cache) Close(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Del provides a mock function with given fields: ctx, keys
+func (_m *Cache) Del(ctx context.Context, keys ...string) (int64, error) {
+	_va := make([]interface{}, len(keys))
+	for _i := range keys {
+		_va[_i] = keys[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, ...string) int64); ok {
+		r0 = rf(ctx, keys...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, ...string) error); ok {
+		r1 = rf(ctx, keys...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Expire provides a mock function with given fields: ctx, key, ttl
+func (_m *Cache) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	ret := _m.Called(ctx, key, ttl)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) error); ok {
+		r0 = rf(ctx, key, ttl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: ctx, key
+func (_m *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDel provides a mock function with given fields: ctx, key
+func (_m *Cache) GetDel(ctx context.Context, key string) ([]byte, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IncrBy provides a mock function with given fields: ctx, key, delta, ttl
+func (_m *Cache) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	ret := _m.Called(ctx, key, delta, ttl)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, time.Duration) int64); ok {
+		r0 = rf(ctx, key, delta, ttl)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64, time.Duration) error); ok {
+		r1 = rf(ctx, key, delta, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Set provides a mock function with given fields: ctx, key, value, ttl
+func (_m *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ret := _m.Called(ctx, key, value, ttl)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, time.Duration) error); ok {
+		r0 = rf(ctx, key, value, ttl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetNX provides a mock function with given fields: ctx, key, value, ttl
+func (_m *Cache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	ret := _m.Called(ctx, key, value, ttl)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte, time.Duration) bool); ok {
+		r0 = rf(ctx, key, value, ttl)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, []byte, time.Duration) error); ok {
+		r1 = rf(ctx, key, value, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TTL provides a mock function with given fields: ctx, key
+func (_m *Cache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func(context.Context, string) time.Duration); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewCache interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewCache creates a new instance of Cache. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewCache(t mockConstructorTestingTNewCache) *Cache {
+	mock := &Cache{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
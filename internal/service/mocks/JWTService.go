@@ -16,20 +16,34 @@ type JWTService struct {
 	mock.Mock
 }
 
-// CreateTokens provides a mock function with given fields: ctx, userID
-func (_m *JWTService) CreateTokens(ctx context.Context, userID string) (entity.Tokens, error) {
-	ret := _m.Called(ctx, userID)
+// AccessTokenJWKS provides a mock function with given fields:
+func (_m *JWTService) AccessTokenJWKS() token.JWKS {
+	ret := _m.Called()
+
+	var r0 token.JWKS
+	if rf, ok := ret.Get(0).(func() token.JWKS); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(token.JWKS)
+	}
+
+	return r0
+}
+
+// CreateTokens provides a mock function with given fields: ctx, userID, scopes
+func (_m *JWTService) CreateTokens(ctx context.Context, userID string, scopes []string) (entity.Tokens, error) {
+	ret := _m.Called(ctx, userID, scopes)
 
 	var r0 entity.Tokens
-	if rf, ok := ret.Get(0).(func(context.Context, string) entity.Tokens); ok {
-		r0 = rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) entity.Tokens); ok {
+		r0 = rf(ctx, userID, scopes)
 	} else {
 		r0 = ret.Get(0).(entity.Tokens)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, string, []string) error); ok {
+		r1 = rf(ctx, userID, scopes)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -88,6 +102,34 @@ func (_m *JWTService) ProlongTokens(ctx context.Context, userID string) {
 	_m.Called(ctx, userID)
 }
 
+// ReloadKeys provides a mock function with given fields:
+func (_m *JWTService) ReloadKeys() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeTokens provides a mock function with given fields: ctx, userID
+func (_m *JWTService) RevokeTokens(ctx context.Context, userID string) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ValidateAccessToken provides a mock function with given fields: ctx, claims
 func (_m *JWTService) ValidateAccessToken(ctx context.Context, claims *token.JWTCustomClaims) error {
 	ret := _m.Called(ctx, claims)
@@ -0,0 +1,74 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	entity "github.com/kenplix/url-shrtnr/internal/entity"
+	mock "github.com/stretchr/testify/mock"
+
+	service "github.com/kenplix/url-shrtnr/internal/service"
+)
+
+// MagicLinkService is an autogenerated mock type for the MagicLinkService type
+type MagicLinkService struct {
+	mock.Mock
+}
+
+// Request provides a mock function with given fields: ctx, schema
+func (_m *MagicLinkService) Request(ctx context.Context, schema service.MagicLinkRequestSchema) (service.MagicLinkBinding, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 service.MagicLinkBinding
+	if rf, ok := ret.Get(0).(func(context.Context, service.MagicLinkRequestSchema) service.MagicLinkBinding); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(service.MagicLinkBinding)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.MagicLinkRequestSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SignIn provides a mock function with given fields: ctx, schema
+func (_m *MagicLinkService) SignIn(ctx context.Context, schema service.MagicLinkSignInSchema) (entity.Tokens, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.Tokens
+	if rf, ok := ret.Get(0).(func(context.Context, service.MagicLinkSignInSchema) entity.Tokens); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.Tokens)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.MagicLinkSignInSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewMagicLinkService interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewMagicLinkService creates a new instance of MagicLinkService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewMagicLinkService(t mockConstructorTestingTNewMagicLinkService) *MagicLinkService {
+	mock := &MagicLinkService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
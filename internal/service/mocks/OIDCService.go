@@ -0,0 +1,74 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	entity "github.com/kenplix/url-shrtnr/internal/entity"
+	mock "github.com/stretchr/testify/mock"
+
+	service "github.com/kenplix/url-shrtnr/internal/service"
+)
+
+// OIDCService is an autogenerated mock type for the OIDCService type
+type OIDCService struct {
+	mock.Mock
+}
+
+// Authorize provides a mock function with given fields: ctx, schema
+func (_m *OIDCService) Authorize(ctx context.Context, schema service.OIDCAuthorizeSchema) (service.OIDCAuthorization, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 service.OIDCAuthorization
+	if rf, ok := ret.Get(0).(func(context.Context, service.OIDCAuthorizeSchema) service.OIDCAuthorization); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(service.OIDCAuthorization)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.OIDCAuthorizeSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SignIn provides a mock function with given fields: ctx, schema
+func (_m *OIDCService) SignIn(ctx context.Context, schema service.OIDCCallbackSchema) (entity.Tokens, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.Tokens
+	if rf, ok := ret.Get(0).(func(context.Context, service.OIDCCallbackSchema) entity.Tokens); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.Tokens)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.OIDCCallbackSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewOIDCService interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewOIDCService creates a new instance of OIDCService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewOIDCService(t mockConstructorTestingTNewOIDCService) *OIDCService {
+	mock := &OIDCService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
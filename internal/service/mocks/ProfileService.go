@@ -0,0 +1,182 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	blob "github.com/kenplix/url-shrtnr/pkg/blob"
+
+	entity "github.com/kenplix/url-shrtnr/internal/entity"
+
+	mock "github.com/stretchr/testify/mock"
+
+	service "github.com/kenplix/url-shrtnr/internal/service"
+)
+
+// ProfileService is an autogenerated mock type for the ProfileService type
+type ProfileService struct {
+	mock.Mock
+}
+
+// ChangeAvatar provides a mock function with given fields: ctx, schema
+func (_m *ProfileService) ChangeAvatar(ctx context.Context, schema service.ChangeAvatarSchema) (entity.User, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, service.ChangeAvatarSchema) entity.User); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.ChangeAvatarSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangeUsername provides a mock function with given fields: ctx, schema
+func (_m *ProfileService) ChangeUsername(ctx context.Context, schema service.ChangeUsernameSchema) (entity.User, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, service.ChangeUsernameSchema) entity.User); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.ChangeUsernameSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteAvatar provides a mock function with given fields: ctx, schema
+func (_m *ProfileService) DeleteAvatar(ctx context.Context, schema service.DeleteAvatarSchema) (entity.User, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, service.DeleteAvatarSchema) entity.User); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.DeleteAvatarSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAvatar provides a mock function with given fields: ctx, key
+func (_m *ProfileService) GetAvatar(ctx context.Context, key string) (blob.Object, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 blob.Object
+	if rf, ok := ret.Get(0).(func(context.Context, string) blob.Object); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Get(0).(blob.Object)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProfile provides a mock function with given fields: ctx, username
+func (_m *ProfileService) GetProfile(ctx context.Context, username string) (entity.Profile, error) {
+	ret := _m.Called(ctx, username)
+
+	var r0 entity.Profile
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.Profile); ok {
+		r0 = rf(ctx, username)
+	} else {
+		r0 = ret.Get(0).(entity.Profile)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsUsernameAvailable provides a mock function with given fields: ctx, username
+func (_m *ProfileService) IsUsernameAvailable(ctx context.Context, username string) (bool, error) {
+	ret := _m.Called(ctx, username)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, username)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, username)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateProfile provides a mock function with given fields: ctx, schema
+func (_m *ProfileService) UpdateProfile(ctx context.Context, schema service.UpdateProfileSchema) (entity.User, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, service.UpdateProfileSchema) entity.User); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.UpdateProfileSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+type mockConstructorTestingTNewProfileService interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewProfileService creates a new instance of ProfileService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewProfileService(t mockConstructorTestingTNewProfileService) *ProfileService {
+	mock := &ProfileService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
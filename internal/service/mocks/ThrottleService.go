@@ -0,0 +1,100 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	service "github.com/kenplix/url-shrtnr/internal/service"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ThrottleService is an autogenerated mock type for the ThrottleService type
+type ThrottleService struct {
+	mock.Mock
+}
+
+// Fail provides a mock function with given fields: ctx, schema
+func (_m *ThrottleService) Fail(ctx context.Context, schema service.ThrottleSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.ThrottleSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Release provides a mock function with given fields: ctx, schema
+func (_m *ThrottleService) Release(ctx context.Context, schema service.ThrottleSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.ThrottleSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Reserve provides a mock function with given fields: ctx, schema
+func (_m *ThrottleService) Reserve(ctx context.Context, schema service.ThrottleSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.ThrottleSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Reset provides a mock function with given fields: ctx, login
+func (_m *ThrottleService) Reset(ctx context.Context, login string) error {
+	ret := _m.Called(ctx, login)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, login)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Unlock provides a mock function with given fields: ctx, schema
+func (_m *ThrottleService) Unlock(ctx context.Context, schema service.ThrottleSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.ThrottleSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewThrottleService interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewThrottleService creates a new instance of ThrottleService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewThrottleService(t mockConstructorTestingTNewThrottleService) *ThrottleService {
+	mock := &ThrottleService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,146 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	entity "github.com/kenplix/url-shrtnr/internal/entity"
+	mock "github.com/stretchr/testify/mock"
+
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+
+	service "github.com/kenplix/url-shrtnr/internal/service"
+)
+
+// TwoFactorService is an autogenerated mock type for the TwoFactorService type
+type TwoFactorService struct {
+	mock.Mock
+}
+
+// ConfirmEnrollment provides a mock function with given fields: ctx, schema
+func (_m *TwoFactorService) ConfirmEnrollment(ctx context.Context, schema service.ConfirmTwoFactorSchema) (entity.RecoveryCodes, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.RecoveryCodes
+	if rf, ok := ret.Get(0).(func(context.Context, service.ConfirmTwoFactorSchema) entity.RecoveryCodes); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.RecoveryCodes)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.ConfirmTwoFactorSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateChallenge provides a mock function with given fields: ctx, userID
+func (_m *TwoFactorService) CreateChallenge(ctx context.Context, userID primitive.ObjectID) (entity.MFAChallenge, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 entity.MFAChallenge
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) entity.MFAChallenge); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(entity.MFAChallenge)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, primitive.ObjectID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Disable provides a mock function with given fields: ctx, schema
+func (_m *TwoFactorService) Disable(ctx context.Context, schema service.DisableTwoFactorSchema) error {
+	ret := _m.Called(ctx, schema)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, service.DisableTwoFactorSchema) error); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Enroll provides a mock function with given fields: ctx, userID
+func (_m *TwoFactorService) Enroll(ctx context.Context, userID primitive.ObjectID) (entity.TwoFactorEnrollment, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 entity.TwoFactorEnrollment
+	if rf, ok := ret.Get(0).(func(context.Context, primitive.ObjectID) entity.TwoFactorEnrollment); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(entity.TwoFactorEnrollment)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, primitive.ObjectID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifyChallenge provides a mock function with given fields: ctx, schema
+func (_m *TwoFactorService) VerifyChallenge(ctx context.Context, schema service.VerifyMFAChallengeSchema) (entity.UserModel, error) {
+	ret := _m.Called(ctx, schema)
+
+	var r0 entity.UserModel
+	if rf, ok := ret.Get(0).(func(context.Context, service.VerifyMFAChallengeSchema) entity.UserModel); ok {
+		r0 = rf(ctx, schema)
+	} else {
+		r0 = ret.Get(0).(entity.UserModel)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.VerifyMFAChallengeSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifyCode provides a mock function with given fields: ctx, user, code
+func (_m *TwoFactorService) VerifyCode(ctx context.Context, user entity.UserModel, code string) error {
+	ret := _m.Called(ctx, user, code)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.UserModel, string) error); ok {
+		r0 = rf(ctx, user, code)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewTwoFactorService interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewTwoFactorService creates a new instance of TwoFactorService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewTwoFactorService(t mockConstructorTestingTNewTwoFactorService) *TwoFactorService {
+	mock := &TwoFactorService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -33,17 +33,24 @@ func (_m *UsersService) ChangeEmail(ctx context.Context, schema service.ChangeEm
 }
 
 // ChangePassword provides a mock function with given fields: ctx, schema
-func (_m *UsersService) ChangePassword(ctx context.Context, schema service.ChangePasswordSchema) error {
+func (_m *UsersService) ChangePassword(ctx context.Context, schema service.ChangePasswordSchema) (entity.Tokens, error) {
 	ret := _m.Called(ctx, schema)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, service.ChangePasswordSchema) error); ok {
+	var r0 entity.Tokens
+	if rf, ok := ret.Get(0).(func(context.Context, service.ChangePasswordSchema) entity.Tokens); ok {
 		r0 = rf(ctx, schema)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(entity.Tokens)
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, service.ChangePasswordSchema) error); ok {
+		r1 = rf(ctx, schema)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // GetByID provides a mock function with given fields: ctx, userID
@@ -0,0 +1,405 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/pkg/oidc"
+)
+
+const (
+	defaultOIDCStateTTL = 10 * time.Minute
+
+	oidcStateSize        = 32
+	oidcNonceSize        = 16
+	oidcBrowserNonceSize = 32
+
+	usernameSuffixAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	usernameSuffixLength   = 6
+	usernameMinLength      = 5
+	usernameBaseMaxLength  = 24
+	usernameAttempts       = 5
+)
+
+type OIDCServiceConfig struct {
+	StateTTL  time.Duration          `mapstructure:"stateTTL"`
+	Providers map[string]oidc.Config `mapstructure:"providers"`
+}
+
+// oidcState is kept in cache between authorization request and callback
+type oidcState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+	// BrowserNonceHash binds state to the browser which started authorization
+	BrowserNonceHash string `json:"browserNonceHash"`
+	// UserID is set when identity is linked to the signed in user
+	UserID string `json:"userID,omitempty"`
+}
+
+type oidcService struct {
+	cache         Cache
+	usersRepo     repository.UsersRepository
+	jwtServ       JWTService
+	twoFactorServ TwoFactorService
+	auditServ     AuditService
+	profileServ   ProfileService
+	providers     map[string]*oidc.Provider
+	stateTTL      time.Duration
+}
+
+func NewOIDCService(
+	cfg OIDCServiceConfig,
+	cache Cache,
+	usersRepo repository.UsersRepository,
+	jwtServ JWTService,
+	twoFactorServ TwoFactorService,
+	auditServ AuditService,
+	profileServ ProfileService,
+) (OIDCService, error) {
+	if cache == nil {
+		return nil, errors.New("cache not provided")
+	}
+
+	if usersRepo == nil {
+		return nil, errors.New("users repository not provided")
+	}
+
+	if jwtServ == nil {
+		return nil, errors.New("jwt service not provided")
+	}
+
+	if twoFactorServ == nil {
+		return nil, errors.New("two-factor service not provided")
+	}
+
+	if auditServ == nil {
+		return nil, errors.New("audit service not provided")
+	}
+
+	if profileServ == nil {
+		return nil, errors.New("profile service not provided")
+	}
+
+	providers := make(map[string]*oidc.Provider, len(cfg.Providers))
+
+	for name, providerCfg := range cfg.Providers {
+		provider, err := oidc.NewProvider(providerCfg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create %q identity provider", name)
+		}
+
+		providers[name] = provider
+	}
+
+	s := &oidcService{
+		cache:         cache,
+		usersRepo:     usersRepo,
+		jwtServ:       jwtServ,
+		twoFactorServ: twoFactorServ,
+		auditServ:     auditServ,
+		profileServ:   profileServ,
+		providers:     providers,
+		stateTTL:      lo.Ternary(cfg.StateTTL > 0, cfg.StateTTL, defaultOIDCStateTTL),
+	}
+
+	return s, nil
+}
+
+func (s *oidcService) Authorize(ctx context.Context, schema OIDCAuthorizeSchema) (OIDCAuthorization, error) {
+	provider, found := s.providers[schema.Provider]
+	if !found {
+		return OIDCAuthorization{}, entity.ErrOIDCProviderNotFound
+	}
+
+	state, err := randomHex(oidcStateSize)
+	if err != nil {
+		return OIDCAuthorization{}, errors.Wrap(err, "failed to generate state")
+	}
+
+	nonce, err := randomHex(oidcNonceSize)
+	if err != nil {
+		return OIDCAuthorization{}, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	browserNonce, err := randomHex(oidcBrowserNonceSize)
+	if err != nil {
+		return OIDCAuthorization{}, errors.Wrap(err, "failed to generate browser nonce")
+	}
+
+	codeVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return OIDCAuthorization{}, errors.Wrap(err, "failed to generate code verifier")
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return OIDCAuthorization{}, errors.Wrapf(err, "failed to create %q authorization url", schema.Provider)
+	}
+
+	stored := oidcState{
+		Provider:         schema.Provider,
+		Nonce:            nonce,
+		CodeVerifier:     codeVerifier,
+		BrowserNonceHash: hashSecret(browserNonce),
+	}
+
+	if !schema.UserID.IsZero() {
+		stored.UserID = schema.UserID.Hex()
+	}
+
+	stateJSON, err := json.Marshal(stored)
+	if err != nil {
+		return OIDCAuthorization{}, errors.Wrap(err, "failed to marshal state")
+	}
+
+	stateKey := oidcStateCacheKey(state)
+
+	err = s.cache.Set(ctx, stateKey, stateJSON, s.stateTTL)
+	if err != nil {
+		return OIDCAuthorization{}, errors.Wrapf(err, "cache: failed to set %q key", stateKey)
+	}
+
+	authorization := OIDCAuthorization{
+		AuthURL: authURL,
+		Nonce:   browserNonce,
+		TTL:     s.stateTTL,
+	}
+
+	return authorization, nil
+}
+
+func (s *oidcService) SignIn(ctx context.Context, schema OIDCCallbackSchema) (entity.Tokens, error) {
+	provider, found := s.providers[schema.Provider]
+	if !found {
+		return entity.Tokens{}, entity.ErrOIDCProviderNotFound
+	}
+
+	stateKey := oidcStateCacheKey(schema.State)
+
+	stateJSON, err := s.cache.Get(ctx, stateKey)
+	if err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			return entity.Tokens{}, entity.ErrOIDCStateNotFound
+		}
+
+		return entity.Tokens{}, errors.Wrapf(err, "cache: failed to get %q key", stateKey)
+	}
+
+	var state oidcState
+	if err = json.Unmarshal(stateJSON, &state); err != nil {
+		return entity.Tokens{}, errors.Wrap(err, "failed to unmarshal state")
+	}
+
+	// callback opened in another browser is rejected but state is kept, so it can't be burned
+	if state.Provider != schema.Provider ||
+		subtle.ConstantTimeCompare([]byte(hashSecret(schema.Nonce)), []byte(state.BrowserNonceHash)) != 1 {
+		return entity.Tokens{}, entity.ErrOIDCStateNotFound
+	}
+
+	// only one of concurrent callbacks deletes the key, which guarantees single use
+	deleted, err := s.cache.Del(ctx, stateKey)
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "cache: failed to delete %q key", stateKey)
+	} else if deleted == 0 {
+		return entity.Tokens{}, entity.ErrOIDCStateNotFound
+	}
+
+	identity, err := provider.Exchange(ctx, schema.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(entity.ErrOIDCAuthenticationFailed, "%s: %s", schema.Provider, err)
+	}
+
+	var user entity.UserModel
+
+	if state.UserID != "" {
+		user, err = s.linkIdentity(ctx, state.UserID, schema.Provider, identity)
+	} else {
+		user, err = s.findOrCreateUser(ctx, schema.Provider, identity)
+	}
+
+	if err != nil {
+		return entity.Tokens{}, err
+	}
+
+	if user.SuspendedAt != nil {
+		return entity.Tokens{}, &entity.SuspendedUserError{UserID: user.ID.Hex()}
+	}
+
+	return startSession(ctx, s.jwtServ, s.twoFactorServ, s.auditServ, user, signInMethodOIDC)
+}
+
+// linkIdentity links provider account to the user who started authorization
+func (s *oidcService) linkIdentity(ctx context.Context, userIDHex, provider string, identity oidc.Identity) (entity.UserModel, error) {
+	userID, err := primitive.ObjectIDFromHex(userIDHex)
+	if err != nil {
+		return entity.UserModel{}, errors.Wrapf(err, "failed to parse user id %q", userIDHex)
+	}
+
+	linked, err := s.usersRepo.FindByIdentity(ctx, provider, identity.Subject)
+	if err == nil {
+		if linked.ID != userID {
+			return entity.UserModel{}, entity.ErrOIDCIdentityAlreadyLinked
+		}
+
+		return linked, nil
+	} else if !errors.Is(err, entity.ErrUserNotFound) {
+		return entity.UserModel{}, errors.Wrapf(err, "failed to find user[%s:%q]", provider, identity.Subject)
+	}
+
+	err = s.usersRepo.LinkIdentity(ctx, repository.LinkIdentitySchema{
+		UserID:   userID,
+		Identity: newIdentityModel(provider, identity),
+	})
+	if err != nil {
+		if errors.Is(err, entity.ErrOIDCIdentityAlreadyLinked) {
+			return entity.UserModel{}, err
+		}
+
+		return entity.UserModel{}, errors.Wrapf(err, "user[id:%q]: failed to link %q identity", userIDHex, provider)
+	}
+
+	user, err := s.usersRepo.FindByID(ctx, userID)
+	if err != nil {
+		return entity.UserModel{}, errors.Wrapf(err, "failed to get user[id:%q]", userIDHex)
+	}
+
+	return user, nil
+}
+
+// findOrCreateUser returns user linked to the provider account, otherwise new user is created.
+// Emails of local users aren't verified, so account is never linked by email: anyone could sign up
+// with the email of the provider account owner beforehand. Existing user must sign in and link it instead
+func (s *oidcService) findOrCreateUser(ctx context.Context, provider string, identity oidc.Identity) (entity.UserModel, error) {
+	user, err := s.usersRepo.FindByIdentity(ctx, provider, identity.Subject)
+	if err == nil {
+		return user, nil
+	} else if !errors.Is(err, entity.ErrUserNotFound) {
+		return entity.UserModel{}, errors.Wrapf(err, "failed to find user[%s:%q]", provider, identity.Subject)
+	}
+
+	// emails of users are stored lowercased, so existing user is found regardless of case
+	identity.Email = strings.ToLower(strings.TrimSpace(identity.Email))
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return entity.UserModel{}, entity.ErrOIDCEmailNotVerified
+	}
+
+	_, err = s.usersRepo.FindByEmail(ctx, identity.Email)
+	if err == nil {
+		return entity.UserModel{}, entity.ErrOIDCEmailInUse
+	} else if !errors.Is(err, entity.ErrUserNotFound) {
+		return entity.UserModel{}, errors.Wrapf(err, "failed to find user[email:%q]", identity.Email)
+	}
+
+	username, err := s.generateUsername(ctx, identity)
+	if err != nil {
+		return entity.UserModel{}, err
+	}
+
+	now := time.Now()
+
+	user = entity.UserModel{
+		Username:   username,
+		Email:      identity.Email,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Identities: []entity.IdentityModel{newIdentityModel(provider, identity)},
+	}
+
+	err = s.usersRepo.Create(ctx, user)
+	if err != nil {
+		return entity.UserModel{}, errors.Wrapf(err, "failed to create %+v user", user)
+	}
+
+	user, err = s.usersRepo.FindByIdentity(ctx, provider, identity.Subject)
+	if err != nil {
+		return entity.UserModel{}, errors.Wrapf(err, "failed to find created user[%s:%q]", provider, identity.Subject)
+	}
+
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionSignUp,
+		ActorID: user.ID,
+		Details: map[string]string{"provider": provider},
+	})
+
+	return user, nil
+}
+
+// generateUsername returns free username derived from preferred username or email of the identity
+func (s *oidcService) generateUsername(ctx context.Context, identity oidc.Identity) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	base = sanitizeUsername(base)
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+
+	for i := 0; i < usernameAttempts; i++ {
+		if len(candidate) >= usernameMinLength {
+			available, err := s.profileServ.IsUsernameAvailable(ctx, candidate)
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to check availability of %q username", candidate)
+			} else if available {
+				return candidate, nil
+			}
+		}
+
+		suffix, err := randomString(usernameSuffixLength, usernameSuffixAlphabet)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to generate username suffix")
+		}
+
+		candidate = base + "_" + suffix
+	}
+
+	return "", errors.New("failed to generate free username")
+}
+
+// sanitizeUsername keeps only characters allowed in usernames
+func sanitizeUsername(s string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || (unicode.IsDigit(r) && b.Len() != 0)):
+			b.WriteRune(r)
+		case b.Len() != 0 && b.Len() < usernameBaseMaxLength && !strings.HasSuffix(b.String(), "_"):
+			b.WriteRune('_')
+		}
+
+		if b.Len() >= usernameBaseMaxLength {
+			break
+		}
+	}
+
+	return strings.Trim(b.String(), "_")
+}
+
+func newIdentityModel(provider string, identity oidc.Identity) entity.IdentityModel {
+	return entity.IdentityModel{
+		Provider: provider,
+		Subject:  identity.Subject,
+		LinkedAt: time.Now(),
+	}
+}
+
+func oidcStateCacheKey(state string) string {
+	return fmt.Sprintf("oidc:state:%s", state)
+}
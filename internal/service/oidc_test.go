@@ -0,0 +1,257 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
+	"github.com/kenplix/url-shrtnr/pkg/blob"
+	"github.com/kenplix/url-shrtnr/pkg/oidc"
+	"github.com/kenplix/url-shrtnr/pkg/oidc/oidctest"
+)
+
+func TestOIDCService_SignIn(t *testing.T) {
+	runWithCaches(t, testOIDCServiceSignIn)
+}
+
+func testOIDCServiceSignIn(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+	const testProvider = "fake"
+
+	server := oidctest.NewServer()
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+
+	repos, err := repository.New(ctx, repository.Config{
+		Use:    "filedb",
+		FileDB: repository.FileDBConfig{Path: t.TempDir()},
+	})
+	require.NoErrorf(t, err, "failed to create repositories: %s", err)
+
+	existing := entity.UserModel{
+		Username:  "kenplix",
+		Email:     "tolstoi.job@gmail.com",
+		CreatedAt: time.Now(),
+	}
+	require.NoError(t, repos.Users.Create(ctx, existing))
+
+	existing, err = repos.Users.FindByEmail(ctx, existing.Email)
+	require.NoError(t, err)
+
+	blobStore, err := blob.NewStore(blob.Config{Local: blob.LocalConfig{Path: t.TempDir()}})
+	require.NoErrorf(t, err, "failed to create blob store: %s", err)
+
+	profileServ, err := service.NewProfileService(service.ProfileServiceConfig{}, repos.Users, blobStore, testAuditService(t))
+	require.NoErrorf(t, err, "failed to create profile service: %s", err)
+
+	var (
+		jwtServ       = servMocks.NewJWTService(t)
+		twoFactorServ = servMocks.NewTwoFactorService(t)
+	)
+
+	jwtServ.
+		On("CreateTokens", mock.Anything, mock.Anything, entity.DefaultScopes).
+		Return(func(_ context.Context, userID string, _ []string) entity.Tokens {
+			return entity.Tokens{AccessToken: userID}
+		}, nil).
+		Maybe()
+
+	oidcServ, err := service.NewOIDCService(service.OIDCServiceConfig{
+		Providers: map[string]oidc.Config{
+			testProvider: {
+				Issuer:       server.URL,
+				ClientID:     oidctest.ClientID,
+				ClientSecret: oidctest.ClientSecret,
+				RedirectURL:  "http://localhost/api/v1/auth/oidc/fake/callback",
+			},
+		},
+	}, cache, repos.Users, jwtServ, twoFactorServ, testAuditService(t), profileServ)
+	require.NoErrorf(t, err, "failed to create oidc service: %s", err)
+
+	authorize := func(t *testing.T, userID primitive.ObjectID, claims map[string]any) service.OIDCCallbackSchema {
+		t.Helper()
+
+		authorization, err := oidcServ.Authorize(ctx, service.OIDCAuthorizeSchema{Provider: testProvider, UserID: userID})
+		require.NoErrorf(t, err, "failed to authorize: %s", err)
+		require.NotEmpty(t, authorization.Nonce)
+
+		server.SetClaims(claims)
+
+		callback, err := server.Authorize(authorization.AuthURL)
+		require.NoErrorf(t, err, "failed to pass consent page: %s", err)
+
+		return service.OIDCCallbackSchema{
+			Provider: testProvider,
+			State:    callback.Get("state"),
+			Code:     callback.Get("code"),
+			Nonce:    authorization.Nonce,
+		}
+	}
+
+	t.Run("unknown provider", func(t *testing.T) {
+		_, err := oidcServ.Authorize(ctx, service.OIDCAuthorizeSchema{Provider: "unknown"})
+		assert.ErrorIs(t, err, entity.ErrOIDCProviderNotFound)
+	})
+
+	t.Run("new user is created", func(t *testing.T) {
+		schema := authorize(t, primitive.NilObjectID, map[string]any{
+			"sub":                "new-user",
+			"email":              "New.User@Example.com",
+			"email_verified":     true,
+			"preferred_username": "new.user",
+		})
+
+		tokens, err := oidcServ.SignIn(ctx, schema)
+		require.NoError(t, err)
+
+		user, err := repos.Users.FindByIdentity(ctx, testProvider, "new-user")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID.Hex(), tokens.AccessToken)
+		assert.Equal(t, "new_user", user.Username)
+		assert.Equal(t, "new.user@example.com", user.Email)
+
+		_, err = oidcServ.SignIn(ctx, schema)
+		assert.ErrorIs(t, err, entity.ErrOIDCStateNotFound, "state must be single use")
+	})
+
+	t.Run("recently changed username is reserved", func(t *testing.T) {
+		renamed := entity.UserModel{
+			Username:  "old_name",
+			Email:     "renamed@example.com",
+			CreatedAt: time.Now(),
+		}
+		require.NoError(t, repos.Users.Create(ctx, renamed))
+
+		renamed, err := repos.Users.FindByEmail(ctx, renamed.Email)
+		require.NoError(t, err)
+
+		err = repos.Users.ChangeUsername(ctx, repository.ChangeUsernameSchema{
+			UserID:      renamed.ID,
+			NewUsername: "new_name",
+			ChangedAt:   time.Now(),
+		})
+		require.NoError(t, err)
+
+		_, err = oidcServ.SignIn(ctx, authorize(t, primitive.NilObjectID, map[string]any{
+			"sub":                "old-name-user",
+			"email":              "old.name@example.com",
+			"email_verified":     true,
+			"preferred_username": "old_name",
+		}))
+		require.NoError(t, err)
+
+		user, err := repos.Users.FindByIdentity(ctx, testProvider, "old-name-user")
+		require.NoError(t, err)
+		assert.NotEqual(t, "old_name", user.Username)
+		assert.True(t, strings.HasPrefix(user.Username, "old_name"), "username must be derived from the preferred one")
+	})
+
+	t.Run("callback opened in another browser", func(t *testing.T) {
+		schema := authorize(t, existing.ID, map[string]any{
+			"sub": "victim-account",
+		})
+
+		victimSchema := schema
+		victimSchema.Nonce = "<another browser nonce>"
+
+		_, err := oidcServ.SignIn(ctx, victimSchema)
+		assert.ErrorIs(t, err, entity.ErrOIDCStateNotFound)
+
+		_, err = repos.Users.FindByIdentity(ctx, testProvider, "victim-account")
+		assert.ErrorIs(t, err, entity.ErrUserNotFound, "identity must not be linked from another browser")
+
+		_, err = oidcServ.SignIn(ctx, schema)
+		assert.NoError(t, err, "state must be kept for the browser which started authorization")
+	})
+
+	t.Run("unverified local account exists", func(t *testing.T) {
+		_, err := oidcServ.SignIn(ctx, authorize(t, primitive.NilObjectID, map[string]any{
+			"sub":            "existing-user",
+			"email":          " Tolstoi.Job@Gmail.com",
+			"email_verified": true,
+		}))
+		require.ErrorIs(t, err, entity.ErrOIDCEmailInUse, "account with unverified email must not be linked automatically")
+
+		_, err = repos.Users.FindByIdentity(ctx, testProvider, "existing-user")
+		assert.ErrorIs(t, err, entity.ErrUserNotFound)
+
+		tokens, err := oidcServ.SignIn(ctx, authorize(t, existing.ID, map[string]any{
+			"sub":            "existing-user",
+			"email":          "tolstoi.job@gmail.com",
+			"email_verified": true,
+		}))
+		require.NoError(t, err, "signed in user must be able to link identity")
+		assert.Equal(t, existing.ID.Hex(), tokens.AccessToken)
+
+		tokens, err = oidcServ.SignIn(ctx, authorize(t, primitive.NilObjectID, map[string]any{
+			"sub": "existing-user",
+		}))
+		require.NoError(t, err, "linked identity must be found without email")
+		assert.Equal(t, existing.ID.Hex(), tokens.AccessToken)
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		_, err := oidcServ.SignIn(ctx, authorize(t, primitive.NilObjectID, map[string]any{
+			"sub":   "unverified-user",
+			"email": "unverified@example.com",
+		}))
+		assert.ErrorIs(t, err, entity.ErrOIDCEmailNotVerified)
+	})
+
+	t.Run("identity linked to another user", func(t *testing.T) {
+		_, err := oidcServ.SignIn(ctx, authorize(t, existing.ID, map[string]any{
+			"sub": "new-user",
+		}))
+		assert.ErrorIs(t, err, entity.ErrOIDCIdentityAlreadyLinked)
+	})
+
+	t.Run("signed in user links identity", func(t *testing.T) {
+		tokens, err := oidcServ.SignIn(ctx, authorize(t, existing.ID, map[string]any{
+			"sub": "second-account",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, existing.ID.Hex(), tokens.AccessToken)
+
+		user, err := repos.Users.FindByIdentity(ctx, testProvider, "second-account")
+		require.NoError(t, err)
+		assert.Equal(t, existing.ID, user.ID)
+	})
+
+	t.Run("second authentication factor required", func(t *testing.T) {
+		err := repos.Users.EnableTwoFactor(ctx, repository.EnableTwoFactorSchema{
+			UserID:    existing.ID,
+			TwoFactor: entity.TwoFactorModel{TOTPSecret: testTOTPSecret},
+		})
+		require.NoError(t, err)
+
+		twoFactorServ.
+			On("CreateChallenge", mock.Anything, existing.ID).
+			Return(entity.MFAChallenge{MFAToken: "<mfa token>"}, nil)
+
+		_, err = oidcServ.SignIn(ctx, authorize(t, primitive.NilObjectID, map[string]any{
+			"sub": "existing-user",
+		}))
+
+		var mfaRequiredErr *entity.MFARequiredError
+		require.ErrorAs(t, err, &mfaRequiredErr)
+		assert.Equal(t, "<mfa token>", mfaRequiredErr.Challenge.MFAToken)
+	})
+
+	t.Run("provider authentication failed", func(t *testing.T) {
+		schema := authorize(t, primitive.NilObjectID, map[string]any{"sub": "existing-user"})
+		schema.Code = "invalid"
+
+		_, err := oidcServ.SignIn(ctx, schema)
+		assert.ErrorIs(t, err, entity.ErrOIDCAuthenticationFailed)
+	})
+}
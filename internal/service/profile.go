@@ -0,0 +1,384 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"time"
+
+	// register decoders of supported avatar formats
+	_ "image/gif"
+	_ "image/jpeg"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/pkg/blob"
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+const (
+	defaultUsernameChangeCooldown = 30 * 24 * time.Hour
+	defaultUsernameRedirectPeriod = 30 * 24 * time.Hour
+	defaultAvatarSize             = 256
+	// maxAvatarPixels protects from decompression bombs, image is rejected before decoding its pixels
+	maxAvatarPixels = 25_000_000
+	avatarKeyPrefix = "avatars/"
+)
+
+type ProfileServiceConfig struct {
+	// UsernameChangeCooldown is a minimal period between username changes
+	UsernameChangeCooldown time.Duration `mapstructure:"usernameChangeCooldown"`
+	// UsernameRedirectPeriod is a period during which previous username redirects to the current one
+	// and can't be taken by another user
+	UsernameRedirectPeriod time.Duration `mapstructure:"usernameRedirectPeriod"`
+	// AvatarSize is a side of square avatar image in pixels
+	AvatarSize int `mapstructure:"avatarSize"`
+}
+
+type profileService struct {
+	usersRepo      repository.UsersRepository
+	blobStore      blob.Store
+	auditServ      AuditService
+	changeCooldown time.Duration
+	redirectPeriod time.Duration
+	avatarSize     int
+}
+
+func NewProfileService(
+	cfg ProfileServiceConfig,
+	usersRepo repository.UsersRepository,
+	blobStore blob.Store,
+	auditServ AuditService,
+) (ProfileService, error) {
+	if usersRepo == nil {
+		return nil, errors.New("users repository not provided")
+	}
+
+	if blobStore == nil {
+		return nil, errors.New("blob store not provided")
+	}
+
+	if auditServ == nil {
+		return nil, errors.New("audit service not provided")
+	}
+
+	s := &profileService{
+		usersRepo:      usersRepo,
+		blobStore:      blobStore,
+		auditServ:      auditServ,
+		changeCooldown: lo.Ternary(cfg.UsernameChangeCooldown > 0, cfg.UsernameChangeCooldown, defaultUsernameChangeCooldown),
+		redirectPeriod: lo.Ternary(cfg.UsernameRedirectPeriod > 0, cfg.UsernameRedirectPeriod, defaultUsernameRedirectPeriod),
+		avatarSize:     lo.Ternary(cfg.AvatarSize > 0, cfg.AvatarSize, defaultAvatarSize),
+	}
+
+	return s, nil
+}
+
+func (s *profileService) GetProfile(ctx context.Context, username string) (entity.Profile, error) {
+	user, err := s.usersRepo.FindByUsername(ctx, username)
+	if err == nil {
+		return user.Profile(), nil
+	} else if !errors.Is(err, entity.ErrUserNotFound) {
+		return entity.Profile{}, errors.Wrapf(err, "failed to find user[username:%q]", username)
+	}
+
+	user, err = s.usersRepo.FindByPreviousUsername(ctx, username, time.Now().Add(-s.redirectPeriod))
+	if err != nil {
+		return entity.Profile{}, errors.Wrapf(err, "failed to find user[previousUsername:%q]", username)
+	}
+
+	return entity.Profile{}, &entity.UsernameMovedError{Username: user.Username}
+}
+
+func (s *profileService) ChangeUsername(ctx context.Context, schema ChangeUsernameSchema) (entity.User, error) {
+	user, err := s.usersRepo.FindByID(ctx, schema.UserID)
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "failed to get user[id:%q]", schema.UserID.Hex())
+	}
+
+	if err = checkUserVersion(user, schema.Version); err != nil {
+		return entity.User{}, err
+	}
+
+	if user.Username == schema.NewUsername {
+		return user.Filter(), nil
+	}
+
+	now := time.Now()
+
+	if user.UsernameChangedAt != nil {
+		availableAt := user.UsernameChangedAt.Add(s.changeCooldown)
+		if now.Before(availableAt) {
+			return entity.User{}, &entity.UsernameChangeCooldownError{AvailableAt: availableAt}
+		}
+	}
+
+	// username which redirects to another user is reserved until redirect period is over,
+	// while own previous username may be returned back
+	owner, err := s.usersRepo.FindByPreviousUsername(ctx, schema.NewUsername, now.Add(-s.redirectPeriod))
+	if err == nil && owner.ID != user.ID {
+		return entity.User{}, usernameTakenError()
+	} else if err != nil && !errors.Is(err, entity.ErrUserNotFound) {
+		return entity.User{}, errors.Wrapf(err, "failed to find user[previousUsername:%q]", schema.NewUsername)
+	}
+
+	err = s.usersRepo.ChangeUsername(ctx, repository.ChangeUsernameSchema{
+		UserID:      schema.UserID,
+		NewUsername: schema.NewUsername,
+		ChangedAt:   now,
+		Version:     schema.Version,
+	})
+	if err != nil {
+		if errors.Is(err, entity.ErrUsernameTaken) {
+			return entity.User{}, usernameTakenError()
+		}
+
+		return entity.User{}, errors.Wrapf(err, "user[id:%q]: failed to change username", schema.UserID.Hex())
+	}
+
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionUsernameChanged,
+		ActorID: schema.UserID,
+		Details: map[string]string{"oldUsername": user.Username, "newUsername": schema.NewUsername},
+	})
+
+	return s.getUser(ctx, schema.UserID)
+}
+
+func (s *profileService) IsUsernameAvailable(ctx context.Context, username string) (bool, error) {
+	_, err := s.usersRepo.FindByUsername(ctx, username)
+	if err == nil {
+		return false, nil
+	} else if !errors.Is(err, entity.ErrUserNotFound) {
+		return false, errors.Wrapf(err, "failed to find user[username:%q]", username)
+	}
+
+	_, err = s.usersRepo.FindByPreviousUsername(ctx, username, time.Now().Add(-s.redirectPeriod))
+	if err == nil {
+		return false, nil
+	} else if !errors.Is(err, entity.ErrUserNotFound) {
+		return false, errors.Wrapf(err, "failed to find user[previousUsername:%q]", username)
+	}
+
+	return true, nil
+}
+
+func (s *profileService) UpdateProfile(ctx context.Context, schema UpdateProfileSchema) (entity.User, error) {
+	user, err := s.usersRepo.FindByID(ctx, schema.UserID)
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "failed to get user[id:%q]", schema.UserID.Hex())
+	}
+
+	if err = checkUserVersion(user, schema.Version); err != nil {
+		return entity.User{}, err
+	}
+
+	err = s.usersRepo.UpdateProfile(ctx, repository.UpdateProfileSchema{
+		UserID:      schema.UserID,
+		DisplayName: lo.FromPtrOr(schema.DisplayName, user.DisplayName),
+		Bio:         lo.FromPtrOr(schema.Bio, user.Bio),
+		Version:     schema.Version,
+	})
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "user[id:%q]: failed to update profile", schema.UserID.Hex())
+	}
+
+	return s.getUser(ctx, schema.UserID)
+}
+
+func (s *profileService) ChangeAvatar(ctx context.Context, schema ChangeAvatarSchema) (entity.User, error) {
+	user, err := s.usersRepo.FindByID(ctx, schema.UserID)
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "failed to get user[id:%q]", schema.UserID.Hex())
+	}
+
+	if err = checkUserVersion(user, schema.Version); err != nil {
+		return entity.User{}, err
+	}
+
+	avatar, err := s.processAvatar(schema.Content)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	// key changes together with image, so served avatars may be cached forever. User ID is hashed
+	// as well, so deletion of replaced avatar doesn't affect other users with the same image
+	h := sha256.New()
+	h.Write(schema.UserID[:])
+	h.Write(avatar)
+
+	key := avatarKeyPrefix + hex.EncodeToString(h.Sum(nil)) + ".png"
+
+	err = s.blobStore.Put(ctx, key, "image/png", bytes.NewReader(avatar))
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "user[id:%q]: failed to store avatar", schema.UserID.Hex())
+	}
+
+	err = s.usersRepo.ChangeAvatar(ctx, repository.ChangeAvatarSchema{UserID: schema.UserID, Avatar: key, Version: schema.Version})
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "user[id:%q]: failed to change avatar", schema.UserID.Hex())
+	}
+
+	if user.Avatar != key {
+		s.deleteAvatar(ctx, user.Avatar)
+	}
+
+	return s.getUser(ctx, schema.UserID)
+}
+
+func (s *profileService) DeleteAvatar(ctx context.Context, schema DeleteAvatarSchema) (entity.User, error) {
+	user, err := s.usersRepo.FindByID(ctx, schema.UserID)
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "failed to get user[id:%q]", schema.UserID.Hex())
+	}
+
+	if err = checkUserVersion(user, schema.Version); err != nil {
+		return entity.User{}, err
+	}
+
+	if user.Avatar == "" {
+		return user.Filter(), nil
+	}
+
+	err = s.usersRepo.ChangeAvatar(ctx, repository.ChangeAvatarSchema{UserID: schema.UserID, Version: schema.Version})
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "user[id:%q]: failed to remove avatar", schema.UserID.Hex())
+	}
+
+	s.deleteAvatar(ctx, user.Avatar)
+
+	return s.getUser(ctx, schema.UserID)
+}
+
+func (s *profileService) GetAvatar(ctx context.Context, key string) (blob.Object, error) {
+	if err := blob.ValidateKey(key); err != nil || !strings.HasPrefix(key, avatarKeyPrefix) {
+		return blob.Object{}, blob.ErrNotFound
+	}
+
+	object, err := s.blobStore.Get(ctx, key)
+	if err != nil {
+		return blob.Object{}, errors.Wrapf(err, "failed to get avatar[key:%q]", key)
+	}
+
+	return object, nil
+}
+
+func (s *profileService) getUser(ctx context.Context, userID primitive.ObjectID) (entity.User, error) {
+	user, err := s.usersRepo.FindByID(ctx, userID)
+	if err != nil {
+		return entity.User{}, errors.Wrapf(err, "failed to get user[id:%q]", userID.Hex())
+	}
+
+	return user.Filter(), nil
+}
+
+// deleteAvatar removes replaced avatar, failure is only logged since avatar is already detached from the user
+func (s *profileService) deleteAvatar(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+
+	if err := s.blobStore.Delete(ctx, key); err != nil {
+		log.LoggerFromContext(ctx).Warn("failed to delete avatar",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+	}
+}
+
+// processAvatar decodes JPEG, PNG or GIF image, crops it to square and downscales to avatar size
+func (s *profileService) processAvatar(content io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+
+	cfg, _, err := image.DecodeConfig(io.TeeReader(content, &buf))
+	if err != nil {
+		return nil, entity.ErrUnsupportedImage
+	}
+
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxAvatarPixels {
+		return nil, entity.ErrUnsupportedImage
+	}
+
+	img, _, err := image.Decode(io.MultiReader(&buf, content))
+	if err != nil {
+		return nil, entity.ErrUnsupportedImage
+	}
+
+	avatar := resizeImage(img, cropSquare(img), s.avatarSize)
+
+	var out bytes.Buffer
+	if err = png.Encode(&out, avatar); err != nil {
+		return nil, errors.Wrap(err, "failed to encode avatar")
+	}
+
+	return out.Bytes(), nil
+}
+
+// cropSquare returns centered square part of the image
+func cropSquare(img image.Image) image.Rectangle {
+	b := img.Bounds()
+
+	side := lo.Min([]int{b.Dx(), b.Dy()})
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// resizeImage scales square region of the image to size x size with box filter,
+// smaller images are upscaled with nearest neighbour
+func resizeImage(img image.Image, region image.Rectangle, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	side := region.Dx()
+
+	for dy := 0; dy < size; dy++ {
+		y0 := region.Min.Y + dy*side/size
+		y1 := lo.Max([]int{region.Min.Y + (dy+1)*side/size, y0 + 1})
+
+		for dx := 0; dx < size; dx++ {
+			x0 := region.Min.X + dx*side/size
+			x1 := lo.Max([]int{region.Min.X + (dx+1)*side/size, x0 + 1})
+
+			var r, g, b, a, n uint64
+
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					cr, cg, cb, ca := img.At(x, y).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			dst.SetRGBA(dx, dy, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return dst
+}
+
+func usernameTakenError() error {
+	return &entity.ValidationError{
+		CoreError: entity.CoreError{
+			Code:    errorcode.AlreadyExists,
+			Message: entity.ErrUsernameTaken.Error(),
+		},
+		Field: "username",
+	}
+}
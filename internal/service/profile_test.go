@@ -0,0 +1,186 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/entity/errorcode"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	"github.com/kenplix/url-shrtnr/pkg/blob"
+)
+
+func newProfileTestService(t *testing.T, cfg service.ProfileServiceConfig) (service.ProfileService, *repository.Repositories, blob.Store) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	repos, err := repository.New(ctx, repository.Config{
+		Use:    "filedb",
+		FileDB: repository.FileDBConfig{Path: t.TempDir()},
+	})
+	require.NoErrorf(t, err, "failed to create repositories: %s", err)
+
+	t.Cleanup(func() {
+		_ = repos.Close(ctx)
+	})
+
+	blobStore, err := blob.NewStore(blob.Config{Local: blob.LocalConfig{Path: t.TempDir()}})
+	require.NoErrorf(t, err, "failed to create blob store: %s", err)
+
+	profileServ, err := service.NewProfileService(cfg, repos.Users, blobStore, testAuditService(t))
+	require.NoErrorf(t, err, "failed to create profile service: %s", err)
+
+	return profileServ, repos, blobStore
+}
+
+func TestProfileService_ChangeUsername(t *testing.T) {
+	ctx := context.Background()
+
+	profileServ, repos, _ := newProfileTestService(t, service.ProfileServiceConfig{UsernameChangeCooldown: time.Hour})
+
+	user := entity.UserModel{ID: primitive.NewObjectID(), Username: "kenplix"}
+	other := entity.UserModel{ID: primitive.NewObjectID(), Username: "other"}
+
+	require.NoError(t, repos.Users.Create(ctx, user))
+	require.NoError(t, repos.Users.Create(ctx, other))
+
+	var validationErr *entity.ValidationError
+
+	_, err := profileServ.ChangeUsername(ctx, service.ChangeUsernameSchema{UserID: user.ID, NewUsername: "other"})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, errorcode.AlreadyExists, validationErr.Code)
+
+	changed, err := profileServ.ChangeUsername(ctx, service.ChangeUsernameSchema{UserID: user.ID, NewUsername: "tolstoi"})
+	require.NoError(t, err)
+	assert.Equal(t, "tolstoi", changed.Username)
+	require.NotNil(t, changed.UsernameChangedAt)
+
+	_, err = profileServ.ChangeUsername(ctx, service.ChangeUsernameSchema{UserID: user.ID, NewUsername: "kenplix2"})
+
+	var cooldownErr *entity.UsernameChangeCooldownError
+	require.ErrorAs(t, err, &cooldownErr)
+	assert.WithinDuration(t, changed.UsernameChangedAt.Add(time.Hour), cooldownErr.AvailableAt, time.Second)
+
+	profile, err := profileServ.GetProfile(ctx, "tolstoi")
+	require.NoError(t, err)
+	assert.Equal(t, "tolstoi", profile.Username)
+
+	_, err = profileServ.GetProfile(ctx, "kenplix")
+
+	var movedErr *entity.UsernameMovedError
+	require.ErrorAs(t, err, &movedErr)
+	assert.Equal(t, "tolstoi", movedErr.Username)
+
+	_, err = profileServ.ChangeUsername(ctx, service.ChangeUsernameSchema{UserID: other.ID, NewUsername: "kenplix"})
+	require.ErrorAs(t, err, &validationErr, "previous username must be reserved during redirect period")
+
+	for username, expected := range map[string]bool{"tolstoi": false, "kenplix": false, "free": true} {
+		available, err := profileServ.IsUsernameAvailable(ctx, username)
+		require.NoError(t, err)
+		assert.Equalf(t, expected, available, "availability of %q username", username)
+	}
+
+	_, err = profileServ.GetProfile(ctx, "unknown")
+	assert.ErrorIs(t, err, entity.ErrUserNotFound)
+}
+
+func TestProfileService_UpdateProfile(t *testing.T) {
+	ctx := context.Background()
+
+	profileServ, repos, _ := newProfileTestService(t, service.ProfileServiceConfig{})
+
+	user := entity.UserModel{ID: primitive.NewObjectID(), Username: "kenplix", Bio: "Go developer"}
+	require.NoError(t, repos.Users.Create(ctx, user))
+
+	displayName := "Oleksandr Tolstoi"
+
+	updated, err := profileServ.UpdateProfile(ctx, service.UpdateProfileSchema{UserID: user.ID, DisplayName: &displayName})
+	require.NoError(t, err)
+	assert.Equal(t, displayName, updated.DisplayName)
+	assert.Equal(t, "Go developer", updated.Bio, "omitted fields must be kept")
+
+	bio := ""
+
+	updated, err = profileServ.UpdateProfile(ctx, service.UpdateProfileSchema{UserID: user.ID, Bio: &bio})
+	require.NoError(t, err)
+	assert.Equal(t, displayName, updated.DisplayName)
+	assert.Empty(t, updated.Bio)
+
+	_, err = profileServ.UpdateProfile(ctx, service.UpdateProfileSchema{UserID: user.ID, Bio: &bio, Version: updated.Version - 1})
+	assert.ErrorIs(t, err, entity.ErrUserVersionConflict, "profile must not be updated from stale version")
+
+	versioned, err := profileServ.UpdateProfile(ctx, service.UpdateProfileSchema{UserID: user.ID, Bio: &bio, Version: updated.Version})
+	require.NoError(t, err)
+	assert.Equal(t, updated.Version+1, versioned.Version)
+}
+
+func TestProfileService_Avatar(t *testing.T) {
+	ctx := context.Background()
+
+	profileServ, repos, blobStore := newProfileTestService(t, service.ProfileServiceConfig{AvatarSize: 32})
+
+	user := entity.UserModel{ID: primitive.NewObjectID(), Username: "kenplix"}
+	require.NoError(t, repos.Users.Create(ctx, user))
+
+	_, err := profileServ.ChangeAvatar(ctx, service.ChangeAvatarSchema{UserID: user.ID, Content: strings.NewReader("not an image")})
+	require.ErrorIs(t, err, entity.ErrUnsupportedImage)
+
+	first, err := profileServ.ChangeAvatar(ctx, service.ChangeAvatarSchema{UserID: user.ID, Content: bytes.NewReader(testJPEG(t, 300, 200))})
+	require.NoError(t, err)
+	require.NotEmpty(t, first.Avatar)
+
+	object, err := profileServ.GetAvatar(ctx, first.Avatar)
+	require.NoError(t, err)
+
+	img, err := png.Decode(object)
+	require.NoError(t, err)
+	require.NoError(t, object.Close())
+	assert.Equal(t, image.Rect(0, 0, 32, 32), img.Bounds())
+	assert.Equal(t, "image/png", object.ContentType)
+
+	second, err := profileServ.ChangeAvatar(ctx, service.ChangeAvatarSchema{UserID: user.ID, Content: bytes.NewReader(testJPEG(t, 10, 40))})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Avatar, second.Avatar)
+
+	_, err = blobStore.Get(ctx, first.Avatar)
+	assert.ErrorIs(t, err, blob.ErrNotFound, "replaced avatar must be deleted")
+
+	deleted, err := profileServ.DeleteAvatar(ctx, service.DeleteAvatarSchema{UserID: user.ID})
+	require.NoError(t, err)
+	assert.Empty(t, deleted.Avatar)
+
+	_, err = profileServ.GetAvatar(ctx, second.Avatar)
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+
+	_, err = profileServ.GetAvatar(ctx, "exports/../secret")
+	assert.ErrorIs(t, err, blob.ErrNotFound, "keys outside avatars must not be served")
+}
+
+func testJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+
+	return buf.Bytes()
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// randomHex returns hex encoded string of size cryptographically secure random bytes
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "failed to read random bytes")
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// randomString returns cryptographically secure random string of length n built from alphabet
+func randomString(n int, alphabet string) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+	buf := make([]byte, n)
+
+	for i := range buf {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to generate random index")
+		}
+
+		buf[i] = alphabet[idx.Int64()]
+	}
+
+	return string(buf), nil
+}
+
+// hashSecret returns hex encoded SHA-256 hash of the secret. Unlike passwords,
+// secrets are long random strings, so slow password hashing isn't required to protect them
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
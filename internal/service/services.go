@@ -2,14 +2,17 @@ package service
 
 import (
 	"context"
+	"io"
+	"time"
 
-	"github.com/go-redis/redis/v9"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/kenplix/url-shrtnr/internal/entity"
 	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/pkg/blob"
 	"github.com/kenplix/url-shrtnr/pkg/hash"
+	"github.com/kenplix/url-shrtnr/pkg/mail"
 	"github.com/kenplix/url-shrtnr/pkg/token"
 )
 
@@ -17,12 +20,18 @@ import (
 //
 //go:generate mockery --dir . --name JWTService --output ./mocks
 type JWTService interface {
-	CreateTokens(ctx context.Context, userID string) (entity.Tokens, error)
+	CreateTokens(ctx context.Context, userID string, scopes []string) (entity.Tokens, error)
 	ProlongTokens(ctx context.Context, userID string)
 	ParseAccessToken(token string) (*token.JWTCustomClaims, error)
 	ParseRefreshToken(token string) (*token.JWTCustomClaims, error)
 	ValidateAccessToken(ctx context.Context, claims *token.JWTCustomClaims) error
 	ValidateRefreshToken(ctx context.Context, claims *token.JWTCustomClaims) error
+	// RevokeTokens invalidates all tokens of the user issued before the call
+	RevokeTokens(ctx context.Context, userID string) error
+	// AccessTokenJWKS returns public keys which may be used by other services to verify access tokens
+	AccessTokenJWKS() token.JWKS
+	// ReloadKeys re-reads signing keys of access and refresh tokens
+	ReloadKeys() error
 }
 
 type UserSignUpSchema struct {
@@ -34,6 +43,13 @@ type UserSignUpSchema struct {
 type UserSignInSchema struct {
 	Login    string
 	Password string
+	// IP is an address of the client, used for brute-force protection
+	IP string
+}
+
+type UserSignInMFASchema struct {
+	MFAToken string
+	Code     string
 }
 
 // AuthService is a service for authorization/authentication
@@ -42,41 +58,367 @@ type UserSignInSchema struct {
 type AuthService interface {
 	SignUp(ctx context.Context, schema UserSignUpSchema) error
 	SignIn(ctx context.Context, schema UserSignInSchema) (entity.Tokens, error)
+	SignInMFA(ctx context.Context, schema UserSignInMFASchema) (entity.Tokens, error)
 	SignOut(ctx context.Context, userID primitive.ObjectID) error
+	// RefreshTokens issues new tokens pair with current scopes of the user, refresh token must be validated beforehand.
+	// *entity.SuspendedUserError is returned for suspended user and entity.ErrDeletionScheduled for user scheduled for deletion
+	RefreshTokens(ctx context.Context, userID primitive.ObjectID) (entity.Tokens, error)
 }
 
 type ChangeEmailSchema struct {
 	UserID   primitive.ObjectID
 	NewEmail string
+	// Version is expected version of the user, it isn't checked if zero
+	Version int64
 }
 
 type ChangePasswordSchema struct {
 	UserID          primitive.ObjectID
 	CurrentPassword string
 	NewPassword     string
+	// KeepCurrentSession keeps the current device signed in with new tokens pair.
+	// Tokens issued before the change are revoked on every device regardless of it
+	KeepCurrentSession bool
+	// Version is expected version of the user, it isn't checked if zero
+	Version int64
 }
 
-// UsersService is a service for users
+// UsersService is a service for users, changes return entity.ErrUserVersionConflict
+// if the user was changed since expected version
 //
 //go:generate mockery --dir . --name UsersService --output ./mocks
 type UsersService interface {
 	GetByID(ctx context.Context, userID primitive.ObjectID) (entity.User, error)
 	ChangeEmail(ctx context.Context, schema ChangeEmailSchema) error
-	ChangePassword(ctx context.Context, schema ChangePasswordSchema) error
+	// ChangePassword revokes all tokens of the user and returns new tokens pair when current session is kept
+	ChangePassword(ctx context.Context, schema ChangePasswordSchema) (entity.Tokens, error)
+}
+
+type ConfirmTwoFactorSchema struct {
+	UserID primitive.ObjectID
+	Code   string
+}
+
+type DisableTwoFactorSchema struct {
+	UserID   primitive.ObjectID
+	Password string
+	Code     string
+}
+
+type VerifyMFAChallengeSchema struct {
+	MFAToken string
+	Code     string
+}
+
+// TwoFactorService is a service for TOTP two-factor authentication
+//
+//go:generate mockery --dir . --name TwoFactorService --output ./mocks
+type TwoFactorService interface {
+	Enroll(ctx context.Context, userID primitive.ObjectID) (entity.TwoFactorEnrollment, error)
+	ConfirmEnrollment(ctx context.Context, schema ConfirmTwoFactorSchema) (entity.RecoveryCodes, error)
+	Disable(ctx context.Context, schema DisableTwoFactorSchema) error
+	CreateChallenge(ctx context.Context, userID primitive.ObjectID) (entity.MFAChallenge, error)
+	// VerifyChallenge returns user who passed the challenge. User of the challenge is returned
+	// together with entity.ErrIncorrectTwoFactorCode, so failed attempt can be attributed to it
+	VerifyChallenge(ctx context.Context, schema VerifyMFAChallengeSchema) (entity.UserModel, error)
+	// VerifyCode checks TOTP or recovery code of the user with enabled two-factor authentication,
+	// it's used to re-authenticate user before dangerous actions
+	VerifyCode(ctx context.Context, user entity.UserModel, code string) error
+}
+
+type ThrottleSchema struct {
+	Login string
+	// MagicLink is an email to which sign in links are requested. Requests are counted apart from Login,
+	// so flooding them can't lock out password sign in
+	MagicLink string
+	IP        string
+	// MagicLinkIP is an IP from which sign in links are requested. Requests are counted apart from IP,
+	// so flooding them can't lock out sign in from the shared address
+	MagicLinkIP string
+}
+
+// ThrottleService protects sign in from brute-force attacks with exponential backoff and temporary lockout
+//
+//go:generate mockery --dir . --name ThrottleService --output ./mocks
+type ThrottleService interface {
+	// Reserve counts attempt of login, magic link email and IP before it's verified. *entity.TooManyAttemptsError
+	// is returned without counting if any of them is currently blocked or has reached lockout threshold
+	Reserve(ctx context.Context, schema ThrottleSchema) error
+	// Fail blocks login, magic link email and IP of the failed reserved attempt according to number of attempts
+	Fail(ctx context.Context, schema ThrottleSchema) error
+	// Release rolls back reserved attempt which succeeded
+	Release(ctx context.Context, schema ThrottleSchema) error
+	// Reset clears failed attempts and link requests of login after successful sign in
+	Reset(ctx context.Context, login string) error
+	// Unlock clears failed attempts and lockout of login, magic link email and/or IPs
+	Unlock(ctx context.Context, schema ThrottleSchema) error
+}
+
+type CreateAPIKeySchema struct {
+	UserID    primitive.ObjectID
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+type RevokeAPIKeySchema struct {
+	UserID primitive.ObjectID
+	KeyID  primitive.ObjectID
+}
+
+// APIKeysService is a service for users personal access tokens
+//
+//go:generate mockery --dir . --name APIKeysService --output ./mocks
+type APIKeysService interface {
+	// Create returns created key together with its secret, which can't be recovered afterwards
+	Create(ctx context.Context, schema CreateAPIKeySchema) (entity.CreatedAPIKey, error)
+	List(ctx context.Context, userID primitive.ObjectID) ([]entity.APIKey, error)
+	Revoke(ctx context.Context, schema RevokeAPIKeySchema) error
+	// Authenticate returns not expired key matching provided secret and records its usage
+	Authenticate(ctx context.Context, apiKey string) (entity.APIKeyModel, error)
+}
+
+type OIDCAuthorizeSchema struct {
+	Provider string
+	// UserID is set when provider account is linked to the signed in user
+	UserID primitive.ObjectID
+}
+
+// OIDCAuthorization binds authorization to the browser which started it. Nonce must be
+// returned on callback, so callback opened in another browser is rejected
+type OIDCAuthorization struct {
+	AuthURL string
+	Nonce   string
+	TTL     time.Duration
+}
+
+type OIDCCallbackSchema struct {
+	Provider string
+	State    string
+	Code     string
+	Nonce    string
+}
+
+// OIDCService is a service for sign in with external OpenID Connect and OAuth2 providers
+//
+//go:generate mockery --dir . --name OIDCService --output ./mocks
+type OIDCService interface {
+	// Authorize returns URL of the provider consent page and nonce binding authorization to the browser
+	Authorize(ctx context.Context, schema OIDCAuthorizeSchema) (OIDCAuthorization, error)
+	// SignIn completes authorization and returns tokens pair of the linked, found by verified
+	// email or created user. *entity.MFARequiredError is returned for users with two-factor authentication
+	SignIn(ctx context.Context, schema OIDCCallbackSchema) (entity.Tokens, error)
+}
+
+type MagicLinkRequestSchema struct {
+	Email string
+	IP    string
+}
+
+// MagicLinkBinding binds requested link to the browser. Nonce must be returned
+// on sign in, so link forwarded to another browser can't be used
+type MagicLinkBinding struct {
+	Nonce string
+	TTL   time.Duration
+}
+
+type MagicLinkSignInSchema struct {
+	Token string
+	Nonce string
+	IP    string
+}
+
+// MagicLinkService is a service for passwordless sign in with single-use links sent by email
+//
+//go:generate mockery --dir . --name MagicLinkService --output ./mocks
+type MagicLinkService interface {
+	// Request sends link to the user with provided email. Binding is returned
+	// whether user exists or not, so response doesn't reveal registered emails
+	Request(ctx context.Context, schema MagicLinkRequestSchema) (MagicLinkBinding, error)
+	// SignIn exchanges link token for tokens pair. *entity.MFARequiredError is returned
+	// for users with two-factor authentication
+	SignIn(ctx context.Context, schema MagicLinkSignInSchema) (entity.Tokens, error)
+}
+
+type ListAuditEventsSchema struct {
+	// UserID matches events performed by or on the user, events of all users are listed if empty
+	UserID  primitive.ObjectID
+	Action  string
+	Page    int
+	PerPage int
+}
+
+// AuditService is a service for security audit log
+//
+//go:generate mockery --dir . --name AuditService --output ./mocks
+type AuditService interface {
+	// Record writes event, client IP, user agent and request ID are taken from the context
+	Record(ctx context.Context, event entity.AuditEventModel) error
+	List(ctx context.Context, schema ListAuditEventsSchema) (entity.AuditEventsPage, error)
+}
+
+type ListUsersSchema struct {
+	// Search matches username or email, all users are listed if empty
+	Search  string
+	Page    int
+	PerPage int
+}
+
+type SuspendUserSchema struct {
+	AdminID primitive.ObjectID
+	UserID  primitive.ObjectID
+	Reason  string
+}
+
+type AdminActionSchema struct {
+	AdminID primitive.ObjectID
+	UserID  primitive.ObjectID
+}
+
+type SetRoleSchema struct {
+	// AdminID is empty when role is set from command line, e.g. to bootstrap the first administrator
+	AdminID primitive.ObjectID
+	UserID  primitive.ObjectID
+	Role    string
+}
+
+type UnlockSignInSchema struct {
+	AdminID primitive.ObjectID
+	Login   string
+	IP      string
+}
+
+// AdminService is a service for users management by administrators.
+// Every action which changes state is written to the audit log
+//
+//go:generate mockery --dir . --name AdminService --output ./mocks
+type AdminService interface {
+	ListUsers(ctx context.Context, schema ListUsersSchema) (entity.UsersPage, error)
+	GetUser(ctx context.Context, userID primitive.ObjectID) (entity.User, error)
+	// SuspendUser prevents user from using the service and signs user out from all devices
+	SuspendUser(ctx context.Context, schema SuspendUserSchema) error
+	UnsuspendUser(ctx context.Context, schema AdminActionSchema) error
+	// SignOutUser revokes all tokens of the user
+	SignOutUser(ctx context.Context, schema AdminActionSchema) error
+	// ResetTwoFactor disables two-factor authentication of the user who lost access to it
+	ResetTwoFactor(ctx context.Context, schema AdminActionSchema) error
+	// UnlockSignIn clears sign in lockout of login and/or IP
+	UnlockSignIn(ctx context.Context, schema UnlockSignInSchema) error
+	// SetRole grants role to the user, entity.ErrUnknownRole is returned for roles not listed in entity.Roles
+	SetRole(ctx context.Context, schema SetRoleSchema) error
+}
+
+type DataExportSchema struct {
+	UserID   primitive.ObjectID
+	ExportID string
+}
+
+type ScheduleDeletionSchema struct {
+	UserID   primitive.ObjectID
+	Password string
+	// Token is one-time deletion confirmation sent by email to user who has no password
+	Token string
+	// Code is required when user has enabled two-factor authentication
+	Code string
+}
+
+// AccountService is a service for personal data export and account self-deletion
+//
+//go:generate mockery --dir . --name AccountService --output ./mocks
+type AccountService interface {
+	// RequestExport starts preparation of personal data archive in background
+	RequestExport(ctx context.Context, userID primitive.ObjectID) (entity.DataExport, error)
+	GetExport(ctx context.Context, schema DataExportSchema) (entity.DataExport, error)
+	// DownloadExport returns ZIP archive of the ready export
+	DownloadExport(ctx context.Context, schema DataExportSchema) ([]byte, error)
+	// ScheduleDeletion re-authenticates user, schedules deletion after grace period
+	// and signs user out from all devices. User who has no password gets deletion confirmation
+	// by email first and entity.ErrDeletionConfirmationSent is returned
+	ScheduleDeletion(ctx context.Context, schema ScheduleDeletionSchema) (entity.User, error)
+	CancelDeletion(ctx context.Context, userID primitive.ObjectID) (entity.User, error)
+	// PurgeDeleted deletes accounts whose grace period is over together with owned data
+	// and returns number of deleted accounts
+	PurgeDeleted(ctx context.Context) (int, error)
+}
+
+type ChangeUsernameSchema struct {
+	UserID      primitive.ObjectID
+	NewUsername string
+	// Version is expected version of the user, it isn't checked if zero
+	Version int64
+}
+
+// UpdateProfileSchema changes only provided fields, empty string clears the field
+type UpdateProfileSchema struct {
+	UserID      primitive.ObjectID
+	DisplayName *string
+	Bio         *string
+	// Version is expected version of the user, it isn't checked if zero
+	Version int64
+}
+
+type ChangeAvatarSchema struct {
+	UserID  primitive.ObjectID
+	Content io.Reader
+	// Version is expected version of the user, it isn't checked if zero
+	Version int64
+}
+
+type DeleteAvatarSchema struct {
+	UserID primitive.ObjectID
+	// Version is expected version of the user, it isn't checked if zero
+	Version int64
+}
+
+// ProfileService is a service for public profile of the user: username, display name, bio and avatar.
+// Changes return entity.ErrUserVersionConflict if the user was changed since expected version
+//
+//go:generate mockery --dir . --name ProfileService --output ./mocks
+type ProfileService interface {
+	// GetProfile returns *entity.UsernameMovedError if username was recently changed
+	GetProfile(ctx context.Context, username string) (entity.Profile, error)
+	// ChangeUsername returns *entity.UsernameChangeCooldownError if username was changed recently
+	// and *entity.ValidationError if username is taken or reserved by another user
+	ChangeUsername(ctx context.Context, schema ChangeUsernameSchema) (entity.User, error)
+	// IsUsernameAvailable reports whether username is neither taken nor reserved by user who recently changed it
+	IsUsernameAvailable(ctx context.Context, username string) (bool, error)
+	UpdateProfile(ctx context.Context, schema UpdateProfileSchema) (entity.User, error)
+	// ChangeAvatar crops and resizes image, entity.ErrUnsupportedImage is returned for invalid images
+	ChangeAvatar(ctx context.Context, schema ChangeAvatarSchema) (entity.User, error)
+	DeleteAvatar(ctx context.Context, schema DeleteAvatarSchema) (entity.User, error)
+	// GetAvatar returns avatar image, which must be closed
+	GetAvatar(ctx context.Context, key string) (blob.Object, error)
 }
 
 type Dependencies struct {
-	Cache            *redis.Client
-	Repos            *repository.Repositories
-	HasherService    hash.HasherService
-	JWTServiceConfig JWTServiceConfig
+	Cache                  Cache
+	Repos                  *repository.Repositories
+	HasherService          hash.HasherService
+	JWTServiceConfig       JWTServiceConfig
+	TwoFactorServiceConfig TwoFactorServiceConfig
+	ThrottleServiceConfig  ThrottleServiceConfig
+	OIDCServiceConfig      OIDCServiceConfig
+	MagicLinkServiceConfig MagicLinkServiceConfig
+	AccountServiceConfig   AccountServiceConfig
+	ProfileServiceConfig   ProfileServiceConfig
+	MailSender             mail.Sender
+	BlobStore              blob.Store
 }
 
 // Services is a collection of all services we have in the project.
 type Services struct {
-	JWT   JWTService
-	Auth  AuthService
-	Users UsersService
+	JWT       JWTService
+	Auth      AuthService
+	Users     UsersService
+	TwoFactor TwoFactorService
+	Throttle  ThrottleService
+	APIKeys   APIKeysService
+	OIDC      OIDCService
+	MagicLink MagicLinkService
+	Admin     AdminService
+	Audit     AuditService
+	Account   AccountService
+	Profile   ProfileService
 }
 
 func NewServices(deps Dependencies) (*Services, error) {
@@ -84,25 +426,98 @@ func NewServices(deps Dependencies) (*Services, error) {
 		return nil, errors.New("repositories not provided")
 	}
 
+	auditServ, err := NewAuditService(deps.Repos.Audit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create audit service")
+	}
+
 	jwtServ, err := NewJWTService(deps.JWTServiceConfig, deps.Cache)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create jwt service")
 	}
 
-	authServ, err := NewAuthService(deps.Cache, deps.Repos.Users, deps.HasherService, jwtServ)
+	twoFactorServ, err := NewTwoFactorService(deps.TwoFactorServiceConfig, deps.Cache, deps.Repos.Users, deps.HasherService)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create two-factor service")
+	}
+
+	throttleServ, err := NewThrottleService(deps.ThrottleServiceConfig, deps.Cache)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create throttle service")
+	}
+
+	profileServ, err := NewProfileService(deps.ProfileServiceConfig, deps.Repos.Users, deps.BlobStore, auditServ)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create profile service")
+	}
+
+	authServ, err := NewAuthService(deps.Cache, deps.Repos.Users, deps.HasherService, jwtServ, twoFactorServ, throttleServ, auditServ, profileServ)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create auth service")
 	}
 
-	usersServ, err := NewUsersService(deps.Repos.Users, deps.HasherService)
+	usersServ, err := NewUsersService(deps.Repos.Users, deps.HasherService, jwtServ, auditServ)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create users service")
 	}
 
+	apiKeysServ, err := NewAPIKeysService(deps.Repos.APIKeys)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create api keys service")
+	}
+
+	oidcServ, err := NewOIDCService(deps.OIDCServiceConfig, deps.Cache, deps.Repos.Users, jwtServ, twoFactorServ, auditServ, profileServ)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create oidc service")
+	}
+
+	magicLinkServ, err := NewMagicLinkService(
+		deps.MagicLinkServiceConfig,
+		deps.Cache,
+		deps.Repos.Users,
+		jwtServ,
+		twoFactorServ,
+		throttleServ,
+		auditServ,
+		deps.MailSender,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create magic link service")
+	}
+
+	adminServ, err := NewAdminService(deps.Repos.Users, auditServ, jwtServ, throttleServ)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create admin service")
+	}
+
+	accountServ, err := NewAccountService(
+		deps.AccountServiceConfig,
+		deps.Cache,
+		deps.Repos,
+		deps.HasherService,
+		jwtServ,
+		twoFactorServ,
+		auditServ,
+		deps.BlobStore,
+		deps.MailSender,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create account service")
+	}
+
 	s := &Services{
-		JWT:   jwtServ,
-		Auth:  authServ,
-		Users: usersServ,
+		JWT:       jwtServ,
+		Auth:      authServ,
+		Users:     usersServ,
+		TwoFactor: twoFactorServ,
+		Throttle:  throttleServ,
+		APIKeys:   apiKeysServ,
+		OIDC:      oidcServ,
+		MagicLink: magicLinkServ,
+		Admin:     adminServ,
+		Audit:     auditServ,
+		Account:   accountServ,
+		Profile:   profileServ,
 	}
 
 	return s, nil
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+)
+
+const (
+	defaultThrottleWindow          = 15 * time.Minute
+	defaultThrottleBaseDelay       = time.Second
+	defaultThrottleMaxDelay        = 5 * time.Minute
+	defaultThrottleLockoutDuration = 30 * time.Minute
+
+	defaultLoginFreeAttempts     = 3
+	defaultLoginLockoutThreshold = 10
+	defaultIPFreeAttempts        = 10
+	defaultIPLockoutThreshold    = 50
+)
+
+type ThrottleLimitsConfig struct {
+	// FreeAttempts is a number of failed attempts allowed without any delay
+	FreeAttempts int `mapstructure:"freeAttempts"`
+	// LockoutThreshold is a number of failed attempts after which subject is locked out
+	LockoutThreshold int `mapstructure:"lockoutThreshold"`
+}
+
+type ThrottleServiceConfig struct {
+	// Window is a period during which failed attempts are counted
+	Window          time.Duration        `mapstructure:"window"`
+	BaseDelay       time.Duration        `mapstructure:"baseDelay"`
+	MaxDelay        time.Duration        `mapstructure:"maxDelay"`
+	LockoutDuration time.Duration        `mapstructure:"lockoutDuration"`
+	Login           ThrottleLimitsConfig `mapstructure:"login"`
+	IP              ThrottleLimitsConfig `mapstructure:"ip"`
+}
+
+type throttleSubject struct {
+	kind   string
+	value  string
+	limits ThrottleLimitsConfig
+}
+
+type throttleService struct {
+	cache           Cache
+	window          time.Duration
+	baseDelay       time.Duration
+	maxDelay        time.Duration
+	lockoutDuration time.Duration
+	loginLimits     ThrottleLimitsConfig
+	ipLimits        ThrottleLimitsConfig
+}
+
+func NewThrottleService(cfg ThrottleServiceConfig, cache Cache) (ThrottleService, error) {
+	if cache == nil {
+		return nil, errors.New("cache not provided")
+	}
+
+	s := &throttleService{
+		cache:           cache,
+		window:          lo.Ternary(cfg.Window > 0, cfg.Window, defaultThrottleWindow),
+		baseDelay:       lo.Ternary(cfg.BaseDelay > 0, cfg.BaseDelay, defaultThrottleBaseDelay),
+		maxDelay:        lo.Ternary(cfg.MaxDelay > 0, cfg.MaxDelay, defaultThrottleMaxDelay),
+		lockoutDuration: lo.Ternary(cfg.LockoutDuration > 0, cfg.LockoutDuration, defaultThrottleLockoutDuration),
+		loginLimits:     withDefaultLimits(cfg.Login, defaultLoginFreeAttempts, defaultLoginLockoutThreshold),
+		ipLimits:        withDefaultLimits(cfg.IP, defaultIPFreeAttempts, defaultIPLockoutThreshold),
+	}
+
+	if s.baseDelay > s.maxDelay {
+		return nil, fmt.Errorf("base delay [%s] must not exceed max delay [%s]", s.baseDelay, s.maxDelay)
+	}
+
+	return s, nil
+}
+
+func withDefaultLimits(limits ThrottleLimitsConfig, freeAttempts, lockoutThreshold int) ThrottleLimitsConfig {
+	if limits.FreeAttempts <= 0 {
+		limits.FreeAttempts = freeAttempts
+	}
+
+	if limits.LockoutThreshold <= limits.FreeAttempts {
+		limits.LockoutThreshold = lo.Max([]int{lockoutThreshold, limits.FreeAttempts + 1})
+	}
+
+	return limits
+}
+
+func (s *throttleService) Reserve(ctx context.Context, schema ThrottleSchema) error {
+	subjects := s.subjects(schema)
+
+	var retryAfter time.Duration
+
+	for _, subject := range subjects {
+		blockKey := throttleBlockCacheKey(subject.kind, subject.value)
+
+		ttl, err := s.cache.TTL(ctx, blockKey)
+		if err != nil {
+			return errors.Wrapf(err, "cache: failed to get %q key TTL", blockKey)
+		}
+
+		if ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+
+	if retryAfter > 0 {
+		return &entity.TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+
+	// attempt is counted before credentials are verified, so concurrent attempts,
+	// which all passed the block check, can't exceed lockout threshold
+	for i, subject := range subjects {
+		failuresKey := throttleFailuresCacheKey(subject.kind, subject.value)
+
+		attempts, err := s.cache.IncrBy(ctx, failuresKey, 1, s.window)
+		if err != nil {
+			return errors.Wrapf(err, "cache: failed to increment %q key", failuresKey)
+		}
+
+		if attempts > int64(subject.limits.LockoutThreshold) {
+			if err = s.release(ctx, subjects[:i+1]); err != nil {
+				return err
+			}
+
+			// attempts in progress either fail and lock subject out or succeed and release their reservations
+			return &entity.TooManyAttemptsError{RetryAfter: s.baseDelay}
+		}
+	}
+
+	return nil
+}
+
+func (s *throttleService) Fail(ctx context.Context, schema ThrottleSchema) error {
+	for _, subject := range s.subjects(schema) {
+		failuresKey := throttleFailuresCacheKey(subject.kind, subject.value)
+
+		// attempt was already counted by Reserve, zero delta reads counter and prolongs the window
+		failures, err := s.cache.IncrBy(ctx, failuresKey, 0, s.window)
+		if err != nil {
+			return errors.Wrapf(err, "cache: failed to read %q key", failuresKey)
+		}
+
+		delay := s.delay(int(failures), subject.limits)
+		if delay <= 0 {
+			continue
+		}
+
+		blockKey := throttleBlockCacheKey(subject.kind, subject.value)
+
+		err = s.cache.Set(ctx, blockKey, []byte(strconv.FormatInt(failures, 10)), delay)
+		if err != nil {
+			return errors.Wrapf(err, "cache: failed to set %q key", blockKey)
+		}
+	}
+
+	return nil
+}
+
+func (s *throttleService) Release(ctx context.Context, schema ThrottleSchema) error {
+	return s.release(ctx, s.subjects(schema))
+}
+
+func (s *throttleService) release(ctx context.Context, subjects []throttleSubject) error {
+	for _, subject := range subjects {
+		failuresKey := throttleFailuresCacheKey(subject.kind, subject.value)
+
+		// window is set again, so counter which expired since reservation doesn't stay negative forever
+		_, err := s.cache.IncrBy(ctx, failuresKey, -1, s.window)
+		if err != nil {
+			return errors.Wrapf(err, "cache: failed to decrement %q key", failuresKey)
+		}
+	}
+
+	return nil
+}
+
+func (s *throttleService) Reset(ctx context.Context, login string) error {
+	return s.Unlock(ctx, ThrottleSchema{Login: login, MagicLink: login})
+}
+
+func (s *throttleService) Unlock(ctx context.Context, schema ThrottleSchema) error {
+	var keys []string
+
+	for _, subject := range s.subjects(schema) {
+		keys = append(keys,
+			throttleFailuresCacheKey(subject.kind, subject.value),
+			throttleBlockCacheKey(subject.kind, subject.value),
+		)
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if _, err := s.cache.Del(ctx, keys...); err != nil {
+		return errors.Wrapf(err, "cache: failed to delete %q keys", keys)
+	}
+
+	return nil
+}
+
+// delay returns exponentially growing block duration for the provided number of failures
+func (s *throttleService) delay(failures int, limits ThrottleLimitsConfig) time.Duration {
+	if failures >= limits.LockoutThreshold {
+		return s.lockoutDuration
+	}
+
+	exceeded := failures - limits.FreeAttempts
+	if exceeded <= 0 {
+		return 0
+	}
+
+	delay := s.baseDelay
+	for i := 1; i < exceeded && delay < s.maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > s.maxDelay {
+		delay = s.maxDelay
+	}
+
+	return delay
+}
+
+func (s *throttleService) subjects(schema ThrottleSchema) []throttleSubject {
+	var subjects []throttleSubject
+
+	if login := strings.ToLower(strings.TrimSpace(schema.Login)); login != "" {
+		subjects = append(subjects, throttleSubject{kind: "login", value: login, limits: s.loginLimits})
+	}
+
+	if email := strings.ToLower(strings.TrimSpace(schema.MagicLink)); email != "" {
+		subjects = append(subjects, throttleSubject{kind: "magiclink", value: email, limits: s.loginLimits})
+	}
+
+	if schema.IP != "" {
+		subjects = append(subjects, throttleSubject{kind: "ip", value: schema.IP, limits: s.ipLimits})
+	}
+
+	if schema.MagicLinkIP != "" {
+		subjects = append(subjects, throttleSubject{kind: "magiclink-ip", value: schema.MagicLinkIP, limits: s.ipLimits})
+	}
+
+	return subjects
+}
+
+func throttleFailuresCacheKey(kind, value string) string {
+	return fmt.Sprintf("throttle:%s:%s:failures", kind, value)
+}
+
+func throttleBlockCacheKey(kind, value string) string {
+	return fmt.Sprintf("throttle:%s:%s:blocked", kind, value)
+}
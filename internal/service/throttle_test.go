@@ -0,0 +1,260 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/service"
+)
+
+func TestThrottleService(t *testing.T) {
+	type step struct {
+		fails      int
+		unlock     *service.ThrottleSchema
+		reset      string
+		forward    time.Duration
+		retryAfter time.Duration
+	}
+
+	testCfg := service.ThrottleServiceConfig{
+		Window:          10 * time.Minute,
+		BaseDelay:       time.Second,
+		MaxDelay:        4 * time.Second,
+		LockoutDuration: time.Hour,
+		Login: service.ThrottleLimitsConfig{
+			FreeAttempts:     2,
+			LockoutThreshold: 7,
+		},
+		IP: service.ThrottleLimitsConfig{
+			FreeAttempts:     3,
+			LockoutThreshold: 20,
+		},
+	}
+
+	testSchema := service.ThrottleSchema{
+		Login: "Tolstoi.Job@gmail.com",
+		IP:    "192.0.2.1",
+	}
+
+	testCases := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "free attempts",
+			steps: []step{
+				{fails: 2, retryAfter: 0},
+			},
+		},
+		{
+			name: "exponential backoff",
+			steps: []step{
+				{fails: 3, retryAfter: time.Second},
+				{fails: 1, retryAfter: 2 * time.Second},
+				{fails: 1, retryAfter: 4 * time.Second},
+				{fails: 1, retryAfter: 4 * time.Second},
+			},
+		},
+		{
+			name: "block expires",
+			steps: []step{
+				{fails: 3, retryAfter: time.Second},
+				{forward: time.Second, retryAfter: 0},
+			},
+		},
+		{
+			name: "lockout",
+			steps: []step{
+				{fails: 7, retryAfter: time.Hour},
+			},
+		},
+		{
+			name: "reset after successful sign in",
+			steps: []step{
+				{fails: 3, retryAfter: time.Second},
+				{reset: "tolstoi.job@gmail.com", retryAfter: 0},
+				{fails: 1, retryAfter: time.Second},
+			},
+		},
+		{
+			name: "unlock",
+			steps: []step{
+				{fails: 7, retryAfter: time.Hour},
+				{unlock: &testSchema, retryAfter: 0},
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runWithCaches(t, func(t *testing.T, cache service.Cache, fastForward func(time.Duration)) {
+				throttleServ, err := service.NewThrottleService(testCfg, cache)
+				require.NoErrorf(t, err, "failed to create throttle service: %s", err)
+
+				ctx := context.Background()
+
+				for i, s := range tc.steps {
+					for j := 0; j < s.fails; j++ {
+						// client waits until block expires before the next attempt
+						err = throttleServ.Reserve(ctx, testSchema)
+
+						var tooManyAttemptsErr *entity.TooManyAttemptsError
+						if errors.As(err, &tooManyAttemptsErr) {
+							fastForward(tooManyAttemptsErr.RetryAfter)
+							err = throttleServ.Reserve(ctx, testSchema)
+						}
+
+						require.NoErrorf(t, err, "step %d: failed to reserve attempt: %s", i, err)
+
+						err = throttleServ.Fail(ctx, testSchema)
+						require.NoErrorf(t, err, "step %d: failed to register failure: %s", i, err)
+					}
+
+					if s.reset != "" {
+						err = throttleServ.Reset(ctx, s.reset)
+						require.NoErrorf(t, err, "step %d: failed to reset: %s", i, err)
+					}
+
+					if s.unlock != nil {
+						err = throttleServ.Unlock(ctx, *s.unlock)
+						require.NoErrorf(t, err, "step %d: failed to unlock: %s", i, err)
+					}
+
+					fastForward(s.forward)
+
+					err = throttleServ.Reserve(ctx, testSchema)
+					if s.retryAfter == 0 {
+						assert.NoErrorf(t, err, "step %d: unexpected error", i)
+
+						// checked attempt succeeds, so it isn't counted
+						err = throttleServ.Release(ctx, testSchema)
+						require.NoErrorf(t, err, "step %d: failed to release attempt: %s", i, err)
+
+						continue
+					}
+
+					var tooManyAttemptsErr *entity.TooManyAttemptsError
+					require.ErrorAsf(t, err, &tooManyAttemptsErr, "step %d: expected too many attempts error", i)
+					assert.Equalf(t, s.retryAfter, tooManyAttemptsErr.RetryAfter, "step %d", i)
+				}
+			})
+		})
+	}
+}
+
+func TestThrottleService_ConcurrentAttempts(t *testing.T) {
+	const attempts = 20
+
+	testCfg := service.ThrottleServiceConfig{
+		BaseDelay: time.Second,
+		Login: service.ThrottleLimitsConfig{
+			FreeAttempts:     2,
+			LockoutThreshold: 5,
+		},
+	}
+
+	testSchema := service.ThrottleSchema{Login: "kenplix"}
+
+	t.Parallel()
+
+	runWithCaches(t, func(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+		throttleServ, err := service.NewThrottleService(testCfg, cache)
+		require.NoErrorf(t, err, "failed to create throttle service: %s", err)
+
+		ctx := context.Background()
+
+		var (
+			wg       sync.WaitGroup
+			reserved = make(chan error, attempts)
+		)
+
+		// every attempt passes block check before any of them fails
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				reserved <- throttleServ.Reserve(ctx, testSchema)
+			}()
+		}
+
+		wg.Wait()
+		close(reserved)
+
+		var succeeded int
+
+		for err := range reserved {
+			if err == nil {
+				succeeded++
+				continue
+			}
+
+			var tooManyAttemptsErr *entity.TooManyAttemptsError
+			require.ErrorAs(t, err, &tooManyAttemptsErr)
+			assert.Equal(t, time.Second, tooManyAttemptsErr.RetryAfter)
+		}
+
+		assert.Equal(t, testCfg.Login.LockoutThreshold, succeeded, "concurrent attempts must not exceed lockout threshold")
+
+		require.NoError(t, throttleServ.Release(ctx, testSchema))
+		assert.NoError(t, throttleServ.Reserve(ctx, testSchema), "released attempt must free its reservation")
+
+		require.NoError(t, throttleServ.Fail(ctx, testSchema))
+
+		var tooManyAttemptsErr *entity.TooManyAttemptsError
+		require.ErrorAs(t, throttleServ.Reserve(ctx, testSchema), &tooManyAttemptsErr)
+		assert.Equal(t, 30*time.Minute, tooManyAttemptsErr.RetryAfter, "failure of reserved attempt must lock subject out")
+	})
+}
+
+func TestThrottleService_Subjects(t *testing.T) {
+	const (
+		testEmail = "tolstoi.job@gmail.com"
+		testIP    = "192.0.2.1"
+	)
+
+	testCfg := service.ThrottleServiceConfig{
+		Login: service.ThrottleLimitsConfig{FreeAttempts: 1, LockoutThreshold: 2},
+		IP:    service.ThrottleLimitsConfig{FreeAttempts: 1, LockoutThreshold: 2},
+	}
+
+	t.Parallel()
+
+	runWithCaches(t, func(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+		throttleServ, err := service.NewThrottleService(testCfg, cache)
+		require.NoErrorf(t, err, "failed to create throttle service: %s", err)
+
+		ctx := context.Background()
+
+		// link requests are counted apart from sign in attempts
+		linkRequests := service.ThrottleSchema{MagicLink: testEmail, MagicLinkIP: testIP}
+
+		for i := 0; i < testCfg.Login.LockoutThreshold; i++ {
+			require.NoError(t, throttleServ.Reserve(ctx, linkRequests))
+			require.NoError(t, throttleServ.Fail(ctx, linkRequests))
+		}
+
+		var tooManyAttemptsErr *entity.TooManyAttemptsError
+		require.ErrorAs(t, throttleServ.Reserve(ctx, linkRequests), &tooManyAttemptsErr)
+
+		signIn := service.ThrottleSchema{Login: testEmail, IP: testIP}
+		require.NoError(t, throttleServ.Reserve(ctx, signIn), "link requests must not lock out sign in")
+		require.NoError(t, throttleServ.Release(ctx, signIn))
+
+		require.NoError(t, throttleServ.Reset(ctx, testEmail))
+		require.ErrorAs(t, throttleServ.Reserve(ctx, linkRequests), &tooManyAttemptsErr, "IP of link requests must stay locked")
+
+		assert.NoError(t, throttleServ.Reserve(ctx, service.ThrottleSchema{MagicLink: testEmail}),
+			"successful sign in must reset link requests of the email")
+	})
+}
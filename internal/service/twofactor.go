@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	"github.com/kenplix/url-shrtnr/pkg/hash"
+	"github.com/kenplix/url-shrtnr/pkg/otp/totp"
+)
+
+const (
+	defaultTwoFactorIssuer        = "url-shrtnr"
+	defaultRecoveryCodesCount     = 10
+	defaultTwoFactorEnrollmentTTL = 10 * time.Minute
+	defaultMFAChallengeTTL        = 5 * time.Minute
+	defaultMFAMaxAttempts         = 5
+
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeLength   = 10
+	mfaTokenSize         = 32
+)
+
+type TwoFactorServiceConfig struct {
+	Issuer        string        `mapstructure:"issuer"`
+	TOTP          totp.Config   `mapstructure:"totp"`
+	RecoveryCodes int           `mapstructure:"recoveryCodes"`
+	EnrollmentTTL time.Duration `mapstructure:"enrollmentTTL"`
+	ChallengeTTL  time.Duration `mapstructure:"challengeTTL"`
+	MaxAttempts   int           `mapstructure:"maxAttempts"`
+}
+
+type twoFactorService struct {
+	cache         Cache
+	usersRepo     repository.UsersRepository
+	hasherServ    hash.HasherService
+	generator     *totp.Generator
+	issuer        string
+	recoveryCodes int
+	enrollmentTTL time.Duration
+	challengeTTL  time.Duration
+	maxAttempts   int
+}
+
+func NewTwoFactorService(
+	cfg TwoFactorServiceConfig,
+	cache Cache,
+	usersRepo repository.UsersRepository,
+	hasherServ hash.HasherService,
+) (TwoFactorService, error) {
+	if cache == nil {
+		return nil, errors.New("cache not provided")
+	}
+
+	if usersRepo == nil {
+		return nil, errors.New("users repository not provided")
+	}
+
+	if hasherServ == nil {
+		return nil, errors.New("hasher service not provided")
+	}
+
+	s := &twoFactorService{
+		cache:         cache,
+		usersRepo:     usersRepo,
+		hasherServ:    hasherServ,
+		generator:     totp.NewGenerator(totp.SetConfig(cfg.TOTP)),
+		issuer:        lo.Ternary(cfg.Issuer != "", cfg.Issuer, defaultTwoFactorIssuer),
+		recoveryCodes: lo.Ternary(cfg.RecoveryCodes > 0, cfg.RecoveryCodes, defaultRecoveryCodesCount),
+		enrollmentTTL: lo.Ternary(cfg.EnrollmentTTL > 0, cfg.EnrollmentTTL, defaultTwoFactorEnrollmentTTL),
+		challengeTTL:  lo.Ternary(cfg.ChallengeTTL > 0, cfg.ChallengeTTL, defaultMFAChallengeTTL),
+		maxAttempts:   lo.Ternary(cfg.MaxAttempts > 0, cfg.MaxAttempts, defaultMFAMaxAttempts),
+	}
+
+	return s, nil
+}
+
+func (s *twoFactorService) Enroll(ctx context.Context, userID primitive.ObjectID) (entity.TwoFactorEnrollment, error) {
+	user, err := s.usersRepo.FindByID(ctx, userID)
+	if err != nil {
+		return entity.TwoFactorEnrollment{}, errors.Wrapf(err, "failed to get user[id:%q]", userID.Hex())
+	}
+
+	if user.TwoFactor != nil {
+		return entity.TwoFactorEnrollment{}, entity.ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := s.generator.GenerateSecret()
+	if err != nil {
+		return entity.TwoFactorEnrollment{}, errors.Wrap(err, "failed to generate TOTP secret")
+	}
+
+	enrollmentKey := twoFactorEnrollmentCacheKey(userID.Hex())
+
+	err = s.cache.Set(ctx, enrollmentKey, []byte(secret), s.enrollmentTTL)
+	if err != nil {
+		return entity.TwoFactorEnrollment{}, errors.Wrapf(err, "cache: failed to set %q key", enrollmentKey)
+	}
+
+	uri := s.generator.KeyURI(s.issuer, user.Username, secret)
+
+	qrCode, err := totp.QRCode(uri)
+	if err != nil {
+		return entity.TwoFactorEnrollment{}, errors.Wrap(err, "failed to create QR code")
+	}
+
+	enrollment := entity.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    uri,
+		QRCode: qrCode,
+	}
+
+	return enrollment, nil
+}
+
+func (s *twoFactorService) ConfirmEnrollment(ctx context.Context, schema ConfirmTwoFactorSchema) (entity.RecoveryCodes, error) {
+	enrollmentKey := twoFactorEnrollmentCacheKey(schema.UserID.Hex())
+
+	secretBytes, err := s.cache.Get(ctx, enrollmentKey)
+	if err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			return entity.RecoveryCodes{}, entity.ErrTwoFactorEnrollmentNotFound
+		}
+
+		return entity.RecoveryCodes{}, errors.Wrapf(err, "cache: failed to get %q key", enrollmentKey)
+	}
+
+	secret := string(secretBytes)
+
+	if _, ok := s.generator.Validate(normalizeCode(schema.Code), secret, time.Now()); !ok {
+		return entity.RecoveryCodes{}, entity.ErrIncorrectTwoFactorCode
+	}
+
+	codes, hashes, err := s.generateRecoveryCodes()
+	if err != nil {
+		return entity.RecoveryCodes{}, errors.Wrap(err, "failed to generate recovery codes")
+	}
+
+	err = s.usersRepo.EnableTwoFactor(ctx, repository.EnableTwoFactorSchema{
+		UserID: schema.UserID,
+		TwoFactor: entity.TwoFactorModel{
+			TOTPSecret:         secret,
+			RecoveryCodeHashes: hashes,
+			EnabledAt:          time.Now(),
+		},
+	})
+	if err != nil {
+		return entity.RecoveryCodes{}, errors.Wrapf(err, "user[id:%q]: failed to enable two-factor authentication", schema.UserID.Hex())
+	}
+
+	s.cache.Del(ctx, enrollmentKey)
+
+	return entity.RecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+func (s *twoFactorService) Disable(ctx context.Context, schema DisableTwoFactorSchema) error {
+	user, err := s.usersRepo.FindByID(ctx, schema.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get user[id:%q]", schema.UserID.Hex())
+	}
+
+	if user.TwoFactor == nil {
+		return entity.ErrTwoFactorNotEnabled
+	}
+
+	if ok := s.hasherServ.VerifyPassword(schema.Password, user.PasswordHash); !ok {
+		return entity.ErrIncorrectCredentials
+	}
+
+	if err = s.verifyCode(ctx, user, schema.Code); err != nil {
+		return err
+	}
+
+	err = s.usersRepo.DisableTwoFactor(ctx, schema.UserID)
+	if err != nil {
+		return errors.Wrapf(err, "user[id:%q]: failed to disable two-factor authentication", schema.UserID.Hex())
+	}
+
+	return nil
+}
+
+func (s *twoFactorService) CreateChallenge(ctx context.Context, userID primitive.ObjectID) (entity.MFAChallenge, error) {
+	mfaToken, err := randomHex(mfaTokenSize)
+	if err != nil {
+		return entity.MFAChallenge{}, errors.Wrap(err, "failed to generate mfa token")
+	}
+
+	challengeKey := mfaChallengeCacheKey(mfaToken)
+
+	err = s.cache.Set(ctx, challengeKey, []byte(userID.Hex()), s.challengeTTL)
+	if err != nil {
+		return entity.MFAChallenge{}, errors.Wrapf(err, "cache: failed to set %q key", challengeKey)
+	}
+
+	challenge := entity.MFAChallenge{
+		MFAToken:  mfaToken,
+		ExpiresIn: int64(s.challengeTTL / time.Second),
+	}
+
+	return challenge, nil
+}
+
+func (s *twoFactorService) VerifyChallenge(ctx context.Context, schema VerifyMFAChallengeSchema) (entity.UserModel, error) {
+	challengeKey := mfaChallengeCacheKey(schema.MFAToken)
+
+	userIDBytes, err := s.cache.Get(ctx, challengeKey)
+	if err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			return entity.UserModel{}, entity.ErrMFATokenNotFound
+		}
+
+		return entity.UserModel{}, errors.Wrapf(err, "cache: failed to get %q key", challengeKey)
+	}
+
+	userIDHex := string(userIDBytes)
+
+	userID, err := primitive.ObjectIDFromHex(userIDHex)
+	if err != nil {
+		return entity.UserModel{}, errors.Wrapf(err, "failed to parse %q userID", userIDHex)
+	}
+
+	user, err := s.usersRepo.FindByID(ctx, userID)
+	if err != nil {
+		return entity.UserModel{}, errors.Wrapf(err, "failed to get user[id:%q]", userIDHex)
+	}
+
+	if user.TwoFactor == nil {
+		s.cache.Del(ctx, challengeKey)
+		return entity.UserModel{}, entity.ErrMFATokenNotFound
+	}
+
+	attemptsKey := mfaAttemptsCacheKey(schema.MFAToken)
+
+	err = s.verifyCode(ctx, user, schema.Code)
+	if err != nil {
+		if errors.Is(err, entity.ErrIncorrectTwoFactorCode) {
+			attempts, _ := s.cache.IncrBy(ctx, attemptsKey, 1, s.challengeTTL)
+
+			if attempts >= int64(s.maxAttempts) {
+				s.cache.Del(ctx, challengeKey, attemptsKey)
+			}
+
+			// user is returned, so failed attempt can be attributed to the account
+			return user, err
+		}
+
+		return entity.UserModel{}, err
+	}
+
+	s.cache.Del(ctx, challengeKey, attemptsKey)
+
+	return user, nil
+}
+
+func (s *twoFactorService) VerifyCode(ctx context.Context, user entity.UserModel, code string) error {
+	if user.TwoFactor == nil {
+		return entity.ErrTwoFactorNotEnabled
+	}
+
+	return s.verifyCode(ctx, user, code)
+}
+
+// verifyCode checks TOTP code or consumes one of the recovery codes
+func (s *twoFactorService) verifyCode(ctx context.Context, user entity.UserModel, code string) error {
+	code = normalizeCode(code)
+
+	counter, ok := s.generator.Validate(code, user.TwoFactor.TOTPSecret, time.Now())
+	if ok {
+		usedKey := usedTOTPCacheKey(user.ID.Hex(), counter)
+		ttl := s.generator.Period() * time.Duration(2*s.generator.Skew()+1)
+
+		fresh, err := s.cache.SetNX(ctx, usedKey, []byte("1"), ttl)
+		if err != nil {
+			return errors.Wrapf(err, "cache: failed to set %q key", usedKey)
+		} else if !fresh {
+			return entity.ErrIncorrectTwoFactorCode
+		}
+
+		return nil
+	}
+
+	return s.useRecoveryCode(ctx, user, code)
+}
+
+func (s *twoFactorService) useRecoveryCode(ctx context.Context, user entity.UserModel, code string) error {
+	code = strings.ReplaceAll(code, "-", "")
+	if len(code) != recoveryCodeLength {
+		return entity.ErrIncorrectTwoFactorCode
+	}
+
+	for _, codeHash := range user.TwoFactor.RecoveryCodeHashes {
+		if !s.hasherServ.VerifyPassword(code, codeHash) {
+			continue
+		}
+
+		// removal is conditional, so code used by concurrent request since user was read is rejected
+		err := s.usersRepo.RemoveRecoveryCode(ctx, repository.RemoveRecoveryCodeSchema{
+			UserID:           user.ID,
+			RecoveryCodeHash: codeHash,
+		})
+		if err != nil {
+			if errors.Is(err, entity.ErrIncorrectTwoFactorCode) {
+				return entity.ErrIncorrectTwoFactorCode
+			}
+
+			return errors.Wrapf(err, "user[id:%q]: failed to remove recovery code", user.ID.Hex())
+		}
+
+		return nil
+	}
+
+	return entity.ErrIncorrectTwoFactorCode
+}
+
+func (s *twoFactorService) generateRecoveryCodes() (codes, hashes []string, _ error) {
+	codes = make([]string, 0, s.recoveryCodes)
+	hashes = make([]string, 0, s.recoveryCodes)
+
+	for i := 0; i < s.recoveryCodes; i++ {
+		code, err := randomString(recoveryCodeLength, recoveryCodeAlphabet)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		codeHash, err := s.hasherServ.HashPassword(code)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to hash recovery code")
+		}
+
+		half := recoveryCodeLength / 2
+
+		codes = append(codes, code[:half]+"-"+code[half:])
+		hashes = append(hashes, codeHash)
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
+
+func twoFactorEnrollmentCacheKey(userID string) string {
+	return fmt.Sprintf("2fa-enrollment:%s", userID)
+}
+
+func usedTOTPCacheKey(userID string, counter uint64) string {
+	return fmt.Sprintf("2fa-used:%s:%d", userID, counter)
+}
+
+func mfaChallengeCacheKey(mfaToken string) string {
+	return fmt.Sprintf("mfa:%s", mfaToken)
+}
+
+func mfaAttemptsCacheKey(mfaToken string) string {
+	return fmt.Sprintf("mfa-attempts:%s", mfaToken)
+}
@@ -0,0 +1,441 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/kenplix/url-shrtnr/internal/entity"
+	"github.com/kenplix/url-shrtnr/internal/repository"
+	repoMocks "github.com/kenplix/url-shrtnr/internal/repository/mocks"
+	"github.com/kenplix/url-shrtnr/internal/service"
+	hashMocks "github.com/kenplix/url-shrtnr/pkg/hash/mocks"
+	"github.com/kenplix/url-shrtnr/pkg/otp/totp"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+func TestTwoFactorService_Enroll(t *testing.T) {
+	type args struct {
+		userID primitive.ObjectID
+	}
+
+	type ret struct {
+		hasErr bool
+	}
+
+	type mockBehavior func(*repoMocks.UsersRepository)
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "failed to get user",
+			args: args{
+				userID: primitive.NewObjectID(),
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository) {
+				usersRepo.
+					On("FindByID", mock.Anything, mock.Anything).
+					Return(entity.UserModel{}, assert.AnError)
+			},
+		},
+		{
+			name: "two-factor authentication already enabled",
+			args: args{
+				userID: primitive.NewObjectID(),
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository) {
+				usersRepo.
+					On("FindByID", mock.Anything, mock.Anything).
+					Return(entity.UserModel{TwoFactor: &entity.TwoFactorModel{}}, nil)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				userID: primitive.NewObjectID(),
+			},
+			ret: ret{
+				hasErr: false,
+			},
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository) {
+				usersRepo.
+					On("FindByID", mock.Anything, mock.Anything).
+					Return(entity.UserModel{Username: "kenplix"}, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runWithCaches(t, func(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+				var (
+					usersRepo  = repoMocks.NewUsersRepository(t)
+					hasherServ = hashMocks.NewHasherService(t)
+				)
+
+				twoFactorServ, err := service.NewTwoFactorService(service.TwoFactorServiceConfig{}, cache, usersRepo, hasherServ)
+				require.NoErrorf(t, err, "failed to create two-factor service: %s", err)
+
+				tc.mockBehavior(usersRepo)
+
+				enrollment, err := twoFactorServ.Enroll(context.Background(), tc.args.userID)
+				assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+
+				if !tc.ret.hasErr {
+					secret, e := cache.Get(context.Background(), service.TwoFactorEnrollmentCacheKey(tc.args.userID.Hex()))
+					require.NoErrorf(t, e, "failed to get enrollment secret: %s", e)
+
+					assert.Equal(t, string(secret), enrollment.Secret)
+					assert.Contains(t, enrollment.URI, "otpauth://totp/url-shrtnr:kenplix")
+					assert.NotEmpty(t, enrollment.QRCode)
+				}
+			})
+		})
+	}
+}
+
+func TestTwoFactorService_ConfirmEnrollment(t *testing.T) {
+	type args struct {
+		code func(t *testing.T) string
+	}
+
+	type ret struct {
+		recoveryCodes int
+		hasErr        bool
+	}
+
+	type mockBehavior func(*repoMocks.UsersRepository, *hashMocks.HasherService)
+
+	testCases := []struct {
+		name         string
+		enrolled     bool
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name:     "enrollment not found",
+			enrolled: false,
+			args: args{
+				code: testTOTPCode,
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(_ *repoMocks.UsersRepository, _ *hashMocks.HasherService) {},
+		},
+		{
+			name:     "incorrect code",
+			enrolled: true,
+			args: args{
+				code: func(_ *testing.T) string { return "000000" },
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(_ *repoMocks.UsersRepository, _ *hashMocks.HasherService) {},
+		},
+		{
+			name:     "failed to enable two-factor authentication",
+			enrolled: true,
+			args: args{
+				code: testTOTPCode,
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+				hasherServ.
+					On("HashPassword", mock.Anything).
+					Return("<recovery code hash>", nil)
+
+				usersRepo.
+					On("EnableTwoFactor", mock.Anything, mock.Anything).
+					Return(assert.AnError)
+			},
+		},
+		{
+			name:     "ok",
+			enrolled: true,
+			args: args{
+				code: testTOTPCode,
+			},
+			ret: ret{
+				recoveryCodes: 10,
+				hasErr:        false,
+			},
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+				hasherServ.
+					On("HashPassword", mock.Anything).
+					Return("<recovery code hash>", nil)
+
+				usersRepo.
+					On("EnableTwoFactor", mock.Anything, mock.Anything).
+					Return(nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runWithCaches(t, func(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+				var (
+					usersRepo  = repoMocks.NewUsersRepository(t)
+					hasherServ = hashMocks.NewHasherService(t)
+				)
+
+				twoFactorServ, err := service.NewTwoFactorService(service.TwoFactorServiceConfig{}, cache, usersRepo, hasherServ)
+				require.NoErrorf(t, err, "failed to create two-factor service: %s", err)
+
+				userID := primitive.NewObjectID()
+
+				if tc.enrolled {
+					err = cache.Set(context.Background(), service.TwoFactorEnrollmentCacheKey(userID.Hex()), []byte(testTOTPSecret), 0)
+					require.NoErrorf(t, err, "failed to set enrollment secret: %s", err)
+				}
+
+				tc.mockBehavior(usersRepo, hasherServ)
+
+				codes, err := twoFactorServ.ConfirmEnrollment(context.Background(), service.ConfirmTwoFactorSchema{
+					UserID: userID,
+					Code:   tc.args.code(t),
+				})
+				assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+				assert.Len(t, codes.RecoveryCodes, tc.ret.recoveryCodes)
+			})
+		})
+	}
+}
+
+func TestTwoFactorService_VerifyChallenge(t *testing.T) {
+	type args struct {
+		mfaToken string
+		code     func(t *testing.T) string
+	}
+
+	type ret struct {
+		hasErr bool
+	}
+
+	type mockBehavior func(*repoMocks.UsersRepository, *hashMocks.HasherService)
+
+	testUser := entity.UserModel{
+		ID: primitive.NewObjectID(),
+		TwoFactor: &entity.TwoFactorModel{
+			TOTPSecret:         testTOTPSecret,
+			RecoveryCodeHashes: []string{"<first code hash>", "<second code hash>"},
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		args         args
+		ret          ret
+		mockBehavior mockBehavior
+	}{
+		{
+			name: "mfa token not found",
+			args: args{
+				mfaToken: "<unknown mfa token>",
+				code:     testTOTPCode,
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(_ *repoMocks.UsersRepository, _ *hashMocks.HasherService) {},
+		},
+		{
+			name: "incorrect code",
+			args: args{
+				mfaToken: "<mfa token>",
+				code:     func(_ *testing.T) string { return "00000-00000" },
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+				usersRepo.
+					On("FindByID", mock.Anything, mock.Anything).
+					Return(testUser, nil)
+
+				hasherServ.
+					On("VerifyPassword", mock.Anything, mock.Anything).
+					Return(false)
+			},
+		},
+		{
+			name: "recovery code",
+			args: args{
+				mfaToken: "<mfa token>",
+				code:     func(_ *testing.T) string { return "ABCDE-FGHJK" },
+			},
+			ret: ret{
+				hasErr: false,
+			},
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+				usersRepo.
+					On("FindByID", mock.Anything, mock.Anything).
+					Return(testUser, nil)
+
+				hasherServ.
+					On("VerifyPassword", "abcdefghjk", "<first code hash>").
+					Return(false)
+
+				hasherServ.
+					On("VerifyPassword", "abcdefghjk", "<second code hash>").
+					Return(true)
+
+				usersRepo.
+					On("RemoveRecoveryCode", mock.Anything, repository.RemoveRecoveryCodeSchema{
+						UserID:           testUser.ID,
+						RecoveryCodeHash: "<second code hash>",
+					}).
+					Return(nil)
+			},
+		},
+		{
+			name: "recovery code used concurrently",
+			args: args{
+				mfaToken: "<mfa token>",
+				code:     func(_ *testing.T) string { return "ABCDE-FGHJK" },
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+				usersRepo.
+					On("FindByID", mock.Anything, mock.Anything).
+					Return(testUser, nil)
+
+				hasherServ.
+					On("VerifyPassword", "abcdefghjk", "<first code hash>").
+					Return(true)
+
+				// code was removed by concurrent sign in after user had been read
+				usersRepo.
+					On("RemoveRecoveryCode", mock.Anything, mock.Anything).
+					Return(entity.ErrIncorrectTwoFactorCode)
+			},
+		},
+		{
+			name: "ok",
+			args: args{
+				mfaToken: "<mfa token>",
+				code:     testTOTPCode,
+			},
+			ret: ret{
+				hasErr: false,
+			},
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, _ *hashMocks.HasherService) {
+				usersRepo.
+					On("FindByID", mock.Anything, mock.Anything).
+					Return(testUser, nil)
+			},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runWithCaches(t, func(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+				var (
+					usersRepo  = repoMocks.NewUsersRepository(t)
+					hasherServ = hashMocks.NewHasherService(t)
+				)
+
+				twoFactorServ, err := service.NewTwoFactorService(service.TwoFactorServiceConfig{}, cache, usersRepo, hasherServ)
+				require.NoErrorf(t, err, "failed to create two-factor service: %s", err)
+
+				err = cache.Set(context.Background(), service.MFAChallengeCacheKey("<mfa token>"), []byte(testUser.ID.Hex()), 0)
+				require.NoErrorf(t, err, "failed to set mfa challenge: %s", err)
+
+				tc.mockBehavior(usersRepo, hasherServ)
+
+				_, err = twoFactorServ.VerifyChallenge(context.Background(), service.VerifyMFAChallengeSchema{
+					MFAToken: tc.args.mfaToken,
+					Code:     tc.args.code(t),
+				})
+				assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+
+				if !tc.ret.hasErr {
+					_, err = cache.Get(context.Background(), service.MFAChallengeCacheKey(tc.args.mfaToken))
+					assert.ErrorIsf(t, err, service.ErrCacheMiss, "mfa token must be single-use")
+				}
+			})
+		})
+	}
+}
+
+func TestTwoFactorService_VerifyChallenge_CodeReuse(t *testing.T) {
+	t.Parallel()
+
+	runWithCaches(t, testTwoFactorServiceVerifyChallengeCodeReuse)
+}
+
+func testTwoFactorServiceVerifyChallengeCodeReuse(t *testing.T, cache service.Cache, _ func(time.Duration)) {
+	var (
+		usersRepo  = repoMocks.NewUsersRepository(t)
+		hasherServ = hashMocks.NewHasherService(t)
+	)
+
+	twoFactorServ, err := service.NewTwoFactorService(service.TwoFactorServiceConfig{}, cache, usersRepo, hasherServ)
+	require.NoErrorf(t, err, "failed to create two-factor service: %s", err)
+
+	testUser := entity.UserModel{
+		ID:        primitive.NewObjectID(),
+		TwoFactor: &entity.TwoFactorModel{TOTPSecret: testTOTPSecret},
+	}
+
+	usersRepo.
+		On("FindByID", mock.Anything, mock.Anything).
+		Return(testUser, nil)
+
+	code := testTOTPCode(t)
+
+	for i, mfaToken := range []string{"<first mfa token>", "<second mfa token>"} {
+		err = cache.Set(context.Background(), service.MFAChallengeCacheKey(mfaToken), []byte(testUser.ID.Hex()), 0)
+		require.NoErrorf(t, err, "failed to set mfa challenge: %s", err)
+
+		user, err := twoFactorServ.VerifyChallenge(context.Background(), service.VerifyMFAChallengeSchema{
+			MFAToken: mfaToken,
+			Code:     code,
+		})
+		if i == 0 {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorIs(t, err, entity.ErrIncorrectTwoFactorCode)
+		}
+
+		assert.Equal(t, testUser.ID, user.ID, "user of the challenge must be returned")
+	}
+}
+
+func testTOTPCode(t *testing.T) string {
+	t.Helper()
+
+	code, err := totp.NewGenerator().GenerateCode(testTOTPSecret, time.Now())
+	require.NoErrorf(t, err, "failed to generate TOTP code: %s", err)
+
+	return code
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/kenplix/url-shrtnr/pkg/hash"
 
@@ -15,11 +16,15 @@ import (
 type usersService struct {
 	usersRepo  repository.UsersRepository
 	hasherServ hash.HasherService
+	jwtServ    JWTService
+	auditServ  AuditService
 }
 
 func NewUsersService(
 	usersRepo repository.UsersRepository,
 	hasherServ hash.HasherService,
+	jwtServ JWTService,
+	auditServ AuditService,
 ) (UsersService, error) {
 	if usersRepo == nil {
 		return nil, errors.New("users repository not provided")
@@ -29,9 +34,19 @@ func NewUsersService(
 		return nil, errors.New("hasher service not provided")
 	}
 
+	if jwtServ == nil {
+		return nil, errors.New("jwt service not provided")
+	}
+
+	if auditServ == nil {
+		return nil, errors.New("audit service not provided")
+	}
+
 	s := &usersService{
 		usersRepo:  usersRepo,
 		hasherServ: hasherServ,
+		jwtServ:    jwtServ,
+		auditServ:  auditServ,
 	}
 
 	return s, nil
@@ -50,35 +65,86 @@ func (s *usersService) ChangeEmail(ctx context.Context, schema ChangeEmailSchema
 	err := s.usersRepo.ChangeEmail(ctx, repository.ChangeEmailSchema{
 		UserID:   schema.UserID,
 		NewEmail: schema.NewEmail,
+		Version:  schema.Version,
 	})
 	if err != nil {
 		return errors.Wrapf(err, "user[id:%q]: failed to change email", schema.UserID.Hex())
 	}
 
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionEmailChanged,
+		ActorID: schema.UserID,
+	})
+
 	return nil
 }
 
-func (s *usersService) ChangePassword(ctx context.Context, schema ChangePasswordSchema) error {
+func (s *usersService) ChangePassword(ctx context.Context, schema ChangePasswordSchema) (entity.Tokens, error) {
 	user, err := s.usersRepo.FindByID(ctx, schema.UserID)
 	if err != nil {
-		return errors.Wrapf(err, "failed to get user[id:%q]", user.ID.Hex())
+		return entity.Tokens{}, errors.Wrapf(err, "failed to get user[id:%q]", schema.UserID.Hex())
+	}
+
+	if err = checkUserVersion(user, schema.Version); err != nil {
+		return entity.Tokens{}, err
 	}
 
 	if ok := s.hasherServ.VerifyPassword(schema.CurrentPassword, user.PasswordHash); !ok {
-		return entity.ErrIncorrectCredentials
+		recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+			Action:  entity.AuditActionPasswordChanged,
+			Outcome: entity.AuditOutcomeFailure,
+			ActorID: schema.UserID,
+			Details: map[string]string{"reason": "incorrect_password"},
+		})
+
+		return entity.Tokens{}, entity.ErrIncorrectCredentials
 	}
 
 	passwordHash, err := s.hasherServ.HashPassword(schema.NewPassword)
 	if err != nil {
-		return errors.Wrapf(err, "failed to hash %q password", schema.NewPassword)
+		return entity.Tokens{}, errors.Wrapf(err, "failed to hash %q password", schema.NewPassword)
 	}
 
 	err = s.usersRepo.ChangePassword(ctx, repository.ChangePasswordSchema{
 		UserID:          schema.UserID,
 		NewPasswordHash: passwordHash,
+		Version:         schema.Version,
 	})
 	if err != nil {
-		return errors.Wrapf(err, "user[id:%q]: failed to change password", schema.UserID)
+		return entity.Tokens{}, errors.Wrapf(err, "user[id:%q]: failed to change password", schema.UserID.Hex())
+	}
+
+	recordAudit(ctx, s.auditServ, entity.AuditEventModel{
+		Action:  entity.AuditActionPasswordChanged,
+		ActorID: schema.UserID,
+		Details: map[string]string{"keepCurrentSession": strconv.FormatBool(schema.KeepCurrentSession)},
+	})
+
+	userID := schema.UserID.Hex()
+
+	// tokens issued before the change are revoked on every device, including the current one
+	err = s.jwtServ.RevokeTokens(ctx, userID)
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "user[id:%q]: failed to revoke tokens", userID)
+	}
+
+	if !schema.KeepCurrentSession {
+		return entity.Tokens{}, nil
+	}
+
+	tokens, err := s.jwtServ.CreateTokens(ctx, userID, user.Scopes())
+	if err != nil {
+		return entity.Tokens{}, errors.Wrapf(err, "user[id:%q]: failed to create tokens", userID)
+	}
+
+	return tokens, nil
+}
+
+// checkUserVersion fails early if the user was changed since expected version,
+// repository checks version again when the user is changed
+func checkUserVersion(user entity.UserModel, version int64) error {
+	if version != 0 && user.Version != version {
+		return entity.ErrUserVersionConflict
 	}
 
 	return nil
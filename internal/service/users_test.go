@@ -12,6 +12,7 @@ import (
 	"github.com/kenplix/url-shrtnr/internal/entity"
 	repoMocks "github.com/kenplix/url-shrtnr/internal/repository/mocks"
 	"github.com/kenplix/url-shrtnr/internal/service"
+	servMocks "github.com/kenplix/url-shrtnr/internal/service/mocks"
 	hashMocks "github.com/kenplix/url-shrtnr/pkg/hash/mocks"
 )
 
@@ -80,7 +81,7 @@ func TestUsersService_ChangeEmail(t *testing.T) {
 				hasherServ = hashMocks.NewHasherService(t)
 			)
 
-			usersServ, err := service.NewUsersService(usersRepo, hasherServ)
+			usersServ, err := service.NewUsersService(usersRepo, hasherServ, servMocks.NewJWTService(t), testAuditService(t))
 			require.NoErrorf(t, err, "failed to create users service: %s", err)
 
 			tc.mockBehavior(usersRepo)
@@ -97,10 +98,11 @@ func TestUsersService_ChangePassword(t *testing.T) {
 	}
 
 	type ret struct {
+		tokens entity.Tokens
 		hasErr bool
 	}
 
-	type mockBehavior func(*repoMocks.UsersRepository, *hashMocks.HasherService)
+	type mockBehavior func(*repoMocks.UsersRepository, *hashMocks.HasherService, *servMocks.JWTService)
 
 	testChangePasswordSchema := func(t *testing.T) service.ChangePasswordSchema {
 		t.Helper()
@@ -126,7 +128,7 @@ func TestUsersService_ChangePassword(t *testing.T) {
 			ret: ret{
 				hasErr: true,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService, _ *servMocks.JWTService) {
 				usersRepo.
 					On("FindByID", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, assert.AnError)
@@ -140,7 +142,7 @@ func TestUsersService_ChangePassword(t *testing.T) {
 			ret: ret{
 				hasErr: true,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService, _ *servMocks.JWTService) {
 				usersRepo.
 					On("FindByID", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, nil)
@@ -165,7 +167,7 @@ func TestUsersService_ChangePassword(t *testing.T) {
 			ret: ret{
 				hasErr: true,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService, _ *servMocks.JWTService) {
 				usersRepo.
 					On("FindByID", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, nil)
@@ -187,7 +189,7 @@ func TestUsersService_ChangePassword(t *testing.T) {
 			ret: ret{
 				hasErr: true,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService, _ *servMocks.JWTService) {
 				usersRepo.
 					On("FindByID", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, nil)
@@ -213,7 +215,7 @@ func TestUsersService_ChangePassword(t *testing.T) {
 			ret: ret{
 				hasErr: false,
 			},
-			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService) {
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService, jwtServ *servMocks.JWTService) {
 				usersRepo.
 					On("FindByID", mock.Anything, mock.Anything).
 					Return(entity.UserModel{}, nil)
@@ -229,6 +231,89 @@ func TestUsersService_ChangePassword(t *testing.T) {
 				usersRepo.
 					On("ChangePassword", mock.Anything, mock.Anything, mock.Anything).
 					Return(nil)
+
+				jwtServ.
+					On("RevokeTokens", mock.Anything, mock.Anything).
+					Return(nil)
+			},
+		},
+		{
+			name: "failed to revoke tokens",
+			args: args{
+				schema: testChangePasswordSchema(t),
+			},
+			ret: ret{
+				hasErr: true,
+			},
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService, jwtServ *servMocks.JWTService) {
+				usersRepo.
+					On("FindByID", mock.Anything, mock.Anything).
+					Return(entity.UserModel{}, nil)
+
+				hasherServ.
+					On("VerifyPassword", mock.Anything, mock.Anything).
+					Return(true)
+
+				hasherServ.
+					On("HashPassword", mock.Anything).
+					Return("<password hash>", nil)
+
+				usersRepo.
+					On("ChangePassword", mock.Anything, mock.Anything, mock.Anything).
+					Return(nil)
+
+				jwtServ.
+					On("RevokeTokens", mock.Anything, mock.Anything).
+					Return(assert.AnError)
+			},
+		},
+		{
+			name: "ok with other devices signed out",
+			args: args{
+				schema: func(t *testing.T) service.ChangePasswordSchema {
+					t.Helper()
+
+					schema := testChangePasswordSchema(t)
+					schema.KeepCurrentSession = true
+
+					return schema
+				}(t),
+			},
+			ret: ret{
+				tokens: entity.Tokens{
+					AccessToken:  "<access token>",
+					RefreshToken: "<refresh token>",
+				},
+				hasErr: false,
+			},
+			mockBehavior: func(usersRepo *repoMocks.UsersRepository, hasherServ *hashMocks.HasherService, jwtServ *servMocks.JWTService) {
+				usersRepo.
+					On("FindByID", mock.Anything, mock.Anything).
+					Return(entity.UserModel{}, nil)
+
+				hasherServ.
+					On("VerifyPassword", mock.Anything, mock.Anything).
+					Return(true)
+
+				hasherServ.
+					On("HashPassword", mock.Anything).
+					Return("<password hash>", nil)
+
+				usersRepo.
+					On("ChangePassword", mock.Anything, mock.Anything, mock.Anything).
+					Return(nil)
+
+				revoke := jwtServ.
+					On("RevokeTokens", mock.Anything, mock.Anything).
+					Return(nil)
+
+				jwtServ.
+					On("CreateTokens", mock.Anything, mock.Anything, entity.DefaultScopes).
+					Return(entity.Tokens{
+						AccessToken:  "<access token>",
+						RefreshToken: "<refresh token>",
+					}, nil).
+					NotBefore(revoke)
 			},
 		},
 	}
@@ -240,15 +325,17 @@ func TestUsersService_ChangePassword(t *testing.T) {
 			var (
 				usersRepo  = repoMocks.NewUsersRepository(t)
 				hasherServ = hashMocks.NewHasherService(t)
+				jwtServ    = servMocks.NewJWTService(t)
 			)
 
-			usersServ, err := service.NewUsersService(usersRepo, hasherServ)
+			usersServ, err := service.NewUsersService(usersRepo, hasherServ, jwtServ, testAuditService(t))
 			require.NoErrorf(t, err, "failed to create users service: %s", err)
 
-			tc.mockBehavior(usersRepo, hasherServ)
+			tc.mockBehavior(usersRepo, hasherServ, jwtServ)
 
-			err = usersServ.ChangePassword(context.Background(), tc.args.schema)
+			tokens, err := usersServ.ChangePassword(context.Background(), tc.args.schema)
 			assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+			assert.Equal(t, tc.ret.tokens, tokens)
 		})
 	}
 }
@@ -0,0 +1,64 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+type Config struct {
+	// Use is a storage of blobs, only local filesystem is supported for now
+	Use   string      `mapstructure:"use"`
+	Local LocalConfig `mapstructure:"local"`
+}
+
+// Object is a stored blob opened for reading
+type Object struct {
+	io.ReadSeekCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// Store keeps binary objects, like uploaded images, under slash separated keys
+//
+//go:generate mockery --dir . --name Store --output ./mocks
+type Store interface {
+	// Put creates or replaces object with provided key
+	Put(ctx context.Context, key, contentType string, content io.Reader) error
+	// Get opens object for reading, returned object must be closed
+	Get(ctx context.Context, key string) (Object, error)
+	// Delete removes object, missing object isn't an error
+	Delete(ctx context.Context, key string) error
+}
+
+func NewStore(cfg Config) (Store, error) {
+	switch cfg.Use {
+	case "local", "":
+		return newLocalStore(cfg.Local)
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", cfg.Use)
+	}
+}
+
+var keyRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+(\.[a-zA-Z0-9]+)?(/[a-zA-Z0-9_-]+(\.[a-zA-Z0-9]+)?)*$`)
+
+// ValidateKey checks that key consists of path segments without special names, like "..",
+// so it can't address objects outside the store
+func ValidateKey(key string) error {
+	if !keyRegexp.MatchString(key) || strings.Contains(key, "..") {
+		return ErrInvalidKey
+	}
+
+	return nil
+}
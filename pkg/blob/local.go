@@ -0,0 +1,117 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+type LocalConfig struct {
+	Path string `mapstructure:"path"`
+}
+
+// localStore keeps objects as files, content type is derived from the key extension
+type localStore struct {
+	dir string
+}
+
+func newLocalStore(cfg LocalConfig) (*localStore, error) {
+	path := cfg.Path
+	if path == "" {
+		path = filepath.Join("bin", "blobs")
+	}
+
+	err := os.MkdirAll(path, 0o700)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %q director(y/ies)", path)
+	}
+
+	return &localStore{dir: path}, nil
+}
+
+func (s *localStore) Put(_ context.Context, key, _ string, content io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create directory of %q", key)
+	}
+
+	// object is written to the temporary file first, so readers never see partially written object
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return errors.Wrapf(err, "failed to create temporary file for %q", key)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "failed to write %q", key)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Wrapf(err, "failed to write %q", key)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localStore) Get(_ context.Context, key string) (Object, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Object{}, ErrNotFound
+		}
+
+		return Object{}, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return Object{}, err
+	}
+
+	obj := Object{
+		ReadSeekCloser: f,
+		ContentType:    mime.TypeByExtension(filepath.Ext(key)),
+		Size:           info.Size(),
+		ModTime:        info.ModTime(),
+	}
+
+	return obj, nil
+}
+
+func (s *localStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *localStore) path(key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
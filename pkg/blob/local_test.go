@@ -0,0 +1,70 @@
+package blob_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/pkg/blob"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := blob.NewStore(blob.Config{
+		Use:   "local",
+		Local: blob.LocalConfig{Path: t.TempDir()},
+	})
+	require.NoErrorf(t, err, "failed to create blob store: %s", err)
+
+	const key = "avatars/9f86d081884c7d65.png"
+
+	require.NoError(t, store.Put(ctx, key, "image/png", strings.NewReader("first")))
+	require.NoError(t, store.Put(ctx, key, "image/png", strings.NewReader("second")))
+
+	obj, err := store.Get(ctx, key)
+	require.NoError(t, err)
+
+	content, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	require.NoError(t, obj.Close())
+
+	assert.Equal(t, "second", string(content))
+	assert.Equal(t, "image/png", obj.ContentType)
+	assert.Equal(t, int64(len("second")), obj.Size)
+
+	require.NoError(t, store.Delete(ctx, key))
+	require.NoError(t, store.Delete(ctx, key), "deletion of missing object must succeed")
+
+	_, err = store.Get(ctx, key)
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+}
+
+func TestValidateKey(t *testing.T) {
+	testCases := []struct {
+		key   string
+		valid bool
+	}{
+		{key: "avatars/9f86d081884c7d65.png", valid: true},
+		{key: "export", valid: true},
+		{key: "", valid: false},
+		{key: "../users.json", valid: false},
+		{key: "avatars/../../users.json", valid: false},
+		{key: "/etc/passwd", valid: false},
+		{key: "avatars//image.png", valid: false},
+		{key: `avatars\image.png`, valid: false},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			err := blob.ValidateKey(tc.key)
+			assert.Equalf(t, tc.valid, err == nil, "unexpected result: %v", err)
+		})
+	}
+}
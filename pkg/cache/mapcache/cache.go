@@ -8,7 +8,10 @@ import (
 
 const TTLWithoutExpiration time.Duration = -1
 
-var ErrItemNotFound = errors.New("cache: item not found")
+var (
+	ErrItemNotFound = errors.New("cache: item not found")
+	ErrNotInteger   = errors.New("cache: item value is not an integer")
+)
 
 type item struct {
 	value     any
@@ -16,46 +19,110 @@ type item struct {
 	ttl       time.Duration
 }
 
+// expired reports whether ttl of the item is over, like in redis item expires as soon as its ttl reaches zero
+func (it item) expired(now time.Time) bool {
+	return it.ttl != TTLWithoutExpiration && now.Sub(it.createdAt) >= it.ttl
+}
+
 type Cache struct {
 	cache map[string]item
 	mux   sync.RWMutex
+	done  chan struct{}
+	once  sync.Once
+	now   func() time.Time
 }
 
 // New uses map to store key:value data in-memory.
 func New() *Cache {
-	c := &Cache{cache: make(map[string]item)}
+	return NewWithClock(time.Now)
+}
+
+// NewWithClock is like New, but current time is returned by now, so expiration can be controlled in tests
+func NewWithClock(now func() time.Time) *Cache {
+	c := &Cache{
+		cache: make(map[string]item),
+		done:  make(chan struct{}),
+		now:   now,
+	}
 	go c.setTTLTimer()
 
 	return c
 }
 
 func (c *Cache) setTTLTimer() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
 	for {
 		c.mux.Lock()
+		now := c.now()
 		for key, value := range c.cache {
-			if value.ttl != TTLWithoutExpiration && time.Since(value.createdAt) > value.ttl {
+			if value.expired(now) {
 				delete(c.cache, key)
 			}
 		}
 		c.mux.Unlock()
 
-		<-time.After(time.Second)
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close stops removal of expired items in background, items may still be read
+func (c *Cache) Close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+// Range calls fn for each not expired item with its remaining time to live or TTLWithoutExpiration.
+// Cache must not be modified from fn
+func (c *Cache) Range(fn func(key string, value any, ttl time.Duration)) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	now := c.now()
+
+	for key, it := range c.cache {
+		if it.expired(now) {
+			continue
+		}
+
+		ttl := TTLWithoutExpiration
+		if it.ttl != TTLWithoutExpiration {
+			ttl = it.ttl - now.Sub(it.createdAt)
+		}
+
+		fn(key, it.value, ttl)
 	}
 }
 
 func (c *Cache) Set(key string, value any, ttl time.Duration) {
 	c.mux.Lock()
-	c.cache[key] = item{
-		value:     value,
-		createdAt: time.Now(),
-		ttl:       ttl,
-	}
+	c.set(key, value, ttl)
 	c.mux.Unlock()
 }
 
+// SetNX sets value only if key doesn't exist and reports whether it was set
+func (c *Cache) SetNX(key string, value any, ttl time.Duration) bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if _, ex := c.get(key); ex {
+		return false
+	}
+
+	c.set(key, value, ttl)
+
+	return true
+}
+
 func (c *Cache) Get(key string) (any, error) {
 	c.mux.RLock()
-	it, ex := c.cache[key]
+	it, ex := c.get(key)
 	c.mux.RUnlock()
 
 	if !ex {
@@ -65,34 +132,110 @@ func (c *Cache) Get(key string) (any, error) {
 	return it.value, nil
 }
 
+// GetDel returns value and deletes key atomically, so value is returned only once
+func (c *Cache) GetDel(key string) (any, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	it, ex := c.get(key)
+	if !ex {
+		return nil, ErrItemNotFound
+	}
+
+	delete(c.cache, key)
+
+	return it.value, nil
+}
+
 func (c *Cache) Del(key string) {
 	c.mux.Lock()
 	delete(c.cache, key)
 	c.mux.Unlock()
 }
 
+// Incr atomically increments int64 value of the key, missing key is set to 1 without expiration.
+// TTL of existing key is kept
+func (c *Cache) Incr(key string) (int64, error) {
+	return c.IncrBy(key, 1, TTLWithoutExpiration)
+}
+
+// IncrBy atomically adds delta to int64 value of the key, missing key is set to delta.
+// Positive expiration is set to the key in the same step, otherwise TTL of existing key is kept
+// and missing key is set without expiration
+func (c *Cache) IncrBy(key string, delta int64, expiration time.Duration) (int64, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if expiration <= 0 {
+		expiration = TTLWithoutExpiration
+	}
+
+	it, ex := c.get(key)
+	if !ex {
+		c.set(key, delta, expiration)
+		return delta, nil
+	}
+
+	n, ok := it.value.(int64)
+	if !ok {
+		return 0, ErrNotInteger
+	}
+
+	if expiration != TTLWithoutExpiration {
+		c.set(key, n+delta, expiration)
+	} else {
+		it.value = n + delta
+		c.cache[key] = it
+	}
+
+	return n + delta, nil
+}
+
+// TTL returns remaining time to live of the key or TTLWithoutExpiration
 func (c *Cache) TTL(key string) (time.Duration, error) {
 	c.mux.RLock()
-	it, ex := c.cache[key]
+	it, ex := c.get(key)
 	c.mux.RUnlock()
 
 	if !ex {
 		return 0, ErrItemNotFound
 	}
 
-	return it.ttl - time.Since(it.createdAt), nil
+	if it.ttl == TTLWithoutExpiration {
+		return TTLWithoutExpiration, nil
+	}
+
+	return it.ttl - c.now().Sub(it.createdAt), nil
 }
 
 func (c *Cache) Expire(key string, expiration time.Duration) error {
-	c.mux.RLock()
-	it, ex := c.cache[key]
-	c.mux.RUnlock()
+	c.mux.Lock()
+	defer c.mux.Unlock()
 
+	it, ex := c.get(key)
 	if !ex {
 		return ErrItemNotFound
 	}
 
-	c.Set(key, it.value, expiration)
+	c.set(key, it.value, expiration)
 
 	return nil
 }
+
+// get returns not expired item, expired items are deleted by TTL timer
+func (c *Cache) get(key string) (item, bool) {
+	it, ex := c.cache[key]
+	if !ex || it.expired(c.now()) {
+		return item{}, false
+	}
+
+	return it, true
+}
+
+func (c *Cache) set(key string, value any, ttl time.Duration) {
+	c.cache[key] = item{
+		value:     value,
+		createdAt: c.now(),
+		ttl:       ttl,
+	}
+}
@@ -1,9 +1,13 @@
 package argon2
 
 import (
+	"strings"
+
 	"github.com/alexedwards/argon2id"
 )
 
+const prefix = "$argon2id$"
+
 type Hasher struct {
 	params *argon2id.Params
 }
@@ -29,3 +33,22 @@ func (h *Hasher) VerifyPassword(password, hash string) bool {
 	match, _ := argon2id.ComparePasswordAndHash(password, hash)
 	return match
 }
+
+// Identify reports whether hash is encoded argon2id hash
+func (h *Hasher) Identify(hash string) bool {
+	return strings.HasPrefix(hash, prefix)
+}
+
+// NeedsRehash reports whether hash was created with parameters different from the current ones
+func (h *Hasher) NeedsRehash(hash string) bool {
+	params, salt, key, err := argon2id.DecodeHash(hash)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != h.params.Memory ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		uint32(len(salt)) != h.params.SaltLength ||
+		uint32(len(key)) != h.params.KeyLength
+}
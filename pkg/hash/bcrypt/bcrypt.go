@@ -1,9 +1,13 @@
 package bcrypt
 
 import (
+	"strings"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
+var prefixes = []string{"$2a$", "$2b$", "$2y$"}
+
 type Hasher struct {
 	cost int
 }
@@ -27,3 +31,24 @@ func (h *Hasher) VerifyPassword(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// Identify reports whether hash is encoded bcrypt hash
+func (h *Hasher) Identify(hash string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NeedsRehash reports whether hash was created with cost different from the current one
+func (h *Hasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
@@ -1,35 +1,192 @@
 package hash
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
 
 	"github.com/kenplix/url-shrtnr/pkg/hash/argon2"
 	"github.com/kenplix/url-shrtnr/pkg/hash/bcrypt"
+	"github.com/kenplix/url-shrtnr/pkg/hash/pbkdf2"
+	"github.com/kenplix/url-shrtnr/pkg/hash/scrypt"
 )
 
+// pepperPrefix marks hashes of peppered passwords. It's followed by ID of the pepper
+// and the hash, e.g. "$pepper$<id>$2a$...". Hashes created before peppers got IDs have empty ID
+const pepperPrefix = "$pepper$"
+
 // HasherService provides hashing logic to securely store passwords.
 //
 //go:generate mockery --dir . --name HasherService --output ./mocks
 type HasherService interface {
 	HashPassword(password string) (string, error)
 	VerifyPassword(password, hash string) bool
+	// NeedsRehash reports whether hash should be replaced with the new one,
+	// because it was created by another algorithm, with outdated parameters or pepper settings
+	NeedsRehash(hash string) bool
+}
+
+// Verifier verifies passwords against hashes of the specific algorithm
+type Verifier interface {
+	// Identify reports whether hash was created by the algorithm
+	Identify(hash string) bool
+	VerifyPassword(password, hash string) bool
+}
+
+// Hasher creates and verifies hashes of the specific algorithm
+type Hasher interface {
+	Verifier
+	HashPassword(password string) (string, error)
+	NeedsRehash(hash string) bool
 }
 
 type Config struct {
 	Use    string        `mapstructure:"use"`
 	Bcrypt bcrypt.Config `mapstructure:"bcrypt"`
 	Argon2 argon2.Config `mapstructure:"argon2"`
+	// Pepper is an optional server-side secret mixed into passwords before hashing
+	Pepper string `mapstructure:"pepper"`
+	// PepperID identifies pepper in hashes, so pepper can be rotated
+	PepperID string `mapstructure:"pepperID"`
+	// OldPeppers are previous peppers formatted as "<id>:<pepper>". Passwords hashed with them
+	// are still verified and need rehash with the current pepper
+	OldPeppers []string `mapstructure:"oldPeppers"`
+}
+
+type hasherService struct {
+	hasher    Hasher
+	verifiers []Verifier
+	pepper    []byte
+	pepperID  string
+	// peppers are current and old peppers by their IDs
+	peppers map[string][]byte
 }
 
+// NewHasherService creates HasherService which hashes passwords with the algorithm chosen by cfg.Use
+// and verifies passwords against hashes of any supported algorithm
 func NewHasherService(cfg Config) (HasherService, error) {
+	var (
+		bcryptHasher = bcrypt.NewHasherService(bcrypt.SetConfig(cfg.Bcrypt))
+		argon2Hasher = argon2.NewHasherService(argon2.SetConfig(cfg.Argon2))
+	)
+
+	var hasher Hasher
+
 	switch cfg.Use {
 	case "bcrypt":
-		hasher := bcrypt.NewHasherService(bcrypt.SetConfig(cfg.Bcrypt))
-		return hasher, nil
+		hasher = bcryptHasher
 	case "argon2":
-		hasher := argon2.NewHasherService(argon2.SetConfig(cfg.Argon2))
-		return hasher, nil
+		hasher = argon2Hasher
 	default:
 		return nil, fmt.Errorf("unknown hasher %q", cfg.Use)
 	}
+
+	if strings.Contains(cfg.PepperID, "$") {
+		return nil, fmt.Errorf("pepper ID %q must not contain '$'", cfg.PepperID)
+	}
+
+	peppers := make(map[string][]byte, len(cfg.OldPeppers)+1)
+
+	for _, old := range cfg.OldPeppers {
+		id, pepper, found := strings.Cut(old, ":")
+		if !found || pepper == "" || strings.Contains(id, "$") {
+			return nil, errors.New("old pepper must be formatted as \"<id>:<pepper>\"")
+		}
+
+		if _, found = peppers[id]; found || (id == cfg.PepperID && cfg.Pepper != "") {
+			return nil, fmt.Errorf("pepper ID %q is not unique", id)
+		}
+
+		peppers[id] = []byte(pepper)
+	}
+
+	if cfg.Pepper != "" {
+		peppers[cfg.PepperID] = []byte(cfg.Pepper)
+	}
+
+	s := &hasherService{
+		hasher: hasher,
+		verifiers: []Verifier{
+			argon2Hasher,
+			bcryptHasher,
+			scrypt.NewVerifier(),
+			pbkdf2.NewVerifier(),
+		},
+		pepper:   []byte(cfg.Pepper),
+		pepperID: cfg.PepperID,
+		peppers:  peppers,
+	}
+
+	return s, nil
+}
+
+func (s *hasherService) HashPassword(password string) (string, error) {
+	if len(s.pepper) == 0 {
+		return s.hasher.HashPassword(password)
+	}
+
+	hash, err := s.hasher.HashPassword(s.season(s.pepper, password))
+	if err != nil {
+		return "", err
+	}
+
+	// hashes of supported algorithms start with '$', which separates them from pepper ID
+	return pepperPrefix + s.pepperID + hash, nil
+}
+
+func (s *hasherService) VerifyPassword(password, hash string) bool {
+	if strings.HasPrefix(hash, pepperPrefix) {
+		var id string
+		id, hash = parsePeppered(hash)
+
+		pepper, found := s.peppers[id]
+		if !found {
+			return false
+		}
+
+		password = s.season(pepper, password)
+	}
+
+	for _, verifier := range s.verifiers {
+		if verifier.Identify(hash) {
+			return verifier.VerifyPassword(password, hash)
+		}
+	}
+
+	return false
+}
+
+func (s *hasherService) NeedsRehash(hash string) bool {
+	peppered := strings.HasPrefix(hash, pepperPrefix)
+	if peppered != (len(s.pepper) != 0) {
+		return true
+	}
+
+	if peppered {
+		var id string
+		if id, hash = parsePeppered(hash); id != s.pepperID {
+			return true
+		}
+	}
+
+	return !s.hasher.Identify(hash) || s.hasher.NeedsRehash(hash)
+}
+
+// season mixes pepper into password. HMAC output is encoded to keep
+// the result printable and within bcrypt 72 bytes limit
+func (s *hasherService) season(pepper []byte, password string) string {
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(password))
+
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parsePeppered returns ID of the pepper and the hash of peppered password
+func parsePeppered(peppered string) (id, hash string) {
+	id, hash, _ = strings.Cut(strings.TrimPrefix(peppered, pepperPrefix), "$")
+	return id, "$" + hash
 }
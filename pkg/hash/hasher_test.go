@@ -0,0 +1,283 @@
+package hash_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/pkg/hash"
+	"github.com/kenplix/url-shrtnr/pkg/hash/argon2"
+	"github.com/kenplix/url-shrtnr/pkg/hash/bcrypt"
+)
+
+const testPassword = "1wE$Rty2"
+
+func testConfig(use string) hash.Config {
+	return hash.Config{
+		Use: use,
+		Bcrypt: bcrypt.Config{
+			Cost: 4,
+		},
+		Argon2: argon2.Config{
+			Memory:      1024,
+			Iterations:  1,
+			Parallelism: 1,
+			SaltLength:  16,
+			KeyLength:   16,
+		},
+	}
+}
+
+func mustHash(t *testing.T, cfg hash.Config) string {
+	t.Helper()
+
+	hasherServ, err := hash.NewHasherService(cfg)
+	require.NoErrorf(t, err, "failed to create hasher service: %s", err)
+
+	passwordHash, err := hasherServ.HashPassword(testPassword)
+	require.NoErrorf(t, err, "failed to hash password: %s", err)
+
+	return passwordHash
+}
+
+func TestNewHasherService(t *testing.T) {
+	t.Parallel()
+
+	_, err := hash.NewHasherService(testConfig("md5"))
+	assert.Error(t, err)
+
+	cfg := testConfig("argon2")
+	cfg.OldPeppers = []string{"<pepper without ID>"}
+
+	_, err = hash.NewHasherService(cfg)
+	assert.Error(t, err, "old pepper without ID must be rejected")
+
+	cfg = testConfig("argon2")
+	cfg.Pepper, cfg.PepperID = "<pepper>", "2"
+	cfg.OldPeppers = []string{"2:<old pepper>"}
+
+	_, err = hash.NewHasherService(cfg)
+	assert.Error(t, err, "old pepper with ID of the current one must be rejected")
+}
+
+func TestHasherService(t *testing.T) {
+	type ret struct {
+		verified    bool
+		needsRehash bool
+	}
+
+	testCases := []struct {
+		name string
+		cfg  hash.Config
+		hash func(t *testing.T) string
+		ret  ret
+	}{
+		{
+			name: "current algorithm",
+			cfg:  testConfig("argon2"),
+			hash: func(t *testing.T) string {
+				return mustHash(t, testConfig("argon2"))
+			},
+			ret: ret{verified: true, needsRehash: false},
+		},
+		{
+			name: "another algorithm",
+			cfg:  testConfig("argon2"),
+			hash: func(t *testing.T) string {
+				return mustHash(t, testConfig("bcrypt"))
+			},
+			ret: ret{verified: true, needsRehash: true},
+		},
+		{
+			name: "outdated argon2 parameters",
+			cfg:  testConfig("argon2"),
+			hash: func(t *testing.T) string {
+				cfg := testConfig("argon2")
+				cfg.Argon2.Iterations = 2
+
+				return mustHash(t, cfg)
+			},
+			ret: ret{verified: true, needsRehash: true},
+		},
+		{
+			name: "outdated bcrypt cost",
+			cfg:  testConfig("bcrypt"),
+			hash: func(t *testing.T) string {
+				cfg := testConfig("bcrypt")
+				cfg.Bcrypt.Cost = 5
+
+				return mustHash(t, cfg)
+			},
+			ret: ret{verified: true, needsRehash: true},
+		},
+		{
+			name: "imported scrypt hash",
+			cfg:  testConfig("argon2"),
+			hash: func(t *testing.T) string {
+				return "$scrypt$ln=10,r=8,p=1$c2FsdHlzYWx0eXNhbHQxNg$RD73PoxCKPLyj+P9fJaHLVLXMc8G8V74P5qrwCn7wdo"
+			},
+			ret: ret{verified: true, needsRehash: true},
+		},
+		{
+			name: "imported pbkdf2 hash",
+			cfg:  testConfig("bcrypt"),
+			hash: func(t *testing.T) string {
+				return "$pbkdf2-sha256$1000$c2FsdHlzYWx0eXNhbHQxNg$TIs0i3ZngHOeLhsitagM54j6qy/V.BJYcH0r/ivH1jA"
+			},
+			ret: ret{verified: true, needsRehash: true},
+		},
+		{
+			name: "unknown algorithm",
+			cfg:  testConfig("argon2"),
+			hash: func(t *testing.T) string {
+				return "5f4dcc3b5aa765d61d8327deb882cf99"
+			},
+			ret: ret{verified: false, needsRehash: true},
+		},
+		{
+			name: "peppered hash",
+			cfg: func() hash.Config {
+				cfg := testConfig("argon2")
+				cfg.Pepper = "<pepper>"
+
+				return cfg
+			}(),
+			hash: func(t *testing.T) string {
+				cfg := testConfig("argon2")
+				cfg.Pepper = "<pepper>"
+
+				passwordHash := mustHash(t, cfg)
+				require.True(t, strings.HasPrefix(passwordHash, "$pepper$"))
+
+				return passwordHash
+			},
+			ret: ret{verified: true, needsRehash: false},
+		},
+		{
+			name: "hash without pepper",
+			cfg: func() hash.Config {
+				cfg := testConfig("argon2")
+				cfg.Pepper = "<pepper>"
+
+				return cfg
+			}(),
+			hash: func(t *testing.T) string {
+				return mustHash(t, testConfig("argon2"))
+			},
+			ret: ret{verified: true, needsRehash: true},
+		},
+		{
+			name: "wrong pepper",
+			cfg: func() hash.Config {
+				cfg := testConfig("argon2")
+				cfg.Pepper = "<another pepper>"
+
+				return cfg
+			}(),
+			hash: func(t *testing.T) string {
+				cfg := testConfig("argon2")
+				cfg.Pepper = "<pepper>"
+
+				return mustHash(t, cfg)
+			},
+			ret: ret{verified: false, needsRehash: false},
+		},
+		{
+			name: "peppered hash without configured pepper",
+			cfg:  testConfig("argon2"),
+			hash: func(t *testing.T) string {
+				cfg := testConfig("argon2")
+				cfg.Pepper = "<pepper>"
+
+				return mustHash(t, cfg)
+			},
+			ret: ret{verified: false, needsRehash: true},
+		},
+		{
+			name: "peppered hash with ID",
+			cfg: func() hash.Config {
+				cfg := testConfig("argon2")
+				cfg.Pepper, cfg.PepperID = "<pepper>", "1"
+
+				return cfg
+			}(),
+			hash: func(t *testing.T) string {
+				cfg := testConfig("argon2")
+				cfg.Pepper, cfg.PepperID = "<pepper>", "1"
+
+				passwordHash := mustHash(t, cfg)
+				require.True(t, strings.HasPrefix(passwordHash, "$pepper$1$argon2id$"))
+
+				return passwordHash
+			},
+			ret: ret{verified: true, needsRehash: false},
+		},
+		{
+			name: "rotated pepper",
+			cfg: func() hash.Config {
+				cfg := testConfig("argon2")
+				cfg.Pepper, cfg.PepperID = "<another pepper>", "2"
+				cfg.OldPeppers = []string{"1:<pepper>"}
+
+				return cfg
+			}(),
+			hash: func(t *testing.T) string {
+				cfg := testConfig("argon2")
+				cfg.Pepper, cfg.PepperID = "<pepper>", "1"
+
+				return mustHash(t, cfg)
+			},
+			ret: ret{verified: true, needsRehash: true},
+		},
+		{
+			name: "rotated pepper without ID",
+			cfg: func() hash.Config {
+				cfg := testConfig("argon2")
+				cfg.Pepper, cfg.PepperID = "<another pepper>", "2"
+				cfg.OldPeppers = []string{":<pepper>"}
+
+				return cfg
+			}(),
+			hash: func(t *testing.T) string {
+				cfg := testConfig("argon2")
+				cfg.Pepper = "<pepper>"
+
+				return mustHash(t, cfg)
+			},
+			ret: ret{verified: true, needsRehash: true},
+		},
+		{
+			name: "unknown pepper ID",
+			cfg: func() hash.Config {
+				cfg := testConfig("argon2")
+				cfg.Pepper, cfg.PepperID = "<another pepper>", "2"
+
+				return cfg
+			}(),
+			hash: func(t *testing.T) string {
+				cfg := testConfig("argon2")
+				cfg.Pepper, cfg.PepperID = "<pepper>", "1"
+
+				return mustHash(t, cfg)
+			},
+			ret: ret{verified: false, needsRehash: true},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hasherServ, err := hash.NewHasherService(tc.cfg)
+			require.NoErrorf(t, err, "failed to create hasher service: %s", err)
+
+			passwordHash := tc.hash(t)
+
+			assert.Equal(t, tc.ret.verified, hasherServ.VerifyPassword(testPassword, passwordHash))
+			assert.Equal(t, tc.ret.needsRehash, hasherServ.NeedsRehash(passwordHash))
+			assert.False(t, hasherServ.VerifyPassword("2ytR$Ew1", passwordHash))
+		})
+	}
+}
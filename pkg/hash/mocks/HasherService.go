@@ -30,6 +30,20 @@ func (_m *HasherService) HashPassword(password string) (string, error) {
 	return r0, r1
 }
 
+// NeedsRehash provides a mock function with given fields: _a0
+func (_m *HasherService) NeedsRehash(_a0 string) bool {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // VerifyPassword provides a mock function with given fields: password, _a1
 func (_m *HasherService) VerifyPassword(password string, _a1 string) bool {
 	ret := _m.Called(password, _a1)
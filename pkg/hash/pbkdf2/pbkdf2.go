@@ -0,0 +1,91 @@
+// Package pbkdf2 verifies passwords against PBKDF2 hashes of imported accounts.
+//
+// Hashes are expected in the modular crypt format used by passlib:
+//
+//	$pbkdf2-<digest>$<iterations>$<salt>$<key>
+//
+// where digest is one of sha1, sha256 or sha512, and salt and key are encoded
+// with adapted base64 (unpadded standard base64 with "." instead of "+").
+package pbkdf2
+
+import (
+	"crypto/sha1" //nolint:gosec // passlib default digest of imported hashes
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const prefix = "$pbkdf2"
+
+var digests = map[string]func() hash.Hash{
+	"pbkdf2":        sha1.New,
+	"pbkdf2-sha256": sha256.New,
+	"pbkdf2-sha512": sha512.New,
+}
+
+type Verifier struct{}
+
+func NewVerifier() *Verifier {
+	return &Verifier{}
+}
+
+// Identify reports whether hash is encoded PBKDF2 hash
+func (v *Verifier) Identify(hash string) bool {
+	return strings.HasPrefix(hash, prefix)
+}
+
+func (v *Verifier) VerifyPassword(password, hash string) bool {
+	digest, iterations, salt, key, err := decodeHash(hash)
+	if err != nil {
+		return false
+	}
+
+	otherKey := pbkdf2.Key([]byte(password), salt, iterations, len(key), digest)
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1
+}
+
+func decodeHash(encoded string) (digest func() hash.Hash, iterations int, salt, key []byte, err error) {
+	// "", scheme, iterations, salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return nil, 0, nil, nil, fmt.Errorf("invalid pbkdf2 hash format")
+	}
+
+	digest, ok := digests[parts[1]]
+	if !ok {
+		return nil, 0, nil, nil, fmt.Errorf("unsupported pbkdf2 scheme %q", parts[1])
+	}
+
+	iterations, err = strconv.Atoi(parts[2])
+	if err != nil || iterations <= 0 {
+		return nil, 0, nil, nil, fmt.Errorf("invalid pbkdf2 iterations %q", parts[2])
+	}
+
+	salt, err = decodeAB64(parts[3])
+	if err != nil {
+		return nil, 0, nil, nil, fmt.Errorf("invalid pbkdf2 hash salt: %w", err)
+	}
+
+	key, err = decodeAB64(parts[4])
+	if err != nil {
+		return nil, 0, nil, nil, fmt.Errorf("invalid pbkdf2 hash key: %w", err)
+	}
+
+	if len(key) == 0 {
+		return nil, 0, nil, nil, fmt.Errorf("empty pbkdf2 hash key")
+	}
+
+	return digest, iterations, salt, key, nil
+}
+
+func decodeAB64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.ReplaceAll(s, ".", "+"))
+}
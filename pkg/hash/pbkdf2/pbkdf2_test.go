@@ -0,0 +1,82 @@
+package pbkdf2_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kenplix/url-shrtnr/pkg/hash/pbkdf2"
+)
+
+func TestVerifier_VerifyPassword(t *testing.T) {
+	type args struct {
+		password string
+		hash     string
+	}
+
+	testCases := []struct {
+		name string
+		args args
+		ret  bool
+	}{
+		{
+			name: "sha1",
+			args: args{
+				password: "1wE$Rty2",
+				hash:     "$pbkdf2$1000$c2FsdHlzYWx0eXNhbHQxNg$1ELnVW/TPt/lR079s.g2Scn5v5c",
+			},
+			ret: true,
+		},
+		{
+			name: "sha256",
+			args: args{
+				password: "1wE$Rty2",
+				hash:     "$pbkdf2-sha256$1000$c2FsdHlzYWx0eXNhbHQxNg$TIs0i3ZngHOeLhsitagM54j6qy/V.BJYcH0r/ivH1jA",
+			},
+			ret: true,
+		},
+		{
+			name: "sha512",
+			args: args{
+				password: "1wE$Rty2",
+				hash:     "$pbkdf2-sha512$1000$c2FsdHlzYWx0eXNhbHQxNg$828dPPBrfItS7jA095t2j1hYBcg2bhVXoFO540VSoAemRbBxb2z5ikttxpK3Uqe/hVd4b2SFUg1Xv3yrby54Yw",
+			},
+			ret: true,
+		},
+		{
+			name: "incorrect password",
+			args: args{
+				password: "2ytR$Ew1",
+				hash:     "$pbkdf2-sha256$1000$c2FsdHlzYWx0eXNhbHQxNg$TIs0i3ZngHOeLhsitagM54j6qy/V.BJYcH0r/ivH1jA",
+			},
+			ret: false,
+		},
+		{
+			name: "unsupported digest",
+			args: args{
+				password: "1wE$Rty2",
+				hash:     "$pbkdf2-md5$1000$c2FsdHlzYWx0eXNhbHQxNg$TIs0i3ZngHOeLhsitagM54j6qy/V.BJYcH0r/ivH1jA",
+			},
+			ret: false,
+		},
+		{
+			name: "invalid iterations",
+			args: args{
+				password: "1wE$Rty2",
+				hash:     "$pbkdf2-sha256$-1$c2FsdHlzYWx0eXNhbHQxNg$TIs0i3ZngHOeLhsitagM54j6qy/V.BJYcH0r/ivH1jA",
+			},
+			ret: false,
+		},
+	}
+
+	t.Parallel()
+
+	verifier := pbkdf2.NewVerifier()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.True(t, verifier.Identify(tc.args.hash))
+			assert.Equal(t, tc.ret, verifier.VerifyPassword(tc.args.password, tc.args.hash))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Package scrypt verifies passwords against scrypt hashes of imported accounts.
+//
+// Hashes are expected in the modular crypt format used by passlib:
+//
+//	$scrypt$ln=<log2(N)>,r=<r>,p=<p>$<salt>$<key>
+//
+// where salt and key are encoded with unpadded standard base64.
+package scrypt
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const prefix = "$scrypt$"
+
+type params struct {
+	logN uint8
+	r    int
+	p    int
+}
+
+type Verifier struct{}
+
+func NewVerifier() *Verifier {
+	return &Verifier{}
+}
+
+// Identify reports whether hash is encoded scrypt hash
+func (v *Verifier) Identify(hash string) bool {
+	return strings.HasPrefix(hash, prefix)
+}
+
+func (v *Verifier) VerifyPassword(password, hash string) bool {
+	p, salt, key, err := decodeHash(hash)
+	if err != nil {
+		return false
+	}
+
+	otherKey, err := scrypt.Key([]byte(password), salt, 1<<p.logN, p.r, p.p, len(key))
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1
+}
+
+func decodeHash(hash string) (p params, salt, key []byte, err error) {
+	// "", "scrypt", params, salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 5 || parts[1] != "scrypt" {
+		return params{}, nil, nil, fmt.Errorf("invalid scrypt hash format")
+	}
+
+	_, err = fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &p.logN, &p.r, &p.p)
+	if err != nil {
+		return params{}, nil, nil, fmt.Errorf("invalid scrypt hash parameters: %w", err)
+	}
+
+	if p.logN == 0 || p.logN > 31 {
+		return params{}, nil, nil, fmt.Errorf("invalid scrypt cost parameter ln=%d", p.logN)
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return params{}, nil, nil, fmt.Errorf("invalid scrypt hash salt: %w", err)
+	}
+
+	key, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params{}, nil, nil, fmt.Errorf("invalid scrypt hash key: %w", err)
+	}
+
+	if len(key) == 0 {
+		return params{}, nil, nil, fmt.Errorf("empty scrypt hash key")
+	}
+
+	return p, salt, key, nil
+}
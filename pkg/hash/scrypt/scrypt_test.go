@@ -0,0 +1,56 @@
+package scrypt_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kenplix/url-shrtnr/pkg/hash/scrypt"
+)
+
+func TestVerifier_VerifyPassword(t *testing.T) {
+	const testHash = "$scrypt$ln=10,r=8,p=1$c2FsdHlzYWx0eXNhbHQxNg$RD73PoxCKPLyj+P9fJaHLVLXMc8G8V74P5qrwCn7wdo"
+
+	type args struct {
+		password string
+		hash     string
+	}
+
+	testCases := []struct {
+		name string
+		args args
+		ret  bool
+	}{
+		{
+			name: "correct password",
+			args: args{password: "1wE$Rty2", hash: testHash},
+			ret:  true,
+		},
+		{
+			name: "incorrect password",
+			args: args{password: "2ytR$Ew1", hash: testHash},
+			ret:  false,
+		},
+		{
+			name: "invalid parameters",
+			args: args{password: "1wE$Rty2", hash: "$scrypt$ln=0,r=8,p=1$c2FsdHlzYWx0eXNhbHQxNg$RD73PoxCKPLyj+P9fJaHLVLXMc8G8V74P5qrwCn7wdo"},
+			ret:  false,
+		},
+		{
+			name: "malformed hash",
+			args: args{password: "1wE$Rty2", hash: "$scrypt$ln=10,r=8,p=1$c2FsdHlzYWx0eXNhbHQxNg"},
+			ret:  false,
+		},
+	}
+
+	t.Parallel()
+
+	verifier := scrypt.NewVerifier()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.True(t, verifier.Identify(tc.args.hash))
+			assert.Equal(t, tc.ret, verifier.VerifyPassword(tc.args.password, tc.args.hash))
+		})
+	}
+}
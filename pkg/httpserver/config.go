@@ -22,6 +22,28 @@ type Config struct {
 	WriteTimeout      time.Duration `mapstructure:"writeTimeout"`
 	IdleTimeout       time.Duration `mapstructure:"idleTimeout"`
 	ShutdownTimeout   time.Duration `mapstructure:"shutdownTimeout"`
+	// TrustedProxies, CORS and AuthCookies are not used by the Server itself, they are consumed by the HTTP router
+	TrustedProxies []string          `mapstructure:"trustedProxies"`
+	CORS           CORSConfig        `mapstructure:"cors"`
+	AuthCookies    AuthCookiesConfig `mapstructure:"authCookies"`
+}
+
+type CORSConfig struct {
+	// AllowOrigins is a list of origins a cross-domain request can be executed from, "*" allows all origins
+	AllowOrigins []string `mapstructure:"allowOrigins"`
+	// AllowCredentials allows requests with cookies, it can't be used together with "*" origin
+	AllowCredentials bool          `mapstructure:"allowCredentials"`
+	MaxAge           time.Duration `mapstructure:"maxAge"`
+}
+
+// AuthCookiesConfig configures browser auth mode in which tokens are kept in HttpOnly cookies
+type AuthCookiesConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Domain  string `mapstructure:"domain"`
+	// SameSite is one of lax, strict or none
+	SameSite string `mapstructure:"sameSite"`
+	// MaxAge is a lifetime of cookies, it should match refresh token TTL. Zero makes session cookies
+	MaxAge time.Duration `mapstructure:"maxAge"`
 }
 
 func SetConfig(cfg Config) Option {
@@ -0,0 +1,115 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/kenplix/url-shrtnr/pkg/log"
+)
+
+type Config struct {
+	// Use is a sender of emails: smtp or log, which only writes emails to the logger for development
+	Use  string     `mapstructure:"use"`
+	SMTP SMTPConfig `mapstructure:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     string `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers emails to users
+//
+//go:generate mockery --dir . --name Sender --output ./mocks
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func NewSender(cfg Config) (Sender, error) {
+	switch cfg.Use {
+	case "smtp":
+		return newSMTPSender(cfg.SMTP)
+	case "log", "":
+		return &logSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail sender %q", cfg.Use)
+	}
+}
+
+type smtpSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func newSMTPSender(cfg SMTPConfig) (*smtpSender, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("smtp host not provided")
+	}
+
+	if cfg.From == "" {
+		return nil, errors.New("sender address not provided")
+	}
+
+	s := &smtpSender{
+		addr: net.JoinHostPort(cfg.Host, cfg.Port),
+		from: cfg.From,
+	}
+
+	if cfg.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return s, nil
+}
+
+func (s *smtpSender) Send(_ context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return errors.New("message headers must not contain line breaks")
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	err := smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, []byte(b.String()))
+	if err != nil {
+		return errors.Wrapf(err, "failed to send email to %q", msg.To)
+	}
+
+	return nil
+}
+
+type logSender struct{}
+
+func (s *logSender) Send(ctx context.Context, msg Message) error {
+	logger := log.LoggerFromContext(ctx)
+	logger.Info("email",
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+	)
+
+	return nil
+}
@@ -0,0 +1,44 @@
+// Code generated by mockery v2.16.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mail "github.com/kenplix/url-shrtnr/pkg/mail"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Sender is an autogenerated mock type for the Sender type
+type Sender struct {
+	mock.Mock
+}
+
+// Send provides a mock function with given fields: ctx, msg
+func (_m *Sender) Send(ctx context.Context, msg mail.Message) error {
+	ret := _m.Called(ctx, msg)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, mail.Message) error); ok {
+		r0 = rf(ctx, msg)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewSender interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewSender creates a new instance of Sender. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewSender(t mockConstructorTestingTNewSender) *Sender {
+	mock := &Sender{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
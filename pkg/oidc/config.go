@@ -0,0 +1,16 @@
+package oidc
+
+type Config struct {
+	// Issuer is used for OpenID Connect discovery, leave it empty for plain OAuth2 providers
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"clientID"`
+	ClientSecret string   `mapstructure:"clientSecret"`
+	RedirectURL  string   `mapstructure:"redirectURL"`
+	Scopes       []string `mapstructure:"scopes"`
+	// AuthURL, TokenURL and UserInfoURL are required by plain OAuth2 providers (GitHub)
+	AuthURL     string `mapstructure:"authURL"`
+	TokenURL    string `mapstructure:"tokenURL"`
+	UserInfoURL string `mapstructure:"userInfoURL"`
+	// TrustEmail treats emails as verified for providers which don't return "email_verified" claim
+	TrustEmail bool `mapstructure:"trustEmail"`
+}
@@ -0,0 +1,247 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const codeVerifierSize = 32
+
+var defaultScopes = []string{oidc.ScopeOpenID, "profile", "email"}
+
+// Identity is an end-user information asserted by the provider
+type Identity struct {
+	// Subject is an identifier of the end-user unique within the provider
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// Username is a preferred username of the end-user (optional)
+	Username string
+}
+
+// Provider implements OAuth2 authorization code flow with PKCE. When issuer is configured
+// endpoints are discovered and identity is taken from the verified ID token, otherwise
+// it's requested from the userinfo endpoint
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewProvider(cfg Config) (*Provider, error) {
+	if cfg.ClientID == "" {
+		return nil, errors.New("client id not provided")
+	}
+
+	if cfg.RedirectURL == "" {
+		return nil, errors.New("redirect url not provided")
+	}
+
+	if cfg.Issuer == "" && (cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "") {
+		return nil, errors.New("neither issuer nor auth, token and userinfo urls provided")
+	}
+
+	p := &Provider{
+		cfg:    cfg,
+		client: http.DefaultClient,
+	}
+
+	return p, nil
+}
+
+// AuthCodeURL returns URL of the provider consent page. Nonce and code verifier
+// must be kept until callback and passed to Exchange
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	config, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	opts := []oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("code_challenge", CodeChallenge(codeVerifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	}
+
+	if p.cfg.Issuer != "" {
+		opts = append(opts, oidc.Nonce(nonce))
+	}
+
+	return config.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange trades authorization code for tokens and returns identity of the end-user
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (Identity, error) {
+	config, verifier, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	ctx = oidc.ClientContext(ctx, p.client)
+
+	token, err := config.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+	if err != nil {
+		return Identity{}, errors.Wrap(err, "failed to exchange authorization code")
+	}
+
+	if verifier == nil {
+		return p.userInfo(ctx, token)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New("token response has no id_token")
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, errors.Wrap(err, "failed to verify id token")
+	}
+
+	if idToken.Nonce != nonce {
+		return Identity{}, errors.New("id token nonce mismatch")
+	}
+
+	var claims identityClaims
+	if err = idToken.Claims(&claims); err != nil {
+		return Identity{}, errors.Wrap(err, "failed to parse id token claims")
+	}
+
+	return claims.identity(p.cfg.TrustEmail), nil
+}
+
+// discover lazily resolves provider endpoints, so unavailable provider doesn't prevent application start
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	endpoint := oauth2.Endpoint{
+		AuthURL:  p.cfg.AuthURL,
+		TokenURL: p.cfg.TokenURL,
+	}
+
+	var verifier *oidc.IDTokenVerifier
+
+	if p.cfg.Issuer != "" {
+		// provider keeps context to fetch rotated keys, so it mustn't be bound to the request
+		discoveryCtx := oidc.ClientContext(context.Background(), p.client)
+
+		provider, err := oidc.NewProvider(discoveryCtx, p.cfg.Issuer)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to discover %q issuer", p.cfg.Issuer)
+		}
+
+		endpoint = provider.Endpoint()
+		verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	}
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 && p.cfg.Issuer != "" {
+		scopes = defaultScopes
+	}
+
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     endpoint,
+		Scopes:       scopes,
+	}
+	p.verifier = verifier
+
+	return p.oauth2, p.verifier, nil
+}
+
+func (p *Provider) userInfo(ctx context.Context, token *oauth2.Token) (Identity, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.UserInfoURL, http.NoBody)
+	if err != nil {
+		return Identity{}, errors.Wrap(err, "failed to create userinfo request")
+	}
+
+	req.Header.Set("Accept", "application/json")
+	token.SetAuthHeader(req)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Identity{}, errors.Wrap(err, "failed to request userinfo")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Identity{}, fmt.Errorf("userinfo request failed with %q status", resp.Status)
+	}
+
+	var claims identityClaims
+	if err = json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return Identity{}, errors.Wrap(err, "failed to decode userinfo")
+	}
+
+	return claims.identity(p.cfg.TrustEmail), nil
+}
+
+// identityClaims covers both standard OpenID Connect claims and
+// GitHub-like userinfo responses with numeric "id" and "login"
+type identityClaims struct {
+	Subject           string          `json:"sub"`
+	ID                json.RawMessage `json:"id"`
+	Email             string          `json:"email"`
+	EmailVerified     *bool           `json:"email_verified"`
+	PreferredUsername string          `json:"preferred_username"`
+	Login             string          `json:"login"`
+}
+
+func (c identityClaims) identity(trustEmail bool) Identity {
+	identity := Identity{
+		Subject:       c.Subject,
+		Email:         c.Email,
+		EmailVerified: c.EmailVerified != nil && *c.EmailVerified,
+		Username:      c.PreferredUsername,
+	}
+
+	if identity.Subject == "" && len(c.ID) != 0 && string(c.ID) != "null" {
+		identity.Subject = strings.Trim(string(c.ID), `"`)
+	}
+
+	if identity.Username == "" {
+		identity.Username = c.Login
+	}
+
+	if c.EmailVerified == nil && trustEmail {
+		identity.EmailVerified = identity.Email != ""
+	}
+
+	return identity
+}
+
+// NewCodeVerifier returns random PKCE code verifier
+func NewCodeVerifier() (string, error) {
+	buf := make([]byte, codeVerifierSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "failed to read random bytes")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CodeChallenge returns S256 PKCE code challenge of the verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,169 @@
+package oidc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kenplix/url-shrtnr/pkg/oidc"
+	"github.com/kenplix/url-shrtnr/pkg/oidc/oidctest"
+)
+
+func TestProvider(t *testing.T) {
+	server := oidctest.NewServer()
+	t.Cleanup(server.Close)
+
+	type args struct {
+		config   oidc.Config
+		claims   map[string]any
+		verifier func(verifier string) string
+		nonce    func(nonce string) string
+	}
+
+	type ret struct {
+		identity oidc.Identity
+		hasErr   bool
+	}
+
+	testOIDCConfig := oidc.Config{
+		Issuer:       server.URL,
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "http://localhost/callback",
+	}
+
+	testOAuth2Config := oidc.Config{
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "http://localhost/callback",
+		AuthURL:      server.URL + "/authorize",
+		TokenURL:     server.URL + "/token",
+		UserInfoURL:  server.URL + "/userinfo",
+	}
+
+	same := func(s string) string { return s }
+
+	testCases := []struct {
+		name string
+		args args
+		ret  ret
+	}{
+		{
+			name: "id token identity",
+			args: args{
+				config: testOIDCConfig,
+				claims: map[string]any{
+					"sub":                "248289761001",
+					"email":              "janedoe@example.com",
+					"email_verified":     true,
+					"preferred_username": "j.doe",
+				},
+				verifier: same,
+				nonce:    same,
+			},
+			ret: ret{
+				identity: oidc.Identity{
+					Subject:       "248289761001",
+					Email:         "janedoe@example.com",
+					EmailVerified: true,
+					Username:      "j.doe",
+				},
+			},
+		},
+		{
+			name: "unverified email",
+			args: args{
+				config: testOIDCConfig,
+				claims: map[string]any{
+					"sub":   "248289761001",
+					"email": "janedoe@example.com",
+				},
+				verifier: same,
+				nonce:    same,
+			},
+			ret: ret{
+				identity: oidc.Identity{
+					Subject: "248289761001",
+					Email:   "janedoe@example.com",
+				},
+			},
+		},
+		{
+			name: "userinfo identity",
+			args: args{
+				config: func() oidc.Config {
+					cfg := testOAuth2Config
+					cfg.TrustEmail = true
+
+					return cfg
+				}(),
+				claims: map[string]any{
+					"id":    583231,
+					"login": "octocat",
+					"email": "octocat@github.com",
+				},
+				verifier: same,
+				nonce:    same,
+			},
+			ret: ret{
+				identity: oidc.Identity{
+					Subject:       "583231",
+					Email:         "octocat@github.com",
+					EmailVerified: true,
+					Username:      "octocat",
+				},
+			},
+		},
+		{
+			name: "wrong code verifier",
+			args: args{
+				config:   testOIDCConfig,
+				claims:   map[string]any{"sub": "248289761001"},
+				verifier: func(string) string { return "wrong" },
+				nonce:    same,
+			},
+			ret: ret{
+				hasErr: true,
+			},
+		},
+		{
+			name: "nonce mismatch",
+			args: args{
+				config:   testOIDCConfig,
+				claims:   map[string]any{"sub": "248289761001"},
+				verifier: same,
+				nonce:    func(string) string { return "wrong" },
+			},
+			ret: ret{
+				hasErr: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server.SetClaims(tc.args.claims)
+
+			provider, err := oidc.NewProvider(tc.args.config)
+			require.NoErrorf(t, err, "failed to create provider: %s", err)
+
+			verifier, err := oidc.NewCodeVerifier()
+			require.NoErrorf(t, err, "failed to create code verifier: %s", err)
+
+			ctx := context.Background()
+
+			authURL, err := provider.AuthCodeURL(ctx, "<state>", "<nonce>", verifier)
+			require.NoErrorf(t, err, "failed to create auth code url: %s", err)
+
+			callback, err := server.Authorize(authURL)
+			require.NoErrorf(t, err, "failed to authorize: %s", err)
+			assert.Equal(t, "<state>", callback.Get("state"))
+
+			identity, err := provider.Exchange(ctx, callback.Get("code"), tc.args.verifier(verifier), tc.args.nonce("<nonce>"))
+			assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+			assert.Equal(t, tc.ret.identity, identity)
+		})
+	}
+}
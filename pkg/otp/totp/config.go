@@ -0,0 +1,17 @@
+package totp
+
+import "time"
+
+type Config struct {
+	Digits int           `mapstructure:"digits"`
+	Period time.Duration `mapstructure:"period"`
+	Skew   uint          `mapstructure:"skew"`
+}
+
+func SetConfig(cfg Config) Option {
+	return Preset(
+		SetDigits(cfg.Digits),
+		SetPeriod(cfg.Period),
+		SetSkew(cfg.Skew),
+	)
+}
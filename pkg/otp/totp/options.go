@@ -0,0 +1,47 @@
+package totp
+
+import "time"
+
+// Option configures a Generator.
+type Option interface {
+	apply(g *Generator)
+}
+
+type optionFunc func(g *Generator)
+
+func (fn optionFunc) apply(g *Generator) {
+	fn(g)
+}
+
+// Preset turns a list of Option instances into an Option
+func Preset(options ...Option) Option {
+	return optionFunc(func(g *Generator) {
+		for _, option := range options {
+			option.apply(g)
+		}
+	})
+}
+
+func SetDigits(digits int) Option {
+	return optionFunc(func(g *Generator) {
+		if digits >= 6 && digits <= 8 {
+			g.digits = digits
+		}
+	})
+}
+
+func SetPeriod(period time.Duration) Option {
+	return optionFunc(func(g *Generator) {
+		if period >= time.Second {
+			g.period = period
+		}
+	})
+}
+
+func SetSkew(skew uint) Option {
+	return optionFunc(func(g *Generator) {
+		if skew != 0 {
+			g.skew = skew
+		}
+	})
+}
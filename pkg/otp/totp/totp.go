@@ -0,0 +1,161 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // RFC 6238 default algorithm supported by every authenticator app
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	defaultDigits     = 6
+	defaultPeriod     = 30 * time.Second
+	defaultSkew       = 1
+	defaultSecretSize = 20
+	defaultQRCodeSize = 256
+)
+
+var b32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Generator implements RFC 6238 time-based one-time passwords
+type Generator struct {
+	digits     int
+	period     time.Duration
+	skew       uint
+	secretSize int
+}
+
+func NewGenerator(options ...Option) *Generator {
+	g := Generator{
+		digits:     defaultDigits,
+		period:     defaultPeriod,
+		skew:       defaultSkew,
+		secretSize: defaultSecretSize,
+	}
+
+	Preset(options...).apply(&g)
+
+	return &g
+}
+
+// Period returns time step duration
+func (g *Generator) Period() time.Duration { return g.period }
+
+// Skew returns number of time steps accepted before and after the current one
+func (g *Generator) Skew() uint { return g.skew }
+
+// GenerateSecret returns new random base32 encoded shared secret
+func (g *Generator) GenerateSecret() (string, error) {
+	secret := make([]byte, g.secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", errors.Wrap(err, "failed to generate random secret")
+	}
+
+	return b32NoPadding.EncodeToString(secret), nil
+}
+
+// GenerateCode returns one-time password for the time step which includes t
+func (g *Generator) GenerateCode(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return g.code(key, g.counter(t)), nil
+}
+
+// Validate reports whether code is valid for the time step which includes t
+// or for one of the neighbouring time steps allowed by skew.
+// On success the matched time step counter is returned, so callers can prevent code reuse.
+func (g *Generator) Validate(code, secret string, t time.Time) (counter uint64, ok bool) {
+	if len(code) != g.digits {
+		return 0, false
+	}
+
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	current := g.counter(t)
+	for i := -int64(g.skew); i <= int64(g.skew); i++ {
+		c := uint64(int64(current) + i)
+		if subtle.ConstantTimeCompare([]byte(g.code(key, c)), []byte(code)) == 1 {
+			return c, true
+		}
+	}
+
+	return 0, false
+}
+
+// KeyURI returns otpauth:// URI understood by authenticator apps
+//
+// See https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func (g *Generator) KeyURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(g.digits))
+	params.Set("period", fmt.Sprint(int64(g.period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: params.Encode(),
+	}
+
+	return u.String()
+}
+
+// QRCode returns PNG encoded QR code of the provided key URI
+func QRCode(uri string) ([]byte, error) {
+	png, err := qrcode.Encode(uri, qrcode.Medium, defaultQRCodeSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode QR code")
+	}
+
+	return png, nil
+}
+
+func (g *Generator) counter(t time.Time) uint64 {
+	return uint64(t.Unix() / int64(g.period/time.Second))
+}
+
+func (g *Generator) code(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(math.Pow10(g.digits))
+
+	return fmt.Sprintf("%0*d", g.digits, value%mod)
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+
+	key, err := b32NoPadding.DecodeString(secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode base32 secret")
+	}
+
+	return key, nil
+}
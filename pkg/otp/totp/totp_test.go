@@ -0,0 +1,172 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_GenerateCode(t *testing.T) {
+	type args struct {
+		time time.Time
+	}
+
+	type ret struct {
+		code string
+	}
+
+	// Test vectors from RFC 6238 Appendix B (SHA1 mode)
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	testCases := []struct {
+		name string
+		args args
+		ret  ret
+	}{
+		{
+			name: "59",
+			args: args{time: time.Unix(59, 0)},
+			ret:  ret{code: "94287082"},
+		},
+		{
+			name: "1111111109",
+			args: args{time: time.Unix(1111111109, 0)},
+			ret:  ret{code: "07081804"},
+		},
+		{
+			name: "1111111111",
+			args: args{time: time.Unix(1111111111, 0)},
+			ret:  ret{code: "14050471"},
+		},
+		{
+			name: "1234567890",
+			args: args{time: time.Unix(1234567890, 0)},
+			ret:  ret{code: "89005924"},
+		},
+		{
+			name: "2000000000",
+			args: args{time: time.Unix(2000000000, 0)},
+			ret:  ret{code: "69279037"},
+		},
+		{
+			name: "20000000000",
+			args: args{time: time.Unix(20000000000, 0)},
+			ret:  ret{code: "65353130"},
+		},
+	}
+
+	t.Parallel()
+
+	g := NewGenerator(SetDigits(8))
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := g.GenerateCode(secret, tc.args.time)
+			require.NoErrorf(t, err, "failed to generate code: %s", err)
+			assert.Equal(t, tc.ret.code, code)
+		})
+	}
+}
+
+func TestGenerator_Validate(t *testing.T) {
+	type args struct {
+		code func(t *testing.T, g *Generator, secret string, now time.Time) string
+	}
+
+	type ret struct {
+		ok bool
+	}
+
+	testCases := []struct {
+		name string
+		args args
+		ret  ret
+	}{
+		{
+			name: "wrong length",
+			args: args{
+				code: func(_ *testing.T, _ *Generator, _ string, _ time.Time) string { return "12345" },
+			},
+			ret: ret{ok: false},
+		},
+		{
+			name: "code from distant past",
+			args: args{
+				code: func(t *testing.T, g *Generator, secret string, now time.Time) string {
+					t.Helper()
+
+					code, err := g.GenerateCode(secret, now.Add(-5*g.Period()))
+					require.NoErrorf(t, err, "failed to generate code: %s", err)
+
+					return code
+				},
+			},
+			ret: ret{ok: false},
+		},
+		{
+			name: "code from previous time step",
+			args: args{
+				code: func(t *testing.T, g *Generator, secret string, now time.Time) string {
+					t.Helper()
+
+					code, err := g.GenerateCode(secret, now.Add(-g.Period()))
+					require.NoErrorf(t, err, "failed to generate code: %s", err)
+
+					return code
+				},
+			},
+			ret: ret{ok: true},
+		},
+		{
+			name: "ok",
+			args: args{
+				code: func(t *testing.T, g *Generator, secret string, now time.Time) string {
+					t.Helper()
+
+					code, err := g.GenerateCode(secret, now)
+					require.NoErrorf(t, err, "failed to generate code: %s", err)
+
+					return code
+				},
+			},
+			ret: ret{ok: true},
+		},
+	}
+
+	t.Parallel()
+
+	g := NewGenerator()
+
+	secret, err := g.GenerateSecret()
+	require.NoErrorf(t, err, "failed to generate secret: %s", err)
+
+	now := time.Unix(1672531200, 0)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, ok := g.Validate(tc.args.code(t, g, secret, now), secret, now)
+			assert.Equal(t, tc.ret.ok, ok)
+		})
+	}
+}
+
+func TestGenerator_KeyURI(t *testing.T) {
+	t.Parallel()
+
+	g := NewGenerator()
+
+	uri, err := url.Parse(g.KeyURI("url-shrtnr", "kenplix", "JBSWY3DPEHPK3PXP"))
+	require.NoErrorf(t, err, "failed to parse key URI: %s", err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/url-shrtnr:kenplix", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "url-shrtnr", uri.Query().Get("issuer"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+	assert.Equal(t, "30", uri.Query().Get("period"))
+}
@@ -0,0 +1,24 @@
+package requestinfo
+
+import "context"
+
+type infoContext struct{}
+
+// Info describes client and request on whose behalf the code is executed
+type Info struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+// ContextWithInfo returns a copy of parent context in which the request info is stored
+func ContextWithInfo(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoContext{}, info)
+}
+
+// FromContext returns request info from context, empty info is returned
+// when code isn't executed on behalf of any request
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(infoContext{}).(Info)
+	return info
+}
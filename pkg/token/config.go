@@ -1,18 +1,60 @@
 package token
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
 
 type Config struct {
-	PrivateKey string        `mapstructure:"privateKey"`
-	PublicKey  string        `mapstructure:"publicKey"`
-	TTL        time.Duration `mapstructure:"ttl"`
+	// Algorithm is a signing algorithm of PrivateKey and PublicKey: RS256 (default), ES256 or EdDSA.
+	// Algorithms of KeysDir keys are determined by key types
+	Algorithm  string          `mapstructure:"algorithm"`
+	PrivateKey string          `mapstructure:"privateKey"`
+	PublicKey  string          `mapstructure:"publicKey"`
+	Migration  MigrationConfig `mapstructure:"migration"`
+	// KeysDir is a directory with key ring files, takes precedence over PrivateKey and PublicKey.
+	// It contains <kid>.pem private keys, <kid>.pub.pem verification only public keys
+	// and "active" file with ID of the key used for signing
+	KeysDir string `mapstructure:"keysDir"`
+	// GenerateKeys creates Ed25519 signing key in KeysDir if it has no active key,
+	// so deployments without provisioned keys are able to start
+	GenerateKeys bool `mapstructure:"generateKeys"`
+	// Issuer and Audience are put into tokens and required on parse if provided
+	Issuer   string        `mapstructure:"issuer"`
+	Audience string        `mapstructure:"audience"`
+	TTL      time.Duration `mapstructure:"ttl"`
+}
+
+// MigrationConfig allows to change signing algorithm without invalidating issued tokens
+type MigrationConfig struct {
+	// Algorithm is a signing algorithm of tokens issued before migration
+	Algorithm string `mapstructure:"algorithm"`
+	// PublicKey is a base64 encoded PEM public key of tokens issued before migration
+	PublicKey string `mapstructure:"publicKey"`
 }
 
 func SetConfig(cfg Config) Option {
-	preset := Preset(
-		SetPrivateKey(cfg.PrivateKey),
-		SetPublicKey(cfg.PublicKey),
-	)
+	preset := keyOptions(cfg.Algorithm, cfg.PrivateKey, cfg.PublicKey)
+
+	if cfg.Migration.PublicKey != "" {
+		preset = Preset(preset, SetLegacyPublicKey(cfg.Migration.Algorithm, cfg.Migration.PublicKey))
+	}
+
+	if cfg.KeysDir != "" {
+		preset = SetKeysDir(cfg.KeysDir)
+
+		if cfg.GenerateKeys {
+			preset = Preset(preset, SetGenerateKeys())
+		}
+	}
+
+	preset = Preset(preset, SetIssuer(cfg.Issuer), SetAudience(cfg.Audience))
 
 	if cfg.TTL != 0 {
 		preset = Preset(preset, SetTTL(cfg.TTL))
@@ -20,3 +62,18 @@ func SetConfig(cfg Config) Option {
 
 	return preset
 }
+
+func keyOptions(algorithm, privateKey, publicKey string) Option {
+	switch algorithm {
+	case "", RS256:
+		return Preset(SetPrivateKey(privateKey), SetPublicKey(publicKey))
+	case ES256:
+		return Preset(SetECPrivateKey(privateKey), SetECPublicKey(publicKey))
+	case EdDSA:
+		return Preset(SetEdPrivateKey(privateKey), SetEdPublicKey(publicKey))
+	default:
+		return optionFunc(func(s *jwtService) error {
+			return fmt.Errorf("unsupported signing algorithm %q", algorithm)
+		})
+	}
+}
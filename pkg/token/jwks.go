@@ -0,0 +1,55 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWK is a JSON Web Key (RFC 7517) representation of a public key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	// RSA key parameters
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Elliptic curve and Edwards curve key parameters
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set used by third parties to verify tokens
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func newJWK(k *key) JWK {
+	jwk := JWK{
+		Use:       "sig",
+		Algorithm: k.method.Alg(),
+		KeyID:     k.id,
+	}
+
+	switch publicKey := k.publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encodeInt(publicKey.N)
+		jwk.E = encodeInt(big.NewInt(int64(publicKey.E)))
+	case *ecdsa.PublicKey:
+		jwk.KeyType = "EC"
+		jwk.Curve = publicKey.Curve.Params().Name
+		jwk.X = encodeCoordinate(publicKey.X, publicKey.Curve)
+		jwk.Y = encodeCoordinate(publicKey.Y, publicKey.Curve)
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	}
+
+	return jwk
+}
@@ -1,8 +1,10 @@
 package token
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap/zapcore"
@@ -11,19 +13,33 @@ import (
 
 	"github.com/golang-jwt/jwt"
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 )
 
 //go:generate mockery --dir . --name JWTService --output ./mocks
 type JWTService interface {
-	CreateToken(id string) (jwt, uid string, err error)
+	CreateToken(id string, scopes []string) (jwt, uid string, err error)
 	ParseToken(jwt string) (*JWTCustomClaims, error)
 	TokenTTL() time.Duration
+	// JWKS returns public keys which may be used to verify tokens
+	JWKS() JWKS
+	// Reload re-reads keys from the keys directory if it was configured
+	Reload() error
 }
 
 type jwtService struct {
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
-	ttl        time.Duration
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+	// legacyPublicKey verifies tokens signed with the previous algorithm during migration
+	legacyPublicKey crypto.PublicKey
+	keysDir         string
+	generateKeys    bool
+	issuer          string
+	audience        string
+	ttl             time.Duration
+
+	mu   sync.RWMutex
+	ring *keyRing
 }
 
 func NewJWTService(cfg Config) (JWTService, error) {
@@ -32,14 +48,40 @@ func NewJWTService(cfg Config) (JWTService, error) {
 		return nil, err
 	}
 
+	if s.keysDir != "" {
+		if s.generateKeys {
+			if err := generateKeyRing(s.keysDir); err != nil {
+				return nil, errors.Wrap(err, "failed to generate key ring")
+			}
+		}
+
+		if err := s.Reload(); err != nil {
+			return nil, err
+		}
+	} else {
+		ring, err := s.staticKeyRing()
+		if err != nil {
+			return nil, err
+		}
+
+		s.ring = ring
+	}
+
 	return &s, nil
 }
 
 type JWTCustomClaims struct {
 	UID string `json:"uid"`
+	// Scopes restrict operations that token bearer may perform
+	Scopes []string `json:"scopes,omitempty"`
 	jwt.StandardClaims
 }
 
+// HasScopes reports whether token grants all provided scopes
+func (c *JWTCustomClaims) HasScopes(scopes ...string) bool {
+	return lo.Every(c.Scopes, scopes)
+}
+
 func (c *JWTCustomClaims) Valid() error {
 	if c.UID == "" {
 		return errors.New("token has empty UID")
@@ -51,7 +93,9 @@ func (c *JWTCustomClaims) Valid() error {
 func (c *JWTCustomClaims) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddString("uid", c.UID)
 	enc.AddString("sub", c.Subject)
-
+	enc.AddString("iss", c.Issuer)
+	enc.AddString("aud", c.Audience)
+	enc.AddString("scopes", strings.Join(c.Scopes, " "))
 	enc.AddInt64("exp", c.ExpiresAt)
 	enc.AddInt64("iat", c.IssuedAt)
 	enc.AddInt64("nbf", c.NotBefore)
@@ -59,30 +103,43 @@ func (c *JWTCustomClaims) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	return nil
 }
 
-func (s *jwtService) CreateToken(id string) (tokenString, uid string, err error) {
+func (s *jwtService) CreateToken(id string, scopes []string) (tokenString, uid string, err error) {
 	uid = uuid.New().String()
 	now := time.Now().UTC()
 
-	tokenString, err = jwt.NewWithClaims(jwt.SigningMethodRS256, &JWTCustomClaims{
-		UID: uid,
+	active := s.keyRing().active
+
+	token := jwt.NewWithClaims(active.method, &JWTCustomClaims{
+		UID:    uid,
+		Scopes: scopes,
 		StandardClaims: jwt.StandardClaims{
+			Issuer:    s.issuer,
+			Audience:  s.audience,
 			Subject:   id,
 			ExpiresAt: now.Add(s.ttl).Unix(),
 			IssuedAt:  now.Unix(),
 			NotBefore: now.Unix(),
 		},
-	}).SignedString(s.privateKey)
+	})
+	token.Header["kid"] = active.id
+
+	tokenString, err = token.SignedString(active.privateKey)
 
 	return tokenString, uid, err
 }
 
 func (s *jwtService) ParseToken(tokenString string) (*JWTCustomClaims, error) {
+	ring := s.keyRing()
+
 	token, err := jwt.ParseWithClaims(tokenString, &JWTCustomClaims{}, func(token *jwt.Token) (i any, err error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %#v", token.Header["alg"])
+		kid, _ := token.Header["kid"].(string)
+
+		k, ok := ring.verificationKey(kid, token.Method.Alg())
+		if !ok {
+			return nil, fmt.Errorf("unexpected signing method %#v or key ID %q", token.Header["alg"], kid)
 		}
 
-		return s.publicKey, nil
+		return k.publicKey, nil
 	})
 	if err != nil {
 		return nil, err
@@ -93,7 +150,79 @@ func (s *jwtService) ParseToken(tokenString string) (*JWTCustomClaims, error) {
 		return nil, errors.New("error get claims from token")
 	}
 
+	if s.issuer != "" && !claims.VerifyIssuer(s.issuer, true) {
+		return nil, fmt.Errorf("unexpected token issuer %q", claims.Issuer)
+	}
+
+	if s.audience != "" && !claims.VerifyAudience(s.audience, true) {
+		return nil, fmt.Errorf("unexpected token audience %q", claims.Audience)
+	}
+
 	return claims, nil
 }
 
 func (s *jwtService) TokenTTL() time.Duration { return s.ttl }
+
+func (s *jwtService) JWKS() JWKS {
+	return s.keyRing().jwks()
+}
+
+func (s *jwtService) Reload() error {
+	if s.keysDir == "" {
+		return nil
+	}
+
+	ring, err := loadKeyRing(s.keysDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to load key ring")
+	}
+
+	s.mu.Lock()
+	s.ring = ring
+	s.mu.Unlock()
+
+	return nil
+}
+
+// staticKeyRing creates key ring from keys provided by options
+func (s *jwtService) staticKeyRing() (*keyRing, error) {
+	signer, ok := s.privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", s.privateKey)
+	}
+
+	privateMethod, err := signingMethod(signer.Public())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid private key")
+	}
+
+	active, err := newKey("", s.privateKey, s.publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid public key")
+	}
+
+	if privateMethod != active.method {
+		return nil, fmt.Errorf("private key algorithm %q mismatches public key algorithm %q",
+			privateMethod.Alg(),
+			active.method.Alg(),
+		)
+	}
+
+	var legacy *key
+
+	if s.legacyPublicKey != nil {
+		legacy, err = newKey("", nil, s.legacyPublicKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid legacy public key")
+		}
+	}
+
+	return newKeyRing(active, legacy), nil
+}
+
+func (s *jwtService) keyRing() *keyRing {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ring
+}
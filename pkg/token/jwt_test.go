@@ -2,6 +2,9 @@ package token
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -10,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt"
 	"github.com/stretchr/testify/require"
 
 	"github.com/stretchr/testify/assert"
@@ -102,6 +106,12 @@ func TestNewJWTService(t *testing.T) {
 					privateKey: privateKey,
 					publicKey:  publicKey,
 					ttl:        time.Minute,
+					ring: newKeyRing(&key{
+						id:         thumbprint(publicKey),
+						method:     jwt.SigningMethodRS256,
+						privateKey: privateKey,
+						publicKey:  publicKey,
+					}, nil),
 				},
 				hasErr: false,
 			},
@@ -149,3 +159,295 @@ func encodeRSAPublicKey(t *testing.T, publicKey *rsa.PublicKey) string {
 
 	return base64.StdEncoding.EncodeToString(buf.Bytes())
 }
+
+func TestJWTService_Algorithms(t *testing.T) {
+	type ret struct {
+		keyType string
+		curve   string
+		hasErr  bool
+	}
+
+	testCases := []struct {
+		name   string
+		config func(t *testing.T) Config
+		ret    ret
+	}{
+		{
+			name: "RS256",
+			config: func(t *testing.T) Config {
+				privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+				require.NoErrorf(t, err, "failed to generate RSA keypair: %s", err)
+
+				return Config{
+					Algorithm:  RS256,
+					PrivateKey: encodeRSAPrivateKey(t, privateKey),
+					PublicKey:  encodeRSAPublicKey(t, &privateKey.PublicKey),
+					TTL:        time.Minute,
+				}
+			},
+			ret: ret{keyType: "RSA"},
+		},
+		{
+			name: "ES256",
+			config: func(t *testing.T) Config {
+				privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				require.NoErrorf(t, err, "failed to generate ECDSA keypair: %s", err)
+
+				return Config{
+					Algorithm:  ES256,
+					PrivateKey: encodePKCS8PrivateKey(t, privateKey),
+					PublicKey:  encodePKIXPublicKey(t, &privateKey.PublicKey),
+					TTL:        time.Minute,
+				}
+			},
+			ret: ret{keyType: "EC", curve: "P-256"},
+		},
+		{
+			name: "EdDSA",
+			config: func(t *testing.T) Config {
+				publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+				require.NoErrorf(t, err, "failed to generate Ed25519 keypair: %s", err)
+
+				return Config{
+					Algorithm:  EdDSA,
+					PrivateKey: encodePKCS8PrivateKey(t, privateKey),
+					PublicKey:  encodePKIXPublicKey(t, publicKey),
+					TTL:        time.Minute,
+				}
+			},
+			ret: ret{keyType: "OKP", curve: "Ed25519"},
+		},
+		{
+			name: "ES256 with P-384 curve",
+			config: func(t *testing.T) Config {
+				privateKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+				require.NoErrorf(t, err, "failed to generate ECDSA keypair: %s", err)
+
+				return Config{
+					Algorithm:  ES256,
+					PrivateKey: encodePKCS8PrivateKey(t, privateKey),
+					PublicKey:  encodePKIXPublicKey(t, &privateKey.PublicKey),
+					TTL:        time.Minute,
+				}
+			},
+			ret: ret{hasErr: true},
+		},
+		{
+			name: "mismatched keys",
+			config: func(t *testing.T) Config {
+				privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				require.NoErrorf(t, err, "failed to generate ECDSA keypair: %s", err)
+
+				publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+				require.NoErrorf(t, err, "failed to generate Ed25519 keypair: %s", err)
+
+				return Config{
+					Algorithm:  ES256,
+					PrivateKey: encodePKCS8PrivateKey(t, privateKey),
+					PublicKey:  encodePKIXPublicKey(t, publicKey),
+					TTL:        time.Minute,
+				}
+			},
+			ret: ret{hasErr: true},
+		},
+		{
+			name: "unsupported algorithm",
+			config: func(t *testing.T) Config {
+				return Config{
+					Algorithm: "HS256",
+					TTL:       time.Minute,
+				}
+			},
+			ret: ret{hasErr: true},
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := tc.config(t)
+
+			jwtServ, err := NewJWTService(cfg)
+			assert.Falsef(t, (err != nil) != tc.ret.hasErr, "expected error: %t, but got: %v", tc.ret.hasErr, err)
+
+			if tc.ret.hasErr {
+				return
+			}
+
+			tokenString, uid, err := jwtServ.CreateToken("<user id>", nil)
+			require.NoErrorf(t, err, "failed to create token: %s", err)
+
+			claims, err := jwtServ.ParseToken(tokenString)
+			require.NoErrorf(t, err, "failed to parse token: %s", err)
+			assert.Equal(t, uid, claims.UID)
+
+			jwks := jwtServ.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, cfg.Algorithm, jwks.Keys[0].Algorithm)
+			assert.Equal(t, tc.ret.keyType, jwks.Keys[0].KeyType)
+			assert.Equal(t, tc.ret.curve, jwks.Keys[0].Curve)
+		})
+	}
+}
+
+func TestJWTService_Migration(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoErrorf(t, err, "failed to generate RSA keypair: %s", err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoErrorf(t, err, "failed to generate ECDSA keypair: %s", err)
+
+	oldServ, err := NewJWTService(Config{
+		PrivateKey: encodeRSAPrivateKey(t, rsaKey),
+		PublicKey:  encodeRSAPublicKey(t, &rsaKey.PublicKey),
+		TTL:        time.Minute,
+	})
+	require.NoErrorf(t, err, "failed to create JWT service: %s", err)
+
+	oldToken, _, err := oldServ.CreateToken("<user id>", nil)
+	require.NoErrorf(t, err, "failed to create token: %s", err)
+
+	newConfig := Config{
+		Algorithm:  ES256,
+		PrivateKey: encodePKCS8PrivateKey(t, ecKey),
+		PublicKey:  encodePKIXPublicKey(t, &ecKey.PublicKey),
+		TTL:        time.Minute,
+	}
+
+	newServ, err := NewJWTService(newConfig)
+	require.NoErrorf(t, err, "failed to create JWT service: %s", err)
+
+	_, err = newServ.ParseToken(oldToken)
+	assert.Error(t, err, "tokens of the old algorithm must be rejected without migration")
+
+	newConfig.Migration = MigrationConfig{
+		Algorithm: RS256,
+		PublicKey: encodeRSAPublicKey(t, &rsaKey.PublicKey),
+	}
+
+	migrationServ, err := NewJWTService(newConfig)
+	require.NoErrorf(t, err, "failed to create JWT service: %s", err)
+
+	_, err = migrationServ.ParseToken(oldToken)
+	assert.NoError(t, err, "tokens of the old algorithm must be accepted during migration")
+
+	newToken, _, err := migrationServ.CreateToken("<user id>", nil)
+	require.NoErrorf(t, err, "failed to create token: %s", err)
+
+	parsed, _, err := new(jwt.Parser).ParseUnverified(newToken, &JWTCustomClaims{})
+	require.NoErrorf(t, err, "failed to parse token: %s", err)
+	assert.Equal(t, ES256, parsed.Method.Alg(), "new tokens must be issued with the new algorithm")
+
+	newConfig.Migration.Algorithm = EdDSA
+
+	_, err = NewJWTService(newConfig)
+	assert.Error(t, err, "migration key must match migration algorithm")
+}
+
+func encodePKCS8PrivateKey(t *testing.T, privateKey any) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoErrorf(t, err, "failed to marshal private key: %s", err)
+
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: der,
+	}))
+}
+
+func encodePKIXPublicKey(t *testing.T, publicKey any) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoErrorf(t, err, "failed to marshal public key: %s", err)
+
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: der,
+	}))
+}
+
+func TestJWTService_IssuerAndAudience(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoErrorf(t, err, "failed to generate RSA keypair: %s", err)
+
+	testConfig := func(issuer, audience string) Config {
+		return Config{
+			PrivateKey: encodeRSAPrivateKey(t, privateKey),
+			PublicKey:  encodeRSAPublicKey(t, &privateKey.PublicKey),
+			Issuer:     issuer,
+			Audience:   audience,
+			TTL:        time.Minute,
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		creator Config
+		parser  Config
+		hasErr  bool
+	}{
+		{
+			name:    "not configured",
+			creator: testConfig("", ""),
+			parser:  testConfig("", ""),
+			hasErr:  false,
+		},
+		{
+			name:    "matching",
+			creator: testConfig("https://sh.rt", "api"),
+			parser:  testConfig("https://sh.rt", "api"),
+			hasErr:  false,
+		},
+		{
+			name:    "another issuer",
+			creator: testConfig("https://staging.sh.rt", "api"),
+			parser:  testConfig("https://sh.rt", "api"),
+			hasErr:  true,
+		},
+		{
+			name:    "another audience",
+			creator: testConfig("https://sh.rt", "internal"),
+			parser:  testConfig("https://sh.rt", "api"),
+			hasErr:  true,
+		},
+		{
+			name:    "missing claims",
+			creator: testConfig("", ""),
+			parser:  testConfig("https://sh.rt", "api"),
+			hasErr:  true,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			creator, err := NewJWTService(tc.creator)
+			require.NoErrorf(t, err, "failed to create JWT service: %s", err)
+
+			parser, err := NewJWTService(tc.parser)
+			require.NoErrorf(t, err, "failed to create JWT service: %s", err)
+
+			tokenString, _, err := creator.CreateToken("<user id>", []string{"links:read", "links:write"})
+			require.NoErrorf(t, err, "failed to create token: %s", err)
+
+			claims, err := parser.ParseToken(tokenString)
+			assert.Falsef(t, (err != nil) != tc.hasErr, "expected error: %t, but got: %v", tc.hasErr, err)
+
+			if tc.hasErr {
+				return
+			}
+
+			assert.Equal(t, tc.creator.Issuer, claims.Issuer)
+			assert.Equal(t, tc.creator.Audience, claims.Audience)
+			assert.True(t, claims.HasScopes("links:read"))
+			assert.True(t, claims.HasScopes("links:write", "links:read"))
+			assert.False(t, claims.HasScopes("links:read", "admin"))
+		})
+	}
+}
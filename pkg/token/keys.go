@@ -0,0 +1,366 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+)
+
+const (
+	// privateKeySuffix is a suffix of PEM encoded private key files, key may be used for signing and verification
+	privateKeySuffix = ".pem"
+	// publicKeySuffix is a suffix of PEM encoded public key files, key may be used only for verification
+	publicKeySuffix = ".pub.pem"
+	// activeKeyFile is a name of file containing ID of the key used for signing
+	activeKeyFile = "active"
+)
+
+type key struct {
+	id         string
+	method     jwt.SigningMethod
+	privateKey crypto.PrivateKey // nil for verification only keys
+	publicKey  crypto.PublicKey
+}
+
+func newKey(id string, privateKey crypto.PrivateKey, publicKey crypto.PublicKey) (*key, error) {
+	method, err := signingMethod(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		id = thumbprint(publicKey)
+	}
+
+	k := &key{
+		id:         id,
+		method:     method,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+	}
+
+	return k, nil
+}
+
+// keyRing holds keys used for tokens verification and the active one used for signing
+type keyRing struct {
+	active *key
+	// legacy verifies tokens without key ID signed with the previous algorithm
+	legacy *key
+	keys   map[string]*key
+}
+
+func newKeyRing(active, legacy *key) *keyRing {
+	ring := &keyRing{
+		active: active,
+		legacy: legacy,
+		keys:   map[string]*key{active.id: active},
+	}
+
+	if legacy != nil {
+		ring.keys[legacy.id] = legacy
+	}
+
+	return ring
+}
+
+// loadKeyRing reads keys from dir. Keys are retired by removing their files from dir
+func loadKeyRing(dir string) (*keyRing, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %q keys directory", dir)
+	}
+
+	ring := &keyRing{
+		keys: make(map[string]*key),
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+
+		switch {
+		case strings.HasSuffix(name, publicKeySuffix):
+			id := strings.TrimSuffix(name, publicKeySuffix)
+			if _, ok := ring.keys[id]; ok {
+				continue
+			}
+
+			buf, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read %q public key", id)
+			}
+
+			publicKey, err := parsePublicKeyFromPEM(buf)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse %q public key", id)
+			}
+
+			ring.keys[id], err = newKey(id, nil, publicKey)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %q public key", id)
+			}
+		case strings.HasSuffix(name, privateKeySuffix):
+			id := strings.TrimSuffix(name, privateKeySuffix)
+
+			buf, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read %q private key", id)
+			}
+
+			privateKey, err := parsePrivateKeyFromPEM(buf)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse %q private key", id)
+			}
+
+			ring.keys[id], err = newKey(id, privateKey, privateKey.Public())
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %q private key", id)
+			}
+		}
+	}
+
+	buf, err := os.ReadFile(filepath.Join(dir, activeKeyFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read active key ID")
+	}
+
+	activeID := strings.TrimSpace(string(buf))
+
+	active, ok := ring.keys[activeID]
+	if !ok {
+		return nil, errors.Errorf("active key %q not found", activeID)
+	}
+
+	if active.privateKey == nil {
+		return nil, errors.Errorf("active key %q has no private key", activeID)
+	}
+
+	ring.active = active
+
+	return ring, nil
+}
+
+// generateKeyRing creates dir with Ed25519 active key unless dir already has active key
+func generateKeyRing(dir string) error {
+	_, err := os.Stat(filepath.Join(dir, activeKeyFile))
+	if err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "failed to check active key ID")
+	}
+
+	err = os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %q keys directory", dir)
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate private key")
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal private key")
+	}
+
+	id := thumbprint(publicKey)
+
+	err = os.WriteFile(
+		filepath.Join(dir, id+privateKeySuffix),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		0o600,
+	)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write %q private key", id)
+	}
+
+	// active key ID is written last, so interrupted generation is repeated on the next start
+	err = os.WriteFile(filepath.Join(dir, activeKeyFile), []byte(id+"\n"), 0o600)
+	if err != nil {
+		return errors.Wrap(err, "failed to write active key ID")
+	}
+
+	return nil
+}
+
+// verificationKey returns key with provided ID if it's used with alg signing algorithm.
+// Tokens issued before key IDs were introduced have no ID and are verified
+// with the active key or the legacy one during algorithm migration
+func (r *keyRing) verificationKey(id, alg string) (*key, bool) {
+	var candidates []*key
+
+	if id != "" {
+		if k, ok := r.keys[id]; ok {
+			candidates = append(candidates, k)
+		}
+	} else {
+		candidates = append(candidates, r.active)
+		if r.legacy != nil {
+			candidates = append(candidates, r.legacy)
+		}
+	}
+
+	for _, k := range candidates {
+		if k.method.Alg() == alg {
+			return k, true
+		}
+	}
+
+	return nil, false
+}
+
+func (r *keyRing) jwks() JWKS {
+	ids := make([]string, 0, len(r.keys))
+	for id := range r.keys {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	set := JWKS{Keys: make([]JWK, 0, len(ids))}
+	for _, id := range ids {
+		set.Keys = append(set.Keys, newJWK(r.keys[id]))
+	}
+
+	return set
+}
+
+// signingMethod returns signing method used with the public key
+func signingMethod(publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported ECDSA curve %q", key.Curve.Params().Name)
+		}
+
+		return jwt.SigningMethodES256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+func parsePrivateKeyFromPEM(buf []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return nil, errors.New("key must be PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+
+		return signer, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("unsupported private key format")
+}
+
+func parsePublicKeyFromPEM(buf []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(buf)
+	if block == nil {
+		return nil, errors.New("key must be PEM encoded")
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("unsupported public key format")
+}
+
+// thumbprint computes RFC 7638 JWK thumbprint of the public key
+func thumbprint(publicKey crypto.PublicKey) string {
+	var members any
+
+	// required members in lexicographic order
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{
+			E:   encodeInt(big.NewInt(int64(key.E))),
+			Kty: "RSA",
+			N:   encodeInt(key.N),
+		}
+	case *ecdsa.PublicKey:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{
+			Crv: key.Curve.Params().Name,
+			Kty: "EC",
+			X:   encodeCoordinate(key.X, key.Curve),
+			Y:   encodeCoordinate(key.Y, key.Curve),
+		}
+	case ed25519.PublicKey:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{
+			Crv: "Ed25519",
+			Kty: "OKP",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		}
+	}
+
+	buf, _ := json.Marshal(members)
+	sum := sha256.Sum256(buf)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// encodeCoordinate encodes elliptic curve point coordinate padded to the curve size
+func encodeCoordinate(i *big.Int, curve elliptic.Curve) string {
+	buf := make([]byte, (curve.Params().BitSize+7)/8)
+
+	return base64.RawURLEncoding.EncodeToString(i.FillBytes(buf))
+}
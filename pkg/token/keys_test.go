@@ -0,0 +1,236 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyFiles(t *testing.T, dir, id string, publicOnly bool) *rsa.PrivateKey {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoErrorf(t, err, "failed to generate RSA keypair: %s", err)
+
+	var block *pem.Block
+
+	name := id + privateKeySuffix
+
+	if publicOnly {
+		pkixPublicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+		require.NoErrorf(t, err, "failed to marshal public key: %s", err)
+
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: pkixPublicKey}
+		name = id + publicKeySuffix
+	} else {
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}
+	}
+
+	err = os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0o600)
+	require.NoErrorf(t, err, "failed to write %q key: %s", id, err)
+
+	return privateKey
+}
+
+func activateKey(t *testing.T, dir, id string) {
+	t.Helper()
+
+	err := os.WriteFile(filepath.Join(dir, activeKeyFile), []byte(id+"\n"), 0o600)
+	require.NoErrorf(t, err, "failed to activate %q key: %s", id, err)
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	testCases := []struct {
+		name    string
+		prepare func(t *testing.T, dir string)
+		keys    []string
+		hasErr  bool
+	}{
+		{
+			name:    "missing active key file",
+			prepare: func(t *testing.T, dir string) { writeKeyFiles(t, dir, "k1", false) },
+			hasErr:  true,
+		},
+		{
+			name: "active key not found",
+			prepare: func(t *testing.T, dir string) {
+				writeKeyFiles(t, dir, "k1", false)
+				activateKey(t, dir, "k2")
+			},
+			hasErr: true,
+		},
+		{
+			name: "active key without private key",
+			prepare: func(t *testing.T, dir string) {
+				writeKeyFiles(t, dir, "k1", true)
+				activateKey(t, dir, "k1")
+			},
+			hasErr: true,
+		},
+		{
+			name: "ok",
+			prepare: func(t *testing.T, dir string) {
+				writeKeyFiles(t, dir, "k1", true)
+				writeKeyFiles(t, dir, "k2", false)
+				activateKey(t, dir, "k2")
+
+				publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+				require.NoErrorf(t, err, "failed to generate Ed25519 keypair: %s", err)
+
+				pkixPublicKey, err := x509.MarshalPKIXPublicKey(publicKey)
+				require.NoErrorf(t, err, "failed to marshal public key: %s", err)
+
+				err = os.WriteFile(
+					filepath.Join(dir, "k0"+publicKeySuffix),
+					pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkixPublicKey}),
+					0o600,
+				)
+				require.NoErrorf(t, err, "failed to write file: %s", err)
+
+				err = os.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0o600)
+				require.NoErrorf(t, err, "failed to write file: %s", err)
+			},
+			keys:   []string{"k0", "k1", "k2"},
+			hasErr: false,
+		},
+	}
+
+	t.Parallel()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tc.prepare(t, dir)
+
+			ring, err := loadKeyRing(dir)
+			assert.Falsef(t, (err != nil) != tc.hasErr, "expected error: %t, but got: %v", tc.hasErr, err)
+
+			if tc.hasErr {
+				return
+			}
+
+			var keys []string
+			for _, k := range ring.jwks().Keys {
+				keys = append(keys, k.KeyID)
+			}
+
+			assert.Equal(t, tc.keys, keys)
+		})
+	}
+}
+
+func TestJWTService_KeyRotation(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	writeKeyFiles(t, dir, "k1", false)
+	activateKey(t, dir, "k1")
+
+	jwtServ, err := NewJWTService(Config{KeysDir: dir, TTL: time.Minute})
+	require.NoErrorf(t, err, "failed to create JWT service: %s", err)
+
+	oldToken, _, err := jwtServ.CreateToken("<user id>", nil)
+	require.NoErrorf(t, err, "failed to create token: %s", err)
+
+	writeKeyFiles(t, dir, "k2", false)
+	activateKey(t, dir, "k2")
+	require.NoError(t, jwtServ.Reload())
+
+	newToken, _, err := jwtServ.CreateToken("<user id>", nil)
+	require.NoErrorf(t, err, "failed to create token: %s", err)
+
+	parsed, _, err := new(jwt.Parser).ParseUnverified(newToken, &JWTCustomClaims{})
+	require.NoErrorf(t, err, "failed to parse token: %s", err)
+	assert.Equal(t, "k2", parsed.Header["kid"])
+
+	_, err = jwtServ.ParseToken(oldToken)
+	assert.NoError(t, err, "token signed with previous key must be valid until key is retired")
+
+	_, err = jwtServ.ParseToken(newToken)
+	assert.NoError(t, err)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "k1"+privateKeySuffix)))
+	require.NoError(t, jwtServ.Reload())
+
+	_, err = jwtServ.ParseToken(oldToken)
+	assert.Error(t, err, "token signed with retired key must be rejected")
+
+	require.NoError(t, os.Remove(filepath.Join(dir, activeKeyFile)))
+	assert.Error(t, jwtServ.Reload())
+
+	_, err = jwtServ.ParseToken(newToken)
+	assert.NoError(t, err, "failed reload must keep previous keys")
+}
+
+func TestJWTService_GenerateKeys(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "keys")
+
+	jwtServ, err := NewJWTService(Config{KeysDir: dir, GenerateKeys: true, TTL: time.Minute})
+	require.NoErrorf(t, err, "failed to create JWT service: %s", err)
+
+	activeKeyID, err := os.ReadFile(filepath.Join(dir, activeKeyFile))
+	require.NoErrorf(t, err, "failed to read active key ID: %s", err)
+
+	token, _, err := jwtServ.CreateToken("<user id>", nil)
+	require.NoErrorf(t, err, "failed to create token: %s", err)
+
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &JWTCustomClaims{})
+	require.NoErrorf(t, err, "failed to parse token: %s", err)
+	assert.Equal(t, strings.TrimSpace(string(activeKeyID)), parsed.Header["kid"])
+	assert.Equal(t, jwt.SigningMethodEdDSA.Alg(), parsed.Header["alg"])
+
+	restarted, err := NewJWTService(Config{KeysDir: dir, GenerateKeys: true, TTL: time.Minute})
+	require.NoErrorf(t, err, "failed to create JWT service: %s", err)
+
+	_, err = restarted.ParseToken(token)
+	assert.NoError(t, err, "generated key must be kept across restarts")
+}
+
+func TestJWTService_TokenWithoutKeyID(t *testing.T) {
+	t.Parallel()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoErrorf(t, err, "failed to generate RSA keypair: %s", err)
+
+	jwtServ, err := NewJWTService(Config{
+		PrivateKey: encodeRSAPrivateKey(t, privateKey),
+		PublicKey:  encodeRSAPublicKey(t, &privateKey.PublicKey),
+		TTL:        time.Minute,
+	})
+	require.NoErrorf(t, err, "failed to create JWT service: %s", err)
+
+	now := time.Now()
+
+	legacyToken, err := jwt.NewWithClaims(jwt.SigningMethodRS256, &JWTCustomClaims{
+		UID: "<uid>",
+		StandardClaims: jwt.StandardClaims{
+			Subject:   "<user id>",
+			ExpiresAt: now.Add(time.Minute).Unix(),
+			IssuedAt:  now.Unix(),
+		},
+	}).SignedString(privateKey)
+	require.NoErrorf(t, err, "failed to sign token: %s", err)
+
+	_, err = jwtServ.ParseToken(legacyToken)
+	assert.NoError(t, err)
+
+	jwks := jwtServ.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, thumbprint(&privateKey.PublicKey), jwks.Keys[0].KeyID)
+	assert.Equal(t, "RS256", jwks.Keys[0].Algorithm)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+}
@@ -15,27 +15,27 @@ type JWTService struct {
 	mock.Mock
 }
 
-// CreateToken provides a mock function with given fields: id
-func (_m *JWTService) CreateToken(id string) (string, string, error) {
-	ret := _m.Called(id)
+// CreateToken provides a mock function with given fields: id, scopes
+func (_m *JWTService) CreateToken(id string, scopes []string) (string, string, error) {
+	ret := _m.Called(id, scopes)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(string, []string) string); ok {
+		r0 = rf(id, scopes)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 string
-	if rf, ok := ret.Get(1).(func(string) string); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(string, []string) string); ok {
+		r1 = rf(id, scopes)
 	} else {
 		r1 = ret.Get(1).(string)
 	}
 
 	var r2 error
-	if rf, ok := ret.Get(2).(func(string) error); ok {
-		r2 = rf(id)
+	if rf, ok := ret.Get(2).(func(string, []string) error); ok {
+		r2 = rf(id, scopes)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -43,6 +43,20 @@ func (_m *JWTService) CreateToken(id string) (string, string, error) {
 	return r0, r1, r2
 }
 
+// JWKS provides a mock function with given fields:
+func (_m *JWTService) JWKS() token.JWKS {
+	ret := _m.Called()
+
+	var r0 token.JWKS
+	if rf, ok := ret.Get(0).(func() token.JWKS); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(token.JWKS)
+	}
+
+	return r0
+}
+
 // ParseToken provides a mock function with given fields: jwt
 func (_m *JWTService) ParseToken(jwt string) (*token.JWTCustomClaims, error) {
 	ret := _m.Called(jwt)
@@ -66,6 +80,20 @@ func (_m *JWTService) ParseToken(jwt string) (*token.JWTCustomClaims, error) {
 	return r0, r1
 }
 
+// Reload provides a mock function with given fields:
+func (_m *JWTService) Reload() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // TokenTTL provides a mock function with given fields:
 func (_m *JWTService) TokenTTL() time.Duration {
 	ret := _m.Called()
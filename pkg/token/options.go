@@ -1,7 +1,9 @@
 package token
 
 import (
+	"crypto"
 	"encoding/base64"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -34,43 +36,149 @@ func Preset(options ...Option) Option {
 }
 
 func SetPrivateKey(privateKey string) Option {
+	return setPrivateKey("RSA", privateKey, func(key []byte) (crypto.PrivateKey, error) {
+		return jwt.ParseRSAPrivateKeyFromPEM(key)
+	})
+}
+
+func SetPublicKey(publicKey string) Option {
+	return setPublicKey("RSA", publicKey, func(key []byte) (crypto.PublicKey, error) {
+		return jwt.ParseRSAPublicKeyFromPEM(key)
+	})
+}
+
+func SetECPrivateKey(privateKey string) Option {
+	return setPrivateKey("ECDSA", privateKey, func(key []byte) (crypto.PrivateKey, error) {
+		return jwt.ParseECPrivateKeyFromPEM(key)
+	})
+}
+
+func SetECPublicKey(publicKey string) Option {
+	return setPublicKey("ECDSA", publicKey, func(key []byte) (crypto.PublicKey, error) {
+		return jwt.ParseECPublicKeyFromPEM(key)
+	})
+}
+
+func SetEdPrivateKey(privateKey string) Option {
+	return setPrivateKey("Ed25519", privateKey, jwt.ParseEdPrivateKeyFromPEM)
+}
+
+func SetEdPublicKey(publicKey string) Option {
+	return setPublicKey("Ed25519", publicKey, jwt.ParseEdPublicKeyFromPEM)
+}
+
+// SetLegacyPublicKey configures public key of the previous signing algorithm.
+// Tokens signed with it are accepted while new tokens are issued with the current algorithm
+func SetLegacyPublicKey(algorithm, publicKey string) Option {
 	return optionFunc(func(s *jwtService) error {
-		if privateKey == "" {
-			return errors.New("empty RSA private key")
+		decodedPublicKey, err := decodeKey("legacy public key", publicKey)
+		if err != nil {
+			return err
 		}
 
-		decodedPrivateKey, err := base64.StdEncoding.DecodeString(privateKey)
+		key, err := parsePublicKeyFromPEM(decodedPublicKey)
 		if err != nil {
-			return errors.Wrap(err, "failed to decode RSA private key")
+			return errors.Wrap(err, "failed to parse legacy public key")
 		}
 
-		key, err := jwt.ParseRSAPrivateKeyFromPEM(decodedPrivateKey)
+		method, err := signingMethod(key)
 		if err != nil {
-			return errors.Wrap(err, "failed to parse RSA private key")
+			return errors.Wrap(err, "invalid legacy public key")
 		}
 
-		s.privateKey = key
+		if method.Alg() != algorithm {
+			return fmt.Errorf("legacy public key is not %q key", algorithm)
+		}
+
+		s.legacyPublicKey = key
+
 		return nil
 	})
 }
 
-func SetPublicKey(publicKey string) Option {
+func setPrivateKey(kind, privateKey string, parse func([]byte) (crypto.PrivateKey, error)) Option {
 	return optionFunc(func(s *jwtService) error {
-		if publicKey == "" {
-			return errors.New("empty RSA public key")
+		decodedPrivateKey, err := decodeKey(kind+" private key", privateKey)
+		if err != nil {
+			return err
 		}
 
-		decodedPublicKey, err := base64.StdEncoding.DecodeString(publicKey)
+		key, err := parse(decodedPrivateKey)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse %s private key", kind)
+		}
+
+		s.privateKey = key
+
+		return nil
+	})
+}
+
+func setPublicKey(kind, publicKey string, parse func([]byte) (crypto.PublicKey, error)) Option {
+	return optionFunc(func(s *jwtService) error {
+		decodedPublicKey, err := decodeKey(kind+" public key", publicKey)
 		if err != nil {
-			return errors.Wrap(err, "failed to decode RSA public key")
+			return err
 		}
 
-		key, err := jwt.ParseRSAPublicKeyFromPEM(decodedPublicKey)
+		key, err := parse(decodedPublicKey)
 		if err != nil {
-			return errors.Wrap(err, "failed to parse RSA public key")
+			return errors.Wrapf(err, "failed to parse %s public key", kind)
 		}
 
 		s.publicKey = key
+
+		return nil
+	})
+}
+
+// decodeKey decodes base64 encoded PEM key
+func decodeKey(name, key string) ([]byte, error) {
+	if key == "" {
+		return nil, fmt.Errorf("empty %s", name)
+	}
+
+	decodedKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s", name)
+	}
+
+	return decodedKey, nil
+}
+
+// SetKeysDir configures directory from which key ring is loaded and reloaded
+func SetKeysDir(dir string) Option {
+	return optionFunc(func(s *jwtService) error {
+		if dir == "" {
+			return errors.New("empty keys directory")
+		}
+
+		s.keysDir = dir
+
+		return nil
+	})
+}
+
+// SetGenerateKeys enables generation of the signing key if keys directory has no active key
+func SetGenerateKeys() Option {
+	return optionFunc(func(s *jwtService) error {
+		s.generateKeys = true
+		return nil
+	})
+}
+
+// SetIssuer configures "iss" claim of created tokens, empty issuer is not verified
+func SetIssuer(issuer string) Option {
+	return optionFunc(func(s *jwtService) error {
+		s.issuer = issuer
+		return nil
+	})
+}
+
+// SetAudience configures "aud" claim of created tokens, empty audience is not verified
+func SetAudience(audience string) Option {
+	return optionFunc(func(s *jwtService) error {
+		s.audience = audience
 		return nil
 	})
 }